@@ -0,0 +1,123 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Decision is the outcome of an audited request.
+type Decision string
+
+const (
+	DecisionAllow  Decision = "allow"  // The handler ran and the request succeeded
+	DecisionDeny   Decision = "deny"   // The session, CSRF token, RBAC or a policy denied the request (401 / 403)
+	DecisionFailed Decision = "failed" // The request was rejected for another reason, e.g., invalid input
+	DecisionError  Decision = "error"  // The request failed with a server error
+)
+
+// Event is a single audit record of a protected route.
+type Event struct {
+	Time         time.Time `json:"time"`
+	RequestID    string    `json:"request_id,omitempty"`
+	Subject      string    `json:"subject,omitempty"`
+	Impersonator string    `json:"impersonator,omitempty"`
+	SessionGroup string    `json:"session_group,omitempty"`
+	Method       string    `json:"method"`
+	Route        string    `json:"route"`
+	IP           string    `json:"ip,omitempty"`
+	Status       int       `json:"status"`
+	Decision     Decision  `json:"decision"`
+
+	// Reason is the client message of the error that ended the request
+	Reason string `json:"reason,omitempty"`
+
+	// Reasons are the denial reasons of attribute policies
+	Reasons []string `json:"reasons,omitempty"`
+
+	// Permissions and Roles are the RBAC requirements of the route
+	Permissions []string `json:"permissions,omitempty"`
+	Roles       []string `json:"roles,omitempty"`
+
+	Duration time.Duration `json:"duration"`
+}
+
+// DecisionForStatus classifies a response status code.
+func DecisionForStatus(status int) Decision {
+	switch {
+	case status == 401 || status == 403:
+		return DecisionDeny
+	case status >= 500:
+		return DecisionError
+	case status >= 400:
+		return DecisionFailed
+	default:
+		return DecisionAllow
+	}
+}
+
+// Sink stores audit events, e.g., NewZapSink, NewFileSink or NewForwarderSink. Write may be called
+// concurrently.
+type Sink interface {
+	Write(ctx context.Context, event Event) error
+}
+
+// SinkFunc adapts a function to a Sink.
+type SinkFunc func(ctx context.Context, event Event) error
+
+func (f SinkFunc) Write(ctx context.Context, event Event) error {
+	return f(ctx, event)
+}
+
+// MultiSink writes every event to all sinks, the errors of the failing ones are joined.
+func MultiSink(sinks ...Sink) Sink {
+	return SinkFunc(func(ctx context.Context, event Event) error {
+		var errs []error
+		for _, sink := range sinks {
+			if sink == nil {
+				continue
+			}
+			if err := sink.Write(ctx, event); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		return errors.Join(errs...)
+	})
+}
+
+var globalSink = struct {
+	sync.RWMutex
+	sink Sink
+}{}
+
+// SetSink sets the sink audit events are recorded to, nil disables auditing.
+func SetSink(sink Sink) {
+	globalSink.Lock()
+	defer globalSink.Unlock()
+	globalSink.sink = sink
+}
+
+// GetSink returns the sink set with SetSink.
+func GetSink() Sink {
+	globalSink.RLock()
+	defer globalSink.RUnlock()
+	return globalSink.sink
+}
+
+// Enabled reports whether a sink is set.
+func Enabled() bool {
+	return GetSink() != nil
+}
+
+// Record writes the event to the sink, it is a no-op without one.
+func Record(ctx context.Context, event Event) error {
+	sink := GetSink()
+	if sink == nil {
+		return nil
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	return sink.Write(ctx, event)
+}
@@ -0,0 +1,148 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDecisionForStatus(t *testing.T) {
+	tests := map[int]Decision{
+		200: DecisionAllow,
+		204: DecisionAllow,
+		400: DecisionFailed,
+		401: DecisionDeny,
+		403: DecisionDeny,
+		413: DecisionFailed,
+		500: DecisionError,
+	}
+	for status, expected := range tests {
+		if decision := DecisionForStatus(status); decision != expected {
+			t.Errorf("DecisionForStatus(%d) = %q, expected %q", status, decision, expected)
+		}
+	}
+}
+
+func TestRecord(t *testing.T) {
+	t.Run("No sink is a no-op", func(t *testing.T) {
+		SetSink(nil)
+		if Enabled() {
+			t.Error("Expected auditing to be disabled")
+		}
+		if err := Record(context.Background(), Event{}); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+
+	t.Run("Multi sinks write to all and join errors", func(t *testing.T) {
+		var written []string
+		SetSink(MultiSink(
+			SinkFunc(func(_ context.Context, event Event) error {
+				written = append(written, "first:"+event.Subject)
+				return fmt.Errorf("first failed")
+			}),
+			nil,
+			SinkFunc(func(_ context.Context, event Event) error {
+				if event.Time.IsZero() {
+					t.Error("Expected Record to stamp the time")
+				}
+				written = append(written, "second:"+event.Subject)
+				return nil
+			}),
+		))
+		defer SetSink(nil)
+
+		err := Record(context.Background(), Event{Subject: "user-1"})
+		if err == nil || len(written) != 2 {
+			t.Errorf("Expected both sinks to be written and the error returned, got %v / %v", written, err)
+		}
+	})
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	sink, err := NewFileSink(path)
+	if err != nil {
+		t.Fatalf("Failed to open the file sink: %v", err)
+	}
+
+	for _, subject := range []string{"user-1", "user-2"} {
+		if err = sink.Write(context.Background(), Event{Subject: subject, Decision: DecisionAllow}); err != nil {
+			t.Fatalf("Failed to write: %v", err)
+		}
+	}
+	if err = sink.Close(); err != nil {
+		t.Fatalf("Failed to close: %v", err)
+	}
+	if err = sink.Write(context.Background(), Event{}); err == nil {
+		t.Error("Expected writes after Close to fail")
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open the audit file: %v", err)
+	}
+	defer file.Close()
+
+	var subjects []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var event Event
+		if err = json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("Expected a JSON line, got %q", scanner.Text())
+		}
+		subjects = append(subjects, event.Subject)
+	}
+	if len(subjects) != 2 || subjects[0] != "user-1" || subjects[1] != "user-2" {
+		t.Errorf("Unexpected events %v", subjects)
+	}
+}
+
+func TestForwarderSink(t *testing.T) {
+	t.Run("Keys events by subject", func(t *testing.T) {
+		var key string
+		var payload []byte
+		sink := NewForwarderSink(ForwarderFunc(func(_ context.Context, k string, p []byte) error {
+			key, payload = k, p
+			return nil
+		}))
+		if err := sink.Write(context.Background(), Event{Subject: "user-1", Route: "/orders"}); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		var event Event
+		if key != "user-1" || json.Unmarshal(payload, &event) != nil || event.Route != "/orders" {
+			t.Errorf("Unexpected forwarded event %q: %s", key, payload)
+		}
+	})
+
+	t.Run("HTTP forwarder posts JSON and checks the status", func(t *testing.T) {
+		status := http.StatusAccepted
+		var body []byte
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+				t.Errorf("Unexpected request %s %s", r.Method, r.Header.Get("Content-Type"))
+			}
+			body, _ = io.ReadAll(r.Body)
+			w.WriteHeader(status)
+		}))
+		defer server.Close()
+
+		sink := NewForwarderSink(NewHTTPForwarder(server.URL, nil))
+		if err := sink.Write(context.Background(), Event{Subject: "user-1"}); err != nil || len(body) == 0 {
+			t.Fatalf("Expected the event to be posted, got %v", err)
+		}
+
+		status = http.StatusInternalServerError
+		if err := sink.Write(context.Background(), Event{Subject: "user-1"}); err == nil {
+			t.Error("Expected a failing collector to return an error")
+		}
+	})
+}
@@ -0,0 +1,137 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// NewZapSink logs every event at info level, a nil logger uses zap.L().
+func NewZapSink(logger *zap.Logger) Sink {
+	return SinkFunc(func(_ context.Context, event Event) error {
+		target := logger
+		if target == nil {
+			target = zap.L()
+		}
+		target.Info("Audit event",
+			zap.Time("time", event.Time),
+			zap.String("request_id", event.RequestID),
+			zap.String("subject", event.Subject),
+			zap.String("impersonator", event.Impersonator),
+			zap.String("session_group", event.SessionGroup),
+			zap.String("method", event.Method),
+			zap.String("route", event.Route),
+			zap.String("ip", event.IP),
+			zap.Int("status", event.Status),
+			zap.String("decision", string(event.Decision)),
+			zap.String("reason", event.Reason),
+			zap.Strings("reasons", event.Reasons),
+			zap.Strings("permissions", event.Permissions),
+			zap.Strings("roles", event.Roles),
+			zap.Duration("duration", event.Duration))
+		return nil
+	})
+}
+
+// FileSink appends every event as a line of JSON (JSONL) to a file.
+type FileSink struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewFileSink opens, or creates, the file in append mode.
+func NewFileSink(path string) (*FileSink, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit file: %w", err)
+	}
+	return &FileSink{file: file}, nil
+}
+
+func (s *FileSink) Write(_ context.Context, event Event) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit event: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return fmt.Errorf("audit file is closed")
+	}
+	if _, err = s.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit event: %w", err)
+	}
+	return nil
+}
+
+// Close flushes and closes the file, later writes fail.
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Sync()
+	if closeErr := s.file.Close(); err == nil {
+		err = closeErr
+	}
+	s.file = nil
+	return err
+}
+
+// Forwarder ships encoded events to another system, e.g., a Kafka producer keyed by subject or an HTTP
+// collector (see NewHTTPForwarder).
+type Forwarder interface {
+	Forward(ctx context.Context, key string, payload []byte) error
+}
+
+// ForwarderFunc adapts a function to a Forwarder.
+type ForwarderFunc func(ctx context.Context, key string, payload []byte) error
+
+func (f ForwarderFunc) Forward(ctx context.Context, key string, payload []byte) error {
+	return f(ctx, key, payload)
+}
+
+// NewForwarderSink encodes every event as JSON and forwards it with the subject as the key, so a partitioned
+// log keeps the events of a subject in order.
+func NewForwarderSink(forwarder Forwarder) Sink {
+	return SinkFunc(func(ctx context.Context, event Event) error {
+		payload, err := json.Marshal(event)
+		if err != nil {
+			return fmt.Errorf("failed to marshal audit event: %w", err)
+		}
+		return forwarder.Forward(ctx, event.Subject, payload)
+	})
+}
+
+// NewHTTPForwarder POSTs every event as JSON to the URL, a nil client uses http.DefaultClient.
+func NewHTTPForwarder(url string, client *http.Client) Forwarder {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return ForwarderFunc(func(ctx context.Context, _ string, payload []byte) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		res, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer res.Body.Close()
+
+		if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusMultipleChoices {
+			return fmt.Errorf("audit collector responded with status %d", res.StatusCode)
+		}
+		return nil
+	})
+}
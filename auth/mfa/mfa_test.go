@@ -0,0 +1,216 @@
+package mfa
+
+import (
+	"encoding/base32"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/core"
+	"github.com/grzegorzmaniak/gothic/errors"
+	"github.com/grzegorzmaniak/gothic/helpers"
+)
+
+func TestHOTPVectors(t *testing.T) {
+	// - RFC 4226 appendix D
+	key := []byte("12345678901234567890")
+	expected := []string{"755224", "287082", "359152", "969429", "338314", "254676", "287922", "162583", "399871", "520489"}
+	for counter, want := range expected {
+		got, err := HOTP(key, uint64(counter), 6, AlgorithmSHA1)
+		if err != nil || got != want {
+			t.Errorf("HOTP(%d) = %s, %v, want %s", counter, got, err, want)
+		}
+	}
+}
+
+func TestTOTPVectors(t *testing.T) {
+	// - RFC 6238 appendix B
+	secrets := map[Algorithm]string{
+		AlgorithmSHA1:   base32.StdEncoding.EncodeToString([]byte("12345678901234567890")),
+		AlgorithmSHA256: base32.StdEncoding.EncodeToString([]byte("12345678901234567890123456789012")),
+		AlgorithmSHA512: base32.StdEncoding.EncodeToString([]byte("1234567890123456789012345678901234567890123456789012345678901234")),
+	}
+	tests := []struct {
+		unix      int64
+		algorithm Algorithm
+		want      string
+	}{
+		{59, AlgorithmSHA1, "94287082"},
+		{59, AlgorithmSHA256, "46119246"},
+		{59, AlgorithmSHA512, "90693936"},
+		{1111111109, AlgorithmSHA1, "07081804"},
+		{1234567890, AlgorithmSHA256, "91819424"},
+		{20000000000, AlgorithmSHA512, "47863826"},
+	}
+	for _, tt := range tests {
+		totp := &TOTP{Digits: 8, Algorithm: tt.algorithm}
+		got, err := totp.Generate(secrets[tt.algorithm], time.Unix(tt.unix, 0))
+		if err != nil || got != tt.want {
+			t.Errorf("TOTP(%d, %s) = %s, %v, want %s", tt.unix, tt.algorithm, got, err, tt.want)
+		}
+	}
+}
+
+func TestTOTPVerify(t *testing.T) {
+	secret, err := GenerateSecret(0)
+	if err != nil {
+		t.Fatalf("GenerateSecret() error = %v", err)
+	}
+	totp := &TOTP{}
+	now := time.Unix(1_700_000_000, 0)
+
+	code, _ := totp.Generate(secret, now.Add(-DefaultPeriod))
+	step, err := totp.Verify(strings.ToLower(secret), code, 0, now)
+	if err != nil || step != totp.Step(now)-1 {
+		t.Fatalf("Expected the previous step to be accepted, got %d, %v", step, err)
+	}
+	if _, err = totp.Verify(secret, code, step, now); err != ErrCodeReused {
+		t.Errorf("Expected a replayed code to be rejected, got %v", err)
+	}
+
+	late, _ := totp.Generate(secret, now.Add(-2*DefaultPeriod))
+	if _, err = totp.Verify(secret, late, 0, now); err != ErrInvalidCode {
+		t.Errorf("Expected a code outside the skew to be rejected, got %v", err)
+	}
+	strict := &TOTP{Skew: -1}
+	if _, err = strict.Verify(secret, code, 0, now); err != ErrInvalidCode {
+		t.Errorf("Expected a zero skew to only accept the current step, got %v", err)
+	}
+	if _, err = totp.Verify(secret, "12345", 0, now); err != ErrInvalidCode {
+		t.Errorf("Expected a short code to be rejected, got %v", err)
+	}
+	if _, err = GenerateSecret(8); err == nil {
+		t.Error("Expected a short secret to be rejected")
+	}
+}
+
+func TestVerifyHOTP(t *testing.T) {
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+	next, err := VerifyHOTP(secret, "969429", 1, 3, 0, "")
+	if err != nil || next != 4 {
+		t.Fatalf("Expected counter 3 to match within the lookahead, got %d, %v", next, err)
+	}
+	if _, err = VerifyHOTP(secret, "969429", next, 3, 0, ""); err != ErrInvalidCode {
+		t.Errorf("Expected a used counter to be rejected, got %v", err)
+	}
+}
+
+func TestURI(t *testing.T) {
+	totp := &TOTP{Issuer: "Acme Corp"}
+	uri, err := url.Parse(totp.URI("jane@example.com", "JBSWY3DPEHPK3PXP"))
+	if err != nil {
+		t.Fatalf("Failed to parse URI: %v", err)
+	}
+	if uri.Scheme != "otpauth" || uri.Host != "totp" || uri.Path != "/Acme Corp:jane@example.com" {
+		t.Errorf("Unexpected URI %s", uri)
+	}
+	query := uri.Query()
+	if query.Get("secret") != "JBSWY3DPEHPK3PXP" || query.Get("issuer") != "Acme Corp" || query.Get("digits") != "6" || query.Get("period") != "30" || query.Get("algorithm") != "SHA1" {
+		t.Errorf("Unexpected URI parameters %v", query)
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, hashes, err := GenerateRecoveryCodes(0)
+	if err != nil {
+		t.Fatalf("GenerateRecoveryCodes() error = %v", err)
+	}
+	if len(codes) != DefaultRecoveryCodeCount || len(hashes) != len(codes) {
+		t.Fatalf("Expected %d codes, got %d", DefaultRecoveryCodeCount, len(codes))
+	}
+	if len(codes[0]) != RecoveryCodeLength+len(RecoveryCodeSeparator) || codes[0] == codes[1] {
+		t.Errorf("Unexpected codes %v", codes[:2])
+	}
+	if strings.Contains(strings.Join(hashes, ""), strings.ReplaceAll(codes[3], RecoveryCodeSeparator, "")) {
+		t.Error("Expected the hashes to not contain the codes")
+	}
+
+	index, err := VerifyRecoveryCode(" "+strings.ToUpper(codes[3]), hashes)
+	if err != nil || index != 3 {
+		t.Errorf("Expected the fourth code to match, got %d, %v", index, err)
+	}
+	if _, err = VerifyRecoveryCode("aaaaa-aaaaa", hashes); err != ErrInvalidCode {
+		t.Errorf("Expected an unknown code to be rejected, got %v", err)
+	}
+}
+
+type testBaseRoute struct{}
+
+type verifyInput struct {
+	Code string `json:"code" validate:"required"`
+}
+
+type verifyOutput struct {
+	Token string `json:"token"`
+}
+
+func TestStepUp(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	key, _ := helpers.GenerateSymmetricKey(helpers.AESKeySize32)
+	manager, err := core.NewSessionManagerBuilder().WithKey("k1", key).Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	defer manager.Close()
+
+	secret, _ := GenerateSecret(0)
+	totp := &TOTP{}
+	var lastStep int64
+
+	router := gin.New()
+	ctor := core.NewRouteConstructor(router, testBaseRoute{}, manager, nil)
+	core.GET(ctor, "/secret", Require(core.AuthenticatedJSONAPI()), func(_ *struct{}, _ *core.Handler[testBaseRoute]) (*struct{}, *errors.AppError) {
+		return &struct{}{}, nil
+	})
+	core.POST(ctor, "/mfa/totp", core.AuthenticatedJSONAPI().WithoutCsrf(), func(input *verifyInput, data *core.Handler[testBaseRoute]) (*verifyOutput, *errors.AppError) {
+		token, step, appErr := VerifyTOTP(data.Context, data.SessionManager, data.Claims, data.SessionHeader, totp, secret, input.Code, lastStep)
+		if appErr != nil {
+			return nil, appErr
+		}
+		lastStep = step
+		return &verifyOutput{Token: token}, nil
+	})
+
+	issueCtx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	issueCtx.Request = httptest.NewRequest(http.MethodPost, "/login", nil)
+	token, err := core.IssueBearerToken(issueCtx, manager, "default", &core.SessionClaims{Claims: map[string]string{"subject": "user-1"}})
+	if err != nil {
+		t.Fatalf("IssueBearerToken() error = %v", err)
+	}
+
+	call := func(method string, path string, token string, body string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(method, path, strings.NewReader(body))
+		request.Header.Set(core.DefaultSessionAuthorizationHeaderName, token)
+		request.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	if recorder := call(http.MethodGet, "/secret", token, ""); recorder.Code != http.StatusUnauthorized || !strings.Contains(recorder.Body.String(), core.StepUpRequiredError) {
+		t.Fatalf("Expected a step-up error, got %d %s", recorder.Code, recorder.Body.String())
+	}
+	if recorder := call(http.MethodPost, "/mfa/totp", token, `{"code":"000000"}`); recorder.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected a wrong code to be rejected, got %d %s", recorder.Code, recorder.Body.String())
+	}
+
+	code, _ := totp.Generate(secret, time.Now())
+	recorder := call(http.MethodPost, "/mfa/totp", token, `{"code":"`+code+`"}`)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected the code to be accepted, got %d %s", recorder.Code, recorder.Body.String())
+	}
+	var output verifyOutput
+	_ = json.Unmarshal(recorder.Body.Bytes(), &output)
+
+	if recorder = call(http.MethodGet, "/secret", output.Token, ""); recorder.Code != http.StatusOK {
+		t.Errorf("Expected the elevated session to pass, got %d %s", recorder.Code, recorder.Body.String())
+	}
+	if recorder = call(http.MethodPost, "/mfa/totp", token, `{"code":"`+code+`"}`); recorder.Code != http.StatusUnauthorized {
+		t.Errorf("Expected the code to not be accepted twice, got %d", recorder.Code)
+	}
+}
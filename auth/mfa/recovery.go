@@ -0,0 +1,69 @@
+package mfa
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+const (
+	DefaultRecoveryCodeCount = 10
+	RecoveryCodeLength       = 10  // Characters, 50 bits of entropy
+	RecoveryCodeSeparator    = "-" // Recovery codes are shown as xxxxx-xxxxx
+)
+
+var recoveryEncoding = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+// GenerateRecoveryCodes returns count (Default: DefaultRecoveryCodeCount) single-use recovery codes to show the
+// subject once, and their hashes to store instead of the codes.
+func GenerateRecoveryCodes(count int) ([]string, []string, error) {
+	if count <= 0 {
+		count = DefaultRecoveryCodeCount
+	}
+
+	codes, hashes := make([]string, count), make([]string, count)
+	random := make([]byte, (RecoveryCodeLength*5+7)/8)
+	for i := range codes {
+		if _, err := rand.Read(random); err != nil {
+			return nil, nil, fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+		code := recoveryEncoding.EncodeToString(random)[:RecoveryCodeLength]
+		codes[i] = code[:RecoveryCodeLength/2] + RecoveryCodeSeparator + code[RecoveryCodeLength/2:]
+		hashes[i] = HashRecoveryCode(code)
+	}
+	return codes, hashes, nil
+}
+
+// HashRecoveryCode returns the stored form of a recovery code. The codes are random, so a plain SHA-256 is enough
+// to keep them from being read out of a database dump.
+func HashRecoveryCode(code string) string {
+	sum := sha256.Sum256([]byte(normalizeRecoveryCode(code)))
+	return hex.EncodeToString(sum[:])
+}
+
+// VerifyRecoveryCode checks code against the stored hashes and returns the index of the matching hash, remove it
+// so the code can not be used again. ErrInvalidCode is returned when no hash matches.
+func VerifyRecoveryCode(code string, hashes []string) (int, error) {
+	hashed := []byte(HashRecoveryCode(code))
+
+	// - Compare against every hash, the time taken does not reveal which one matched
+	match := -1
+	for i, stored := range hashes {
+		if subtle.ConstantTimeCompare(hashed, []byte(stored)) == 1 && match < 0 {
+			match = i
+		}
+	}
+	if match < 0 {
+		return -1, ErrInvalidCode
+	}
+	return match, nil
+}
+
+// normalizeRecoveryCode accepts codes typed with any case, spaces or separators.
+func normalizeRecoveryCode(code string) string {
+	return strings.NewReplacer(RecoveryCodeSeparator, "", " ", "").Replace(strings.ToLower(code))
+}
@@ -0,0 +1,103 @@
+package mfa
+
+import (
+	stderrors "errors"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/core"
+	"github.com/grzegorzmaniak/gothic/errors"
+)
+
+const (
+	MethodClaim = "amr" // The second factor the session was verified with, see the Method* values
+
+	MethodTOTP     = "otp"
+	MethodRecovery = "recovery"
+)
+
+// Require makes a route require a session verified with a second factor, sessions without one get a 401 with
+// the core.StepUpRequiredError detail.
+func Require(config *core.APIConfiguration) *core.APIConfiguration {
+	return config.WithMinimumAuthLevel(core.AuthLevelMultiFactor)
+}
+
+// VerifyTOTP verifies a TOTP code of the session's subject and raises the session to core.AuthLevelMultiFactor
+// (see core.ElevateSession). lastStep and the returned step work like in TOTP.Verify, store the step so the code
+// can not be replayed. For bearer sessions the new token is returned. Errors are ready to return from a handler,
+// wrong or reused codes are a 401.
+func VerifyTOTP(
+	ctx *gin.Context,
+	sessionManager core.SessionManager,
+	claims *core.SessionClaims,
+	header *core.SessionHeader,
+	totp *TOTP,
+	secret string,
+	code string,
+	lastStep int64,
+) (string, int64, *errors.AppError) {
+	step, err := totp.Verify(secret, code, lastStep, time.Now())
+	if err != nil {
+		return "", 0, codeError(err)
+	}
+
+	token, appErr := elevate(ctx, sessionManager, claims, header, MethodTOTP)
+	if appErr != nil {
+		return "", 0, appErr
+	}
+	return token, step, nil
+}
+
+// VerifyRecovery verifies a recovery code against the subject's stored hashes and raises the session like
+// VerifyTOTP. The index of the used hash is returned, remove it from the stored hashes.
+func VerifyRecovery(
+	ctx *gin.Context,
+	sessionManager core.SessionManager,
+	claims *core.SessionClaims,
+	header *core.SessionHeader,
+	code string,
+	hashes []string,
+) (string, int, *errors.AppError) {
+	index, err := VerifyRecoveryCode(code, hashes)
+	if err != nil {
+		return "", -1, codeError(err)
+	}
+
+	token, appErr := elevate(ctx, sessionManager, claims, header, MethodRecovery)
+	if appErr != nil {
+		return "", -1, appErr
+	}
+	return token, index, nil
+}
+
+func elevate(ctx *gin.Context, sessionManager core.SessionManager, claims *core.SessionClaims, header *core.SessionHeader, method string) (string, *errors.AppError) {
+	if claims == nil || !claims.HasSession {
+		return "", errors.NewUnauthorized("A session is required to verify a second factor", nil)
+	}
+
+	// - ElevateSession copies the claims, set the method on a copy so a failure leaves them untouched
+	verified := &core.SessionClaims{Claims: make(map[string]string, len(claims.Claims)+1), HasSession: true}
+	for name, value := range claims.Claims {
+		verified.Claims[name] = value
+	}
+	verified.SetClaim(MethodClaim, method)
+
+	token, err := core.ElevateSession(ctx, sessionManager, verified, header, core.AuthLevelMultiFactor)
+	if err != nil {
+		var appErr *errors.AppError
+		if stderrors.As(err, &appErr) {
+			return "", appErr
+		}
+		return "", errors.NewInternalServerError("Failed to elevate the session", err)
+	}
+	*claims = *verified
+	return token, nil
+}
+
+// codeError turns a verification failure into the AppError returned to the client.
+func codeError(err error) *errors.AppError {
+	if stderrors.Is(err, ErrInvalidCode) || stderrors.Is(err, ErrCodeReused) {
+		return errors.NewUnauthorized("Invalid verification code", err)
+	}
+	return errors.NewInternalServerError("Failed to verify the code", err)
+}
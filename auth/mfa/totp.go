@@ -0,0 +1,248 @@
+package mfa
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultSecretSize = 20 // Bytes, the size of an HMAC-SHA1 block as recommended by RFC 4226
+	DefaultDigits     = 6
+	DefaultPeriod     = 30 * time.Second
+	DefaultSkew       = 1 // Steps accepted before and after the current one, for clock drift
+
+	MinimumSecretSize = 16
+	MinimumDigits     = 6
+	MaximumDigits     = 8
+)
+
+// Algorithm is the HMAC hash of the one-time passwords, most authenticator apps only support AlgorithmSHA1.
+type Algorithm string
+
+const (
+	AlgorithmSHA1   Algorithm = "SHA1" // Default
+	AlgorithmSHA256 Algorithm = "SHA256"
+	AlgorithmSHA512 Algorithm = "SHA512"
+)
+
+var (
+	// ErrInvalidCode is returned when a code does not match.
+	ErrInvalidCode = errors.New("invalid one-time code")
+
+	// ErrCodeReused is returned when a TOTP code of an already used time step is presented again.
+	ErrCodeReused = errors.New("one-time code was already used")
+)
+
+var secretEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateSecret returns a random base32 secret of size bytes (Default: DefaultSecretSize), as shown to the
+// subject and stored (encrypted, e.g., with core.SealClaims) by the application.
+func GenerateSecret(size int) (string, error) {
+	if size == 0 {
+		size = DefaultSecretSize
+	}
+	if size < MinimumSecretSize {
+		return "", fmt.Errorf("secret size must be at least %d bytes, got %d", MinimumSecretSize, size)
+	}
+
+	secret := make([]byte, size)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("failed to generate secret: %w", err)
+	}
+	return secretEncoding.EncodeToString(secret), nil
+}
+
+// decodeSecret decodes a base32 secret, ignoring case, spaces and padding as typed by users.
+func decodeSecret(secret string) ([]byte, error) {
+	normalized := strings.ToUpper(strings.TrimRight(strings.ReplaceAll(secret, " ", ""), "="))
+	key, err := secretEncoding.DecodeString(normalized)
+	if err != nil {
+		return nil, fmt.Errorf("invalid secret: %w", err)
+	}
+	return key, nil
+}
+
+// TOTP generates and verifies RFC 6238 time-based one-time passwords, the zero value uses the defaults every
+// authenticator app supports.
+type TOTP struct {
+	// Issuer is shown by authenticator apps next to the account, e.g., the product name.
+	Issuer string
+
+	// Digits of the codes, 6 to 8 (Default: DefaultDigits)
+	Digits int
+
+	// Period is how long a code is valid, in whole seconds (Default: DefaultPeriod)
+	Period time.Duration
+
+	// Skew is how many periods before and after the current one are accepted, zero only accepts the current one
+	// (Default: DefaultSkew, set a negative value for zero)
+	Skew int
+
+	// Algorithm is the HMAC hash (Default: AlgorithmSHA1)
+	Algorithm Algorithm
+}
+
+func (t *TOTP) digits() int {
+	if t.Digits == 0 {
+		return DefaultDigits
+	}
+	return t.Digits
+}
+
+func (t *TOTP) period() time.Duration {
+	if t.Period < time.Second {
+		return DefaultPeriod
+	}
+	return t.Period
+}
+
+func (t *TOTP) skew() int {
+	switch {
+	case t.Skew < 0:
+		return 0
+	case t.Skew == 0:
+		return DefaultSkew
+	}
+	return t.Skew
+}
+
+func (t *TOTP) algorithm() Algorithm {
+	if t.Algorithm == "" {
+		return AlgorithmSHA1
+	}
+	return t.Algorithm
+}
+
+// Step returns the time step of at, the counter the code is derived from.
+func (t *TOTP) Step(at time.Time) int64 {
+	return at.Unix() / int64(t.period()/time.Second)
+}
+
+// URI returns the otpauth:// URI authenticator apps enroll from, usually shown as a QR code.
+func (t *TOTP) URI(account string, secret string) string {
+	label := url.PathEscape(account)
+	query := url.Values{}
+	query.Set("secret", secret)
+	if t.Issuer != "" {
+		label = url.PathEscape(t.Issuer) + ":" + label
+		query.Set("issuer", t.Issuer)
+	}
+	query.Set("algorithm", string(t.algorithm()))
+	query.Set("digits", strconv.Itoa(t.digits()))
+	query.Set("period", strconv.Itoa(int(t.period()/time.Second)))
+	return "otpauth://totp/" + label + "?" + query.Encode()
+}
+
+// Generate returns the code of the secret at the given time.
+func (t *TOTP) Generate(secret string, at time.Time) (string, error) {
+	key, err := decodeSecret(secret)
+	if err != nil {
+		return "", err
+	}
+	return HOTP(key, uint64(t.Step(at)), t.digits(), t.algorithm())
+}
+
+// Verify checks code against the steps within Skew of at. lastStep is the step of the subject's last accepted
+// code (0 if none), codes of that step or earlier are rejected with ErrCodeReused so an observed code can not be
+// replayed. The matched step is returned, store it as the next lastStep.
+func (t *TOTP) Verify(secret string, code string, lastStep int64, at time.Time) (int64, error) {
+	key, err := decodeSecret(secret)
+	if err != nil {
+		return 0, err
+	}
+	code = strings.ReplaceAll(code, " ", "")
+	if len(code) != t.digits() {
+		return 0, ErrInvalidCode
+	}
+
+	current := t.Step(at)
+	skew := int64(t.skew())
+	for step := current - skew; step <= current+skew; step++ {
+		expected, err := HOTP(key, uint64(step), t.digits(), t.algorithm())
+		if err != nil {
+			return 0, err
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			if step <= lastStep {
+				return 0, ErrCodeReused
+			}
+			return step, nil
+		}
+	}
+	return 0, ErrInvalidCode
+}
+
+// VerifyHOTP checks an RFC 4226 counter based code against counter and the next lookahead counters, e.g., for
+// hardware tokens. The counter to store for the next verification is returned.
+func VerifyHOTP(secret string, code string, counter uint64, lookahead int, digits int, algorithm Algorithm) (uint64, error) {
+	key, err := decodeSecret(secret)
+	if err != nil {
+		return counter, err
+	}
+	if digits == 0 {
+		digits = DefaultDigits
+	}
+	if algorithm == "" {
+		algorithm = AlgorithmSHA1
+	}
+
+	code = strings.ReplaceAll(code, " ", "")
+	for next := counter; next <= counter+uint64(max(lookahead, 0)); next++ {
+		expected, err := HOTP(key, next, digits, algorithm)
+		if err != nil {
+			return counter, err
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return next + 1, nil
+		}
+	}
+	return counter, ErrInvalidCode
+}
+
+// HOTP returns the RFC 4226 code of key at counter.
+func HOTP(key []byte, counter uint64, digits int, algorithm Algorithm) (string, error) {
+	if digits < MinimumDigits || digits > MaximumDigits {
+		return "", fmt.Errorf("digits must be between %d and %d, got %d", MinimumDigits, MaximumDigits, digits)
+	}
+
+	var newHash func() hash.Hash
+	switch algorithm {
+	case AlgorithmSHA1:
+		newHash = sha1.New
+	case AlgorithmSHA256:
+		newHash = sha256.New
+	case AlgorithmSHA512:
+		newHash = sha512.New
+	default:
+		return "", fmt.Errorf("unsupported algorithm '%s'", algorithm)
+	}
+
+	var message [8]byte
+	binary.BigEndian.PutUint64(message[:], counter)
+	mac := hmac.New(newHash, key)
+	mac.Write(message[:])
+	sum := mac.Sum(nil)
+
+	// - Dynamic truncation, RFC 4226 section 5.3
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	modulo := uint32(1)
+	for range digits {
+		modulo *= 10
+	}
+	return fmt.Sprintf("%0*d", digits, value%modulo), nil
+}
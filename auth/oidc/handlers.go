@@ -0,0 +1,272 @@
+package oidc
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/core"
+	"github.com/grzegorzmaniak/gothic/errors"
+	"github.com/grzegorzmaniak/gothic/helpers"
+	"go.uber.org/zap"
+)
+
+const (
+	FlowVersion   = "OF1" // Version of the flow cookie format
+	FlowDelimiter = "."   // Separates the key id and the encrypted flow
+	stateSize     = 32
+	nonceSize     = 32
+	verifierSize  = 64 // PKCE verifiers are 43 to 128 characters
+)
+
+// flowState carries the login across the redirect to the provider, it is encrypted with the session key.
+type flowState struct {
+	State     string `json:"state"`
+	Nonce     string `json:"nonce"`
+	Verifier  string `json:"verifier"`
+	ReturnTo  string `json:"returnTo,omitempty"`
+	ExpiresAt int64  `json:"expiresAt"`
+}
+
+// DefaultClaimsMapper copies sub, iss, email and name into the session claims.
+func DefaultClaimsMapper(_ context.Context, token *IDToken) (*core.SessionClaims, error) {
+	claims := &core.SessionClaims{Claims: map[string]string{}}
+	for name, value := range map[string]string{
+		"sub":   token.Subject,
+		"iss":   token.Issuer,
+		"email": token.Email,
+		"name":  token.Name,
+	} {
+		if value != "" {
+			claims.SetClaim(name, value)
+		}
+	}
+	return claims, nil
+}
+
+func codeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// safeReturnPath only allows local paths, so the login can not be turned into an open redirect. Browsers strip
+// tabs and newlines and read backslashes as slashes, e.g., "/\t/evil.example" is followed to //evil.example, so
+// control characters and backslashes are rejected anywhere in the path.
+func safeReturnPath(path string) bool {
+	if strings.ContainsFunc(path, func(r rune) bool { return r < 0x20 || r == 0x7f || r == '\\' }) {
+		return false
+	}
+	parsed, err := url.Parse(path)
+	if err != nil || parsed.Scheme != "" || parsed.Host != "" || parsed.Opaque != "" {
+		return false
+	}
+	return strings.HasPrefix(path, "/") && !strings.HasPrefix(path, "//")
+}
+
+func newFlowState(returnTo string, ttl time.Duration) (*flowState, error) {
+	state, err := helpers.GenerateID(stateSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate state: %w", err)
+	}
+	nonce, err := helpers.GenerateID(nonceSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	verifier, err := helpers.GenerateID(verifierSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate code verifier: %w", err)
+	}
+
+	if !safeReturnPath(returnTo) {
+		returnTo = ""
+	}
+	return &flowState{
+		State:     state,
+		Nonce:     nonce,
+		Verifier:  verifier,
+		ReturnTo:  returnTo,
+		ExpiresAt: time.Now().Add(ttl).Unix(),
+	}, nil
+}
+
+func (p *Provider) flowTTL() time.Duration {
+	return helpers.DefaultTimeDuration(p.config.FlowTTL, DefaultFlowTTL)
+}
+
+// setFlowCookie stores the flow, SameSite=Lax so the cookie survives the top level redirect back from the provider.
+func (p *Provider) setFlowCookie(ctx *gin.Context, value string, maxAge int) {
+	authorizationData := p.config.SessionManager.GetAuthorizationConfiguration()
+	if authorizationData == nil {
+		authorizationData = &core.SessionAuthorizationConfiguration{}
+	}
+
+	// - Set directly, gin's SetSameSite would leak Lax into the session cookie set by the callback
+	http.SetCookie(ctx.Writer, &http.Cookie{
+		Name:     helpers.DefaultString(p.config.FlowCookieName, DefaultFlowCookieName),
+		Value:    value,
+		MaxAge:   maxAge,
+		Path:     helpers.DefaultString(authorizationData.CookiePath, core.DefaultSessionAuthorizationPath),
+		Domain:   helpers.DefaultString(authorizationData.CookieDomain, core.DefaultSessionAuthorizationDomain),
+		Secure:   helpers.DefaultBool(authorizationData.CookieSecure, core.DefaultSessionAuthorizationSecure),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func (p *Provider) encodeFlow(flow *flowState) (string, error) {
+	marshaledFlow, err := json.Marshal(flow)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal flow: %w", err)
+	}
+
+	sessionKey, keyId, err := p.config.SessionManager.GetSessionKey()
+	if err != nil {
+		return "", fmt.Errorf("failed to get session key: %w", err)
+	}
+
+	encryptedFlow, err := helpers.SymmetricEncrypt(sessionKey, marshaledFlow, []byte(keyId+FlowVersion))
+	if err != nil {
+		return "", fmt.Errorf("failed to encrypt flow: %w", err)
+	}
+	return keyId + FlowDelimiter + base64.RawURLEncoding.EncodeToString(encryptedFlow), nil
+}
+
+func (p *Provider) decodeFlow(value string) (*flowState, error) {
+	keyId, encodedFlow, found := strings.Cut(value, FlowDelimiter)
+	if !found || keyId == "" {
+		return nil, fmt.Errorf("invalid flow cookie format")
+	}
+
+	sessionKey, err := p.config.SessionManager.GetOldSessionKey(keyId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session key: %w", err)
+	}
+
+	encryptedFlow, err := base64.RawURLEncoding.DecodeString(encodedFlow)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode flow cookie: %w", err)
+	}
+
+	marshaledFlow, err := helpers.SymmetricDecrypt(sessionKey, encryptedFlow, []byte(keyId+FlowVersion))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt flow cookie: %w", err)
+	}
+
+	var flow flowState
+	if err = json.Unmarshal(marshaledFlow, &flow); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal flow: %w", err)
+	}
+	if flow.ExpiresAt < time.Now().Unix() {
+		return nil, fmt.Errorf("login flow has expired")
+	}
+	return &flow, nil
+}
+
+// LoginHandler starts the authorization code flow: it stores a fresh state, nonce and PKCE verifier in the
+// encrypted flow cookie and redirects to the provider. A local path in the return_to query parameter is where
+// the callback redirects to.
+func (p *Provider) LoginHandler() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		flow, err := newFlowState(ctx.Query(ReturnToQueryParameter), p.flowTTL())
+		if err != nil {
+			helpers.ErrorResponse(ctx, errors.NewInternalServerError("Failed to start login", err))
+			return
+		}
+
+		cookieValue, err := p.encodeFlow(flow)
+		if err != nil {
+			helpers.ErrorResponse(ctx, errors.NewInternalServerError("Failed to start login", err))
+			return
+		}
+
+		target, err := p.authorizationURL(flow)
+		if err != nil {
+			helpers.ErrorResponse(ctx, errors.NewInternalServerError("Failed to start login", err))
+			return
+		}
+
+		p.setFlowCookie(ctx, cookieValue, int(p.flowTTL().Seconds()))
+		ctx.Header("Cache-Control", "no-store")
+		ctx.Redirect(http.StatusFound, target)
+	}
+}
+
+// callback completes the flow and returns the verified ID token.
+func (p *Provider) callback(ctx *gin.Context) (*flowState, *IDToken, *errors.AppError) {
+	cookieValue, err := ctx.Cookie(helpers.DefaultString(p.config.FlowCookieName, DefaultFlowCookieName))
+	if err != nil || cookieValue == "" {
+		return nil, nil, errors.NewBadRequest("Login flow not found", err)
+	}
+
+	// - The flow is single use, whatever the outcome
+	p.setFlowCookie(ctx, "", -1)
+
+	flow, err := p.decodeFlow(cookieValue)
+	if err != nil {
+		return nil, nil, errors.NewBadRequest("Login flow is invalid", err)
+	}
+
+	if subtle.ConstantTimeCompare([]byte(ctx.Query("state")), []byte(flow.State)) != 1 {
+		return nil, nil, errors.NewBadRequest("Login state does not match", nil)
+	}
+
+	if providerError := ctx.Query("error"); providerError != "" {
+		return nil, nil, errors.NewUnauthorized("Login was not completed", fmt.Errorf("provider returned '%s': %s", providerError, ctx.Query("error_description")))
+	}
+
+	code := ctx.Query("code")
+	if code == "" {
+		return nil, nil, errors.NewBadRequest("Authorization code is missing", nil)
+	}
+
+	tokens, err := p.exchange(ctx, code, flow.Verifier)
+	if err != nil {
+		return nil, nil, errors.NewUnauthorized("Failed to exchange authorization code", err)
+	}
+
+	idToken, err := p.verifyIDToken(ctx, tokens.IDToken, flow.Nonce)
+	if err != nil {
+		return nil, nil, errors.NewUnauthorized("ID token is invalid", err)
+	}
+	return flow, idToken, nil
+}
+
+// CallbackHandler completes the flow: it checks the state, exchanges the code with the PKCE verifier, verifies
+// the ID token and its nonce, maps it with Config.MapClaims and issues the session cookie with SetSessionCookie.
+func (p *Provider) CallbackHandler() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Header("Cache-Control", "no-store")
+
+		flow, idToken, appErr := p.callback(ctx)
+		if appErr != nil {
+			helpers.Logger(ctx).Debug("OIDC callback failed", zap.Error(appErr))
+			helpers.ErrorResponse(ctx, appErr)
+			return
+		}
+
+		mapClaims := p.config.MapClaims
+		if mapClaims == nil {
+			mapClaims = DefaultClaimsMapper
+		}
+		claims, err := mapClaims(ctx, idToken)
+		if err != nil || claims == nil {
+			helpers.ErrorResponse(ctx, errors.NewForbidden("Login is not allowed", err))
+			return
+		}
+
+		if err = core.SetSessionCookie(ctx, p.config.SessionManager, p.sessionGroup(), claims); err != nil {
+			helpers.ErrorResponse(ctx, errors.NewInternalServerError("Failed to create session", err))
+			return
+		}
+
+		ctx.Redirect(http.StatusFound, helpers.DefaultString(flow.ReturnTo, helpers.DefaultString(p.config.PostLoginPath, DefaultPostLoginPath)))
+	}
+}
@@ -0,0 +1,376 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MinimumKeyRefreshInterval limits how often an unknown key id triggers a JWKS refresh, so forged tokens can
+// not be used to hammer the provider.
+const MinimumKeyRefreshInterval = 30 * time.Second
+
+// IDToken is a verified ID token.
+type IDToken struct {
+	Issuer        string
+	Subject       string
+	Audience      []string
+	ExpiresAt     time.Time
+	IssuedAt      time.Time
+	Nonce         string
+	Email         string
+	EmailVerified bool
+	Name          string
+
+	// Claims holds every claim of the token, e.g., for provider specific groups or roles.
+	Claims map[string]interface{}
+
+	// Raw is the encoded token, e.g., for the id_token_hint of RP-initiated logout.
+	Raw string
+}
+
+// audience accepts both forms of the aud claim.
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+	var multiple []string
+	if err := json.Unmarshal(data, &multiple); err != nil {
+		return fmt.Errorf("aud is neither a string nor an array of strings")
+	}
+	*a = multiple
+	return nil
+}
+
+type idTokenClaims struct {
+	Issuer          string   `json:"iss"`
+	Subject         string   `json:"sub"`
+	Audience        audience `json:"aud"`
+	AuthorizedParty string   `json:"azp"`
+	ExpiresAt       int64    `json:"exp"`
+	IssuedAt        int64    `json:"iat"`
+	Nonce           string   `json:"nonce"`
+	Email           string   `json:"email"`
+	EmailVerified   bool     `json:"email_verified"`
+	Name            string   `json:"name"`
+}
+
+type joseHeader struct {
+	Algorithm string `json:"alg"`
+	KeyId     string `json:"kid"`
+}
+
+type jsonWebKey struct {
+	KeyType string `json:"kty"`
+	KeyId   string `json:"kid"`
+	Use     string `json:"use"`
+	N       string `json:"n"`
+	E       string `json:"e"`
+	Curve   string `json:"crv"`
+	X       string `json:"x"`
+	Y       string `json:"y"`
+}
+
+// keySet caches the signing keys of the provider, refreshing them when a token names an unknown key id.
+type keySet struct {
+	mu          sync.RWMutex
+	client      *http.Client
+	uri         string
+	keys        map[string]crypto.PublicKey
+	refreshedAt time.Time
+}
+
+func newKeySet(client *http.Client, uri string) *keySet {
+	return &keySet{client: client, uri: uri, keys: make(map[string]crypto.PublicKey)}
+}
+
+func (s *keySet) lookup(keyId string) (crypto.PublicKey, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// - Providers with a single key may leave kid out
+	if keyId == "" && len(s.keys) == 1 {
+		for _, key := range s.keys {
+			return key, true
+		}
+	}
+	key, ok := s.keys[keyId]
+	return key, ok
+}
+
+func (s *keySet) get(ctx context.Context, keyId string) (crypto.PublicKey, error) {
+	if key, ok := s.lookup(keyId); ok {
+		return key, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if key, ok := s.keys[keyId]; ok {
+		return key, nil
+	}
+	if time.Since(s.refreshedAt) < MinimumKeyRefreshInterval {
+		return nil, fmt.Errorf("unknown key id '%s'", keyId)
+	}
+	s.refreshedAt = time.Now()
+
+	var document struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := getJSON(ctx, s.client, s.uri, &document); err != nil {
+		return nil, fmt.Errorf("failed to fetch jwks: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(document.Keys))
+	for _, jwk := range document.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		if key, err := jwk.publicKey(); err == nil {
+			keys[jwk.KeyId] = key
+		}
+	}
+	s.keys = keys
+
+	if keyId == "" && len(keys) == 1 {
+		for _, key := range keys {
+			return key, nil
+		}
+	}
+	key, ok := keys[keyId]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id '%s'", keyId)
+	}
+	return key, nil
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(decoded), nil
+}
+
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.KeyType {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil || !e.IsInt64() {
+			return nil, fmt.Errorf("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Curve {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve '%s'", k.Curve)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, fmt.Errorf("point is not on curve '%s'", k.Curve)
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+
+	case "OKP":
+		if k.Curve != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve '%s'", k.Curve)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+
+	default:
+		return nil, fmt.Errorf("unsupported key type '%s'", k.KeyType)
+	}
+}
+
+func digest(hash crypto.Hash, message []byte) []byte {
+	switch hash {
+	case crypto.SHA384:
+		sum := sha512.Sum384(message)
+		return sum[:]
+	case crypto.SHA512:
+		sum := sha512.Sum512(message)
+		return sum[:]
+	default:
+		sum := sha256.Sum256(message)
+		return sum[:]
+	}
+}
+
+// verifySignature checks a JWS signature, the algorithm must match the key type so a token can not pick a
+// weaker check. "none" and the HMAC algorithms are never accepted.
+func verifySignature(algorithm string, key crypto.PublicKey, message []byte, signature []byte) error {
+	hashes := map[string]crypto.Hash{"256": crypto.SHA256, "384": crypto.SHA384, "512": crypto.SHA512}
+
+	switch {
+	case algorithm == "EdDSA":
+		if publicKey, ok := key.(ed25519.PublicKey); ok && ed25519.Verify(publicKey, message, signature) {
+			return nil
+		}
+
+	case strings.HasPrefix(algorithm, "RS"), strings.HasPrefix(algorithm, "PS"):
+		publicKey, ok := key.(*rsa.PublicKey)
+		hash, known := hashes[algorithm[2:]]
+		if !ok || !known {
+			break
+		}
+		if algorithm[0] == 'R' && rsa.VerifyPKCS1v15(publicKey, hash, digest(hash, message), signature) == nil {
+			return nil
+		}
+		if algorithm[0] == 'P' && rsa.VerifyPSS(publicKey, hash, digest(hash, message), signature, nil) == nil {
+			return nil
+		}
+
+	case strings.HasPrefix(algorithm, "ES"):
+		publicKey, ok := key.(*ecdsa.PublicKey)
+		hash, known := hashes[algorithm[2:]]
+		if !ok || !known {
+			break
+		}
+
+		// - JWS signatures are the fixed size r || s, not ASN.1
+		size := (publicKey.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			break
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if ecdsa.Verify(publicKey, digest(hash, message), r, s) {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid %s signature", algorithm)
+}
+
+// verifyIDToken checks the signature, issuer, audience, lifetime and nonce of an ID token.
+func (p *Provider) verifyIDToken(ctx context.Context, raw string, nonce string) (*IDToken, error) {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("id token is not a signed JWT")
+	}
+
+	headerBytes, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode id token header: %w", err)
+	}
+	var header joseHeader
+	if err = json.Unmarshal(headerBytes, &header); err != nil {
+		return nil, fmt.Errorf("failed to parse id token header: %w", err)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode id token signature: %w", err)
+	}
+
+	key, err := p.keys.get(ctx, header.KeyId)
+	if err != nil {
+		return nil, err
+	}
+	if err = verifySignature(header.Algorithm, key, []byte(parts[0]+"."+parts[1]), signature); err != nil {
+		return nil, err
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode id token payload: %w", err)
+	}
+	var claims idTokenClaims
+	if err = json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("failed to parse id token claims: %w", err)
+	}
+	allClaims := map[string]interface{}{}
+	if err = json.Unmarshal(payload, &allClaims); err != nil {
+		return nil, fmt.Errorf("failed to parse id token claims: %w", err)
+	}
+
+	if strings.TrimSuffix(claims.Issuer, "/") != strings.TrimSuffix(p.metadata.Issuer, "/") {
+		return nil, fmt.Errorf("unexpected issuer '%s'", claims.Issuer)
+	}
+	if !containsString(claims.Audience, p.config.ClientID) {
+		return nil, fmt.Errorf("id token is not issued for this client")
+	}
+	if len(claims.Audience) > 1 && claims.AuthorizedParty != p.config.ClientID {
+		return nil, fmt.Errorf("id token is authorized for another party")
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("id token has no subject")
+	}
+
+	skew := p.config.ClockSkew
+	if skew <= 0 {
+		skew = DefaultClockSkew
+	}
+	now := time.Now()
+	if claims.ExpiresAt == 0 || now.After(time.Unix(claims.ExpiresAt, 0).Add(skew)) {
+		return nil, fmt.Errorf("id token is expired")
+	}
+	if claims.IssuedAt != 0 && time.Unix(claims.IssuedAt, 0).After(now.Add(skew)) {
+		return nil, fmt.Errorf("id token is issued in the future")
+	}
+	if subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(nonce)) != 1 {
+		return nil, fmt.Errorf("id token nonce does not match")
+	}
+
+	return &IDToken{
+		Issuer:        claims.Issuer,
+		Subject:       claims.Subject,
+		Audience:      claims.Audience,
+		ExpiresAt:     time.Unix(claims.ExpiresAt, 0),
+		IssuedAt:      time.Unix(claims.IssuedAt, 0),
+		Nonce:         claims.Nonce,
+		Email:         claims.Email,
+		EmailVerified: claims.EmailVerified,
+		Name:          claims.Name,
+		Claims:        allClaims,
+		Raw:           raw,
+	}, nil
+}
+
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
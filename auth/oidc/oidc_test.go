@@ -0,0 +1,329 @@
+package oidc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eko/gocache/lib/v4/cache"
+	"github.com/gin-gonic/gin"
+	internalcache "github.com/grzegorzmaniak/gothic/cache"
+	"github.com/grzegorzmaniak/gothic/core"
+	"github.com/grzegorzmaniak/gothic/helpers"
+)
+
+type testSessionManager struct {
+	core.DefaultSessionManager
+	key          []byte
+	cacheManager *internalcache.DefaultCacheManager
+}
+
+func (m *testSessionManager) GetAuthorizationConfiguration() *core.SessionAuthorizationConfiguration {
+	return &core.SessionAuthorizationConfiguration{}
+}
+func (m *testSessionManager) GetCsrfData() *core.CsrfCookieData { return &core.CsrfCookieData{} }
+func (m *testSessionManager) GetSessionKey() ([]byte, string, error) {
+	return m.key, "key-1", nil
+}
+func (m *testSessionManager) GetOldSessionKey(keyId string) ([]byte, error) {
+	if keyId != "key-1" {
+		return nil, fmt.Errorf("unknown key id '%s'", keyId)
+	}
+	return m.key, nil
+}
+func (m *testSessionManager) VerifySession(context.Context, *core.SessionClaims, *core.SessionHeader) (bool, error) {
+	return true, nil
+}
+func (m *testSessionManager) StoreSession(context.Context, *core.SessionClaims, *core.SessionHeader) error {
+	return nil
+}
+func (m *testSessionManager) GetSubjectIdentifier(claims *core.SessionClaims) (string, error) {
+	subject, _ := claims.GetClaim("sub")
+	return subject, nil
+}
+func (m *testSessionManager) GetCache() (cache.CacheInterface[[]byte], error) {
+	return m.cacheManager.GetCache()
+}
+
+// fakeProvider is a minimal OpenID provider signing ES256 ID tokens.
+type fakeProvider struct {
+	*httptest.Server
+	key      *ecdsa.PrivateKey
+	mu       sync.Mutex
+	codes    map[string]url.Values // code -> authorization request
+	badNonce bool
+}
+
+func newFakeProvider(t *testing.T) *fakeProvider {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	provider := &fakeProvider{key: key, codes: map[string]url.Values{}}
+	mux := http.NewServeMux()
+	provider.Server = httptest.NewServer(mux)
+
+	mux.HandleFunc(DiscoveryPath, func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(Metadata{
+			Issuer:                provider.URL,
+			AuthorizationEndpoint: provider.URL + "/authorize",
+			TokenEndpoint:         provider.URL + "/token",
+			JwksURI:               provider.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		encode := func(value *big.Int) string {
+			return base64.RawURLEncoding.EncodeToString(value.FillBytes(make([]byte, 32)))
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"keys": []jsonWebKey{{
+			KeyType: "EC", KeyId: "idp-1", Use: "sig", Curve: "P-256", X: encode(key.X), Y: encode(key.Y),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		provider.mu.Lock()
+		authorization, ok := provider.codes[r.PostForm.Get("code")]
+		delete(provider.codes, r.PostForm.Get("code"))
+		provider.mu.Unlock()
+
+		if !ok || codeChallenge(r.PostForm.Get("code_verifier")) != authorization.Get("code_challenge") {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"invalid_grant"}`))
+			return
+		}
+
+		nonce := authorization.Get("nonce")
+		if provider.badNonce {
+			nonce = "replayed"
+		}
+		_ = json.NewEncoder(w).Encode(tokenResponse{AccessToken: "access", TokenType: "Bearer", IDToken: provider.sign(t, map[string]interface{}{
+			"iss": provider.URL, "sub": "user-1", "aud": "client", "email": "john@example.com",
+			"exp": time.Now().Add(time.Hour).Unix(), "iat": time.Now().Unix(), "nonce": nonce,
+		})})
+	})
+	return provider
+}
+
+func (p *fakeProvider) sign(t *testing.T, claims map[string]interface{}) string {
+	header, _ := json.Marshal(map[string]string{"alg": "ES256", "kid": "idp-1", "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	digest := sha256.Sum256([]byte(signingInput))
+	r, s, err := ecdsa.Sign(rand.Reader, p.key, digest[:])
+	if err != nil {
+		t.Fatalf("Failed to sign id token: %v", err)
+	}
+	signature := append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// authorize plays the user logging in at the provider, returning the callback query.
+func (p *fakeProvider) authorize(t *testing.T, location string) url.Values {
+	t.Helper()
+	target, err := url.Parse(location)
+	if err != nil {
+		t.Fatalf("Invalid authorization redirect: %v", err)
+	}
+	query := target.Query()
+	if query.Get("code_challenge_method") != "S256" || query.Get("nonce") == "" || query.Get("client_id") != "client" {
+		t.Fatalf("Unexpected authorization request %v", query)
+	}
+
+	code, _ := helpers.GenerateID(16)
+	p.mu.Lock()
+	p.codes[code] = query
+	p.mu.Unlock()
+	return url.Values{"code": {code}, "state": {query.Get("state")}}
+}
+
+func TestOIDCFlow(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	idp := newFakeProvider(t)
+	defer idp.Close()
+
+	key, _ := helpers.GenerateSymmetricKey(helpers.AESKeySize32)
+	sessionManager := &testSessionManager{key: key, cacheManager: internalcache.BuildDefaultCacheManager(nil)}
+
+	provider, err := NewProvider(context.Background(), Config{
+		Issuer:         idp.URL,
+		ClientID:       "client",
+		RedirectURL:    "https://app.example.com/callback",
+		SessionManager: sessionManager,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	router := gin.New()
+	router.GET("/login", provider.LoginHandler())
+	router.GET("/callback", provider.CallbackHandler())
+
+	serve := func(target string, cookies []*http.Cookie) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodGet, target, nil)
+		for _, cookie := range cookies {
+			request.AddCookie(cookie)
+		}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	login := func(returnTo string) (url.Values, []*http.Cookie) {
+		t.Helper()
+		recorder := serve("/login?"+url.Values{ReturnToQueryParameter: {returnTo}}.Encode(), nil)
+		if recorder.Code != http.StatusFound {
+			t.Fatalf("Expected a redirect to the provider, got %d", recorder.Code)
+		}
+		return idp.authorize(t, recorder.Header().Get("Location")), recorder.Result().Cookies()
+	}
+
+	t.Run("Successful login issues a session", func(t *testing.T) {
+		query, cookies := login("/dashboard")
+		recorder := serve("/callback?"+query.Encode(), cookies)
+		if recorder.Code != http.StatusFound || recorder.Header().Get("Location") != "/dashboard" {
+			t.Fatalf("Expected a redirect to /dashboard, got %d %q: %s", recorder.Code, recorder.Header().Get("Location"), recorder.Body.String())
+		}
+
+		found := false
+		for _, cookie := range recorder.Result().Cookies() {
+			if cookie.Name == core.DefaultSessionAuthorizationName && cookie.Value != "" {
+				found = true
+			}
+		}
+		if !found {
+			t.Error("Expected a session cookie")
+		}
+	})
+
+	t.Run("Open redirects are ignored", func(t *testing.T) {
+		for _, returnTo := range []string{"//evil.example.com", "/\t/evil.example.com", "/\n/evil.example.com", "/\\evil.example.com", "/a\\b", "https://evil.example.com"} {
+			query, cookies := login(returnTo)
+			if recorder := serve("/callback?"+query.Encode(), cookies); recorder.Header().Get("Location") != DefaultPostLoginPath {
+				t.Errorf("Expected a redirect to %s for %q, got %q", DefaultPostLoginPath, returnTo, recorder.Header().Get("Location"))
+			}
+		}
+		if !safeReturnPath("/reports?tab=1#top") {
+			t.Error("Expected local paths to be allowed")
+		}
+	})
+
+	t.Run("State mismatch is rejected", func(t *testing.T) {
+		query, cookies := login("")
+		query.Set("state", "forged")
+		if recorder := serve("/callback?"+query.Encode(), cookies); recorder.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400, got %d", recorder.Code)
+		}
+	})
+
+	t.Run("Missing flow cookie is rejected", func(t *testing.T) {
+		query, _ := login("")
+		if recorder := serve("/callback?"+query.Encode(), nil); recorder.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400, got %d", recorder.Code)
+		}
+	})
+
+	t.Run("Nonce mismatch is rejected", func(t *testing.T) {
+		idp.badNonce = true
+		defer func() { idp.badNonce = false }()
+
+		query, cookies := login("")
+		if recorder := serve("/callback?"+query.Encode(), cookies); recorder.Code != http.StatusUnauthorized {
+			t.Errorf("Expected 401, got %d", recorder.Code)
+		}
+	})
+}
+
+func TestVerifyIDToken(t *testing.T) {
+	idp := newFakeProvider(t)
+	defer idp.Close()
+
+	key, _ := helpers.GenerateSymmetricKey(helpers.AESKeySize32)
+	provider, err := NewProvider(context.Background(), Config{
+		Issuer: idp.URL, ClientID: "client", RedirectURL: "https://app.example.com/callback",
+		SessionManager: &testSessionManager{key: key, cacheManager: internalcache.BuildDefaultCacheManager(nil)},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	valid := func() map[string]interface{} {
+		return map[string]interface{}{
+			"iss": idp.URL, "sub": "user-1", "aud": []string{"client"}, "nonce": "n",
+			"exp": time.Now().Add(time.Hour).Unix(), "iat": time.Now().Unix(),
+		}
+	}
+
+	if token, err := provider.verifyIDToken(context.Background(), idp.sign(t, valid()), "n"); err != nil || token.Subject != "user-1" {
+		t.Fatalf("Expected a valid token, got %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(claims map[string]interface{})
+	}{
+		{name: "Wrong issuer", modify: func(c map[string]interface{}) { c["iss"] = "https://evil.example.com" }},
+		{name: "Wrong audience", modify: func(c map[string]interface{}) { c["aud"] = "other" }},
+		{name: "Expired", modify: func(c map[string]interface{}) { c["exp"] = time.Now().Add(-time.Hour).Unix() }},
+		{name: "Multiple audiences without azp", modify: func(c map[string]interface{}) { c["aud"] = []string{"client", "other"} }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := valid()
+			tt.modify(claims)
+			if _, err := provider.verifyIDToken(context.Background(), idp.sign(t, claims), "n"); err == nil {
+				t.Error("Expected the token to be rejected")
+			}
+		})
+	}
+
+	t.Run("Unsigned token", func(t *testing.T) {
+		header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","kid":"idp-1"}`))
+		payload, _ := json.Marshal(valid())
+		if _, err := provider.verifyIDToken(context.Background(), header+"."+base64.RawURLEncoding.EncodeToString(payload)+".", "n"); err == nil {
+			t.Error("Expected alg none to be rejected")
+		}
+	})
+}
+
+func TestEndSessionRedirect(t *testing.T) {
+	provider := &Provider{
+		config:   Config{ClientID: "client"},
+		metadata: Metadata{EndSessionEndpoint: "https://idp.example.com/logout"},
+	}
+
+	endSession := provider.EndSessionRedirect("https://app.example.com/")
+	target, err := endSession(nil, &core.SessionClaims{Claims: map[string]string{core.SessionModeClaim: DefaultSessionGroup}})
+	if err != nil {
+		t.Fatalf("Failed to build end session URL: %v", err)
+	}
+	parsed, _ := url.Parse(target)
+	if parsed.Host != "idp.example.com" || parsed.Query().Get("client_id") != "client" ||
+		parsed.Query().Get("post_logout_redirect_uri") != "https://app.example.com/" {
+		t.Errorf("Unexpected end session URL %q", target)
+	}
+
+	// - Sessions from other login methods stay local
+	if target, _ = endSession(nil, &core.SessionClaims{Claims: map[string]string{core.SessionModeClaim: "password"}}); target != "" {
+		t.Errorf("Expected no redirect for another session group, got %q", target)
+	}
+
+	provider.metadata.EndSessionEndpoint = ""
+	if _, err = provider.EndSessionURL("", ""); err == nil {
+		t.Error("Expected an error without an end session endpoint")
+	}
+}
@@ -0,0 +1,276 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/core"
+	"github.com/grzegorzmaniak/gothic/helpers"
+)
+
+const (
+	DiscoveryPath          = "/.well-known/openid-configuration"
+	DefaultSessionGroup    = "oidc"
+	DefaultFlowCookieName  = "oidc_flow"
+	DefaultFlowTTL         = 10 * time.Minute
+	DefaultPostLoginPath   = "/"
+	DefaultClockSkew       = time.Minute
+	DefaultRequestTimeout  = 10 * time.Second
+	MaximumResponseSize    = 1024 * 1024 // Discovery, JWKS and token responses larger than this are rejected
+	ReturnToQueryParameter = "return_to" // Relative path the login handler redirects to after the callback
+)
+
+// DefaultScopes are requested when Config.Scopes is empty.
+var DefaultScopes = []string{"openid", "profile", "email"}
+
+// ClaimsMapper turns a verified ID token into the claims of the GoThic session.
+type ClaimsMapper func(ctx context.Context, token *IDToken) (*core.SessionClaims, error)
+
+// Config configures a Provider, Issuer, ClientID, RedirectURL and SessionManager are required.
+type Config struct {
+	// Issuer is the issuer URL, the discovery document is fetched from Issuer + DiscoveryPath.
+	Issuer string
+
+	ClientID     string
+	ClientSecret string // Optional, public clients rely on PKCE alone
+
+	// RedirectURL is the absolute URL the callback handler is mounted on.
+	RedirectURL string
+
+	// Scopes requested from the provider (Default: DefaultScopes)
+	Scopes []string
+
+	// SessionManager issues the session cookie after a successful callback.
+	SessionManager core.SessionManager
+
+	// SessionGroup is the session mode of the issued sessions (Default: DefaultSessionGroup)
+	SessionGroup string
+
+	// MapClaims maps the ID token into the session claims (Default: DefaultClaimsMapper)
+	MapClaims ClaimsMapper
+
+	// PostLoginPath is where the callback redirects to when the login did not ask for a return path
+	// (Default: DefaultPostLoginPath)
+	PostLoginPath string
+
+	// FlowCookieName is the cookie holding the encrypted state, nonce and PKCE verifier between the login and
+	// the callback (Default: DefaultFlowCookieName)
+	FlowCookieName string
+
+	// FlowTTL is how long a login can take (Default: DefaultFlowTTL)
+	FlowTTL time.Duration
+
+	// ClockSkew is the leeway applied to the ID token exp and iat (Default: DefaultClockSkew)
+	ClockSkew time.Duration
+
+	// HTTPClient is used for discovery, JWKS and token requests (Default: a client with DefaultRequestTimeout)
+	HTTPClient *http.Client
+}
+
+// Metadata is the subset of the discovery document the Provider uses.
+type Metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JwksURI               string `json:"jwks_uri"`
+	EndSessionEndpoint    string `json:"end_session_endpoint,omitempty"`
+}
+
+// Provider runs the authorization code flow against a single OpenID provider.
+type Provider struct {
+	config   Config
+	metadata Metadata
+	client   *http.Client
+	keys     *keySet
+}
+
+// tokenResponse is the token endpoint response.
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	IDToken     string `json:"id_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+// NewProvider validates the configuration and fetches the discovery document of the issuer.
+func NewProvider(ctx context.Context, config Config) (*Provider, error) {
+	switch {
+	case config.Issuer == "":
+		return nil, fmt.Errorf("issuer is required")
+	case config.ClientID == "":
+		return nil, fmt.Errorf("client id is required")
+	case config.RedirectURL == "":
+		return nil, fmt.Errorf("redirect url is required")
+	case config.SessionManager == nil:
+		return nil, fmt.Errorf("session manager is required")
+	}
+
+	client := config.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: DefaultRequestTimeout}
+	}
+
+	issuer := strings.TrimSuffix(config.Issuer, "/")
+	var metadata Metadata
+	if err := getJSON(ctx, client, issuer+DiscoveryPath, &metadata); err != nil {
+		return nil, fmt.Errorf("failed to fetch discovery document: %w", err)
+	}
+
+	// - The issuer must match exactly, or tokens from another tenant of the same host could be accepted
+	if strings.TrimSuffix(metadata.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("discovery issuer '%s' does not match '%s'", metadata.Issuer, config.Issuer)
+	}
+	if metadata.AuthorizationEndpoint == "" || metadata.TokenEndpoint == "" || metadata.JwksURI == "" {
+		return nil, fmt.Errorf("discovery document is missing the authorization, token or jwks endpoint")
+	}
+
+	return &Provider{
+		config:   config,
+		metadata: metadata,
+		client:   client,
+		keys:     newKeySet(client, metadata.JwksURI),
+	}, nil
+}
+
+// Metadata returns the discovery document of the provider.
+func (p *Provider) Metadata() Metadata {
+	return p.metadata
+}
+
+func (p *Provider) scopes() []string {
+	if len(p.config.Scopes) == 0 {
+		return DefaultScopes
+	}
+	return p.config.Scopes
+}
+
+func (p *Provider) sessionGroup() string {
+	return helpers.DefaultString(p.config.SessionGroup, DefaultSessionGroup)
+}
+
+// authorizationURL builds the URL the login handler redirects to.
+func (p *Provider) authorizationURL(flow *flowState) (string, error) {
+	endpoint, err := url.Parse(p.metadata.AuthorizationEndpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid authorization endpoint: %w", err)
+	}
+
+	query := endpoint.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", p.config.ClientID)
+	query.Set("redirect_uri", p.config.RedirectURL)
+	query.Set("scope", strings.Join(p.scopes(), " "))
+	query.Set("state", flow.State)
+	query.Set("nonce", flow.Nonce)
+	query.Set("code_challenge", codeChallenge(flow.Verifier))
+	query.Set("code_challenge_method", "S256")
+	endpoint.RawQuery = query.Encode()
+	return endpoint.String(), nil
+}
+
+// exchange trades the authorization code for the provider's tokens.
+func (p *Provider) exchange(ctx context.Context, code string, verifier string) (*tokenResponse, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.config.RedirectURL},
+		"code_verifier": {verifier},
+		"client_id":     {p.config.ClientID},
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, p.metadata.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build token request: %w", err)
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Accept", "application/json")
+	if p.config.ClientSecret != "" {
+		request.SetBasicAuth(url.QueryEscape(p.config.ClientID), url.QueryEscape(p.config.ClientSecret))
+	}
+
+	var tokens tokenResponse
+	if err = doJSON(p.client, request, &tokens); err != nil {
+		return nil, fmt.Errorf("token request failed: %w", err)
+	}
+	if tokens.IDToken == "" {
+		return nil, fmt.Errorf("token response has no id_token")
+	}
+	return &tokens, nil
+}
+
+func getJSON(ctx context.Context, client *http.Client, target string, value interface{}) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Accept", "application/json")
+	return doJSON(client, request, value)
+}
+
+func doJSON(client *http.Client, request *http.Request, value interface{}) error {
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(response.Body, MaximumResponseSize+1))
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if len(body) > MaximumResponseSize {
+		return fmt.Errorf("response exceeds %d bytes", MaximumResponseSize)
+	}
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d: %s", response.StatusCode, strings.TrimSpace(string(body)))
+	}
+	if err = json.Unmarshal(body, value); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// EndSessionURL builds the RP-initiated logout URL of the provider. The id token hint is optional, the client id
+// identifies the client without it.
+func (p *Provider) EndSessionURL(postLogoutRedirectURI string, idTokenHint string) (string, error) {
+	if p.metadata.EndSessionEndpoint == "" {
+		return "", fmt.Errorf("provider does not support RP-initiated logout")
+	}
+
+	endpoint, err := url.Parse(p.metadata.EndSessionEndpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid end session endpoint: %w", err)
+	}
+
+	query := endpoint.Query()
+	query.Set("client_id", p.config.ClientID)
+	if idTokenHint != "" {
+		query.Set("id_token_hint", idTokenHint)
+	}
+	if postLogoutRedirectURI != "" {
+		query.Set("post_logout_redirect_uri", postLogoutRedirectURI)
+	}
+	endpoint.RawQuery = query.Encode()
+	return endpoint.String(), nil
+}
+
+// EndSessionRedirect adapts EndSessionURL for core.LogoutConfiguration.EndSession, only sessions issued by this
+// provider (its SessionGroup) are sent to it.
+func (p *Provider) EndSessionRedirect(postLogoutRedirectURI string) core.EndSessionFunc {
+	return func(_ *gin.Context, claims *core.SessionClaims) (string, error) {
+		if claims == nil {
+			return "", nil
+		}
+		if group, _ := claims.GetClaim(core.SessionModeClaim); group != p.sessionGroup() {
+			return "", nil
+		}
+		return p.EndSessionURL(postLogoutRedirectURI, "")
+	}
+}
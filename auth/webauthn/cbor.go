@@ -0,0 +1,176 @@
+package webauthn
+
+import (
+	"fmt"
+	"math"
+)
+
+const (
+	cborMaxDepth = 16 // Attestation objects and COSE keys nest a few levels at most
+	cborMaxItems = 4096
+
+	cborUnsigned = 0
+	cborNegative = 1
+	cborBytes    = 2
+	cborText     = 3
+	cborArray    = 4
+	cborMap      = 5
+	cborTag      = 6
+	cborSimple   = 7
+)
+
+// decodeCBOR decodes the first CBOR item of data and returns it with the number of bytes it used. Only the
+// subset authenticators produce is supported: integers (int64), byte and text strings, arrays ([]any), maps
+// (map[any]any with int64 or string keys), booleans and null. Indefinite lengths and tags are rejected.
+func decodeCBOR(data []byte) (any, int, error) {
+	decoder := &cborDecoder{data: data}
+	value, err := decoder.decode(0)
+	if err != nil {
+		return nil, 0, err
+	}
+	return value, decoder.offset, nil
+}
+
+type cborDecoder struct {
+	data   []byte
+	offset int
+}
+
+func (d *cborDecoder) read(size uint64) ([]byte, error) {
+	if size > uint64(len(d.data)-d.offset) {
+		return nil, fmt.Errorf("cbor: unexpected end of data")
+	}
+	value := d.data[d.offset : d.offset+int(size)]
+	d.offset += int(size)
+	return value, nil
+}
+
+// head reads the initial byte and argument of an item.
+func (d *cborDecoder) head() (byte, uint64, error) {
+	initial, err := d.read(1)
+	if err != nil {
+		return 0, 0, err
+	}
+	major, info := initial[0]>>5, initial[0]&0x1f
+
+	var argument uint64
+	switch {
+	case info < 24:
+		argument = uint64(info)
+	case info <= 27:
+		raw, err := d.read(1 << (info - 24))
+		if err != nil {
+			return 0, 0, err
+		}
+		for _, b := range raw {
+			argument = argument<<8 | uint64(b)
+		}
+	default:
+		return 0, 0, fmt.Errorf("cbor: unsupported additional information %d", info)
+	}
+	return major, argument, nil
+}
+
+func (d *cborDecoder) decode(depth int) (any, error) {
+	if depth > cborMaxDepth {
+		return nil, fmt.Errorf("cbor: nesting exceeds %d levels", cborMaxDepth)
+	}
+
+	start := d.offset
+	major, argument, err := d.head()
+	if err != nil {
+		return nil, err
+	}
+
+	switch major {
+	case cborUnsigned:
+		if argument > math.MaxInt64 {
+			return nil, fmt.Errorf("cbor: integer overflows int64")
+		}
+		return int64(argument), nil
+
+	case cborNegative:
+		if argument > math.MaxInt64 {
+			return nil, fmt.Errorf("cbor: integer overflows int64")
+		}
+		return -1 - int64(argument), nil
+
+	case cborBytes, cborText:
+		raw, err := d.read(argument)
+		if err != nil {
+			return nil, err
+		}
+		if major == cborText {
+			return string(raw), nil
+		}
+		return append([]byte(nil), raw...), nil
+
+	case cborArray:
+		if argument > cborMaxItems {
+			return nil, fmt.Errorf("cbor: array exceeds %d items", cborMaxItems)
+		}
+		array := make([]any, 0, argument)
+		for range argument {
+			item, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, item)
+		}
+		return array, nil
+
+	case cborMap:
+		if argument > cborMaxItems {
+			return nil, fmt.Errorf("cbor: map exceeds %d items", cborMaxItems)
+		}
+		entries := make(map[any]any, argument)
+		for range argument {
+			key, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			switch key.(type) {
+			case int64, string:
+			default:
+				return nil, fmt.Errorf("cbor: unsupported map key type %T", key)
+			}
+			if _, duplicate := entries[key]; duplicate {
+				return nil, fmt.Errorf("cbor: duplicate map key %v", key)
+			}
+			value, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			entries[key] = value
+		}
+		return entries, nil
+
+	case cborSimple:
+		// - Floats share the major type, their bits must not be read as a simple value
+		if d.data[start]&0x1f >= 24 {
+			return nil, fmt.Errorf("cbor: floats are not supported")
+		}
+		switch argument {
+		case 20:
+			return false, nil
+		case 21:
+			return true, nil
+		case 22, 23:
+			return nil, nil
+		}
+		return nil, fmt.Errorf("cbor: unsupported simple value %d", argument)
+	}
+	return nil, fmt.Errorf("cbor: unsupported major type %d", major)
+}
+
+// cborInt returns the integer at key of a decoded map.
+func cborInt(entries map[any]any, key any) (int64, bool) {
+	value, ok := entries[key].(int64)
+	return value, ok
+}
+
+// cborBytesOf returns the byte string at key of a decoded map.
+func cborBytesOf(entries map[any]any, key any) ([]byte, bool) {
+	value, ok := entries[key].([]byte)
+	return value, ok
+}
@@ -0,0 +1,573 @@
+package webauthn
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eko/gocache/lib/v4/store"
+	"github.com/grzegorzmaniak/gothic/core"
+	"github.com/grzegorzmaniak/gothic/errors"
+	"github.com/grzegorzmaniak/gothic/helpers"
+)
+
+const (
+	DefaultSessionGroup       = "webauthn"
+	DefaultChallengeTTL       = 5 * time.Minute
+	ChallengeSize             = 32
+	UserIDSize                = 32
+	MaximumUserIDSize         = 64          // Spec limit of the user handle
+	MaximumCredentialIDSize   = 1023        // Spec limit of a credential id
+	ChallengeCacheKeyPrefix   = "webauthn:" // Key: webauthn:<challenge>
+	PublicKeyCredentialType   = "public-key"
+	AttestationConveyanceNone = "none"
+)
+
+// UserVerification is the user verification requirement of a ceremony, e.g., a PIN or biometric on the
+// authenticator.
+type UserVerification string
+
+const (
+	UserVerificationRequired    UserVerification = "required"
+	UserVerificationPreferred   UserVerification = "preferred" // Default
+	UserVerificationDiscouraged UserVerification = "discouraged"
+)
+
+// ResidentKey is whether registration asks for a discoverable credential (passkey), needed for logins without
+// a username.
+type ResidentKey string
+
+const (
+	ResidentKeyRequired    ResidentKey = "required"
+	ResidentKeyPreferred   ResidentKey = "preferred" // Default
+	ResidentKeyDiscouraged ResidentKey = "discouraged"
+)
+
+// consumingChallenges holds the challenges being consumed, so two concurrent requests of this process can't both
+// finish the same ceremony between the cache read and delete.
+var consumingChallenges sync.Map
+
+// Config configures a RelyingParty, RPID, Origins and SessionManager are required.
+type Config struct {
+	// RPID is the relying party id credentials are scoped to, the registrable domain of the origins,
+	// e.g., "example.com".
+	RPID string
+
+	// RPName is shown by authenticators during registration (Default: RPID)
+	RPName string
+
+	// Origins are the exact origins ceremonies may run on, e.g., "https://app.example.com". Each must be https
+	// (or http on localhost) and on RPID or a subdomain of it.
+	Origins []string
+
+	// SessionManager stores the ceremony challenges in its cache and issues the sessions.
+	SessionManager core.SessionManager
+
+	// SessionGroup is the session mode of the issued sessions (Default: DefaultSessionGroup)
+	SessionGroup string
+
+	// ChallengeTTL is how long a ceremony can take, also sent to the browser as the timeout
+	// (Default: DefaultChallengeTTL)
+	ChallengeTTL time.Duration
+
+	// UserVerification is requested from authenticators (Default: UserVerificationPreferred)
+	UserVerification UserVerification
+
+	// ResidentKey is requested at registration (Default: ResidentKeyPreferred)
+	ResidentKey ResidentKey
+
+	// Algorithms offered at registration, in order of preference (Default: DefaultAlgorithms)
+	Algorithms []COSEAlgorithm
+
+	// MapClaims maps a verified assertion into the session claims (Default: DefaultClaimsMapper)
+	MapClaims ClaimsMapper
+}
+
+// RelyingParty runs the WebAuthn registration and assertion ceremonies of one RP ID.
+type RelyingParty struct {
+	config   Config
+	rpIDHash [sha256.Size]byte
+}
+
+// User is the account credentials are registered for. ID is the user handle stored on the authenticator, it
+// must be random and not contain personal data, see GenerateUserID.
+type User struct {
+	ID          []byte
+	Name        string
+	DisplayName string
+
+	// Credentials already registered, excluded at registration and allowed at login
+	Credentials []Credential
+}
+
+// Credential is a registered public key credential, store it for the user and update it after every login.
+type Credential struct {
+	ID                []byte        `json:"id"`
+	PublicKey         []byte        `json:"publicKey"` // COSE_Key
+	Algorithm         COSEAlgorithm `json:"algorithm"`
+	UserHandle        []byte        `json:"userHandle"`
+	SignCount         uint32        `json:"signCount"`
+	AAGUID            []byte        `json:"aaguid,omitempty"`
+	Transports        []string      `json:"transports,omitempty"`
+	BackupEligible    bool          `json:"backupEligible"`
+	BackupState       bool          `json:"backupState"`
+	AttestationFormat string        `json:"attestationFormat,omitempty"`
+}
+
+// CredentialLookup returns the stored credential with the given id, userHandle is set by the authenticator for
+// discoverable credentials. Return nil when the credential is unknown.
+type CredentialLookup func(ctx context.Context, credentialID []byte, userHandle []byte) (*Credential, error)
+
+// Base64URL is binary data sent as unpadded base64url, the encoding of the WebAuthn JSON serialization.
+type Base64URL []byte
+
+func (b Base64URL) MarshalJSON() ([]byte, error) {
+	return json.Marshal(base64.RawURLEncoding.EncodeToString(b))
+}
+
+func (b *Base64URL) UnmarshalJSON(data []byte) error {
+	var encoded string
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		return err
+	}
+	decoded, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(encoded, "="))
+	if err != nil {
+		return fmt.Errorf("invalid base64url: %w", err)
+	}
+	*b = decoded
+	return nil
+}
+
+type RelyingPartyEntity struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type UserEntity struct {
+	ID          Base64URL `json:"id"`
+	Name        string    `json:"name"`
+	DisplayName string    `json:"displayName"`
+}
+
+type CredentialParameter struct {
+	Type      string        `json:"type"`
+	Algorithm COSEAlgorithm `json:"alg"`
+}
+
+type CredentialDescriptor struct {
+	Type       string    `json:"type"`
+	ID         Base64URL `json:"id"`
+	Transports []string  `json:"transports,omitempty"`
+}
+
+type AuthenticatorSelection struct {
+	ResidentKey      ResidentKey      `json:"residentKey"`
+	RequireResident  bool             `json:"requireResidentKey"`
+	UserVerification UserVerification `json:"userVerification"`
+}
+
+// CreationOptions are the registration options, pass them to navigator.credentials.create() (e.g., with
+// PublicKeyCredential.parseCreationOptionsFromJSON).
+type CreationOptions struct {
+	RP                     RelyingPartyEntity     `json:"rp"`
+	User                   UserEntity             `json:"user"`
+	Challenge              string                 `json:"challenge"`
+	PubKeyCredParams       []CredentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int64                  `json:"timeout"`
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials,omitempty"`
+	AuthenticatorSelection AuthenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                 `json:"attestation"`
+}
+
+// RequestOptions are the login options, pass them to navigator.credentials.get() (e.g., with
+// PublicKeyCredential.parseRequestOptionsFromJSON).
+type RequestOptions struct {
+	Challenge        string                 `json:"challenge"`
+	Timeout          int64                  `json:"timeout"`
+	RPID             string                 `json:"rpId"`
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials,omitempty"`
+	UserVerification UserVerification       `json:"userVerification"`
+}
+
+type AuthenticatorAttestationResponse struct {
+	ClientDataJSON    Base64URL `json:"clientDataJSON" validate:"required"`
+	AttestationObject Base64URL `json:"attestationObject" validate:"required"`
+	Transports        []string  `json:"transports,omitempty"`
+}
+
+// RegistrationResponse is the JSON serialization of the credential returned by navigator.credentials.create().
+type RegistrationResponse struct {
+	ID       string                           `json:"id"`
+	RawID    Base64URL                        `json:"rawId" validate:"required"`
+	Type     string                           `json:"type"`
+	Response AuthenticatorAttestationResponse `json:"response"`
+}
+
+type AuthenticatorAssertionResponse struct {
+	ClientDataJSON    Base64URL `json:"clientDataJSON" validate:"required"`
+	AuthenticatorData Base64URL `json:"authenticatorData" validate:"required"`
+	Signature         Base64URL `json:"signature" validate:"required"`
+	UserHandle        Base64URL `json:"userHandle,omitempty"`
+}
+
+// AssertionResponse is the JSON serialization of the credential returned by navigator.credentials.get().
+type AssertionResponse struct {
+	ID       string                         `json:"id"`
+	RawID    Base64URL                      `json:"rawId" validate:"required"`
+	Type     string                         `json:"type"`
+	Response AuthenticatorAssertionResponse `json:"response"`
+}
+
+// Assertion is a verified login.
+type Assertion struct {
+	// Credential is the used credential with the new sign count and backup state, store it.
+	Credential *Credential
+
+	// UserVerified is set when the authenticator verified the user, e.g., with a PIN or biometric.
+	UserVerified bool
+
+	// UserID is the user the ceremony was started for, nil for logins without a username.
+	UserID []byte
+}
+
+// AuthLevel is the level of a session started with the assertion, a user verified passkey is multi-factor
+// on its own (possession and knowledge or inherence).
+func (a *Assertion) AuthLevel() core.AuthLevel {
+	if a.UserVerified {
+		return core.AuthLevelMultiFactor
+	}
+	return core.AuthLevelSingleFactor
+}
+
+// ceremony is the state of a started ceremony, stored in the cache under its challenge.
+type ceremony struct {
+	Type               string           `json:"type"`
+	UserID             []byte           `json:"userId,omitempty"`
+	AllowedCredentials [][]byte         `json:"allowedCredentials,omitempty"`
+	UserVerification   UserVerification `json:"userVerification"`
+}
+
+// New validates the configuration and returns the RelyingParty.
+func New(config Config) (*RelyingParty, error) {
+	switch {
+	case config.RPID == "":
+		return nil, fmt.Errorf("rp id is required")
+	case len(config.Origins) == 0:
+		return nil, fmt.Errorf("at least one origin is required")
+	case config.SessionManager == nil:
+		return nil, fmt.Errorf("session manager is required")
+	}
+
+	rpID := strings.ToLower(config.RPID)
+	for _, origin := range config.Origins {
+		parsed, err := url.Parse(origin)
+		if err != nil || parsed.Host == "" || parsed.Path != "" {
+			return nil, fmt.Errorf("origin '%s' is not a valid origin", origin)
+		}
+		host := strings.ToLower(parsed.Hostname())
+		if parsed.Scheme != "https" && !(parsed.Scheme == "http" && host == "localhost") {
+			return nil, fmt.Errorf("origin '%s' must use https", origin)
+		}
+		if host != rpID && !strings.HasSuffix(host, "."+rpID) {
+			return nil, fmt.Errorf("origin '%s' is not on rp id '%s'", origin, config.RPID)
+		}
+	}
+
+	for _, algorithm := range config.Algorithms {
+		if !slices.Contains(DefaultAlgorithms, algorithm) {
+			return nil, fmt.Errorf("unsupported algorithm %d", algorithm)
+		}
+	}
+
+	config.RPID = rpID
+	return &RelyingParty{config: config, rpIDHash: sha256.Sum256([]byte(rpID))}, nil
+}
+
+// GenerateUserID returns a random user handle for a new User.
+func GenerateUserID() ([]byte, error) {
+	id := make([]byte, UserIDSize)
+	if _, err := rand.Read(id); err != nil {
+		return nil, fmt.Errorf("failed to generate user id: %w", err)
+	}
+	return id, nil
+}
+
+func (rp *RelyingParty) challengeTTL() time.Duration {
+	return helpers.DefaultTimeDuration(rp.config.ChallengeTTL, DefaultChallengeTTL)
+}
+
+func (rp *RelyingParty) userVerification() UserVerification {
+	if rp.config.UserVerification == "" {
+		return UserVerificationPreferred
+	}
+	return rp.config.UserVerification
+}
+
+func (rp *RelyingParty) algorithms() []COSEAlgorithm {
+	if len(rp.config.Algorithms) == 0 {
+		return DefaultAlgorithms
+	}
+	return rp.config.Algorithms
+}
+
+func descriptors(credentials []Credential) []CredentialDescriptor {
+	result := make([]CredentialDescriptor, 0, len(credentials))
+	for _, credential := range credentials {
+		result = append(result, CredentialDescriptor{Type: PublicKeyCredentialType, ID: credential.ID, Transports: credential.Transports})
+	}
+	return result
+}
+
+// startCeremony stores the ceremony under a fresh challenge and returns the challenge.
+func (rp *RelyingParty) startCeremony(ctx context.Context, state *ceremony) (string, error) {
+	cache, err := rp.config.SessionManager.GetCache()
+	if err != nil || cache == nil {
+		return "", fmt.Errorf("failed to get cache: %w", err)
+	}
+
+	random := make([]byte, ChallengeSize)
+	if _, err = rand.Read(random); err != nil {
+		return "", fmt.Errorf("failed to generate challenge: %w", err)
+	}
+	challenge := base64.RawURLEncoding.EncodeToString(random)
+
+	marshaledState, err := json.Marshal(state)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal ceremony: %w", err)
+	}
+	// - Synchronous, the browser can answer before a buffered write would land
+	if err = cache.Set(ctx, ChallengeCacheKeyPrefix+challenge, marshaledState, store.WithExpiration(rp.challengeTTL()), store.WithSynchronousSet()); err != nil {
+		return "", fmt.Errorf("failed to store challenge: %w", err)
+	}
+	return challenge, nil
+}
+
+// consumeCeremony loads and deletes the ceremony of a challenge, a challenge can only be used once.
+func (rp *RelyingParty) consumeCeremony(ctx context.Context, challenge string, ceremonyType string) (*ceremony, error) {
+	if _, busy := consumingChallenges.LoadOrStore(challenge, struct{}{}); busy {
+		return nil, fmt.Errorf("challenge is already being used")
+	}
+	defer consumingChallenges.Delete(challenge)
+
+	cache, err := rp.config.SessionManager.GetCache()
+	if err != nil || cache == nil {
+		return nil, fmt.Errorf("failed to get cache: %w", err)
+	}
+	cacheKey := ChallengeCacheKeyPrefix + challenge
+	marshaledState, err := cache.Get(ctx, cacheKey)
+	if err != nil {
+		return nil, fmt.Errorf("challenge is unknown, expired or already used")
+	}
+	if err = cache.Delete(ctx, cacheKey); err != nil {
+		return nil, fmt.Errorf("failed to consume challenge: %w", err)
+	}
+
+	var state ceremony
+	if err = json.Unmarshal(marshaledState, &state); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal ceremony: %w", err)
+	}
+	if state.Type != ceremonyType {
+		return nil, fmt.Errorf("challenge was issued for '%s'", state.Type)
+	}
+	return &state, nil
+}
+
+// BeginRegistration starts registering a credential for the user, send the options to the browser.
+func (rp *RelyingParty) BeginRegistration(ctx context.Context, user *User) (*CreationOptions, *errors.AppError) {
+	if user == nil || len(user.ID) == 0 || len(user.ID) > MaximumUserIDSize || user.Name == "" {
+		return nil, errors.NewInternalServerError("Invalid user", fmt.Errorf("user id (1 to %d bytes) and name are required", MaximumUserIDSize))
+	}
+
+	userVerification := rp.userVerification()
+	challenge, err := rp.startCeremony(ctx, &ceremony{Type: clientDataTypeCreate, UserID: user.ID, UserVerification: userVerification})
+	if err != nil {
+		return nil, errors.NewInternalServerError("Failed to start registration", err)
+	}
+
+	residentKey := rp.config.ResidentKey
+	if residentKey == "" {
+		residentKey = ResidentKeyPreferred
+	}
+	parameters := make([]CredentialParameter, 0, len(rp.algorithms()))
+	for _, algorithm := range rp.algorithms() {
+		parameters = append(parameters, CredentialParameter{Type: PublicKeyCredentialType, Algorithm: algorithm})
+	}
+
+	return &CreationOptions{
+		RP:                 RelyingPartyEntity{ID: rp.config.RPID, Name: helpers.DefaultString(rp.config.RPName, rp.config.RPID)},
+		User:               UserEntity{ID: user.ID, Name: user.Name, DisplayName: helpers.DefaultString(user.DisplayName, user.Name)},
+		Challenge:          challenge,
+		PubKeyCredParams:   parameters,
+		Timeout:            rp.challengeTTL().Milliseconds(),
+		ExcludeCredentials: descriptors(user.Credentials),
+		AuthenticatorSelection: AuthenticatorSelection{
+			ResidentKey:      residentKey,
+			RequireResident:  residentKey == ResidentKeyRequired,
+			UserVerification: userVerification,
+		},
+		Attestation: AttestationConveyanceNone,
+	}, nil
+}
+
+// FinishRegistration verifies the browser's response for the user the registration was started for and
+// returns the new credential to store. Attestation is not requested, so attestation statements are not
+// verified, the format is recorded on the credential.
+func (rp *RelyingParty) FinishRegistration(ctx context.Context, user *User, response *RegistrationResponse) (*Credential, *errors.AppError) {
+	if user == nil || response == nil || response.Type != PublicKeyCredentialType {
+		return nil, errors.NewBadRequest("Invalid registration response", nil)
+	}
+
+	challenge, err := rp.verifyClientData(response.Response.ClientDataJSON, clientDataTypeCreate)
+	if err != nil {
+		return nil, errors.NewBadRequest("Invalid registration response", err)
+	}
+	state, err := rp.consumeCeremony(ctx, challenge, clientDataTypeCreate)
+	if err != nil {
+		return nil, errors.NewUnauthorized("Registration failed", err)
+	}
+	if !bytes.Equal(state.UserID, user.ID) {
+		return nil, errors.NewUnauthorized("Registration failed", fmt.Errorf("registration was started for another user"))
+	}
+
+	format, rawAuthData, err := parseAttestationObject(response.Response.AttestationObject)
+	if err != nil {
+		return nil, errors.NewBadRequest("Invalid registration response", err)
+	}
+	authData, err := parseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return nil, errors.NewBadRequest("Invalid registration response", err)
+	}
+	if err = rp.verifyAuthenticatorData(authData, state.UserVerification); err != nil {
+		return nil, errors.NewUnauthorized("Registration failed", err)
+	}
+	if !authData.has(flagAttestedData) || !bytes.Equal(authData.CredentialID, response.RawID) {
+		return nil, errors.NewBadRequest("Invalid registration response", fmt.Errorf("attested credential data is missing or does not match the credential id"))
+	}
+
+	algorithm, _, err := parsePublicKey(authData.PublicKey)
+	if err != nil {
+		return nil, errors.NewBadRequest("Invalid registration response", err)
+	}
+	if !slices.Contains(rp.algorithms(), algorithm) {
+		return nil, errors.NewBadRequest("Invalid registration response", fmt.Errorf("algorithm %d was not offered", algorithm))
+	}
+	for _, existing := range user.Credentials {
+		if bytes.Equal(existing.ID, authData.CredentialID) {
+			return nil, errors.NewConflict("Credential is already registered", nil)
+		}
+	}
+
+	return &Credential{
+		ID:                authData.CredentialID,
+		PublicKey:         authData.PublicKey,
+		Algorithm:         algorithm,
+		UserHandle:        user.ID,
+		SignCount:         authData.SignCount,
+		AAGUID:            authData.AAGUID,
+		Transports:        response.Response.Transports,
+		BackupEligible:    authData.has(flagBackupEligible),
+		BackupState:       authData.has(flagBackupState),
+		AttestationFormat: format,
+	}, nil
+}
+
+// BeginLogin starts a login, send the options to the browser. With a user only their credentials are accepted,
+// without one (nil) the authenticator offers its discoverable credentials (passkeys) for the RP ID.
+func (rp *RelyingParty) BeginLogin(ctx context.Context, user *User) (*RequestOptions, *errors.AppError) {
+	state := &ceremony{Type: clientDataTypeGet, UserVerification: rp.userVerification()}
+	var allowed []CredentialDescriptor
+	if user != nil {
+		if len(user.Credentials) == 0 {
+			return nil, errors.NewBadRequest("No credentials are registered", nil)
+		}
+		state.UserID = user.ID
+		allowed = descriptors(user.Credentials)
+		for _, credential := range user.Credentials {
+			state.AllowedCredentials = append(state.AllowedCredentials, credential.ID)
+		}
+	}
+
+	challenge, err := rp.startCeremony(ctx, state)
+	if err != nil {
+		return nil, errors.NewInternalServerError("Failed to start login", err)
+	}
+	return &RequestOptions{
+		Challenge:        challenge,
+		Timeout:          rp.challengeTTL().Milliseconds(),
+		RPID:             rp.config.RPID,
+		AllowCredentials: allowed,
+		UserVerification: state.UserVerification,
+	}, nil
+}
+
+// FinishLogin verifies the browser's response: the challenge, origin, RP ID, flags and the signature of the
+// credential returned by lookup. A sign count that did not increase is rejected as a possibly cloned
+// authenticator. Store the returned Assertion.Credential, it carries the new sign count.
+func (rp *RelyingParty) FinishLogin(ctx context.Context, response *AssertionResponse, lookup CredentialLookup) (*Assertion, *errors.AppError) {
+	if response == nil || response.Type != PublicKeyCredentialType || lookup == nil {
+		return nil, errors.NewBadRequest("Invalid login response", nil)
+	}
+
+	challenge, err := rp.verifyClientData(response.Response.ClientDataJSON, clientDataTypeGet)
+	if err != nil {
+		return nil, errors.NewBadRequest("Invalid login response", err)
+	}
+	state, err := rp.consumeCeremony(ctx, challenge, clientDataTypeGet)
+	if err != nil {
+		return nil, errors.NewUnauthorized("Login failed", err)
+	}
+	if len(state.AllowedCredentials) > 0 && !slices.ContainsFunc(state.AllowedCredentials, func(id []byte) bool { return bytes.Equal(id, response.RawID) }) {
+		return nil, errors.NewUnauthorized("Login failed", fmt.Errorf("credential was not allowed for this login"))
+	}
+	if len(state.UserID) == 0 && len(response.Response.UserHandle) == 0 {
+		return nil, errors.NewBadRequest("Invalid login response", fmt.Errorf("user handle is required without a username"))
+	}
+
+	credential, err := lookup(ctx, response.RawID, response.Response.UserHandle)
+	if err != nil {
+		return nil, errors.NewInternalServerError("Failed to look up credential", err)
+	}
+	if credential == nil || !bytes.Equal(credential.ID, response.RawID) {
+		return nil, errors.NewUnauthorized("Login failed", fmt.Errorf("credential is unknown"))
+	}
+	if len(state.UserID) > 0 && !bytes.Equal(credential.UserHandle, state.UserID) {
+		return nil, errors.NewUnauthorized("Login failed", fmt.Errorf("credential belongs to another user"))
+	}
+	if len(response.Response.UserHandle) > 0 && !bytes.Equal(credential.UserHandle, response.Response.UserHandle) {
+		return nil, errors.NewUnauthorized("Login failed", fmt.Errorf("user handle does not match the credential"))
+	}
+
+	authData, err := parseAuthenticatorData(response.Response.AuthenticatorData)
+	if err != nil {
+		return nil, errors.NewBadRequest("Invalid login response", err)
+	}
+	if err = rp.verifyAuthenticatorData(authData, state.UserVerification); err != nil {
+		return nil, errors.NewUnauthorized("Login failed", err)
+	}
+	if err = verifySignature(credential.PublicKey, response.Response.AuthenticatorData, response.Response.ClientDataJSON, response.Response.Signature); err != nil {
+		return nil, errors.NewUnauthorized("Login failed", err)
+	}
+
+	// - Authenticators without a counter always send 0, a counter that did not move means a copy of the key
+	if (authData.SignCount != 0 || credential.SignCount != 0) && authData.SignCount <= credential.SignCount {
+		return nil, errors.NewUnauthorized("Login failed", fmt.Errorf("sign count %d did not increase from %d", authData.SignCount, credential.SignCount))
+	}
+
+	updated := *credential
+	updated.SignCount = authData.SignCount
+	updated.BackupState = authData.has(flagBackupState)
+	return &Assertion{
+		Credential:   &updated,
+		UserVerified: authData.has(flagUserVerified),
+		UserID:       state.UserID,
+	}, nil
+}
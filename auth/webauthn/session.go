@@ -0,0 +1,102 @@
+package webauthn
+
+import (
+	"context"
+	"encoding/base64"
+	stderrors "errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/auth/mfa"
+	"github.com/grzegorzmaniak/gothic/core"
+	"github.com/grzegorzmaniak/gothic/errors"
+	"github.com/grzegorzmaniak/gothic/helpers"
+)
+
+// MethodWebAuthn is the mfa.MethodClaim value of sessions started or elevated with a WebAuthn credential
+// ("hwk", proof of possession of a hardware-secured key, RFC 8176).
+const MethodWebAuthn = "hwk"
+
+// ClaimsMapper turns a verified assertion into the claims of the GoThic session.
+type ClaimsMapper func(ctx context.Context, assertion *Assertion) (*core.SessionClaims, error)
+
+// DefaultClaimsMapper sets sub to the base64url user handle of the credential.
+func DefaultClaimsMapper(_ context.Context, assertion *Assertion) (*core.SessionClaims, error) {
+	claims := &core.SessionClaims{Claims: map[string]string{}}
+	claims.SetClaim("sub", base64.RawURLEncoding.EncodeToString(assertion.Credential.UserHandle))
+	return claims, nil
+}
+
+// sessionClaims maps the assertion and stamps the method and the AuthLevel it earned.
+func (rp *RelyingParty) sessionClaims(ctx *gin.Context, assertion *Assertion) (*core.SessionClaims, *errors.AppError) {
+	if assertion == nil || assertion.Credential == nil {
+		return nil, errors.NewInternalServerError("Assertion is nil", nil)
+	}
+
+	mapClaims := rp.config.MapClaims
+	if mapClaims == nil {
+		mapClaims = DefaultClaimsMapper
+	}
+	claims, err := mapClaims(ctx, assertion)
+	if err != nil || claims == nil {
+		return nil, errors.NewForbidden("Login is not allowed", err)
+	}
+	claims.SetClaim(mfa.MethodClaim, MethodWebAuthn)
+	claims.SetAuthLevel(assertion.AuthLevel())
+	return claims, nil
+}
+
+// Login starts a cookie session for a verified assertion with SetSessionCookie. The session is
+// core.AuthLevelMultiFactor when the authenticator verified the user, core.AuthLevelSingleFactor otherwise.
+func (rp *RelyingParty) Login(ctx *gin.Context, assertion *Assertion) *errors.AppError {
+	claims, appErr := rp.sessionClaims(ctx, assertion)
+	if appErr != nil {
+		return appErr
+	}
+	if err := core.SetSessionCookie(ctx, rp.config.SessionManager, helpers.DefaultString(rp.config.SessionGroup, DefaultSessionGroup), claims); err != nil {
+		return errors.NewInternalServerError("Failed to create session", err)
+	}
+	return nil
+}
+
+// LoginBearer is Login for bearer sessions, the token is returned.
+func (rp *RelyingParty) LoginBearer(ctx *gin.Context, assertion *Assertion) (string, *errors.AppError) {
+	claims, appErr := rp.sessionClaims(ctx, assertion)
+	if appErr != nil {
+		return "", appErr
+	}
+	token, err := core.IssueBearerToken(ctx, rp.config.SessionManager, helpers.DefaultString(rp.config.SessionGroup, DefaultSessionGroup), claims)
+	if err != nil {
+		return "", errors.NewInternalServerError("Failed to create session", err)
+	}
+	return token, nil
+}
+
+// Elevate steps an existing session up to core.AuthLevelMultiFactor with a verified assertion, the credential is
+// the second factor next to the session's login. The login must have been started with BeginLogin for the
+// session's user, so only their credentials were accepted. For bearer sessions the new token is returned.
+func (rp *RelyingParty) Elevate(ctx *gin.Context, claims *core.SessionClaims, header *core.SessionHeader, assertion *Assertion) (string, *errors.AppError) {
+	if assertion == nil || len(assertion.UserID) == 0 {
+		return "", errors.NewUnauthorized("Step-up requires a login started for the session's user", nil)
+	}
+	if claims == nil || !claims.HasSession {
+		return "", errors.NewUnauthorized("A session is required to verify a second factor", nil)
+	}
+
+	// - ElevateSession copies the claims, set the method on a copy so a failure leaves them untouched
+	verified := &core.SessionClaims{Claims: make(map[string]string, len(claims.Claims)+1), HasSession: true}
+	for name, value := range claims.Claims {
+		verified.Claims[name] = value
+	}
+	verified.SetClaim(mfa.MethodClaim, MethodWebAuthn)
+
+	token, err := core.ElevateSession(ctx, rp.config.SessionManager, verified, header, core.AuthLevelMultiFactor)
+	if err != nil {
+		var appErr *errors.AppError
+		if stderrors.As(err, &appErr) {
+			return "", appErr
+		}
+		return "", errors.NewInternalServerError("Failed to elevate the session", err)
+	}
+	*claims = *verified
+	return token, nil
+}
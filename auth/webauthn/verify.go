@@ -0,0 +1,278 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"slices"
+)
+
+// COSEAlgorithm is a COSE algorithm identifier of a credential public key.
+type COSEAlgorithm int64
+
+const (
+	AlgorithmES256 COSEAlgorithm = -7
+	AlgorithmEdDSA COSEAlgorithm = -8
+	AlgorithmRS256 COSEAlgorithm = -257
+)
+
+// DefaultAlgorithms are offered at registration, in order of preference.
+var DefaultAlgorithms = []COSEAlgorithm{AlgorithmES256, AlgorithmEdDSA, AlgorithmRS256}
+
+const (
+	coseKeyType   = 1
+	coseAlgorithm = 3
+	coseCurve     = -1 // EC2 and OKP
+	coseX         = -2 // EC2 and OKP
+	coseY         = -3 // EC2
+	coseModulus   = -1 // RSA
+	coseExponent  = -2 // RSA
+
+	coseKeyTypeOKP = 1
+	coseKeyTypeEC2 = 2
+	coseKeyTypeRSA = 3
+	coseCurveP256  = 1
+	coseCurveEd    = 6
+
+	minimumRSAKeyBits = 2048
+)
+
+// Authenticator data flags, https://www.w3.org/TR/webauthn-3/#authdata-flags
+const (
+	flagUserPresent    = 0x01
+	flagUserVerified   = 0x04
+	flagBackupEligible = 0x08
+	flagBackupState    = 0x10
+	flagAttestedData   = 0x40
+	flagExtensionData  = 0x80
+
+	authenticatorDataMinimumSize = 37 // rpIdHash, flags and signCount
+	aaguidSize                   = 16
+)
+
+const (
+	clientDataTypeCreate = "webauthn.create"
+	clientDataTypeGet    = "webauthn.get"
+)
+
+// clientData is the collected client data the browser signs over, https://www.w3.org/TR/webauthn-3/#dictdef-collectedclientdata
+type clientData struct {
+	Type        string `json:"type"`
+	Challenge   string `json:"challenge"`
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin,omitempty"`
+}
+
+// authenticatorData is the parsed authenticator data of a registration or assertion.
+type authenticatorData struct {
+	RPIDHash  []byte
+	Flags     byte
+	SignCount uint32
+
+	// - Only present at registration
+	AAGUID       []byte
+	CredentialID []byte
+	PublicKey    []byte // COSE_Key
+}
+
+func (a *authenticatorData) has(flag byte) bool {
+	return a.Flags&flag != 0
+}
+
+// verifyClientData checks the type, challenge and origin of the client data and returns the challenge.
+func (rp *RelyingParty) verifyClientData(raw []byte, ceremonyType string) (string, error) {
+	var data clientData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return "", fmt.Errorf("invalid client data: %w", err)
+	}
+	if data.Type != ceremonyType {
+		return "", fmt.Errorf("client data type '%s' is not '%s'", data.Type, ceremonyType)
+	}
+	if data.Challenge == "" {
+		return "", fmt.Errorf("client data has no challenge")
+	}
+	if !slices.Contains(rp.config.Origins, data.Origin) {
+		return "", fmt.Errorf("origin '%s' is not allowed", data.Origin)
+	}
+
+	// - A cross origin iframe could run the ceremony on behalf of another site
+	if data.CrossOrigin {
+		return "", fmt.Errorf("cross origin ceremonies are not allowed")
+	}
+	return data.Challenge, nil
+}
+
+// parseAuthenticatorData parses the authenticator data, the attested credential data is read when present.
+func parseAuthenticatorData(raw []byte) (*authenticatorData, error) {
+	if len(raw) < authenticatorDataMinimumSize {
+		return nil, fmt.Errorf("authenticator data is too short")
+	}
+	data := &authenticatorData{
+		RPIDHash:  raw[:32],
+		Flags:     raw[32],
+		SignCount: binary.BigEndian.Uint32(raw[33:37]),
+	}
+	rest := raw[authenticatorDataMinimumSize:]
+
+	if data.has(flagAttestedData) {
+		if len(rest) < aaguidSize+2 {
+			return nil, fmt.Errorf("attested credential data is too short")
+		}
+		data.AAGUID = rest[:aaguidSize]
+		idLength := int(binary.BigEndian.Uint16(rest[aaguidSize:]))
+		rest = rest[aaguidSize+2:]
+		if idLength == 0 || idLength > MaximumCredentialIDSize || len(rest) < idLength {
+			return nil, fmt.Errorf("invalid credential id length %d", idLength)
+		}
+		data.CredentialID = rest[:idLength]
+		rest = rest[idLength:]
+
+		// - The COSE key is not length prefixed, its CBOR encoding tells where it ends
+		_, used, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, fmt.Errorf("invalid credential public key: %w", err)
+		}
+		data.PublicKey = rest[:used]
+		rest = rest[used:]
+	}
+
+	if data.has(flagExtensionData) {
+		_, used, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, fmt.Errorf("invalid extension data: %w", err)
+		}
+		rest = rest[used:]
+	}
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("authenticator data has %d trailing bytes", len(rest))
+	}
+	return data, nil
+}
+
+// verifyAuthenticatorData checks the RP ID hash and the user presence / verification flags.
+func (rp *RelyingParty) verifyAuthenticatorData(data *authenticatorData, userVerification UserVerification) error {
+	if subtle.ConstantTimeCompare(data.RPIDHash, rp.rpIDHash[:]) != 1 {
+		return fmt.Errorf("authenticator data is not for RP ID '%s'", rp.config.RPID)
+	}
+	if !data.has(flagUserPresent) {
+		return fmt.Errorf("user presence flag is not set")
+	}
+	if userVerification == UserVerificationRequired && !data.has(flagUserVerified) {
+		return fmt.Errorf("user verification is required")
+	}
+	if !data.has(flagBackupEligible) && data.has(flagBackupState) {
+		return fmt.Errorf("backup state is set on a credential that is not backup eligible")
+	}
+	return nil
+}
+
+// parsePublicKey decodes a COSE_Key into its algorithm and Go public key.
+func parsePublicKey(coseKey []byte) (COSEAlgorithm, crypto.PublicKey, error) {
+	decoded, _, err := decodeCBOR(coseKey)
+	if err != nil {
+		return 0, nil, err
+	}
+	entries, ok := decoded.(map[any]any)
+	if !ok {
+		return 0, nil, fmt.Errorf("public key is not a COSE key")
+	}
+	keyType, _ := cborInt(entries, int64(coseKeyType))
+	algorithm, ok := cborInt(entries, int64(coseAlgorithm))
+	if !ok {
+		return 0, nil, fmt.Errorf("public key has no algorithm")
+	}
+
+	switch COSEAlgorithm(algorithm) {
+	case AlgorithmES256:
+		curve, _ := cborInt(entries, int64(coseCurve))
+		x, _ := cborBytesOf(entries, int64(coseX))
+		y, _ := cborBytesOf(entries, int64(coseY))
+		if keyType != coseKeyTypeEC2 || curve != coseCurveP256 || len(x) != 32 || len(y) != 32 {
+			return 0, nil, fmt.Errorf("invalid ES256 public key")
+		}
+		key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !key.Curve.IsOnCurve(key.X, key.Y) {
+			return 0, nil, fmt.Errorf("ES256 public key is not on the curve")
+		}
+		return AlgorithmES256, key, nil
+
+	case AlgorithmEdDSA:
+		curve, _ := cborInt(entries, int64(coseCurve))
+		x, _ := cborBytesOf(entries, int64(coseX))
+		if keyType != coseKeyTypeOKP || curve != coseCurveEd || len(x) != ed25519.PublicKeySize {
+			return 0, nil, fmt.Errorf("invalid EdDSA public key")
+		}
+		return AlgorithmEdDSA, ed25519.PublicKey(x), nil
+
+	case AlgorithmRS256:
+		modulus, _ := cborBytesOf(entries, int64(coseModulus))
+		exponent, _ := cborBytesOf(entries, int64(coseExponent))
+		if keyType != coseKeyTypeRSA || len(exponent) == 0 || len(exponent) > 4 {
+			return 0, nil, fmt.Errorf("invalid RS256 public key")
+		}
+		key := &rsa.PublicKey{N: new(big.Int).SetBytes(modulus), E: int(new(big.Int).SetBytes(exponent).Int64())}
+		if key.N.BitLen() < minimumRSAKeyBits {
+			return 0, nil, fmt.Errorf("RS256 public key is smaller than %d bits", minimumRSAKeyBits)
+		}
+		return AlgorithmRS256, key, nil
+	}
+	return 0, nil, fmt.Errorf("unsupported algorithm %d", algorithm)
+}
+
+// verifySignature checks an assertion signature, made over the authenticator data and the client data hash.
+func verifySignature(coseKey []byte, authenticatorData []byte, clientDataJSON []byte, signature []byte) error {
+	algorithm, publicKey, err := parsePublicKey(coseKey)
+	if err != nil {
+		return err
+	}
+
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	signed := append(append([]byte(nil), authenticatorData...), clientDataHash[:]...)
+
+	switch algorithm {
+	case AlgorithmES256:
+		digest := sha256.Sum256(signed)
+		if !ecdsa.VerifyASN1(publicKey.(*ecdsa.PublicKey), digest[:], signature) {
+			return fmt.Errorf("invalid ES256 signature")
+		}
+	case AlgorithmEdDSA:
+		if !ed25519.Verify(publicKey.(ed25519.PublicKey), signed, signature) {
+			return fmt.Errorf("invalid EdDSA signature")
+		}
+	case AlgorithmRS256:
+		digest := sha256.Sum256(signed)
+		if err = rsa.VerifyPKCS1v15(publicKey.(*rsa.PublicKey), crypto.SHA256, digest[:], signature); err != nil {
+			return fmt.Errorf("invalid RS256 signature: %w", err)
+		}
+	}
+	return nil
+}
+
+// parseAttestationObject returns the format and authenticator data of an attestation object.
+func parseAttestationObject(raw []byte) (string, []byte, error) {
+	decoded, used, err := decodeCBOR(raw)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid attestation object: %w", err)
+	}
+	if used != len(raw) {
+		return "", nil, fmt.Errorf("attestation object has trailing bytes")
+	}
+	entries, ok := decoded.(map[any]any)
+	if !ok {
+		return "", nil, fmt.Errorf("attestation object is not a map")
+	}
+	format, _ := entries["fmt"].(string)
+	authData, ok := cborBytesOf(entries, "authData")
+	if format == "" || !ok {
+		return "", nil, fmt.Errorf("attestation object is missing fmt or authData")
+	}
+	return format, authData, nil
+}
@@ -0,0 +1,431 @@
+package webauthn
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/auth/mfa"
+	"github.com/grzegorzmaniak/gothic/core"
+	"github.com/grzegorzmaniak/gothic/errors"
+	"github.com/grzegorzmaniak/gothic/helpers"
+)
+
+const testOrigin = "https://app.example.com"
+
+// encodeCBOR encodes the values the fake authenticator needs, map keys are sorted for a stable output.
+func encodeCBOR(value any) []byte {
+	head := func(major byte, argument uint64) []byte {
+		switch {
+		case argument < 24:
+			return []byte{major<<5 | byte(argument)}
+		case argument <= 0xff:
+			return []byte{major<<5 | 24, byte(argument)}
+		case argument <= 0xffff:
+			return binary.BigEndian.AppendUint16([]byte{major<<5 | 25}, uint16(argument))
+		}
+		return binary.BigEndian.AppendUint32([]byte{major<<5 | 26}, uint32(argument))
+	}
+
+	switch v := value.(type) {
+	case int:
+		if v < 0 {
+			return head(cborNegative, uint64(-1-v))
+		}
+		return head(cborUnsigned, uint64(v))
+	case []byte:
+		return append(head(cborBytes, uint64(len(v))), v...)
+	case string:
+		return append(head(cborText, uint64(len(v))), v...)
+	case map[any]any:
+		keys := make([]any, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool { return string(encodeCBOR(keys[i])) < string(encodeCBOR(keys[j])) })
+		out := head(cborMap, uint64(len(v)))
+		for _, key := range keys {
+			out = append(out, encodeCBOR(key)...)
+			out = append(out, encodeCBOR(v[key])...)
+		}
+		return out
+	}
+	panic("unsupported cbor value")
+}
+
+// authenticator is a fake platform authenticator holding one credential.
+type authenticator struct {
+	id        []byte
+	ecKey     *ecdsa.PrivateKey
+	edKey     ed25519.PrivateKey
+	signCount uint32
+	flags     byte
+}
+
+func newAuthenticator(t *testing.T, eddsa bool) *authenticator {
+	t.Helper()
+	a := &authenticator{id: []byte("credential-" + t.Name()), flags: flagUserPresent | flagUserVerified}
+	if eddsa {
+		_, a.edKey, _ = ed25519.GenerateKey(rand.Reader)
+	} else {
+		a.ecKey, _ = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	}
+	return a
+}
+
+func (a *authenticator) coseKey() []byte {
+	if a.edKey != nil {
+		return encodeCBOR(map[any]any{1: coseKeyTypeOKP, 3: int(AlgorithmEdDSA), -1: coseCurveEd, -2: []byte(a.edKey.Public().(ed25519.PublicKey))})
+	}
+	x, y := make([]byte, 32), make([]byte, 32)
+	a.ecKey.X.FillBytes(x)
+	a.ecKey.Y.FillBytes(y)
+	return encodeCBOR(map[any]any{1: coseKeyTypeEC2, 3: int(AlgorithmES256), -1: coseCurveP256, -2: x, -3: y})
+}
+
+func (a *authenticator) authData(rpID string, attested bool) []byte {
+	rpIDHash := sha256.Sum256([]byte(rpID))
+	flags := a.flags
+	if attested {
+		flags |= flagAttestedData
+	}
+	data := append(rpIDHash[:], flags)
+	data = binary.BigEndian.AppendUint32(data, a.signCount)
+	if attested {
+		data = append(data, make([]byte, aaguidSize)...)
+		data = binary.BigEndian.AppendUint16(data, uint16(len(a.id)))
+		data = append(data, a.id...)
+		data = append(data, a.coseKey()...)
+	}
+	return data
+}
+
+func clientDataJSON(ceremonyType string, challenge string, origin string) []byte {
+	data, _ := json.Marshal(clientData{Type: ceremonyType, Challenge: challenge, Origin: origin})
+	return data
+}
+
+func (a *authenticator) create(options *CreationOptions) *RegistrationResponse {
+	return &RegistrationResponse{
+		ID:    base64.RawURLEncoding.EncodeToString(a.id),
+		RawID: a.id,
+		Type:  PublicKeyCredentialType,
+		Response: AuthenticatorAttestationResponse{
+			ClientDataJSON:    clientDataJSON(clientDataTypeCreate, options.Challenge, testOrigin),
+			AttestationObject: encodeCBOR(map[any]any{"fmt": "none", "attStmt": map[any]any{}, "authData": a.authData(options.RP.ID, true)}),
+			Transports:        []string{"internal"},
+		},
+	}
+}
+
+func (a *authenticator) get(options *RequestOptions, userHandle []byte) *AssertionResponse {
+	a.signCount++
+	authData := a.authData(options.RPID, false)
+	clientDataJSON := clientDataJSON(clientDataTypeGet, options.Challenge, testOrigin)
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	signed := append(append([]byte(nil), authData...), clientDataHash[:]...)
+
+	var signature []byte
+	if a.edKey != nil {
+		signature = ed25519.Sign(a.edKey, signed)
+	} else {
+		digest := sha256.Sum256(signed)
+		signature, _ = ecdsa.SignASN1(rand.Reader, a.ecKey, digest[:])
+	}
+	return &AssertionResponse{
+		ID:    base64.RawURLEncoding.EncodeToString(a.id),
+		RawID: a.id,
+		Type:  PublicKeyCredentialType,
+		Response: AuthenticatorAssertionResponse{
+			ClientDataJSON:    clientDataJSON,
+			AuthenticatorData: authData,
+			Signature:         signature,
+			UserHandle:        userHandle,
+		},
+	}
+}
+
+func newTestRelyingParty(t *testing.T) (*RelyingParty, core.SessionManager) {
+	t.Helper()
+	key, _ := helpers.GenerateSymmetricKey(helpers.AESKeySize32)
+	manager, err := core.NewSessionManagerBuilder().WithKey("k1", key).Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	t.Cleanup(func() { _ = manager.Close() })
+
+	rp, err := New(Config{RPID: "Example.com", RPName: "Example", Origins: []string{testOrigin}, SessionManager: manager})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return rp, manager
+}
+
+func register(t *testing.T, rp *RelyingParty, a *authenticator) (*User, *Credential) {
+	t.Helper()
+	userID, _ := GenerateUserID()
+	user := &User{ID: userID, Name: "jane@example.com"}
+	options, appErr := rp.BeginRegistration(context.Background(), user)
+	if appErr != nil {
+		t.Fatalf("BeginRegistration() error = %v", appErr)
+	}
+	credential, appErr := rp.FinishRegistration(context.Background(), user, a.create(options))
+	if appErr != nil {
+		t.Fatalf("FinishRegistration() error = %v", appErr)
+	}
+	user.Credentials = append(user.Credentials, *credential)
+	return user, credential
+}
+
+func lookupOf(user *User) CredentialLookup {
+	return func(_ context.Context, credentialID []byte, _ []byte) (*Credential, error) {
+		for i := range user.Credentials {
+			if string(user.Credentials[i].ID) == string(credentialID) {
+				return &user.Credentials[i], nil
+			}
+		}
+		return nil, nil
+	}
+}
+
+func TestDecodeCBOR(t *testing.T) {
+	value, used, err := decodeCBOR([]byte{0xa2, 0x01, 0x02, 0x20, 0x43, 0x01, 0x02, 0x03, 0xff})
+	if err != nil || used != 8 {
+		t.Fatalf("decodeCBOR() = %v, %d, %v", value, used, err)
+	}
+	entries := value.(map[any]any)
+	if entries[int64(1)] != int64(2) || string(entries[int64(-1)].([]byte)) != "\x01\x02\x03" {
+		t.Errorf("Unexpected map %v", entries)
+	}
+
+	for name, data := range map[string][]byte{
+		"float":      {0xf9, 0x00, 0x14},
+		"indefinite": {0x5f},
+		"truncated":  {0x43, 0x01},
+		"duplicate":  {0xa2, 0x01, 0x01, 0x01, 0x02},
+		"tag":        {0xc0, 0x01},
+	} {
+		if _, _, err = decodeCBOR(data); err == nil {
+			t.Errorf("Expected %s to be rejected", name)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	rp, manager := newTestRelyingParty(t)
+	if rp.config.RPID != "example.com" {
+		t.Errorf("Expected the RP ID to be lowercased, got '%s'", rp.config.RPID)
+	}
+
+	for _, origin := range []string{"http://app.example.com", "https://example.org", "https://badexample.com", "https://app.example.com/path"} {
+		if _, err := New(Config{RPID: "example.com", Origins: []string{origin}, SessionManager: manager}); err == nil {
+			t.Errorf("Expected origin '%s' to be rejected", origin)
+		}
+	}
+	if _, err := New(Config{RPID: "localhost", Origins: []string{"http://localhost:8080"}, SessionManager: manager}); err != nil {
+		t.Errorf("Expected http on localhost to be allowed, got %v", err)
+	}
+}
+
+func TestRegistration(t *testing.T) {
+	rp, _ := newTestRelyingParty(t)
+	a := newAuthenticator(t, false)
+	user, credential := register(t, rp, a)
+	if credential.Algorithm != AlgorithmES256 || string(credential.UserHandle) != string(user.ID) || credential.AttestationFormat != "none" {
+		t.Errorf("Unexpected credential %+v", credential)
+	}
+
+	options, _ := rp.BeginRegistration(context.Background(), user)
+	if len(options.ExcludeCredentials) != 1 || options.Timeout != DefaultChallengeTTL.Milliseconds() || options.RP.ID != "example.com" {
+		t.Errorf("Unexpected creation options %+v", options)
+	}
+	response := a.create(options)
+	if _, appErr := rp.FinishRegistration(context.Background(), user, response); appErr == nil || appErr.Code != http.StatusConflict {
+		t.Errorf("Expected a registered credential to conflict, got %v", appErr)
+	}
+	if _, appErr := rp.FinishRegistration(context.Background(), user, response); appErr == nil || appErr.Code != http.StatusUnauthorized {
+		t.Errorf("Expected a used challenge to be rejected, got %v", appErr)
+	}
+
+	other := &User{ID: []byte("other"), Name: "other"}
+	options, _ = rp.BeginRegistration(context.Background(), user)
+	if _, appErr := rp.FinishRegistration(context.Background(), other, newAuthenticator(t, false).create(options)); appErr == nil {
+		t.Error("Expected a registration of another user to be rejected")
+	}
+
+	options, _ = rp.BeginRegistration(context.Background(), other)
+	response = newAuthenticator(t, false).create(options)
+	response.Response.ClientDataJSON = clientDataJSON(clientDataTypeCreate, options.Challenge, "https://evil.example.org")
+	if _, appErr := rp.FinishRegistration(context.Background(), other, response); appErr == nil {
+		t.Error("Expected a foreign origin to be rejected")
+	}
+}
+
+func TestLogin(t *testing.T) {
+	rp, _ := newTestRelyingParty(t)
+	a := newAuthenticator(t, false)
+	user, _ := register(t, rp, a)
+
+	options, appErr := rp.BeginLogin(context.Background(), user)
+	if appErr != nil || len(options.AllowCredentials) != 1 {
+		t.Fatalf("BeginLogin() = %+v, %v", options, appErr)
+	}
+	response := a.get(options, nil)
+	assertion, appErr := rp.FinishLogin(context.Background(), response, lookupOf(user))
+	if appErr != nil {
+		t.Fatalf("FinishLogin() error = %v", appErr)
+	}
+	if assertion.Credential.SignCount != 1 || !assertion.UserVerified || assertion.AuthLevel() != core.AuthLevelMultiFactor {
+		t.Errorf("Unexpected assertion %+v", assertion)
+	}
+	if _, appErr = rp.FinishLogin(context.Background(), response, lookupOf(user)); appErr == nil {
+		t.Error("Expected a replayed assertion to be rejected")
+	}
+	user.Credentials[0] = *assertion.Credential
+
+	// - A copy of the key that is behind on the counter
+	options, _ = rp.BeginLogin(context.Background(), user)
+	a.signCount = 0
+	if _, appErr = rp.FinishLogin(context.Background(), a.get(options, nil), lookupOf(user)); appErr == nil || !strings.Contains(appErr.Error(), "sign count") {
+		t.Errorf("Expected a stale sign count to be rejected, got %v", appErr)
+	}
+
+	options, _ = rp.BeginLogin(context.Background(), user)
+	response = a.get(options, nil)
+	response.Response.Signature[len(response.Response.Signature)-1] ^= 0xff
+	if _, appErr = rp.FinishLogin(context.Background(), response, lookupOf(user)); appErr == nil || appErr.Code != http.StatusUnauthorized {
+		t.Errorf("Expected a bad signature to be rejected, got %v", appErr)
+	}
+
+	// - Credentials of another user are not accepted for this user's login
+	stranger := newAuthenticator(t, false)
+	stranger.id = []byte("stranger")
+	strangerUser, _ := register(t, rp, stranger)
+	options, _ = rp.BeginLogin(context.Background(), user)
+	if _, appErr = rp.FinishLogin(context.Background(), stranger.get(options, nil), lookupOf(strangerUser)); appErr == nil {
+		t.Error("Expected a credential that was not allowed to be rejected")
+	}
+}
+
+func TestDiscoverableLogin(t *testing.T) {
+	rp, _ := newTestRelyingParty(t)
+	a := newAuthenticator(t, true)
+	a.flags = flagUserPresent
+	user, _ := register(t, rp, a)
+
+	options, appErr := rp.BeginLogin(context.Background(), nil)
+	if appErr != nil || len(options.AllowCredentials) != 0 {
+		t.Fatalf("BeginLogin() = %+v, %v", options, appErr)
+	}
+	if _, appErr = rp.FinishLogin(context.Background(), a.get(options, nil), lookupOf(user)); appErr == nil {
+		t.Error("Expected a discoverable login without a user handle to be rejected")
+	}
+
+	options, _ = rp.BeginLogin(context.Background(), nil)
+	assertion, appErr := rp.FinishLogin(context.Background(), a.get(options, user.ID), lookupOf(user))
+	if appErr != nil {
+		t.Fatalf("FinishLogin() error = %v", appErr)
+	}
+	if assertion.UserVerified || assertion.AuthLevel() != core.AuthLevelSingleFactor || assertion.UserID != nil {
+		t.Errorf("Unexpected assertion %+v", assertion)
+	}
+
+	required, _ := New(Config{RPID: "example.com", Origins: []string{testOrigin}, SessionManager: rp.config.SessionManager, UserVerification: UserVerificationRequired})
+	options, _ = required.BeginLogin(context.Background(), nil)
+	if _, appErr = required.FinishLogin(context.Background(), a.get(options, user.ID), lookupOf(user)); appErr == nil {
+		t.Error("Expected a login without user verification to be rejected when it is required")
+	}
+}
+
+type testBaseRoute struct{}
+
+type sessionOutput struct {
+	Claims map[string]string `json:"claims,omitempty"`
+	Token  string            `json:"token,omitempty"`
+}
+
+func TestSession(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	rp, manager := newTestRelyingParty(t)
+	a := newAuthenticator(t, false)
+	user, _ := register(t, rp, a)
+
+	router := gin.New()
+	ctor := core.NewRouteConstructor(router, testBaseRoute{}, manager, nil)
+	core.GET(ctor, "/secret", mfa.Require(core.AuthenticatedJSONAPI()), func(_ *struct{}, data *core.Handler[testBaseRoute]) (*sessionOutput, *errors.AppError) {
+		return &sessionOutput{Claims: data.Claims.Claims}, nil
+	})
+	core.POST(ctor, "/step-up", core.AuthenticatedJSONAPI().WithoutCsrf(), func(input *AssertionResponse, data *core.Handler[testBaseRoute]) (*sessionOutput, *errors.AppError) {
+		assertion, appErr := rp.FinishLogin(data.Context, input, lookupOf(user))
+		if appErr != nil {
+			return nil, appErr
+		}
+		token, appErr := rp.Elevate(data.Context, data.Claims, data.SessionHeader, assertion)
+		if appErr != nil {
+			return nil, appErr
+		}
+		return &sessionOutput{Token: token}, nil
+	})
+	call := func(token string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodGet, "/secret", nil)
+		request.Header.Set(core.DefaultSessionAuthorizationHeaderName, token)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/login", nil)
+	options, _ := rp.BeginLogin(ctx, user)
+	assertion, appErr := rp.FinishLogin(ctx, a.get(options, nil), lookupOf(user))
+	if appErr != nil {
+		t.Fatalf("FinishLogin() error = %v", appErr)
+	}
+	token, appErr := rp.LoginBearer(ctx, assertion)
+	if appErr != nil {
+		t.Fatalf("LoginBearer() error = %v", appErr)
+	}
+	recorder := call(token)
+	if recorder.Code != http.StatusOK || !strings.Contains(recorder.Body.String(), `"amr":"hwk"`) || !strings.Contains(recorder.Body.String(), base64.RawURLEncoding.EncodeToString(user.ID)) {
+		t.Fatalf("Expected a user verified passkey login to be multi-factor, got %d %s", recorder.Code, recorder.Body.String())
+	}
+
+	// - A password session stepped up with a security key
+	password, _ := core.IssueBearerToken(ctx, manager, "default", &core.SessionClaims{Claims: map[string]string{"sub": "user-1"}})
+	if recorder = call(password); recorder.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected the password session to need a step-up, got %d", recorder.Code)
+	}
+	user.Credentials[0] = *assertion.Credential
+	options, _ = rp.BeginLogin(ctx, user)
+	body, _ := json.Marshal(a.get(options, nil))
+	request := httptest.NewRequest(http.MethodPost, "/step-up", strings.NewReader(string(body)))
+	request.Header.Set(core.DefaultSessionAuthorizationHeaderName, password)
+	request.Header.Set("Content-Type", "application/json")
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	var elevated sessionOutput
+	_ = json.Unmarshal(recorder.Body.Bytes(), &elevated)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected the step-up to succeed, got %d %s", recorder.Code, recorder.Body.String())
+	}
+	if recorder = call(elevated.Token); recorder.Code != http.StatusOK {
+		t.Errorf("Expected the elevated session to pass, got %d %s", recorder.Code, recorder.Body.String())
+	}
+
+	assertion.UserID = nil
+	if _, appErr = rp.Elevate(ctx, &core.SessionClaims{HasSession: true}, &core.SessionHeader{}, assertion); appErr == nil {
+		t.Error("Expected a step-up with a login not bound to a user to be rejected")
+	}
+}
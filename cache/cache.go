@@ -1,6 +1,7 @@
 package cache
 
 import (
+	"context"
 	"fmt"
 	"sync"
 	"time"
@@ -20,6 +21,9 @@ const (
 	DefaultStoreExpirationForRistrettoAdapter = 5 * time.Minute
 )
 
+// ErrCacheClosed is returned by GetCache once the cache manager has been shut down.
+var ErrCacheClosed = fmt.Errorf("cache: %w", helpers.ErrShutdown)
+
 type DefaultCacheConfig struct {
 
 	// RistrettoMaxCost defines the maximum "cost" for the Ristretto cache.
@@ -48,35 +52,48 @@ type DefaultCacheManager struct {
 	CacheInstance  cache.CacheInterface[[]byte]
 	CacheInitOnce  sync.Once
 	CacheInitError error
+
+	// newStore builds the store of managers created by BuildMemcachedCacheManager or BuildBigCacheManager, nil
+	// uses Ristretto. It returns the function closing the store's client.
+	newStore func() (store.StoreInterface, func() error, error)
+
+	closeMu    sync.Mutex
+	closed     bool
+	closeStore func() error
 }
 
 func (m *DefaultCacheManager) GetCache() (cache.CacheInterface[[]byte], error) {
+	m.closeMu.Lock()
+	closed := m.closed
+	m.closeMu.Unlock()
+	if closed {
+		return nil, ErrCacheClosed
+	}
+
 	m.CacheInitOnce.Do(func() {
-		// BuildDefaultCacheManager sets reasonable defaults, so we can assume CacheConfig is always non-nil here.
-		// But, just in case, I will still default the values.
-		ristrettoClient, err := ristretto.NewCache(&ristretto.Config{
-			NumCounters: helpers.DefaultInt64(m.CacheConfig.RistrettoNumCounters, DefaultRistrettoNumCounters),
-			MaxCost:     helpers.DefaultInt64(m.CacheConfig.RistrettoMaxCost, DefaultRistrettoMaxCost),
-			BufferItems: helpers.DefaultInt64(m.CacheConfig.RistrettoBufferItems, DefaultRistrettoBufferItems),
-			Metrics:     false,
-		})
+		// - A preset instance, e.g., a ChainCacheManager's cache, is used as is
+		if m.CacheInstance != nil {
+			return
+		}
+
+		newStore := m.newStore
+		if newStore == nil {
+			newStore = func() (store.StoreInterface, func() error, error) { return newRistrettoStore(m.CacheConfig) }
+		}
 
+		cacheStore, closeStore, err := newStore()
 		if err != nil {
-			zap.L().Error("DefaultCacheManager: Failed to create Ristretto cache client during initialization", zap.Error(err))
-			m.CacheInitError = fmt.Errorf("ristretto client initialization failed: %w", err)
+			zap.L().Error("DefaultCacheManager: Failed to create the cache store during initialization", zap.Error(err))
+			m.CacheInitError = fmt.Errorf("cache store initialization failed: %w", err)
 			return
 		}
 
-		ristrettoStoreAdapter := ristrettoStore.NewRistretto(
-			ristrettoClient,
-			store.WithExpiration(helpers.DefaultTimeDuration(
-				m.CacheConfig.DefaultStoreExpirationForRistrettoAdapter,
-				DefaultStoreExpirationForRistrettoAdapter,
-			)),
-		)
+		m.closeMu.Lock()
+		m.closeStore = closeStore
+		m.closeMu.Unlock()
 
-		m.CacheInstance = cache.New[[]byte](ristrettoStoreAdapter)
-		zap.L().Info("DefaultCacheManager: Ristretto cache instance initialized successfully.")
+		m.CacheInstance = WithNamespace(cache.New[[]byte](cacheStore), KeyNamespace())
+		zap.L().Info("DefaultCacheManager: Cache instance initialized successfully.", zap.String("store", cacheStore.GetType()))
 	})
 
 	if m.CacheInitError != nil {
@@ -91,6 +108,56 @@ func (m *DefaultCacheManager) GetCache() (cache.CacheInterface[[]byte], error) {
 	return m.CacheInstance, nil
 }
 
+// Shutdown closes the client of the store (stopping Ristretto's and BigCache's goroutines, or memcached's idle
+// connections), GetCache returns ErrCacheClosed afterwards. A preset CacheInstance is not owned by the manager and
+// is left open, e.g., close a ChainCacheManager itself. Calling it more than once is safe.
+func (m *DefaultCacheManager) Shutdown(_ context.Context) error {
+	m.closeMu.Lock()
+	defer m.closeMu.Unlock()
+	if m.closed {
+		return nil
+	}
+	m.closed = true
+
+	if m.closeStore == nil {
+		return nil
+	}
+	return m.closeStore()
+}
+
+// Close shuts down the cache, see Shutdown.
+func (m *DefaultCacheManager) Close() error {
+	return m.Shutdown(context.Background())
+}
+
+// newRistrettoStore builds the Ristretto store of a DefaultCacheManager.
+func newRistrettoStore(config DefaultCacheConfig) (store.StoreInterface, func() error, error) {
+	// BuildDefaultCacheManager sets reasonable defaults, so we can assume CacheConfig is always non-nil here.
+	// But, just in case, I will still default the values.
+	ristrettoClient, err := ristretto.NewCache(&ristretto.Config{
+		NumCounters: helpers.DefaultInt64(config.RistrettoNumCounters, DefaultRistrettoNumCounters),
+		MaxCost:     helpers.DefaultInt64(config.RistrettoMaxCost, DefaultRistrettoMaxCost),
+		BufferItems: helpers.DefaultInt64(config.RistrettoBufferItems, DefaultRistrettoBufferItems),
+		Metrics:     false,
+	})
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("ristretto client initialization failed: %w", err)
+	}
+
+	closeClient := func() error {
+		ristrettoClient.Close()
+		return nil
+	}
+	return ristrettoStore.NewRistretto(
+		ristrettoClient,
+		store.WithExpiration(helpers.DefaultTimeDuration(
+			config.DefaultStoreExpirationForRistrettoAdapter,
+			DefaultStoreExpirationForRistrettoAdapter,
+		)),
+	), closeClient, nil
+}
+
 func BuildDefaultCacheManager(config *DefaultCacheConfig) *DefaultCacheManager {
 	if config == nil {
 		config = &DefaultCacheConfig{
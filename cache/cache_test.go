@@ -2,8 +2,12 @@
 package cache
 
 import (
+	"context"
+	"errors"
 	"testing"
 	"time"
+
+	"github.com/grzegorzmaniak/gothic/helpers"
 )
 
 func TestBuildDefaultCacheManager_NilConfig_AppliesDefaults(t *testing.T) {
@@ -86,3 +90,25 @@ func TestDefaultCacheManager_GetCache_InitializesCache(t *testing.T) {
 		t.Fatalf("expected same cache instance on second call, got different instances")
 	}
 }
+
+func TestDefaultCacheManager_Shutdown(t *testing.T) {
+	m := BuildDefaultCacheManager(nil)
+	if _, err := m.GetCache(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if err := m.Shutdown(context.Background()); err != nil {
+		t.Fatalf("expected a clean shutdown, got %v", err)
+	}
+	if err := m.Close(); err != nil {
+		t.Fatalf("expected a second shutdown to be a no-op, got %v", err)
+	}
+	if _, err := m.GetCache(); !errors.Is(err, ErrCacheClosed) || !errors.Is(err, helpers.ErrShutdown) {
+		t.Fatalf("expected ErrCacheClosed after shutdown, got %v", err)
+	}
+
+	// - A manager that never built its cache has nothing to close
+	if err := BuildBigCacheManager(nil).Close(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
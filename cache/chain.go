@@ -0,0 +1,262 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/eko/gocache/lib/v4/cache"
+	"github.com/eko/gocache/lib/v4/store"
+	"github.com/grzegorzmaniak/gothic/helpers"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultChainLocalTTL caps how long entries are kept in the local tier, so changes made by other instances
+	// are picked up even without an InvalidationBus.
+	DefaultChainLocalTTL = 30 * time.Second
+
+	ChainCacheType = "gothic_chain"
+)
+
+// ChainInvalidation is published to the other instances when an entry is removed from the chain.
+type ChainInvalidation struct {
+	Key   string   `json:"key,omitempty"`
+	Tags  []string `json:"tags,omitempty"`
+	Clear bool     `json:"clear,omitempty"`
+}
+
+// InvalidationBus fans invalidations out to the local tiers of the other instances, e.g., over Redis pub/sub.
+// Instances receive their own invalidations as well, applying them twice is harmless.
+type InvalidationBus interface {
+	Publish(ctx context.Context, invalidation ChainInvalidation) error
+	Subscribe(ctx context.Context, handler func(invalidation ChainInvalidation)) error
+}
+
+type ChainCacheConfig struct {
+
+	// Local is the in-process tier (Default: a Ristretto cache with the BuildDefaultCacheManager(nil) config)
+	Local cache.SetterCacheInterface[[]byte]
+
+	// Remote is the shared tier, e.g., cache.New[[]byte](redisStore). It is required.
+	Remote cache.SetterCacheInterface[[]byte]
+
+	// LocalTTL caps the expiration of entries in the local tier (Default: DefaultChainLocalTTL)
+	LocalTTL time.Duration
+
+	// RemoteTTL is the expiration of remote entries set without one, zero leaves it to the remote store.
+	RemoteTTL time.Duration
+
+	// Invalidations publishes deletes, tag invalidations and clears to the other instances (Default: nil, local
+	// entries of other instances expire after LocalTTL)
+	Invalidations InvalidationBus
+}
+
+// ChainCacheManager combines a local and a remote cache, reads are served locally and fall back to the remote
+// tier, which backfills the local one. Keys are prefixed with the KeyNamespace in both tiers. Its GetCache can be returned by session and RBAC managers as is, or set as
+// a DefaultCacheManager's CacheInstance, so every cache user gets both tiers.
+type ChainCacheManager struct {
+	Config ChainCacheConfig
+
+	once     sync.Once
+	instance cache.CacheInterface[[]byte]
+	initErr  error
+
+	closeMu    sync.Mutex
+	closed     bool
+	closeStore func() error
+}
+
+// BuildChainCacheManager creates a ChainCacheManager, the tiers are set up on the first GetCache.
+func BuildChainCacheManager(config ChainCacheConfig) *ChainCacheManager {
+	return &ChainCacheManager{Config: config}
+}
+
+func (m *ChainCacheManager) GetCache() (cache.CacheInterface[[]byte], error) {
+	m.closeMu.Lock()
+	closed := m.closed
+	m.closeMu.Unlock()
+	if closed {
+		return nil, ErrCacheClosed
+	}
+
+	m.once.Do(func() {
+		chain, closeStore, err := newChainCache(m.Config)
+		if err != nil {
+			zap.L().Error("ChainCacheManager: Failed to initialize the cache tiers", zap.Error(err))
+			m.initErr = err
+			return
+		}
+
+		m.closeMu.Lock()
+		m.closeStore = closeStore
+		m.closeMu.Unlock()
+		m.instance = WithNamespace(chain, KeyNamespace())
+	})
+	if m.initErr != nil {
+		return nil, m.initErr
+	}
+	return m.instance, nil
+}
+
+// Shutdown closes the default local tier, the Local and Remote tiers given in the config are owned by the caller
+// and left open. GetCache returns ErrCacheClosed afterwards. Calling it more than once is safe.
+func (m *ChainCacheManager) Shutdown(_ context.Context) error {
+	m.closeMu.Lock()
+	defer m.closeMu.Unlock()
+	if m.closed {
+		return nil
+	}
+	m.closed = true
+
+	if m.closeStore == nil {
+		return nil
+	}
+	return m.closeStore()
+}
+
+// Close shuts down the cache, see Shutdown.
+func (m *ChainCacheManager) Close() error {
+	return m.Shutdown(context.Background())
+}
+
+// chainCache implements cache.CacheInterface over a local and a remote tier.
+type chainCache struct {
+	local         cache.SetterCacheInterface[[]byte]
+	remote        cache.SetterCacheInterface[[]byte]
+	localTTL      time.Duration
+	remoteTTL     time.Duration
+	invalidations InvalidationBus
+}
+
+// newChainCache builds the chain, closeStore closes the local tier when it was built here.
+func newChainCache(config ChainCacheConfig) (chain *chainCache, closeStore func() error, err error) {
+	if config.Remote == nil {
+		return nil, nil, fmt.Errorf("chain cache: a remote tier is required")
+	}
+
+	local := config.Local
+	if local == nil {
+		localStore, closeLocal, err := newRistrettoStore(BuildDefaultCacheManager(nil).CacheConfig)
+		if err != nil {
+			return nil, nil, fmt.Errorf("chain cache: failed to build the local tier: %w", err)
+		}
+		local = cache.New[[]byte](localStore)
+		closeStore = closeLocal
+	}
+
+	chain = &chainCache{
+		local:         local,
+		remote:        config.Remote,
+		localTTL:      helpers.DefaultTimeDuration(config.LocalTTL, DefaultChainLocalTTL),
+		remoteTTL:     config.RemoteTTL,
+		invalidations: config.Invalidations,
+	}
+
+	if chain.invalidations != nil {
+		if err := chain.invalidations.Subscribe(context.Background(), chain.applyInvalidation); err != nil {
+			if closeStore != nil {
+				_ = closeStore()
+			}
+			return nil, nil, fmt.Errorf("chain cache: failed to subscribe to invalidations: %w", err)
+		}
+	}
+	return chain, closeStore, nil
+}
+
+// localExpiration caps ttl to the local TTL, zero means the entry has no expiration of its own.
+func (c *chainCache) localExpiration(ttl time.Duration) time.Duration {
+	if ttl <= 0 || ttl > c.localTTL {
+		return c.localTTL
+	}
+	return ttl
+}
+
+func (c *chainCache) Get(ctx context.Context, key any) ([]byte, error) {
+	if value, err := c.local.Get(ctx, key); err == nil {
+		return value, nil
+	}
+
+	value, ttl, err := c.remote.GetWithTTL(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	// - Remote hits are kept locally for at most their remaining TTL
+	if setErr := c.local.Set(ctx, key, value, store.WithExpiration(c.localExpiration(ttl))); setErr != nil {
+		zap.L().Debug("ChainCacheManager: Failed to backfill the local tier", zap.Error(setErr))
+	}
+	return value, nil
+}
+
+func (c *chainCache) Set(ctx context.Context, key any, object []byte, options ...store.Option) error {
+	applied := store.ApplyOptions(options...)
+
+	remoteOptions := options
+	if applied.Expiration == 0 && c.remoteTTL > 0 {
+		remoteOptions = append(append([]store.Option(nil), options...), store.WithExpiration(c.remoteTTL))
+	}
+	if err := c.remote.Set(ctx, key, object, remoteOptions...); err != nil {
+		return fmt.Errorf("chain cache: failed to set the remote tier: %w", err)
+	}
+
+	localOptions := append(append([]store.Option(nil), options...), store.WithExpiration(c.localExpiration(applied.Expiration)))
+	if err := c.local.Set(ctx, key, object, localOptions...); err != nil {
+		return fmt.Errorf("chain cache: failed to set the local tier: %w", err)
+	}
+	return nil
+}
+
+func (c *chainCache) Delete(ctx context.Context, key any) error {
+	err := errors.Join(c.remote.Delete(ctx, key), c.local.Delete(ctx, key))
+	if keyString, ok := key.(string); ok {
+		c.publish(ctx, ChainInvalidation{Key: keyString})
+	}
+	return err
+}
+
+func (c *chainCache) Invalidate(ctx context.Context, options ...store.InvalidateOption) error {
+	err := errors.Join(c.remote.Invalidate(ctx, options...), c.local.Invalidate(ctx, options...))
+	if tags := store.ApplyInvalidateOptions(options...).Tags; len(tags) > 0 {
+		c.publish(ctx, ChainInvalidation{Tags: tags})
+	}
+	return err
+}
+
+func (c *chainCache) Clear(ctx context.Context) error {
+	err := errors.Join(c.remote.Clear(ctx), c.local.Clear(ctx))
+	c.publish(ctx, ChainInvalidation{Clear: true})
+	return err
+}
+
+func (c *chainCache) GetType() string {
+	return ChainCacheType
+}
+
+func (c *chainCache) publish(ctx context.Context, invalidation ChainInvalidation) {
+	if c.invalidations == nil {
+		return
+	}
+	if err := c.invalidations.Publish(ctx, invalidation); err != nil {
+		zap.L().Warn("ChainCacheManager: Failed to publish an invalidation, other instances keep their entries until LocalTTL", zap.Error(err))
+	}
+}
+
+// applyInvalidation removes an invalidated entry from the local tier only, the remote one is shared.
+func (c *chainCache) applyInvalidation(invalidation ChainInvalidation) {
+	ctx := context.Background()
+	var err error
+	switch {
+	case invalidation.Clear:
+		err = c.local.Clear(ctx)
+	case len(invalidation.Tags) > 0:
+		err = c.local.Invalidate(ctx, store.WithInvalidateTags(invalidation.Tags))
+	case invalidation.Key != "":
+		err = c.local.Delete(ctx, invalidation.Key)
+	}
+	if err != nil {
+		zap.L().Debug("ChainCacheManager: Failed to apply an invalidation", zap.Error(err))
+	}
+}
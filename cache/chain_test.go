@@ -0,0 +1,157 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eko/gocache/lib/v4/cache"
+	"github.com/eko/gocache/lib/v4/store"
+)
+
+type memoryInvalidationBus struct {
+	mu       sync.Mutex
+	handlers []func(ChainInvalidation)
+}
+
+func (b *memoryInvalidationBus) Publish(_ context.Context, invalidation ChainInvalidation) error {
+	b.mu.Lock()
+	handlers := b.handlers[:len(b.handlers):len(b.handlers)]
+	b.mu.Unlock()
+	for _, handler := range handlers {
+		handler(invalidation)
+	}
+	return nil
+}
+
+func (b *memoryInvalidationBus) Subscribe(_ context.Context, handler func(ChainInvalidation)) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, handler)
+	return nil
+}
+
+// recordingTier records the expiration of the last Set and reports ttl on reads.
+type recordingTier struct {
+	cache.SetterCacheInterface[[]byte]
+	ttl        time.Duration
+	expiration time.Duration
+}
+
+func (r *recordingTier) Set(ctx context.Context, key any, object []byte, options ...store.Option) error {
+	r.expiration = store.ApplyOptions(options...).Expiration
+	return r.SetterCacheInterface.Set(ctx, key, object, options...)
+}
+
+func (r *recordingTier) GetWithTTL(ctx context.Context, key any) ([]byte, time.Duration, error) {
+	value, _, err := r.SetterCacheInterface.GetWithTTL(ctx, key)
+	return value, r.ttl, err
+}
+
+func newRistrettoTier(t *testing.T) cache.SetterCacheInterface[[]byte] {
+	t.Helper()
+	instance, err := BuildDefaultCacheManager(nil).GetCache()
+	if err != nil {
+		t.Fatalf("expected cache, got %v", err)
+	}
+	return instance.(cache.SetterCacheInterface[[]byte])
+}
+
+func TestChainCacheManager_RequiresRemote(t *testing.T) {
+	if _, err := BuildChainCacheManager(ChainCacheConfig{}).GetCache(); err == nil {
+		t.Fatal("expected an error without a remote tier")
+	}
+}
+
+func TestChainCacheManager_ReadsThroughAndCapsLocalTTL(t *testing.T) {
+	ctx := context.Background()
+	local := &recordingTier{SetterCacheInterface: newRistrettoTier(t)}
+	remote := &recordingTier{SetterCacheInterface: newRistrettoTier(t), ttl: time.Hour}
+	chain, err := BuildChainCacheManager(ChainCacheConfig{Local: local, Remote: remote, LocalTTL: time.Minute}).GetCache()
+	if err != nil {
+		t.Fatalf("expected cache, got %v", err)
+	}
+
+	if err = remote.SetterCacheInterface.Set(ctx, "k", []byte("v")); err != nil {
+		t.Fatalf("remote set: %v", err)
+	}
+	time.Sleep(10 * time.Millisecond) // - Let the cache settle
+
+	if value, err := chain.Get(ctx, "k"); err != nil || string(value) != "v" {
+		t.Fatalf("expected a remote hit, got %q (%v)", value, err)
+	}
+	if local.expiration != time.Minute {
+		t.Fatalf("expected the backfill to be capped to a minute, got %v", local.expiration)
+	}
+	time.Sleep(10 * time.Millisecond) // - Let the cache settle
+
+	if value, err := local.Get(ctx, "k"); err != nil || string(value) != "v" {
+		t.Fatalf("expected the local tier to be backfilled, got %q (%v)", value, err)
+	}
+
+	if err = chain.Set(ctx, "s", []byte("x"), store.WithExpiration(time.Hour)); err != nil {
+		t.Fatalf("chain set: %v", err)
+	}
+	if remote.expiration != time.Hour || local.expiration != time.Minute {
+		t.Fatalf("expected per-tier TTLs of 1h and 1m, got %v and %v", remote.expiration, local.expiration)
+	}
+
+	if err = chain.Set(ctx, "short", []byte("x"), store.WithExpiration(time.Second)); err != nil {
+		t.Fatalf("chain set: %v", err)
+	}
+	if local.expiration != time.Second {
+		t.Fatalf("expected a TTL under the cap to be kept locally, got %v", local.expiration)
+	}
+}
+
+func TestChainCacheManager_FansOutInvalidations(t *testing.T) {
+	ctx := context.Background()
+	remote := newRistrettoTier(t)
+	bus := &memoryInvalidationBus{}
+
+	localA, localB := newRistrettoTier(t), newRistrettoTier(t)
+	chainA, err := BuildChainCacheManager(ChainCacheConfig{Local: localA, Remote: remote, Invalidations: bus}).GetCache()
+	if err != nil {
+		t.Fatalf("expected cache, got %v", err)
+	}
+	chainB, err := BuildChainCacheManager(ChainCacheConfig{Local: localB, Remote: remote, Invalidations: bus}).GetCache()
+	if err != nil {
+		t.Fatalf("expected cache, got %v", err)
+	}
+
+	if err = chainA.Set(ctx, "k", []byte("v")); err != nil {
+		t.Fatalf("chain set: %v", err)
+	}
+	time.Sleep(10 * time.Millisecond) // - Let the cache settle
+
+	if value, err := chainB.Get(ctx, "k"); err != nil || string(value) != "v" {
+		t.Fatalf("expected instance B to read through, got %q (%v)", value, err)
+	}
+	time.Sleep(10 * time.Millisecond) // - Let the cache settle
+
+	if err = chainA.Delete(ctx, "k"); err != nil {
+		t.Fatalf("chain delete: %v", err)
+	}
+	time.Sleep(10 * time.Millisecond) // - Let the cache settle
+
+	if _, err = localB.Get(ctx, "k"); err == nil {
+		t.Fatal("expected the delete to be fanned out to instance B's local tier")
+	}
+	if _, err = chainB.Get(ctx, "k"); err == nil {
+		t.Fatal("expected the key to be gone from both tiers")
+	}
+}
+
+func TestDefaultCacheManager_KeepsPresetInstance(t *testing.T) {
+	chain, err := BuildChainCacheManager(ChainCacheConfig{Remote: newRistrettoTier(t)}).GetCache()
+	if err != nil {
+		t.Fatalf("expected cache, got %v", err)
+	}
+
+	m := BuildDefaultCacheManager(nil)
+	m.CacheInstance = chain
+	if instance, err := m.GetCache(); err != nil || instance.GetType() != ChainCacheType {
+		t.Fatalf("expected the preset chain cache, got %v (%v)", instance, err)
+	}
+}
@@ -0,0 +1,145 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/eko/gocache/lib/v4/cache"
+	"github.com/eko/gocache/lib/v4/store"
+)
+
+// NamespaceSeparator separates the namespace from the key, e.g., "billing:bearer_token:<tokenIdentifier>".
+const NamespaceSeparator = ":"
+
+var keyNamespace atomic.Value
+
+// SetKeyNamespace sets the namespace prefixed to every key and tag of the caches created afterwards by
+// DefaultCacheManager (including BuildMemcachedCacheManager and BuildBigCacheManager) and ChainCacheManager, so
+// applications sharing a store, e.g., a Redis, never read each other's bearer tokens or RBAC entries. Call it once
+// at startup, before any manager's GetCache. An empty namespace (the default) keeps the bare keys.
+func SetKeyNamespace(namespace string) {
+	keyNamespace.Store(namespace)
+}
+
+// KeyNamespace returns the namespace set with SetKeyNamespace.
+func KeyNamespace() string {
+	namespace, _ := keyNamespace.Load().(string)
+	return namespace
+}
+
+// NamespacedKey prefixes key with namespace, keys are returned as is without one.
+func NamespacedKey(namespace string, key string) string {
+	if namespace == "" {
+		return key
+	}
+	return namespace + NamespaceSeparator + key
+}
+
+// WithNamespace wraps instance so every key and tag is prefixed with namespace, e.g., for the caches returned by
+// custom session or RBAC managers. Clear is passed through and still clears the whole store.
+func WithNamespace(instance cache.CacheInterface[[]byte], namespace string) cache.CacheInterface[[]byte] {
+	if instance == nil || namespace == "" {
+		return instance
+	}
+	return &namespacedCache{instance: instance, namespace: namespace}
+}
+
+type namespacedCache struct {
+	instance  cache.CacheInterface[[]byte]
+	namespace string
+}
+
+func (c *namespacedCache) key(key any) any {
+	if keyString, ok := key.(string); ok {
+		return NamespacedKey(c.namespace, keyString)
+	}
+	return key
+}
+
+func (c *namespacedCache) tags(tags []string) []string {
+	namespaced := make([]string, len(tags))
+	for i, tag := range tags {
+		namespaced[i] = NamespacedKey(c.namespace, tag)
+	}
+	return namespaced
+}
+
+func (c *namespacedCache) Get(ctx context.Context, key any) ([]byte, error) {
+	return c.instance.Get(ctx, c.key(key))
+}
+
+func (c *namespacedCache) Set(ctx context.Context, key any, object []byte, options ...store.Option) error {
+	if tags := store.ApplyOptions(options...).Tags; len(tags) > 0 {
+		options = append(append([]store.Option(nil), options...), store.WithTags(c.tags(tags)))
+	}
+	return c.instance.Set(ctx, c.key(key), object, options...)
+}
+
+func (c *namespacedCache) Delete(ctx context.Context, key any) error {
+	return c.instance.Delete(ctx, c.key(key))
+}
+
+func (c *namespacedCache) Invalidate(ctx context.Context, options ...store.InvalidateOption) error {
+	if tags := store.ApplyInvalidateOptions(options...).Tags; len(tags) > 0 {
+		options = append(append([]store.InvalidateOption(nil), options...), store.WithInvalidateTags(c.tags(tags)))
+	}
+	return c.instance.Invalidate(ctx, options...)
+}
+
+func (c *namespacedCache) Clear(ctx context.Context) error {
+	return c.instance.Clear(ctx)
+}
+
+func (c *namespacedCache) GetType() string {
+	return c.instance.GetType()
+}
+
+// MigrateNamespace moves keys from one namespace to another in the unwrapped instance, e.g., the bearer tokens and
+// RBAC entries stored under bare keys before SetKeyNamespace was introduced, so sessions are not re-verified all at
+// once. Stores can not list their keys, the caller passes them (e.g., from a Redis SCAN). Remaining TTLs are kept
+// when the store reports them, otherwise ttl is used. Missing keys are skipped and the errors of the others joined.
+func MigrateNamespace(ctx context.Context, instance cache.CacheInterface[[]byte], from string, to string, ttl time.Duration, keys ...string) error {
+	if instance == nil {
+		return fmt.Errorf("cache: instance is nil, can not migrate keys")
+	}
+	if from == to {
+		return nil
+	}
+
+	var errs []error
+	for _, key := range keys {
+		oldKey, newKey := NamespacedKey(from, key), NamespacedKey(to, key)
+
+		value, remaining, err := getWithTTL(ctx, instance, oldKey)
+		if err != nil {
+			continue
+		}
+		if remaining <= 0 {
+			remaining = ttl
+		}
+
+		var options []store.Option
+		if remaining > 0 {
+			options = append(options, store.WithExpiration(remaining))
+		}
+		if err = instance.Set(ctx, newKey, value, options...); err != nil {
+			errs = append(errs, fmt.Errorf("key '%s': %w", key, err))
+			continue
+		}
+		if err = instance.Delete(ctx, oldKey); err != nil {
+			errs = append(errs, fmt.Errorf("key '%s': %w", key, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func getWithTTL(ctx context.Context, instance cache.CacheInterface[[]byte], key string) ([]byte, time.Duration, error) {
+	if setter, ok := instance.(cache.SetterCacheInterface[[]byte]); ok {
+		return setter.GetWithTTL(ctx, key)
+	}
+	value, err := instance.Get(ctx, key)
+	return value, 0, err
+}
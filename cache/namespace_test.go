@@ -0,0 +1,107 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/eko/gocache/lib/v4/cache"
+	"github.com/eko/gocache/lib/v4/store"
+)
+
+func newRawCache(t *testing.T) cache.CacheInterface[[]byte] {
+	t.Helper()
+	raw, closeStore, err := newRistrettoStore(BuildDefaultCacheManager(nil).CacheConfig)
+	if err != nil {
+		t.Fatalf("expected store, got %v", err)
+	}
+	t.Cleanup(func() { _ = closeStore() })
+	return cache.New[[]byte](raw)
+}
+
+func TestWithNamespace_PrefixesKeysAndTags(t *testing.T) {
+	ctx := context.Background()
+	raw := newRawCache(t)
+	billing, shop := WithNamespace(raw, "billing"), WithNamespace(raw, "shop")
+
+	if err := billing.Set(ctx, "bearer_token:abc", []byte("billing"), store.WithTags([]string{"subject:1"})); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+	if err := shop.Set(ctx, "bearer_token:abc", []byte("shop"), store.WithTags([]string{"subject:1"})); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+	time.Sleep(10 * time.Millisecond) // - Let the cache settle
+
+	if value, err := raw.Get(ctx, "billing:bearer_token:abc"); err != nil || string(value) != "billing" {
+		t.Fatalf("expected the namespaced raw key, got %q (%v)", value, err)
+	}
+	if value, err := shop.Get(ctx, "bearer_token:abc"); err != nil || string(value) != "shop" {
+		t.Fatalf("expected namespaces not to collide, got %q (%v)", value, err)
+	}
+
+	if err := billing.Invalidate(ctx, store.WithInvalidateTags([]string{"subject:1"})); err != nil {
+		t.Fatalf("invalidate: %v", err)
+	}
+	time.Sleep(10 * time.Millisecond) // - Let the cache settle
+
+	if _, err := billing.Get(ctx, "bearer_token:abc"); err == nil {
+		t.Fatalf("expected the tag invalidation to drop the billing entry")
+	}
+	if _, err := shop.Get(ctx, "bearer_token:abc"); err != nil {
+		t.Fatalf("expected the shop entry to survive another namespace's invalidation, got %v", err)
+	}
+
+	if WithNamespace(raw, "") != raw {
+		t.Fatalf("expected an empty namespace to return the instance as is")
+	}
+}
+
+func TestSetKeyNamespace_AppliesToManagers(t *testing.T) {
+	SetKeyNamespace("billing")
+	t.Cleanup(func() { SetKeyNamespace("") })
+
+	ctx := context.Background()
+	c, err := BuildDefaultCacheManager(nil).GetCache()
+	if err != nil {
+		t.Fatalf("expected cache, got %v", err)
+	}
+	if _, ok := c.(*namespacedCache); !ok {
+		t.Fatalf("expected the default manager to be namespaced, got %T", c)
+	}
+
+	remote := newRawCache(t).(cache.SetterCacheInterface[[]byte])
+	chain, err := BuildChainCacheManager(ChainCacheConfig{Remote: remote}).GetCache()
+	if err != nil {
+		t.Fatalf("expected cache, got %v", err)
+	}
+	if err = chain.Set(ctx, "role_perms:admin", []byte("v")); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+	time.Sleep(10 * time.Millisecond) // - Let the cache settle
+
+	if _, err = remote.Get(ctx, "billing:role_perms:admin"); err != nil {
+		t.Fatalf("expected the remote tier to hold the namespaced key, got %v", err)
+	}
+}
+
+func TestMigrateNamespace(t *testing.T) {
+	ctx := context.Background()
+	raw := newRawCache(t)
+
+	if err := raw.Set(ctx, "bearer_token:abc", []byte("v")); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+	time.Sleep(10 * time.Millisecond) // - Let the cache settle
+
+	if err := MigrateNamespace(ctx, raw, "", "billing", time.Minute, "bearer_token:abc", "bearer_token:missing"); err != nil {
+		t.Fatalf("expected missing keys to be skipped, got %v", err)
+	}
+	time.Sleep(10 * time.Millisecond) // - Let the cache settle
+
+	if value, err := WithNamespace(raw, "billing").Get(ctx, "bearer_token:abc"); err != nil || string(value) != "v" {
+		t.Fatalf("expected the key under the new namespace, got %q (%v)", value, err)
+	}
+	if _, err := raw.Get(ctx, "bearer_token:abc"); err == nil {
+		t.Fatalf("expected the bare key to be removed")
+	}
+}
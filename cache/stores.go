@@ -0,0 +1,162 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/allegro/bigcache/v3"
+	"github.com/bradfitz/gomemcache/memcache"
+	"github.com/eko/gocache/lib/v4/store"
+	bigcacheStore "github.com/eko/gocache/store/bigcache/v4"
+	memcacheStore "github.com/eko/gocache/store/memcache/v4"
+	"github.com/grzegorzmaniak/gothic/helpers"
+)
+
+const (
+	DefaultMemcachedServer = "127.0.0.1:11211"
+
+	// MemcachedMaxKeyLength is the longest key memcached accepts, longer keys are hashed.
+	MemcachedMaxKeyLength    = 250
+	MemcachedHashedKeyPrefix = "sha256:" // Key: sha256:<hex of the original key>
+
+	DefaultBigCacheShards      = 1024
+	DefaultBigCacheCleanWindow = time.Minute
+	DefaultBigCacheEntrySize   = 512
+)
+
+type MemcachedCacheConfig struct {
+
+	// Servers are the memcached addresses, keys are spread across them (Default: DefaultMemcachedServer)
+	Servers []string
+
+	// Timeout is the socket read/write timeout (Default: memcache.DefaultTimeout, 500ms)
+	Timeout time.Duration
+
+	// MaxIdleConns is the number of idle connections kept per server (Default: memcache.DefaultMaxIdleConns, 2)
+	MaxIdleConns int
+
+	// DefaultExpiration is used when Set() is not given a store.WithExpiration option
+	// (Default: DefaultStoreExpirationForRistrettoAdapter)
+	DefaultExpiration time.Duration
+}
+
+type BigCacheConfig struct {
+
+	// LifeWindow is how long every entry is kept. BigCache has no per-entry expiration, so the TTLs given to Set()
+	// are ignored: keep it at or below the shortest TTL the cache is used with, e.g., the RBAC and bearer TTLs
+	// (Default: DefaultStoreExpirationForRistrettoAdapter)
+	LifeWindow time.Duration
+
+	// CleanWindow is the interval expired entries are removed at (Default: DefaultBigCacheCleanWindow)
+	CleanWindow time.Duration
+
+	// Shards is the number of shards, it must be a power of two (Default: DefaultBigCacheShards)
+	Shards int
+
+	// MaxEntrySize is the expected entry size in bytes, used to size the shards up front
+	// (Default: DefaultBigCacheEntrySize)
+	MaxEntrySize int
+
+	// HardMaxCacheSizeMB caps the memory used by the cache, oldest entries are overwritten first (Default: 0, unlimited)
+	HardMaxCacheSizeMB int
+}
+
+// BuildMemcachedCacheManager creates a cache manager storing entries in memcached. Keys memcached would reject,
+// longer than MemcachedMaxKeyLength or containing spaces and control characters, are stored under their hash.
+// The servers are only contacted on the first cache operation.
+func BuildMemcachedCacheManager(config *MemcachedCacheConfig) *DefaultCacheManager {
+	if config == nil {
+		config = &MemcachedCacheConfig{}
+	}
+	cfg := *config
+
+	return &DefaultCacheManager{
+		newStore: func() (store.StoreInterface, func() error, error) {
+			servers := cfg.Servers
+			if len(servers) == 0 {
+				servers = []string{DefaultMemcachedServer}
+			}
+
+			client := memcache.New(servers...)
+			client.Timeout = helpers.DefaultTimeDuration(cfg.Timeout, memcache.DefaultTimeout)
+			client.MaxIdleConns = helpers.DefaultInt(cfg.MaxIdleConns, memcache.DefaultMaxIdleConns)
+
+			return &memcachedKeyStore{StoreInterface: memcacheStore.NewMemcache(
+				client,
+				store.WithExpiration(helpers.DefaultTimeDuration(cfg.DefaultExpiration, DefaultStoreExpirationForRistrettoAdapter)),
+			)}, client.Close, nil
+		},
+	}
+}
+
+// BuildBigCacheManager creates a cache manager storing entries in an in-process BigCache, which keeps them off the
+// garbage collected heap. Every entry expires after the same LifeWindow, see BigCacheConfig.
+func BuildBigCacheManager(config *BigCacheConfig) *DefaultCacheManager {
+	if config == nil {
+		config = &BigCacheConfig{}
+	}
+	cfg := *config
+
+	return &DefaultCacheManager{
+		newStore: func() (store.StoreInterface, func() error, error) {
+			bigcacheConfig := bigcache.DefaultConfig(helpers.DefaultTimeDuration(cfg.LifeWindow, DefaultStoreExpirationForRistrettoAdapter))
+			bigcacheConfig.CleanWindow = helpers.DefaultTimeDuration(cfg.CleanWindow, DefaultBigCacheCleanWindow)
+			bigcacheConfig.Shards = helpers.DefaultInt(cfg.Shards, DefaultBigCacheShards)
+			bigcacheConfig.MaxEntrySize = helpers.DefaultInt(cfg.MaxEntrySize, DefaultBigCacheEntrySize)
+			bigcacheConfig.HardMaxCacheSize = cfg.HardMaxCacheSizeMB
+			bigcacheConfig.Verbose = false
+
+			client, err := bigcache.New(context.Background(), bigcacheConfig)
+			if err != nil {
+				return nil, nil, err
+			}
+			return bigcacheStore.NewBigcache(client), client.Close, nil
+		},
+	}
+}
+
+// memcachedKeyStore hashes the keys memcached would reject, so every key used by the other modules, e.g.,
+// response cache keys containing the route, can be stored.
+type memcachedKeyStore struct {
+	store.StoreInterface
+}
+
+func memcachedKey(key any) any {
+	keyString, ok := key.(string)
+	if !ok {
+		return key
+	}
+	if len(keyString) <= MemcachedMaxKeyLength {
+		legal := true
+		for i := 0; i < len(keyString); i++ {
+			if keyString[i] <= ' ' || keyString[i] == 0x7f {
+				legal = false
+				break
+			}
+		}
+		if legal {
+			return keyString
+		}
+	}
+
+	hash := sha256.Sum256([]byte(keyString))
+	return MemcachedHashedKeyPrefix + hex.EncodeToString(hash[:])
+}
+
+func (s *memcachedKeyStore) Get(ctx context.Context, key any) (any, error) {
+	return s.StoreInterface.Get(ctx, memcachedKey(key))
+}
+
+func (s *memcachedKeyStore) GetWithTTL(ctx context.Context, key any) (any, time.Duration, error) {
+	return s.StoreInterface.GetWithTTL(ctx, memcachedKey(key))
+}
+
+func (s *memcachedKeyStore) Set(ctx context.Context, key any, value any, options ...store.Option) error {
+	return s.StoreInterface.Set(ctx, memcachedKey(key), value, options...)
+}
+
+func (s *memcachedKeyStore) Delete(ctx context.Context, key any) error {
+	return s.StoreInterface.Delete(ctx, memcachedKey(key))
+}
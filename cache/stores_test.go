@@ -0,0 +1,101 @@
+package cache
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/ristretto"
+	"github.com/eko/gocache/lib/v4/store"
+	ristrettoStore "github.com/eko/gocache/store/ristretto/v4"
+)
+
+func TestBuildBigCacheManager_SetGetDelete(t *testing.T) {
+	ctx := context.Background()
+	m := BuildBigCacheManager(&BigCacheConfig{LifeWindow: time.Minute, Shards: 16})
+
+	c, err := m.GetCache()
+	if err != nil {
+		t.Fatalf("expected cache, got %v", err)
+	}
+	if again, _ := m.GetCache(); again != c {
+		t.Fatalf("expected the same instance on every call")
+	}
+
+	if err = c.Set(ctx, "response_cache:GET /notes/:id", []byte("v"), store.WithExpiration(time.Second)); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+	if value, err := c.Get(ctx, "response_cache:GET /notes/:id"); err != nil || string(value) != "v" {
+		t.Fatalf("expected v, got %q (%v)", value, err)
+	}
+	if err = c.Delete(ctx, "response_cache:GET /notes/:id"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err = c.Get(ctx, "response_cache:GET /notes/:id"); err == nil {
+		t.Fatalf("expected a miss after delete")
+	}
+}
+
+func TestBuildBigCacheManager_InvalidConfig(t *testing.T) {
+	if _, err := BuildBigCacheManager(&BigCacheConfig{Shards: 3}).GetCache(); err == nil {
+		t.Fatalf("expected a shard count that is not a power of two to fail")
+	}
+}
+
+func TestBuildMemcachedCacheManager_BuildsLazily(t *testing.T) {
+	// - No server is contacted until the first operation
+	m := BuildMemcachedCacheManager(&MemcachedCacheConfig{Servers: []string{"127.0.0.1:1"}})
+	c, err := m.GetCache()
+	if err != nil || c == nil {
+		t.Fatalf("expected cache, got %v", err)
+	}
+	if _, err = c.Get(context.Background(), "k"); err == nil {
+		t.Fatalf("expected an unreachable server to fail the read")
+	}
+}
+
+func TestMemcachedKey(t *testing.T) {
+	if key := memcachedKey("rbac:subject:42"); key != "rbac:subject:42" {
+		t.Fatalf("expected legal keys to be kept, got %v", key)
+	}
+
+	for _, illegal := range []string{"response_cache:GET /notes", "tab\tkey", strings.Repeat("k", MemcachedMaxKeyLength+1)} {
+		key := memcachedKey(illegal).(string)
+		if !strings.HasPrefix(key, MemcachedHashedKeyPrefix) || len(key) > MemcachedMaxKeyLength {
+			t.Fatalf("expected %q to be hashed, got %q", illegal, key)
+		}
+		if memcachedKey(illegal) != key {
+			t.Fatalf("expected hashing to be stable")
+		}
+	}
+}
+
+func TestMemcachedKeyStore_HashesEveryOperation(t *testing.T) {
+	ctx := context.Background()
+	client, err := ristretto.NewCache(&ristretto.Config{NumCounters: 1000, MaxCost: 100, BufferItems: 64})
+	if err != nil {
+		t.Fatalf("expected ristretto client, got %v", err)
+	}
+	inner := ristrettoStore.NewRistretto(client)
+	s := &memcachedKeyStore{StoreInterface: inner}
+	key := "response_cache:GET /notes/:id"
+
+	if err = s.Set(ctx, key, []byte("v")); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+	time.Sleep(10 * time.Millisecond) // - Let the cache settle
+
+	if _, err = inner.Get(ctx, memcachedKey(key)); err != nil {
+		t.Fatalf("expected the value under the hashed key, got %v", err)
+	}
+	if value, err := s.Get(ctx, key); err != nil || string(value.([]byte)) != "v" {
+		t.Fatalf("expected v, got %v (%v)", value, err)
+	}
+	if err = s.Delete(ctx, key); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err = s.Get(ctx, key); err == nil {
+		t.Fatalf("expected a miss after delete")
+	}
+}
@@ -0,0 +1,220 @@
+// Package codegen generates typed API clients from the OpenAPI document of the registered routes, see
+// core.GenerateOpenAPI. Routes are registered at runtime, so the generator is usually called from a small
+// go:generate program that builds the router and writes the client:
+//
+//	document := core.GenerateOpenAPI(ctor, core.OpenAPIConfig{})
+//	source, err := codegen.GoClient(document, codegen.Config{Package: "apiclient"})
+package codegen
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/grzegorzmaniak/gothic/core"
+	"github.com/grzegorzmaniak/gothic/helpers"
+)
+
+const (
+	DefaultPackage    = "client"
+	DefaultClientName = "Client"
+
+	generatedHeader = "Code generated by gothic codegen. DO NOT EDIT."
+)
+
+// Config configures the generated client.
+type Config struct {
+	// Package of the generated Go client (Default: DefaultPackage)
+	Package string
+
+	// ClientName is the name of the generated client type / class (Default: DefaultClientName)
+	ClientName string
+}
+
+func (config Config) withDefaults() Config {
+	config.Package = helpers.DefaultString(config.Package, DefaultPackage)
+	config.ClientName = helpers.DefaultString(config.ClientName, DefaultClientName)
+	return config
+}
+
+// parameter is a path, query or header parameter of an operation.
+type parameter struct {
+	Name     string // Name on the wire
+	Field    string // Exported identifier
+	In       string
+	Required bool
+	Schema   *core.OpenAPISchema
+}
+
+// operation is the language neutral description of a route the generators emit a method for.
+type operation struct {
+	Name         string // Exported identifier, from the operationId
+	Method       string
+	Path         string
+	Parameters   []parameter
+	Body         *core.OpenAPISchema
+	BodyRequired bool
+	Response     *core.OpenAPISchema
+	Csrf         bool
+}
+
+// HasInput reports whether the generated method takes an input.
+func (op operation) HasInput() bool {
+	return len(op.Parameters) > 0 || op.Body != nil
+}
+
+// securityNames are the wire names of the bearer and CSRF headers.
+type securityNames struct {
+	Bearer string
+	Csrf   string
+}
+
+func documentSecurity(document *core.OpenAPIDocument) securityNames {
+	names := securityNames{Bearer: core.DefaultSessionAuthorizationHeaderName, Csrf: core.DefaultCsrfCookieName}
+	if scheme := document.Components.SecuritySchemes[core.OpenAPIBearerScheme]; scheme != nil {
+		names.Bearer = helpers.DefaultString(scheme.Name, names.Bearer)
+	}
+	if scheme := document.Components.SecuritySchemes[core.OpenAPICsrfTokenScheme]; scheme != nil {
+		names.Csrf = helpers.DefaultString(scheme.Name, names.Csrf)
+	}
+	return names
+}
+
+// documentOperations returns the operations of the document sorted by path and method.
+func documentOperations(document *core.OpenAPIDocument) []operation {
+	paths := make([]string, 0, len(document.Paths))
+	for path := range document.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var operations []operation
+	for _, path := range paths {
+		item := document.Paths[path]
+		methods := make([]string, 0, len(item))
+		for method := range item {
+			methods = append(methods, method)
+		}
+		sort.Strings(methods)
+
+		for _, method := range methods {
+			operations = append(operations, newOperation(strings.ToUpper(method), path, item[method]))
+		}
+	}
+	return operations
+}
+
+func newOperation(method, path string, source *core.OpenAPIOperation) operation {
+	op := operation{
+		Name:   exportedName(helpers.DefaultString(source.OperationID, strings.ToLower(method)+" "+path)),
+		Method: method,
+		Path:   path,
+	}
+
+	for _, param := range source.Parameters {
+		op.Parameters = append(op.Parameters, parameter{
+			Name:     param.Name,
+			Field:    exportedName(param.Name),
+			In:       param.In,
+			Required: param.Required || param.In == "path",
+			Schema:   param.Schema,
+		})
+	}
+
+	if source.RequestBody != nil {
+		if media := source.RequestBody.Content["application/json"]; media != nil {
+			op.Body = media.Schema
+			op.BodyRequired = source.RequestBody.Required
+		}
+	}
+
+	if success := source.Responses["200"]; success != nil {
+		if media := success.Content["application/json"]; media != nil {
+			op.Response = media.Schema
+		}
+	}
+
+	for _, requirement := range source.Security {
+		if _, ok := requirement[core.OpenAPICsrfTokenScheme]; ok {
+			op.Csrf = true
+		}
+	}
+	return op
+}
+
+// pathSegments splits an OpenAPI path into literal and parameter segments, parameters are returned as
+// their name with isParam set.
+func pathSegments(path string) (segments []string, isParam []bool) {
+	for path != "" {
+		start := strings.Index(path, "{")
+		end := strings.Index(path, "}")
+		if start < 0 || end < start {
+			segments, isParam = append(segments, path), append(isParam, false)
+			break
+		}
+		if start > 0 {
+			segments, isParam = append(segments, path[:start]), append(isParam, false)
+		}
+		segments, isParam = append(segments, path[start+1:end]), append(isParam, true)
+		path = path[end+1:]
+	}
+	return segments, isParam
+}
+
+// componentName returns the component a schema references, or "".
+func componentName(schema *core.OpenAPISchema) string {
+	if schema == nil {
+		return ""
+	}
+	return strings.TrimPrefix(schema.Ref, "#/components/schemas/")
+}
+
+// exportedName converts a wire name to an exported identifier, e.g., x-tenant -> XTenant.
+func exportedName(name string) string {
+	var builder strings.Builder
+	upper := true
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if builder.Len() == 0 && unicode.IsDigit(r) {
+			builder.WriteString("X")
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		builder.WriteRune(r)
+	}
+	if builder.Len() == 0 {
+		return "X"
+	}
+	return builder.String()
+}
+
+// unexportedName lower cases the first letter of an identifier, e.g., for TypeScript properties.
+func unexportedName(name string) string {
+	name = exportedName(name)
+	runes := []rune(name)
+	runes[0] = unicode.ToLower(runes[0])
+	return string(runes)
+}
+
+func sortedKeys[V any](values map[string]V) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func containsString(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,159 @@
+package codegen
+
+import (
+	"context"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+	"testing"
+
+	"github.com/eko/gocache/lib/v4/cache"
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/core"
+	"github.com/grzegorzmaniak/gothic/errors"
+	"github.com/grzegorzmaniak/gothic/rbac"
+)
+
+type testRoute struct{}
+
+// testSessionManager only provides the configuration the document is generated from.
+type testSessionManager struct {
+	core.DefaultSessionManager
+}
+
+func (m *testSessionManager) GetAuthorizationConfiguration() *core.SessionAuthorizationConfiguration {
+	return &core.SessionAuthorizationConfiguration{AuthorizationHeaderName: "Authorization"}
+}
+func (m *testSessionManager) GetCsrfData() *core.CsrfCookieData { return &core.CsrfCookieData{} }
+func (m *testSessionManager) GetSessionKey() ([]byte, string, error) {
+	return nil, "", nil
+}
+func (m *testSessionManager) GetOldSessionKey(string) ([]byte, error) { return nil, nil }
+func (m *testSessionManager) VerifySession(context.Context, *core.SessionClaims, *core.SessionHeader) (bool, error) {
+	return true, nil
+}
+func (m *testSessionManager) StoreSession(context.Context, *core.SessionClaims, *core.SessionHeader) error {
+	return nil
+}
+func (m *testSessionManager) GetRbacManager() rbac.Manager { return nil }
+func (m *testSessionManager) GetSubjectIdentifier(*core.SessionClaims) (string, error) {
+	return "", nil
+}
+func (m *testSessionManager) GetCache() (cache.CacheInterface[[]byte], error) { return nil, nil }
+
+type orderInput struct {
+	ID       string   `uri:"id"`
+	Tenant   string   `header:"X-Tenant"`
+	Quantity int      `json:"quantity" validate:"required"`
+	Tags     []string `json:"tags"`
+}
+
+type order struct {
+	ID       string `json:"id"`
+	Quantity int64  `json:"quantity"`
+	Lines    []struct {
+		SKU string `json:"sku"`
+	} `json:"lines"`
+}
+
+type listInput struct {
+	Page   int    `form:"page"`
+	Status string `form:"status" validate:"omitempty,oneof=open closed"`
+}
+
+type orderList struct {
+	Orders []order `json:"orders"`
+}
+
+func testDocument(t *testing.T) *core.OpenAPIDocument {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	ctor := core.NewRouteConstructor(gin.New(), testRoute{}, &testSessionManager{}, nil)
+
+	core.PUT(ctor, "/orders/:id", core.AuthenticatedJSONAPI(), func(_ *orderInput, _ *core.Handler[testRoute]) (*order, *errors.AppError) {
+		return nil, nil
+	})
+	core.GET(ctor, "/orders", core.PublicRoute(), func(_ *listInput, _ *core.Handler[testRoute]) (*orderList, *errors.AppError) {
+		return nil, nil
+	})
+	core.DELETE(ctor, "/orders/:id", core.AuthenticatedJSONAPI().WithManualResponse(), func(_ *struct{}, _ *core.Handler[testRoute]) (*struct{}, *errors.AppError) {
+		return nil, nil
+	})
+	return core.GenerateOpenAPI(ctor, core.OpenAPIConfig{})
+}
+
+func TestGoClient(t *testing.T) {
+	source, err := GoClient(testDocument(t), Config{Package: "apiclient", ClientName: "OrdersClient"})
+	if err != nil {
+		t.Fatalf("Failed to generate the client: %v", err)
+	}
+
+	// - The client has to type check against the standard library
+	fileSet := token.NewFileSet()
+	file, err := parser.ParseFile(fileSet, "client.go", source, parser.ParseComments)
+	if err != nil {
+		t.Fatalf("Generated client does not parse: %v\n%s", err, source)
+	}
+	config := types.Config{Importer: importer.ForCompiler(fileSet, "source", nil)}
+	pkg, err := config.Check("apiclient", fileSet, []*ast.File{file}, nil)
+	if err != nil {
+		t.Fatalf("Generated client does not type check: %v\n%s", err, source)
+	}
+
+	client, ok := pkg.Scope().Lookup("OrdersClient").Type().(*types.Named)
+	if !ok {
+		t.Fatal("Expected the OrdersClient type")
+	}
+	methods := map[string]string{}
+	for i := 0; i < client.NumMethods(); i++ {
+		methods[client.Method(i).Name()] = client.Method(i).Type().String()
+	}
+	expected := map[string]string{
+		"PutOrdersId":    "func(ctx context.Context, input *apiclient.PutOrdersIdInput) (*apiclient.Order, error)",
+		"GetOrders":      "func(ctx context.Context, input *apiclient.GetOrdersInput) (*apiclient.OrderList, error)",
+		"DeleteOrdersId": "func(ctx context.Context, input *apiclient.DeleteOrdersIdInput) error",
+	}
+	for name, signature := range expected {
+		if methods[name] != signature {
+			t.Errorf("Expected %s to be %q, got %q", name, signature, methods[name])
+		}
+	}
+
+	for _, fragment := range []string{
+		`BearerHeader = "Authorization"`,
+		`appendParam(header, http.CanonicalHeaderKey("X-Tenant"), input.XTenant)`,
+		`"/orders/" + url.PathEscape(formatParam(input.Id))`,
+		`c.do(ctx, "PUT", path, nil, header, input.Body, &output, true)`,
+		`c.do(ctx, "GET", path, query, nil, nil, &output, false)`,
+		"Lines    []OrderLinesItem `json:\"lines,omitempty\"`",
+	} {
+		if !strings.Contains(string(source), fragment) {
+			t.Errorf("Expected the client to contain %q\n%s", fragment, source)
+		}
+	}
+}
+
+func TestTypeScriptClient(t *testing.T) {
+	source, err := TypeScriptClient(testDocument(t), Config{})
+	if err != nil {
+		t.Fatalf("Failed to generate the client: %v", err)
+	}
+
+	for _, fragment := range []string{
+		`export const BEARER_HEADER = "Authorization";`,
+		"export interface Order {",
+		"export class Client {",
+		"export interface PutOrdersIdInput {\n  id: string;\n  xTenant?: string;\n  body: {",
+		`status?: "open" | "closed";`,
+		"async putOrdersId(input: PutOrdersIdInput): Promise<Order> {",
+		"return this.request<Order>(\"PUT\", `/orders/${encodeURIComponent(String(input.id))}`, {  }, { \"X-Tenant\": input.xTenant }, input.body, true);",
+		"async deleteOrdersId(input: DeleteOrdersIdInput): Promise<void> {",
+	} {
+		if !strings.Contains(string(source), fragment) {
+			t.Errorf("Expected the client to contain %q\n%s", fragment, source)
+		}
+	}
+}
@@ -0,0 +1,306 @@
+package codegen
+
+import (
+	"fmt"
+	"go/format"
+	"strconv"
+	"strings"
+
+	"github.com/grzegorzmaniak/gothic/core"
+)
+
+// goRuntime is the transport shared by the generated methods, it only depends on the standard library.
+const goRuntime = `
+// APIError is returned for responses with an error status, Message and Details are decoded from the body.
+type APIError struct {
+	Status  int             ` + "`json:\"-\"`" + `
+	Message string          ` + "`json:\"error\"`" + `
+	Details json.RawMessage ` + "`json:\"details,omitempty\"`" + `
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("api error %d: %s", e.Status, e.Message)
+}
+
+// {{Client}} calls the API. Cookie sessions need an HTTPClient with a cookie jar (NewClient sets one up), the
+// CSRF cookie is echoed in the CsrfHeader of routes that require it. Set BearerToken for bearer sessions.
+type {{Client}} struct {
+	BaseURL     string
+	HTTPClient  *http.Client
+	BearerToken string
+}
+
+// New{{Client}} returns a client for the API at baseURL with a cookie jar.
+func New{{Client}}(baseURL string) *{{Client}} {
+	jar, _ := cookiejar.New(nil)
+	return &{{Client}}{BaseURL: strings.TrimSuffix(baseURL, "/"), HTTPClient: &http.Client{Jar: jar}}
+}
+
+func (c *{{Client}}) do(ctx context.Context, method, path string, query url.Values, header http.Header, body, output any, csrf bool) error {
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode the request body: %w", err)
+		}
+		reader = bytes.NewReader(encoded)
+	}
+
+	target := c.BaseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	request, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return err
+	}
+	for name, values := range header {
+		request.Header[name] = values
+	}
+	request.Header.Set("Accept", "application/json")
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	if c.BearerToken != "" {
+		request.Header.Set(BearerHeader, c.BearerToken)
+	}
+	if csrf && httpClient.Jar != nil {
+		for _, cookie := range httpClient.Jar.Cookies(request.URL) {
+			if cookie.Name == CsrfHeader {
+				request.Header.Set(CsrfHeader, cookie.Value)
+			}
+		}
+	}
+
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode >= http.StatusBadRequest {
+		apiErr := &APIError{Status: response.StatusCode}
+		_ = json.NewDecoder(response.Body).Decode(apiErr)
+		if apiErr.Message == "" {
+			apiErr.Message = response.Status
+		}
+		return apiErr
+	}
+
+	if output == nil || response.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if err = json.NewDecoder(response.Body).Decode(output); err != nil && err != io.EOF {
+		return fmt.Errorf("failed to decode the response body: %w", err)
+	}
+	return nil
+}
+
+// formatParam formats a path, query or header value.
+func formatParam(value any) string {
+	if t, ok := value.(time.Time); ok {
+		return t.Format(time.RFC3339)
+	}
+	return fmt.Sprint(value)
+}
+
+// appendParam adds a query or header value, zero values are skipped and slices are added per element.
+func appendParam(values map[string][]string, name string, value any) {
+	reflected := reflect.ValueOf(value)
+	if !reflected.IsValid() || reflected.IsZero() {
+		return
+	}
+	if reflected.Kind() == reflect.Slice {
+		for i := 0; i < reflected.Len(); i++ {
+			values[name] = append(values[name], formatParam(reflected.Index(i).Interface()))
+		}
+		return
+	}
+	values[name] = append(values[name], formatParam(value))
+}
+`
+
+// goGenerator collects the type declarations of a Go client.
+type goGenerator struct {
+	declared map[string]bool
+	types    strings.Builder
+}
+
+// GoClient generates a Go client for the document. The client only depends on the standard library, every
+// operation becomes a method taking an <Operation>Input (parameters and Body) and returning the decoded
+// response.
+func GoClient(document *core.OpenAPIDocument, config Config) ([]byte, error) {
+	config = config.withDefaults()
+	names := documentSecurity(document)
+	generator := &goGenerator{declared: make(map[string]bool)}
+
+	var methods strings.Builder
+	for _, op := range documentOperations(document) {
+		generator.method(&methods, config.ClientName, op)
+	}
+	for _, name := range sortedKeys(document.Components.Schemas) {
+		if name != core.OpenAPIErrorSchema {
+			generator.declare(exportedName(name), document.Components.Schemas[name])
+		}
+	}
+
+	var source strings.Builder
+	fmt.Fprintf(&source, "// %s\n\npackage %s\n\n", generatedHeader, config.Package)
+	source.WriteString("import (\n\t\"bytes\"\n\t\"context\"\n\t\"encoding/json\"\n\t\"fmt\"\n\t\"io\"\n\t\"net/http\"\n\t\"net/http/cookiejar\"\n\t\"net/url\"\n\t\"reflect\"\n\t\"strings\"\n\t\"time\"\n)\n\n")
+	fmt.Fprintf(&source, "const (\n\tBearerHeader = %q\n\tCsrfHeader = %q\n)\n", names.Bearer, names.Csrf)
+	source.WriteString(strings.ReplaceAll(goRuntime, "{{Client}}", config.ClientName))
+	source.WriteString(generator.types.String())
+	source.WriteString(methods.String())
+
+	formatted, err := format.Source([]byte(source.String()))
+	if err != nil {
+		return nil, fmt.Errorf("generated Go client is invalid: %w", err)
+	}
+	return formatted, nil
+}
+
+// typeOf returns the Go type of a schema, inline objects are declared as hint.
+func (g *goGenerator) typeOf(schema *core.OpenAPISchema, hint string) string {
+	if schema == nil {
+		return "json.RawMessage"
+	}
+	if name := componentName(schema); name != "" {
+		return exportedName(name)
+	}
+
+	switch schema.Type {
+	case "string":
+		switch schema.Format {
+		case "date-time":
+			return "time.Time"
+		case "byte":
+			return "[]byte"
+		}
+		return "string"
+	case "integer":
+		if schema.Format == "int64" {
+			return "int64"
+		}
+		return "int"
+	case "number":
+		if schema.Format == "float" {
+			return "float32"
+		}
+		return "float64"
+	case "boolean":
+		return "bool"
+	case "array":
+		return "[]" + g.typeOf(schema.Items, hint+"Item")
+	case "object":
+		if schema.AdditionalProperties != nil && len(schema.Properties) == 0 {
+			return "map[string]" + g.typeOf(schema.AdditionalProperties, hint+"Value")
+		}
+		g.declare(hint, schema)
+		return hint
+	default:
+		return "json.RawMessage"
+	}
+}
+
+// declare writes a struct declaration for an object schema once.
+func (g *goGenerator) declare(name string, schema *core.OpenAPISchema) {
+	if g.declared[name] {
+		return
+	}
+	g.declared[name] = true
+
+	if schema.Type != "object" || (len(schema.Properties) == 0 && schema.AdditionalProperties != nil) {
+		fmt.Fprintf(&g.types, "\ntype %s %s\n", name, g.typeOf(schema, name+"Value"))
+		return
+	}
+
+	var fields strings.Builder
+	for _, property := range sortedKeys(schema.Properties) {
+		tag := property
+		if !containsString(schema.Required, property) {
+			tag += ",omitempty"
+		}
+		fieldName := exportedName(property)
+		fmt.Fprintf(&fields, "\t%s %s `json:%s`\n", fieldName, g.typeOf(schema.Properties[property], name+fieldName), strconv.Quote(tag))
+	}
+	fmt.Fprintf(&g.types, "\ntype %s struct {\n%s}\n", name, fields.String())
+}
+
+// method writes the input type and the method of an operation.
+func (g *goGenerator) method(methods *strings.Builder, clientName string, op operation) {
+	inputName := op.Name + "Input"
+	if op.HasInput() {
+		var fields strings.Builder
+		for _, param := range op.Parameters {
+			fmt.Fprintf(&fields, "\t%s %s // %s %s\n", param.Field, g.typeOf(param.Schema, op.Name+param.Field), param.In, param.Name)
+		}
+		if op.Body != nil {
+			fmt.Fprintf(&fields, "\tBody %s\n", g.typeOf(op.Body, op.Name+"Body"))
+		}
+		fmt.Fprintf(&g.types, "\n// %s is the input of %s.\ntype %s struct {\n%s}\n", inputName, op.Name, inputName, fields.String())
+	}
+
+	outputType := ""
+	if op.Response != nil {
+		outputType = g.typeOf(op.Response, op.Name+"Response")
+	}
+
+	fmt.Fprintf(methods, "\n// %s calls %s %s.\nfunc (c *%s) %s(ctx context.Context", op.Name, op.Method, op.Path, clientName, op.Name)
+	if op.HasInput() {
+		fmt.Fprintf(methods, ", input *%s", inputName)
+	}
+	if outputType != "" {
+		fmt.Fprintf(methods, ") (*%s, error) {\n", outputType)
+	} else {
+		methods.WriteString(") error {\n")
+	}
+
+	// - Path, with the parameters escaped
+	segments, isParam := pathSegments(op.Path)
+	parts := make([]string, 0, len(segments))
+	for i, segment := range segments {
+		if isParam[i] {
+			parts = append(parts, fmt.Sprintf("url.PathEscape(formatParam(input.%s))", exportedName(segment)))
+		} else {
+			parts = append(parts, strconv.Quote(segment))
+		}
+	}
+	if len(parts) == 0 {
+		parts = append(parts, `"/"`)
+	}
+	fmt.Fprintf(methods, "\tpath := %s\n", strings.Join(parts, " + "))
+
+	query, header, body := "nil", "nil", "nil"
+	for _, param := range op.Parameters {
+		switch param.In {
+		case "query":
+			if query == "nil" {
+				methods.WriteString("\tquery := url.Values{}\n")
+				query = "query"
+			}
+			fmt.Fprintf(methods, "\tappendParam(query, %q, input.%s)\n", param.Name, param.Field)
+		case "header":
+			if header == "nil" {
+				methods.WriteString("\theader := http.Header{}\n")
+				header = "header"
+			}
+			fmt.Fprintf(methods, "\tappendParam(header, http.CanonicalHeaderKey(%q), input.%s)\n", param.Name, param.Field)
+		}
+	}
+	if op.Body != nil {
+		body = "input.Body"
+	}
+
+	if outputType == "" {
+		fmt.Fprintf(methods, "\treturn c.do(ctx, %q, path, %s, %s, %s, nil, %t)\n}\n", op.Method, query, header, body, op.Csrf)
+		return
+	}
+	fmt.Fprintf(methods, "\tvar output %s\n", outputType)
+	fmt.Fprintf(methods, "\tif err := c.do(ctx, %q, path, %s, %s, %s, &output, %t); err != nil {\n\t\treturn nil, err\n\t}\n", op.Method, query, header, body, op.Csrf)
+	methods.WriteString("\treturn &output, nil\n}\n")
+}
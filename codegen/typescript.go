@@ -0,0 +1,251 @@
+package codegen
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/grzegorzmaniak/gothic/core"
+)
+
+// typeScriptRuntime is the fetch based transport shared by the generated methods.
+const typeScriptRuntime = `
+export class APIError extends Error {
+  constructor(public readonly status: number, message: string, public readonly details?: unknown) {
+    super(message);
+    this.name = "APIError";
+  }
+}
+
+type Params = Record<string, unknown>;
+
+function readCookie(name: string): string | undefined {
+  if (typeof document === "undefined") {
+    return undefined;
+  }
+  const prefix = name + "=";
+  for (const part of document.cookie.split("; ")) {
+    if (part.startsWith(prefix)) {
+      return decodeURIComponent(part.slice(prefix.length));
+    }
+  }
+  return undefined;
+}
+
+function isSet(value: unknown): boolean {
+  return value !== undefined && value !== null && value !== "";
+}
+
+/**
+ * {{Client}} calls the API. Cookie sessions are sent with credentials: "include" and the CSRF cookie is echoed in
+ * the CSRF_HEADER of routes that require it. Set bearerToken for bearer sessions.
+ */
+export class {{Client}} {
+  constructor(
+    public baseURL: string,
+    public bearerToken?: string,
+    private readonly fetchImpl: typeof fetch = fetch.bind(globalThis),
+  ) {}
+
+  private async request<T>(method: string, path: string, query: Params, headers: Params, body: unknown, csrf: boolean): Promise<T> {
+    const search = new URLSearchParams();
+    for (const [name, value] of Object.entries(query)) {
+      for (const item of Array.isArray(value) ? value : [value]) {
+        if (isSet(item)) {
+          search.append(name, String(item));
+        }
+      }
+    }
+
+    const requestHeaders: Record<string, string> = { Accept: "application/json" };
+    for (const [name, value] of Object.entries(headers)) {
+      if (isSet(value)) {
+        requestHeaders[name] = String(value);
+      }
+    }
+    if (body !== undefined) {
+      requestHeaders["Content-Type"] = "application/json";
+    }
+    if (this.bearerToken) {
+      requestHeaders[BEARER_HEADER] = this.bearerToken;
+    }
+    const csrfToken = csrf ? readCookie(CSRF_HEADER) : undefined;
+    if (csrfToken) {
+      requestHeaders[CSRF_HEADER] = csrfToken;
+    }
+
+    const encodedQuery = search.toString();
+    const response = await this.fetchImpl(this.baseURL.replace(/\/$/, "") + path + (encodedQuery ? "?" + encodedQuery : ""), {
+      method,
+      headers: requestHeaders,
+      body: body === undefined ? undefined : JSON.stringify(body),
+      credentials: "include",
+    });
+
+    if (!response.ok) {
+      let payload: { error?: string; details?: unknown } = {};
+      try {
+        payload = await response.json();
+      } catch {
+        // - The error body is not JSON
+      }
+      throw new APIError(response.status, payload.error ?? response.statusText, payload.details);
+    }
+
+    const text = await response.text();
+    return (text ? JSON.parse(text) : undefined) as T;
+  }
+{{Methods}}}
+`
+
+var typeScriptIdentifier = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+// TypeScriptClient generates a TypeScript client for the document, built on fetch. Every operation becomes a
+// method taking an <Operation>Input (parameters and body) and resolving to the decoded response.
+func TypeScriptClient(document *core.OpenAPIDocument, config Config) ([]byte, error) {
+	config = config.withDefaults()
+	names := documentSecurity(document)
+
+	var source strings.Builder
+	fmt.Fprintf(&source, "// %s\n\n", generatedHeader)
+	fmt.Fprintf(&source, "export const BEARER_HEADER = %s;\nexport const CSRF_HEADER = %s;\n", strconv.Quote(names.Bearer), strconv.Quote(names.Csrf))
+
+	for _, name := range sortedKeys(document.Components.Schemas) {
+		if name == core.OpenAPIErrorSchema {
+			continue
+		}
+		schema := document.Components.Schemas[name]
+		if schema.Type == "object" && len(schema.Properties) > 0 {
+			fmt.Fprintf(&source, "\nexport interface %s %s\n", exportedName(name), typeScriptType(schema))
+		} else {
+			fmt.Fprintf(&source, "\nexport type %s = %s;\n", exportedName(name), typeScriptType(schema))
+		}
+	}
+
+	var methods strings.Builder
+	for _, op := range documentOperations(document) {
+		inputName := op.Name + "Input"
+		if op.HasInput() {
+			fmt.Fprintf(&source, "\nexport interface %s {\n", inputName)
+			for _, param := range op.Parameters {
+				optional := "?"
+				if param.Required {
+					optional = ""
+				}
+				fmt.Fprintf(&source, "  %s%s: %s;\n", unexportedName(param.Name), optional, typeScriptType(param.Schema))
+			}
+			if op.Body != nil {
+				optional := "?"
+				if op.BodyRequired {
+					optional = ""
+				}
+				fmt.Fprintf(&source, "  body%s: %s;\n", optional, typeScriptType(op.Body))
+			}
+			source.WriteString("}\n")
+		}
+		writeTypeScriptMethod(&methods, op, inputName)
+	}
+
+	runtime := strings.ReplaceAll(typeScriptRuntime, "{{Client}}", config.ClientName)
+	source.WriteString(strings.Replace(runtime, "{{Methods}}", methods.String(), 1))
+	return []byte(source.String()), nil
+}
+
+func writeTypeScriptMethod(methods *strings.Builder, op operation, inputName string) {
+	outputType := "void"
+	if op.Response != nil {
+		outputType = typeScriptType(op.Response)
+	}
+
+	methodName := unexportedName(op.Name)
+	fmt.Fprintf(methods, "\n  /** %s calls %s %s. */\n  async %s(", methodName, op.Method, op.Path, methodName)
+	if op.HasInput() {
+		fmt.Fprintf(methods, "input: %s", inputName)
+	}
+	fmt.Fprintf(methods, "): Promise<%s> {\n", outputType)
+
+	// - Path, with the parameters escaped
+	var path strings.Builder
+	segments, isParam := pathSegments(op.Path)
+	for i, segment := range segments {
+		if isParam[i] {
+			fmt.Fprintf(&path, "${encodeURIComponent(String(input.%s))}", unexportedName(segment))
+		} else {
+			path.WriteString(strings.ReplaceAll(segment, "`", "\\`"))
+		}
+	}
+
+	var query, headers []string
+	for _, param := range op.Parameters {
+		entry := fmt.Sprintf("%s: input.%s", strconv.Quote(param.Name), unexportedName(param.Name))
+		switch param.In {
+		case "query":
+			query = append(query, entry)
+		case "header":
+			headers = append(headers, entry)
+		}
+	}
+
+	body := "undefined"
+	if op.Body != nil {
+		body = "input.body"
+	}
+
+	fmt.Fprintf(methods, "    return this.request<%s>(%s, `%s`, { %s }, { %s }, %s, %t);\n  }\n",
+		outputType, strconv.Quote(op.Method), path.String(), strings.Join(query, ", "), strings.Join(headers, ", "), body, op.Csrf)
+}
+
+// typeScriptType returns the TypeScript type of a schema, inline objects become object literal types.
+func typeScriptType(schema *core.OpenAPISchema) string {
+	if schema == nil {
+		return "unknown"
+	}
+	if name := componentName(schema); name != "" {
+		return exportedName(name)
+	}
+
+	switch schema.Type {
+	case "string":
+		if len(schema.Enum) > 0 {
+			values := make([]string, 0, len(schema.Enum))
+			for _, value := range schema.Enum {
+				values = append(values, strconv.Quote(fmt.Sprint(value)))
+			}
+			return strings.Join(values, " | ")
+		}
+		return "string"
+	case "integer", "number":
+		return "number"
+	case "boolean":
+		return "boolean"
+	case "array":
+		item := typeScriptType(schema.Items)
+		if strings.Contains(item, " | ") {
+			item = "(" + item + ")"
+		}
+		return item + "[]"
+	case "object":
+		if schema.AdditionalProperties != nil && len(schema.Properties) == 0 {
+			return "Record<string, " + typeScriptType(schema.AdditionalProperties) + ">"
+		}
+
+		var builder strings.Builder
+		builder.WriteString("{\n")
+		for _, property := range sortedKeys(schema.Properties) {
+			name := property
+			if !typeScriptIdentifier.MatchString(name) {
+				name = strconv.Quote(name)
+			}
+			optional := "?"
+			if containsString(schema.Required, property) {
+				optional = ""
+			}
+			fmt.Fprintf(&builder, "  %s%s: %s;\n", name, optional, typeScriptType(schema.Properties[property]))
+		}
+		builder.WriteString("}")
+		return builder.String()
+	default:
+		return "unknown"
+	}
+}
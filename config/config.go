@@ -0,0 +1,328 @@
+package config
+
+import (
+	"bytes"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/grzegorzmaniak/gothic/cache"
+	"github.com/grzegorzmaniak/gothic/core"
+	"github.com/grzegorzmaniak/gothic/helpers"
+	"github.com/grzegorzmaniak/gothic/rbac"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	CacheStoreRistretto = "ristretto"
+	CacheStoreBigCache  = "bigcache"
+	CacheStoreMemcached = "memcached"
+)
+
+// Config holds the deployment tunables of the framework. Unset (zero) values keep the framework's defaults, e.g.,
+// core.DefaultSessionExpiration, so a file only needs the settings it changes:
+//
+//	session:
+//	  cookie_name: sid
+//	  expiration: 12h
+//	csrf:
+//	  same_site: Lax
+//	cache:
+//	  store: memcached
+//	  namespace: billing
+//	  memcached_servers: [cache-1:11211, cache-2:11211]
+//	rbac:
+//	  role_permissions_ttl: 10m
+//
+// Durations use Go's syntax (e.g., 90s, 12h). Every setting can be overridden with an environment variable, see
+// ApplyEnv.
+type Config struct {
+	Session SessionConfig `yaml:"session"`
+	Csrf    CsrfConfig    `yaml:"csrf"`
+	Cache   CacheConfig   `yaml:"cache"`
+	Rbac    RbacConfig    `yaml:"rbac"`
+}
+
+// SessionConfig maps to core.SessionAuthorizationConfiguration.
+type SessionConfig struct {
+	CookieName              string         `yaml:"cookie_name"`
+	CookiePath              string         `yaml:"cookie_path"`
+	CookieDomain            string         `yaml:"cookie_domain"`
+	CookieSameSite          string         `yaml:"cookie_same_site"`
+	CookiePrefix            string         `yaml:"cookie_prefix"`
+	CookiePartitioned       bool           `yaml:"cookie_partitioned"`
+	HostOnlyCookies         bool           `yaml:"host_only_cookies"`
+	AuthorizationHeaderName string         `yaml:"authorization_header_name"`
+	MaxAuthorizationSize    int            `yaml:"max_authorization_size"`
+	Expiration              time.Duration  `yaml:"expiration"`
+	RefreshTime             time.Duration  `yaml:"refresh_time"`
+	VerifyTime              time.Duration  `yaml:"verify_time"`
+	SplitCookies            bool           `yaml:"split_cookies"`
+	CookieChunkSize         int            `yaml:"cookie_chunk_size"`
+	MaxCookieChunks         int            `yaml:"max_cookie_chunks"`
+	DecodeCacheTTL          time.Duration  `yaml:"decode_cache_ttl"`
+	RotateOnIssue           bool           `yaml:"rotate_on_issue"`
+	RememberMeCookieName    string         `yaml:"remember_me_cookie_name"`
+	RememberMeExpiration    time.Duration  `yaml:"remember_me_expiration"`
+	APIKeyHeaderName        string         `yaml:"api_key_header_name"`
+	SignalRefresh           bool           `yaml:"signal_refresh"`
+	RefreshGracePeriod      time.Duration  `yaml:"refresh_grace_period"`
+	SessionLimits           map[string]int `yaml:"session_limits"`
+	SessionMetadata         bool           `yaml:"session_metadata"`
+}
+
+// CsrfConfig maps to core.CsrfCookieData.
+type CsrfConfig struct {
+	Name        string        `yaml:"name"`
+	Path        string        `yaml:"path"`
+	Domain      string        `yaml:"domain"`
+	SameSite    string        `yaml:"same_site"`
+	Prefix      string        `yaml:"prefix"`
+	Partitioned bool          `yaml:"partitioned"`
+	Expiration  time.Duration `yaml:"expiration"`
+	RefreshTime time.Duration `yaml:"refresh_time"`
+	TokenSize   int           `yaml:"token_size"`
+}
+
+// CacheConfig selects and tunes the cache built by CacheManager.
+type CacheConfig struct {
+
+	// Store is CacheStoreRistretto, CacheStoreBigCache or CacheStoreMemcached (Default: CacheStoreRistretto)
+	Store string `yaml:"store"`
+
+	// Namespace is set with cache.SetKeyNamespace by CacheManager (Default: "", bare keys)
+	Namespace string `yaml:"namespace"`
+
+	// DefaultExpiration is used for entries set without a TTL (Default: cache.DefaultStoreExpirationForRistrettoAdapter)
+	DefaultExpiration time.Duration `yaml:"default_expiration"`
+
+	RistrettoMaxCost     int64 `yaml:"ristretto_max_cost"`
+	RistrettoNumCounters int64 `yaml:"ristretto_num_counters"`
+	RistrettoBufferItems int64 `yaml:"ristretto_buffer_items"`
+
+	MemcachedServers []string      `yaml:"memcached_servers"`
+	MemcachedTimeout time.Duration `yaml:"memcached_timeout"`
+
+	// BigCacheLifeWindow must not exceed the RBAC TTLs, BigCache ignores per-entry TTLs
+	// (Default: cache.DefaultStoreExpirationForRistrettoAdapter)
+	BigCacheLifeWindow time.Duration `yaml:"bigcache_life_window"`
+	BigCacheMaxSizeMB  int           `yaml:"bigcache_max_size_mb"`
+}
+
+// RbacConfig maps to rbac.DefaultRBACManagerConfig.
+type RbacConfig struct {
+	SubjectPermissionsTTL time.Duration `yaml:"subject_permissions_ttl"`
+	SubjectRolesTTL       time.Duration `yaml:"subject_roles_ttl"`
+	RolePermissionsTTL    time.Duration `yaml:"role_permissions_ttl"`
+	SubjectStaleWindow    time.Duration `yaml:"subject_stale_window"`
+}
+
+// Read decodes a YAML (or JSON) configuration. Unknown keys are rejected, so a typo fails instead of being ignored.
+// It does not apply the environment or validate, see Load.
+func Read(reader io.Reader) (*Config, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read configuration: %w", err)
+	}
+
+	config := &Config{}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return config, nil
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err = decoder.Decode(config); err != nil {
+		return nil, fmt.Errorf("failed to decode configuration: %w", err)
+	}
+	return config, nil
+}
+
+// Load reads the YAML file at path (skipped when path is empty), applies the environment variables starting with
+// envPrefix (DefaultEnvPrefix when empty) on top of it and validates the result.
+func Load(path string, envPrefix string) (*Config, error) {
+	config := &Config{}
+	if path != "" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open configuration: %w", err)
+		}
+		defer file.Close()
+
+		if config, err = Read(file); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	if err := ApplyEnv(config, helpers.DefaultString(envPrefix, DefaultEnvPrefix)); err != nil {
+		return nil, err
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// Validate rejects negative durations, refresh times not shorter than their expiration, unknown cache stores and
+// the cookie settings core.ValidateConfiguration would reject at startup.
+func (c *Config) Validate() error {
+	var errs []error
+	invalid := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	durations := map[string]time.Duration{
+		"session.expiration": c.Session.Expiration, "session.refresh_time": c.Session.RefreshTime,
+		"session.verify_time": c.Session.VerifyTime, "session.decode_cache_ttl": c.Session.DecodeCacheTTL,
+		"session.remember_me_expiration": c.Session.RememberMeExpiration, "csrf.expiration": c.Csrf.Expiration,
+		"csrf.refresh_time": c.Csrf.RefreshTime, "cache.default_expiration": c.Cache.DefaultExpiration,
+		"cache.memcached_timeout": c.Cache.MemcachedTimeout, "cache.bigcache_life_window": c.Cache.BigCacheLifeWindow,
+		"rbac.subject_permissions_ttl": c.Rbac.SubjectPermissionsTTL, "rbac.subject_roles_ttl": c.Rbac.SubjectRolesTTL,
+		"rbac.role_permissions_ttl": c.Rbac.RolePermissionsTTL, "rbac.subject_stale_window": c.Rbac.SubjectStaleWindow,
+	}
+	for name, duration := range durations {
+		if duration < 0 {
+			invalid("%s must not be negative, got %s", name, duration)
+		}
+	}
+
+	sessionExpiration := helpers.DefaultTimeDuration(c.Session.Expiration, core.DefaultSessionExpiration)
+	if refresh := helpers.DefaultTimeDuration(c.Session.RefreshTime, core.DefaultSessionRefreshTime); refresh >= sessionExpiration {
+		invalid("session.refresh_time (%s) must be shorter than session.expiration (%s)", refresh, sessionExpiration)
+	}
+	csrfExpiration := helpers.DefaultTimeDuration(c.Csrf.Expiration, core.DefaultCsrfExpiration)
+	if refresh := helpers.DefaultTimeDuration(c.Csrf.RefreshTime, core.DefaultCsrfRefreshTime); refresh >= csrfExpiration {
+		invalid("csrf.refresh_time (%s) must be shorter than csrf.expiration (%s)", refresh, csrfExpiration)
+	}
+	if size := c.Session.MaxAuthorizationSize; size != 0 && (size < core.MinimumSessionAuthorizationSize || size > core.MaximumSessionAuthorizationSize) {
+		invalid("session.max_authorization_size must be between %d and %d, got %d", core.MinimumSessionAuthorizationSize, core.MaximumSessionAuthorizationSize, size)
+	}
+	if c.Session.MaxAuthorizationSize < 0 || c.Session.CookieChunkSize < 0 || c.Session.MaxCookieChunks < 0 || c.Csrf.TokenSize < 0 {
+		invalid("sizes must not be negative")
+	}
+
+	switch c.Cache.Store {
+	case "", CacheStoreRistretto, CacheStoreMemcached:
+	case CacheStoreBigCache:
+		// - BigCache keeps every entry for its life window, longer than the RBAC TTLs would serve revoked permissions
+		lifeWindow := helpers.DefaultTimeDuration(c.Cache.BigCacheLifeWindow, cache.DefaultStoreExpirationForRistrettoAdapter)
+		rbacConfig := c.RbacManagerConfig()
+		manager := &rbac.DefaultRBACManager{DefaultRBACManagerConfig: rbacConfig}
+		shortest := min(manager.GetSubjectPermissionsCacheTtl(), manager.GetSubjectRolesCacheTtl(), manager.GetRolePermissionsCacheTtl())
+		if lifeWindow > shortest {
+			invalid("cache.bigcache_life_window (%s) must not exceed the shortest RBAC TTL (%s)", lifeWindow, shortest)
+		}
+	default:
+		invalid("cache.store must be %s, %s or %s, got '%s'", CacheStoreRistretto, CacheStoreBigCache, CacheStoreMemcached, c.Cache.Store)
+	}
+
+	authorization := c.SessionAuthorization()
+	if err := authorization.ValidateCookies(); err != nil {
+		invalid("session: %w", err)
+	}
+	csrf := c.CsrfCookieData()
+	if authorization.HostOnlyCookies && csrf.Prefix != core.CookiePrefixNone && csrf.Prefix != core.CookiePrefixHost {
+		invalid("csrf: host_only_cookies conflicts with the %s prefix", csrf.Prefix)
+	} else if err := csrf.ValidateCookies(); err != nil {
+		invalid("csrf: %w", err)
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("config: invalid configuration: %w", stderrors.Join(errs...))
+	}
+	return nil
+}
+
+// SessionAuthorization returns the session configuration for core.SessionManager.GetAuthorizationConfiguration.
+func (c *Config) SessionAuthorization() *core.SessionAuthorizationConfiguration {
+	return &core.SessionAuthorizationConfiguration{
+		CookieName:              c.Session.CookieName,
+		CookiePath:              c.Session.CookiePath,
+		CookieDomain:            c.Session.CookieDomain,
+		CookieSameSite:          c.Session.CookieSameSite,
+		CookiePrefix:            core.CookiePrefix(c.Session.CookiePrefix),
+		CookiePartitioned:       c.Session.CookiePartitioned,
+		HostOnlyCookies:         c.Session.HostOnlyCookies,
+		AuthorizationHeaderName: c.Session.AuthorizationHeaderName,
+		MaxAuthorizationSize:    c.Session.MaxAuthorizationSize,
+		Expiration:              c.Session.Expiration,
+		RefreshTime:             c.Session.RefreshTime,
+		VerifyTime:              c.Session.VerifyTime,
+		SplitCookies:            c.Session.SplitCookies,
+		CookieChunkSize:         c.Session.CookieChunkSize,
+		MaxCookieChunks:         c.Session.MaxCookieChunks,
+		DecodeCacheTTL:          c.Session.DecodeCacheTTL,
+		RotateOnIssue:           c.Session.RotateOnIssue,
+		RememberMeCookieName:    c.Session.RememberMeCookieName,
+		RememberMeExpiration:    c.Session.RememberMeExpiration,
+		APIKeyHeaderName:        c.Session.APIKeyHeaderName,
+		SignalRefresh:           c.Session.SignalRefresh,
+		RefreshGracePeriod:      c.Session.RefreshGracePeriod,
+		SessionLimits:           c.Session.SessionLimits,
+		SessionMetadata:         c.Session.SessionMetadata,
+	}
+}
+
+// CsrfCookieData returns the CSRF configuration for core.SessionManager.GetCsrfData.
+func (c *Config) CsrfCookieData() *core.CsrfCookieData {
+	return &core.CsrfCookieData{
+		Name:          c.Csrf.Name,
+		Path:          c.Csrf.Path,
+		Domain:        c.Csrf.Domain,
+		SameSite:      c.Csrf.SameSite,
+		Prefix:        core.CookiePrefix(c.Csrf.Prefix),
+		Partitioned:   c.Csrf.Partitioned,
+		Expiration:    c.Csrf.Expiration,
+		RefreshTime:   c.Csrf.RefreshTime,
+		CsrfTokenSize: c.Csrf.TokenSize,
+	}
+}
+
+// RbacManagerConfig returns the TTLs for rbac.DefaultRBACManager.
+func (c *Config) RbacManagerConfig() rbac.DefaultRBACManagerConfig {
+	return rbac.DefaultRBACManagerConfig{
+		UserPermissionsCacheTTL: c.Rbac.SubjectPermissionsTTL,
+		UserRolesCacheTTL:       c.Rbac.SubjectRolesTTL,
+		RolePermissionsCacheTTL: c.Rbac.RolePermissionsTTL,
+		SubjectStaleWindow:      c.Rbac.SubjectStaleWindow,
+	}
+}
+
+// CacheManager builds the configured cache manager. A Namespace is set globally with cache.SetKeyNamespace, so
+// call it before any cache is used.
+func (c *Config) CacheManager() *cache.DefaultCacheManager {
+	if c.Cache.Namespace != "" {
+		cache.SetKeyNamespace(c.Cache.Namespace)
+	}
+
+	switch c.Cache.Store {
+	case CacheStoreBigCache:
+		return cache.BuildBigCacheManager(&cache.BigCacheConfig{
+			LifeWindow:         c.Cache.BigCacheLifeWindow,
+			HardMaxCacheSizeMB: c.Cache.BigCacheMaxSizeMB,
+		})
+	case CacheStoreMemcached:
+		return cache.BuildMemcachedCacheManager(&cache.MemcachedCacheConfig{
+			Servers:           c.Cache.MemcachedServers,
+			Timeout:           c.Cache.MemcachedTimeout,
+			DefaultExpiration: c.Cache.DefaultExpiration,
+		})
+	default:
+		return cache.BuildDefaultCacheManager(&cache.DefaultCacheConfig{
+			RistrettoMaxCost:                          helpers.DefaultInt64(c.Cache.RistrettoMaxCost, cache.DefaultRistrettoMaxCost),
+			RistrettoNumCounters:                      helpers.DefaultInt64(c.Cache.RistrettoNumCounters, cache.DefaultRistrettoNumCounters),
+			RistrettoBufferItems:                      helpers.DefaultInt64(c.Cache.RistrettoBufferItems, cache.DefaultRistrettoBufferItems),
+			DefaultStoreExpirationForRistrettoAdapter: helpers.DefaultTimeDuration(c.Cache.DefaultExpiration, cache.DefaultStoreExpirationForRistrettoAdapter),
+		})
+	}
+}
+
+// SessionManagerBuilder returns a core.SessionManagerBuilder with the configured cookies and cache, add the keys
+// and the rest of the options before calling Build.
+func (c *Config) SessionManagerBuilder() *core.SessionManagerBuilder {
+	return core.NewSessionManagerBuilder().
+		WithCookieConfig(c.SessionAuthorization(), c.CsrfCookieData()).
+		WithCache(c.CacheManager())
+}
@@ -0,0 +1,193 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grzegorzmaniak/gothic/cache"
+	"github.com/grzegorzmaniak/gothic/core"
+)
+
+func TestRead(t *testing.T) {
+	input := `
+session:
+  cookie_name: sid
+  expiration: 12h
+  refresh_time: 10m
+  host_only_cookies: true
+csrf:
+  same_site: Lax
+  token_size: 48
+cache:
+  store: memcached
+  memcached_servers: [cache-1:11211, cache-2:11211]
+rbac:
+  role_permissions_ttl: 10m
+`
+	config, err := Read(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if config.Session.CookieName != "sid" || config.Session.Expiration != 12*time.Hour || config.Session.RefreshTime != 10*time.Minute || !config.Session.HostOnlyCookies {
+		t.Errorf("Unexpected session configuration: %+v", config.Session)
+	}
+	if config.Csrf.SameSite != "Lax" || config.Csrf.TokenSize != 48 {
+		t.Errorf("Unexpected CSRF configuration: %+v", config.Csrf)
+	}
+	if config.Cache.Store != CacheStoreMemcached || len(config.Cache.MemcachedServers) != 2 {
+		t.Errorf("Unexpected cache configuration: %+v", config.Cache)
+	}
+	if config.Rbac.RolePermissionsTTL != 10*time.Minute {
+		t.Errorf("Expected a role permissions TTL of 10m, got %s", config.Rbac.RolePermissionsTTL)
+	}
+	if err = config.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"Unknown section", "sessions:\n  cookie_name: sid\n"},
+		{"Unknown key", "session:\n  cookie: sid\n"},
+		{"Invalid duration", "session:\n  expiration: soon\n"},
+		{"Malformed", "{"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Read(strings.NewReader(tt.input)); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
+
+func TestApplyEnv(t *testing.T) {
+	config := &Config{Session: SessionConfig{CookieName: "from-file", Expiration: time.Hour}}
+	t.Setenv("APP_SESSION_COOKIE_NAME", "from-env")
+	t.Setenv("APP_SESSION_SPLIT_COOKIES", "true")
+	t.Setenv("APP_SESSION_MAX_COOKIE_CHUNKS", "4")
+	t.Setenv("APP_CACHE_MEMCACHED_SERVERS", "cache-1:11211, cache-2:11211,")
+	t.Setenv("APP_RBAC_SUBJECT_ROLES_TTL", "90s")
+
+	if err := ApplyEnv(config, "app"); err != nil {
+		t.Fatalf("ApplyEnv() error = %v", err)
+	}
+	if config.Session.CookieName != "from-env" || !config.Session.SplitCookies || config.Session.MaxCookieChunks != 4 {
+		t.Errorf("Unexpected session configuration: %+v", config.Session)
+	}
+	if config.Session.Expiration != time.Hour {
+		t.Errorf("Expected the unset expiration to be kept, got %s", config.Session.Expiration)
+	}
+	if servers := config.Cache.MemcachedServers; len(servers) != 2 || servers[1] != "cache-2:11211" {
+		t.Errorf("Unexpected memcached servers: %q", servers)
+	}
+	if config.Rbac.SubjectRolesTTL != 90*time.Second {
+		t.Errorf("Expected a subject roles TTL of 90s, got %s", config.Rbac.SubjectRolesTTL)
+	}
+
+	t.Setenv("APP_SESSION_EXPIRATION", "a week")
+	if err := ApplyEnv(config, "APP"); err == nil || !strings.Contains(err.Error(), "APP_SESSION_EXPIRATION") {
+		t.Errorf("Expected an error naming the variable, got %v", err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gothic.yaml")
+	if err := os.WriteFile(path, []byte("session:\n  cookie_name: sid\n  expiration: 1h\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GOTHIC_SESSION_EXPIRATION", "2h")
+
+	config, err := Load(path, "")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if config.Session.CookieName != "sid" || config.Session.Expiration != 2*time.Hour {
+		t.Errorf("Expected the environment to override the file, got %+v", config.Session)
+	}
+
+	t.Setenv("GOTHIC_SESSION_REFRESH_TIME", "3h")
+	if _, err = Load(path, ""); err == nil {
+		t.Error("Expected a refresh time longer than the expiration to be rejected")
+	}
+	if _, err = Load(filepath.Join(t.TempDir(), "missing.yaml"), ""); err == nil {
+		t.Error("Expected a missing file to be rejected")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Config
+		wantErr bool
+	}{
+		{"Defaults", Config{}, false},
+		{"Negative duration", Config{Rbac: RbacConfig{SubjectRolesTTL: -time.Second}}, true},
+		{"Refresh after default expiration", Config{Csrf: CsrfConfig{RefreshTime: 9 * time.Hour}}, true},
+		{"Authorization size too small", Config{Session: SessionConfig{MaxAuthorizationSize: 16}}, true},
+		{"Unknown store", Config{Cache: CacheConfig{Store: "redis"}}, true},
+		{"Unknown SameSite", Config{Session: SessionConfig{CookieSameSite: "Sideways"}}, true},
+		{"Host prefix with domain", Config{Csrf: CsrfConfig{Prefix: string(core.CookiePrefixHost), Domain: "example.com"}}, true},
+		{"BigCache life window", Config{Cache: CacheConfig{Store: CacheStoreBigCache, BigCacheLifeWindow: 30 * time.Second}}, false},
+		{"BigCache outlives RBAC", Config{Cache: CacheConfig{Store: CacheStoreBigCache}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.config.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConverters(t *testing.T) {
+	config := &Config{
+		Session: SessionConfig{CookieName: "sid", CookiePrefix: string(core.CookiePrefixSecure), Expiration: time.Hour},
+		Csrf:    CsrfConfig{Name: "csrf", TokenSize: 48},
+		Rbac:    RbacConfig{SubjectPermissionsTTL: time.Minute, SubjectStaleWindow: 5 * time.Second},
+	}
+
+	authorization := config.SessionAuthorization()
+	if authorization.CookieName != "sid" || authorization.CookiePrefix != core.CookiePrefixSecure || authorization.Expiration != time.Hour {
+		t.Errorf("Unexpected session authorization: %+v", authorization)
+	}
+	if csrf := config.CsrfCookieData(); csrf.Name != "csrf" || csrf.CsrfTokenSize != 48 {
+		t.Errorf("Unexpected CSRF cookie data: %+v", csrf)
+	}
+	if rbacConfig := config.RbacManagerConfig(); rbacConfig.UserPermissionsCacheTTL != time.Minute || rbacConfig.SubjectStaleWindow != 5*time.Second {
+		t.Errorf("Unexpected RBAC configuration: %+v", rbacConfig)
+	}
+
+	manager := config.CacheManager()
+	defer manager.Close()
+	if _, err := manager.GetCache(); err != nil {
+		t.Fatalf("GetCache() error = %v", err)
+	}
+
+	built, err := config.SessionManagerBuilder().WithKey("test-1", make([]byte, 32)).Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	defer built.Close()
+	if got := built.GetAuthorizationConfiguration().CookieName; got != "sid" {
+		t.Errorf("Expected the built manager to use the configured cookie name, got '%s'", got)
+	}
+}
+
+func TestCacheManagerNamespace(t *testing.T) {
+	defer cache.SetKeyNamespace(cache.KeyNamespace())
+
+	config := &Config{Cache: CacheConfig{Store: CacheStoreBigCache, Namespace: "billing"}}
+	manager := config.CacheManager()
+	defer manager.Close()
+
+	if got := cache.KeyNamespace(); got != "billing" {
+		t.Errorf("Expected the namespace 'billing', got '%s'", got)
+	}
+}
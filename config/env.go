@@ -0,0 +1,93 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultEnvPrefix prefixes the environment variables read by Load, e.g., GOTHIC_SESSION_COOKIE_NAME.
+const DefaultEnvPrefix = "GOTHIC"
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// ApplyEnv overrides config with the environment variables named <PREFIX>_<SECTION>_<KEY> after the YAML keys, e.g.,
+// GOTHIC_SESSION_EXPIRATION=12h or GOTHIC_CACHE_MEMCACHED_SERVERS=cache-1:11211,cache-2:11211 (lists are comma
+// separated). Unset variables keep the current value, an unparsable one is an error.
+func ApplyEnv(config *Config, prefix string) error {
+	if config == nil {
+		return fmt.Errorf("config: configuration is nil")
+	}
+	return applyEnv(reflect.ValueOf(config).Elem(), strings.ToUpper(prefix))
+}
+
+func applyEnv(value reflect.Value, prefix string) error {
+	valueType := value.Type()
+	for i := 0; i < valueType.NumField(); i++ {
+		tag := strings.Split(valueType.Field(i).Tag.Get("yaml"), ",")[0]
+		if tag == "" || tag == "-" {
+			continue
+		}
+
+		name := prefix + "_" + strings.ToUpper(tag)
+		field := value.Field(i)
+		if field.Kind() == reflect.Struct {
+			if err := applyEnv(field, name); err != nil {
+				return err
+			}
+			continue
+		}
+
+		raw, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		if err := setField(field, strings.TrimSpace(raw)); err != nil {
+			return fmt.Errorf("config: %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func setField(field reflect.Value, raw string) error {
+	// - Duration is an int64, check it before the kinds
+	if field.Type() == durationType {
+		duration, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(duration))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(raw)
+	case reflect.Bool:
+		parsed, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		field.SetBool(parsed)
+	case reflect.Int, reflect.Int64:
+		parsed, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return err
+		}
+		field.SetInt(parsed)
+	case reflect.Slice:
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		field.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported type %s", field.Type())
+	}
+	return nil
+}
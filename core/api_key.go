@@ -0,0 +1,263 @@
+package core
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eko/gocache/lib/v4/store"
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/errors"
+	"github.com/grzegorzmaniak/gothic/helpers"
+	"github.com/grzegorzmaniak/gothic/rbac"
+	"go.uber.org/zap"
+)
+
+const (
+	DefaultAPIKeyHeaderName = "x-service-key" // Bearer sessions use DefaultSessionAuthorizationHeaderName
+	DefaultAPIKeyPrefix     = "gk"
+	APIKeyDelimiter         = "_" // Keys are <prefix>_<id>_<secret>
+	APIKeyIDSize            = 16
+	APIKeySecretSize        = 40
+
+	// APIKeySessionGroup is the session mode of requests authenticated with an API key, use it in the Allow and
+	// Block lists of routes.
+	APIKeySessionGroup = "api_key"
+
+	APIKeyIDClaim = "___ak" // The id of the API key the request authenticated with
+
+	APIKeyLastUsedCacheKeyPrefix = "api_key_used:" // Key: api_key_used:<id>
+	DefaultAPIKeyLastUsedTTL     = 90 * 24 * time.Hour
+
+	apiKeyContextKey = "gothic_api_key"
+)
+
+// APIKey is the stored record of a long-lived machine credential. Only the hash of the secret is kept, the key
+// itself is shown once when it is created, see GenerateAPIKey.
+type APIKey struct {
+	// ID is the public part of the key, the store looks keys up by it.
+	ID string `json:"id"`
+
+	// Hash is the SHA-256 of the key's secret, see HashAPIKey.
+	Hash string `json:"hash"`
+
+	// Name describes the key to its owner, e.g., "CI deploys".
+	Name string `json:"name,omitempty"`
+
+	// Claims are the session claims of requests made with the key, e.g., the manager's subject claim.
+	Claims map[string]string `json:"claims,omitempty"`
+
+	// Roles and Permissions (named permissions) are what the key is granted, they are checked against the
+	// route's RBAC requirements instead of the subject's grants.
+	Roles       []string `json:"roles,omitempty"`
+	Permissions []string `json:"permissions,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at,omitempty"` // Zero never expires
+	RevokedAt time.Time `json:"revoked_at,omitempty"` // Zero is not revoked
+}
+
+// Active reports whether the key can be used at the given time.
+func (k *APIKey) Active(at time.Time) bool {
+	return k.RevokedAt.IsZero() && (k.ExpiresAt.IsZero() || at.Before(k.ExpiresAt))
+}
+
+// APIKeyStore looks up stored API keys by their id, return nil for unknown ids.
+type APIKeyStore interface {
+	GetAPIKey(ctx context.Context, id string) (*APIKey, error)
+}
+
+// APIKeyProvider is implemented by session managers that accept API keys on routes with AllowAPIKeys, see
+// SessionManagerBuilder.WithAPIKeyStore.
+type APIKeyProvider interface {
+	GetAPIKeyStore() APIKeyStore
+}
+
+// HashAPIKey returns the stored hash of an API key's secret, bound to its id. The secrets are random, so a plain
+// SHA-256 is enough to keep the keys from being read out of a database dump.
+func HashAPIKey(id string, secret string) string {
+	sum := sha256.Sum256([]byte(id + APIKeyDelimiter + secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// GenerateAPIKey creates a key with the given prefix (Default: DefaultAPIKeyPrefix), e.g., "acme_live". The
+// returned key is shown to its owner once, store the returned record (with the Claims, grants and expiry filled
+// in) through your APIKeyStore.
+func GenerateAPIKey(prefix string) (string, *APIKey, error) {
+	prefix = helpers.DefaultString(prefix, DefaultAPIKeyPrefix)
+	id, err := helpers.GenerateID(APIKeyIDSize)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to generate API key id: %w", err)
+	}
+	secret, err := helpers.GenerateID(APIKeySecretSize)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to generate API key secret: %w", err)
+	}
+
+	key := &APIKey{ID: id, Hash: HashAPIKey(id, secret), CreatedAt: time.Now()}
+	return prefix + APIKeyDelimiter + id + APIKeyDelimiter + secret, key, nil
+}
+
+// ParseAPIKey splits a key into its prefix, id and secret. The prefix may contain the delimiter, the id and
+// secret never do.
+func ParseAPIKey(value string) (prefix string, id string, secret string, err error) {
+	rest, secret, found := cutLast(value, APIKeyDelimiter)
+	prefix, id, foundId := cutLast(rest, APIKeyDelimiter)
+	if !found || !foundId || prefix == "" || len(id) != APIKeyIDSize || len(secret) != APIKeySecretSize {
+		return "", "", "", fmt.Errorf("invalid API key format")
+	}
+	return prefix, id, secret, nil
+}
+
+func cutLast(value string, delimiter string) (string, string, bool) {
+	index := strings.LastIndex(value, delimiter)
+	if index < 0 {
+		return value, "", false
+	}
+	return value[:index], value[index+len(delimiter):], true
+}
+
+// AuthenticateAPIKey looks the key up through the manager's APIKeyStore and checks its secret and that it is
+// active. The returned record is the one of the store.
+func AuthenticateAPIKey(ctx context.Context, sessionManager SessionManager, value string) (*APIKey, error) {
+	provider, ok := sessionManager.(APIKeyProvider)
+	if !ok || provider.GetAPIKeyStore() == nil {
+		return nil, fmt.Errorf("session manager has no API key store")
+	}
+
+	_, id, secret, err := ParseAPIKey(value)
+	if err != nil {
+		return nil, err
+	}
+	key, err := provider.GetAPIKeyStore().GetAPIKey(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up API key: %w", err)
+	}
+
+	// - Unknown keys are hashed too, so the time taken does not tell which ids exist
+	hash := HashAPIKey(id, secret)
+	if key == nil || key.ID != id {
+		subtle.ConstantTimeCompare([]byte(hash), []byte(hash))
+		return nil, fmt.Errorf("API key is unknown")
+	}
+	if subtle.ConstantTimeCompare([]byte(hash), []byte(key.Hash)) != 1 {
+		return nil, fmt.Errorf("API key is unknown")
+	}
+	if !key.Active(time.Now()) {
+		return nil, fmt.Errorf("API key is expired or revoked")
+	}
+	return key, nil
+}
+
+// APIKeyFromContext returns the API key the request authenticated with.
+func APIKeyFromContext(ctx *gin.Context) (*APIKey, bool) {
+	value, ok := ctx.Get(apiKeyContextKey)
+	if !ok {
+		return nil, false
+	}
+	key, ok := value.(*APIKey)
+	return key, ok
+}
+
+// APIKeyLastUsed returns when the key was last used, tracked in the manager's cache for DefaultAPIKeyLastUsedTTL.
+func APIKeyLastUsed(ctx context.Context, sessionManager SessionManager, id string) (time.Time, bool) {
+	cache, err := sessionManager.GetCache()
+	if err != nil || cache == nil {
+		return time.Time{}, false
+	}
+	value, err := cache.Get(ctx, APIKeyLastUsedCacheKeyPrefix+id)
+	if err != nil {
+		return time.Time{}, false
+	}
+	unix, err := strconv.ParseInt(string(value), 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(unix, 0), true
+}
+
+// recordAPIKeyUse stores when the key was last used, failures are only logged.
+func recordAPIKeyUse(ctx *gin.Context, sessionManager SessionManager, id string) {
+	cache, err := sessionManager.GetCache()
+	if err == nil && cache != nil {
+		err = cache.Set(ctx, APIKeyLastUsedCacheKeyPrefix+id, []byte(strconv.FormatInt(time.Now().Unix(), 10)), store.WithExpiration(DefaultAPIKeyLastUsedTTL))
+	}
+	if err != nil {
+		helpers.Logger(ctx).Debug("Failed to record API key use", zap.String("api_key", id), zap.Error(err))
+	}
+}
+
+// apiKeyValue returns the API key of the request, or "".
+func apiKeyValue(ctx *gin.Context, authorizationData *SessionAuthorizationConfiguration) string {
+	name := DefaultAPIKeyHeaderName
+	if authorizationData != nil {
+		name = helpers.DefaultString(authorizationData.APIKeyHeaderName, DefaultAPIKeyHeaderName)
+	}
+	return ctx.GetHeader(name)
+}
+
+// establishAPIKeySession authenticates the API key of a request to a route with AllowAPIKeys. found is false
+// when the request carries no key, it then goes through the session checks as usual. Requests with a key carry
+// no CSRF token, the key is never sent by a browser on its own.
+func establishAPIKeySession(
+	ctx *gin.Context,
+	sessionManager SessionManager,
+	sessionConfig *APIConfiguration,
+) (claims *SessionClaims, group string, found bool, appErr *errors.AppError) {
+	value := apiKeyValue(ctx, sessionManager.GetAuthorizationConfiguration())
+	if value == "" {
+		return nil, "", false, nil
+	}
+
+	key, err := AuthenticateAPIKey(ctx, sessionManager, value)
+	if err != nil {
+		helpers.Logger(ctx).Debug("API key authentication failed", zap.Error(err))
+		return nil, "", true, errors.NewUnauthorized("", err)
+	}
+
+	claims = &SessionClaims{Claims: make(map[string]string, len(key.Claims)+2), HasSession: true}
+	for name, value := range key.Claims {
+		claims.Claims[name] = value
+	}
+	claims.SetClaim(SessionModeClaim, APIKeySessionGroup)
+	claims.SetClaim(APIKeyIDClaim, key.ID)
+
+	// - The route's Allow / Block lists and the manager's claim checks apply like for sessions
+	if _, claims, group, appErr = _verifyClaimsAndHandleSessionState(ctx, sessionManager, sessionConfig, claims, nil, APIKeySessionGroup); appErr != nil {
+		return nil, "", true, appErr
+	}
+	if claims == nil {
+		return nil, "", true, errors.NewUnauthorized("", nil)
+	}
+
+	ctx.Set(apiKeyContextKey, key)
+	recordAPIKeyUse(ctx, sessionManager, key.ID)
+	return claims, group, true, nil
+}
+
+// APIKeyRbacCachePrefix prefixes the key id the denials of an API key's subject are cached under.
+const APIKeyRbacCachePrefix = "api_key:"
+
+// checkAPIKeyAccess checks the route's RBAC requirements against the grants of the request's API key. The
+// permissions denied to the key's subject still apply, a key can't be granted what its subject is denied.
+func checkAPIKeyAccess(ctx *gin.Context, sessionManager SessionManager, rbacManager rbac.Manager, key *APIKey, claims *SessionClaims, requirements rbac.AccessRequirements) (bool, error) {
+	named, err := rbac.NewPermissionSet(key.Permissions...)
+	if err != nil {
+		return false, fmt.Errorf("API key '%s' has invalid permissions: %w", key.ID, err)
+	}
+
+	// - Without a subject there is nothing to deny, unless the manager denies permissions
+	subjectIdentifier, err := sessionManager.GetSubjectIdentifier(claims)
+	if err != nil {
+		if _, denies := rbacManager.(rbac.DenyManager); denies {
+			return false, fmt.Errorf("API key '%s' has no subject to check denials for: %w", key.ID, err)
+		}
+		subjectIdentifier = ""
+	}
+	return rbac.CheckGrantedAccess(rbacContext(ctx), rbacManager, subjectIdentifier, APIKeyRbacCachePrefix+key.ID, key.Roles, named, requirements)
+}
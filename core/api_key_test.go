@@ -0,0 +1,237 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	internalcache "github.com/grzegorzmaniak/gothic/cache"
+	"github.com/grzegorzmaniak/gothic/errors"
+	"github.com/grzegorzmaniak/gothic/rbac"
+)
+
+type mapAPIKeyStore map[string]*APIKey
+
+func (s mapAPIKeyStore) GetAPIKey(_ context.Context, id string) (*APIKey, error) {
+	return s[id], nil
+}
+
+func TestParseAPIKey(t *testing.T) {
+	value, key, err := GenerateAPIKey("acme_live")
+	if err != nil {
+		t.Fatalf("Failed to generate the API key: %v", err)
+	}
+	prefix, id, secret, err := ParseAPIKey(value)
+	if err != nil || prefix != "acme_live" || id != key.ID {
+		t.Fatalf("Expected the key to parse, got '%s', '%s', %v", prefix, id, err)
+	}
+	if key.Hash != HashAPIKey(id, secret) || strings.Contains(key.Hash, secret) {
+		t.Error("Expected only the hash of the secret to be stored")
+	}
+
+	for _, invalid := range []string{"", "gk", "gk_short_secret", "_" + id + "_" + secret, value[:len(value)-1]} {
+		if _, _, _, err = ParseAPIKey(invalid); err == nil {
+			t.Errorf("Expected '%s' to be rejected", invalid)
+		}
+	}
+}
+
+func TestAPIKeyRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newKey := func(permissions []string, roles []string) (string, *APIKey) {
+		value, key, err := GenerateAPIKey("")
+		if err != nil {
+			t.Fatalf("Failed to generate the API key: %v", err)
+		}
+		key.Claims = map[string]string{DefaultBuilderSubjectClaim: "service-1"}
+		key.Permissions, key.Roles = permissions, roles
+		return value, key
+	}
+	reader, readerKey := newKey([]string{"reports.*"}, nil)
+	editor, editorKey := newKey(nil, []string{"editor"})
+	revoked, revokedKey := newKey([]string{"reports.*"}, nil)
+	revokedKey.RevokedAt = time.Now()
+	expired, expiredKey := newKey([]string{"reports.*"}, nil)
+	expiredKey.ExpiresAt = time.Now().Add(-time.Minute)
+	store := mapAPIKeyStore{readerKey.ID: readerKey, editorKey.ID: editorKey, revokedKey.ID: revokedKey, expiredKey.ID: expiredKey}
+
+	manager, err := NewSessionManagerBuilder().
+		WithKey("k1", newBuilderKey(t)).
+		WithRbac(&roleRbacManager{namedRbacManager{cacheManager: internalcache.BuildDefaultCacheManager(nil)}}).
+		WithAPIKeyStore(store).
+		Build()
+	if err != nil {
+		t.Fatalf("Expected Build to succeed, got %v", err)
+	}
+	defer manager.Close()
+
+	type output struct {
+		Subject string `json:"subject"`
+		Key     string `json:"key"`
+	}
+	handler := func(_ *struct{}, data *Handler[testBaseRoute]) (*output, *errors.AppError) {
+		subject, _ := data.Claims.GetClaim(DefaultBuilderSubjectClaim)
+		key, _ := APIKeyFromContext(data.Context)
+		if key == nil {
+			return &output{Subject: subject}, nil
+		}
+		return &output{Subject: subject, Key: key.ID}, nil
+	}
+
+	router := gin.New()
+	ctor := NewRouteConstructor(router, testBaseRoute{}, manager, nil)
+	POST(ctor, "/reports", AuthenticatedJSONAPI().WithAPIKeys().WithNamedPermissions("reports.read").WithRbacPolicy(rbac.PermissionsOnly), handler)
+	POST(ctor, "/articles", AuthenticatedJSONAPI().WithAPIKeys().WithPermissions(rbac.NewPermission(1)).WithRbacPolicy(rbac.PermissionsOnly), handler)
+	POST(ctor, "/sessions-only", AuthenticatedJSONAPI(), handler)
+	POST(ctor, "/humans", AuthenticatedJSONAPI().WithAPIKeys().BlockModes(APIKeySessionGroup), handler)
+
+	request := func(path string, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		req.Header.Set(DefaultAPIKeyHeaderName, key)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	t.Run("Keys authenticate without a CSRF token", func(t *testing.T) {
+		recorder := request("/reports", reader)
+		if recorder.Code != http.StatusOK || !strings.Contains(recorder.Body.String(), "service-1") || !strings.Contains(recorder.Body.String(), readerKey.ID) {
+			t.Fatalf("Expected the key to be accepted, got %d: %s", recorder.Code, recorder.Body.String())
+		}
+		if recorder.Header().Get("Set-Cookie") != "" {
+			t.Error("Expected no session cookie for API key requests")
+		}
+	})
+
+	t.Run("Roles of the key grant access", func(t *testing.T) {
+		if recorder := request("/articles", editor); recorder.Code != http.StatusOK {
+			t.Errorf("Expected the key's role to grant access, got %d: %s", recorder.Code, recorder.Body.String())
+		}
+		if recorder := request("/articles", reader); recorder.Code != http.StatusUnauthorized {
+			t.Errorf("Expected a key without the permission to be denied, got %d", recorder.Code)
+		}
+		if recorder := request("/reports", editor); recorder.Code != http.StatusUnauthorized {
+			t.Errorf("Expected a key without the named permission to be denied, got %d", recorder.Code)
+		}
+	})
+
+	t.Run("Invalid keys are rejected", func(t *testing.T) {
+		_, id, _, _ := ParseAPIKey(reader)
+		forged := DefaultAPIKeyPrefix + APIKeyDelimiter + id + APIKeyDelimiter + strings.Repeat("a", APIKeySecretSize)
+		unknown, _ := newKey(nil, nil)
+		for name, key := range map[string]string{"forged": forged, "unknown": unknown, "revoked": revoked, "expired": expired, "malformed": "gk_nope"} {
+			if recorder := request("/reports", key); recorder.Code != http.StatusUnauthorized {
+				t.Errorf("%s: expected 401, got %d", name, recorder.Code)
+			}
+		}
+	})
+
+	t.Run("Routes opt in to API keys", func(t *testing.T) {
+		if recorder := request("/sessions-only", reader); recorder.Code != http.StatusUnauthorized {
+			t.Errorf("Expected keys to be ignored on routes without AllowAPIKeys, got %d", recorder.Code)
+		}
+		if recorder := request("/humans", reader); recorder.Code != http.StatusUnauthorized {
+			t.Errorf("Expected the route to block the api_key mode, got %d", recorder.Code)
+		}
+	})
+
+	t.Run("Last use is tracked", func(t *testing.T) {
+		before := time.Now().Add(-time.Second)
+		request("/reports", reader)
+
+		// - Cache writes are asynchronous
+		deadline := time.Now().Add(time.Second)
+		for time.Now().Before(deadline) {
+			if used, ok := APIKeyLastUsed(context.Background(), manager, readerKey.ID); ok && !used.Before(before.Truncate(time.Second)) {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Error("Expected the key's last use to be recorded")
+	})
+}
+
+func TestAPIKeyHeaderConflict(t *testing.T) {
+	_, err := NewSessionManagerBuilder().
+		WithKey("k1", newBuilderKey(t)).
+		WithCookieConfig(&SessionAuthorizationConfiguration{APIKeyHeaderName: "X-Api-Key"}, nil).
+		Build()
+	if err == nil {
+		t.Error("Expected an API key header equal to the bearer header to be rejected")
+	}
+}
+
+// denyingRbacManager grants the exporter role every report permission, and denies service-1 exports.
+type denyingRbacManager struct {
+	roleRbacManager
+}
+
+func (m *denyingRbacManager) GetRoleNamedPermissions(_ context.Context, roleIdentifier string) (rbac.PermissionSet, error) {
+	if roleIdentifier == "exporter" {
+		return rbac.MustPermissionSet("reports.*"), nil
+	}
+	return rbac.PermissionSet{}, nil
+}
+
+func (m *denyingRbacManager) GetSubjectDeniedPermissions(_ context.Context, subjectIdentifier string) (*rbac.DeniedPermissions, error) {
+	if subjectIdentifier == "service-1" {
+		return &rbac.DeniedPermissions{NamedPermissions: rbac.MustPermissionSet("reports.export")}, nil
+	}
+	return nil, nil
+}
+
+func TestAPIKeyDenials(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newKey := func(claims map[string]string) (string, *APIKey) {
+		value, key, err := GenerateAPIKey("")
+		if err != nil {
+			t.Fatalf("Failed to generate the API key: %v", err)
+		}
+		key.Claims, key.Roles = claims, []string{"exporter"}
+		return value, key
+	}
+	exporter, exporterKey := newKey(map[string]string{DefaultBuilderSubjectClaim: "service-1"})
+	anonymous, anonymousKey := newKey(nil)
+
+	manager, err := NewSessionManagerBuilder().
+		WithKey("k1", newBuilderKey(t)).
+		WithRbac(&denyingRbacManager{roleRbacManager{namedRbacManager{cacheManager: internalcache.BuildDefaultCacheManager(nil)}}}).
+		WithAPIKeyStore(mapAPIKeyStore{exporterKey.ID: exporterKey, anonymousKey.ID: anonymousKey}).
+		Build()
+	if err != nil {
+		t.Fatalf("Expected Build to succeed, got %v", err)
+	}
+	defer manager.Close()
+
+	router := gin.New()
+	ctor := NewRouteConstructor(router, testBaseRoute{}, manager, nil)
+	handler := func(_ *struct{}, _ *Handler[testBaseRoute]) (*struct{}, *errors.AppError) {
+		return &struct{}{}, nil
+	}
+	POST(ctor, "/reports/export", AuthenticatedJSONAPI().WithAPIKeys().WithNamedPermissions("reports.export").WithRbacPolicy(rbac.PermissionsOnly), handler)
+	POST(ctor, "/reports/read", AuthenticatedJSONAPI().WithAPIKeys().WithNamedPermissions("reports.read").WithRbacPolicy(rbac.PermissionsOnly), handler)
+
+	request := func(path string, key string) int {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		req.Header.Set(DefaultAPIKeyHeaderName, key)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	if code := request("/reports/export", exporter); code != http.StatusUnauthorized {
+		t.Errorf("Expected the subject's denial to override the key's role, got %d", code)
+	}
+	if code := request("/reports/read", exporter); code != http.StatusOK {
+		t.Errorf("Expected the key's role to grant the other permissions, got %d", code)
+	}
+	if code := request("/reports/read", anonymous); code != http.StatusInternalServerError {
+		t.Errorf("Expected a key without a subject to fail closed when the manager denies permissions, got %d", code)
+	}
+}
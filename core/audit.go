@@ -0,0 +1,111 @@
+package core
+
+import (
+	"context"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/audit"
+	"github.com/grzegorzmaniak/gothic/helpers"
+	"github.com/grzegorzmaniak/gothic/rbac"
+	"go.uber.org/zap"
+)
+
+// AuditMode selects which requests of a route are recorded to the audit sink (see audit.SetSink).
+type AuditMode int
+
+const (
+	// AuditProtected records routes that require a session or RBAC permissions / roles (Default)
+	AuditProtected AuditMode = iota
+
+	// AuditAlways records every request of the route, e.g., a public login route
+	AuditAlways
+
+	// AuditNever never records the route, e.g., a noisy health check
+	AuditNever
+)
+
+const DefaultAuditTimeout = 10 * time.Second
+
+// shouldAudit reports whether the route's requests are recorded.
+func (config *APIConfiguration) shouldAudit() bool {
+	switch config.Audit {
+	case AuditAlways:
+		return true
+	case AuditNever:
+		return false
+	default:
+		return config.SessionRequired || config.Roles != nil || config.Permissions != nil || len(config.NamedPermissions) > 0
+	}
+}
+
+// recordRouteAudit records the outcome of the request once the response is written, it is deferred by the
+// executors. The event is captured on the request and written in the background.
+func recordRouteAudit(
+	ctx *gin.Context,
+	sessionManager SessionManager,
+	sessionConfig *APIConfiguration,
+	claims *SessionClaims,
+	started time.Time,
+) {
+	if !audit.Enabled() || !sessionConfig.shouldAudit() || ctx.Request == nil {
+		return
+	}
+
+	route := ctx.FullPath()
+	if route == "" {
+		route = ctx.Request.URL.Path
+	}
+
+	status := ctx.Writer.Status()
+	event := audit.Event{
+		Time:        time.Now(),
+		RequestID:   helpers.GetRequestID(ctx),
+		Method:      ctx.Request.Method,
+		Route:       route,
+		IP:          ctx.ClientIP(),
+		Status:      status,
+		Decision:    audit.DecisionForStatus(status),
+		Permissions: sessionConfig.NamedPermissions,
+		Duration:    time.Since(started),
+	}
+	if sessionConfig.Roles != nil {
+		event.Roles = *sessionConfig.Roles
+	}
+
+	if claims != nil && claims.HasSession {
+		event.SessionGroup, _ = claims.GetClaim(SessionModeClaim)
+		event.Impersonator, _ = claims.GetImpersonator()
+		if subject, err := sessionManager.GetSubjectIdentifier(claims); err == nil {
+			event.Subject = subject
+		}
+	}
+
+	if appErr := helpers.GetResponseError(ctx); appErr != nil {
+		event.Status = appErr.Code
+		event.Decision = audit.DecisionForStatus(appErr.Code)
+		event.Reason = appErr.Message
+		if details, ok := appErr.Details.(map[string]interface{}); ok {
+			if reasons, ok := details["reasons"].([]rbac.DenyReason); ok {
+				for _, reason := range reasons {
+					event.Reasons = append(event.Reasons, reason.Code)
+				}
+			}
+		}
+	}
+
+	requestCtx := context.WithoutCancel(ctx.Request.Context())
+	recorded := helpers.LifecycleOf(sessionManager).Go(func(backgroundCtx context.Context) {
+		auditCtx, cancel := context.WithTimeout(requestCtx, DefaultAuditTimeout)
+		defer cancel()
+		stop := context.AfterFunc(backgroundCtx, cancel)
+		defer stop()
+
+		if err := audit.Record(auditCtx, event); err != nil {
+			helpers.Logger(auditCtx).Error("Failed to record audit event", zap.Error(err), zap.String("request_id", event.RequestID))
+		}
+	})
+	if !recorded {
+		helpers.Logger(ctx).Warn("Audit event dropped, session manager is shutting down", zap.String("request_id", event.RequestID))
+	}
+}
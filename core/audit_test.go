@@ -0,0 +1,87 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/audit"
+	"github.com/grzegorzmaniak/gothic/errors"
+	"github.com/grzegorzmaniak/gothic/rbac"
+)
+
+type auditTestOutput struct {
+	Message string `json:"message"`
+}
+
+func TestRouteAudit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	events := make(chan audit.Event, 8)
+	audit.SetSink(audit.SinkFunc(func(_ context.Context, event audit.Event) error {
+		events <- event
+		return nil
+	}))
+	defer audit.SetSink(nil)
+
+	router := gin.New()
+	ctor := NewRouteConstructor(router, testBaseRoute{}, newMockSessionManager(t), nil)
+	handler := func(_ *struct{}, _ *Handler[testBaseRoute]) (*auditTestOutput, *errors.AppError) {
+		return &auditTestOutput{Message: "ok"}, nil
+	}
+	roles := []string{"admin"}
+	GET(ctor, "/protected/:id", AdminRoute(rbac.NewPermission(1)).WithRoles(roles...), handler)
+	GET(ctor, "/login", PublicRoute(), handler)
+	GET(ctor, "/signup", PublicRoute().WithAudit(AuditAlways), handler)
+	GET(ctor, "/health", AuthenticatedJSONAPI().WithAudit(AuditNever), handler)
+
+	serve := func(target string) {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+	}
+	next := func() (audit.Event, bool) {
+		select {
+		case event := <-events:
+			return event, true
+		case <-time.After(100 * time.Millisecond):
+			return audit.Event{}, false
+		}
+	}
+
+	t.Run("Protected routes record denials", func(t *testing.T) {
+		serve("/protected/42")
+		event, ok := next()
+		if !ok {
+			t.Fatal("Expected an audit event")
+		}
+		if event.Decision != audit.DecisionDeny || event.Status != http.StatusUnauthorized || event.Route != "/protected/:id" {
+			t.Errorf("Unexpected event %+v", event)
+		}
+		if len(event.Roles) != 1 || event.Roles[0] != "admin" || event.RequestID == "" {
+			t.Errorf("Expected the route requirements and request id, got %+v", event)
+		}
+	})
+
+	t.Run("Public routes are not recorded by default", func(t *testing.T) {
+		serve("/login")
+		if event, ok := next(); ok {
+			t.Errorf("Expected no event, got %+v", event)
+		}
+	})
+
+	t.Run("AuditAlways records public routes", func(t *testing.T) {
+		serve("/signup")
+		event, ok := next()
+		if !ok || event.Decision != audit.DecisionAllow || event.Status != http.StatusOK {
+			t.Errorf("Expected an allow event, got %+v", event)
+		}
+	})
+
+	t.Run("AuditNever skips protected routes", func(t *testing.T) {
+		serve("/health")
+		if event, ok := next(); ok {
+			t.Errorf("Expected no event, got %+v", event)
+		}
+	})
+}
@@ -0,0 +1,176 @@
+package core
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/errors"
+	"github.com/grzegorzmaniak/gothic/helpers"
+	"go.uber.org/zap"
+)
+
+// AuthLevel is the authentication context class (ACR) of a session, higher levels are stronger.
+type AuthLevel int
+
+const (
+	// AuthLevelNone is the level of sessions that never set one (Default)
+	AuthLevelNone AuthLevel = iota
+
+	// AuthLevelSingleFactor is a login with a single factor, e.g., a password or an identity provider
+	AuthLevelSingleFactor
+
+	// AuthLevelMultiFactor is a login completed with a second factor, e.g., TOTP or WebAuthn
+	AuthLevelMultiFactor
+)
+
+const (
+	AuthLevelClaim = "___al" // The AuthLevel of the session
+	AuthTimeClaim  = "___at" // Unix seconds the session last reached its AuthLevel
+
+	// StepUpRequiredError is the "error" detail of the 401 returned when a route needs a higher AuthLevel,
+	// clients should start the step-up (MFA) flow when they see it.
+	StepUpRequiredError = "step_up_required"
+)
+
+// GetAuthLevel returns the AuthLevel of the session, AuthLevelNone if it is not set.
+func (d *SessionClaims) GetAuthLevel() AuthLevel {
+	value, ok := d.GetClaim(AuthLevelClaim)
+	if !ok {
+		return AuthLevelNone
+	}
+	level, err := strconv.Atoi(value)
+	if err != nil || level < 0 {
+		return AuthLevelNone
+	}
+	return AuthLevel(level)
+}
+
+// GetAuthTime returns when the session last reached its AuthLevel.
+func (d *SessionClaims) GetAuthTime() (time.Time, bool) {
+	authTime, err := d.GetTime(AuthTimeClaim)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return authTime, true
+}
+
+// SetAuthLevel sets the AuthLevel of the session and stamps the AuthTimeClaim, use it on the claims passed to
+// SetSessionCookie / IssueBearerToken at login.
+func (d *SessionClaims) SetAuthLevel(level AuthLevel) {
+	d.SetInt(AuthLevelClaim, int64(level))
+	d.SetTime(AuthTimeClaim, time.Now())
+}
+
+// processAuthLevel rejects sessions below the route's MinimumAuthLevel with a step-up error.
+func processAuthLevel(
+	ctx *gin.Context,
+	sessionConfig *APIConfiguration,
+	claims *SessionClaims,
+) *errors.AppError {
+	if sessionConfig.MinimumAuthLevel <= AuthLevelNone {
+		return nil
+	}
+
+	current := AuthLevelNone
+	if claims != nil && claims.HasSession {
+		current = claims.GetAuthLevel()
+	}
+	if current >= sessionConfig.MinimumAuthLevel {
+		return nil
+	}
+
+	helpers.Logger(ctx).Debug("Session auth level is insufficient",
+		zap.Int("current_level", int(current)),
+		zap.Int("required_level", int(sessionConfig.MinimumAuthLevel)))
+	return errors.NewUnauthorized("Additional verification is required", nil, map[string]interface{}{
+		"error":          StepUpRequiredError,
+		"step_up":        true,
+		"current_level":  current,
+		"required_level": sessionConfig.MinimumAuthLevel,
+	})
+}
+
+// ElevateSession raises the AuthLevel of an existing session, e.g., once the subject completed a second factor,
+// without going through the login again. The claims keep their session identifier and the token keeps its
+// original expiration. Cookie sessions get a new session cookie, for bearer sessions the new token is returned.
+// Lowering the level is not possible, a new login is needed for that.
+func ElevateSession(
+	ctx *gin.Context,
+	sessionManager SessionManager,
+	claims *SessionClaims,
+	header *SessionHeader,
+	level AuthLevel,
+) (string, error) {
+	if ctx == nil {
+		return "", errors.NewInternalServerError("Context is nil", nil)
+	}
+	if sessionManager == nil {
+		return "", errors.NewInternalServerError("Session manager is nil", nil)
+	}
+	if claims == nil || !claims.HasSession || header == nil {
+		return "", errors.NewUnauthorized("A session is required to elevate", nil)
+	}
+	if level < claims.GetAuthLevel() {
+		return "", errors.NewBadRequest("The auth level of a session can not be lowered", nil)
+	}
+
+	elevated := &SessionClaims{Claims: make(map[string]string, len(claims.Claims)+2), HasSession: true}
+	for name, value := range claims.Claims {
+		elevated.Claims[name] = value
+	}
+	elevated.SetAuthLevel(level)
+
+	if !header.Bearer {
+		if err := SetRefreshSessionCookie(ctx, sessionManager, elevated, header); err != nil {
+			return "", err
+		}
+		*claims = *elevated
+		return "", nil
+	}
+
+	token, err := refreshBearerToken(ctx, sessionManager, elevated, header)
+	if err != nil {
+		return "", err
+	}
+	*claims = *elevated
+	return token, nil
+}
+
+// refreshBearerToken issues a bearer token with new claims that expires with the old one.
+func refreshBearerToken(
+	ctx *gin.Context,
+	sessionManager SessionManager,
+	claims *SessionClaims,
+	header *SessionHeader,
+) (string, error) {
+	authorizationData := sessionManager.GetAuthorizationConfiguration()
+	if authorizationData == nil {
+		return "", errors.NewInternalServerError("Authorization data is nil", nil)
+	}
+
+	group, ok := claims.GetClaim(SessionModeClaim)
+	if !ok {
+		return "", errors.NewInternalServerError("Session mode claim is missing", nil)
+	}
+
+	remaining := time.Until(time.Unix(header.IssuedAt+header.LifetimeSec, 0))
+	if remaining <= 0 {
+		return "", errors.NewUnauthorized("Session has expired", nil)
+	}
+
+	verifyTime := helpers.DefaultTimeDuration(authorizationData.VerifyTime, DefaultAuthorizationVerifyTime)
+	bearerHeader := NewSessionHeader(true, remaining, verifyTime)
+	token, err := CreateAuthorization(group, &bearerHeader, *authorizationData, claims, sessionManager)
+	if err != nil {
+		return "", errors.NewInternalServerError("Failed to create authorization", fmt.Errorf("failed to refresh bearer: %w", err))
+	}
+
+	if err = sessionManager.StoreSession(ctx, claims, nil); err != nil {
+		return "", errors.NewInternalServerError("Failed to store bearer", err)
+	}
+
+	publishSessionEvent(ctx, sessionManager, SessionEventRefreshed, claims, "")
+	return token, nil
+}
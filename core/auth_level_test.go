@@ -0,0 +1,132 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestProcessAuthLevel(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+
+	config := (&APIConfiguration{}).WithMinimumAuthLevel(AuthLevelMultiFactor)
+	claims := &SessionClaims{HasSession: true, Claims: map[string]string{}}
+	claims.SetAuthLevel(AuthLevelSingleFactor)
+
+	appErr := processAuthLevel(ctx, config, claims)
+	if appErr == nil || appErr.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected a 401 for a single factor session, got %v", appErr)
+	}
+	details, ok := appErr.Details.(map[string]interface{})
+	if !ok || details["error"] != StepUpRequiredError || details["required_level"] != AuthLevelMultiFactor {
+		t.Errorf("Expected the step-up details, got %v", appErr.Details)
+	}
+
+	claims.SetAuthLevel(AuthLevelMultiFactor)
+	if appErr = processAuthLevel(ctx, config, claims); appErr != nil {
+		t.Errorf("Expected a multi factor session to pass, got %v", appErr)
+	}
+	if appErr = processAuthLevel(ctx, &APIConfiguration{}, nil); appErr != nil {
+		t.Errorf("Expected routes without a minimum to pass, got %v", appErr)
+	}
+}
+
+func TestElevateSession(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mgr := newMockSessionManager(t)
+
+	login := func() *SessionClaims {
+		claims := &SessionClaims{Claims: map[string]string{"subject": "user-1"}}
+		claims.SetAuthLevel(AuthLevelSingleFactor)
+		return claims
+	}
+	extract := func(request *http.Request) (*SessionHeader, *SessionClaims) {
+		ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+		ctx.Request = request
+		header, claims, _, _, err := extractSession(ctx, mgr)
+		if err != nil || claims == nil {
+			t.Fatalf("Failed to extract the session: %v", err)
+		}
+		return header, claims
+	}
+
+	t.Run("Cookie sessions get a new cookie with the same identity", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+		ctx.Request = httptest.NewRequest(http.MethodPost, "/login", nil)
+		if err := SetSessionCookie(ctx, mgr, "default", login()); err != nil {
+			t.Fatalf("Failed to set the session cookie: %v", err)
+		}
+
+		request := httptest.NewRequest(http.MethodPost, "/mfa", nil)
+		for _, cookie := range recorder.Result().Cookies() {
+			request.AddCookie(cookie)
+		}
+		header, claims := extract(request)
+		sessionId := claims.Claims[SessionIdentifier]
+
+		elevateRecorder := httptest.NewRecorder()
+		elevateCtx, _ := gin.CreateTestContext(elevateRecorder)
+		elevateCtx.Request = request
+		token, err := ElevateSession(elevateCtx, mgr, claims, header, AuthLevelMultiFactor)
+		if err != nil || token != "" {
+			t.Fatalf("Expected the cookie session to be elevated, got %q / %v", token, err)
+		}
+		if claims.GetAuthLevel() != AuthLevelMultiFactor {
+			t.Errorf("Expected the passed claims to be updated, got level %d", claims.GetAuthLevel())
+		}
+
+		elevatedRequest := httptest.NewRequest(http.MethodGet, "/", nil)
+		for _, cookie := range elevateRecorder.Result().Cookies() {
+			elevatedRequest.AddCookie(cookie)
+		}
+		elevatedHeader, elevatedClaims := extract(elevatedRequest)
+		if elevatedClaims.GetAuthLevel() != AuthLevelMultiFactor || elevatedClaims.Claims[SessionIdentifier] != sessionId {
+			t.Errorf("Expected the same session at the multi factor level, got %v", elevatedClaims.Claims)
+		}
+		if expiry := elevatedHeader.IssuedAt + elevatedHeader.LifetimeSec; expiry > header.IssuedAt+header.LifetimeSec {
+			t.Errorf("Expected the elevated session to keep its expiration")
+		}
+	})
+
+	t.Run("Bearer sessions get a new token", func(t *testing.T) {
+		ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+		ctx.Request = httptest.NewRequest(http.MethodPost, "/login", nil)
+		token, err := IssueBearerToken(ctx, mgr, "default", login())
+		if err != nil {
+			t.Fatalf("Failed to issue the bearer token: %v", err)
+		}
+
+		request := httptest.NewRequest(http.MethodPost, "/mfa", nil)
+		request.Header.Set(DefaultSessionAuthorizationHeaderName, token)
+		header, claims := extract(request)
+
+		elevatedToken, err := ElevateSession(ctx, mgr, claims, header, AuthLevelMultiFactor)
+		if err != nil || elevatedToken == "" || elevatedToken == token {
+			t.Fatalf("Expected a new bearer token, got %v", err)
+		}
+
+		elevatedRequest := httptest.NewRequest(http.MethodGet, "/", nil)
+		elevatedRequest.Header.Set(DefaultSessionAuthorizationHeaderName, elevatedToken)
+		elevatedHeader, elevatedClaims := extract(elevatedRequest)
+		if !elevatedHeader.Bearer || elevatedClaims.GetAuthLevel() != AuthLevelMultiFactor {
+			t.Errorf("Expected a multi factor bearer, got %+v %v", elevatedHeader, elevatedClaims.Claims)
+		}
+	})
+
+	t.Run("The level can not be lowered", func(t *testing.T) {
+		ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+		ctx.Request = httptest.NewRequest(http.MethodPost, "/", nil)
+		claims := login()
+		claims.HasSession = true
+		claims.SetAuthLevel(AuthLevelMultiFactor)
+		header := NewSessionHeader(false, DefaultSessionExpiration, DefaultSessionRefreshTime)
+		if _, err := ElevateSession(ctx, mgr, claims, &header, AuthLevelSingleFactor); err == nil {
+			t.Error("Expected lowering the level to fail")
+		}
+	})
+}
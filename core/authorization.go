@@ -26,9 +26,16 @@ const (
 	DefaultAuthorizationExpiration = time.Hour * 24 * 30
 	DefaultAuthorizationVerifyTime = time.Minute * 10
 
+	DefaultRememberMeCookieName = "remember"
+	DefaultRememberMeExpiration = time.Hour * 24 * 30
+
 	MinimumSessionAuthorizationSize = 128
 	MaximumSessionAuthorizationSize = (1024 * 4) - 1
 
+	DefaultCookieChunkSize = 3800 // Leaves room for the cookie name within the 4KB browser limit
+	DefaultMaxCookieChunks = 4    // Many proxies limit a single request header to 8-16KB
+	CookieChunkSeparator   = "."  // Chunk cookies are named <CookieName>.<index>
+
 	MinimumSessionKeyIdSize = 1
 	MaximumSessionKeyIdSize = 32
 
@@ -46,13 +53,103 @@ type SessionAuthorizationConfiguration struct {
 	CookieDomain            string
 	CookieSecure            bool
 	CookieHttpOnly          bool
-	CookieSameSite          string
+	CookieSameSite          string // "Strict", "Lax" or "None" (Default: DefaultSessionAuthorizationSameSite)
 	AuthorizationHeaderName string
 	Delimiter               string
 	MaxAuthorizationSize    int
 	Expiration              time.Duration
 	RefreshTime             time.Duration
 	VerifyTime              time.Duration
+
+	// PayloadCompression compresses the claims before encryption, when that makes them smaller
+	// (Default: PayloadCompressionNone)
+	PayloadCompression PayloadCompression
+
+	// SplitCookies spreads tokens larger than CookieChunkSize across numbered cookies (session.0, session.1, ...),
+	// raising the size budget to CookieChunkSize * MaxCookieChunks. The AEAD covers the reassembled token, so
+	// missing, reordered or altered chunks are rejected (Default: false)
+	SplitCookies bool
+
+	// CookieChunkSize is the largest value of a single cookie when SplitCookies is set (Default: DefaultCookieChunkSize)
+	CookieChunkSize int
+
+	// MaxCookieChunks is the largest number of cookies a token is split into (Default: DefaultMaxCookieChunks)
+	MaxCookieChunks int
+
+	// KeyMode selects between encrypted (KeyModeSymmetric) and signed (KeyModeSigned) tokens, signed tokens can be
+	// verified by other services holding only the public key (Default: KeyModeSymmetric)
+	KeyMode KeyMode
+
+	// Binding ties sessions to the IP prefix and User-Agent of the client they were issued to (Default: disabled)
+	Binding SessionBinding
+
+	// DecodeCacheTTL keeps decoded tokens in memory, keyed by a hash of the token, so a token seen again within
+	// the TTL is not decrypted and decoded again. Expiry, VerifySession and RBAC are still checked on every request,
+	// cache hits are not counted as key usage. It is capped at MaximumDecodeCacheTTL and needs a session manager
+	// embedding DefaultSessionManager (Default: 0, disabled)
+	DecodeCacheTTL time.Duration
+
+	// DecodeCacheSize is the largest number of cached tokens (Default: DefaultDecodeCacheSize)
+	DecodeCacheSize int
+
+	// RotateOnIssue makes SetSessionCookie always issue a new SessionIdentifier, CSRF tie and RBAC cache
+	// identifier, even when the claims carry them from an earlier session, and revoke the session the request
+	// arrived with (see SessionRevoker). It guards logins against session fixation (Default: false)
+	RotateOnIssue bool
+
+	// CookiePartitioned sets the Partitioned (CHIPS) attribute on the session cookies, for apps embedded in third
+	// party iframes, it requires CookieSecure (Default: false)
+	CookiePartitioned bool
+
+	// CookiePrefix prefixes the session cookie names with "__Secure-" or "__Host-", the browser then enforces
+	// Secure (and for "__Host-" no Domain and Path=/), see CookiePrefixHost (Default: CookiePrefixNone)
+	CookiePrefix CookiePrefix
+
+	// HostOnlyCookies enforces __Host- session, remember-me and CSRF cookies: Secure, no Domain and Path=/, so a
+	// sibling subdomain can't plant or read them. Conflicting settings fail ValidateConfiguration (Default: false)
+	HostOnlyCookies bool
+
+	// RememberMeCookieName is the name of the remember-me cookie, see SetRememberMeCookie
+	// (Default: DefaultRememberMeCookieName)
+	RememberMeCookieName string
+
+	// RememberMeExpiration is the lifetime of remember-me tokens, it should outlive Expiration
+	// (Default: DefaultRememberMeExpiration)
+	RememberMeExpiration time.Duration
+
+	// APIKeyHeaderName is the header API keys are sent in on routes with AllowAPIKeys, it must differ from
+	// AuthorizationHeaderName (Default: DefaultAPIKeyHeaderName)
+	APIKeyHeaderName string
+
+	// SignalRefresh leaves refreshing session cookies to the client: routes set SessionRefreshDueHeader instead of
+	// rotating the cookie mid-request, and the client calls NewRefreshHandler, so concurrent tabs don't race to
+	// overwrite each other's refreshed cookie (Default: false)
+	SignalRefresh bool
+
+	// RefreshGracePeriod makes a refresh supersede the cookie it replaced: requests still carrying the old cookie,
+	// e.g., ones sent by another tab at the same time, are accepted for the grace period and rejected after it.
+	// Refreshes are tracked in the session manager's cache (Default: 0, old cookies stay valid until they expire)
+	RefreshGracePeriod time.Duration
+
+	// SessionLimits caps the concurrent sessions of a subject per session group, SessionLimitAnyGroup covers the
+	// groups without their own limit. Issuing a session beyond the limit revokes the least recently used ones, so 1
+	// keeps a single active session. It needs a session manager implementing SessionLister and SessionRevoker
+	// (Default: nil, unlimited)
+	SessionLimits map[string]int
+
+	// SessionMetadata writes the issue time, client IP, device label (see DeviceLabel) and, when the session manager
+	// implements GeoResolverProvider, the approximate location into new sessions, for ListActiveSessions. Signed
+	// tokens (KeyModeSigned) can be read by the client (Default: false)
+	SessionMetadata bool
+}
+
+// authorizationSizeBudget returns the largest token allowed by the configuration.
+func authorizationSizeBudget(authorizationData *SessionAuthorizationConfiguration) int {
+	if authorizationData.SplitCookies {
+		return helpers.DefaultInt(authorizationData.CookieChunkSize, DefaultCookieChunkSize) *
+			helpers.DefaultInt(authorizationData.MaxCookieChunks, DefaultMaxCookieChunks)
+	}
+	return helpers.DefaultInt(authorizationData.MaxAuthorizationSize, MaximumSessionAuthorizationSize)
 }
 
 func ensureBasicClaims(group string, claims *SessionClaims, sessionManager SessionManager) error {
@@ -92,7 +189,28 @@ func ensureBasicClaims(group string, claims *SessionClaims, sessionManager Sessi
 	return nil
 }
 
-// CreateAuthorization creates a secure, encrypted, and versioned authorization token.
+// encryptAuthorization returns the key id and the encoded AES-GCM ciphertext of the value.
+func encryptAuthorization(sessionManager SessionManager, authorizationValue string) (string, string, error) {
+	sessionKey, keyId, err := sessionManager.GetSessionKey()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get session key: %w", err)
+	}
+
+	if len(keyId) < MinimumSessionKeyIdSize || len(keyId) > MaximumSessionKeyIdSize {
+		return "", "", fmt.Errorf("invalid keyId size: must be between %d and %d characters", MinimumSessionKeyIdSize, MaximumSessionKeyIdSize)
+	}
+
+	// Encrypt the value with the keyId and version as associated data for integrity.
+	associatedData := []byte(keyId + SessionAuthorizationVersion)
+	encryptedValue, err := helpers.SymmetricEncrypt(sessionKey, []byte(authorizationValue), associatedData)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to encrypt authorization value: %w", err)
+	}
+
+	return keyId, base64.RawURLEncoding.EncodeToString(encryptedValue), nil
+}
+
+// CreateAuthorization creates a secure, encrypted (or signed, see KeyModeSigned), and versioned authorization token.
 func CreateAuthorization(
 	group string,
 	authorizationHeader *SessionHeader,
@@ -114,12 +232,16 @@ func CreateAuthorization(
 		return "", fmt.Errorf("failed to ensure basic claims: %w", err)
 	}
 
+	if err := ValidateClaims(claims); err != nil {
+		return "", fmt.Errorf("claims do not match the claim schema: %w", err)
+	}
+
 	authorizationHeaderString, err := authorizationHeader.Encode()
 	if err != nil {
 		return "", fmt.Errorf("failed to encode header: %w", err)
 	}
 
-	AuthorizationPayload, err := claims.EncodePayload()
+	AuthorizationPayload, err := claims.EncodeCompressedPayload(authorizationData.PayloadCompression)
 	if err != nil {
 		return "", fmt.Errorf("failed to encode payload: %w", err)
 	}
@@ -127,33 +249,34 @@ func CreateAuthorization(
 	delimiter := helpers.DefaultString(authorizationData.Delimiter, DefaultSessionAuthorizationDelimiter)
 	authorizationValue := fmt.Sprintf("%s%s%s", authorizationHeaderString, delimiter, AuthorizationPayload)
 
-	sessionKey, keyId, err := sessionManager.GetSessionKey()
-	if err != nil {
-		return "", fmt.Errorf("failed to get session key: %w", err)
+	version, keyId, encodedValue := SessionAuthorizationVersion, "", ""
+	if authorizationData.KeyMode == KeyModeSigned {
+		version = SignedAuthorizationVersion
+		keyId, encodedValue, err = signAuthorization(sessionManager, delimiter, authorizationValue)
+	} else {
+		keyId, encodedValue, err = encryptAuthorization(sessionManager, authorizationValue)
 	}
-
-	if len(keyId) < MinimumSessionKeyIdSize || len(keyId) > MaximumSessionKeyIdSize {
-		return "", fmt.Errorf("invalid keyId size: must be between %d and %d characters", MinimumSessionKeyIdSize, MaximumSessionKeyIdSize)
-	}
-
-	// Encrypt the value with the keyId and version as associated data for integrity.
-	associatedData := []byte(keyId + SessionAuthorizationVersion)
-	encryptedValue, err := helpers.SymmetricEncrypt(sessionKey, []byte(authorizationValue), associatedData)
 	if err != nil {
-		return "", fmt.Errorf("failed to encrypt authorization value: %w", err)
+		return "", err
 	}
-
-	encodedValue := base64.RawURLEncoding.EncodeToString(encryptedValue)
+	keyUsage.issued(keyId)
 
 	var sb strings.Builder
 
-	sb.Grow(len(SessionAuthorizationVersion) + len(delimiter) + len(keyId) + len(delimiter) + len(encodedValue))
-	sb.WriteString(SessionAuthorizationVersion)
+	sb.Grow(len(version) + len(delimiter) + len(keyId) + len(delimiter) + len(encodedValue))
+	sb.WriteString(version)
 	sb.WriteString(delimiter)
 	sb.WriteString(keyId)
 	sb.WriteString(delimiter)
 	sb.WriteString(encodedValue)
 
+	// - Fail loudly here, the browser would drop the cookie without a word
+	maxSize := authorizationSizeBudget(&authorizationData)
+	if sb.Len() > maxSize {
+		return "", fmt.Errorf("%w: %d bytes exceeds the maximum of %d bytes (%d bytes of claims)",
+			ErrAuthorizationTooLarge, sb.Len(), maxSize, len(AuthorizationPayload))
+	}
+
 	return sb.String(), nil
 }
 
@@ -1,8 +1,10 @@
 package core
 
 import (
+	"context"
 	"encoding/binary"
 	"fmt"
+	"net/http"
 	"time"
 
 	"github.com/eko/gocache/lib/v4/store"
@@ -28,15 +30,26 @@ func GetAuthorizationBearer(
 		return "", fmt.Errorf("authorization data is nil")
 	}
 
-	authorizationHeaderName := helpers.DefaultString(authorizationData.AuthorizationHeaderName, DefaultSessionAuthorizationHeaderName)
-	authorizationHeader := ctx.GetHeader(authorizationHeaderName)
+	authorizationHeader := authorizationBearerValue(ctx, authorizationData)
 	if authorizationHeader == "" {
-		return "", fmt.Errorf("authorization header '%s' is empty", authorizationHeaderName)
+		return "", fmt.Errorf("authorization header '%s' is empty", helpers.DefaultString(authorizationData.AuthorizationHeaderName, DefaultSessionAuthorizationHeaderName))
 	}
 
 	return authorizationHeader, nil
 }
 
+// canonicalAuthorizationHeaderName saves canonicalizing (and allocating) the default header name per request.
+var canonicalAuthorizationHeaderName = http.CanonicalHeaderKey(DefaultSessionAuthorizationHeaderName)
+
+// authorizationBearerValue returns the bearer token of the request, or "".
+func authorizationBearerValue(ctx *gin.Context, authorizationData *SessionAuthorizationConfiguration) string {
+	name := authorizationData.AuthorizationHeaderName
+	if name == "" || name == DefaultSessionAuthorizationHeaderName {
+		name = canonicalAuthorizationHeaderName
+	}
+	return ctx.GetHeader(name)
+}
+
 func IssueBearerToken(
 	ctx *gin.Context,
 	sessionManager SessionManager,
@@ -73,6 +86,11 @@ func IssueCustomBearerToken(
 		return "", errors.NewInternalServerError("Authorization data is nil", nil)
 	}
 
+	if err := bindSession(ctx, authorizationData, claims); err != nil {
+		return "", errors.NewInternalServerError("Failed to bind session", err)
+	}
+	setSessionMetadata(ctx, sessionManager, authorizationData, claims)
+
 	headerExpiration := helpers.DefaultTimeDuration(authorizationData.Expiration, DefaultAuthorizationExpiration)
 	headerRefreshTime := helpers.DefaultTimeDuration(authorizationData.VerifyTime, DefaultAuthorizationVerifyTime)
 	authorizationHeader := NewSessionHeader(true, headerExpiration, headerRefreshTime)
@@ -85,7 +103,11 @@ func IssueCustomBearerToken(
 	if err = sessionManager.StoreSession(ctx, claims, nil); err != nil {
 		return "", errors.NewInternalServerError("Failed to store bearer", err)
 	}
+	if err = enforceSessionLimit(ctx, sessionManager, authorizationData, group, claims); err != nil {
+		return "", errors.NewInternalServerError("Failed to enforce the session limit", err)
+	}
 
+	publishSessionEvent(ctx, sessionManager, SessionEventIssued, claims, "")
 	return authorizationString, nil
 }
 
@@ -189,7 +211,11 @@ func BearerSetCache(
 
 	// - The cache TTL should be slightly longer than the refresh period to avoid premature eviction.
 	cacheTTL := refreshPeriod + (5 * time.Minute)
-	if err = cache.Set(ctx, cacheKey, b, store.WithExpiration(cacheTTL)); err != nil {
+	// - Transient write failures are retried with the session manager's policy, see helpers.RetryPolicyProvider
+	err = helpers.RetryPolicyOf(sessionManager).Do(ctx, func(retryCtx context.Context) error {
+		return cache.Set(retryCtx, cacheKey, b, store.WithExpiration(cacheTTL))
+	})
+	if err != nil {
 		return fmt.Errorf("failed to set cache: %w", err)
 	}
 
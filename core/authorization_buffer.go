@@ -0,0 +1,96 @@
+package core
+
+import (
+	"crypto/cipher"
+	"encoding/base64"
+	"fmt"
+	"sync"
+	"unsafe"
+
+	"github.com/grzegorzmaniak/gothic/helpers"
+)
+
+const (
+	// maximumCachedSessionCiphers bounds sessionCiphers, it is cleared once full. Keys rotate rarely, so this is
+	// only reached by session managers generating keys on the fly.
+	maximumCachedSessionCiphers = 64
+
+	// maximumPooledAuthorizationBuffer stops buffers grown by oversized (e.g., chunked) tokens from being pooled
+	maximumPooledAuthorizationBuffer = MaximumSessionAuthorizationSize * 4
+)
+
+// authorizationBuffer is the scratch space a token is decoded in, every request reuses one from
+// authorizationBuffers. Slices into it must not outlive putAuthorizationBuffer.
+type authorizationBuffer struct {
+	token          []byte // The base64 decoded, then decrypted in place, token
+	decoded        []byte // The base64 decoded header or payload
+	associatedData []byte
+}
+
+var authorizationBuffers = sync.Pool{
+	New: func() any {
+		return &authorizationBuffer{
+			token:          make([]byte, 0, MaximumSessionAuthorizationSize),
+			decoded:        make([]byte, 0, MaximumSessionAuthorizationSize),
+			associatedData: make([]byte, 0, MaximumSessionKeyIdSize+MaximumAuthorizationVersionSize),
+		}
+	},
+}
+
+func getAuthorizationBuffer() *authorizationBuffer {
+	return authorizationBuffers.Get().(*authorizationBuffer)
+}
+
+func putAuthorizationBuffer(buffer *authorizationBuffer) {
+	if cap(buffer.token) > maximumPooledAuthorizationBuffer || cap(buffer.decoded) > maximumPooledAuthorizationBuffer {
+		return
+	}
+	authorizationBuffers.Put(buffer)
+}
+
+// stringBytes returns the bytes of a string without copying them, they must not be modified.
+func stringBytes(value string) []byte {
+	return unsafe.Slice(unsafe.StringData(value), len(value))
+}
+
+// decodeBase64Into decodes raw URL base64 into dst, growing it if needed.
+func decodeBase64Into(dst []byte, src []byte) ([]byte, error) {
+	size := base64.RawURLEncoding.DecodedLen(len(src))
+	if cap(dst) < size {
+		dst = make([]byte, size)
+	}
+	written, err := base64.RawURLEncoding.Decode(dst[:size], src)
+	if err != nil {
+		return dst[:0], err
+	}
+	return dst[:written], nil
+}
+
+// sessionCiphers caches the AES-GCM AEAD of each session key, building one costs three allocations.
+var sessionCiphers = struct {
+	sync.RWMutex
+	byKey map[string]cipher.AEAD
+}{byKey: make(map[string]cipher.AEAD)}
+
+// sessionCipher returns the (cached) AES-GCM AEAD of a session key.
+func sessionCipher(key []byte) (cipher.AEAD, error) {
+	sessionCiphers.RLock()
+	aead, ok := sessionCiphers.byKey[string(key)]
+	sessionCiphers.RUnlock()
+	if ok {
+		return aead, nil
+	}
+
+	aead, err := helpers.NewSymmetricAEAD(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the session cipher: %w", err)
+	}
+
+	sessionCiphers.Lock()
+	defer sessionCiphers.Unlock()
+	if len(sessionCiphers.byKey) >= maximumCachedSessionCiphers {
+		clear(sessionCiphers.byKey)
+	}
+	sessionCiphers.byKey[string(key)] = aead
+	return aead, nil
+}
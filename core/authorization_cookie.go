@@ -2,26 +2,116 @@ package core
 
 import (
 	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/gin-gonic/gin"
 	"github.com/grzegorzmaniak/gothic/errors"
 	"github.com/grzegorzmaniak/gothic/helpers"
 )
 
+func setSessionCookiePart(
+	ctx *gin.Context,
+	authData *SessionAuthorizationConfiguration,
+	name string,
+	value string,
+	maxAge int,
+) {
+	setCookie(ctx, sessionCookieConfig(authData, name, value, maxAge))
+}
+
 func applySessionCookie(
 	ctx *gin.Context,
 	authData *SessionAuthorizationConfiguration,
 	value string,
 	maxAge int,
 ) {
-	ctx.SetCookie(
-		helpers.DefaultString(authData.CookieName, DefaultSessionAuthorizationName),
-		value,
-		maxAge,
-		helpers.DefaultString(authData.CookiePath, DefaultSessionAuthorizationPath),
-		helpers.DefaultString(authData.CookieDomain, DefaultSessionAuthorizationDomain),
-		helpers.DefaultBool(authData.CookieSecure, DefaultSessionAuthorizationSecure),
-		helpers.DefaultBool(authData.CookieHttpOnly, DefaultSessionAuthorizationHttpOnly),
-	)
+	name := helpers.DefaultString(authData.CookieName, DefaultSessionAuthorizationName)
+	if !authData.SplitCookies {
+		setSessionCookiePart(ctx, authData, name, value, maxAge)
+		return
+	}
+
+	// - Tokens that fit a single cookie keep the plain name, clearing removes every chunk
+	var chunks []string
+	if value != "" && maxAge >= 0 {
+		chunks = SplitAuthorization(value, helpers.DefaultInt(authData.CookieChunkSize, DefaultCookieChunkSize))
+	}
+
+	switch {
+	case len(chunks) > 1:
+		for index, chunk := range chunks {
+			setSessionCookiePart(ctx, authData, cookieChunkName(name, index), chunk, maxAge)
+		}
+		if _, ok := requestCookie(ctx.Request, sessionCookieName(authData)); ok {
+			setSessionCookiePart(ctx, authData, name, "", -1)
+		}
+	case len(chunks) == 1:
+		setSessionCookiePart(ctx, authData, name, chunks[0], maxAge)
+	default:
+		setSessionCookiePart(ctx, authData, name, "", -1)
+	}
+
+	// - Expire chunks left over from a previous, larger token
+	if ctx.Request == nil {
+		return
+	}
+	for _, cookie := range ctx.Request.Cookies() {
+		index, ok := parseCookieChunkIndex(sessionCookieName(authData), cookie.Name)
+		if ok && (len(chunks) < 2 || index >= len(chunks)) {
+			setSessionCookiePart(ctx, authData, cookieChunkName(name, index), "", -1)
+		}
+	}
+}
+
+// SplitAuthorization splits a token into chunks of at most chunkSize bytes, see
+// SessionAuthorizationConfiguration.SplitCookies.
+func SplitAuthorization(value string, chunkSize int) []string {
+	if chunkSize <= 0 || len(value) <= chunkSize {
+		return []string{value}
+	}
+
+	chunks := make([]string, 0, (len(value)+chunkSize-1)/chunkSize)
+	for start := 0; start < len(value); start += chunkSize {
+		end := min(start+chunkSize, len(value))
+		chunks = append(chunks, value[start:end])
+	}
+	return chunks
+}
+
+func cookieChunkName(name string, index int) string {
+	return name + CookieChunkSeparator + strconv.Itoa(index)
+}
+
+// parseCookieChunkIndex returns the index of a chunk cookie of the session cookie.
+func parseCookieChunkIndex(name string, cookieName string) (int, bool) {
+	suffix, found := strings.CutPrefix(cookieName, name+CookieChunkSeparator)
+	if !found {
+		return 0, false
+	}
+	index, err := strconv.Atoi(suffix)
+	return index, err == nil && index >= 0
+}
+
+// getChunkedSessionCookie reassembles session.0, session.1, ... in order, stopping at the first missing index.
+func getChunkedSessionCookie(ctx *gin.Context, authorizationData *SessionAuthorizationConfiguration, name string) (string, error) {
+	var sb strings.Builder
+	maxChunks := helpers.DefaultInt(authorizationData.MaxCookieChunks, DefaultMaxCookieChunks)
+	for index := 0; index < maxChunks; index++ {
+		chunk, err := ctx.Cookie(cookieChunkName(name, index))
+		if err != nil || chunk == "" {
+			break
+		}
+		sb.WriteString(chunk)
+	}
+
+	if sb.Len() == 0 {
+		return "", fmt.Errorf("failed to get cookie '%s' or its chunks", name)
+	}
+	return sb.String(), nil
 }
 
 func GetSessionCookie(
@@ -41,15 +131,61 @@ func GetSessionCookie(
 		return "", fmt.Errorf("authorization data is nil")
 	}
 
-	authorizationCookieName := helpers.DefaultString(authorizationData.CookieName, DefaultSessionAuthorizationName)
-	authorizationCookieValue, err := ctx.Cookie(authorizationCookieName)
-	if err != nil || authorizationCookieValue == "" {
-		return "", fmt.Errorf("failed to get cookie '%s': %w", authorizationCookieName, err)
+	authorizationCookieValue, err := sessionCookieValue(ctx, authorizationData)
+	if err != nil {
+		return "", err
+	}
+	if authorizationCookieValue == "" {
+		authorizationCookieName := sessionCookieName(authorizationData)
+		return "", fmt.Errorf("failed to get cookie '%s': %w", authorizationCookieName, http.ErrNoCookie)
 	}
 
 	return authorizationCookieValue, nil
 }
 
+// sessionCookieValue returns the session cookie of the request (reassembled when SplitCookies is set), or "" when
+// there is none.
+func sessionCookieValue(ctx *gin.Context, authorizationData *SessionAuthorizationConfiguration) (string, error) {
+	authorizationCookieName := sessionCookieName(authorizationData)
+	authorizationCookieValue, ok := requestCookie(ctx.Request, authorizationCookieName)
+	if (!ok || authorizationCookieValue == "") && authorizationData.SplitCookies {
+		return getChunkedSessionCookie(ctx, authorizationData, authorizationCookieName)
+	}
+	return authorizationCookieValue, nil
+}
+
+// requestCookie returns the value of the first cookie called name, like gin.Context.Cookie, but without parsing
+// (and allocating) every other cookie of the request.
+func requestCookie(request *http.Request, name string) (string, bool) {
+	if request == nil {
+		return "", false
+	}
+	for _, line := range request.Header["Cookie"] {
+		for line != "" {
+			var part string
+			part, line, _ = strings.Cut(line, ";")
+			cookieName, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+			if !ok || strings.TrimSpace(cookieName) != name {
+				continue
+			}
+
+			if len(value) > 1 && value[0] == '"' && value[len(value)-1] == '"' {
+				value = value[1 : len(value)-1]
+			}
+			// - gin unescapes cookie values, tokens never need it
+			if strings.ContainsAny(value, "%+") {
+				unescaped, err := url.QueryUnescape(value)
+				if err != nil {
+					return "", false
+				}
+				value = unescaped
+			}
+			return value, true
+		}
+	}
+	return "", false
+}
+
 func SetSessionCookie(
 	ctx *gin.Context,
 	sessionManager SessionManager,
@@ -61,6 +197,14 @@ func SetSessionCookie(
 	}
 
 	authorizationData := sessionManager.GetAuthorizationConfiguration()
+	if authorizationData != nil && authorizationData.RotateOnIssue && ctx != nil && claims != nil {
+		if err := revokePreviousSessions(ctx, sessionManager, claims); err != nil {
+			return errors.NewInternalServerError("Failed to revoke the previous session", err)
+		}
+		for _, name := range sessionIdentityClaims {
+			delete(claims.Claims, name)
+		}
+	}
 	return SetCustomSessionCookie(ctx, sessionManager, group, claims, authorizationData)
 }
 
@@ -87,6 +231,15 @@ func SetCustomSessionCookie(
 		return errors.NewInternalServerError("Authorization data is nil", nil)
 	}
 
+	if err := authorizationData.ValidateCookies(); err != nil {
+		return errors.NewInternalServerError("Invalid session cookie configuration", err)
+	}
+
+	if err := bindSession(ctx, authorizationData, claims); err != nil {
+		return errors.NewInternalServerError("Failed to bind session", err)
+	}
+	setSessionMetadata(ctx, sessionManager, authorizationData, claims)
+
 	// - Create the Authorization header
 	sessionExpiration := helpers.DefaultTimeDuration(authorizationData.Expiration, DefaultSessionExpiration)
 	sessionRefreshTime := helpers.DefaultTimeDuration(authorizationData.RefreshTime, DefaultSessionRefreshTime)
@@ -99,6 +252,9 @@ func SetCustomSessionCookie(
 	if err = sessionManager.StoreSession(ctx, claims, nil); err != nil {
 		return errors.NewInternalServerError("Failed to store session", err)
 	}
+	if err = enforceSessionLimit(ctx, sessionManager, authorizationData, group, claims); err != nil {
+		return errors.NewInternalServerError("Failed to enforce the session limit", err)
+	}
 
 	expirationSeconds := int(helpers.DefaultTimeDuration(authorizationData.Expiration, DefaultSessionExpiration).Seconds())
 	applySessionCookie(ctx, authorizationData, authorizationString, expirationSeconds)
@@ -109,6 +265,7 @@ func SetCustomSessionCookie(
 		return errors.NewInternalServerError("Failed to set CSRF Authorization", err)
 	}
 
+	publishSessionEvent(ctx, sessionManager, SessionEventIssued, claims, "")
 	return nil
 }
 
@@ -157,6 +314,11 @@ func SetCustomRefreshSessionCookie(
 		return errors.NewInternalServerError("Authorization data is nil", nil)
 	}
 
+	if err := authorizationData.ValidateCookies(); err != nil {
+		return errors.NewInternalServerError("Invalid session cookie configuration", err)
+	}
+
+	refreshedAt := time.Now()
 	authorizationString, err := CreateRefreshAuthorization(*authorizationData, claims, header, sessionManager)
 	if err != nil {
 		return err
@@ -164,7 +326,9 @@ func SetCustomRefreshSessionCookie(
 
 	expirationSeconds := int(helpers.DefaultTimeDuration(authorizationData.Expiration, DefaultSessionExpiration).Seconds())
 	applySessionCookie(ctx, authorizationData, authorizationString, expirationSeconds)
+	recordSessionRefresh(ctx, sessionManager, authorizationData, claims, header, refreshedAt)
 
+	publishSessionEvent(ctx, sessionManager, SessionEventRefreshed, claims, "")
 	return nil
 }
 
@@ -0,0 +1,159 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestSplitAuthorization(t *testing.T) {
+	chunks := SplitAuthorization(strings.Repeat("a", 25), 10)
+	if len(chunks) != 3 || chunks[0] != strings.Repeat("a", 10) || chunks[2] != "aaaaa" {
+		t.Errorf("Unexpected chunks %v", chunks)
+	}
+	if chunks = SplitAuthorization("short", 10); len(chunks) != 1 || chunks[0] != "short" {
+		t.Errorf("Expected a single chunk, got %v", chunks)
+	}
+}
+
+func TestSplitSessionCookies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mgr := newMockSessionManager(t)
+	mgr.authorizationData.SplitCookies = true
+	mgr.authorizationData.CookieChunkSize = 300
+	mgr.authorizationData.MaxCookieChunks = 10
+
+	issue := func(claims *SessionClaims, existing []*http.Cookie) []*http.Cookie {
+		t.Helper()
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+		ctx.Request = httptest.NewRequest(http.MethodPost, "/login", nil)
+		for _, cookie := range existing {
+			ctx.Request.AddCookie(cookie)
+		}
+		if err := SetSessionCookie(ctx, mgr, "user", claims); err != nil {
+			t.Fatalf("Failed to set session cookie: %v", err)
+		}
+		return recorder.Result().Cookies()
+	}
+
+	extract := func(cookies []*http.Cookie) (*SessionClaims, error) {
+		ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+		ctx.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+		for _, cookie := range cookies {
+			if cookie.MaxAge >= 0 && strings.HasPrefix(cookie.Name, DefaultSessionAuthorizationName) {
+				ctx.Request.AddCookie(cookie)
+			}
+		}
+		_, claims, _, _, err := extractSession(ctx, mgr)
+		return claims, err
+	}
+
+	large := strings.Repeat("billing.invoices.read,", 40)
+	cookies := issue(&SessionClaims{Claims: map[string]string{"permissions": large}}, nil)
+
+	chunkCount := 0
+	for _, cookie := range cookies {
+		if strings.HasPrefix(cookie.Name, DefaultSessionAuthorizationName+CookieChunkSeparator) {
+			chunkCount++
+			if len(cookie.Value) > 300 {
+				t.Errorf("Chunk %s is larger than the chunk size: %d", cookie.Name, len(cookie.Value))
+			}
+		}
+	}
+	if chunkCount < 2 {
+		t.Fatalf("Expected the token to be split, got %d chunks", chunkCount)
+	}
+
+	t.Run("Chunks are reassembled", func(t *testing.T) {
+		claims, err := extract(cookies)
+		if err != nil || claims == nil {
+			t.Fatalf("Failed to extract session: %v", err)
+		}
+		if permissions, _ := claims.GetClaim("permissions"); permissions != large {
+			t.Error("Reassembled claims do not match")
+		}
+	})
+
+	t.Run("Reordered chunks are rejected", func(t *testing.T) {
+		swapped := make([]*http.Cookie, 0, len(cookies))
+		for _, cookie := range cookies {
+			copied := *cookie
+			switch copied.Name {
+			case DefaultSessionAuthorizationName + ".0":
+				copied.Name = DefaultSessionAuthorizationName + ".1"
+			case DefaultSessionAuthorizationName + ".1":
+				copied.Name = DefaultSessionAuthorizationName + ".0"
+			}
+			swapped = append(swapped, &copied)
+		}
+		if _, err := extract(swapped); err == nil {
+			t.Error("Expected reordered chunks to fail decryption")
+		}
+	})
+
+	t.Run("A smaller token expires the stale chunks", func(t *testing.T) {
+		mgr.authorizationData.CookieChunkSize = 2048
+		defer func() { mgr.authorizationData.CookieChunkSize = 300 }()
+
+		replaced := issue(&SessionClaims{}, cookies)
+		expired := map[string]bool{}
+		for _, cookie := range replaced {
+			if cookie.MaxAge < 0 {
+				expired[cookie.Name] = true
+			}
+		}
+		for index := 0; index < chunkCount; index++ {
+			if !expired[cookieChunkName(DefaultSessionAuthorizationName, index)] {
+				t.Errorf("Expected chunk %d to be expired", index)
+			}
+		}
+		if !hasCookie(replaced, DefaultSessionAuthorizationName) {
+			t.Error("Expected the token to fit the plain cookie")
+		}
+		if _, err := extract(replaced); err != nil {
+			t.Errorf("Expected the single cookie session to extract, got %v", err)
+		}
+	})
+}
+
+func hasCookie(cookies []*http.Cookie, name string) bool {
+	for _, cookie := range cookies {
+		if cookie.Name == name && cookie.MaxAge >= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func TestRequestCookie(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	request.Header.Add("Cookie", "other=1; session=first; quoted=\"abc\"")
+	request.Header.Add("Cookie", "session=second; escaped=a%2Bb")
+
+	tests := []struct {
+		name  string
+		want  string
+		found bool
+	}{
+		{name: "session", want: "first", found: true},
+		{name: "quoted", want: "abc", found: true},
+		{name: "escaped", want: "a+b", found: true},
+		{name: "missing", want: "", found: false},
+		{name: "sess", want: "", found: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+			ctx.Request = request
+			ginValue, ginErr := ctx.Cookie(tt.name)
+			value, found := requestCookie(request, tt.name)
+			if value != tt.want || found != tt.found || value != ginValue || found != (ginErr == nil) {
+				t.Errorf("requestCookie(%q) = %q, %v, gin returns %q, %v", tt.name, value, found, ginValue, ginErr)
+			}
+		})
+	}
+}
@@ -2,89 +2,137 @@ package core
 
 import (
 	"bytes"
-	"encoding/base64"
+	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/grzegorzmaniak/gothic/helpers"
 	"strings"
 )
 
+// errInvalidSessionParts wraps the failures to verify a token, as opposed to failures to decode a verified one.
+var errInvalidSessionParts = errors.New("failed to extract session parts")
+
 const (
 	SourceNone   = "none"
 	SourceHeader = "header"
 	SourceCookie = "cookie"
 )
 
+// decryptAuthorization decrypts the AES-GCM part of a KeyModeSymmetric token, in place in the buffer.
+func decryptAuthorization(sessionManager SessionManager, keyVersion string, keyId string, encryptedPart []byte, buffer *authorizationBuffer) ([]byte, error) {
+	sessionKey, err := sessionManager.GetOldSessionKey(keyId)
+	if err != nil {
+		keyUsage.failed(UnknownKeyId)
+		return nil, fmt.Errorf("failed to retrieve session key: %w", err)
+	}
+
+	buffer.token, err = decodeBase64Into(buffer.token, encryptedPart)
+	if err != nil {
+		return nil, fmt.Errorf("failed to base64-decode token: %w", err)
+	}
+
+	aead, err := sessionCipher(sessionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	// - The associated data is what authenticates the ciphertext.
+	buffer.associatedData = append(append(buffer.associatedData[:0], keyId...), keyVersion...)
+	decryptedValue, err := helpers.SymmetricDecryptInPlace(aead, buffer.token, buffer.associatedData)
+	if err != nil {
+		keyUsage.failed(keyId)
+		return nil, err
+	}
+	keyUsage.validated(keyId)
+	return decryptedValue, nil
+}
+
+// extractSessionAuthorizationParts verifies a token and returns its (still encoded) header and payload.
 func extractSessionAuthorizationParts(
 	AuthorizationData *SessionAuthorizationConfiguration,
 	sessionManager SessionManager,
 	authorizationValue string,
 ) (header string, payload string, err error) {
+	buffer := getAuthorizationBuffer()
+	defer putAuthorizationBuffer(buffer)
+
+	headerBytes, payloadBytes, err := extractSessionAuthorizationBytes(AuthorizationData, sessionManager, authorizationValue, buffer)
+	if err != nil {
+		return "", "", err
+	}
+	return string(headerBytes), string(payloadBytes), nil
+}
+
+// extractSessionAuthorizationBytes is extractSessionAuthorizationParts without copies, the header and payload
+// point into the buffer.
+func extractSessionAuthorizationBytes(
+	AuthorizationData *SessionAuthorizationConfiguration,
+	sessionManager SessionManager,
+	authorizationValue string,
+	buffer *authorizationBuffer,
+) (header []byte, payload []byte, err error) {
 	// --- 1. Grouped Validations ---
 	if AuthorizationData == nil {
-		return "", "", fmt.Errorf("AuthorizationData cannot be nil")
+		return nil, nil, fmt.Errorf("AuthorizationData cannot be nil")
 	}
 
 	delimiter := helpers.DefaultString(AuthorizationData.Delimiter, DefaultSessionAuthorizationDelimiter)
 	name := helpers.DefaultString(AuthorizationData.CookieName, DefaultSessionAuthorizationName)
 
 	if authorizationValue == "" {
-		return "", "", fmt.Errorf("authorization token '%s' is empty", name)
+		return nil, nil, fmt.Errorf("authorization token '%s' is empty", name)
 	}
 
-	maxSize := helpers.DefaultInt(AuthorizationData.MaxAuthorizationSize, MaximumSessionAuthorizationSize)
+	maxSize := authorizationSizeBudget(AuthorizationData)
 	if len(authorizationValue) > maxSize {
-		return "", "", fmt.Errorf("authorization token '%s' exceeds maximum size of %d bytes", name, maxSize)
+		return nil, nil, fmt.Errorf("authorization token '%s' exceeds maximum size of %d bytes", name, maxSize)
 	}
 	if len(authorizationValue) < MinimumSessionAuthorizationSize {
-		return "", "", fmt.Errorf("authorization token '%s' is too small, minimum size is %d bytes", name, MinimumSessionAuthorizationSize)
+		return nil, nil, fmt.Errorf("authorization token '%s' is too small, minimum size is %d bytes", name, MinimumSessionAuthorizationSize)
 	}
 
-	// --- 2. Initial Split & Validation ---
-	splitValues := strings.SplitN(authorizationValue, delimiter, 3)
-	if len(splitValues) != 3 {
-		return "", "", fmt.Errorf("invalid token format for '%s': expected 3 parts, but found %d", name, len(splitValues))
+	// --- 2. Initial Split & Validation, without allocating the parts ---
+	keyVersion, rest, found := strings.Cut(authorizationValue, delimiter)
+	keyId, encryptedPart, foundKeyId := strings.Cut(rest, delimiter)
+	if !found || !foundKeyId {
+		return nil, nil, fmt.Errorf("invalid token format for '%s': expected 3 parts", name)
 	}
 
-	keyVersion, keyId, encryptedPart := splitValues[0], splitValues[1], splitValues[2]
-
 	if len(keyId) < MinimumSessionKeyIdSize || len(keyId) > MaximumSessionKeyIdSize {
-		return "", "", fmt.Errorf("invalid keyId size in token '%s'", name)
+		return nil, nil, fmt.Errorf("invalid keyId size in token '%s'", name)
 	}
 	if len(keyVersion) < MinimumAuthorizationVersionSize || len(keyVersion) > MaximumAuthorizationVersionSize {
-		return "", "", fmt.Errorf("invalid keyVersion size in token '%s'", name)
-	}
-
-	// --- 3. Decryption Logic ---
-	sessionKey, err := sessionManager.GetOldSessionKey(keyId)
-	if err != nil {
-		return "", "", fmt.Errorf("failed to retrieve session key for '%s': %w", name, err)
-	}
-
-	decodedValue, err := base64.RawURLEncoding.DecodeString(encryptedPart)
-	if err != nil {
-		return "", "", fmt.Errorf("failed to base64-decode token '%s': %w", name, err)
+		return nil, nil, fmt.Errorf("invalid keyVersion size in token '%s'", name)
 	}
 
-	// - The associated data is what authenticates the ciphertext.
-	associatedData := []byte(keyId + keyVersion)
-	decryptedValue, err := helpers.SymmetricDecrypt(sessionKey, decodedValue, associatedData)
-	if err != nil {
-		return "", "", fmt.Errorf("failed to decrypt token '%s': %w", name, err)
+	// - The version must match the key mode, a token can't pick how it is checked
+	var decryptedValue []byte
+	if AuthorizationData.KeyMode == KeyModeSigned {
+		if keyVersion != SignedAuthorizationVersion {
+			return nil, nil, fmt.Errorf("token '%s' is not a signed token", name)
+		}
+		decryptedValue, err = verifySignedAuthorization(sessionManager, keyVersion, keyId, delimiter, stringBytes(encryptedPart), buffer)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to verify token '%s': %w", name, err)
+		}
+	} else {
+		if keyVersion == SignedAuthorizationVersion {
+			return nil, nil, fmt.Errorf("token '%s' is signed, but the key mode is symmetric", name)
+		}
+		decryptedValue, err = decryptAuthorization(sessionManager, keyVersion, keyId, stringBytes(encryptedPart), buffer)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to decrypt token '%s': %w", name, err)
+		}
 	}
 
-	// --- 4. Optimized Final Split (working with []byte) ---
+	// --- 3. Final Split (working with []byte) ---
 	// Use bytes.Index to find the delimiter without allocating a new slice of strings.
-	delimiterBytes := []byte(delimiter)
-	splitIndex := bytes.Index(decryptedValue, delimiterBytes)
+	splitIndex := bytes.Index(decryptedValue, stringBytes(delimiter))
 	if splitIndex == -1 {
-		return "", "", fmt.Errorf("invalid decrypted token format for '%s': missing final delimiter", name)
+		return nil, nil, fmt.Errorf("invalid decrypted token format for '%s': missing final delimiter", name)
 	}
 
-	header = string(decryptedValue[:splitIndex])
-	payload = string(decryptedValue[splitIndex+len(delimiterBytes):])
-
-	return header, payload, nil
+	return decryptedValue[:splitIndex], decryptedValue[splitIndex+len(delimiter):], nil
 }
 
 func extractSession(ctx *gin.Context, sessionManager SessionManager) (*SessionHeader, *SessionClaims, string, string, error) {
@@ -93,47 +141,79 @@ func extractSession(ctx *gin.Context, sessionManager SessionManager) (*SessionHe
 		return nil, nil, "", SourceNone, fmt.Errorf("authorization data is not configured")
 	}
 
-	// --- Flattened logic for source extraction ---
+	// --- Flattened logic for source extraction, a missing header is not an error worth allocating ---
 	source := SourceHeader
-	authorizationValue, err := GetAuthorizationBearer(ctx, sessionManager)
-	if err != nil {
+	authorizationValue := authorizationBearerValue(ctx, authorizationData)
+	if authorizationValue == "" {
 		// - Check if there is a session cookie
-		authorizationValue, err = GetSessionCookie(ctx, sessionManager)
-		if err != nil {
+		var err error
+		authorizationValue, err = sessionCookieValue(ctx, authorizationData)
+		if err != nil || authorizationValue == "" {
 			// - No session header or cookie was found. This is a valid sessionless case.
 			return nil, nil, "", SourceNone, nil
 		}
 		source = SourceCookie
 	}
 
-	// --- Continue with the extraction logic ---
-	headerStr, payloadStr, err := extractSessionAuthorizationParts(authorizationData, sessionManager, authorizationValue)
-	if err != nil {
-		// - Development only - If this fails, it usually means the session has been tampered with or
-		// the session key has changed (like in development mode), therefore, if we are in development mode,
-		// we return nil, nil, SourceNone, "", nil, to allow the session to be refreshed with a new session key.
-		// Note: In test & production modes, we return the error to prevent silent failures.
-		if gin.Mode() == gin.DebugMode {
-			return nil, nil, "", SourceNone, nil
+	// --- Continue with the extraction logic, the token is decoded in a pooled buffer ---
+	decode := func() (*SessionHeader, *SessionClaims, string, error) {
+		buffer := getAuthorizationBuffer()
+		defer putAuthorizationBuffer(buffer)
+
+		headerBytes, payloadBytes, err := extractSessionAuthorizationBytes(authorizationData, sessionManager, authorizationValue, buffer)
+		if err != nil {
+			return nil, nil, "", fmt.Errorf("%w: %w", errInvalidSessionParts, err)
 		}
+		return decodeAuthorizationBytes(headerBytes, payloadBytes, buffer)
+	}
 
-		return nil, nil, source, "", fmt.Errorf("failed to extract session parts: %w", err)
+	var decodedHeader *SessionHeader
+	var claims *SessionClaims
+	var group string
+	var err error
+	cache := decodeCacheOf(sessionManager)
+	if ttl, size := decodeCacheSettings(authorizationData); ttl > 0 && cache != nil {
+		decodedHeader, claims, group, err = cache.decode(ctx, authorizationValue, ttl, size, decode)
+	} else {
+		decodedHeader, claims, group, err = decode()
 	}
 
-	decodedHeader, err := Decode(headerStr) // Decode was already taking a string, this is fine
+	// - Development only - If the parts can't be extracted, it usually means the session has been tampered with or
+	// the session key has changed (like in development mode), therefore, if we are in development mode,
+	// we return nil, nil, SourceNone, "", nil, to allow the session to be refreshed with a new session key.
+	// Note: In test & production modes, we return the error to prevent silent failures.
+	if errors.Is(err, errInvalidSessionParts) && gin.Mode() == gin.DebugMode {
+		return nil, nil, "", SourceNone, nil
+	}
 	if err != nil {
-		return nil, nil, source, "", fmt.Errorf("failed to decode header: %w", err)
+		return nil, nil, source, "", err
+	}
+
+	return decodedHeader, claims, group, source, nil
+}
+
+// decodeAuthorizationBytes decodes the verified header and payload of a token, and checks the claims. The
+// buffer's decoded space is reused for the header and the payload.
+func decodeAuthorizationBytes(header []byte, payload []byte, buffer *authorizationBuffer) (*SessionHeader, *SessionClaims, string, error) {
+	decodedHeader := &SessionHeader{}
+	var err error
+	if buffer.decoded, err = decodeSessionHeader(header, buffer.decoded, decodedHeader); err != nil {
+		return nil, nil, "", fmt.Errorf("failed to decode header: %w", err)
 	}
 
 	claims := &SessionClaims{HasSession: true}
-	if err := claims.DecodePayload(payloadStr); err != nil { // DecodePayload was also taking a string
-		return nil, nil, source, "", fmt.Errorf("failed to decode payload: %w", err)
+	if buffer.decoded, err = claims.decodePayload(payload, buffer.decoded); err != nil {
+		return nil, nil, "", fmt.Errorf("failed to decode payload: %w", err)
 	}
 
 	group, ok := claims.GetClaim(SessionModeClaim)
 	if !ok || group == "" {
-		return nil, nil, source, "", fmt.Errorf("session mode claim is missing or empty")
+		return nil, nil, "", fmt.Errorf("session mode claim is missing or empty")
+	}
+
+	if err := ValidateClaims(claims); err != nil {
+		return nil, nil, "", fmt.Errorf("claims do not match the claim schema: %w", err)
 	}
 
-	return &decodedHeader, claims, group, source, nil
+	return decodedHeader, claims, group, nil
 }
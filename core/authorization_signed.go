@@ -0,0 +1,122 @@
+package core
+
+import (
+	"encoding/base64"
+	"fmt"
+	"slices"
+
+	"github.com/grzegorzmaniak/gothic/helpers"
+)
+
+// KeyMode selects how authorization tokens are protected.
+type KeyMode int
+
+const (
+	// KeyModeSymmetric encrypts tokens with AES-GCM using SessionManager.GetSessionKey (Default)
+	KeyModeSymmetric KeyMode = iota
+
+	// KeyModeSigned signs tokens with an Ed25519 or ECDSA key from SigningKeyManager.GetSigningKey. The claims are
+	// readable by the client, but any service holding the public key can verify them without sharing a secret.
+	KeyModeSigned
+)
+
+const (
+	SignedAuthorizationVersion = "SS1"
+	maximumSignatureSize       = 255 // The signature length is stored in a single byte
+)
+
+// SigningKeyManager is implemented by session managers using KeyModeSigned. CSRF tokens and download grants are
+// still encrypted with the symmetric session key.
+type SigningKeyManager interface {
+
+	// GetSigningKey Is used to get the freshest PKCS #8 private key, see helpers.GenerateSigningKeyPair.
+	// Services that only verify tokens can return an error.
+	GetSigningKey() (privateKey []byte, keyIdentifier string, error error)
+
+	// GetVerificationKey Is used to get the PKIX public key (or the private key) for a key identifier
+	GetVerificationKey(string) (publicKey []byte, error error)
+}
+
+func signingKeyManager(sessionManager SessionManager) (SigningKeyManager, error) {
+	manager, ok := sessionManager.(SigningKeyManager)
+	if !ok {
+		return nil, fmt.Errorf("session manager does not implement SigningKeyManager, required by KeyModeSigned")
+	}
+	return manager, nil
+}
+
+// appendSignedMessage appends what the signature covers to dst, the version and key id are included so neither
+// can be swapped.
+func appendSignedMessage(dst []byte, keyVersion string, keyId string, delimiter string, value []byte) []byte {
+	message := slices.Grow(dst, len(keyVersion)+len(keyId)+len(delimiter)*2+len(value))
+	message = append(message, keyVersion...)
+	message = append(message, delimiter...)
+	message = append(message, keyId...)
+	message = append(message, delimiter...)
+	return append(message, value...)
+}
+
+// signAuthorization returns the key id and the encoded signature length, signature and value.
+func signAuthorization(sessionManager SessionManager, delimiter string, authorizationValue string) (string, string, error) {
+	manager, err := signingKeyManager(sessionManager)
+	if err != nil {
+		return "", "", err
+	}
+
+	signingKey, keyId, err := manager.GetSigningKey()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get signing key: %w", err)
+	}
+	if len(keyId) < MinimumSessionKeyIdSize || len(keyId) > MaximumSessionKeyIdSize {
+		return "", "", fmt.Errorf("invalid keyId size: must be between %d and %d characters", MinimumSessionKeyIdSize, MaximumSessionKeyIdSize)
+	}
+
+	signature, err := helpers.AsymmetricSign(signingKey, appendSignedMessage(nil, SignedAuthorizationVersion, keyId, delimiter, []byte(authorizationValue)))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to sign authorization value: %w", err)
+	}
+	if len(signature) > maximumSignatureSize {
+		return "", "", fmt.Errorf("signature of %d bytes is too large", len(signature))
+	}
+
+	// - A single base64 part, the delimiter may be a base64url character
+	signedValue := make([]byte, 0, 1+len(signature)+len(authorizationValue))
+	signedValue = append(signedValue, byte(len(signature)))
+	signedValue = append(signedValue, signature...)
+	signedValue = append(signedValue, authorizationValue...)
+	return keyId, base64.RawURLEncoding.EncodeToString(signedValue), nil
+}
+
+// verifySignedAuthorization checks the signature of a KeyModeSigned token and returns the signed value, which is
+// decoded into the buffer.
+func verifySignedAuthorization(sessionManager SessionManager, keyVersion string, keyId string, delimiter string, signedPart []byte, buffer *authorizationBuffer) ([]byte, error) {
+	manager, err := signingKeyManager(sessionManager)
+	if err != nil {
+		return nil, err
+	}
+
+	verificationKey, err := manager.GetVerificationKey(keyId)
+	if err != nil {
+		keyUsage.failed(UnknownKeyId)
+		return nil, fmt.Errorf("failed to retrieve verification key: %w", err)
+	}
+
+	buffer.token, err = decodeBase64Into(buffer.token, signedPart)
+	if err != nil {
+		return nil, fmt.Errorf("failed to base64-decode token: %w", err)
+	}
+	decodedValue := buffer.token
+	if len(decodedValue) < 1 || len(decodedValue) < 1+int(decodedValue[0]) {
+		keyUsage.failed(keyId)
+		return nil, fmt.Errorf("signed token is truncated")
+	}
+
+	signature, value := decodedValue[1:1+int(decodedValue[0])], decodedValue[1+int(decodedValue[0]):]
+	buffer.decoded = appendSignedMessage(buffer.decoded[:0], keyVersion, keyId, delimiter, value)
+	if err = helpers.AsymmetricVerify(verificationKey, buffer.decoded, signature); err != nil {
+		keyUsage.failed(keyId)
+		return nil, fmt.Errorf("failed to verify token: %w", err)
+	}
+	keyUsage.validated(keyId)
+	return value, nil
+}
@@ -0,0 +1,121 @@
+package core
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/helpers"
+)
+
+// signingSessionManager issues signed tokens, a verifier is the same manager without the private key.
+type signingSessionManager struct {
+	*mockSessionManager
+	privateKey []byte
+	publicKeys map[string][]byte
+}
+
+func (m *signingSessionManager) GetSigningKey() ([]byte, string, error) {
+	if m.privateKey == nil {
+		return nil, "", fmt.Errorf("this service only verifies tokens")
+	}
+	return m.privateKey, "signing-1", nil
+}
+
+func (m *signingSessionManager) GetVerificationKey(keyId string) ([]byte, error) {
+	key, ok := m.publicKeys[keyId]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id '%s'", keyId)
+	}
+	return key, nil
+}
+
+func TestSignedAuthorization(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	privateKey, publicKey, err := helpers.GenerateSigningKeyPair(helpers.SigningAlgorithmEd25519)
+	if err != nil {
+		t.Fatalf("Failed to generate key pair: %v", err)
+	}
+
+	issuer := &signingSessionManager{
+		mockSessionManager: newMockSessionManager(t),
+		privateKey:         privateKey,
+		publicKeys:         map[string][]byte{"signing-1": publicKey},
+	}
+	issuer.authorizationData.KeyMode = KeyModeSigned
+
+	// - The downstream service only holds the public key
+	verifier := &signingSessionManager{
+		mockSessionManager: newMockSessionManager(t),
+		publicKeys:         map[string][]byte{"signing-1": publicKey},
+	}
+	verifier.authorizationData.KeyMode = KeyModeSigned
+
+	header := NewSessionHeader(false, DefaultSessionExpiration, DefaultSessionRefreshTime)
+	token, err := CreateAuthorization("user", &header, *issuer.authorizationData,
+		&SessionClaims{Claims: map[string]string{"subject": "user-1"}}, issuer)
+	if err != nil {
+		t.Fatalf("Failed to create signed authorization: %v", err)
+	}
+	if !strings.HasPrefix(token, SignedAuthorizationVersion+DefaultSessionAuthorizationDelimiter+"signing-1") {
+		t.Fatalf("Expected a signed token, got %q", token)
+	}
+
+	extract := func(manager SessionManager, value string) (*SessionClaims, error) {
+		ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+		ctx.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+		ctx.Request.AddCookie(&http.Cookie{Name: DefaultSessionAuthorizationName, Value: value})
+		_, claims, _, _, err := extractSession(ctx, manager)
+		return claims, err
+	}
+
+	t.Run("Verified with only the public key", func(t *testing.T) {
+		claims, err := extract(verifier, token)
+		if err != nil || claims == nil {
+			t.Fatalf("Failed to extract session: %v", err)
+		}
+		if subject, _ := claims.GetClaim("subject"); subject != "user-1" {
+			t.Errorf("Expected subject 'user-1', got %q", subject)
+		}
+	})
+
+	t.Run("Verifier can't issue tokens", func(t *testing.T) {
+		if _, err := CreateAuthorization("user", &header, *verifier.authorizationData, &SessionClaims{}, verifier); err == nil {
+			t.Error("Expected issuing without a private key to fail")
+		}
+	})
+
+	t.Run("Tampered tokens are rejected", func(t *testing.T) {
+		tampered := []byte(token)
+		tampered[len(tampered)-2] ^= 0x01
+		if _, err := extract(verifier, string(tampered)); err == nil {
+			t.Error("Expected a tampered token to be rejected")
+		}
+	})
+
+	t.Run("Key mode must match the token", func(t *testing.T) {
+		if _, err := extract(newMockSessionManager(t), token); err == nil {
+			t.Error("Expected a signed token to be rejected in symmetric mode")
+		}
+
+		symmetric := newMockSessionManager(t)
+		encrypted, err := CreateAuthorization("user", &header, *symmetric.authorizationData, &SessionClaims{}, symmetric)
+		if err != nil {
+			t.Fatalf("Failed to create encrypted authorization: %v", err)
+		}
+		if _, err = extract(verifier, encrypted); err == nil {
+			t.Error("Expected an encrypted token to be rejected in signed mode")
+		}
+	})
+
+	t.Run("Requires a SigningKeyManager", func(t *testing.T) {
+		manager := newMockSessionManager(t)
+		manager.authorizationData.KeyMode = KeyModeSigned
+		if _, err := CreateAuthorization("user", &header, *manager.authorizationData, &SessionClaims{}, manager); err == nil {
+			t.Error("Expected an error without a SigningKeyManager")
+		}
+	})
+}
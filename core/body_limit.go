@@ -0,0 +1,89 @@
+package core
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/helpers"
+	"go.uber.org/zap"
+)
+
+const (
+	DefaultMaxBodyBytes    = 1024 * 1024 // 1 MiB
+	DefaultBodyReadTimeout = 30 * time.Second
+)
+
+// ErrBodyReadTimeout is returned when the request body is not received within the route's BodyReadTimeout.
+var ErrBodyReadTimeout = errors.New("request body read timed out")
+
+// deadlineBody fails reads once the deadline has passed, it backs up the connection read deadline for
+// writers that can not set one (e.g., in tests). It can only interrupt a slow body between reads.
+type deadlineBody struct {
+	io.ReadCloser
+	deadline time.Time
+}
+
+func (b *deadlineBody) Read(p []byte) (int, error) {
+	if time.Now().After(b.deadline) {
+		return 0, ErrBodyReadTimeout
+	}
+	n, err := b.ReadCloser.Read(p)
+	if err == nil && time.Now().After(b.deadline) {
+		return n, ErrBodyReadTimeout
+	}
+	return n, err
+}
+
+// limitRequestBody caps the request body at the route's MaxBodyBytes and sets its BodyReadTimeout, it reports
+// bodies whose Content-Length is already too large. The returned function lifts the read deadline again, so
+// it does not apply to the next request on the connection.
+func limitRequestBody(ctx *gin.Context, sessionConfig *APIConfiguration) (func(), bool) {
+	restore := func() {}
+	if ctx.Request == nil || ctx.Request.Body == nil || ctx.Request.Body == http.NoBody {
+		return restore, false
+	}
+
+	// - A negative value disables the limit
+	maxBytes := sessionConfig.MaxBodyBytes
+	if maxBytes == 0 {
+		maxBytes = DefaultMaxBodyBytes
+	}
+	if maxBytes > 0 {
+		if ctx.Request.ContentLength > maxBytes {
+			return restore, true
+		}
+		ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxBytes)
+	}
+
+	timeout := helpers.DefaultTimeDuration(sessionConfig.BodyReadTimeout, DefaultBodyReadTimeout)
+	if timeout < 0 {
+		return restore, false
+	}
+
+	deadline := time.Now().Add(timeout)
+	controller := http.NewResponseController(ctx.Writer)
+	if err := controller.SetReadDeadline(deadline); err == nil {
+		restore = func() {
+			if err := controller.SetReadDeadline(time.Time{}); err != nil {
+				helpers.Logger(ctx).Debug("Failed to lift the body read deadline", zap.Error(err))
+			}
+		}
+	}
+	ctx.Request.Body = &deadlineBody{ReadCloser: ctx.Request.Body, deadline: deadline}
+	return restore, false
+}
+
+// isBodyTooLarge reports whether err was caused by a body over MaxBodyBytes.
+func isBodyTooLarge(err error) bool {
+	var maxBytesErr *http.MaxBytesError
+	return errors.As(err, &maxBytesErr)
+}
+
+// isBodyReadTimeout reports whether err was caused by a body that was not received within BodyReadTimeout.
+func isBodyReadTimeout(err error) bool {
+	return errors.Is(err, ErrBodyReadTimeout) || errors.Is(err, os.ErrDeadlineExceeded)
+}
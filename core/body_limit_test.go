@@ -0,0 +1,92 @@
+package core
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/errors"
+)
+
+type bodyLimitInput struct {
+	Name string `json:"name"`
+}
+
+// slowBody returns a chunk per read, sleeping in between.
+type slowBody struct {
+	chunks []string
+	delay  time.Duration
+}
+
+func (b *slowBody) Read(p []byte) (int, error) {
+	if len(b.chunks) == 0 {
+		return 0, io.EOF
+	}
+	time.Sleep(b.delay)
+	n := copy(p, b.chunks[0])
+	b.chunks = b.chunks[1:]
+	return n, nil
+}
+
+func TestRequestBodyLimits(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	ctor := NewRouteConstructor(router, testBaseRoute{}, newMockSessionManager(t), nil)
+	handler := func(input *bodyLimitInput, _ *Handler[testBaseRoute]) (*bodyLimitInput, *errors.AppError) {
+		return input, nil
+	}
+	POST(ctor, "/limited", PublicRoute().WithMaxBodyBytes(32), handler)
+	POST(ctor, "/unlimited", PublicRoute().WithMaxBodyBytes(-1), handler)
+
+	slow := PublicRoute()
+	slow.BodyReadTimeout = 20 * time.Millisecond
+	POST(ctor, "/slow", slow, handler)
+
+	serve := func(request *http.Request) *httptest.ResponseRecorder {
+		request.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		return recorder
+	}
+	largeBody := `{"name":"` + strings.Repeat("a", 64) + `"}`
+
+	t.Run("Small bodies are bound", func(t *testing.T) {
+		recorder := serve(httptest.NewRequest(http.MethodPost, "/limited", strings.NewReader(`{"name":"gothic"}`)))
+		if recorder.Code != http.StatusOK || !strings.Contains(recorder.Body.String(), "gothic") {
+			t.Errorf("Expected 200, got %d: %s", recorder.Code, recorder.Body.String())
+		}
+	})
+
+	t.Run("Declared Content-Length over the limit", func(t *testing.T) {
+		if recorder := serve(httptest.NewRequest(http.MethodPost, "/limited", strings.NewReader(largeBody))); recorder.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("Expected 413, got %d", recorder.Code)
+		}
+	})
+
+	t.Run("Chunked bodies over the limit", func(t *testing.T) {
+		request := httptest.NewRequest(http.MethodPost, "/limited", io.NopCloser(strings.NewReader(largeBody)))
+		request.ContentLength = -1
+		if recorder := serve(request); recorder.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("Expected 413, got %d", recorder.Code)
+		}
+	})
+
+	t.Run("Negative limits disable the check", func(t *testing.T) {
+		if recorder := serve(httptest.NewRequest(http.MethodPost, "/unlimited", strings.NewReader(largeBody))); recorder.Code != http.StatusOK {
+			t.Errorf("Expected 200, got %d", recorder.Code)
+		}
+	})
+
+	t.Run("Slow bodies time out", func(t *testing.T) {
+		body := &slowBody{chunks: []string{`{"na`, `me":`, `"slow"}`}, delay: 15 * time.Millisecond}
+		request := httptest.NewRequest(http.MethodPost, "/slow", body)
+		request.ContentLength = -1
+		if recorder := serve(request); recorder.Code != http.StatusRequestTimeout {
+			t.Errorf("Expected 408, got %d: %s", recorder.Code, recorder.Body.String())
+		}
+	})
+}
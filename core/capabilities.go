@@ -0,0 +1,118 @@
+package core
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/grzegorzmaniak/gothic/errors"
+	"github.com/grzegorzmaniak/gothic/helpers"
+	"github.com/grzegorzmaniak/gothic/rbac"
+	"go.uber.org/zap"
+)
+
+// Capabilities is the response of NewCapabilitiesHandler.
+type Capabilities struct {
+	// CacheControl lets the client reuse the capabilities for as long as the RBAC cache could serve them anyway
+	CacheControl string `header:"Cache-Control" json:"-"`
+
+	// Permissions are the names of the granted bitset permissions (see rbac.PermissionRegistry) and the granted
+	// named permissions, wildcards are kept as granted, e.g., "billing.*"
+	Permissions []string `json:"permissions"`
+
+	// Roles are the roles of the subject
+	Roles []string `json:"roles"`
+
+	// Denied are the names of the permissions explicitly denied to the subject, they are never part of Permissions
+	// but a granted wildcard covering them is, e.g., "reports.*" alongside a denied "reports.export"
+	Denied []string `json:"denied,omitempty"`
+
+	// Tenant is the tenant the permissions and roles are scoped to, see APIConfiguration.TenantResolver
+	Tenant string `json:"tenant,omitempty"`
+
+	// PermissionBits is the serialized permission bitset (see rbac.Permission.Serialize), it includes the bits the
+	// registry has no name for
+	PermissionBits string `json:"permission_bits,omitempty"`
+}
+
+// sessionRbacSubject returns the RBAC manager, subject identifier and RBAC cache identifier of a session.
+func sessionRbacSubject(sessionManager SessionManager, claims *SessionClaims) (rbac.Manager, string, string, error) {
+	rbacManager := sessionManager.GetRbacManager()
+	if rbacManager == nil {
+		return nil, "", "", fmt.Errorf("session manager has no RBAC manager")
+	}
+
+	rbacCacheId, ok := claims.GetClaim(RbacCacheIdentifier)
+	if !ok || len(rbacCacheId) != helpers.AESKeySize32 {
+		return nil, "", "", fmt.Errorf("session has no valid RBAC cache identifier")
+	}
+
+	subjectIdentifier, err := sessionManager.GetSubjectIdentifier(claims)
+	if err != nil {
+		return nil, "", "", fmt.Errorf("failed to get subject identifier: %w", err)
+	}
+	return rbacManager, subjectIdentifier, rbacCacheId, nil
+}
+
+// capabilitiesMaxAge is the shortest of the RBAC cache TTLs, a cached response is never older than what the
+// RBAC cache would return.
+func capabilitiesMaxAge(rbacManager rbac.Manager) time.Duration {
+	return min(
+		rbacManager.GetSubjectPermissionsCacheTtl(),
+		rbacManager.GetSubjectRolesCacheTtl(),
+		rbacManager.GetRolePermissionsCacheTtl(),
+	)
+}
+
+// NewCapabilitiesHandler returns a route handler describing the caller's effective permissions and roles, so a
+// frontend can hide what the caller cannot use. The bitset permissions are named through registry, which can be
+// nil when only named permissions are used. Hiding UI elements is cosmetic, every route still enforces its own
+// requirements.
+//
+// Register it on an authenticated route, e.g.:
+//
+//	core.GET(routeCtor, "/me/capabilities", core.AuthenticatedJSONAPI(), core.NewCapabilitiesHandler[*AppBaseRoute](registry))
+func NewCapabilitiesHandler[BaseRoute helpers.BaseRouteComponents](
+	registry *rbac.PermissionRegistry,
+) func(*struct{}, *Handler[BaseRoute]) (*Capabilities, *errors.AppError) {
+	return func(_ *struct{}, data *Handler[BaseRoute]) (*Capabilities, *errors.AppError) {
+		if !data.HasSession || data.Claims == nil {
+			return nil, errors.NewUnauthorized("A session is required", nil)
+		}
+
+		rbacManager, subjectIdentifier, rbacCacheId, err := sessionRbacSubject(data.SessionManager, data.Claims)
+		if err != nil {
+			helpers.Logger(data.Context).Debug("Cannot resolve the RBAC subject of the session", zap.Error(err))
+			return nil, errors.NewInternalServerError("Failed to resolve the RBAC subject", err)
+		}
+
+		effective, err := rbac.FetchEffectivePermissions(rbacContext(data.Context), rbacManager, subjectIdentifier, rbacCacheId)
+		if err != nil {
+			return nil, errors.NewInternalServerError("Failed to fetch permissions", err)
+		}
+
+		permissions := effective.NamedPermissions.Names()
+		if registry != nil {
+			permissions = append(permissions, registry.Names(effective.Permissions)...)
+			slices.Sort(permissions)
+			permissions = slices.Compact(permissions)
+		}
+
+		denied := effective.Denied.NamedPermissions.Names()
+		if registry != nil && effective.Denied.Permissions != nil {
+			denied = append(denied, registry.Names(effective.Denied.Permissions)...)
+			slices.Sort(denied)
+			denied = slices.Compact(denied)
+		}
+
+		return &Capabilities{
+			CacheControl:   "private, max-age=" + strconv.Itoa(int(capabilitiesMaxAge(rbacManager).Seconds())),
+			Permissions:    permissions,
+			Roles:          effective.Roles,
+			Denied:         denied,
+			Tenant:         RequestTenant(data.Context),
+			PermissionBits: effective.Permissions.Serialize(),
+		}, nil
+	}
+}
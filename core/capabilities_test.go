@@ -0,0 +1,125 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	internalcache "github.com/grzegorzmaniak/gothic/cache"
+	"github.com/grzegorzmaniak/gothic/rbac"
+)
+
+// roleRbacManager grants named permissions like namedRbacManager, and an "editor" role with bitset permissions.
+type roleRbacManager struct {
+	namedRbacManager
+}
+
+func (m *roleRbacManager) GetSubjectRolesAndPermissions(_ context.Context, subjectIdentifier string) (rbac.Permissions, []string, error) {
+	if subjectIdentifier == "editor-1" {
+		return rbac.Permissions{rbac.NewPermission(0)}, []string{"editor"}, nil
+	}
+	return nil, nil, nil
+}
+
+func (m *roleRbacManager) GetRolePermissions(_ context.Context, roleIdentifier string) (rbac.Permissions, error) {
+	if roleIdentifier == "editor" {
+		return rbac.Permissions{rbac.NewPermission(1), rbac.NewPermission(7)}, nil
+	}
+	return nil, nil
+}
+
+func (m *roleRbacManager) GetRoleNamedPermissions(_ context.Context, roleIdentifier string) (rbac.PermissionSet, error) {
+	if roleIdentifier == "editor" {
+		return rbac.MustPermissionSet("articles.*"), nil
+	}
+	return rbac.PermissionSet{}, nil
+}
+
+func TestNewCapabilitiesHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mgr := newMockSessionManager(t)
+	mgr.rbacManager = &roleRbacManager{namedRbacManager{
+		cacheManager: internalcache.BuildDefaultCacheManager(nil),
+		named:        map[string]rbac.PermissionSet{"editor-1": rbac.MustPermissionSet("comments.delete")},
+	}}
+
+	registry := rbac.NewPermissionRegistry()
+	registry.MustRegister("articles.read", 0)
+	registry.MustRegister("articles.write", 1)
+
+	router := gin.New()
+	ctor := NewRouteConstructor(router, testBaseRoute{}, mgr, nil)
+	GET(ctor, "/capabilities", AuthenticatedJSONAPI(), NewCapabilitiesHandler[testBaseRoute](registry))
+	GET(ctor, "/capabilities/public", PublicRoute(), NewCapabilitiesHandler[testBaseRoute](nil))
+
+	request := func(path, subject string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if subject != "" {
+			ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+			ctx.Request = httptest.NewRequest(http.MethodPost, "/login", nil)
+			token, err := IssueBearerToken(ctx, mgr, "default", &SessionClaims{Claims: map[string]string{"subject": subject}})
+			if err != nil {
+				t.Fatalf("Failed to issue the bearer token: %v", err)
+			}
+			req.Header.Set(DefaultSessionAuthorizationHeaderName, token)
+		}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	t.Run("Returns the flattened permissions and roles", func(t *testing.T) {
+		recorder := request("/capabilities", "editor-1")
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", recorder.Code, recorder.Body.String())
+		}
+
+		var capabilities Capabilities
+		if err := json.Unmarshal(recorder.Body.Bytes(), &capabilities); err != nil {
+			t.Fatalf("Failed to decode the response: %v", err)
+		}
+
+		want := []string{"articles.*", "articles.read", "articles.write", "comments.delete"}
+		if !slices.Equal(capabilities.Permissions, want) {
+			t.Errorf("Expected permissions %v, got %v", want, capabilities.Permissions)
+		}
+		if !slices.Equal(capabilities.Roles, []string{"editor"}) {
+			t.Errorf("Expected the editor role, got %v", capabilities.Roles)
+		}
+
+		bits, err := rbac.DeserializePermission(capabilities.PermissionBits)
+		if err != nil || !bits.Has(rbac.NewPermission(7)) {
+			t.Errorf("Expected the unregistered bit in the permission bits, got %q", capabilities.PermissionBits)
+		}
+		if got := recorder.Header().Get("Cache-Control"); got != "private, max-age=60" {
+			t.Errorf("Expected the cache headers to follow the RBAC cache TTL, got %q", got)
+		}
+	})
+
+	t.Run("Subjects without grants get empty lists", func(t *testing.T) {
+		recorder := request("/capabilities", "reader-1")
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", recorder.Code, recorder.Body.String())
+		}
+		var body map[string]any
+		if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil {
+			t.Fatalf("Failed to decode the response: %v", err)
+		}
+		if permissions, ok := body["permissions"].([]any); !ok || len(permissions) != 0 {
+			t.Errorf("Expected an empty permission list, got %v", body["permissions"])
+		}
+		if roles, ok := body["roles"].([]any); !ok || len(roles) != 0 {
+			t.Errorf("Expected an empty role list, got %v", body["roles"])
+		}
+	})
+
+	t.Run("Sessions are required", func(t *testing.T) {
+		if code := request("/capabilities/public", "").Code; code != http.StatusUnauthorized {
+			t.Errorf("Expected 401 without a session, got %d", code)
+		}
+	})
+}
@@ -0,0 +1,127 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// ClaimType is the type a registered claim's string value must parse as.
+type ClaimType int
+
+const (
+	// ClaimString accepts any value (Default)
+	ClaimString ClaimType = iota
+
+	// ClaimInt must parse as a base 10 integer, see SessionClaims.GetInt.
+	ClaimInt
+
+	// ClaimBool must parse with strconv.ParseBool, see SessionClaims.GetBool.
+	ClaimBool
+
+	// ClaimTime must be unix seconds or RFC 3339, see SessionClaims.GetTime.
+	ClaimTime
+
+	// ClaimJSON must be valid JSON, see SessionClaims.GetJSON.
+	ClaimJSON
+)
+
+// ClaimSpec describes a registered claim.
+type ClaimSpec struct {
+	// Type the value must parse as (Default: ClaimString)
+	Type ClaimType
+
+	// Required claims must be present on every session.
+	Required bool
+
+	// Validate optionally checks the raw value after the type check, e.g., a range or an enum.
+	Validate func(value string) error
+}
+
+var claimSchema = struct {
+	sync.RWMutex
+	specs map[string]ClaimSpec
+}{specs: make(map[string]ClaimSpec)}
+
+// RegisterClaim adds a claim to the schema, sessions are validated against it when they are issued
+// (CreateAuthorization) and when they are decoded, so corrupt claims are rejected early.
+func RegisterClaim(name string, spec ClaimSpec) error {
+	if name == "" {
+		return fmt.Errorf("claim name is empty")
+	}
+	if spec.Type < ClaimString || spec.Type > ClaimJSON {
+		return fmt.Errorf("claim '%s' has an unknown type %d", name, spec.Type)
+	}
+
+	claimSchema.Lock()
+	defer claimSchema.Unlock()
+	claimSchema.specs[name] = spec
+	return nil
+}
+
+// UnregisterClaim removes a claim from the schema.
+func UnregisterClaim(name string) {
+	claimSchema.Lock()
+	defer claimSchema.Unlock()
+	delete(claimSchema.specs, name)
+}
+
+// validateClaim checks a single value against its spec.
+func validateClaim(claims *SessionClaims, name string, spec ClaimSpec) error {
+	value, ok := claims.GetClaim(name)
+	if !ok {
+		if spec.Required {
+			return fmt.Errorf("claim '%s' is required: %w", name, ErrClaimNotFound)
+		}
+		return nil
+	}
+
+	var err error
+	switch spec.Type {
+	case ClaimInt:
+		_, err = claims.GetInt(name)
+	case ClaimBool:
+		_, err = claims.GetBool(name)
+	case ClaimTime:
+		_, err = claims.GetTime(name)
+	case ClaimJSON:
+		if !json.Valid([]byte(value)) {
+			err = fmt.Errorf("claim '%s' is not valid JSON", name)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	if spec.Validate != nil {
+		if err = spec.Validate(value); err != nil {
+			return fmt.Errorf("claim '%s' is invalid: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// ValidateClaims checks the claims against the registered schema, claims that are not registered are not checked.
+func ValidateClaims(claims *SessionClaims) error {
+	if claims == nil {
+		return fmt.Errorf("claims are nil")
+	}
+
+	claimSchema.RLock()
+	defer claimSchema.RUnlock()
+
+	// - Sorted, so the reported error is deterministic
+	names := make([]string, 0, len(claimSchema.specs))
+	for name := range claimSchema.specs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := validateClaim(claims, name, claimSchema.specs[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package core
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestValidateClaims(t *testing.T) {
+	register := func(name string, spec ClaimSpec) {
+		t.Helper()
+		if err := RegisterClaim(name, spec); err != nil {
+			t.Fatalf("Failed to register claim: %v", err)
+		}
+		t.Cleanup(func() { UnregisterClaim(name) })
+	}
+
+	register("tenant", ClaimSpec{Required: true})
+	register("age", ClaimSpec{Type: ClaimInt, Validate: func(value string) error {
+		if value == "0" {
+			return fmt.Errorf("age must be positive")
+		}
+		return nil
+	}})
+	register("beta", ClaimSpec{Type: ClaimBool})
+	register("verified_at", ClaimSpec{Type: ClaimTime})
+	register("prefs", ClaimSpec{Type: ClaimJSON})
+
+	if err := RegisterClaim("", ClaimSpec{}); err == nil {
+		t.Error("Expected an error for an empty name")
+	}
+	if err := RegisterClaim("unknown", ClaimSpec{Type: ClaimType(99)}); err == nil {
+		t.Error("Expected an error for an unknown type")
+	}
+
+	tests := []struct {
+		name    string
+		claims  map[string]string
+		wantErr bool
+	}{
+		{name: "Valid claims", claims: map[string]string{"tenant": "acme", "age": "30", "beta": "true", "verified_at": "1700000000", "prefs": `{"a":1}`}},
+		{name: "Optional claims can be missing", claims: map[string]string{"tenant": "acme"}},
+		{name: "Unregistered claims are not checked", claims: map[string]string{"tenant": "acme", "other": "x"}},
+		{name: "Missing required claim", claims: map[string]string{"age": "30"}, wantErr: true},
+		{name: "Corrupt integer", claims: map[string]string{"tenant": "acme", "age": "thirty"}, wantErr: true},
+		{name: "Custom validation", claims: map[string]string{"tenant": "acme", "age": "0"}, wantErr: true},
+		{name: "Corrupt boolean", claims: map[string]string{"tenant": "acme", "beta": "maybe"}, wantErr: true},
+		{name: "Corrupt time", claims: map[string]string{"tenant": "acme", "verified_at": "yesterday"}, wantErr: true},
+		{name: "Corrupt JSON", claims: map[string]string{"tenant": "acme", "prefs": "{"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateClaims(&SessionClaims{Claims: tt.claims})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Expected error: %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestClaimSchema_IssueAndDecode(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mgr := newMockSessionManager(t)
+	header := NewSessionHeader(true, time.Hour, time.Minute)
+
+	// - Issued before the schema existed
+	token, err := CreateAuthorization("user", &header, *mgr.authorizationData, &SessionClaims{}, mgr)
+	if err != nil {
+		t.Fatalf("Failed to create authorization: %v", err)
+	}
+
+	extract := func() error {
+		ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+		ctx.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+		ctx.Request.Header.Set(DefaultSessionAuthorizationHeaderName, token)
+		_, _, _, _, extractErr := extractSession(ctx, mgr)
+		return extractErr
+	}
+	if err = extract(); err != nil {
+		t.Fatalf("Expected the session to decode without a schema, got %v", err)
+	}
+
+	if err = RegisterClaim("tenant", ClaimSpec{Required: true}); err != nil {
+		t.Fatalf("Failed to register claim: %v", err)
+	}
+	defer UnregisterClaim("tenant")
+
+	t.Run("Issuing rejects claims that do not match", func(t *testing.T) {
+		if _, err := CreateAuthorization("user", &header, *mgr.authorizationData, &SessionClaims{}, mgr); err == nil {
+			t.Error("Expected an error for a missing required claim")
+		}
+		claims := &SessionClaims{Claims: map[string]string{"tenant": "acme"}}
+		if _, err := CreateAuthorization("user", &header, *mgr.authorizationData, claims, mgr); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+
+	t.Run("Decoding rejects claims that do not match", func(t *testing.T) {
+		if err := extract(); err == nil {
+			t.Error("Expected the session to be rejected")
+		}
+	})
+}
@@ -0,0 +1,126 @@
+package core
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/errors"
+	"github.com/grzegorzmaniak/gothic/helpers"
+	"go.uber.org/zap"
+)
+
+const (
+	// ClientCertificateSessionGroup is the session mode of requests authenticated with a client certificate, use
+	// it in the Allow and Block lists of routes.
+	ClientCertificateSessionGroup = "mtls"
+
+	ClientCertificateFingerprintClaim = "___cf" // The SHA-256 fingerprint of the client certificate
+)
+
+// ClientCertificate is the verified leaf certificate a client presented at the TLS layer.
+type ClientCertificate struct {
+	Certificate *x509.Certificate
+
+	// Fingerprint is the hex SHA-256 of the certificate's DER encoding, see CertificateFingerprint.
+	Fingerprint string
+
+	// - The identities of the subject alternative name, e.g., a SPIFFE ID in URIs
+	DNSNames       []string
+	URIs           []string
+	EmailAddresses []string
+}
+
+// ClientCertificateResolver maps a verified client certificate to the claims of the request, e.g., by its SPIFFE
+// ID or a fingerprint pinned in a service registry. Return nil claims for certificates that are not accepted.
+type ClientCertificateResolver func(ctx context.Context, certificate *ClientCertificate) (*SessionClaims, error)
+
+// ClientCertificateProvider is implemented by session managers that accept client certificates on routes with
+// AllowClientCertificates, see SessionManagerBuilder.WithClientCertificateResolver.
+type ClientCertificateProvider interface {
+	GetClientCertificateResolver() ClientCertificateResolver
+}
+
+// CertificateFingerprint returns the hex SHA-256 of the certificate's DER encoding.
+func CertificateFingerprint(certificate *x509.Certificate) string {
+	sum := sha256.Sum256(certificate.Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// ClientCertificateFromContext returns the leaf of the first chain the TLS server verified, nil when the request
+// has none. Certificates that were presented but not verified (tls.RequestClientCert, tls.RequireAnyClientCert)
+// are never used, the server's tls.Config must verify them, e.g., with tls.VerifyClientCertIfGiven and ClientCAs.
+func ClientCertificateFromContext(ctx *gin.Context) *ClientCertificate {
+	if ctx.Request == nil || ctx.Request.TLS == nil {
+		return nil
+	}
+	chains := ctx.Request.TLS.VerifiedChains
+	if len(chains) == 0 || len(chains[0]) == 0 {
+		return nil
+	}
+
+	leaf := chains[0][0]
+	uris := make([]string, 0, len(leaf.URIs))
+	for _, uri := range leaf.URIs {
+		uris = append(uris, uri.String())
+	}
+	return &ClientCertificate{
+		Certificate:    leaf,
+		Fingerprint:    CertificateFingerprint(leaf),
+		DNSNames:       leaf.DNSNames,
+		URIs:           uris,
+		EmailAddresses: leaf.EmailAddresses,
+	}
+}
+
+// extractClientCertificateSession resolves the verified client certificate of a request to a route with
+// AllowClientCertificates. found is false when the request has no verified certificate or the manager no
+// resolver. The certificate is proven by the TLS handshake, so no CSRF token is needed; do not enable it on
+// routes browsers with installed certificates can be lured to.
+func extractClientCertificateSession(
+	ctx *gin.Context,
+	sessionManager SessionManager,
+	sessionConfig *APIConfiguration,
+) (claims *SessionClaims, group string, found bool, appErr *errors.AppError) {
+	provider, ok := sessionManager.(ClientCertificateProvider)
+	if !ok || provider.GetClientCertificateResolver() == nil {
+		return nil, "", false, nil
+	}
+	certificate := ClientCertificateFromContext(ctx)
+	if certificate == nil {
+		return nil, "", false, nil
+	}
+
+	resolved, err := provider.GetClientCertificateResolver()(ctx, certificate)
+	if err != nil || resolved == nil {
+		helpers.Logger(ctx).Debug("Client certificate was not accepted", zap.String("fingerprint", certificate.Fingerprint), zap.Error(err))
+		if err == nil {
+			err = fmt.Errorf("client certificate '%s' is not accepted", certificate.Fingerprint)
+		}
+		return nil, "", true, errors.NewUnauthorized("", err)
+	}
+
+	// - The resolver's claims are copied, the reserved ones are always set from the certificate
+	claims = &SessionClaims{Claims: make(map[string]string, len(resolved.Claims)+3), HasSession: true}
+	for name, value := range resolved.Claims {
+		claims.Claims[name] = value
+	}
+	claims.SetClaim(SessionModeClaim, ClientCertificateSessionGroup)
+	claims.SetClaim(ClientCertificateFingerprintClaim, certificate.Fingerprint)
+
+	// - Every request of a certificate shares its RBAC cache entries
+	if sessionManager.GetRbacManager() != nil {
+		claims.SetClaim(RbacCacheIdentifier, certificate.Fingerprint[:helpers.AESKeySize32])
+	}
+
+	if _, claims, group, appErr = _verifyClaimsAndHandleSessionState(ctx, sessionManager, sessionConfig, claims, nil, ClientCertificateSessionGroup); appErr != nil {
+		return nil, "", true, appErr
+	}
+	if claims == nil {
+		return nil, "", true, errors.NewUnauthorized("", nil)
+	}
+	return claims, group, true, nil
+}
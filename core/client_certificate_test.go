@@ -0,0 +1,131 @@
+package core
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/errors"
+)
+
+func newClientCertificate(t *testing.T, spiffeId string) *x509.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate the key: %v", err)
+	}
+	uri, _ := url.Parse(spiffeId)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "service"},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+		URIs:         []*url.URL{uri},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create the certificate: %v", err)
+	}
+	certificate, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("Failed to parse the certificate: %v", err)
+	}
+	return certificate
+}
+
+func TestClientCertificateRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	billing := newClientCertificate(t, "spiffe://internal/billing")
+	unknown := newClientCertificate(t, "spiffe://internal/unknown")
+
+	manager, err := NewSessionManagerBuilder().
+		WithKey("k1", newBuilderKey(t)).
+		WithClientCertificateResolver(func(_ context.Context, certificate *ClientCertificate) (*SessionClaims, error) {
+			if len(certificate.URIs) != 1 || certificate.URIs[0] != "spiffe://internal/billing" {
+				return nil, nil
+			}
+			return &SessionClaims{Claims: map[string]string{DefaultBuilderSubjectClaim: "billing", SessionModeClaim: "forged"}}, nil
+		}).
+		Build()
+	if err != nil {
+		t.Fatalf("Expected Build to succeed, got %v", err)
+	}
+	defer manager.Close()
+
+	type output struct {
+		Subject     string `json:"subject"`
+		Mode        string `json:"mode"`
+		Fingerprint string `json:"fingerprint"`
+	}
+	handler := func(_ *struct{}, data *Handler[testBaseRoute]) (*output, *errors.AppError) {
+		if data.Claims == nil {
+			return &output{}, nil
+		}
+		subject, _ := data.Claims.GetClaim(DefaultBuilderSubjectClaim)
+		mode, _ := data.Claims.GetClaim(SessionModeClaim)
+		fingerprint, _ := data.Claims.GetClaim(ClientCertificateFingerprintClaim)
+		return &output{Subject: subject, Mode: mode, Fingerprint: fingerprint}, nil
+	}
+
+	router := gin.New()
+	ctor := NewRouteConstructor(router, testBaseRoute{}, manager, nil)
+	POST(ctor, "/internal", AuthenticatedJSONAPI().WithClientCertificates(), handler)
+	POST(ctor, "/sessions-only", AuthenticatedJSONAPI(), handler)
+	POST(ctor, "/optional", PublicRoute().WithClientCertificates(), handler)
+
+	request := func(path string, state *tls.ConnectionState) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		req.TLS = state
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder
+	}
+	verified := func(certificate *x509.Certificate) *tls.ConnectionState {
+		return &tls.ConnectionState{PeerCertificates: []*x509.Certificate{certificate}, VerifiedChains: [][]*x509.Certificate{{certificate}}}
+	}
+
+	t.Run("Verified certificates are resolved to claims", func(t *testing.T) {
+		recorder := request("/internal", verified(billing))
+		body := recorder.Body.String()
+		if recorder.Code != http.StatusOK || !strings.Contains(body, `"subject":"billing"`) || !strings.Contains(body, CertificateFingerprint(billing)) {
+			t.Fatalf("Expected the certificate to be accepted, got %d: %s", recorder.Code, body)
+		}
+		if !strings.Contains(body, `"mode":"`+ClientCertificateSessionGroup+`"`) {
+			t.Errorf("Expected the resolver not to pick the session mode, got %s", body)
+		}
+	})
+
+	t.Run("Unverified and unknown certificates are rejected", func(t *testing.T) {
+		if recorder := request("/internal", &tls.ConnectionState{PeerCertificates: []*x509.Certificate{billing}}); recorder.Code != http.StatusUnauthorized {
+			t.Errorf("Expected an unverified certificate to be ignored, got %d", recorder.Code)
+		}
+		if recorder := request("/internal", verified(unknown)); recorder.Code != http.StatusUnauthorized {
+			t.Errorf("Expected the resolver to reject the certificate, got %d", recorder.Code)
+		}
+		if recorder := request("/internal", nil); recorder.Code != http.StatusUnauthorized {
+			t.Errorf("Expected plain requests to need a session, got %d", recorder.Code)
+		}
+	})
+
+	t.Run("Routes opt in to client certificates", func(t *testing.T) {
+		if recorder := request("/sessions-only", verified(billing)); recorder.Code != http.StatusUnauthorized {
+			t.Errorf("Expected certificates to be ignored on routes without AllowClientCertificates, got %d", recorder.Code)
+		}
+		if recorder := request("/optional", nil); recorder.Code != http.StatusOK || strings.Contains(recorder.Body.String(), "billing") {
+			t.Errorf("Expected an anonymous request without a certificate, got %d: %s", recorder.Code, recorder.Body.String())
+		}
+	})
+}
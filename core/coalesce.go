@@ -0,0 +1,123 @@
+package core
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/errors"
+	"github.com/grzegorzmaniak/gothic/helpers"
+	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	RouteCoalesceKeyPrefix = "route_sf:" // Key: route_sf:<method> <path>?<query>|<group>|<subject>
+)
+
+var routeRequestGroup singleflight.Group
+
+// coalescedResult is what is shared between all callers of a coalesced request.
+type coalescedResult struct {
+	response *routeResponse
+	appErr   *errors.AppError
+}
+
+// CoalesceKeyFunc builds the key used to coalesce identical requests. Requests with the same key that arrive
+// while a previous one is still being handled will share its response. Returning an empty string disables
+// coalescing for that request.
+type CoalesceKeyFunc func(ctx *gin.Context, sessionManager SessionManager, claims *SessionClaims, group string) string
+
+// DefaultCoalesceKey builds a key from the request path, the normalized query string, the session group and the
+// subject identifier, so that two different subjects never share a response.
+//
+// Note: Headers are not part of the key, if the route binds input from headers, provide a custom CoalesceKeyFunc.
+func DefaultCoalesceKey(ctx *gin.Context, sessionManager SessionManager, claims *SessionClaims, group string) string {
+	if ctx == nil || ctx.Request == nil || ctx.Request.URL == nil {
+		return ""
+	}
+
+	subject := ""
+	if claims != nil && claims.HasSession {
+		if sessionManager == nil {
+			return ""
+		}
+
+		subjectIdentifier, err := sessionManager.GetSubjectIdentifier(claims)
+		if err != nil || subjectIdentifier == "" {
+			// - Never risk sharing a response between subjects, just don't coalesce.
+			helpers.Logger(ctx).Debug("Unable to get subject identifier, request will not be coalesced", zap.Error(err))
+			return ""
+		}
+		subject = subjectIdentifier
+	}
+
+	var sb strings.Builder
+	sb.WriteString(ctx.Request.Method)
+	sb.WriteString(" ")
+	sb.WriteString(ctx.Request.URL.Path)
+	sb.WriteString("?")
+	sb.WriteString(ctx.Request.URL.Query().Encode()) // - Encode sorts by key
+	sb.WriteString("|")
+	sb.WriteString(group)
+	sb.WriteString("|")
+	sb.WriteString(subject)
+	return sb.String()
+}
+
+func shouldCoalesce(ctx *gin.Context, sessionConfig *APIConfiguration) bool {
+	return sessionConfig.Coalesce &&
+		!helpers.ResponseCaptured(ctx) &&
+		!sessionConfig.ManualResponse &&
+		ctx.Request != nil &&
+		ctx.Request.Method == http.MethodGet
+}
+
+// executeCoalesced runs fn, sharing its result with any identical GET requests that arrive while it is running.
+// Only the leader's handler is executed, so coalesced routes must not rely on writing to the context
+// (cookies, headers set directly on the context) as followers will not receive them.
+func executeCoalesced(
+	ctx *gin.Context,
+	sessionManager SessionManager,
+	sessionConfig *APIConfiguration,
+	claims *SessionClaims,
+	group string,
+	fn func() (*routeResponse, *errors.AppError),
+) (*routeResponse, *errors.AppError) {
+	if !shouldCoalesce(ctx, sessionConfig) {
+		return fn()
+	}
+
+	keyFunc := sessionConfig.CoalesceKeyFunc
+	if keyFunc == nil {
+		keyFunc = DefaultCoalesceKey
+	}
+
+	key := keyFunc(ctx, sessionManager, claims, group)
+	if key == "" {
+		return fn()
+	}
+
+	// - Shared executions are tracked so the session manager can drain them on shutdown
+	_, done, err := helpers.LifecycleOf(sessionManager).Begin(ctx)
+	if err != nil {
+		return nil, errors.NewAppError(http.StatusServiceUnavailable, "Service is shutting down", err)
+	}
+	defer done()
+
+	result, _, shared := routeRequestGroup.Do(RouteCoalesceKeyPrefix+key, func() (interface{}, error) {
+		response, appErr := fn()
+		return coalescedResult{response: response, appErr: appErr}, nil
+	})
+
+	if shared {
+		helpers.Logger(ctx).Debug("Request was coalesced with an identical in-flight request", zap.String("key", key))
+	}
+
+	data, ok := result.(coalescedResult)
+	if !ok {
+		return nil, errors.NewInternalServerError("Unexpected coalesced result", nil)
+	}
+
+	return data.response, data.appErr
+}
@@ -0,0 +1,128 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/errors"
+)
+
+func newCoalesceTestContext(method string, target string) *gin.Context {
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Request = httptest.NewRequest(method, target, nil)
+	return ctx
+}
+
+func TestDefaultCoalesceKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Query parameter order does not matter", func(t *testing.T) {
+		a := DefaultCoalesceKey(newCoalesceTestContext(http.MethodGet, "/items?b=2&a=1"), nil, nil, "")
+		b := DefaultCoalesceKey(newCoalesceTestContext(http.MethodGet, "/items?a=1&b=2"), nil, nil, "")
+		if a == "" || a != b {
+			t.Errorf("Expected identical keys, got '%s' and '%s'", a, b)
+		}
+	})
+
+	t.Run("Different paths produce different keys", func(t *testing.T) {
+		a := DefaultCoalesceKey(newCoalesceTestContext(http.MethodGet, "/items/1"), nil, nil, "")
+		b := DefaultCoalesceKey(newCoalesceTestContext(http.MethodGet, "/items/2"), nil, nil, "")
+		if a == b {
+			t.Errorf("Expected different keys, got '%s' for both", a)
+		}
+	})
+
+	t.Run("Session without a manager is never coalesced", func(t *testing.T) {
+		claims := &SessionClaims{HasSession: true}
+		if key := DefaultCoalesceKey(newCoalesceTestContext(http.MethodGet, "/items"), nil, claims, "user"); key != "" {
+			t.Errorf("Expected an empty key, got '%s'", key)
+		}
+	})
+}
+
+func TestExecuteCoalesced(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	config := &APIConfiguration{Coalesce: true}
+
+	t.Run("Identical concurrent requests share a single execution", func(t *testing.T) {
+		var calls int32
+		release := make(chan struct{})
+		fn := func() (*routeResponse, *errors.AppError) {
+			atomic.AddInt32(&calls, 1)
+			<-release
+			return &routeResponse{Body: "shared"}, nil
+		}
+
+		var wg sync.WaitGroup
+		results := make([]*routeResponse, 5)
+		for i := range results {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				results[i], _ = executeCoalesced(newCoalesceTestContext(http.MethodGet, "/shared"), nil, config, nil, "", fn)
+			}(i)
+		}
+
+		time.Sleep(20 * time.Millisecond)
+		close(release)
+		wg.Wait()
+
+		if atomic.LoadInt32(&calls) != 1 {
+			t.Errorf("Expected the handler to run once, ran %d times", calls)
+		}
+		for i, result := range results {
+			if result == nil || result.Body != "shared" {
+				t.Errorf("Request %d did not receive the shared response: %+v", i, result)
+			}
+		}
+	})
+
+	t.Run("Non GET requests are never coalesced", func(t *testing.T) {
+		var calls int32
+		fn := func() (*routeResponse, *errors.AppError) {
+			atomic.AddInt32(&calls, 1)
+			return nil, nil
+		}
+
+		for i := 0; i < 3; i++ {
+			_, _ = executeCoalesced(newCoalesceTestContext(http.MethodPost, "/shared"), nil, config, nil, "", fn)
+		}
+
+		if calls != 3 {
+			t.Errorf("Expected 3 executions, got %d", calls)
+		}
+	})
+
+	t.Run("Handler errors are shared", func(t *testing.T) {
+		fn := func() (*routeResponse, *errors.AppError) {
+			return nil, errors.NewNotFound("", nil)
+		}
+
+		_, appErr := executeCoalesced(newCoalesceTestContext(http.MethodGet, "/missing"), nil, config, nil, "", fn)
+		if appErr == nil || appErr.Code != http.StatusNotFound {
+			t.Errorf("Expected a 404 AppError, got %v", appErr)
+		}
+	})
+
+	t.Run("Shut down session managers reject new coalesced work", func(t *testing.T) {
+		mgr := newMockSessionManager(t)
+		if err := mgr.Shutdown(context.Background()); err != nil {
+			t.Fatalf("Failed to shut down: %v", err)
+		}
+
+		fn := func() (*routeResponse, *errors.AppError) {
+			t.Error("Expected the handler not to run")
+			return nil, nil
+		}
+		_, appErr := executeCoalesced(newCoalesceTestContext(http.MethodGet, "/closed"), mgr, config, nil, "", fn)
+		if appErr == nil || appErr.Code != http.StatusServiceUnavailable {
+			t.Errorf("Expected a 503 AppError, got %v", appErr)
+		}
+	})
+}
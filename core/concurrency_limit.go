@@ -0,0 +1,87 @@
+package core
+
+import (
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/errors"
+	"github.com/grzegorzmaniak/gothic/helpers"
+	"go.uber.org/zap"
+)
+
+// ConcurrencyLimitReason is the reason in the details of the 503 sent when a route is saturated.
+const ConcurrencyLimitReason = "concurrency_limit"
+
+// routeSlots holds the semaphore of every route with MaxConcurrent, keyed by routeSlotsKey. The size is fixed on
+// the route's first request.
+var routeSlots sync.Map
+
+// routeSlotsKey identifies a route by its method and registered path, a configuration shared by several routes
+// still limits each of them on its own. The configuration tells apart routes executed outside a router, which
+// have no registered path.
+type routeSlotsKey struct {
+	config *APIConfiguration
+	route  string
+}
+
+// concurrencySlots returns the semaphore of the request's route.
+func concurrencySlots(ctx *gin.Context, sessionConfig *APIConfiguration) chan struct{} {
+	key := routeSlotsKey{config: sessionConfig, route: ctx.Request.Method + " " + ctx.FullPath()}
+	if slots, ok := routeSlots.Load(key); ok {
+		return slots.(chan struct{})
+	}
+	slots, _ := routeSlots.LoadOrStore(key, make(chan struct{}, sessionConfig.MaxConcurrent))
+	return slots.(chan struct{})
+}
+
+// executeLimited runs run once the route has a free slot, see APIConfiguration.MaxConcurrent. Saturated routes
+// answer 503 with a Retry-After and the limit in the details.
+func executeLimited(ctx *gin.Context, sessionConfig *APIConfiguration, run func() (*routeResponse, *errors.AppError)) (*routeResponse, *errors.AppError) {
+	if sessionConfig.MaxConcurrent <= 0 {
+		return run()
+	}
+
+	slots := concurrencySlots(ctx, sessionConfig)
+	select {
+	case slots <- struct{}{}:
+	default:
+		if !waitForSlot(ctx, slots, sessionConfig.MaxConcurrentQueueTimeout) {
+			return nil, concurrencyLimitError(ctx, sessionConfig)
+		}
+	}
+	defer func() { <-slots }()
+	return run()
+}
+
+// waitForSlot queues for a slot until the timeout or the request's context ends.
+func waitForSlot(ctx *gin.Context, slots chan struct{}, timeout time.Duration) bool {
+	if timeout <= 0 {
+		return false
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case slots <- struct{}{}:
+		return true
+	case <-timer.C:
+		return false
+	case <-ctx.Request.Context().Done():
+		return false
+	}
+}
+
+func concurrencyLimitError(ctx *gin.Context, sessionConfig *APIConfiguration) *errors.AppError {
+	helpers.Logger(ctx).Warn("Route is saturated, request shed",
+		zap.String("path", ctx.FullPath()), zap.Int("max_concurrent", sessionConfig.MaxConcurrent))
+
+	retryAfter := max(1, int(math.Ceil(sessionConfig.MaxConcurrentQueueTimeout.Seconds())))
+	ctx.Header("Retry-After", strconv.Itoa(retryAfter))
+	return errors.NewServiceUnavailable("Too many requests are being processed, please try again later.", nil, map[string]interface{}{
+		"reason":         ConcurrencyLimitReason,
+		"max_concurrent": sessionConfig.MaxConcurrent,
+	})
+}
@@ -0,0 +1,121 @@
+package core
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/errors"
+)
+
+func TestConcurrencyLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mgr := newMockSessionManager(t)
+
+	router := gin.New()
+	ctor := NewRouteConstructor(router, testBaseRoute{}, mgr, nil)
+	started, release := make(chan struct{}, 4), make(chan struct{})
+	slow := func(_ *struct{}, _ *Handler[testBaseRoute]) (*struct{}, *errors.AppError) {
+		started <- struct{}{}
+		<-release
+		return &struct{}{}, nil
+	}
+	GET(ctor, "/export", PublicRoute().WithMaxConcurrent(1, 0), slow)
+	GET(ctor, "/report", PublicRoute().WithMaxConcurrent(1, time.Second), slow)
+	GET(ctor, "/queued", PublicRoute().WithMaxConcurrent(1, 20*time.Millisecond), slow)
+	shared := PublicRoute().WithMaxConcurrent(1, 0)
+	GET(ctor, "/shared/a", shared, slow)
+	GET(ctor, "/shared/b", shared, slow)
+
+	request := func(path string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		return recorder
+	}
+	// - hold runs a request in the background until release is closed, once it reached the handler
+	hold := func(path string, wg *sync.WaitGroup, codes chan<- int) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			codes <- request(path).Code
+		}()
+		<-started
+	}
+
+	t.Run("Saturated routes shed requests", func(t *testing.T) {
+		var wg sync.WaitGroup
+		codes := make(chan int, 1)
+		hold("/export", &wg, codes)
+
+		recorder := request("/export")
+		if recorder.Code != http.StatusServiceUnavailable || recorder.Header().Get("Retry-After") != "1" {
+			t.Errorf("Expected a 503 with Retry-After, got %d: %v", recorder.Code, recorder.Header())
+		}
+		var body struct {
+			Details map[string]interface{} `json:"details"`
+		}
+		if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil || body.Details["reason"] != ConcurrencyLimitReason || body.Details["max_concurrent"] != float64(1) {
+			t.Errorf("Expected the limit in the details, got %s", recorder.Body.String())
+		}
+
+		release <- struct{}{}
+		wg.Wait()
+		if code := <-codes; code != http.StatusOK {
+			t.Errorf("Expected the held request to succeed, got %d", code)
+		}
+	})
+
+	t.Run("Queued requests run once a slot frees", func(t *testing.T) {
+		var wg sync.WaitGroup
+		codes := make(chan int, 2)
+		hold("/report", &wg, codes)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			codes <- request("/report").Code
+		}()
+		time.Sleep(20 * time.Millisecond)
+		release <- struct{}{}
+		<-started
+		release <- struct{}{}
+		wg.Wait()
+		if first, second := <-codes, <-codes; first != http.StatusOK || second != http.StatusOK {
+			t.Errorf("Expected both requests to succeed, got %d and %d", first, second)
+		}
+	})
+
+	t.Run("Queued requests give up after the timeout", func(t *testing.T) {
+		var wg sync.WaitGroup
+		codes := make(chan int, 1)
+		hold("/queued", &wg, codes)
+
+		if recorder := request("/queued"); recorder.Code != http.StatusServiceUnavailable {
+			t.Errorf("Expected a 503 after the queue timeout, got %d", recorder.Code)
+		}
+		release <- struct{}{}
+		wg.Wait()
+		<-codes
+	})
+
+	t.Run("Routes sharing a configuration have their own limit", func(t *testing.T) {
+		var wg sync.WaitGroup
+		codes := make(chan int, 2)
+		hold("/shared/a", &wg, codes)
+		hold("/shared/b", &wg, codes)
+
+		if recorder := request("/shared/a"); recorder.Code != http.StatusServiceUnavailable {
+			t.Errorf("Expected the saturated route to shed the request, got %d", recorder.Code)
+		}
+		release <- struct{}{}
+		release <- struct{}{}
+		wg.Wait()
+		if first, second := <-codes, <-codes; first != http.StatusOK || second != http.StatusOK {
+			t.Errorf("Expected both routes to run a request, got %d and %d", first, second)
+		}
+	})
+}
@@ -0,0 +1,287 @@
+package core
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/helpers"
+	"github.com/grzegorzmaniak/gothic/rbac"
+)
+
+// The zero value of APIConfiguration has SessionRequired and RequireCsrf set to false, which is easy to forget.
+// The presets below start from explicit, secure values and the With* / Without* builders relax or extend them,
+// e.g., core.AuthenticatedJSONAPI().WithRoles("editor").AllowModes("default").
+
+// PublicRoute returns a configuration for routes that do not need a session or CSRF token. A session is still
+// extracted when present, so handlers can personalise the response.
+func PublicRoute() *APIConfiguration {
+	return &APIConfiguration{
+		SessionRequired: false,
+		RequireCsrf:     false,
+		RbacPolicy:      rbac.PermissionsOrRole,
+	}
+}
+
+// AuthenticatedJSONAPI returns a configuration for routes that require a session and a CSRF token, with the
+// response handled by the framework.
+func AuthenticatedJSONAPI() *APIConfiguration {
+	return &APIConfiguration{
+		SessionRequired: true,
+		RequireCsrf:     true,
+		ManualResponse:  false,
+		RbacPolicy:      rbac.PermissionsOrRole,
+	}
+}
+
+// AdminRoute returns an AuthenticatedJSONAPI configuration that additionally requires every given permission,
+// roles alone never grant access (rbac.PermissionsOnly). It panics without permissions, as an empty requirement
+// would admit every session.
+func AdminRoute(permissions ...*rbac.Permission) *APIConfiguration {
+	if len(permissions) == 0 {
+		panic("core: AdminRoute needs at least one permission")
+	}
+	config := AuthenticatedJSONAPI()
+	config.Permissions = append(rbac.Permissions{}, permissions...)
+	config.RbacPolicy = rbac.PermissionsOnly
+	return config
+}
+
+// WithPermissions adds required permissions. Without roles, an "or role" policy becomes rbac.PermissionsOnly, see
+// requirePermissions.
+func (config *APIConfiguration) WithPermissions(permissions ...*rbac.Permission) *APIConfiguration {
+	config.Permissions = append(config.Permissions, permissions...)
+	config.flatPermissionsInitialized = false
+	return config.requirePermissions()
+}
+
+// WithNamedPermissions adds required named permissions, e.g., "billing.invoices.read". Without roles, an "or
+// role" policy becomes rbac.PermissionsOnly, see requirePermissions.
+func (config *APIConfiguration) WithNamedPermissions(names ...string) *APIConfiguration {
+	config.NamedPermissions = append(config.NamedPermissions, names...)
+	config.flatNamedPermissions, config.flatNamedPermissionsErr = nil, nil
+	return config.requirePermissions()
+}
+
+// requirePermissions switches rbac.PermissionsOrRole and rbac.PermissionsOrAllRoles to rbac.PermissionsOnly while
+// the route lists no roles. The role check passes when no roles are required, so these policies would admit
+// every session before looking at the permissions. Roles added afterwards need WithRbacPolicy to grant access.
+func (config *APIConfiguration) requirePermissions() *APIConfiguration {
+	if config.Roles != nil && len(*config.Roles) > 0 {
+		return config
+	}
+	if config.RbacPolicy == rbac.PermissionsOrRole || config.RbacPolicy == rbac.PermissionsOrAllRoles {
+		config.RbacPolicy = rbac.PermissionsOnly
+	}
+	return config
+}
+
+// WithRoles adds roles used by the RbacPolicy.
+func (config *APIConfiguration) WithRoles(roles ...string) *APIConfiguration {
+	var merged []string
+	if config.Roles != nil {
+		merged = append(merged, *config.Roles...)
+	}
+	merged = append(merged, roles...)
+	config.Roles = &merged
+	config.flatRoles = nil
+	return config
+}
+
+// WithRbacPolicy sets how roles and permissions are combined.
+func (config *APIConfiguration) WithRbacPolicy(policy rbac.RouteRbacPolicy) *APIConfiguration {
+	config.RbacPolicy = policy
+	return config
+}
+
+// AllowModes adds session modes to the Allow list.
+func (config *APIConfiguration) AllowModes(modes ...string) *APIConfiguration {
+	config.Allow = append(config.Allow, modes...)
+	return config
+}
+
+// BlockModes adds session modes to the Block list.
+func (config *APIConfiguration) BlockModes(modes ...string) *APIConfiguration {
+	config.Block = append(config.Block, modes...)
+	return config
+}
+
+// WithoutCsrf disables the CSRF requirement, only use this for routes that do not change state.
+func (config *APIConfiguration) WithoutCsrf() *APIConfiguration {
+	config.RequireCsrf = false
+	return config
+}
+
+// WithPartialInput makes dynamic routes bind partial inputs, see PartialInput.
+func (config *APIConfiguration) WithPartialInput() *APIConfiguration {
+	config.PartialInput = true
+	return config
+}
+
+// WithCsrfExemptMethods exempts methods of the route from the CSRF check, only use this for methods that do not
+// change state.
+func (config *APIConfiguration) WithCsrfExemptMethods(methods ...string) *APIConfiguration {
+	config.ExemptMethods = append(config.ExemptMethods, methods...)
+	return config
+}
+
+// WithOptionalSession makes the session optional, invalid sessions are dropped instead of rejected.
+func (config *APIConfiguration) WithOptionalSession() *APIConfiguration {
+	config.SessionRequired = false
+	return config
+}
+
+// WithManualResponse lets the handler write the response itself.
+func (config *APIConfiguration) WithManualResponse() *APIConfiguration {
+	config.ManualResponse = true
+	return config
+}
+
+// WithPolicy sets the attribute based PolicyFunc.
+func (config *APIConfiguration) WithPolicy(policy func(ctx *gin.Context, claims *SessionClaims, input interface{}) (*rbac.AttributeDecision, error)) *APIConfiguration {
+	config.PolicyFunc = policy
+	return config
+}
+
+// WithTenantResolver scopes the route's RBAC checks to the resolved tenant, e.g., core.TenantFromParam("tenant").
+func (config *APIConfiguration) WithTenantResolver(resolver TenantResolver) *APIConfiguration {
+	config.TenantResolver = resolver
+	return config
+}
+
+// WithRbacDryRun logs the route's RBAC denials instead of enforcing them.
+func (config *APIConfiguration) WithRbacDryRun() *APIConfiguration {
+	config.RbacDryRun = true
+	return config
+}
+
+// WithOnDenied renders the route's RBAC denials with fn, e.g., an upgrade offer for the missing permissions.
+func (config *APIConfiguration) WithOnDenied(fn DeniedFunc) *APIConfiguration {
+	config.OnDenied = fn
+	return config
+}
+
+// WithCoalescing enables coalescing of identical concurrent GET requests.
+func (config *APIConfiguration) WithCoalescing() *APIConfiguration {
+	config.Coalesce = true
+	return config
+}
+
+// WithLocalization formats the output's `localize` tagged fields for the subject's locale.
+func (config *APIConfiguration) WithLocalization(mode LocalizeMode) *APIConfiguration {
+	config.Localize = mode
+	return config
+}
+
+// WithoutTravelCheck excludes the route from the impossible travel detector.
+func (config *APIConfiguration) WithoutTravelCheck() *APIConfiguration {
+	config.SkipTravelCheck = true
+	return config
+}
+
+// WithMinimumAuthLevel requires sessions of at least the given AuthLevel, e.g., AuthLevelMultiFactor.
+func (config *APIConfiguration) WithMinimumAuthLevel(level AuthLevel) *APIConfiguration {
+	config.MinimumAuthLevel = level
+	return config
+}
+
+// WithMaxBodyBytes caps the request body, a negative value disables the limit.
+func (config *APIConfiguration) WithMaxBodyBytes(maxBytes int64) *APIConfiguration {
+	config.MaxBodyBytes = maxBytes
+	return config
+}
+
+// WithIdempotency replays the stored response to retries that carry the same Idempotency-Key within ttl,
+// zero uses DefaultIdempotencyTTL.
+func (config *APIConfiguration) WithIdempotency(ttl time.Duration) *APIConfiguration {
+	config.Idempotent = true
+	config.IdempotencyTTL = ttl
+	return config
+}
+
+// WithResponseCache caches the successful GET responses of the route for ttl, keyed by vary (zero uses
+// CacheVaryAll).
+func (config *APIConfiguration) WithResponseCache(ttl time.Duration, vary ResponseCacheVary) *APIConfiguration {
+	config.CacheTTL = ttl
+	config.CacheVaryBy = vary
+	return config
+}
+
+// WithCacheInvalidation drops the cached responses of routes, e.g., "GET /notes/:id", after a successful request.
+func (config *APIConfiguration) WithCacheInvalidation(routes ...string) *APIConfiguration {
+	config.InvalidatesCache = append(config.InvalidatesCache, routes...)
+	return config
+}
+
+// WithAudit selects whether the route's requests are recorded to the audit sink.
+func (config *APIConfiguration) WithAudit(mode AuditMode) *APIConfiguration {
+	config.Audit = mode
+	return config
+}
+
+// AsReadOnly marks the route as read-only, so its queries may be routed to a read replica.
+func (config *APIConfiguration) AsReadOnly() *APIConfiguration {
+	config.ReadOnly = true
+	return config
+}
+
+// WithCompression compresses the route's success responses with the best encoding the client accepts.
+func (config *APIConfiguration) WithCompression() *APIConfiguration {
+	config.ResponseEncoding.Compression = helpers.CompressionOn
+	return config
+}
+
+// WithETag tags the route's success responses, answering a matching If-None-Match with 304 Not Modified.
+func (config *APIConfiguration) WithETag(mode helpers.ETagMode) *APIConfiguration {
+	config.ResponseEncoding.ETag = mode
+	return config
+}
+
+// WithAPIKeys accepts API keys on the route next to sessions.
+func (config *APIConfiguration) WithAPIKeys() *APIConfiguration {
+	config.AllowAPIKeys = true
+	return config
+}
+
+// WithClientCertificates accepts verified TLS client certificates on the route next to sessions.
+func (config *APIConfiguration) WithClientCertificates() *APIConfiguration {
+	config.AllowClientCertificates = true
+	return config
+}
+
+// WithHandlerTimeout answers 504 Gateway Timeout when the handler runs past the timeout.
+func (config *APIConfiguration) WithHandlerTimeout(timeout time.Duration) *APIConfiguration {
+	config.HandlerTimeout = timeout
+	return config
+}
+
+// WithMaxConcurrent limits the requests running the handler at once, the others wait up to queueTimeout for a
+// slot.
+func (config *APIConfiguration) WithMaxConcurrent(maxConcurrent int, queueTimeout time.Duration) *APIConfiguration {
+	config.MaxConcurrent = maxConcurrent
+	config.MaxConcurrentQueueTimeout = queueTimeout
+	return config
+}
+
+// WithFeatureFlag hides the route while the feature flag is disabled for the request.
+func (config *APIConfiguration) WithFeatureFlag(flag string) *APIConfiguration {
+	config.FeatureFlag = flag
+	return config
+}
+
+// AsMaintenanceExempt keeps the route available in maintenance mode.
+func (config *APIConfiguration) AsMaintenanceExempt() *APIConfiguration {
+	config.MaintenanceExempt = true
+	return config
+}
+
+// WithCors sets the CORS policy of the route.
+func (config *APIConfiguration) WithCors(policy *CorsPolicy) *APIConfiguration {
+	config.Cors = policy
+	return config
+}
+
+// WithHooks adds hooks run inside the route's lifecycle.
+func (config *APIConfiguration) WithHooks(hooks Hooks) *APIConfiguration {
+	config.Hooks = config.Hooks.merge(hooks)
+	return config
+}
@@ -0,0 +1,114 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	internalcache "github.com/grzegorzmaniak/gothic/cache"
+	"github.com/grzegorzmaniak/gothic/errors"
+	"github.com/grzegorzmaniak/gothic/rbac"
+)
+
+func TestConfigurationPresets(t *testing.T) {
+	tests := []struct {
+		name            string
+		config          *APIConfiguration
+		sessionRequired bool
+		requireCsrf     bool
+		policy          rbac.RouteRbacPolicy
+	}{
+		{name: "PublicRoute", config: PublicRoute(), sessionRequired: false, requireCsrf: false, policy: rbac.PermissionsOrRole},
+		{name: "AuthenticatedJSONAPI", config: AuthenticatedJSONAPI(), sessionRequired: true, requireCsrf: true, policy: rbac.PermissionsOrRole},
+		{name: "AdminRoute", config: AdminRoute(rbac.NewPermission(1)), sessionRequired: true, requireCsrf: true, policy: rbac.PermissionsOnly},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.config.SessionRequired != tt.sessionRequired {
+				t.Errorf("Expected SessionRequired %v, got %v", tt.sessionRequired, tt.config.SessionRequired)
+			}
+			if tt.config.RequireCsrf != tt.requireCsrf {
+				t.Errorf("Expected RequireCsrf %v, got %v", tt.requireCsrf, tt.config.RequireCsrf)
+			}
+			if tt.config.RbacPolicy != tt.policy {
+				t.Errorf("Expected RbacPolicy %v, got %v", tt.policy, tt.config.RbacPolicy)
+			}
+		})
+	}
+
+	t.Run("Builders reset cached lookups", func(t *testing.T) {
+		config := AdminRoute(rbac.NewPermission(1))
+		if !config.GetFlatPermissions().Has(rbac.NewPermission(1)) {
+			t.Fatal("Expected bit 1 to be required")
+		}
+
+		config.WithPermissions(rbac.NewPermission(2)).WithRoles("admin").WithRoles("owner")
+		if !config.GetFlatPermissions().Has(rbac.NewPermission(2)) {
+			t.Error("Expected bit 2 to be required after WithPermissions")
+		}
+		if roles := config.GetFlatRoles(); !roles["admin"] || !roles["owner"] {
+			t.Errorf("Expected both roles, got %v", roles)
+		}
+	})
+}
+
+func TestPresetPermissionsAreRequired(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mgr := newMockSessionManager(t)
+	mgr.rbacManager = &namedRbacManager{
+		cacheManager: internalcache.BuildDefaultCacheManager(nil),
+		named:        map[string]rbac.PermissionSet{"viewer-1": rbac.MustPermissionSet("reports.read")},
+	}
+
+	router := gin.New()
+	ctor := NewRouteConstructor(router, testBaseRoute{}, mgr, nil)
+	handler := func(_ *struct{}, _ *Handler[testBaseRoute]) (*benchmarkOutput, *errors.AppError) {
+		return &benchmarkOutput{Message: "ok"}, nil
+	}
+	POST(ctor, "/reports/export", AuthenticatedJSONAPI().WithoutCsrf().WithNamedPermissions("reports.export"), handler)
+	POST(ctor, "/reports/view", AuthenticatedJSONAPI().WithoutCsrf().WithNamedPermissions("reports.read"), handler)
+	POST(ctor, "/articles", AuthenticatedJSONAPI().WithoutCsrf().WithPermissions(rbac.NewPermission(1)), handler)
+	POST(ctor, "/public/reports", PublicRoute().WithNamedPermissions("reports.export"), handler)
+	POST(ctor, "/editors", AuthenticatedJSONAPI().WithoutCsrf().WithRoles("editor").WithNamedPermissions("reports.export"), handler)
+
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/login", nil)
+	token, err := IssueBearerToken(ctx, mgr, "default", &SessionClaims{Claims: map[string]string{"subject": "viewer-1"}})
+	if err != nil {
+		t.Fatalf("Failed to issue the bearer token: %v", err)
+	}
+
+	for path, want := range map[string]int{
+		"/reports/export": http.StatusUnauthorized,
+		"/reports/view":   http.StatusOK,
+		"/articles":       http.StatusUnauthorized,
+		"/public/reports": http.StatusUnauthorized,
+		"/editors":        http.StatusUnauthorized,
+	} {
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		req.Header.Set(DefaultSessionAuthorizationHeaderName, token)
+		router.ServeHTTP(recorder, req)
+		if recorder.Code != want {
+			t.Errorf("%s: expected %d, got %d: %s", path, want, recorder.Code, recorder.Body.String())
+		}
+	}
+
+	t.Run("Roles keep the or role policy", func(t *testing.T) {
+		config := AuthenticatedJSONAPI().WithRoles("editor").WithPermissions(rbac.NewPermission(1))
+		if config.RbacPolicy != rbac.PermissionsOrRole {
+			t.Errorf("Expected PermissionsOrRole, got %v", config.RbacPolicy)
+		}
+	})
+
+	t.Run("AdminRoute without permissions panics", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Error("Expected AdminRoute() to panic")
+			}
+		}()
+		AdminRoute()
+	})
+}
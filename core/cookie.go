@@ -0,0 +1,244 @@
+package core
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/helpers"
+)
+
+// CookiePrefix is a cookie name prefix browsers enforce, see
+// https://developer.mozilla.org/en-US/docs/Web/HTTP/Headers/Set-Cookie#cookie_prefixes
+type CookiePrefix string
+
+const (
+	// CookiePrefixNone leaves the cookie name as it is (Default)
+	CookiePrefixNone CookiePrefix = ""
+
+	// CookiePrefixSecure ("__Secure-") cookies are only accepted with the Secure attribute
+	CookiePrefixSecure CookiePrefix = "__Secure-"
+
+	// CookiePrefixHost ("__Host-") cookies are only accepted with Secure, without a Domain and with Path=/, so
+	// they are bound to the exact host that set them and can't be planted by a sibling subdomain
+	CookiePrefixHost CookiePrefix = "__Host-"
+)
+
+// CookieConfig holds all the generic parameters for setting a cookie.
+type CookieConfig struct {
+	Name     string
+	Value    string
+	MaxAge   int
+	Path     string
+	Domain   string
+	Secure   bool
+	HttpOnly bool
+
+	// SameSite is "Strict", "Lax" or "None" (case-insensitive), empty leaves the attribute out
+	SameSite string
+
+	// Partitioned sets the Partitioned attribute (CHIPS), the cookie is kept per top level site, so it works in
+	// third party iframes once browsers block unpartitioned third party cookies
+	Partitioned bool
+
+	// Prefix is prepended to Name, the browser enforces its requirements
+	Prefix CookiePrefix
+}
+
+// parseSameSite maps the SameSite setting of the configurations to its http.SameSite value.
+func parseSameSite(sameSite string) (http.SameSite, error) {
+	switch strings.ToLower(sameSite) {
+	case "":
+		return http.SameSiteDefaultMode, nil
+	case "strict":
+		return http.SameSiteStrictMode, nil
+	case "lax":
+		return http.SameSiteLaxMode, nil
+	case "none":
+		return http.SameSiteNoneMode, nil
+	default:
+		return http.SameSiteDefaultMode, fmt.Errorf("unknown SameSite value '%s', expected Strict, Lax or None", sameSite)
+	}
+}
+
+// Validate reports settings a browser would reject or silently drop the cookie for.
+func (c CookieConfig) Validate() error {
+	name := string(c.Prefix) + c.Name
+	sameSite, err := parseSameSite(c.SameSite)
+	if err != nil {
+		return fmt.Errorf("cookie '%s': %w", name, err)
+	}
+
+	switch {
+	case c.Prefix != CookiePrefixNone && c.Prefix != CookiePrefixSecure && c.Prefix != CookiePrefixHost:
+		return fmt.Errorf("cookie '%s': unknown cookie prefix '%s'", name, c.Prefix)
+	case sameSite == http.SameSiteNoneMode && !c.Secure:
+		return fmt.Errorf("cookie '%s': SameSite=None requires Secure", name)
+	case c.Partitioned && !c.Secure:
+		return fmt.Errorf("cookie '%s': Partitioned requires Secure", name)
+	case c.Prefix != CookiePrefixNone && !c.Secure:
+		return fmt.Errorf("cookie '%s': the %s prefix requires Secure", name, c.Prefix)
+	case c.Prefix == CookiePrefixHost && c.Domain != "":
+		return fmt.Errorf("cookie '%s': the %s prefix forbids a Domain", name, c.Prefix)
+	case c.Prefix == CookiePrefixHost && c.Path != "/":
+		return fmt.Errorf("cookie '%s': the %s prefix requires Path=/", name, c.Prefix)
+	}
+	return nil
+}
+
+// setCookie writes the cookie with all of its attributes, gin's SetCookie has no Partitioned and takes SameSite
+// from the context rather than the cookie. Invalid settings are rejected by the Validate calls of the setters.
+func setCookie(ctx *gin.Context, config CookieConfig) {
+	sameSite, _ := parseSameSite(config.SameSite)
+	http.SetCookie(ctx.Writer, &http.Cookie{
+		Name:        string(config.Prefix) + config.Name,
+		Value:       config.Value,
+		MaxAge:      config.MaxAge,
+		Path:        helpers.DefaultString(config.Path, "/"),
+		Domain:      config.Domain,
+		Secure:      config.Secure,
+		HttpOnly:    config.HttpOnly,
+		SameSite:    sameSite,
+		Partitioned: config.Partitioned,
+	})
+}
+
+// cookiePrefix returns the prefix of the session cookies, CookiePrefixHost under HostOnlyCookies.
+func (c *SessionAuthorizationConfiguration) cookiePrefix() CookiePrefix {
+	if c.HostOnlyCookies {
+		return CookiePrefixHost
+	}
+	return c.CookiePrefix
+}
+
+// hostOnlyCookies reports whether the session manager enforces __Host- cookies, see HostOnlyCookies.
+func hostOnlyCookies(sessionManager SessionManager) bool {
+	authorizationData := sessionManager.GetAuthorizationConfiguration()
+	return authorizationData != nil && authorizationData.HostOnlyCookies
+}
+
+// sessionCookieConfig returns the cookie settings of the session (and remember-me) cookies, name excludes the
+// CookiePrefix.
+func sessionCookieConfig(authorizationData *SessionAuthorizationConfiguration, name string, value string, maxAge int) CookieConfig {
+	// - __Host- cookies can't have a Domain, the default one is left out
+	prefix := authorizationData.cookiePrefix()
+	domain := authorizationData.CookieDomain
+	if domain == "" && prefix != CookiePrefixHost {
+		domain = DefaultSessionAuthorizationDomain
+	}
+
+	return CookieConfig{
+		Name:        name,
+		Value:       value,
+		MaxAge:      maxAge,
+		Path:        helpers.DefaultString(authorizationData.CookiePath, DefaultSessionAuthorizationPath),
+		Domain:      domain,
+		Secure:      helpers.DefaultBool(authorizationData.CookieSecure, DefaultSessionAuthorizationSecure),
+		HttpOnly:    helpers.DefaultBool(authorizationData.CookieHttpOnly, DefaultSessionAuthorizationHttpOnly),
+		SameSite:    helpers.DefaultString(authorizationData.CookieSameSite, DefaultSessionAuthorizationSameSite),
+		Partitioned: authorizationData.CookiePartitioned,
+		Prefix:      prefix,
+	}
+}
+
+// csrfCookieConfig returns the cookie settings of the CSRF cookie, hostOnly applies the HostOnlyCookies of the
+// session manager.
+func csrfCookieConfig(csrfData *CsrfCookieData, hostOnly bool, value string, maxAge int) CookieConfig {
+	prefix := csrfData.Prefix
+	if hostOnly {
+		prefix = CookiePrefixHost
+	}
+	return CookieConfig{
+		Name:        helpers.DefaultString(csrfData.Name, DefaultCsrfCookieName),
+		Value:       value,
+		MaxAge:      maxAge,
+		Path:        helpers.DefaultString(csrfData.Path, DefaultCsrfCookiePath),
+		Domain:      helpers.DefaultString(csrfData.Domain, DefaultCsrfCookieDomain),
+		Secure:      helpers.DefaultBool(csrfData.Secure, DefaultCsrfCookieSecure),
+		HttpOnly:    helpers.DefaultBool(csrfData.HttpOnly, DefaultCsrfCookieHttpOnly),
+		SameSite:    helpers.DefaultString(csrfData.SameSite, DefaultCsrfCookieSameSite),
+		Partitioned: csrfData.Partitioned,
+		Prefix:      prefix,
+	}
+}
+
+// ValidateCookies reports whether the session cookie settings are self-consistent, e.g., SameSite=None or a
+// cookie prefix without Secure.
+func (c *SessionAuthorizationConfiguration) ValidateCookies() error {
+	if c.HostOnlyCookies && c.CookiePrefix != CookiePrefixNone && c.CookiePrefix != CookiePrefixHost {
+		return fmt.Errorf("HostOnlyCookies conflicts with the %s CookiePrefix", c.CookiePrefix)
+	}
+	return sessionCookieConfig(c, helpers.DefaultString(c.CookieName, DefaultSessionAuthorizationName), "", 0).Validate()
+}
+
+// ValidateCookies reports whether the CSRF cookie settings are self-consistent.
+func (c *CsrfCookieData) ValidateCookies() error {
+	return c.validateCookies(false)
+}
+
+func (c *CsrfCookieData) validateCookies(hostOnly bool) error {
+	if hostOnly && c.Prefix != CookiePrefixNone && c.Prefix != CookiePrefixHost {
+		return fmt.Errorf("HostOnlyCookies conflicts with the %s CSRF cookie Prefix", c.Prefix)
+	}
+	return csrfCookieConfig(c, hostOnly, "", 0).Validate()
+}
+
+// sessionCookieName returns the name of the session cookie as sent by the browser, including the CookiePrefix.
+func sessionCookieName(authorizationData *SessionAuthorizationConfiguration) string {
+	return string(authorizationData.cookiePrefix()) + helpers.DefaultString(authorizationData.CookieName, DefaultSessionAuthorizationName)
+}
+
+// csrfCookieName returns the name of the CSRF cookie as sent by the browser, the header keeps the plain name.
+func csrfCookieName(csrfData *CsrfCookieData, hostOnly bool) string {
+	return string(csrfCookieConfig(csrfData, hostOnly, "", 0).Prefix) + helpers.DefaultString(csrfData.Name, DefaultCsrfCookieName)
+}
+
+// ValidateConfiguration rejects insecure or self-contradicting cookie settings of a session manager, e.g.,
+// SameSite=None without Secure, a __Host- cookie with a Domain, or cookies without Secure in gin's release mode.
+// NewRouteConstructor runs it, so a misconfigured service fails at startup rather than with cookies the browser
+// silently drops.
+func ValidateConfiguration(sessionManager SessionManager) error {
+	if sessionManager == nil {
+		return fmt.Errorf("session manager is nil")
+	}
+
+	authorizationData := sessionManager.GetAuthorizationConfiguration()
+	if authorizationData == nil {
+		return fmt.Errorf("authorization configuration is nil")
+	}
+	if err := authorizationData.ValidateCookies(); err != nil {
+		return fmt.Errorf("session cookie: %w", err)
+	}
+
+	// - A key in the bearer header would be read as a session token, and the other way around
+	if strings.EqualFold(
+		helpers.DefaultString(authorizationData.APIKeyHeaderName, DefaultAPIKeyHeaderName),
+		helpers.DefaultString(authorizationData.AuthorizationHeaderName, DefaultSessionAuthorizationHeaderName),
+	) {
+		return fmt.Errorf("APIKeyHeaderName must differ from AuthorizationHeaderName")
+	}
+	if err := validateSessionLimits(sessionManager, authorizationData); err != nil {
+		return err
+	}
+
+	csrfData := sessionManager.GetCsrfData()
+	if csrfData == nil {
+		return fmt.Errorf("CSRF configuration is nil")
+	}
+	if err := csrfData.validateCookies(authorizationData.HostOnlyCookies); err != nil {
+		return fmt.Errorf("CSRF cookie: %w", err)
+	}
+
+	// - Plain HTTP is only acceptable while developing
+	if gin.Mode() == gin.ReleaseMode {
+		sessionCookie := sessionCookieConfig(authorizationData, helpers.DefaultString(authorizationData.CookieName, DefaultSessionAuthorizationName), "", 0)
+		if !sessionCookie.Secure {
+			return fmt.Errorf("session cookie: Secure is required in release mode")
+		}
+		if !csrfCookieConfig(csrfData, authorizationData.HostOnlyCookies, "", 0).Secure {
+			return fmt.Errorf("CSRF cookie: Secure is required in release mode")
+		}
+	}
+	return nil
+}
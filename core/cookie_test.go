@@ -0,0 +1,176 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCookieConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  CookieConfig
+		wantErr bool
+	}{
+		{"Defaults", CookieConfig{Name: "session", Path: "/", Secure: true, SameSite: "Strict"}, false},
+		{"Lowercase SameSite", CookieConfig{Name: "session", SameSite: "lax"}, false},
+		{"Unknown SameSite", CookieConfig{Name: "session", SameSite: "Sometimes"}, true},
+		{"SameSite None without Secure", CookieConfig{Name: "session", SameSite: "None"}, true},
+		{"SameSite None with Secure", CookieConfig{Name: "session", SameSite: "None", Secure: true}, false},
+		{"Partitioned without Secure", CookieConfig{Name: "session", Partitioned: true}, true},
+		{"Secure prefix without Secure", CookieConfig{Name: "session", Prefix: CookiePrefixSecure}, true},
+		{"Host prefix", CookieConfig{Name: "session", Path: "/", Secure: true, Prefix: CookiePrefixHost}, false},
+		{"Host prefix with a Domain", CookieConfig{Name: "session", Path: "/", Domain: "example.com", Secure: true, Prefix: CookiePrefixHost}, true},
+		{"Host prefix with a Path", CookieConfig{Name: "session", Path: "/api", Secure: true, Prefix: CookiePrefixHost}, true},
+		{"Unknown prefix", CookieConfig{Name: "session", Secure: true, Prefix: "__Other-"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.config.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCookieAttributes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mgr := newMockSessionManager(t)
+
+	login := func() *httptest.ResponseRecorder {
+		t.Helper()
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+		ctx.Request = httptest.NewRequest(http.MethodPost, "/login", nil)
+		if err := SetSessionCookie(ctx, mgr, "user", &SessionClaims{Claims: map[string]string{"subject": "user-1"}}); err != nil {
+			t.Fatalf("Failed to set session cookie: %v", err)
+		}
+		return recorder
+	}
+
+	t.Run("SameSite is applied", func(t *testing.T) {
+		for _, header := range login().Header().Values("Set-Cookie") {
+			if !strings.Contains(header, "SameSite=Strict") {
+				t.Errorf("Expected SameSite=Strict, got %q", header)
+			}
+		}
+	})
+
+	t.Run("Host prefixed and partitioned cookies", func(t *testing.T) {
+		mgr.authorizationData.CookiePrefix = CookiePrefixHost
+		mgr.authorizationData.CookiePartitioned = true
+		mgr.authorizationData.CookieSameSite = "None"
+		mgr.csrfData.Prefix = CookiePrefixHost
+		defer func() {
+			mgr.authorizationData.CookiePrefix, mgr.authorizationData.CookiePartitioned, mgr.authorizationData.CookieSameSite = "", false, ""
+			mgr.csrfData.Prefix = ""
+		}()
+
+		recorder := login()
+		cookies := map[string]*http.Cookie{}
+		for _, cookie := range recorder.Result().Cookies() {
+			cookies[cookie.Name] = cookie
+		}
+		session, csrf := cookies["__Host-"+DefaultSessionAuthorizationName], cookies["__Host-"+DefaultCsrfCookieName]
+		if session == nil || csrf == nil {
+			t.Fatalf("Expected __Host- prefixed cookies, got %v", cookies)
+		}
+		if session.Domain != "" || session.Path != "/" || !session.Secure || session.SameSite != http.SameSiteNoneMode {
+			t.Errorf("Expected a host-only Secure SameSite=None cookie, got %+v", session)
+		}
+		if !strings.Contains(strings.Join(recorder.Header().Values("Set-Cookie"), "\n"), "Partitioned") {
+			t.Error("Expected the Partitioned attribute")
+		}
+
+		// - The prefixed cookies are read back, the CSRF header keeps its plain name
+		ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+		ctx.Request = httptest.NewRequest(http.MethodPost, "/", nil)
+		ctx.Request.AddCookie(session)
+		ctx.Request.AddCookie(csrf)
+		ctx.Request.Header.Set(DefaultCsrfCookieName, csrf.Value)
+		_, claims, _, _, appErr := _establishSessionContext(ctx, mgr, &APIConfiguration{SessionRequired: true, RequireCsrf: true})
+		if appErr != nil || claims == nil {
+			t.Fatalf("Expected the prefixed session to be accepted, got %v", appErr)
+		}
+	})
+
+	t.Run("Inconsistent settings are rejected", func(t *testing.T) {
+		mgr.authorizationData.CookiePrefix = CookiePrefixHost
+		mgr.authorizationData.CookiePath = "/api"
+		defer func() { mgr.authorizationData.CookiePrefix, mgr.authorizationData.CookiePath = "", "" }()
+
+		ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+		ctx.Request = httptest.NewRequest(http.MethodPost, "/login", nil)
+		if err := SetSessionCookie(ctx, mgr, "user", &SessionClaims{Claims: map[string]string{"subject": "user-1"}}); err == nil {
+			t.Error("Expected the session cookie to be refused")
+		}
+	})
+}
+
+func TestValidateConfiguration(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name    string
+		setup   func(*mockSessionManager)
+		wantErr bool
+	}{
+		{"Defaults", func(*mockSessionManager) {}, false},
+		{"Host-only cookies", func(m *mockSessionManager) { m.authorizationData.HostOnlyCookies = true }, false},
+		{"Host-only cookies with a Domain", func(m *mockSessionManager) {
+			m.authorizationData.HostOnlyCookies = true
+			m.authorizationData.CookieDomain = "example.com"
+		}, true},
+		{"Host-only cookies with another prefix", func(m *mockSessionManager) {
+			m.authorizationData.HostOnlyCookies = true
+			m.authorizationData.CookiePrefix = CookiePrefixSecure
+		}, true},
+		{"Host-only cookies with a CSRF Path", func(m *mockSessionManager) {
+			m.authorizationData.HostOnlyCookies = true
+			m.csrfData.Path = "/api"
+		}, true},
+		{"Unknown CSRF SameSite", func(m *mockSessionManager) { m.csrfData.SameSite = "Sometimes" }, true},
+		{"Missing CSRF configuration", func(m *mockSessionManager) { m.csrfData = nil }, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mgr := newMockSessionManager(t)
+			tt.setup(mgr)
+			if err := ValidateConfiguration(mgr); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateConfiguration() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	t.Run("Host-only cookies are issued with the __Host- prefix", func(t *testing.T) {
+		mgr := newMockSessionManager(t)
+		mgr.authorizationData.HostOnlyCookies = true
+
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+		ctx.Request = httptest.NewRequest(http.MethodPost, "/login", nil)
+		if err := SetSessionCookie(ctx, mgr, "user", &SessionClaims{Claims: map[string]string{"subject": "user-1"}}); err != nil {
+			t.Fatalf("Failed to set session cookie: %v", err)
+		}
+		for _, cookie := range recorder.Result().Cookies() {
+			if !strings.HasPrefix(cookie.Name, string(CookiePrefixHost)) || cookie.Domain != "" || cookie.Path != "/" || !cookie.Secure {
+				t.Errorf("Expected a __Host- cookie, got %+v", cookie)
+			}
+		}
+	})
+
+	t.Run("NewRouteConstructor rejects invalid configurations", func(t *testing.T) {
+		mgr := newMockSessionManager(t)
+		mgr.authorizationData.HostOnlyCookies = true
+		mgr.authorizationData.CookiePath = "/api"
+		defer func() {
+			if recover() == nil {
+				t.Error("Expected NewRouteConstructor to panic")
+			}
+		}()
+		NewRouteConstructor(gin.New(), &testBaseRoute{}, mgr, nil)
+	})
+}
@@ -1,6 +1,9 @@
 package core
 
 import (
+	"context"
+	"time"
+
 	"github.com/gin-gonic/gin"
 	"github.com/grzegorzmaniak/gothic/errors"
 	"github.com/grzegorzmaniak/gothic/helpers"
@@ -19,20 +22,20 @@ func _verifyClaimsAndHandleSessionState(
 	header *SessionHeader,
 	group string,
 ) (*SessionHeader, *SessionClaims, string, *errors.AppError) {
-	isClaimsVerified, verifyErr := sessionManager.VerifyClaims(ctx, claims, sessionConfig)
+	isClaimsVerified, verifyErr := verifyClaimsWithExperiment(ctx, sessionManager, sessionConfig, claims)
 
 	if sessionConfig.SessionRequired {
 		if verifyErr != nil || !isClaimsVerified {
-			zap.L().Debug("Session required but claims verification failed", zap.Error(verifyErr), zap.Bool("isClaimsVerified", isClaimsVerified))
+			helpers.Logger(ctx).Debug("Session required but claims verification failed", zap.Error(verifyErr), zap.Bool("isClaimsVerified", isClaimsVerified))
 			return nil, nil, "", errors.NewUnauthorized("", verifyErr)
 		}
 		if claims == nil || !claims.HasSession {
-			zap.L().Error("Session required, but claims are nil or marked as no session after all checks", zap.Any("claims", claims))
+			helpers.Logger(ctx).Error("Session required, but claims are nil or marked as no session after all checks", zap.Any("claims", claims))
 			return nil, nil, "", errors.NewInternalServerError("", nil)
 		}
 	} else if claims != nil && (verifyErr != nil || !isClaimsVerified) {
 		// - If a session is not required, but an *invalid* one was presented, nullify it.
-		zap.L().Debug("Optional session presented but claims verification failed, nullifying session.", zap.Error(verifyErr))
+		helpers.Logger(ctx).Debug("Optional session presented but claims verification failed, nullifying session.", zap.Error(verifyErr))
 		header = nil
 		claims = nil
 		group = ""
@@ -51,26 +54,86 @@ func _establishSessionContext(
 	sessionConfig *APIConfiguration,
 ) (*SessionHeader, *SessionClaims, *CompleteCsrfToken, string, *errors.AppError) {
 
+	// - API keys stand in for a session on the routes that accept them
+	if sessionConfig.AllowAPIKeys {
+		claims, group, found, appErr := establishAPIKeySession(ctx, sessionManager, sessionConfig)
+		if appErr != nil {
+			return nil, nil, nil, "", appErr
+		}
+		if found {
+			return nil, claims, nil, group, nil
+		}
+	}
+
 	header, claims, group, tokenType, sessionErr := extractSession(ctx, sessionManager)
 
+	// - Rejections of presented sessions and CSRF failures are reported to the SessionEventListener
+	presentedClaims := claims
+	reject := func(appErr *errors.AppError) (*SessionHeader, *SessionClaims, *CompleteCsrfToken, string, *errors.AppError) {
+		if tokenType != SourceNone || appErr.Message == csrfFailureMessage {
+			publishSessionRejection(ctx, sessionManager, presentedClaims, appErr)
+		}
+		return nil, nil, nil, "", appErr
+	}
+
 	// - Check if a session is required and if the session extraction failed
 	if sessionErr != nil && sessionConfig.SessionRequired {
-		zap.L().Debug("Session required but extraction failed", zap.Error(sessionErr), zap.String("group_attempted", group))
-		return nil, nil, nil, "", errors.NewUnauthorized("", sessionErr)
+		helpers.Logger(ctx).Debug("Session required but extraction failed", zap.Error(sessionErr), zap.String("group_attempted", group))
+		return reject(errors.NewUnauthorized("", sessionErr))
+	}
+
+	// - Requests without a token may be authenticated by their TLS client certificate
+	if tokenType == SourceNone && sessionErr == nil && sessionConfig.AllowClientCertificates {
+		certificateClaims, certificateGroup, found, appErr := extractClientCertificateSession(ctx, sessionManager, sessionConfig)
+		if appErr != nil {
+			return nil, nil, nil, "", appErr
+		}
+		if found {
+			return nil, certificateClaims, nil, certificateGroup, nil
+		}
+	}
+
+	// - Remember-me tokens only resume sessions, they are never accepted as one
+	if claims != nil && claims.IsRememberMeToken() {
+		return reject(errors.NewUnauthorized("Remember-me tokens are not sessions", nil))
 	}
 
+	// - Silently resume a missing or expired cookie session from the remember-me token
+	if sessionErr == nil && tokenType != SourceHeader && (header == nil || header.IsExpired() || !header.IsValid()) {
+		resumedHeader, resumedClaims, resumedGroup, err := resumeRememberedSession(ctx, sessionManager)
+		if err != nil {
+			helpers.Logger(ctx).Debug("Failed to resume session from remember-me token", zap.Error(err))
+		} else if resumedClaims != nil {
+			header, claims, group, tokenType = resumedHeader, resumedClaims, resumedGroup, SourceCookie
+		}
+	}
+
+	// - Check the session is used by the client it was issued to, before it is refreshed
+	if bound, appErr := processSessionBinding(ctx, sessionManager, sessionConfig, claims); appErr != nil {
+		return reject(appErr)
+	} else if !bound {
+		header, claims, group = nil, nil, ""
+	}
+
+	var csrfToken *CompleteCsrfToken
+	var appErr *errors.AppError
 	switch tokenType {
 	case SourceHeader:
-		return establishBearerSession(ctx, sessionManager, sessionConfig, claims, header, group)
+		header, claims, csrfToken, group, appErr = establishBearerSession(ctx, sessionManager, sessionConfig, claims, header, group)
 
 	case SourceCookie,
 		SourceNone:
-		return establishCookieSession(ctx, sessionManager, sessionConfig, claims, header, group)
+		header, claims, csrfToken, group, appErr = establishCookieSession(ctx, sessionManager, sessionConfig, claims, header, group)
 
 	default:
-		zap.L().Debug("Session extraction failed", zap.Error(sessionErr), zap.String("group_attempted", group))
-		return nil, nil, nil, "", errors.NewUnauthorized("Invalid session source", sessionErr)
+		helpers.Logger(ctx).Debug("Session extraction failed", zap.Error(sessionErr), zap.String("group_attempted", group))
+		appErr = errors.NewUnauthorized("Invalid session source", sessionErr)
+	}
+
+	if appErr != nil {
+		return reject(appErr)
 	}
+	return header, claims, csrfToken, group, nil
 }
 
 func establishBearerSession(
@@ -83,7 +146,7 @@ func establishBearerSession(
 ) (*SessionHeader, *SessionClaims, *CompleteCsrfToken, string, *errors.AppError) {
 	// 1. Handle initial header validation (unique to both bearer and cookie)
 	if header != nil && (header.IsExpired() || !header.IsValid()) {
-		zap.L().Debug("Bearer session header is invalid or expired", zap.Any("header", header))
+		helpers.Logger(ctx).Debug("Bearer session header is invalid or expired", zap.Any("header", header))
 		if sessionConfig.SessionRequired {
 			return nil, nil, nil, "", errors.NewUnauthorized("", nil)
 		}
@@ -91,9 +154,14 @@ func establishBearerSession(
 	}
 
 	// 2. Handle bearer-specific revalidation logic (unique to bearer)
-	cacheKey, needsRefresh, err := BearerNeedsValidation(ctx, sessionManager, claims)
+	var cacheKey string
+	var needsRefresh bool
+	var err error
+	if claims != nil {
+		cacheKey, needsRefresh, err = BearerNeedsValidation(ctx, sessionManager, claims)
+	}
 	if err != nil {
-		zap.L().Debug("Error checking if bearer needs validation", zap.Error(err))
+		helpers.Logger(ctx).Debug("Error checking if bearer needs validation", zap.Error(err))
 		if sessionConfig.SessionRequired {
 			return nil, nil, nil, "", errors.NewInternalServerError("", err)
 		}
@@ -106,7 +174,7 @@ func establishBearerSession(
 			return nil, nil, nil, "", errors.NewUnauthorized("", reAuthErr)
 		}
 		if cacheErr := BearerSetCache(ctx, sessionManager, cacheKey, header); cacheErr != nil {
-			zap.L().Debug("Error setting bearer cache", zap.Error(cacheErr))
+			helpers.Logger(ctx).Debug("Error setting bearer cache", zap.Error(cacheErr))
 			return nil, nil, nil, "", errors.NewInternalServerError("", cacheErr)
 		}
 	}
@@ -129,29 +197,52 @@ func establishCookieSession(
 	header *SessionHeader,
 	group string,
 ) (*SessionHeader, *SessionClaims, *CompleteCsrfToken, string, *errors.AppError) {
-	// 1. Handle CSRF extraction (unique to cookie)
-	csrfToken, csrfErr := extractCsrf(ctx, sessionManager)
-	if csrfErr != nil {
-		csrfToken = nil
-		if sessionConfig.RequireCsrf {
-			zap.L().Debug("Required CSRF token is invalid", zap.Error(csrfErr))
-			return nil, nil, nil, "", errors.NewUnauthorized("CSRF token is invalid or expired", csrfErr)
+	// - Native clients have no cookie jar, their requests are validated by the header policy instead
+	if csrfModeFor(ctx, sessionManager.GetCsrfData()) == CsrfModeHeaderPolicy {
+		return establishHeaderPolicySession(ctx, sessionManager, sessionConfig, claims, header, group)
+	}
+
+	// 1. Handle CSRF extraction (unique to cookie), safe and exempt methods skip it
+	exempt := requestCsrfExempt(ctx, sessionManager.GetCsrfData(), sessionConfig)
+	var csrfToken *CompleteCsrfToken
+	if !exempt {
+		var csrfErr error
+		csrfToken, csrfErr = extractCsrf(ctx, sessionManager)
+		if csrfErr != nil {
+			csrfToken = nil
+			if sessionConfig.RequireCsrf {
+				helpers.Logger(ctx).Debug("Required CSRF token is invalid", zap.Error(csrfErr))
+				return nil, nil, nil, "", errors.NewUnauthorized(csrfFailureMessage, csrfErr)
+			}
 		}
 	}
 
 	// 2. Handle initial header validation (unique to both bearer and cookie)
 	if header != nil && (header.IsExpired() || !header.IsValid()) {
-		zap.L().Debug("Session header is invalid or expired", zap.Any("header", header))
+		helpers.Logger(ctx).Debug("Session header is invalid or expired", zap.Any("header", header))
 		if sessionConfig.SessionRequired {
 			return nil, nil, nil, "", errors.NewUnauthorized("", nil)
 		}
 		header, claims, group = nil, nil, ""
 	}
 
+	// - Cookies superseded by a refresh are accepted for the RefreshGracePeriod, without being refreshed again
+	superseded := false
+	if header != nil && claims != nil {
+		var stale bool
+		if superseded, stale = supersededSession(ctx, sessionManager, claims, header); stale {
+			helpers.Logger(ctx).Debug("Session cookie was superseded by a refresh")
+			if sessionConfig.SessionRequired {
+				return nil, nil, nil, "", errors.NewUnauthorized("", nil)
+			}
+			header, claims, group = nil, nil, ""
+		}
+	}
+
 	// 3. Handle cookie-specific session refresh (unique to cookie)
-	if header != nil && claims != nil && header.NeedsRefresh() {
-		if err := SetRefreshSessionCookie(ctx, sessionManager, claims, header); err != nil {
-			zap.L().Debug("Error attempting to refresh session cookie", zap.Error(err))
+	if header != nil && claims != nil && !superseded && header.NeedsRefresh() {
+		if err := refreshSessionCookie(ctx, sessionManager, claims, header); err != nil {
+			helpers.Logger(ctx).Debug("Error attempting to refresh session cookie", zap.Error(err))
 			return nil, nil, nil, "", errors.NewInternalServerError("Failed to refresh session", err)
 		}
 	}
@@ -163,23 +254,104 @@ func establishCookieSession(
 	}
 
 	// 5. Perform final CSRF validation.
-	if csrfToken == nil {
+	if exempt {
+		// Exempt requests keep a still fresh CSRF cookie, so read-only traffic doesn't churn it.
+		var err error
+		if csrfToken, err = keepCsrfCookie(ctx, sessionManager, claims); err != nil {
+			helpers.Logger(ctx).Debug("Error attempting to set CSRF cookie", zap.Error(err))
+			return nil, nil, nil, "", errors.NewInternalServerError("Failed to set CSRF cookie", err)
+		}
+	} else if csrfToken == nil {
 		// If the token is nil, and it got to here, it means that the CSRF token is not required, so we can skip validation,
 		// instead we will just issue them a new CSRF token that is automatically tied to their session.
 		csrfToken = &CompleteCsrfToken{}
 		if err := AutoSetCsrfCookie(ctx, sessionManager, claims); err != nil {
-			zap.L().Debug("Error attempting to set anonymous CSRF cookie", zap.Error(err))
+			helpers.Logger(ctx).Debug("Error attempting to set anonymous CSRF cookie", zap.Error(err))
 			return nil, nil, nil, "", errors.NewInternalServerError("Failed to set CSRF cookie", err)
 		}
 	} else if err := validateCsrf(ctx, sessionManager, claims, csrfToken); err != nil {
 		// This means that the user provided a CSRF token, but it is invalid or expired.
-		zap.L().Debug("CSRF validation failed", zap.Error(err))
+		helpers.Logger(ctx).Debug("CSRF validation failed", zap.Error(err))
 		if sessionConfig.RequireCsrf {
-			return nil, nil, nil, "", errors.NewUnauthorized("CSRF token is invalid or expired", err)
+			return nil, nil, nil, "", errors.NewUnauthorized(csrfFailureMessage, err)
+		}
+	}
+
+	// 6. Spend the nonce of state changing requests, the next one is issued even when it fails so the client can retry
+	if csrfData := sessionManager.GetCsrfData(); csrfData.Nonces {
+		var nonceErr error
+		if !exempt && sessionConfig.RequireCsrf {
+			nonceErr = consumeCsrfNonce(ctx, sessionManager, csrfData, claims)
+		}
+		if _, err := issueCsrfNonce(ctx, sessionManager, csrfData, claims); err != nil {
+			helpers.Logger(ctx).Debug("Error attempting to issue CSRF nonce", zap.Error(err))
+			return nil, nil, nil, "", errors.NewInternalServerError("Failed to issue CSRF nonce", err)
+		}
+		if nonceErr != nil {
+			helpers.Logger(ctx).Debug("CSRF nonce validation failed", zap.Error(nonceErr))
+			return nil, nil, nil, "", errors.NewUnauthorized(csrfFailureMessage, nonceErr)
+		}
+	}
+
+	// 7. Return the final state
+	return header, claims, csrfToken, group, nil
+}
+
+// establishHeaderPolicySession mirrors establishCookieSession for clients classified as CsrfModeHeaderPolicy,
+// no CSRF cookie is read or issued.
+func establishHeaderPolicySession(
+	ctx *gin.Context,
+	sessionManager SessionManager,
+	sessionConfig *APIConfiguration,
+	claims *SessionClaims,
+	header *SessionHeader,
+	group string,
+) (*SessionHeader, *SessionClaims, *CompleteCsrfToken, string, *errors.AppError) {
+	// 1. Handle initial header validation
+	if header != nil && (header.IsExpired() || !header.IsValid()) {
+		helpers.Logger(ctx).Debug("Session header is invalid or expired", zap.Any("header", header))
+		if sessionConfig.SessionRequired {
+			return nil, nil, nil, "", errors.NewUnauthorized("", nil)
+		}
+		header, claims, group = nil, nil, ""
+	}
+
+	// - Cookies superseded by a refresh are accepted for the RefreshGracePeriod, without being refreshed again
+	superseded := false
+	if header != nil && claims != nil {
+		var stale bool
+		if superseded, stale = supersededSession(ctx, sessionManager, claims, header); stale {
+			helpers.Logger(ctx).Debug("Session cookie was superseded by a refresh")
+			if sessionConfig.SessionRequired {
+				return nil, nil, nil, "", errors.NewUnauthorized("", nil)
+			}
+			header, claims, group = nil, nil, ""
+		}
+	}
+
+	// 2. Handle session refresh
+	if header != nil && claims != nil && !superseded && header.NeedsRefresh() {
+		if err := refreshSessionCookie(ctx, sessionManager, claims, header); err != nil {
+			helpers.Logger(ctx).Debug("Error attempting to refresh session cookie", zap.Error(err))
+			return nil, nil, nil, "", errors.NewInternalServerError("Failed to refresh session", err)
 		}
 	}
 
-	// 6. Return the final state
+	// 3. Verify claims and handle session state
+	header, claims, group, appErr := _verifyClaimsAndHandleSessionState(ctx, sessionManager, sessionConfig, claims, header, group)
+	if appErr != nil {
+		return nil, nil, nil, "", appErr
+	}
+
+	// 4. Validate the request against the header policy
+	csrfData := sessionManager.GetCsrfData()
+	requireCsrf := sessionConfig.RequireCsrf && !requestCsrfExempt(ctx, csrfData, sessionConfig)
+	csrfToken, err := establishHeaderPolicyCsrf(ctx, csrfData, claims, requireCsrf)
+	if err != nil {
+		helpers.Logger(ctx).Debug("CSRF header policy validation failed", zap.Error(err))
+		return nil, nil, nil, "", errors.NewUnauthorized(csrfFailureMessage, err)
+	}
+
 	return header, claims, csrfToken, group, nil
 }
 
@@ -197,11 +369,11 @@ func validateCsrf(
 	// - Get the x-CSRF token from the header
 	if !csrfToken.IsValid() || csrfToken.IsExpired() {
 		if err := AutoSetCsrfCookie(ctx, sessionManager, claims); err != nil {
-			zap.L().Debug("Error attempting to set CSRF cookie", zap.Error(err))
+			helpers.Logger(ctx).Debug("Error attempting to set CSRF cookie", zap.Error(err))
 			return errors.NewInternalServerError("Failed to set CSRF cookie", err)
 		}
 
-		return errors.NewUnauthorized("CSRF token is invalid or expired", nil)
+		return errors.NewUnauthorized(csrfFailureMessage, nil)
 	}
 
 	// - If the CSRF token is not tied, but the user holds a session, it means that they are using a token
@@ -209,29 +381,29 @@ func validateCsrf(
 	// use the CSRF token tied to their session.
 	if !csrfToken.Tied && claims != nil && claims.HasSession {
 		if err := AutoSetCsrfCookie(ctx, sessionManager, claims); err != nil {
-			zap.L().Debug("Error attempting to set CSRF cookie", zap.Error(err))
+			helpers.Logger(ctx).Debug("Error attempting to set CSRF cookie", zap.Error(err))
 			return errors.NewInternalServerError("Failed to set CSRF cookie", err)
 		}
 
-		return errors.NewUnauthorized("CSRF token is invalid or expired", nil)
+		return errors.NewUnauthorized(csrfFailureMessage, nil)
 	}
 
 	if claims != nil && csrfToken.Tied {
 		csrfTie, ok := claims.GetClaim(CsrfTokenTie)
 		if csrfTie != csrfToken.Tie || !ok {
 			if err := AutoSetCsrfCookie(ctx, sessionManager, claims); err != nil {
-				zap.L().Debug("Error attempting to set CSRF cookie", zap.Error(err))
+				helpers.Logger(ctx).Debug("Error attempting to set CSRF cookie", zap.Error(err))
 				return errors.NewInternalServerError("Failed to set CSRF cookie", err)
 			}
 
-			return errors.NewUnauthorized("CSRF token is invalid or expired", nil)
+			return errors.NewUnauthorized(csrfFailureMessage, nil)
 		}
 	}
 
 	// - Csrf need refresh
 	if csrfToken.NeedsRefresh() {
 		if err := AutoSetCsrfCookie(ctx, sessionManager, claims); err != nil {
-			zap.L().Debug("Error attempting to set CSRF cookie", zap.Error(err))
+			helpers.Logger(ctx).Debug("Error attempting to set CSRF cookie", zap.Error(err))
 			return errors.NewInternalServerError("Failed to set CSRF cookie", err)
 		}
 	}
@@ -243,50 +415,93 @@ func validateCsrf(
 // It returns the validated input, subject, subject-fetched status, or an AppError.
 func prepareHandlerData[InputType any](
 	ctx *gin.Context,
+	sessionConfig *APIConfiguration,
 	validationEngine *validation.Engine,
 ) (*InputType, *errors.AppError) {
 	if validationEngine == nil {
 		validationEngine = validation.NewEngine(nil)
 	}
 
+	// - Bound the body before binding, so a single request can not exhaust memory
+	restoreBody, tooLarge := limitRequestBody(ctx, sessionConfig)
+	defer restoreBody()
+	if tooLarge {
+		return nil, errors.NewPayloadTooLarge("", nil)
+	}
+
 	// - Input validation
 	input, inputErr := validation.InputData[InputType](ctx, validationEngine)
 	if inputErr != nil {
-		zap.L().Debug("Error validating input data", zap.Error(inputErr), zap.Any("raw_input_attempt", input)) // 'input' might be partially populated or nil on error
-		return nil, inputErr
+		helpers.Logger(ctx).Debug("Error validating input data", zap.Error(inputErr), zap.Any("raw_input_attempt", input)) // 'input' might be partially populated or nil on error
+		return nil, bodyLimitAppError(inputErr)
 	}
 
 	return input, nil
 }
 
-// processAndSendHandlerOutput validates the handler's output and sends the response.
-// Returns an AppError if output processing fails.
-func processAndSendHandlerOutput[OutputType any](
+// bodyLimitAppError replaces binding errors caused by the body limits with a 413 or 408.
+func bodyLimitAppError(appErr *errors.AppError) *errors.AppError {
+	switch {
+	case isBodyTooLarge(appErr.Err):
+		return errors.NewPayloadTooLarge("", appErr.Err)
+	case isBodyReadTimeout(appErr.Err):
+		return errors.NewRequestTimeout("", appErr.Err)
+	default:
+		return appErr
+	}
+}
+
+// routeResponse is a validated response, ready to be written to the client.
+type routeResponse struct {
+	Headers map[string]string
+	Body    interface{}
+}
+
+// processHandlerOutput validates the handler's output and prepares the response.
+// Returns a nil response if the route handles its response manually, or an AppError if output processing fails.
+func processHandlerOutput[OutputType any](
 	ctx *gin.Context,
 	output *OutputType,
 	sessionConfig *APIConfiguration,
+	sessionManager SessionManager,
 	validationEngine *validation.Engine,
-) *errors.AppError {
+	claims *SessionClaims,
+) (*routeResponse, *errors.AppError) {
 	if validationEngine == nil {
 		validationEngine = validation.NewEngine(nil)
 	}
 
 	// - Processing stops here, handler is responsible for response
 	if sessionConfig.ManualResponse {
-		zap.L().Debug("Response handling is manual for this route", zap.Any("output_given_by_handler", output))
-		return nil
+		helpers.Logger(ctx).Debug("Response handling is manual for this route", zap.Any("output_given_by_handler", output))
+		return nil, nil
 	}
 
-	// - Output validation
-	responseHeaders, responseBody, outputValErr := validation.OutputData(validationEngine, output)
+	// - Output validation, fields tagged with `redact` the session lacks permission for are redacted
+	permissions := outputPermissionChecker(ctx, sessionManager, claims)
+	responseHeaders, responseBody, outputValErr := validation.OutputDataWithPermissions(validationEngine, output, permissions)
 	if outputValErr != nil {
-		zap.L().Debug("Error validating output data", zap.Error(outputValErr), zap.Any("raw_output_from_handler", output))
-		return outputValErr
+		helpers.Logger(ctx).Debug("Error validating output data", zap.Error(outputValErr), zap.Any("raw_output_from_handler", output))
+		return nil, outputValErr
 	}
 
-	// - Success response
-	helpers.SuccessResponse(ctx, 200, responseBody, responseHeaders)
-	return nil
+	// - Paginated outputs link to their next page
+	if page, ok := any(responseBody).(helpers.Paginated); ok && responseBody != nil {
+		for key, value := range helpers.PaginationHeaders(ctx, page) {
+			responseHeaders[key] = value
+		}
+	}
+
+	// - Money / date fields tagged with `localize` are formatted for the subject's locale
+	return &routeResponse{Headers: responseHeaders, Body: localizeOutput(responseBody, claims, sessionConfig.Localize)}, nil
+}
+
+// sendRouteResponse writes a validated response, a nil response means the handler has already responded.
+func sendRouteResponse(ctx *gin.Context, response *routeResponse) {
+	if response == nil {
+		return
+	}
+	helpers.SuccessResponse(ctx, 200, response.Body, response.Headers)
 }
 
 // processRbac checks if RBAC is enabled and validates permissions/roles.
@@ -296,7 +511,12 @@ func processRbac(
 	sessionConfig *APIConfiguration,
 	claims *SessionClaims,
 ) *errors.AppError {
-	if (sessionConfig.Roles == nil && sessionConfig.Permissions == nil) || claims == nil {
+	if tenantErr := processTenant(ctx, sessionConfig, claims); tenantErr != nil {
+		return tenantErr
+	}
+
+	hasExperiment := sessionConfig.Experiment != nil && sessionConfig.Experiment.AccessRequirements != nil
+	if (sessionConfig.Roles == nil && sessionConfig.Permissions == nil && len(sessionConfig.NamedPermissions) == 0 && !hasExperiment) || claims == nil {
 		return nil
 	}
 
@@ -305,41 +525,136 @@ func processRbac(
 		return errors.NewInternalServerError("RBAC manager is not set", nil)
 	}
 
+	namedPermissions, err := sessionConfig.GetFlatNamedPermissions()
+	if err != nil {
+		helpers.Logger(ctx).Debug("Invalid named permissions on route", zap.Error(err))
+		return errors.NewInternalServerError("Invalid named permissions", err)
+	}
+
+	requirements := rbac.AccessRequirements{
+		Permissions:      sessionConfig.GetFlatPermissions(),
+		NamedPermissions: namedPermissions,
+		Roles:            sessionConfig.GetFlatRoles(),
+		Policy:           sessionConfig.RbacPolicy,
+	}
+
+	// - API keys are checked against their own grants, not the ones of the subject they act for
+	if key, ok := APIKeyFromContext(ctx); ok {
+		keyOk, err := checkAPIKeyAccess(ctx, sessionManager, rbacManager, key, claims, requirements)
+		if err != nil {
+			helpers.Logger(ctx).Debug("Error checking API key permissions", zap.Error(err))
+			return errors.NewInternalServerError("Failed to check permissions", err)
+		}
+		if !keyOk && !sessionConfig.RbacDryRun {
+			helpers.Logger(ctx).Debug("RBAC permissions check failed for API key", zap.String("api_key", key.ID))
+			publishSessionEvent(ctx, sessionManager, SessionEventRbacDenied, claims, "insufficient permissions")
+			subjectIdentifier, _ := sessionManager.GetSubjectIdentifier(claims)
+			return deniedError(ctx, rbacManager, sessionConfig, &DecisionContext{
+				Subject: subjectIdentifier, Claims: claims, APIKey: key, Requirements: requirements,
+			}, "")
+		}
+		return nil
+	}
+
 	rbacCacheId, ok := claims.GetClaim(RbacCacheIdentifier)
 	if !ok || len(rbacCacheId) != helpers.AESKeySize32 {
-		zap.L().Debug("RBAC cache ID is not set or invalid", zap.Any("rbacCacheId", rbacCacheId))
+		helpers.Logger(ctx).Debug("RBAC cache ID is not set or invalid", zap.Any("rbacCacheId", rbacCacheId))
 		return errors.NewInternalServerError("RBAC cache ID is not set or invalid", nil)
 	}
 
 	// - Get the subject identifier from the claims
 	subjectIdentifier, err := sessionManager.GetSubjectIdentifier(claims)
 	if err != nil {
-		zap.L().Debug("Error getting subject identifier", zap.Error(err))
+		helpers.Logger(ctx).Debug("Error getting subject identifier", zap.Error(err))
 		return errors.NewInternalServerError("Failed to get subject identifier", err)
 	}
 
-	rbacOk, err := rbac.CheckPermissions(
-		ctx,
-		rbacManager,
-		subjectIdentifier,
-		rbacCacheId,
-		sessionConfig.GetFlatPermissions(),
-		sessionConfig.GetFlatRoles(),
-		sessionConfig.RbacPolicy,
-	)
+	rbacOk, err := checkAccessWithExperiment(rbacContext(ctx), rbacManager, sessionManager, sessionConfig, claims, subjectIdentifier, rbacCacheId, requirements)
 	if err != nil {
-		zap.L().Debug("Error checking permissions", zap.Error(err))
+		helpers.Logger(ctx).Debug("Error checking permissions", zap.Error(err))
 		return errors.NewInternalServerError("Failed to check permissions", err)
 	}
 
+	if !rbacOk && sessionConfig.RbacDryRun {
+		logDryRunDenial(ctx, rbacManager, subjectIdentifier, rbacCacheId, requirements)
+		return nil
+	}
+
 	if !rbacOk {
-		zap.L().Debug("RBAC permissions check failed", zap.Any("rbacCacheId", rbacCacheId))
-		insufficientPermsErr := errors.NewUnauthorized("Insufficient permissions", nil)
-		insufficientPermsErr.Details = map[string]interface{}{
-			"permissions": sessionConfig.Permissions,
-			"roles":       sessionConfig.Roles,
-		}
-		return insufficientPermsErr
+		helpers.Logger(ctx).Debug("RBAC permissions check failed", zap.Any("rbacCacheId", rbacCacheId))
+		publishSessionEvent(ctx, sessionManager, SessionEventRbacDenied, claims, "insufficient permissions")
+		return deniedError(ctx, rbacManager, sessionConfig, &DecisionContext{
+			Subject: subjectIdentifier, Claims: claims, Requirements: requirements,
+		}, rbacCacheId)
+	}
+
+	return nil
+}
+
+// newInsufficientPermissionsError lists the route's requirements in the details of the denial.
+func newInsufficientPermissionsError(sessionConfig *APIConfiguration) *errors.AppError {
+	insufficientPermsErr := errors.NewUnauthorized("Insufficient permissions", nil)
+	insufficientPermsErr.Details = map[string]interface{}{
+		"permissions":       sessionConfig.Permissions,
+		"named_permissions": sessionConfig.NamedPermissions,
+		"roles":             sessionConfig.Roles,
+	}
+	return insufficientPermsErr
+}
+
+// logDryRunDenial logs a denial of a RbacDryRun route with the trace of the decision, the request is let through.
+func logDryRunDenial(ctx *gin.Context, rbacManager rbac.Manager, subjectIdentifier string, rbacCacheId string, requirements rbac.AccessRequirements) {
+	decision, err := rbac.ExplainAccess(rbacContext(ctx), rbacManager, subjectIdentifier, rbacCacheId, requirements)
+	if err != nil {
+		helpers.Logger(ctx).Warn("RBAC dry run: request would have been denied",
+			zap.String("subject", subjectIdentifier),
+			zap.String("path", ctx.FullPath()),
+			zap.Error(err),
+		)
+		return
+	}
+	helpers.Logger(ctx).Warn("RBAC dry run: request would have been denied",
+		zap.String("subject", subjectIdentifier),
+		zap.String("path", ctx.FullPath()),
+		zap.Any("decision", decision),
+	)
+}
+
+// processPolicy evaluates the attribute based policies (if any) configured on the route.
+// It is run after the RBAC check and input validation, as attribute policies usually need the resource.
+func processPolicy(
+	ctx *gin.Context,
+	sessionConfig *APIConfiguration,
+	claims *SessionClaims,
+	input interface{},
+) *errors.AppError {
+	if sessionConfig.PolicyFunc == nil && sessionConfig.AttributeEvaluator == nil {
+		return nil
+	}
+
+	var attributes rbac.Attributes
+	if claims != nil {
+		attributes = claims.Claims
+	}
+
+	evaluators := []rbac.AttributeEvaluator{sessionConfig.AttributeEvaluator}
+	if sessionConfig.PolicyFunc != nil {
+		evaluators = append(evaluators, rbac.AttributeEvaluatorFunc(func(_ context.Context, _ rbac.Attributes, resource interface{}) (*rbac.AttributeDecision, error) {
+			return sessionConfig.PolicyFunc(ctx, claims, resource)
+		}))
+	}
+
+	decision, err := rbac.EvaluateAttributes(ctx, attributes, input, evaluators...)
+	if err != nil {
+		helpers.Logger(ctx).Debug("Error evaluating attribute policy", zap.Error(err))
+		return errors.NewInternalServerError("Failed to evaluate access policy", err)
+	}
+
+	if !decision.Allowed {
+		helpers.Logger(ctx).Debug("Attribute policy denied access", zap.Any("reasons", decision.Reasons))
+		return errors.NewUnauthorized("Access denied by policy", nil, map[string]interface{}{
+			"reasons": decision.Reasons,
+		})
 	}
 
 	return nil
@@ -359,49 +674,166 @@ func ExecuteRoute[InputType any, OutputType any, BaseRoute helpers.BaseRouteComp
 		validationEngine = validation.NewEngine(nil)
 	}
 
+	started := time.Now()
+	helpers.EnsureRequestID(ctx)
+
+	// - Panics anywhere in the lifecycle get the structured 500, including the ones of PreSession hooks and of
+	//   the session manager while the session is established. The audit record is written after the response.
+	var claims *SessionClaims
+	var recordAudit func()
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			respondToPanic(ctx, sessionManager, claims, recovered)
+		}
+		if recordAudit != nil {
+			recordAudit()
+		}
+	}()
+	helpers.SetResponseFormat(ctx, sessionConfig.ErrorFormat)
+	helpers.SetResponseEncoding(ctx, sessionConfig.ResponseEncoding)
+	if appErr := checkMaintenance(ctx, sessionConfig); appErr != nil {
+		helpers.ErrorResponse(ctx, appErr)
+		return
+	}
+
+	hooks := &HookContext{Context: ctx, Config: sessionConfig, SessionManager: sessionManager, Values: &Values{}}
+	if hookErr := hooks.run(HookPreSession); hookErr != nil {
+		helpers.ErrorResponse(ctx, hookErr)
+		return
+	}
+
 	// - Stage 1: Establish Session Context
-	header, claims, csrfToken, group, appErr := _establishSessionContext(ctx, sessionManager, sessionConfig)
+	header, establishedClaims, csrfToken, group, appErr := _establishSessionContext(ctx, sessionManager, sessionConfig)
+	claims = establishedClaims
+	recordAudit = func() { recordRouteAudit(ctx, sessionManager, sessionConfig, establishedClaims, started) }
 	if appErr != nil {
-		helpers.ErrorResponse(ctx, appErr)
+		helpers.ErrorResponse(ctx, hiddenByFeatureFlag(ctx, sessionManager, sessionConfig, appErr))
+		return
+	}
+
+	hooks.established(header, claims, group)
+	if hookErr := hooks.run(HookPostSession); hookErr != nil {
+		helpers.ErrorResponse(ctx, hookErr)
+		return
+	}
+	claims = hooks.Claims
+
+	// - Feature flags, before anything tells the route exists
+	if flagErr := processFeatureFlag(ctx, sessionManager, sessionConfig, claims, group); flagErr != nil {
+		helpers.ErrorResponse(ctx, flagErr)
+		return
+	}
+
+	// - Risk checks, e.g., impossible travel
+	if riskErr := processTravelCheck(ctx, sessionManager, sessionConfig, claims); riskErr != nil {
+		helpers.ErrorResponse(ctx, riskErr)
+		return
+	}
+
+	// - Step-up authentication
+	if levelErr := processAuthLevel(ctx, sessionConfig, claims); levelErr != nil {
+		helpers.ErrorResponse(ctx, levelErr)
 		return
 	}
 
 	// - Rbac
 	if rbacErr := processRbac(ctx, sessionManager, sessionConfig, claims); rbacErr != nil {
-		zap.L().Debug("RBAC processing failed", zap.Error(rbacErr))
+		helpers.Logger(ctx).Debug("RBAC processing failed", zap.Error(rbacErr))
 		helpers.ErrorResponse(ctx, rbacErr)
 		return
 	}
 
 	// - Stage 2: Prepare Handler Input and Subject Data
-	input, appErr := prepareHandlerData[InputType](ctx, validationEngine)
+	input, appErr := prepareHandlerData[InputType](ctx, sessionConfig, validationEngine)
 	if appErr != nil {
 		helpers.ErrorResponse(ctx, appErr)
 		return
 	}
 
-	// - Stage 3: Call the specific business logic handler
-	output, handlerAppErr := handlerFunc(input, &Handler[BaseRoute]{
-		BaseRoute:      baseRoute,
-		Context:        ctx,
-		SessionHeader:  header,
-		Claims:         claims,
-		HasSession:     claims != nil && claims.HasSession,
-		SessionManager: sessionManager,
-		SessionGroup:   group,
-		CsrfToken:      csrfToken,
-	})
+	// - Attribute based policies (evaluated after RBAC, as they need the validated input)
+	if policyErr := processPolicy(ctx, sessionConfig, claims, input); policyErr != nil {
+		helpers.ErrorResponse(ctx, policyErr)
+		return
+	}
 
-	if handlerAppErr != nil {
-		zap.L().Debug("Error returned from route handler", zap.Error(handlerAppErr), zap.Any("input", input))
-		helpers.ErrorResponse(ctx, handlerAppErr)
+	hooks.Input = input
+	if hookErr := hooks.run(HookPreHandler); hookErr != nil {
+		helpers.ErrorResponse(ctx, hookErr)
 		return
 	}
 
-	// - Stage 4: Process Handler Output and Send Response
-	if appErr = processAndSendHandlerOutput[OutputType](ctx, output, sessionConfig, validationEngine); appErr != nil {
-		helpers.ErrorResponse(ctx, appErr)
+	// - Stage 3: Call the specific business logic handler, any sub-tasks it started are
+	// cancelled once the response has been written.
+	processReplicaHint(ctx, sessionManager, sessionConfig, claims)
+	timeout := startHandlerTimeout(ctx, sessionConfig)
+	defer timeout.stop()
+	handlerData := newHandler(ctx, baseRoute, sessionConfig, sessionManager, header, claims, csrfToken, group)
+	handlerData.Values = hooks.Values
+	defer handlerData.closeTasks()
+
+	handle := func() (*routeResponse, *errors.AppError) {
+		output, handlerAppErr := handlerFunc(input, handlerData)
+		handlerAppErr = hooks.handled(output, handlerAppErr)
+		if handlerAppErr != nil {
+			helpers.Logger(ctx).Debug("Error returned from route handler", zap.Error(handlerAppErr), zap.Any("input", input))
+			return nil, handlerAppErr
+		}
+
+		// - Stage 4: Process Handler Output
+		return processHandlerOutput[OutputType](ctx, output, sessionConfig, sessionManager, validationEngine, claims)
+	}
+
+	response, appErr := executeIdempotent(ctx, sessionManager, sessionConfig, claims, input, func() (*routeResponse, *errors.AppError) {
+		return executeCached(ctx, sessionManager, sessionConfig, claims, group, func() (*routeResponse, *errors.AppError) {
+			return executeCoalesced(ctx, sessionManager, sessionConfig, claims, group, func() (*routeResponse, *errors.AppError) {
+				return executeLimited(ctx, sessionConfig, func() (*routeResponse, *errors.AppError) {
+					return timeout.run(ctx, recoverHandler(ctx, sessionManager, claims, handle))
+				})
+			})
+		})
+	})
+	invalidateCachedRoutes(ctx, sessionManager, sessionConfig, appErr)
+	if appErr != nil {
+		// - A started response is never appended to, e.g., a timed out handler already had its 504 sent
+		if !ctx.Writer.Written() {
+			helpers.ErrorResponse(ctx, appErr)
+		}
+		return
 	}
+
+	processReplicaWrite(ctx, sessionManager, sessionConfig, claims)
+	sendRouteResponse(ctx, response)
+}
+
+// ExecuteRouteE runs the same lifecycle as ExecuteRoute but returns the handler's output or the error instead of
+// sending them, e.g., to call a route from another handler or test it without decoding a response. Headers set
+// along the way, such as refreshed session cookies, stay on the context. Responses are never cached, coalesced
+// or replayed for an Idempotency-Key, and a ManualResponse handler still writes its own.
+func ExecuteRouteE[InputType any, OutputType any, BaseRoute helpers.BaseRouteComponents](
+	ctx *gin.Context,
+	baseRoute BaseRoute,
+	sessionConfig *APIConfiguration,
+	sessionManager SessionManager,
+	validationEngine *validation.Engine,
+	handlerFunc func(input *InputType, data *Handler[BaseRoute]) (*OutputType, *errors.AppError),
+) (*OutputType, *errors.AppError) {
+	captured := helpers.ResponseCaptured(ctx)
+	helpers.SetResponseCapture(ctx, true)
+	defer helpers.SetResponseCapture(ctx, captured)
+	ctx.Set(helpers.ResponseErrorContextKey, (*errors.AppError)(nil))
+
+	// - The handler's goroutine is waited for before ExecuteRoute returns, even past a HandlerTimeout
+	var output *OutputType
+	ExecuteRoute(ctx, baseRoute, sessionConfig, sessionManager, validationEngine, func(input *InputType, data *Handler[BaseRoute]) (*OutputType, *errors.AppError) {
+		var appErr *errors.AppError
+		output, appErr = handlerFunc(input, data)
+		return output, appErr
+	})
+
+	if appErr := helpers.GetResponseError(ctx); appErr != nil {
+		return nil, appErr
+	}
+	return output, nil
 }
 
 // ExecuteDynamicRoute is a light-weight variant for dynamically defined routes.
@@ -423,60 +855,158 @@ func ExecuteDynamicRoute[BaseRoute helpers.BaseRouteComponents](
 		validationEngine = validation.NewEngine(nil)
 	}
 
+	started := time.Now()
+	helpers.EnsureRequestID(ctx)
+
+	// - Panics anywhere in the lifecycle get the structured 500, including the ones of PreSession hooks and of
+	//   the session manager while the session is established. The audit record is written after the response.
+	var claims *SessionClaims
+	var recordAudit func()
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			respondToPanic(ctx, sessionManager, claims, recovered)
+		}
+		if recordAudit != nil {
+			recordAudit()
+		}
+	}()
+	helpers.SetResponseFormat(ctx, sessionConfig.ErrorFormat)
+	helpers.SetResponseEncoding(ctx, sessionConfig.ResponseEncoding)
+	if appErr := checkMaintenance(ctx, sessionConfig); appErr != nil {
+		helpers.ErrorResponse(ctx, appErr)
+		return
+	}
+
+	hooks := &HookContext{Context: ctx, Config: sessionConfig, SessionManager: sessionManager, Values: &Values{}}
+	if hookErr := hooks.run(HookPreSession); hookErr != nil {
+		helpers.ErrorResponse(ctx, hookErr)
+		return
+	}
+
 	// - Stage 1: Establish Session Context
-	header, claims, csrfToken, group, appErr := _establishSessionContext(ctx, sessionManager, sessionConfig)
+	header, establishedClaims, csrfToken, group, appErr := _establishSessionContext(ctx, sessionManager, sessionConfig)
+	claims = establishedClaims
+	recordAudit = func() { recordRouteAudit(ctx, sessionManager, sessionConfig, establishedClaims, started) }
 	if appErr != nil {
-		helpers.ErrorResponse(ctx, appErr)
+		helpers.ErrorResponse(ctx, hiddenByFeatureFlag(ctx, sessionManager, sessionConfig, appErr))
+		return
+	}
+
+	hooks.established(header, claims, group)
+	if hookErr := hooks.run(HookPostSession); hookErr != nil {
+		helpers.ErrorResponse(ctx, hookErr)
+		return
+	}
+	claims = hooks.Claims
+
+	// - Feature flags, before anything tells the route exists
+	if flagErr := processFeatureFlag(ctx, sessionManager, sessionConfig, claims, group); flagErr != nil {
+		helpers.ErrorResponse(ctx, flagErr)
+		return
+	}
+
+	// - Risk checks, e.g., impossible travel
+	if riskErr := processTravelCheck(ctx, sessionManager, sessionConfig, claims); riskErr != nil {
+		helpers.ErrorResponse(ctx, riskErr)
+		return
+	}
+
+	// - Step-up authentication
+	if levelErr := processAuthLevel(ctx, sessionConfig, claims); levelErr != nil {
+		helpers.ErrorResponse(ctx, levelErr)
 		return
 	}
 
 	// - Rbac
 	if rbacErr := processRbac(ctx, sessionManager, sessionConfig, claims); rbacErr != nil {
-		zap.L().Debug("RBAC processing failed", zap.Error(rbacErr))
+		helpers.Logger(ctx).Debug("RBAC processing failed", zap.Error(rbacErr))
 		helpers.ErrorResponse(ctx, rbacErr)
 		return
 	}
 
-	// - Stage 2: Prepare Dynamic Handler Input
-	input, appErr := validation.DynamicInputData(ctx, validationEngine, inputCacheId, inputFieldRules)
+	// - Stage 2: Prepare Dynamic Handler Input, bounding the body first
+	restoreBody, tooLarge := limitRequestBody(ctx, sessionConfig)
+	if tooLarge {
+		restoreBody()
+		helpers.ErrorResponse(ctx, errors.NewPayloadTooLarge("", nil))
+		return
+	}
+	bindDynamicInput := validation.DynamicInputData
+	if sessionConfig.PartialInput {
+		bindDynamicInput = validation.DynamicPartialInputData
+	}
+	input, appErr := bindDynamicInput(ctx, validationEngine, inputCacheId, inputFieldRules)
+	restoreBody()
 	if appErr != nil {
-		helpers.ErrorResponse(ctx, appErr)
+		helpers.ErrorResponse(ctx, bodyLimitAppError(appErr))
 		return
 	}
 
-	// - Stage 3: Call the specific business logic handler
-	output, handlerAppErr := handlerFunc(input, &Handler[BaseRoute]{
-		BaseRoute:      baseRoute,
-		Context:        ctx,
-		SessionHeader:  header,
-		Claims:         claims,
-		HasSession:     claims != nil && claims.HasSession,
-		SessionManager: sessionManager,
-		SessionGroup:   group,
-		CsrfToken:      csrfToken,
-	})
-	if handlerAppErr != nil {
-		zap.L().Debug("Error returned from dynamic route handler", zap.Error(handlerAppErr), zap.Any("input", input))
-		helpers.ErrorResponse(ctx, handlerAppErr)
+	// - Attribute based policies (evaluated after RBAC, as they need the validated input)
+	if policyErr := processPolicy(ctx, sessionConfig, claims, input); policyErr != nil {
+		helpers.ErrorResponse(ctx, policyErr)
 		return
 	}
 
-	// - Stage 4: Process Handler Output and Send Response
-	if sessionConfig.ManualResponse {
-		zap.L().Debug("Response handling is manual for this dynamic route", zap.Any("output_given_by_handler", output))
+	hooks.Input = input
+	if hookErr := hooks.run(HookPreHandler); hookErr != nil {
+		helpers.ErrorResponse(ctx, hookErr)
 		return
 	}
 
-	if outputFieldRules == nil {
-		helpers.ErrorResponse(ctx, errors.NewInternalServerError("Output rules must be provided for dynamic routes", nil))
-		return
+	// - Stage 3: Call the specific business logic handler, any sub-tasks it started are
+	// cancelled once the response has been written.
+	processReplicaHint(ctx, sessionManager, sessionConfig, claims)
+	timeout := startHandlerTimeout(ctx, sessionConfig)
+	defer timeout.stop()
+	handlerData := newHandler(ctx, baseRoute, sessionConfig, sessionManager, header, claims, csrfToken, group)
+	handlerData.Values = hooks.Values
+	defer handlerData.closeTasks()
+
+	handle := func() (*routeResponse, *errors.AppError) {
+		output, handlerAppErr := handlerFunc(input, handlerData)
+		handlerAppErr = hooks.handled(output, handlerAppErr)
+		if handlerAppErr != nil {
+			helpers.Logger(ctx).Debug("Error returned from dynamic route handler", zap.Error(handlerAppErr), zap.Any("input", input))
+			return nil, handlerAppErr
+		}
+
+		// - Stage 4: Process Handler Output
+		if sessionConfig.ManualResponse {
+			helpers.Logger(ctx).Debug("Response handling is manual for this dynamic route", zap.Any("output_given_by_handler", output))
+			return nil, nil
+		}
+
+		if outputFieldRules == nil {
+			return nil, errors.NewInternalServerError("Output rules must be provided for dynamic routes", nil)
+		}
+
+		headers, body, outputErr := validation.DynamicOutputData(validationEngine, outputCacheId, outputFieldRules, output)
+		if outputErr != nil {
+			return nil, outputErr
+		}
+
+		return &routeResponse{Headers: headers, Body: body}, nil
 	}
 
-	headers, body, outputErr := validation.DynamicOutputData(validationEngine, outputCacheId, outputFieldRules, output)
-	if outputErr != nil {
-		helpers.ErrorResponse(ctx, outputErr)
+	response, appErr := executeIdempotent(ctx, sessionManager, sessionConfig, claims, input, func() (*routeResponse, *errors.AppError) {
+		return executeCached(ctx, sessionManager, sessionConfig, claims, group, func() (*routeResponse, *errors.AppError) {
+			return executeCoalesced(ctx, sessionManager, sessionConfig, claims, group, func() (*routeResponse, *errors.AppError) {
+				return executeLimited(ctx, sessionConfig, func() (*routeResponse, *errors.AppError) {
+					return timeout.run(ctx, recoverHandler(ctx, sessionManager, claims, handle))
+				})
+			})
+		})
+	})
+	invalidateCachedRoutes(ctx, sessionManager, sessionConfig, appErr)
+	if appErr != nil {
+		// - A started response is never appended to, e.g., a timed out handler already had its 504 sent
+		if !ctx.Writer.Written() {
+			helpers.ErrorResponse(ctx, appErr)
+		}
 		return
 	}
 
-	helpers.SuccessResponse(ctx, 200, body, headers)
+	processReplicaWrite(ctx, sessionManager, sessionConfig, claims)
+	sendRouteResponse(ctx, response)
 }
@@ -0,0 +1,227 @@
+package core
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/errors"
+	"github.com/grzegorzmaniak/gothic/helpers"
+)
+
+const (
+	CorsAnyOrigin     = "*"              // Allows every origin, it can't be combined with AllowCredentials
+	DefaultCorsMaxAge = 10 * time.Minute // How long browsers may cache a preflight response
+)
+
+// CorsPolicy is the CORS policy of a route, see APIConfiguration.Cors and RouteConstructor.SetCorsPolicy. The
+// headers GoThic reads (Content-Type, the CSRF header and nonce header, the authorization header, Idempotency-Key
+// and X-Request-ID) are always allowed, and the X-Request-ID, X-Session-Refresh-Due, CSRF and nonce response
+// headers are always exposed.
+type CorsPolicy struct {
+	// AllowedOrigins are the origins allowed to call the route, e.g., "https://app.example.com". CorsAnyOrigin
+	// allows every origin.
+	AllowedOrigins []string
+
+	// AllowCredentials lets browsers send cookies, which cookie sessions need. The origins must then be listed,
+	// CorsAnyOrigin is rejected (Default: false)
+	AllowCredentials bool
+
+	// AllowedHeaders are the request headers allowed on top of the ones GoThic reads (Default: nil)
+	AllowedHeaders []string
+
+	// ExposedHeaders are the response headers readable by scripts on top of the ones GoThic sets (Default: nil)
+	ExposedHeaders []string
+
+	// MaxAge is how long browsers may cache a preflight response (Default: DefaultCorsMaxAge)
+	MaxAge time.Duration
+}
+
+// validate checks the policy can be sent, browsers refuse credentials with a wildcard origin.
+func (p *CorsPolicy) validate() error {
+	if p == nil {
+		return nil
+	}
+	if len(p.AllowedOrigins) == 0 {
+		return fmt.Errorf("CORS policy allows no origins")
+	}
+	if p.AllowCredentials && slices.Contains(p.AllowedOrigins, CorsAnyOrigin) {
+		return fmt.Errorf("CORS policy can't allow credentials from any origin, list the origins instead")
+	}
+	return nil
+}
+
+// allowedOrigin returns the value of Access-Control-Allow-Origin for origin, or "" if it is not allowed.
+func (p *CorsPolicy) allowedOrigin(origin string) string {
+	if p == nil || origin == "" {
+		return ""
+	}
+	for _, allowed := range p.AllowedOrigins {
+		if allowed == CorsAnyOrigin {
+			return CorsAnyOrigin
+		}
+		if strings.EqualFold(allowed, origin) {
+			return origin
+		}
+	}
+	return ""
+}
+
+// SetCorsPolicy sets the CORS policy of the routes registered afterwards that have none of their own, nil
+// disables it. Set it before registering the routes.
+func (ctor *RouteConstructor[BaseRoute]) SetCorsPolicy(policy *CorsPolicy) {
+	if err := policy.validate(); err != nil {
+		panic(fmt.Sprintf("core: invalid CORS policy: %v", err))
+	}
+	ctor.cors.Store(policy)
+}
+
+// HandleMethodNotAllowed answers requests to a registered path with another method with a 405 Method Not
+// Allowed error response. Gin lists the path's methods in the Allow header, instead of answering 404.
+func (ctor *RouteConstructor[BaseRoute]) HandleMethodNotAllowed() {
+	ctor.router.HandleMethodNotAllowed = true
+	ctor.router.NoMethod(func(ctx *gin.Context) {
+		helpers.ErrorResponse(ctx, errors.NewMethodNotAllowed("", nil))
+	})
+}
+
+// corsPolicy returns the policy of a route being registered, panicking when it is invalid.
+func (ctor *RouteConstructor[BaseRoute]) corsPolicy(sessionConfig *APIConfiguration) *CorsPolicy {
+	if sessionConfig == nil || sessionConfig.Cors == nil {
+		return ctor.cors.Load()
+	}
+	if err := sessionConfig.Cors.validate(); err != nil {
+		panic(fmt.Sprintf("core: invalid CORS policy: %v", err))
+	}
+	return sessionConfig.Cors
+}
+
+// registerPreflight registers the OPTIONS route answering the preflight requests of path, once per path.
+func (ctor *RouteConstructor[BaseRoute]) registerPreflight(path string, policy *CorsPolicy) {
+	if policy == nil {
+		return
+	}
+
+	ctor.routes.Lock()
+	registered := ctor.routes.preflights[path]
+	if ctor.routes.preflights == nil {
+		ctor.routes.preflights = make(map[string]bool)
+	}
+	ctor.routes.preflights[path] = true
+	ctor.routes.Unlock()
+
+	if !registered {
+		ctor.router.OPTIONS(path, ctor.handlePreflight)
+	}
+}
+
+// handlePreflight answers OPTIONS requests with the methods of the path, and preflight requests with the CORS
+// policy of the requested method. Disallowed origins and methods get no CORS headers, so the browser blocks them.
+func (ctor *RouteConstructor[BaseRoute]) handlePreflight(ctx *gin.Context) {
+	path := ctx.FullPath()
+	methods := []string{http.MethodOptions}
+	var requested *RouteInfo
+	routes := ctor.Routes()
+	for i, route := range routes {
+		if route.Path != path {
+			continue
+		}
+		methods = append(methods, route.Method)
+		if route.Method == http.MethodGet {
+			methods = append(methods, http.MethodHead)
+		}
+		if route.Method == ctx.GetHeader("Access-Control-Request-Method") {
+			requested = &routes[i]
+		}
+	}
+	ctx.Header("Allow", strings.Join(methods, ", "))
+
+	if requested != nil && requested.Cors != nil {
+		origin := requested.Cors.allowedOrigin(ctx.GetHeader("Origin"))
+		ctx.Writer.Header().Add("Vary", "Origin")
+		if origin != "" {
+			policy := requested.Cors
+			setCorsOrigin(ctx, policy, origin)
+			ctx.Header("Access-Control-Allow-Methods", strings.Join(corsMethods(routes, path, ctx.GetHeader("Origin")), ", "))
+			ctx.Header("Access-Control-Allow-Headers", strings.Join(corsAllowedHeaders(ctor.sessionManager, policy), ", "))
+			maxAge := policy.MaxAge
+			if maxAge <= 0 {
+				maxAge = DefaultCorsMaxAge
+			}
+			ctx.Header("Access-Control-Max-Age", strconv.Itoa(int(maxAge.Seconds())))
+		}
+	}
+	ctx.AbortWithStatus(http.StatusNoContent)
+}
+
+// corsMethods returns the methods of path whose policy allows origin.
+func corsMethods(routes []RouteInfo, path string, origin string) []string {
+	var methods []string
+	for _, route := range routes {
+		if route.Path == path && route.Cors.allowedOrigin(origin) != "" {
+			methods = append(methods, route.Method)
+		}
+	}
+	return methods
+}
+
+// corsAllowedHeaders returns the request headers of the policy and the ones GoThic reads.
+func corsAllowedHeaders(sessionManager SessionManager, policy *CorsPolicy) []string {
+	headers := []string{"Content-Type", helpers.RequestIDHeader, IdempotencyKeyHeader}
+	if sessionManager != nil {
+		if csrfData := sessionManager.GetCsrfData(); csrfData != nil {
+			headers = append(headers, helpers.DefaultString(csrfData.Name, DefaultCsrfCookieName))
+			if csrfData.Nonces {
+				headers = append(headers, helpers.DefaultString(csrfData.NonceHeaderName, DefaultCsrfNonceHeaderName))
+			}
+			if csrfData.HeaderPolicy != nil {
+				headers = append(headers, helpers.DefaultString(csrfData.HeaderPolicy.HeaderName, DefaultCsrfClientHeaderName))
+			}
+		}
+		if authorizationData := sessionManager.GetAuthorizationConfiguration(); authorizationData != nil {
+			headers = append(headers, helpers.DefaultString(authorizationData.AuthorizationHeaderName, DefaultSessionAuthorizationHeaderName))
+		}
+	}
+	return append(headers, policy.AllowedHeaders...)
+}
+
+// corsExposedHeaders returns the response headers of the policy and the ones GoThic sets for clients to read, e.g.,
+// the CSRF token of NewCsrfTokenHandler's HeaderOnly responses and the next CSRF nonce.
+func corsExposedHeaders(sessionManager SessionManager, policy *CorsPolicy) []string {
+	headers := []string{helpers.RequestIDHeader, SessionRefreshDueHeader}
+	if sessionManager != nil {
+		if csrfData := sessionManager.GetCsrfData(); csrfData != nil {
+			headers = append(headers, helpers.DefaultString(csrfData.Name, DefaultCsrfCookieName))
+			if csrfData.Nonces {
+				headers = append(headers, helpers.DefaultString(csrfData.NonceHeaderName, DefaultCsrfNonceHeaderName))
+			}
+		}
+	}
+	return append(headers, policy.ExposedHeaders...)
+}
+
+// setCorsOrigin sets the response headers every allowed CORS response carries.
+func setCorsOrigin(ctx *gin.Context, policy *CorsPolicy, origin string) {
+	ctx.Header("Access-Control-Allow-Origin", origin)
+	if policy.AllowCredentials {
+		ctx.Header("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+// applyCors sets the CORS headers of a route's response when the request's origin is allowed.
+func applyCors(ctx *gin.Context, sessionManager SessionManager, policy *CorsPolicy) {
+	if policy == nil {
+		return
+	}
+	ctx.Writer.Header().Add("Vary", "Origin")
+	origin := policy.allowedOrigin(ctx.GetHeader("Origin"))
+	if origin == "" {
+		return
+	}
+	setCorsOrigin(ctx, policy, origin)
+	ctx.Header("Access-Control-Expose-Headers", strings.Join(corsExposedHeaders(sessionManager, policy), ", "))
+}
@@ -0,0 +1,128 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/errors"
+)
+
+func TestCors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mgr := newMockSessionManager(t)
+
+	router := gin.New()
+	ctor := NewRouteConstructor(router, testBaseRoute{}, mgr, nil)
+	ctor.HandleMethodNotAllowed()
+	ctor.SetCorsPolicy(&CorsPolicy{AllowedOrigins: []string{"https://app.example.com"}, AllowCredentials: true, ExposedHeaders: []string{"X-Total"}})
+
+	ok := func(_ *struct{}, _ *Handler[testBaseRoute]) (*struct{}, *errors.AppError) {
+		return &struct{}{}, nil
+	}
+	GET(ctor, "/orders", PublicRoute(), ok)
+	POST(ctor, "/orders", PublicRoute().WithoutCsrf(), ok)
+	GET(ctor, "/public", PublicRoute().WithCors(&CorsPolicy{AllowedOrigins: []string{CorsAnyOrigin}}), ok)
+
+	request := func(method string, path string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	t.Run("Preflight requests get the route's policy", func(t *testing.T) {
+		recorder := request(http.MethodOptions, "/orders", map[string]string{
+			"Origin":                        "https://app.example.com",
+			"Access-Control-Request-Method": http.MethodPost,
+		})
+		header := recorder.Header()
+		if recorder.Code != http.StatusNoContent || header.Get("Access-Control-Allow-Origin") != "https://app.example.com" {
+			t.Fatalf("Expected the origin to be allowed, got %d: %v", recorder.Code, header)
+		}
+		if header.Get("Access-Control-Allow-Credentials") != "true" || header.Get("Access-Control-Allow-Methods") != "GET, POST" {
+			t.Errorf("Expected credentials and both methods, got %v", header)
+		}
+		if allowed := header.Get("Access-Control-Allow-Headers"); !strings.Contains(allowed, DefaultCsrfCookieName) || !strings.Contains(allowed, IdempotencyKeyHeader) {
+			t.Errorf("Expected the CSRF and Idempotency-Key headers to be allowed, got '%s'", allowed)
+		}
+		if header.Get("Access-Control-Max-Age") != "600" || header.Get("Vary") != "Origin" {
+			t.Errorf("Expected the default max age and Vary, got %v", header)
+		}
+	})
+
+	t.Run("Other origins get no CORS headers", func(t *testing.T) {
+		recorder := request(http.MethodOptions, "/orders", map[string]string{
+			"Origin":                        "https://evil.example.com",
+			"Access-Control-Request-Method": http.MethodPost,
+		})
+		if recorder.Header().Get("Access-Control-Allow-Origin") != "" {
+			t.Errorf("Expected no CORS headers, got %v", recorder.Header())
+		}
+		recorder = request(http.MethodGet, "/orders", map[string]string{"Origin": "https://evil.example.com"})
+		if recorder.Code != http.StatusOK || recorder.Header().Get("Access-Control-Allow-Origin") != "" {
+			t.Errorf("Expected no CORS headers on the response, got %v", recorder.Header())
+		}
+	})
+
+	t.Run("Responses carry the CORS headers", func(t *testing.T) {
+		recorder := request(http.MethodGet, "/orders", map[string]string{"Origin": "https://app.example.com"})
+		header := recorder.Header()
+		if header.Get("Access-Control-Allow-Origin") != "https://app.example.com" || header.Get("Access-Control-Allow-Credentials") != "true" {
+			t.Errorf("Expected the origin and credentials to be allowed, got %v", header)
+		}
+		if exposed := header.Get("Access-Control-Expose-Headers"); !strings.Contains(exposed, SessionRefreshDueHeader) || !strings.Contains(exposed, "X-Total") {
+			t.Errorf("Expected the exposed headers, got '%s'", exposed)
+		}
+
+		recorder = request(http.MethodGet, "/public", map[string]string{"Origin": "https://other.example.com"})
+		if recorder.Header().Get("Access-Control-Allow-Origin") != CorsAnyOrigin || recorder.Header().Get("Access-Control-Allow-Credentials") != "" {
+			t.Errorf("Expected the route's wildcard policy, got %v", recorder.Header())
+		}
+	})
+
+	t.Run("CSRF token and nonce headers are allowed and exposed", func(t *testing.T) {
+		mgr.csrfData.Nonces = true
+		defer func() { mgr.csrfData.Nonces = false }()
+
+		recorder := request(http.MethodOptions, "/orders", map[string]string{
+			"Origin":                        "https://app.example.com",
+			"Access-Control-Request-Method": http.MethodPost,
+		})
+		if allowed := recorder.Header().Get("Access-Control-Allow-Headers"); !strings.Contains(allowed, DefaultCsrfNonceHeaderName) {
+			t.Errorf("Expected the nonce header to be allowed, got '%s'", allowed)
+		}
+
+		recorder = request(http.MethodGet, "/orders", map[string]string{"Origin": "https://app.example.com"})
+		exposed := recorder.Header().Get("Access-Control-Expose-Headers")
+		if !strings.Contains(exposed, DefaultCsrfCookieName) || !strings.Contains(exposed, DefaultCsrfNonceHeaderName) {
+			t.Errorf("Expected the CSRF and nonce headers to be exposed, got '%s'", exposed)
+		}
+	})
+
+	t.Run("Other methods get a 405 with the allowed methods", func(t *testing.T) {
+		recorder := request(http.MethodDelete, "/orders", nil)
+		if recorder.Code != http.StatusMethodNotAllowed || !strings.Contains(recorder.Body.String(), "not allowed") {
+			t.Fatalf("Expected a 405 error response, got %d: %s", recorder.Code, recorder.Body.String())
+		}
+		for _, method := range []string{http.MethodGet, http.MethodPost, http.MethodOptions} {
+			if !strings.Contains(recorder.Header().Get("Allow"), method) {
+				t.Errorf("Expected %s in the Allow header, got '%s'", method, recorder.Header().Get("Allow"))
+			}
+		}
+	})
+
+	t.Run("Credentials from any origin are rejected", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Error("Expected the policy to be rejected")
+			}
+		}()
+		ctor.SetCorsPolicy(&CorsPolicy{AllowedOrigins: []string{CorsAnyOrigin}, AllowCredentials: true})
+	})
+}
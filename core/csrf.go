@@ -4,8 +4,12 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
-	"github.com/grzegorzmaniak/gothic/helpers"
+	"net/http"
+	"slices"
 	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/helpers"
 )
 
 const (
@@ -33,6 +37,9 @@ const (
 	DefaultCsrfTokenTieSize = 32 // Default size for the CSRF
 )
 
+// DefaultCsrfSafeMethods are the methods that skip the CSRF check, they must not change state (RFC 9110 9.2.1).
+var DefaultCsrfSafeMethods = []string{http.MethodGet, http.MethodHead, http.MethodOptions}
+
 type CsrfCookieData struct {
 	Name     string // Name of the cookie
 	Path     string // Path for which the cookie is valid (used when setting)
@@ -41,6 +48,13 @@ type CsrfCookieData struct {
 	HttpOnly bool   // If true, cookie cannot be accessed by client-side scripts (used when setting)
 	SameSite string // SameSite attribute (e.g., "Strict", "Lax", "None") (used when setting)
 
+	// Partitioned sets the Partitioned (CHIPS) attribute, it requires Secure (Default: false)
+	Partitioned bool
+
+	// Prefix prefixes the cookie name with "__Secure-" or "__Host-", the header keeps the plain Name
+	// (Default: CookiePrefixNone)
+	Prefix CookiePrefix
+
 	// Delimiter is used to split the cookie's value into parts.
 	// For example, if the cookie value is "header.payload.signature", the delimiter is ".".
 	Delimiter string
@@ -55,6 +69,31 @@ type CsrfCookieData struct {
 
 	// CsrfTokenSize is the size of the CSRF token, default is 32 bytes.
 	CsrfTokenSize int
+
+	// Classifier selects the CsrfMode per request, e.g., ClassifyByHeader("X-Client-Type", "ios", "android").
+	// When nil every request uses CsrfModeCookie.
+	Classifier CsrfModeClassifier
+
+	// HeaderPolicy is used to validate requests classified as CsrfModeHeaderPolicy.
+	HeaderPolicy *CsrfHeaderPolicy
+
+	// SafeMethods never need the CSRF token, even on routes with RequireCsrf (Default: DefaultCsrfSafeMethods)
+	SafeMethods []string
+
+	// CheckSafeMethods makes RequireCsrf apply to SafeMethods as well, e.g., for a legacy API that changes state
+	// on GET (Default: false)
+	CheckSafeMethods bool
+
+	// Nonces makes routes with RequireCsrf also require a one-time nonce, sent back in the NonceHeaderName header.
+	// Every cookie-mode response carries the next nonce, signed and bound to the session's CSRF tie, so a leaked
+	// or replayed request can't be sent twice (Default: false)
+	Nonces bool
+
+	// NonceHeaderName is the request and response header carrying the nonce (Default: DefaultCsrfNonceHeaderName)
+	NonceHeaderName string
+
+	// NonceTTL is how long an issued nonce can be spent for (Default: DefaultCsrfNonceTTL)
+	NonceTTL time.Duration
 }
 
 type CompleteCsrfToken struct {
@@ -118,6 +157,7 @@ func CreateCsrfToken(
 	if err != nil {
 		return "", fmt.Errorf("failed to encrypt CSRF value: %w", err)
 	}
+	keyUsage.issued(keyId)
 
 	// - Encode the encrypted value to base64
 	encodedValue := base64.RawURLEncoding.EncodeToString(encryptedValue)
@@ -131,3 +171,29 @@ func CreateCsrfToken(
 		encodedValue,
 	), nil
 }
+
+// requestCsrfExempt reports whether the request skips the CSRF check of the route, see csrfExempt. WebSocket
+// handshakes never do, see ExecuteWebSocketRoute.
+func requestCsrfExempt(ctx *gin.Context, csrfData *CsrfCookieData, sessionConfig *APIConfiguration) bool {
+	if ctx.GetBool(webSocketHandshakeContextKey) {
+		return false
+	}
+	return csrfExempt(csrfData, sessionConfig, ctx.Request.Method)
+}
+
+// csrfExempt reports whether requests of the method skip the CSRF check of the route, because the method is safe
+// (see CsrfCookieData.SafeMethods) or listed in the route's ExemptMethods. csrfData may be nil.
+func csrfExempt(csrfData *CsrfCookieData, sessionConfig *APIConfiguration, method string) bool {
+	if sessionConfig != nil && slices.Contains(sessionConfig.ExemptMethods, method) {
+		return true
+	}
+	if csrfData != nil && csrfData.CheckSafeMethods {
+		return false
+	}
+
+	safeMethods := DefaultCsrfSafeMethods
+	if csrfData != nil && csrfData.SafeMethods != nil {
+		safeMethods = csrfData.SafeMethods
+	}
+	return slices.Contains(safeMethods, method)
+}
@@ -0,0 +1,117 @@
+package core
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/errors"
+	"github.com/grzegorzmaniak/gothic/helpers"
+	"go.uber.org/zap"
+)
+
+// CsrfTokenConfiguration configures NewCsrfTokenHandler.
+type CsrfTokenConfiguration struct {
+	// SessionManager the CSRF tokens are issued by (Required)
+	SessionManager SessionManager
+
+	// HeaderOnly returns the token in a response header named like the CSRF header, with 204 No Content, instead
+	// of in a CsrfTokenResponse body (Default: false)
+	HeaderOnly bool
+}
+
+// CsrfTokenResponse is the body returned by NewCsrfTokenHandler.
+type CsrfTokenResponse struct {
+	// Token is the value to send in the HeaderName header of state changing requests.
+	Token string `json:"token"`
+
+	// HeaderName is the name of the CSRF header.
+	HeaderName string `json:"header_name"`
+
+	// ExpiresAt is the Unix time the token expires at, fetch a new one before.
+	ExpiresAt int64 `json:"expires_at"`
+
+	// Nonce is the one-time nonce for the next state changing request, see CsrfCookieData.Nonces.
+	Nonce string `json:"nonce,omitempty"`
+}
+
+// currentCsrfToken returns the CSRF cookie of the request when it can be kept for the session of claims: it
+// decodes, is tied to the same session and does not need a refresh yet.
+func currentCsrfToken(ctx *gin.Context, sessionManager SessionManager, csrfData *CsrfCookieData, claims *SessionClaims) (string, *CompleteCsrfToken, bool) {
+	value, ok := requestCookie(ctx.Request, csrfCookieName(csrfData, hostOnlyCookies(sessionManager)))
+	if !ok || value == "" {
+		return "", nil, false
+	}
+	token, err := decodeCsrfToken(csrfData, sessionManager, value)
+	if err != nil || token.IsExpired() || token.NeedsRefresh() {
+		return "", nil, false
+	}
+
+	tie := csrfTieOf(claims)
+	if token.Tied != (tie != "") || token.Tie != tie {
+		return "", nil, false
+	}
+	return value, token, true
+}
+
+// NewCsrfTokenHandler returns a ready-made route that hands the CSRF token to SPAs that can't read the CSRF cookie,
+// e.g., because they are served from another path or origin. It issues (or refreshes) the CSRF cookie exactly like
+// AutoSetCsrfCookie, tied to the session of the request if it has one, and returns the token in a
+// CsrfTokenResponse (or only in a header, see HeaderOnly). A still fresh cookie is returned as it is, so tabs
+// bootstrapping at the same time don't invalidate each other's token. Mount it on GET, e.g.:
+//
+//	router.GET("/csrf", core.NewCsrfTokenHandler(core.CsrfTokenConfiguration{SessionManager: sessionManager}))
+func NewCsrfTokenHandler(config CsrfTokenConfiguration) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		sessionManager := config.SessionManager
+		if sessionManager == nil {
+			helpers.ErrorResponse(ctx, errors.NewInternalServerError("Session manager is nil", nil))
+			return
+		}
+		csrfData := sessionManager.GetCsrfData()
+		if csrfData == nil {
+			helpers.ErrorResponse(ctx, errors.NewInternalServerError("Csrf data is nil", nil))
+			return
+		}
+		ctx.Header("Cache-Control", "no-store")
+
+		// - Invalid or expired sessions get an anonymous token, the same as routes with an optional session
+		header, claims, _, source, err := extractSession(ctx, sessionManager)
+		if err != nil || source == SourceHeader || header == nil || header.IsExpired() || !header.IsValid() {
+			if err != nil {
+				helpers.Logger(ctx).Debug("Issuing an anonymous CSRF token for an invalid session", zap.Error(err))
+			}
+			claims = nil
+		}
+
+		token, current, ok := currentCsrfToken(ctx, sessionManager, csrfData, claims)
+		expiresAt := int64(0)
+		if ok {
+			expiresAt = current.ExpiresAt
+		} else {
+			if token, err = autoSetCsrfCookie(ctx, sessionManager, claims); err != nil {
+				helpers.ErrorResponse(ctx, errors.NewInternalServerError("Failed to set CSRF cookie", err))
+				return
+			}
+			expiresAt = time.Now().Add(helpers.DefaultTimeDuration(csrfData.Expiration, DefaultCsrfExpiration)).Unix()
+		}
+
+		// - The nonce is also set in the NonceHeaderName header, HeaderOnly clients read it from there
+		nonce := ""
+		if csrfData.Nonces {
+			if nonce, err = issueCsrfNonce(ctx, sessionManager, csrfData, claims); err != nil {
+				helpers.ErrorResponse(ctx, errors.NewInternalServerError("Failed to issue CSRF nonce", err))
+				return
+			}
+		}
+
+		headerName := helpers.DefaultString(csrfData.Name, DefaultCsrfCookieName)
+		if config.HeaderOnly {
+			ctx.Header(headerName, token)
+			ctx.Status(http.StatusNoContent)
+			ctx.Writer.WriteHeaderNow()
+			return
+		}
+		ctx.JSON(http.StatusOK, CsrfTokenResponse{Token: token, HeaderName: headerName, ExpiresAt: expiresAt, Nonce: nonce})
+	}
+}
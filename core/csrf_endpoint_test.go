@@ -0,0 +1,116 @@
+package core
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCsrfTokenHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mgr := newMockSessionManager(t)
+
+	fetch := func(config CsrfTokenConfiguration, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+		t.Helper()
+		router := gin.New()
+		config.SessionManager = mgr
+		router.GET("/csrf", NewCsrfTokenHandler(config))
+
+		request := httptest.NewRequest(http.MethodGet, "/csrf", nil)
+		for _, cookie := range cookies {
+			request.AddCookie(cookie)
+		}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	decode := func(recorder *httptest.ResponseRecorder) CsrfTokenResponse {
+		t.Helper()
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", recorder.Code, recorder.Body.String())
+		}
+		var response CsrfTokenResponse
+		if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to decode the response: %v", err)
+		}
+		return response
+	}
+
+	csrfCookie := func(recorder *httptest.ResponseRecorder) *http.Cookie {
+		for _, cookie := range recorder.Result().Cookies() {
+			if cookie.Name == DefaultCsrfCookieName {
+				return cookie
+			}
+		}
+		return nil
+	}
+
+	t.Run("Anonymous token in the body", func(t *testing.T) {
+		recorder := fetch(CsrfTokenConfiguration{})
+		response := decode(recorder)
+		cookie := csrfCookie(recorder)
+		if cookie == nil || cookie.Value != response.Token {
+			t.Fatalf("Expected the token to match the CSRF cookie, got %+v", cookie)
+		}
+		if response.HeaderName != DefaultCsrfCookieName || response.ExpiresAt <= time.Now().Unix() {
+			t.Errorf("Unexpected response %+v", response)
+		}
+		if recorder.Header().Get("Cache-Control") != "no-store" {
+			t.Error("Expected the response not to be cached")
+		}
+	})
+
+	t.Run("Session token passes RequireCsrf", func(t *testing.T) {
+		loginRecorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(loginRecorder)
+		ctx.Request = httptest.NewRequest(http.MethodPost, "/login", nil)
+		if err := SetSessionCookie(ctx, mgr, "user", &SessionClaims{Claims: map[string]string{"subject": "user-1"}}); err != nil {
+			t.Fatalf("Failed to set session cookie: %v", err)
+		}
+		var session *http.Cookie
+		for _, cookie := range loginRecorder.Result().Cookies() {
+			if cookie.Name == DefaultSessionAuthorizationName {
+				session = cookie
+			}
+		}
+
+		recorder := fetch(CsrfTokenConfiguration{}, session)
+		response := decode(recorder)
+
+		ctx, _ = gin.CreateTestContext(httptest.NewRecorder())
+		ctx.Request = httptest.NewRequest(http.MethodPost, "/orders", nil)
+		ctx.Request.AddCookie(session)
+		ctx.Request.AddCookie(csrfCookie(recorder))
+		ctx.Request.Header.Set(response.HeaderName, response.Token)
+		if _, _, _, _, appErr := _establishSessionContext(ctx, mgr, &APIConfiguration{SessionRequired: true, RequireCsrf: true}); appErr != nil {
+			t.Errorf("Expected the token to be accepted, got %v", appErr)
+		}
+	})
+
+	t.Run("Fresh cookies are kept", func(t *testing.T) {
+		first := fetch(CsrfTokenConfiguration{})
+		cookie := csrfCookie(first)
+		second := fetch(CsrfTokenConfiguration{}, cookie)
+		if response := decode(second); response.Token != cookie.Value {
+			t.Error("Expected the existing token to be returned")
+		}
+		if csrfCookie(second) != nil {
+			t.Error("Expected no new CSRF cookie")
+		}
+	})
+
+	t.Run("Header only", func(t *testing.T) {
+		recorder := fetch(CsrfTokenConfiguration{HeaderOnly: true})
+		if recorder.Code != http.StatusNoContent || recorder.Body.Len() != 0 {
+			t.Fatalf("Expected 204 without a body, got %d: %s", recorder.Code, recorder.Body.String())
+		}
+		if token := recorder.Header().Get(DefaultCsrfCookieName); token == "" || token != csrfCookie(recorder).Value {
+			t.Errorf("Expected the token in the %s header", DefaultCsrfCookieName)
+		}
+	})
+}
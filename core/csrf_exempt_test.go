@@ -0,0 +1,93 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCsrfExemptMethods(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mgr := newMockSessionManager(t)
+
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/login", nil)
+	if err := SetSessionCookie(ctx, mgr, "user", &SessionClaims{Claims: map[string]string{"subject": "user-1"}}); err != nil {
+		t.Fatalf("Failed to set session cookie: %v", err)
+	}
+	var session, csrf *http.Cookie
+	for _, cookie := range recorder.Result().Cookies() {
+		switch cookie.Name {
+		case DefaultSessionAuthorizationName:
+			session = cookie
+		case DefaultCsrfCookieName:
+			csrf = cookie
+		}
+	}
+
+	// - Requests carry the cookies, but never the CSRF header
+	establish := func(method string, config *APIConfiguration, cookies ...*http.Cookie) (*httptest.ResponseRecorder, error) {
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+		ctx.Request = httptest.NewRequest(method, "/orders", nil)
+		for _, cookie := range cookies {
+			ctx.Request.AddCookie(cookie)
+		}
+		if _, _, _, _, appErr := _establishSessionContext(ctx, mgr, config); appErr != nil {
+			return recorder, appErr
+		}
+		return recorder, nil
+	}
+
+	t.Run("Method policy", func(t *testing.T) {
+		tests := []struct {
+			name    string
+			method  string
+			config  *APIConfiguration
+			setup   func(*CsrfCookieData)
+			wantErr bool
+		}{
+			{"GET is safe", http.MethodGet, AuthenticatedJSONAPI(), nil, false},
+			{"HEAD is safe", http.MethodHead, AuthenticatedJSONAPI(), nil, false},
+			{"POST needs the token", http.MethodPost, AuthenticatedJSONAPI(), nil, true},
+			{"Exempt POST", http.MethodPost, AuthenticatedJSONAPI().WithCsrfExemptMethods(http.MethodPost), nil, false},
+			{"CheckSafeMethods", http.MethodGet, AuthenticatedJSONAPI(), func(d *CsrfCookieData) { d.CheckSafeMethods = true }, true},
+			{"Custom safe methods", http.MethodHead, AuthenticatedJSONAPI(), func(d *CsrfCookieData) { d.SafeMethods = []string{http.MethodGet} }, true},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				if tt.setup != nil {
+					tt.setup(mgr.csrfData)
+					defer func() { mgr.csrfData.CheckSafeMethods, mgr.csrfData.SafeMethods = false, nil }()
+				}
+				if _, err := establish(tt.method, tt.config, session, csrf); (err != nil) != tt.wantErr {
+					t.Errorf("Expected error %v, got %v", tt.wantErr, err)
+				}
+			})
+		}
+	})
+
+	t.Run("Safe requests keep a fresh CSRF cookie", func(t *testing.T) {
+		recorder, err := establish(http.MethodGet, AuthenticatedJSONAPI(), session, csrf)
+		if err != nil {
+			t.Fatalf("Expected the request to pass, got %v", err)
+		}
+		for _, cookie := range recorder.Result().Cookies() {
+			if cookie.Name == DefaultCsrfCookieName {
+				t.Error("Expected the CSRF cookie not to be reissued")
+			}
+		}
+
+		recorder, _ = establish(http.MethodGet, AuthenticatedJSONAPI(), session)
+		reissued := false
+		for _, cookie := range recorder.Result().Cookies() {
+			reissued = reissued || cookie.Name == DefaultCsrfCookieName
+		}
+		if !reissued {
+			t.Error("Expected a missing CSRF cookie to be issued")
+		}
+	})
+}
@@ -25,14 +25,19 @@ func extractCsrfParts(ctx *gin.Context, csrfData *CsrfCookieData, sessionManager
 		return nil, fmt.Errorf("CSRF header '%s' has an invalid size", name)
 	}
 
-	csrfCookie, err := ctx.Cookie(name)
+	csrfCookie, err := ctx.Cookie(csrfCookieName(csrfData, hostOnlyCookies(sessionManager)))
 	if err != nil {
-		return nil, fmt.Errorf("failed to get CSRF cookie '%s': %w", name, err)
+		return nil, fmt.Errorf("failed to get CSRF cookie '%s': %w", csrfCookieName(csrfData, hostOnlyCookies(sessionManager)), err)
 	}
 	if csrfCookie != csrfHeader {
 		return nil, fmt.Errorf("CSRF token mismatch: header does not match cookie")
 	}
 
+	return decodeCsrfToken(csrfData, sessionManager, csrfHeader)
+}
+
+// decodeCsrfToken decrypts and decodes a CSRF token, without comparing it to the request.
+func decodeCsrfToken(csrfData *CsrfCookieData, sessionManager SessionManager, csrfHeader string) (*CompleteCsrfToken, error) {
 	delimiter := helpers.DefaultString(csrfData.Delimiter, DefaultCsrfCookieDelimiter)
 
 	firstDelim := strings.Index(csrfHeader, delimiter)
@@ -59,6 +64,7 @@ func extractCsrfParts(ctx *gin.Context, csrfData *CsrfCookieData, sessionManager
 
 	sessionKey, err := sessionManager.GetOldSessionKey(keyId)
 	if err != nil {
+		keyUsage.failed(UnknownKeyId)
 		return nil, fmt.Errorf("failed to get session key for CSRF token: %w", err)
 	}
 
@@ -70,8 +76,10 @@ func extractCsrfParts(ctx *gin.Context, csrfData *CsrfCookieData, sessionManager
 	associatedData := []byte(keyId + keyVersion)
 	decryptedValue, err := helpers.SymmetricDecrypt(sessionKey, decodedValue, associatedData)
 	if err != nil {
+		keyUsage.failed(keyId)
 		return nil, fmt.Errorf("failed to decrypt CSRF token: %w", err)
 	}
+	keyUsage.validated(keyId)
 
 	var completeToken CompleteCsrfToken
 	if err = json.Unmarshal(decryptedValue, &completeToken); err != nil {
@@ -0,0 +1,122 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/helpers"
+)
+
+// CsrfMode selects how CSRF protection is applied to a request.
+type CsrfMode int
+
+const (
+	// CsrfModeCookie is the default double-submit mode, the token is stored in a cookie and echoed in a header.
+	CsrfModeCookie CsrfMode = iota
+
+	// CsrfModeHeaderPolicy is meant for native (mobile / desktop) clients without a cookie jar. No CSRF cookie
+	// is issued, the tie is carried purely in the session token, and requests are validated by CsrfHeaderPolicy.
+	CsrfModeHeaderPolicy
+)
+
+const (
+	DefaultCsrfClientHeaderName = "X-Requested-With" // Custom header native clients have to send
+)
+
+// CsrfModeClassifier decides which CsrfMode applies to a request, e.g., based on a client type header or user agent.
+type CsrfModeClassifier func(ctx *gin.Context) CsrfMode
+
+// CsrfHeaderPolicy validates requests made in CsrfModeHeaderPolicy. Browsers cannot attach custom headers to a
+// cross-site request without a CORS preflight, and always send an Origin header on cross-site state changing
+// requests, so requiring the header and checking the origin (if present) replaces the double-submit cookie.
+type CsrfHeaderPolicy struct {
+	// AllowedOrigins is the list of origins that are accepted when an Origin header is present.
+	// Native clients usually send no Origin, any origin not in this list is rejected.
+	AllowedOrigins []string
+
+	// HeaderName is the custom header that must be present (Default: DefaultCsrfClientHeaderName)
+	HeaderName string
+
+	// HeaderValue optionally pins the value of the header, any non-empty value is accepted when unset.
+	HeaderValue string
+}
+
+// ClassifyByHeader returns a CsrfModeClassifier that selects CsrfModeHeaderPolicy when the given header
+// matches one of the values (case-insensitive), and CsrfModeCookie otherwise.
+func ClassifyByHeader(headerName string, values ...string) CsrfModeClassifier {
+	return func(ctx *gin.Context) CsrfMode {
+		header := ctx.GetHeader(headerName)
+		for _, value := range values {
+			if strings.EqualFold(header, value) {
+				return CsrfModeHeaderPolicy
+			}
+		}
+		return CsrfModeCookie
+	}
+}
+
+// csrfModeFor returns the CsrfMode for the request, defaulting to CsrfModeCookie when no classifier is set.
+func csrfModeFor(ctx *gin.Context, csrfData *CsrfCookieData) CsrfMode {
+	if csrfData == nil || csrfData.Classifier == nil {
+		return CsrfModeCookie
+	}
+	return csrfData.Classifier(ctx)
+}
+
+// validateCsrfHeaderPolicy checks the request against the header policy.
+func validateCsrfHeaderPolicy(ctx *gin.Context, policy *CsrfHeaderPolicy) error {
+	if policy == nil {
+		return fmt.Errorf("CSRF header policy is not configured")
+	}
+
+	// - A browser always sends the origin on cross-site requests, so if it is present it has to be trusted
+	if origin := ctx.GetHeader("Origin"); origin != "" {
+		allowed := false
+		for _, allowedOrigin := range policy.AllowedOrigins {
+			if strings.EqualFold(origin, allowedOrigin) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return fmt.Errorf("origin '%s' is not allowed", origin)
+		}
+	}
+
+	headerName := helpers.DefaultString(policy.HeaderName, DefaultCsrfClientHeaderName)
+	headerValue := ctx.GetHeader(headerName)
+	if headerValue == "" {
+		return fmt.Errorf("CSRF client header '%s' is missing", headerName)
+	}
+	if policy.HeaderValue != "" && headerValue != policy.HeaderValue {
+		return fmt.Errorf("CSRF client header '%s' has an unexpected value", headerName)
+	}
+
+	return nil
+}
+
+// establishHeaderPolicyCsrf validates a request in CsrfModeHeaderPolicy. No cookie is issued, the returned token
+// only carries the tie from the session claims so handlers see the same shape as in cookie mode.
+func establishHeaderPolicyCsrf(
+	ctx *gin.Context,
+	csrfData *CsrfCookieData,
+	claims *SessionClaims,
+	requireCsrf bool,
+) (*CompleteCsrfToken, error) {
+	if requireCsrf {
+		if err := validateCsrfHeaderPolicy(ctx, csrfData.HeaderPolicy); err != nil {
+			return nil, err
+		}
+	}
+
+	csrfToken := &CompleteCsrfToken{}
+	if claims != nil {
+		if tie, ok := claims.GetClaim(CsrfTokenTie); ok && tie != "" {
+			csrfToken.Tie = tie
+			csrfToken.Tied = true
+		}
+	}
+
+	return csrfToken, nil
+}
@@ -0,0 +1,82 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newCsrfModeTestContext(headers map[string]string) (*gin.Context, *httptest.ResponseRecorder) {
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/items", nil)
+	for name, value := range headers {
+		ctx.Request.Header.Set(name, value)
+	}
+	return ctx, recorder
+}
+
+func TestValidateCsrfHeaderPolicy(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	policy := &CsrfHeaderPolicy{AllowedOrigins: []string{"https://app.example.com"}}
+
+	tests := []struct {
+		name    string
+		policy  *CsrfHeaderPolicy
+		headers map[string]string
+		wantErr bool
+	}{
+		{name: "Custom header without origin", policy: policy, headers: map[string]string{DefaultCsrfClientHeaderName: "app"}, wantErr: false},
+		{name: "Custom header with allowed origin", policy: policy, headers: map[string]string{DefaultCsrfClientHeaderName: "app", "Origin": "https://app.example.com"}, wantErr: false},
+		{name: "Disallowed origin", policy: policy, headers: map[string]string{DefaultCsrfClientHeaderName: "app", "Origin": "https://evil.example.com"}, wantErr: true},
+		{name: "Missing custom header", policy: policy, headers: map[string]string{}, wantErr: true},
+		{name: "Pinned header value mismatch", policy: &CsrfHeaderPolicy{HeaderName: "X-Client", HeaderValue: "ios"}, headers: map[string]string{"X-Client": "android"}, wantErr: true},
+		{name: "Pinned header value match", policy: &CsrfHeaderPolicy{HeaderName: "X-Client", HeaderValue: "ios"}, headers: map[string]string{"X-Client": "ios"}, wantErr: false},
+		{name: "Missing policy", policy: nil, headers: map[string]string{DefaultCsrfClientHeaderName: "app"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, _ := newCsrfModeTestContext(tt.headers)
+			if err := validateCsrfHeaderPolicy(ctx, tt.policy); (err != nil) != tt.wantErr {
+				t.Errorf("validateCsrfHeaderPolicy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEstablishSessionContext_CsrfModes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mgr := newMockSessionManager(t)
+	mgr.csrfData = &CsrfCookieData{
+		Classifier:   ClassifyByHeader("X-Client-Type", "ios", "android"),
+		HeaderPolicy: &CsrfHeaderPolicy{},
+	}
+	config := &APIConfiguration{RequireCsrf: true}
+
+	t.Run("Native client passes with the custom header and gets no cookie", func(t *testing.T) {
+		ctx, recorder := newCsrfModeTestContext(map[string]string{"X-Client-Type": "ios", DefaultCsrfClientHeaderName: "app"})
+		if _, _, csrfToken, _, appErr := _establishSessionContext(ctx, mgr, config); appErr != nil || csrfToken == nil {
+			t.Fatalf("Expected success, got %v", appErr)
+		}
+		if cookie := recorder.Header().Get("Set-Cookie"); cookie != "" {
+			t.Errorf("Expected no CSRF cookie, got '%s'", cookie)
+		}
+	})
+
+	t.Run("Native client without the custom header is rejected", func(t *testing.T) {
+		ctx, _ := newCsrfModeTestContext(map[string]string{"X-Client-Type": "android"})
+		if _, _, _, _, appErr := _establishSessionContext(ctx, mgr, config); appErr == nil {
+			t.Error("Expected the request to be rejected")
+		}
+	})
+
+	t.Run("Browser client still requires the double-submit cookie", func(t *testing.T) {
+		ctx, _ := newCsrfModeTestContext(map[string]string{DefaultCsrfClientHeaderName: "app"})
+		if _, _, _, _, appErr := _establishSessionContext(ctx, mgr, config); appErr == nil {
+			t.Error("Expected the request to be rejected")
+		}
+	})
+}
@@ -0,0 +1,111 @@
+package core
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eko/gocache/lib/v4/store"
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/helpers"
+)
+
+const (
+	DefaultCsrfNonceHeaderName = "X-CSRF-Nonce"
+	DefaultCsrfNonceTTL        = time.Minute * 30
+	CsrfNonceCacheKeyPrefix    = "csrf_nonce:" // Key: csrf_nonce:<nonce>
+	CsrfNonceSize              = 32
+)
+
+// consumingCsrfNonces holds the nonces being consumed, so two concurrent requests of this process can't both
+// spend the same nonce between the cache read and delete.
+var consumingCsrfNonces sync.Map
+
+// csrfNonceMac signs the nonce for the CSRF tie it is bound to, "" for anonymous sessions.
+func csrfNonceMac(key []byte, keyId string, tie string, nonce string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("csrf-nonce|" + keyId + "|" + tie + "|" + nonce))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// csrfTieOf returns the CSRF tie of the session, "" for anonymous requests.
+func csrfTieOf(claims *SessionClaims) string {
+	if claims == nil {
+		return ""
+	}
+	tie, _ := claims.GetClaim(CsrfTokenTie)
+	return tie
+}
+
+// issueCsrfNonce stores a new one-time nonce bound to the session's CSRF tie and returns it in the
+// NonceHeaderName response header, see CsrfCookieData.Nonces.
+func issueCsrfNonce(ctx *gin.Context, sessionManager SessionManager, csrfData *CsrfCookieData, claims *SessionClaims) (string, error) {
+	key, keyId, err := sessionManager.GetSessionKey()
+	if err != nil {
+		return "", fmt.Errorf("failed to get session key: %w", err)
+	}
+	cache, err := sessionManager.GetCache()
+	if err != nil || cache == nil {
+		return "", fmt.Errorf("failed to get cache: %w", err)
+	}
+
+	nonce, err := helpers.GenerateID(CsrfNonceSize)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate CSRF nonce: %w", err)
+	}
+	tie := csrfTieOf(claims)
+	ttl := helpers.DefaultTimeDuration(csrfData.NonceTTL, DefaultCsrfNonceTTL)
+	if err = cache.Set(ctx, CsrfNonceCacheKeyPrefix+nonce, []byte(tie), store.WithExpiration(ttl)); err != nil {
+		return "", fmt.Errorf("failed to store CSRF nonce: %w", err)
+	}
+
+	token := keyId + DefaultCsrfCookieDelimiter + nonce + DefaultCsrfCookieDelimiter + csrfNonceMac(key, keyId, tie, nonce)
+	ctx.Header(helpers.DefaultString(csrfData.NonceHeaderName, DefaultCsrfNonceHeaderName), token)
+	return token, nil
+}
+
+// consumeCsrfNonce checks the nonce of a state changing request was issued for the session's CSRF tie and has not
+// been used yet, and deletes it.
+func consumeCsrfNonce(ctx *gin.Context, sessionManager SessionManager, csrfData *CsrfCookieData, claims *SessionClaims) error {
+	headerName := helpers.DefaultString(csrfData.NonceHeaderName, DefaultCsrfNonceHeaderName)
+	token := ctx.GetHeader(headerName)
+	keyId, rest, foundKeyId := strings.Cut(token, DefaultCsrfCookieDelimiter)
+	nonce, mac, found := strings.Cut(rest, DefaultCsrfCookieDelimiter)
+	if !foundKeyId || !found || nonce == "" {
+		return fmt.Errorf("CSRF nonce header '%s' is missing or malformed", headerName)
+	}
+
+	// - The signature rejects forged and foreign nonces without a cache lookup
+	key, err := sessionManager.GetOldSessionKey(keyId)
+	if err != nil {
+		return fmt.Errorf("failed to get session key for CSRF nonce: %w", err)
+	}
+	tie := csrfTieOf(claims)
+	if subtle.ConstantTimeCompare([]byte(mac), []byte(csrfNonceMac(key, keyId, tie, nonce))) != 1 {
+		return fmt.Errorf("CSRF nonce is not bound to this session")
+	}
+
+	if _, busy := consumingCsrfNonces.LoadOrStore(nonce, struct{}{}); busy {
+		return fmt.Errorf("CSRF nonce is already being used")
+	}
+	defer consumingCsrfNonces.Delete(nonce)
+
+	cache, err := sessionManager.GetCache()
+	if err != nil || cache == nil {
+		return fmt.Errorf("failed to get cache: %w", err)
+	}
+	cacheKey := CsrfNonceCacheKeyPrefix + nonce
+	stored, err := cache.Get(ctx, cacheKey)
+	if err != nil || subtle.ConstantTimeCompare(stored, []byte(tie)) != 1 {
+		return fmt.Errorf("CSRF nonce is unknown, expired or already used")
+	}
+	if err = cache.Delete(ctx, cacheKey); err != nil {
+		return fmt.Errorf("failed to consume CSRF nonce: %w", err)
+	}
+	return nil
+}
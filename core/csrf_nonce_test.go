@@ -0,0 +1,110 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCsrfNonces(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mgr := newMockSessionManager(t)
+	mgr.csrfData.Nonces = true
+
+	login := func(subject string) (*http.Cookie, *http.Cookie) {
+		t.Helper()
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+		ctx.Request = httptest.NewRequest(http.MethodPost, "/login", nil)
+		if err := SetSessionCookie(ctx, mgr, "user", &SessionClaims{Claims: map[string]string{"subject": subject}}); err != nil {
+			t.Fatalf("Failed to set session cookie: %v", err)
+		}
+		var session, csrf *http.Cookie
+		for _, cookie := range recorder.Result().Cookies() {
+			switch cookie.Name {
+			case DefaultSessionAuthorizationName:
+				session = cookie
+			case DefaultCsrfCookieName:
+				csrf = cookie
+			}
+		}
+		return session, csrf
+	}
+
+	// - Requests carry both cookies and the CSRF header, the returned string is the next nonce
+	establish := func(method string, nonce string, session *http.Cookie, csrf *http.Cookie) (string, error) {
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+		ctx.Request = httptest.NewRequest(method, "/orders", nil)
+		ctx.Request.AddCookie(session)
+		ctx.Request.AddCookie(csrf)
+		ctx.Request.Header.Set(DefaultCsrfCookieName, csrf.Value)
+		if nonce != "" {
+			ctx.Request.Header.Set(DefaultCsrfNonceHeaderName, nonce)
+		}
+		_, _, _, _, appErr := _establishSessionContext(ctx, mgr, AuthenticatedJSONAPI())
+		next := recorder.Header().Get(DefaultCsrfNonceHeaderName)
+		time.Sleep(10 * time.Millisecond) // - Let the cache settle
+		if appErr != nil {
+			return next, appErr
+		}
+		return next, nil
+	}
+
+	session, csrf := login("user-1")
+
+	t.Run("Safe requests hand out a nonce", func(t *testing.T) {
+		nonce, err := establish(http.MethodGet, "", session, csrf)
+		if err != nil || nonce == "" {
+			t.Fatalf("Expected a nonce, got %q, %v", nonce, err)
+		}
+	})
+
+	t.Run("Nonces are spent once", func(t *testing.T) {
+		nonce, _ := establish(http.MethodGet, "", session, csrf)
+		next, err := establish(http.MethodPost, nonce, session, csrf)
+		if err != nil {
+			t.Fatalf("Expected the nonce to be accepted, got %v", err)
+		}
+		if next == "" || next == nonce {
+			t.Errorf("Expected a new nonce, got %q", next)
+		}
+		if _, err = establish(http.MethodPost, nonce, session, csrf); err == nil {
+			t.Error("Expected a replayed nonce to be rejected")
+		}
+		if _, err = establish(http.MethodPost, next, session, csrf); err != nil {
+			t.Errorf("Expected the next nonce to be accepted, got %v", err)
+		}
+	})
+
+	t.Run("Missing nonce still gets the next one", func(t *testing.T) {
+		next, err := establish(http.MethodPost, "", session, csrf)
+		if err == nil {
+			t.Fatal("Expected a request without a nonce to be rejected")
+		}
+		if next == "" {
+			t.Error("Expected a nonce to retry with")
+		}
+	})
+
+	t.Run("Nonces are bound to the session", func(t *testing.T) {
+		nonce, _ := establish(http.MethodGet, "", session, csrf)
+		otherSession, otherCsrf := login("user-2")
+		if _, err := establish(http.MethodPost, nonce, otherSession, otherCsrf); err == nil {
+			t.Error("Expected another session's nonce to be rejected")
+		}
+	})
+
+	t.Run("CSRF endpoint returns a nonce", func(t *testing.T) {
+		router := gin.New()
+		router.GET("/csrf", NewCsrfTokenHandler(CsrfTokenConfiguration{SessionManager: mgr}))
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/csrf", nil))
+		if recorder.Header().Get(DefaultCsrfNonceHeaderName) == "" {
+			t.Errorf("Expected the %s header", DefaultCsrfNonceHeaderName)
+		}
+	})
+}
@@ -6,20 +6,10 @@ import (
 	"github.com/grzegorzmaniak/gothic/helpers"
 )
 
-// CookieConfig holds all the generic parameters for setting a cookie.
-type CookieConfig struct {
-	Name     string
-	Value    string
-	MaxAge   int
-	Path     string
-	Domain   string
-	Secure   bool
-	HttpOnly bool
-}
-
 func applyCsrfCookie(
 	ctx *gin.Context,
 	csrfData *CsrfCookieData,
+	hostOnly bool,
 	value string,
 	maxAge int,
 ) {
@@ -28,15 +18,7 @@ func applyCsrfCookie(
 		return
 	}
 
-	ctx.SetCookie(
-		helpers.DefaultString(csrfData.Name, DefaultCsrfCookieName),
-		value,
-		maxAge,
-		helpers.DefaultString(csrfData.Path, DefaultCsrfCookiePath),
-		helpers.DefaultString(csrfData.Domain, DefaultCsrfCookieDomain),
-		helpers.DefaultBool(csrfData.Secure, DefaultCsrfCookieSecure),
-		helpers.DefaultBool(csrfData.HttpOnly, DefaultCsrfCookieHttpOnly),
-	)
+	setCookie(ctx, csrfCookieConfig(csrfData, hostOnly, value, maxAge))
 }
 
 func SetCsrfCookie(
@@ -44,25 +26,38 @@ func SetCsrfCookie(
 	sessionManager SessionManager,
 	csrfTie string,
 ) error {
+	_, err := setCsrfCookie(ctx, sessionManager, csrfTie)
+	return err
+}
+
+// setCsrfCookie is SetCsrfCookie returning the token it set.
+func setCsrfCookie(
+	ctx *gin.Context,
+	sessionManager SessionManager,
+	csrfTie string,
+) (string, error) {
 	if ctx == nil {
-		return errors.NewInternalServerError("Context is nil", nil)
+		return "", errors.NewInternalServerError("Context is nil", nil)
 	}
 	if sessionManager == nil {
-		return errors.NewInternalServerError("Session manager is nil", nil)
+		return "", errors.NewInternalServerError("Session manager is nil", nil)
 	}
 	csrfData := sessionManager.GetCsrfData()
 	if csrfData == nil {
-		return errors.NewInternalServerError("Csrf data is nil", nil)
+		return "", errors.NewInternalServerError("Csrf data is nil", nil)
+	}
+	if err := csrfData.validateCookies(hostOnlyCookies(sessionManager)); err != nil {
+		return "", errors.NewInternalServerError("Invalid CSRF cookie configuration", err)
 	}
 
 	csrfString, err := CreateCsrfToken(sessionManager, *csrfData, csrfTie)
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	applyCsrfCookie(ctx, csrfData, csrfString, int(helpers.DefaultTimeDuration(csrfData.Expiration, DefaultCsrfExpiration).Seconds()))
+	applyCsrfCookie(ctx, csrfData, hostOnlyCookies(sessionManager), csrfString, int(helpers.DefaultTimeDuration(csrfData.Expiration, DefaultCsrfExpiration).Seconds()))
 
-	return nil
+	return csrfString, nil
 }
 
 func AutoSetCsrfCookie(
@@ -70,25 +65,54 @@ func AutoSetCsrfCookie(
 	sessionManager SessionManager,
 	claims *SessionClaims,
 ) error {
+	_, err := autoSetCsrfCookie(ctx, sessionManager, claims)
+	return err
+}
+
+// autoSetCsrfCookie is AutoSetCsrfCookie returning the token it set.
+func autoSetCsrfCookie(
+	ctx *gin.Context,
+	sessionManager SessionManager,
+	claims *SessionClaims,
+) (string, error) {
 	if ctx == nil {
-		return errors.NewInternalServerError("Context is nil", nil)
+		return "", errors.NewInternalServerError("Context is nil", nil)
 	}
 	if sessionManager == nil {
-		return errors.NewInternalServerError("Session manager is nil", nil)
+		return "", errors.NewInternalServerError("Session manager is nil", nil)
 	}
 
 	// - Handle anonymous user
 	if claims == nil {
-		return SetCsrfCookie(ctx, sessionManager, "")
+		return setCsrfCookie(ctx, sessionManager, "")
 	}
 
 	// - Handle authenticated user
 	csrfTie, ok := claims.GetClaim(CsrfTokenTie)
 	if !ok || csrfTie == "" {
-		return errors.NewInternalServerError("Csrf token tie is missing or empty", nil)
+		return "", errors.NewInternalServerError("Csrf token tie is missing or empty", nil)
 	}
 
-	return SetCsrfCookie(ctx, sessionManager, csrfTie)
+	return setCsrfCookie(ctx, sessionManager, csrfTie)
+}
+
+// keepCsrfCookie returns the CSRF cookie of a request that skips the CSRF check, it is only reissued when it is
+// missing, due for a refresh or tied to another session.
+func keepCsrfCookie(
+	ctx *gin.Context,
+	sessionManager SessionManager,
+	claims *SessionClaims,
+) (*CompleteCsrfToken, error) {
+	if csrfData := sessionManager.GetCsrfData(); csrfData != nil {
+		if _, csrfToken, ok := currentCsrfToken(ctx, sessionManager, csrfData, claims); ok {
+			return csrfToken, nil
+		}
+	}
+
+	if err := AutoSetCsrfCookie(ctx, sessionManager, claims); err != nil {
+		return nil, err
+	}
+	return &CompleteCsrfToken{}, nil
 }
 
 // ClearCsrfCookie now performs a true browser-level deletion of the cookie.
@@ -107,7 +131,7 @@ func ClearCsrfCookie(
 		return errors.NewInternalServerError("Csrf data is nil", nil)
 	}
 
-	applyCsrfCookie(ctx, csrfData, "", -1)
+	applyCsrfCookie(ctx, csrfData, hostOnlyCookies(sessionManager), "", -1)
 
 	return nil
 }
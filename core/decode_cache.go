@@ -0,0 +1,156 @@
+package core
+
+import (
+	"crypto/sha256"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/helpers"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	// MaximumDecodeCacheTTL caps SessionAuthorizationConfiguration.DecodeCacheTTL. A cached token is accepted
+	// without its key for this long after the key is removed from the session manager.
+	MaximumDecodeCacheTTL = 10 * time.Second
+
+	DefaultDecodeCacheSize = 10_000
+
+	// decodedSessionContextKey is the gin context key of the token decoded for the current request.
+	decodedSessionContextKey = "gothic_decoded_session"
+)
+
+// decodedToken is a decoded token, claims is never handed out, callers get a copy.
+type decodedToken struct {
+	token   string
+	header  SessionHeader
+	claims  map[string]string
+	group   string
+	expires time.Time
+}
+
+// session returns a copy of the decoded header and claims.
+func (d *decodedToken) session() (*SessionHeader, *SessionClaims, string) {
+	header := d.header
+	return &header, &SessionClaims{Claims: maps.Clone(d.claims), HasSession: true}, d.group
+}
+
+func newDecodedToken(token string, header *SessionHeader, claims *SessionClaims, group string, ttl time.Duration) *decodedToken {
+	return &decodedToken{
+		token:   token,
+		header:  *header,
+		claims:  maps.Clone(claims.Claims),
+		group:   group,
+		expires: time.Now().Add(ttl),
+	}
+}
+
+// decodeCache holds the tokens decoded by one session manager, keyed by the SHA-256 of the token. Concurrent
+// decodes of the same token are collapsed into one.
+type decodeCache struct {
+	mu       sync.RWMutex
+	entries  map[[sha256.Size]byte]*decodedToken
+	inflight singleflight.Group
+}
+
+// decodeCacheProvider is implemented by session managers embedding DefaultSessionManager.
+type decodeCacheProvider interface {
+	getDecodeCache() *decodeCache
+}
+
+func (m *DefaultSessionManager) getDecodeCache() *decodeCache {
+	return &m.decodeCache
+}
+
+// decodeCacheOf returns the decode cache of a session manager, or nil if it does not embed DefaultSessionManager.
+func decodeCacheOf(sessionManager SessionManager) *decodeCache {
+	if provider, ok := sessionManager.(decodeCacheProvider); ok {
+		return provider.getDecodeCache()
+	}
+	return nil
+}
+
+// decode returns the decoding of a token made earlier in this request or cached within the TTL, or decodes it
+// with fn and caches the result.
+func (c *decodeCache) decode(
+	ctx *gin.Context,
+	token string,
+	ttl time.Duration,
+	size int,
+	fn func() (*SessionHeader, *SessionClaims, string, error),
+) (*SessionHeader, *SessionClaims, string, error) {
+	if header, claims, group, ok := requestDecodedSession(ctx, token); ok {
+		return header, claims, group, nil
+	}
+
+	key := sha256.Sum256(stringBytes(token))
+	c.mu.RLock()
+	entry, ok := c.entries[key]
+	c.mu.RUnlock()
+
+	if !ok || !time.Now().Before(entry.expires) || entry.token != token {
+		// - Concurrent requests with the same token share a single decode, each gets its own copy
+		result, err, _ := c.inflight.Do(string(key[:]), func() (interface{}, error) {
+			header, claims, group, err := fn()
+			if err != nil {
+				return nil, err
+			}
+			entry := newDecodedToken(token, header, claims, group, ttl)
+			c.store(key, entry, size)
+			return entry, nil
+		})
+		if err != nil {
+			return nil, nil, "", err
+		}
+		entry = result.(*decodedToken)
+	}
+
+	ctx.Set(decodedSessionContextKey, entry)
+	header, claims, group := entry.session()
+	return header, claims, group, nil
+}
+
+// store caches an entry, expired entries are swept once the cache is full and the rest dropped if that is not
+// enough.
+func (c *decodeCache) store(key [sha256.Size]byte, entry *decodedToken, size int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[[sha256.Size]byte]*decodedToken)
+	}
+
+	if len(c.entries) >= size {
+		now := time.Now()
+		for existingKey, existing := range c.entries {
+			if !now.Before(existing.expires) {
+				delete(c.entries, existingKey)
+			}
+		}
+		if len(c.entries) >= size {
+			clear(c.entries)
+		}
+	}
+	c.entries[key] = entry
+}
+
+// decodeCacheSettings returns the TTL and size of the decode cache, a zero TTL disables it.
+func decodeCacheSettings(authorizationData *SessionAuthorizationConfiguration) (time.Duration, int) {
+	ttl := min(authorizationData.DecodeCacheTTL, MaximumDecodeCacheTTL)
+	return ttl, helpers.DefaultInt(authorizationData.DecodeCacheSize, DefaultDecodeCacheSize)
+}
+
+// requestDecodedSession returns the session decoded earlier in this request for the same token.
+func requestDecodedSession(ctx *gin.Context, token string) (*SessionHeader, *SessionClaims, string, bool) {
+	value, exists := ctx.Get(decodedSessionContextKey)
+	if !exists {
+		return nil, nil, "", false
+	}
+	entry, ok := value.(*decodedToken)
+	if !ok || entry.token != token {
+		return nil, nil, "", false
+	}
+	header, claims, group := entry.session()
+	return header, claims, group, true
+}
@@ -0,0 +1,147 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// keyCountingSessionManager counts the key lookups, one per decrypted token.
+type keyCountingSessionManager struct {
+	*mockSessionManager
+	lookups atomic.Int32
+}
+
+func (m *keyCountingSessionManager) GetOldSessionKey(keyId string) ([]byte, error) {
+	m.lookups.Add(1)
+	return m.mockSessionManager.GetOldSessionKey(keyId)
+}
+
+func TestDecodeCache(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mgr := &keyCountingSessionManager{mockSessionManager: newMockSessionManager(t)}
+	mgr.authorizationData.DecodeCacheTTL = time.Minute
+
+	issue := func(subject string) string {
+		ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+		ctx.Request = httptest.NewRequest(http.MethodPost, "/login", nil)
+		token, err := IssueBearerToken(ctx, mgr, "default", &SessionClaims{Claims: map[string]string{"subject": subject}})
+		if err != nil {
+			t.Fatalf("Failed to issue the bearer token: %v", err)
+		}
+		return token
+	}
+	newContext := func(token string) *gin.Context {
+		ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+		ctx.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+		ctx.Request.Header.Set(DefaultSessionAuthorizationHeaderName, token)
+		return ctx
+	}
+	extract := func(ctx *gin.Context, manager SessionManager) (*SessionClaims, error) {
+		_, claims, _, _, err := extractSession(ctx, manager)
+		return claims, err
+	}
+
+	t.Run("Tokens are decoded once within the TTL", func(t *testing.T) {
+		token := issue("user-1")
+		mgr.lookups.Store(0)
+
+		first, err := extract(newContext(token), mgr)
+		if err != nil {
+			t.Fatalf("Failed to extract the session: %v", err)
+		}
+		first.SetClaim("subject", "changed")
+
+		second, err := extract(newContext(token), mgr)
+		if err != nil || mgr.lookups.Load() != 1 {
+			t.Fatalf("Expected a cache hit, got %d decodes: %v", mgr.lookups.Load(), err)
+		}
+		if subject, _ := second.GetClaim("subject"); subject != "user-1" {
+			t.Errorf("Expected every caller to get its own claims, got %q", subject)
+		}
+	})
+
+	t.Run("Concurrent decodes of a token are collapsed", func(t *testing.T) {
+		token := issue("user-2")
+		mgr.lookups.Store(0)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := extract(newContext(token), mgr); err != nil {
+					t.Errorf("Failed to extract the session: %v", err)
+				}
+			}()
+		}
+		wg.Wait()
+		if mgr.lookups.Load() != 1 {
+			t.Errorf("Expected a single decode, got %d", mgr.lookups.Load())
+		}
+	})
+
+	t.Run("The request keeps its decoded session", func(t *testing.T) {
+		token := issue("user-3")
+		ctx := newContext(token)
+		if _, err := extract(ctx, mgr); err != nil {
+			t.Fatalf("Failed to extract the session: %v", err)
+		}
+
+		mgr.decodeCache.mu.Lock()
+		clear(mgr.decodeCache.entries)
+		mgr.decodeCache.mu.Unlock()
+		mgr.lookups.Store(0)
+		if _, err := extract(ctx, mgr); err != nil || mgr.lookups.Load() != 0 {
+			t.Errorf("Expected the request's decoded session to be reused, got %d decodes: %v", mgr.lookups.Load(), err)
+		}
+	})
+
+	t.Run("Failures are not cached", func(t *testing.T) {
+		token := issue("user-4")
+		tampered := token[:len(token)-4] + "AAAA"
+		mgr.lookups.Store(0)
+		for i := 0; i < 2; i++ {
+			if _, err := extract(newContext(tampered), mgr); err == nil {
+				t.Fatal("Expected the tampered token to be rejected")
+			}
+		}
+		if mgr.lookups.Load() != 2 {
+			t.Errorf("Expected both attempts to decode, got %d", mgr.lookups.Load())
+		}
+	})
+
+	t.Run("Session managers do not share their cache", func(t *testing.T) {
+		token := issue("user-5")
+		if _, err := extract(newContext(token), mgr); err != nil {
+			t.Fatalf("Failed to extract the session: %v", err)
+		}
+
+		other := newMockSessionManager(t)
+		other.authorizationData.DecodeCacheTTL = time.Minute
+		if _, err := extract(newContext(token), other); err == nil {
+			t.Error("Expected a token of another session manager to be rejected")
+		}
+	})
+
+	t.Run("Without a TTL every request decodes", func(t *testing.T) {
+		mgr.authorizationData.DecodeCacheTTL = 0
+		defer func() { mgr.authorizationData.DecodeCacheTTL = time.Minute }()
+
+		token := issue("user-6")
+		mgr.lookups.Store(0)
+		for i := 0; i < 2; i++ {
+			if _, err := extract(newContext(token), mgr); err != nil {
+				t.Fatalf("Failed to extract the session: %v", err)
+			}
+		}
+		if mgr.lookups.Load() != 2 {
+			t.Errorf("Expected two decodes, got %d", mgr.lookups.Load())
+		}
+	})
+}
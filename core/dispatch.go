@@ -0,0 +1,239 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/errors"
+	"github.com/grzegorzmaniak/gothic/helpers"
+	"github.com/grzegorzmaniak/gothic/validation"
+	"go.uber.org/zap"
+)
+
+const (
+	// DispatchMethod is the method of the synthetic request handed to dispatched handlers.
+	DispatchMethod = "DISPATCH"
+
+	// DispatchPathPrefix prefixes the handler name in the synthetic request's path.
+	DispatchPathPrefix = "/_dispatch/"
+)
+
+// dispatchFunc is a registered handler with its input and output types erased.
+type dispatchFunc func(ctx context.Context, input interface{}, claims *SessionClaims) (interface{}, *errors.AppError)
+
+var dispatchRegistry = struct {
+	sync.RWMutex
+	handlers map[string]dispatchFunc
+}{handlers: make(map[string]dispatchFunc)}
+
+// RegisterDispatch registers a handler under a name so it can be invoked internally with Dispatch. The handler
+// is wrapped with the same claims verification, RBAC and policy checks as ExecuteRoute, only the transport
+// (session extraction, CSRF, input binding and response writing) is skipped.
+func RegisterDispatch[InputType any, OutputType any, BaseRoute helpers.BaseRouteComponents](
+	name string,
+	baseRoute BaseRoute,
+	sessionConfig *APIConfiguration,
+	sessionManager SessionManager,
+	validationEngine *validation.Engine,
+	handlerFunc func(input *InputType, data *Handler[BaseRoute]) (*OutputType, *errors.AppError),
+) error {
+	if name == "" {
+		return fmt.Errorf("dispatch handler name can not be empty")
+	}
+	if sessionConfig == nil || sessionManager == nil || handlerFunc == nil {
+		return fmt.Errorf("dispatch handler '%s' requires a session config, session manager and handler", name)
+	}
+	if validationEngine == nil {
+		validationEngine = validation.NewEngine(nil)
+	}
+
+	fn := func(parent context.Context, rawInput interface{}, claims *SessionClaims) (interface{}, *errors.AppError) {
+		input, err := dispatchInput[InputType](rawInput)
+		if err != nil {
+			return nil, errors.NewValidationFailed(fmt.Sprintf("Invalid input for '%s'", name), err)
+		}
+
+		output, appErr := executeDispatch(parent, name, baseRoute, sessionConfig, sessionManager, validationEngine, input, claims, handlerFunc)
+		if appErr != nil || output == nil {
+			return nil, appErr
+		}
+		return output, nil
+	}
+
+	dispatchRegistry.Lock()
+	defer dispatchRegistry.Unlock()
+	if _, exists := dispatchRegistry.handlers[name]; exists {
+		return fmt.Errorf("dispatch handler '%s' is already registered", name)
+	}
+	dispatchRegistry.handlers[name] = fn
+	return nil
+}
+
+// UnregisterDispatch removes a handler registered with RegisterDispatch.
+func UnregisterDispatch(name string) {
+	dispatchRegistry.Lock()
+	defer dispatchRegistry.Unlock()
+	delete(dispatchRegistry.handlers, name)
+}
+
+// DispatchHandlers returns the names of the registered handlers, sorted.
+func DispatchHandlers() []string {
+	dispatchRegistry.RLock()
+	defer dispatchRegistry.RUnlock()
+
+	names := make([]string, 0, len(dispatchRegistry.handlers))
+	for name := range dispatchRegistry.handlers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Dispatch invokes a registered handler with the given input (InputType or *InputType) on behalf of the
+// claims, e.g., from a batch endpoint or a background job. Nil claims are treated as an anonymous caller, so
+// routes that require a session are rejected.
+func Dispatch(ctx context.Context, handlerName string, input interface{}, claims *SessionClaims) (interface{}, *errors.AppError) {
+	dispatchRegistry.RLock()
+	fn, ok := dispatchRegistry.handlers[handlerName]
+	dispatchRegistry.RUnlock()
+
+	if !ok {
+		return nil, errors.NewNotFound(fmt.Sprintf("Handler '%s' is not registered", handlerName), nil)
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	return fn(ctx, input, claims)
+}
+
+// DispatchAs is Dispatch with the output asserted to *OutputType.
+func DispatchAs[OutputType any](ctx context.Context, handlerName string, input interface{}, claims *SessionClaims) (*OutputType, *errors.AppError) {
+	output, appErr := Dispatch(ctx, handlerName, input, claims)
+	if appErr != nil || output == nil {
+		return nil, appErr
+	}
+
+	typed, ok := output.(*OutputType)
+	if !ok {
+		return nil, errors.NewInternalServerError(fmt.Sprintf("Handler '%s' returned %T", handlerName, output), nil)
+	}
+	return typed, nil
+}
+
+// Dispatch invokes a registered handler on behalf of this handler's session.
+func (h *Handler[BaseRoute]) Dispatch(handlerName string, input interface{}) (interface{}, *errors.AppError) {
+	return Dispatch(h.Context, handlerName, input, h.Claims)
+}
+
+// dispatchInput converts the caller's input into the handler's input type.
+func dispatchInput[InputType any](rawInput interface{}) (*InputType, error) {
+	switch input := rawInput.(type) {
+	case *InputType:
+		if input == nil {
+			return new(InputType), nil
+		}
+		return input, nil
+	case InputType:
+		return &input, nil
+	case nil:
+		return new(InputType), nil
+	default:
+		var expected *InputType
+		return nil, fmt.Errorf("expected %T, got %T", expected, rawInput)
+	}
+}
+
+// newDispatchContext builds the gin context handed to dispatched handlers. It carries the parent's request
+// context and request ID, anything written to it is discarded.
+func newDispatchContext(parent context.Context, handlerName string) *gin.Context {
+	requestContext := parent
+	if ginCtx, ok := parent.(*gin.Context); ok && ginCtx.Request != nil {
+		requestContext = ginCtx.Request.Context()
+	}
+
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Request = httptest.NewRequest(DispatchMethod, DispatchPathPrefix+handlerName, http.NoBody).WithContext(requestContext)
+
+	if requestID := helpers.GetRequestID(parent); requestID != "" {
+		ctx.Set(helpers.RequestIDContextKey, requestID)
+	}
+	helpers.EnsureRequestID(ctx)
+	return ctx
+}
+
+// executeDispatch mirrors ExecuteRoute for an internal call.
+func executeDispatch[InputType any, OutputType any, BaseRoute helpers.BaseRouteComponents](
+	parent context.Context,
+	handlerName string,
+	baseRoute BaseRoute,
+	sessionConfig *APIConfiguration,
+	sessionManager SessionManager,
+	validationEngine *validation.Engine,
+	input *InputType,
+	claims *SessionClaims,
+	handlerFunc func(input *InputType, data *Handler[BaseRoute]) (*OutputType, *errors.AppError),
+) (*OutputType, *errors.AppError) {
+	ctx := newDispatchContext(parent, handlerName)
+	if appErr := checkMaintenance(ctx, sessionConfig); appErr != nil {
+		return nil, appErr
+	}
+
+	// - Stage 1: Verify the caller's claims, there is no transport to extract a session from
+	if claims != nil && !claims.HasSession {
+		claims = nil
+	}
+	if sessionConfig.SessionRequired && claims == nil {
+		return nil, hiddenByFeatureFlag(ctx, sessionManager, sessionConfig, errors.NewUnauthorized("", nil))
+	}
+	group := ""
+	if claims != nil {
+		var appErr *errors.AppError
+		group, _ = claims.GetClaim(SessionModeClaim)
+		if _, claims, group, appErr = _verifyClaimsAndHandleSessionState(ctx, sessionManager, sessionConfig, claims, nil, group); appErr != nil {
+			return nil, hiddenByFeatureFlag(ctx, sessionManager, sessionConfig, appErr)
+		}
+	}
+
+	// - Feature flags, an unlaunched handler can not be reached internally either
+	if flagErr := processFeatureFlag(ctx, sessionManager, sessionConfig, claims, group); flagErr != nil {
+		return nil, flagErr
+	}
+
+	// - Step-up authentication, the caller's session must already be strong enough
+	if levelErr := processAuthLevel(ctx, sessionConfig, claims); levelErr != nil {
+		return nil, levelErr
+	}
+
+	// - Rbac
+	if rbacErr := processRbac(ctx, sessionManager, sessionConfig, claims); rbacErr != nil {
+		helpers.Logger(ctx).Debug("RBAC processing failed for dispatched handler", zap.String("handler", handlerName), zap.Error(rbacErr))
+		return nil, rbacErr
+	}
+
+	// - Stage 2: Validate the input with the same rules as a bound request
+	if err := validationEngine.ValidateStruct(*input); err != nil {
+		return nil, validationEngine.ValidationFailed("Input validation failed", err)
+	}
+
+	// - Attribute based policies
+	if policyErr := processPolicy(ctx, sessionConfig, claims, input); policyErr != nil {
+		return nil, policyErr
+	}
+
+	// - Stage 3: Call the handler, the output is returned as is
+	handlerData := newHandler(ctx, baseRoute, sessionConfig, sessionManager, nil, claims, nil, group)
+	defer handlerData.closeTasks()
+
+	output, appErr := handlerFunc(input, handlerData)
+	if appErr != nil {
+		helpers.Logger(ctx).Debug("Error returned from dispatched handler", zap.String("handler", handlerName), zap.Error(appErr))
+		return nil, appErr
+	}
+	return output, nil
+}
@@ -0,0 +1,86 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/errors"
+	"github.com/grzegorzmaniak/gothic/rbac"
+)
+
+type dispatchInputType struct {
+	Name string `validate:"required"`
+}
+
+type dispatchOutputType struct {
+	Greeting string
+	Method   string
+}
+
+func TestDispatch(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mgr := newMockSessionManager(t)
+	user := &SessionClaims{HasSession: true, Claims: map[string]string{SessionModeClaim: "user", "subject": "user-1"}}
+	admin := &SessionClaims{HasSession: true, Claims: map[string]string{SessionModeClaim: "admin", "subject": "admin-1"}}
+
+	greet := func(input *dispatchInputType, data *Handler[testBaseRoute]) (*dispatchOutputType, *errors.AppError) {
+		return &dispatchOutputType{Greeting: "hello " + input.Name, Method: data.Context.Request.Method}, nil
+	}
+
+	config := AuthenticatedJSONAPI().AllowModes("admin").WithPolicy(func(_ *gin.Context, _ *SessionClaims, input interface{}) (*rbac.AttributeDecision, error) {
+		if input.(*dispatchInputType).Name == "forbidden" {
+			return rbac.Deny(rbac.DenyReason{Code: "forbidden_name"}), nil
+		}
+		return rbac.Allow(), nil
+	})
+
+	if err := RegisterDispatch("test.greet", testBaseRoute{}, config, mgr, nil, greet); err != nil {
+		t.Fatalf("Failed to register dispatch handler: %v", err)
+	}
+	defer UnregisterDispatch("test.greet")
+
+	t.Run("Duplicate names are rejected", func(t *testing.T) {
+		if err := RegisterDispatch("test.greet", testBaseRoute{}, config, mgr, nil, greet); err == nil {
+			t.Error("Expected an error for a duplicate name")
+		}
+	})
+
+	t.Run("Authorized caller gets the handler output", func(t *testing.T) {
+		output, appErr := DispatchAs[dispatchOutputType](context.Background(), "test.greet", dispatchInputType{Name: "bob"}, admin)
+		if appErr != nil {
+			t.Fatalf("Expected no error, got %v", appErr)
+		}
+		if output.Greeting != "hello bob" || output.Method != DispatchMethod {
+			t.Errorf("Expected 'hello bob' over %s, got %+v", DispatchMethod, output)
+		}
+	})
+
+	tests := []struct {
+		name   string
+		handle string
+		input  interface{}
+		claims *SessionClaims
+		code   int
+	}{
+		{name: "Unknown handler", handle: "test.missing", input: &dispatchInputType{Name: "bob"}, claims: admin, code: http.StatusNotFound},
+		{name: "Anonymous caller", handle: "test.greet", input: &dispatchInputType{Name: "bob"}, claims: nil, code: http.StatusUnauthorized},
+		{name: "Mode not allowed", handle: "test.greet", input: &dispatchInputType{Name: "bob"}, claims: user, code: http.StatusUnauthorized},
+		{name: "Invalid input", handle: "test.greet", input: &dispatchInputType{}, claims: admin, code: http.StatusUnprocessableEntity},
+		{name: "Wrong input type", handle: "test.greet", input: "bob", claims: admin, code: http.StatusUnprocessableEntity},
+		{name: "Policy denied", handle: "test.greet", input: &dispatchInputType{Name: "forbidden"}, claims: admin, code: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, appErr := Dispatch(context.Background(), tt.handle, tt.input, tt.claims)
+			if appErr == nil || output != nil {
+				t.Fatalf("Expected an error without output, got %v", output)
+			}
+			if appErr.Code != tt.code {
+				t.Errorf("Expected %d, got %d", tt.code, appErr.Code)
+			}
+		})
+	}
+}
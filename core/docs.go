@@ -0,0 +1,178 @@
+package core
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/grzegorzmaniak/gothic/errors"
+	"github.com/grzegorzmaniak/gothic/helpers"
+)
+
+// DocsUI selects the documentation renderer served by RegisterDocs.
+type DocsUI string
+
+const (
+	DocsUISwagger DocsUI = "swagger"
+	DocsUIRedoc   DocsUI = "redoc"
+)
+
+const (
+	// DefaultDocsEnvVar gates the documentation routes, they are only registered when it is "1" or "true".
+	DefaultDocsEnvVar = "GOTHIC_DOCS"
+
+	DefaultDocsTitle      = "API Documentation"
+	DefaultDocsSpecPath   = "/openapi.json"
+	DefaultSwaggerUIAsset = "https://unpkg.com/swagger-ui-dist@5"
+	DefaultRedocAsset     = "https://cdn.redoc.ly/redoc/latest/bundles/redoc.standalone.js"
+)
+
+// DocsConfig configures the documentation handler.
+type DocsConfig struct {
+	// Title of the documentation page (Default: DefaultDocsTitle)
+	Title string
+
+	// UI selects Swagger UI or Redoc (Default: DocsUISwagger)
+	UI DocsUI
+
+	// SpecProvider returns the OpenAPI document (JSON), it is called on every request so the spec always
+	// reflects the live route registry (Default: OpenAPISpecProvider for the constructor's routes)
+	SpecProvider func() ([]byte, error)
+
+	// Enabled gates the routes, they are not registered at all when it returns false, so internal
+	// documentation is never exposed in public deployments (Default: DocsEnabledFromEnv(DefaultDocsEnvVar))
+	Enabled func() bool
+
+	// RouteConfig protects the documentation routes, e.g., AdminRoute(docsPermission).WithoutCsrf().
+	// The documentation is a page load, so CSRF is not required (Default: a required session without CSRF)
+	RouteConfig *APIConfiguration
+
+	// AssetURL overrides the Swagger UI (base URL) / Redoc (script URL) assets, e.g., to self-host them.
+	AssetURL string
+}
+
+// DocsEnabledFromEnv returns a gate that is open when the environment variable is "1" or "true".
+func DocsEnabledFromEnv(envVar string) func() bool {
+	return func() bool {
+		value := strings.ToLower(strings.TrimSpace(os.Getenv(envVar)))
+		return value == "1" || value == "true"
+	}
+}
+
+var swaggerTemplate = template.Must(template.New("swagger").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<link rel="stylesheet" href="{{.AssetURL}}/swagger-ui.css">
+</head>
+<body>
+<div id="swagger-ui"></div>
+<script src="{{.AssetURL}}/swagger-ui-bundle.js"></script>
+<script>window.ui = SwaggerUIBundle({url: "{{.SpecURL}}", dom_id: "#swagger-ui"});</script>
+</body>
+</html>`))
+
+var redocTemplate = template.Must(template.New("redoc").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+</head>
+<body>
+<redoc spec-url="{{.SpecURL}}"></redoc>
+<script src="{{.AssetURL}}"></script>
+</body>
+</html>`))
+
+type docsPage struct {
+	Title    string
+	SpecURL  string
+	AssetURL string
+}
+
+// renderDocsPage renders the HTML page for the configured UI.
+func renderDocsPage(config DocsConfig, specURL string) ([]byte, error) {
+	page := docsPage{
+		Title:   helpers.DefaultString(config.Title, DefaultDocsTitle),
+		SpecURL: specURL,
+	}
+
+	tmpl := swaggerTemplate
+	page.AssetURL = helpers.DefaultString(config.AssetURL, DefaultSwaggerUIAsset)
+	if config.UI == DocsUIRedoc {
+		tmpl = redocTemplate
+		page.AssetURL = helpers.DefaultString(config.AssetURL, DefaultRedocAsset)
+	}
+
+	var builder strings.Builder
+	if err := tmpl.Execute(&builder, page); err != nil {
+		return nil, fmt.Errorf("failed to render documentation page: %w", err)
+	}
+	return []byte(builder.String()), nil
+}
+
+// docsRouteConfig returns a copy of the configured route config with ManualResponse set, or the default.
+func docsRouteConfig(config DocsConfig) *APIConfiguration {
+	if config.RouteConfig == nil {
+		return AuthenticatedJSONAPI().WithoutCsrf().WithManualResponse()
+	}
+
+	routeConfig := *config.RouteConfig
+	routeConfig.ManualResponse = true
+	return &routeConfig
+}
+
+// RegisterDocs registers the documentation page at basePath and the spec at basePath + DefaultDocsSpecPath.
+// Both routes go through the executor, so the same session and RBAC checks as any other route apply.
+// It returns false if the routes were not registered because the environment gate is closed.
+func RegisterDocs[BaseRoute helpers.BaseRouteComponents](
+	ctor *RouteConstructor[BaseRoute],
+	basePath string,
+	config DocsConfig,
+) (bool, error) {
+	enabled := config.Enabled
+	if enabled == nil {
+		enabled = DocsEnabledFromEnv(DefaultDocsEnvVar)
+	}
+	if !enabled() {
+		return false, nil
+	}
+
+	specProvider := config.SpecProvider
+	if specProvider == nil {
+		specProvider = OpenAPISpecProvider(ctor, OpenAPIConfig{Title: config.Title})
+	}
+
+	// - The root stays "/", gin rejects paths that don't start with one
+	basePath = strings.TrimSuffix(basePath, "/")
+	if basePath == "" {
+		basePath = "/"
+	}
+	specURL := path.Join(basePath, DefaultDocsSpecPath)
+	page, err := renderDocsPage(config, specURL)
+	if err != nil {
+		return false, err
+	}
+
+	routeConfig := docsRouteConfig(config)
+
+	handleRoute(ctor, http.MethodGet, basePath, routeConfig, func(_ *struct{}, data *Handler[BaseRoute]) (*struct{}, *errors.AppError) {
+		data.Context.Data(http.StatusOK, "text/html; charset=utf-8", page)
+		return nil, nil
+	})
+
+	handleRoute(ctor, http.MethodGet, specURL, routeConfig, func(_ *struct{}, data *Handler[BaseRoute]) (*struct{}, *errors.AppError) {
+		spec, specErr := specProvider()
+		if specErr != nil {
+			return nil, errors.NewInternalServerError("Failed to generate the API specification", specErr)
+		}
+		data.Context.Data(http.StatusOK, "application/json", spec)
+		return nil, nil
+	})
+
+	return true, nil
+}
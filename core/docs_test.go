@@ -0,0 +1,94 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRegisterDocs(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	spec := func() ([]byte, error) { return []byte(`{"openapi":"3.0.3"}`), nil }
+
+	serve := func(router *gin.Engine, target string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, target, nil))
+		return recorder
+	}
+
+	t.Run("Closed gate registers nothing", func(t *testing.T) {
+		router := gin.New()
+		ctor := NewRouteConstructor(router, testBaseRoute{}, newMockSessionManager(t), nil)
+		registered, err := RegisterDocs(ctor, "/docs", DocsConfig{SpecProvider: spec, Enabled: func() bool { return false }})
+		if registered || err != nil {
+			t.Fatalf("Expected nothing to be registered, got %v, %v", registered, err)
+		}
+		if recorder := serve(router, "/docs"); recorder.Code != http.StatusNotFound {
+			t.Errorf("Expected 404, got %d", recorder.Code)
+		}
+	})
+
+	t.Run("Default route config requires a session", func(t *testing.T) {
+		router := gin.New()
+		ctor := NewRouteConstructor(router, testBaseRoute{}, newMockSessionManager(t), nil)
+		if _, err := RegisterDocs(ctor, "/docs", DocsConfig{SpecProvider: spec, Enabled: func() bool { return true }}); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if recorder := serve(router, "/docs/openapi.json"); recorder.Code != http.StatusUnauthorized {
+			t.Errorf("Expected 401, got %d", recorder.Code)
+		}
+	})
+
+	t.Run("Serves the UI and the live spec", func(t *testing.T) {
+		router := gin.New()
+		ctor := NewRouteConstructor(router, testBaseRoute{}, newMockSessionManager(t), nil)
+		_, err := RegisterDocs(ctor, "/docs/", DocsConfig{
+			SpecProvider: spec,
+			Enabled:      func() bool { return true },
+			RouteConfig:  PublicRoute(),
+			UI:           DocsUIRedoc,
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		page := serve(router, "/docs")
+		if page.Code != http.StatusOK || !strings.Contains(page.Body.String(), `spec-url="/docs/openapi.json"`) {
+			t.Errorf("Unexpected page response %d: %s", page.Code, page.Body.String())
+		}
+
+		specResponse := serve(router, "/docs/openapi.json")
+		if specResponse.Code != http.StatusOK || specResponse.Body.String() != `{"openapi":"3.0.3"}` {
+			t.Errorf("Unexpected spec response %d: %s", specResponse.Code, specResponse.Body.String())
+		}
+	})
+
+	t.Run("Docs can be served from the root", func(t *testing.T) {
+		router := gin.New()
+		ctor := NewRouteConstructor(router, testBaseRoute{}, newMockSessionManager(t), nil)
+		if _, err := RegisterDocs(ctor, "/", DocsConfig{SpecProvider: spec, Enabled: func() bool { return true }, RouteConfig: PublicRoute()}); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if page := serve(router, "/"); page.Code != http.StatusOK || !strings.Contains(page.Body.String(), `openapi.json`) {
+			t.Errorf("Unexpected page response %d: %s", page.Code, page.Body.String())
+		}
+		if specResponse := serve(router, "/openapi.json"); specResponse.Code != http.StatusOK {
+			t.Errorf("Unexpected spec response %d: %s", specResponse.Code, specResponse.Body.String())
+		}
+	})
+
+	t.Run("Environment gate", func(t *testing.T) {
+		t.Setenv("GOTHIC_DOCS_TEST", "true")
+		if !DocsEnabledFromEnv("GOTHIC_DOCS_TEST")() {
+			t.Error("Expected the gate to be open")
+		}
+		t.Setenv("GOTHIC_DOCS_TEST", "")
+		if DocsEnabledFromEnv("GOTHIC_DOCS_TEST")() {
+			t.Error("Expected the gate to be closed")
+		}
+	})
+}
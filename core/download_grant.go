@@ -0,0 +1,326 @@
+package core
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eko/gocache/lib/v4/store"
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/errors"
+	"github.com/grzegorzmaniak/gothic/helpers"
+	"go.uber.org/zap"
+)
+
+const (
+	DownloadGrantVersion        = "DG1"             // Version of the grant token format
+	DownloadGrantDelimiter      = "."               // Separates the version, key id and payload
+	DownloadGrantQueryParam     = "grant"           // Query parameter the grant is read from
+	DownloadGrantCacheKeyPrefix = "download_grant:" // Key: download_grant:<grantId>
+	DefaultDownloadGrantTTL     = 5 * time.Minute   // Time a grant can be redeemed in
+	MaximumDownloadGrantTTL     = 24 * time.Hour    // Grants are meant to be narrow, not a second session
+	DownloadGrantIdSize         = 32                // Size of the random grant id
+	MaximumDownloadGrantSize    = 2048              // Grants larger than this are rejected before decrypting
+)
+
+// DownloadGrant is a narrow, single-use authorization to run one operation on one resource, e.g., stream
+// an export that takes longer to prepare than the session's refresh window.
+type DownloadGrant struct {
+	Id        string            `json:"id"`
+	Subject   string            `json:"subject"`
+	Operation string            `json:"operation"`
+	Resource  string            `json:"resource"`
+	IssuedAt  int64             `json:"issuedAt"`
+	ExpiresAt int64             `json:"expiresAt"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+}
+
+// IsExpired checks if the grant can no longer be redeemed.
+func (g *DownloadGrant) IsExpired() bool {
+	return g.ExpiresAt < time.Now().Unix()
+}
+
+// DownloadGrantEvent is passed to the audit hook whenever a grant is issued or redeemed (successfully or not).
+type DownloadGrantEvent struct {
+	Action    string         `json:"action"` // "issued" or "redeemed"
+	Grant     *DownloadGrant `json:"grant,omitempty"`
+	RequestID string         `json:"request_id"`
+	Error     error          `json:"-"`
+}
+
+// DownloadGrantAudit is called for every grant event, by default events are logged.
+var DownloadGrantAudit = func(ctx context.Context, event DownloadGrantEvent) {
+	fields := []zap.Field{zap.String("action", event.Action)}
+	if event.Grant != nil {
+		fields = append(fields,
+			zap.String("grant_id", event.Grant.Id),
+			zap.String("subject", event.Grant.Subject),
+			zap.String("operation", event.Grant.Operation),
+			zap.String("resource", event.Grant.Resource))
+	}
+	if event.Error != nil {
+		fields = append(fields, zap.Error(event.Error))
+	}
+	helpers.Logger(ctx).Info("Download grant event", fields...)
+}
+
+// redeemedDownloadGrants records grants redeemed by this process until they expire, the cache may apply writes
+// asynchronously (e.g., ristretto), so it alone can not close the replay window. The cache still covers other
+// instances when it is shared.
+var redeemedDownloadGrants = struct {
+	sync.Mutex
+	expiries map[string]int64
+}{expiries: make(map[string]int64)}
+
+func auditDownloadGrant(ctx context.Context, action string, grant *DownloadGrant, err error) {
+	if DownloadGrantAudit == nil {
+		return
+	}
+	DownloadGrantAudit(ctx, DownloadGrantEvent{
+		Action:    action,
+		Grant:     grant,
+		RequestID: helpers.GetRequestID(ctx),
+		Error:     err,
+	})
+}
+
+// IssueDownloadGrant mints a single-use grant for the session's subject, bound to the operation and resource.
+// The returned token is encrypted with the current session key.
+func IssueDownloadGrant(
+	ctx context.Context,
+	sessionManager SessionManager,
+	claims *SessionClaims,
+	operation string,
+	resource string,
+	ttl time.Duration,
+	metadata map[string]string,
+) (string, error) {
+	if sessionManager == nil {
+		return "", fmt.Errorf("session manager is nil")
+	}
+	if claims == nil || !claims.HasSession {
+		return "", fmt.Errorf("a session is required to issue a download grant")
+	}
+	if operation == "" || resource == "" {
+		return "", fmt.Errorf("download grant operation and resource are required")
+	}
+
+	ttl = helpers.DefaultTimeDuration(ttl, DefaultDownloadGrantTTL)
+	if ttl < 0 || ttl > MaximumDownloadGrantTTL {
+		return "", fmt.Errorf("download grant ttl %s must be between 0 and %s", ttl, MaximumDownloadGrantTTL)
+	}
+
+	subject, err := sessionManager.GetSubjectIdentifier(claims)
+	if err != nil {
+		return "", fmt.Errorf("failed to get subject identifier: %w", err)
+	}
+
+	grantId, err := helpers.GenerateID(DownloadGrantIdSize)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate download grant id: %w", err)
+	}
+
+	now := time.Now()
+	grant := &DownloadGrant{
+		Id:        grantId,
+		Subject:   subject,
+		Operation: operation,
+		Resource:  resource,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(ttl).Unix(),
+		Metadata:  metadata,
+	}
+
+	token, err := encodeDownloadGrant(sessionManager, grant)
+	if err != nil {
+		return "", err
+	}
+
+	auditDownloadGrant(ctx, "issued", grant, nil)
+	return token, nil
+}
+
+// encodeDownloadGrant encrypts the grant with the current session key.
+func encodeDownloadGrant(sessionManager SessionManager, grant *DownloadGrant) (string, error) {
+	marshaledGrant, err := json.Marshal(grant)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal download grant: %w", err)
+	}
+
+	sessionKey, keyId, err := sessionManager.GetSessionKey()
+	if err != nil {
+		return "", fmt.Errorf("failed to get session key: %w", err)
+	}
+	if strings.Contains(keyId, DownloadGrantDelimiter) || keyId == "" {
+		return "", fmt.Errorf("session key id can not be empty or contain '%s'", DownloadGrantDelimiter)
+	}
+
+	encryptedGrant, err := helpers.SymmetricEncrypt(sessionKey, marshaledGrant, []byte(keyId+DownloadGrantVersion))
+	if err != nil {
+		return "", fmt.Errorf("failed to encrypt download grant: %w", err)
+	}
+	keyUsage.issued(keyId)
+
+	return strings.Join([]string{
+		DownloadGrantVersion,
+		keyId,
+		base64.RawURLEncoding.EncodeToString(encryptedGrant),
+	}, DownloadGrantDelimiter), nil
+}
+
+// decodeDownloadGrant decrypts and validates a grant token, it does not check whether the grant was used.
+func decodeDownloadGrant(sessionManager SessionManager, token string, operation string) (*DownloadGrant, error) {
+	if token == "" || len(token) > MaximumDownloadGrantSize {
+		return nil, fmt.Errorf("download grant is missing or has an invalid size")
+	}
+
+	parts := strings.SplitN(token, DownloadGrantDelimiter, 3)
+	if len(parts) != 3 || parts[0] != DownloadGrantVersion {
+		return nil, fmt.Errorf("invalid download grant format")
+	}
+	keyId, encodedGrant := parts[1], parts[2]
+
+	sessionKey, err := sessionManager.GetOldSessionKey(keyId)
+	if err != nil {
+		keyUsage.failed(UnknownKeyId)
+		return nil, fmt.Errorf("failed to get session key for download grant: %w", err)
+	}
+
+	encryptedGrant, err := base64.RawURLEncoding.DecodeString(encodedGrant)
+	if err != nil {
+		return nil, fmt.Errorf("failed to base64-decode download grant: %w", err)
+	}
+
+	decryptedGrant, err := helpers.SymmetricDecrypt(sessionKey, encryptedGrant, []byte(keyId+DownloadGrantVersion))
+	if err != nil {
+		keyUsage.failed(keyId)
+		return nil, fmt.Errorf("failed to decrypt download grant: %w", err)
+	}
+	keyUsage.validated(keyId)
+
+	var grant DownloadGrant
+	if err = json.Unmarshal(decryptedGrant, &grant); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal download grant: %w", err)
+	}
+
+	if grant.IsExpired() {
+		return &grant, fmt.Errorf("download grant has expired")
+	}
+	if grant.Operation != operation {
+		return &grant, fmt.Errorf("download grant is for operation '%s', not '%s'", grant.Operation, operation)
+	}
+
+	return &grant, nil
+}
+
+// markDownloadGrantUsed records the grant as redeemed, it fails if the grant was already used. Without a cache
+// single-use can not be guaranteed, so redemption is refused.
+func markDownloadGrantUsed(ctx context.Context, sessionManager SessionManager, grant *DownloadGrant) error {
+	cacheInstance, err := sessionManager.GetCache()
+	if err != nil {
+		return fmt.Errorf("failed to get cache for download grant: %w", err)
+	}
+	if cacheInstance == nil {
+		return fmt.Errorf("a cache is required to redeem download grants")
+	}
+
+	redeemedDownloadGrants.Lock()
+	defer redeemedDownloadGrants.Unlock()
+
+	// - Drop the grants that expired, they are rejected before reaching this point
+	now := time.Now().Unix()
+	for id, expiresAt := range redeemedDownloadGrants.expiries {
+		if expiresAt < now {
+			delete(redeemedDownloadGrants.expiries, id)
+		}
+	}
+
+	cacheKey := DownloadGrantCacheKeyPrefix + grant.Id
+	if _, used := redeemedDownloadGrants.expiries[grant.Id]; used {
+		return fmt.Errorf("download grant has already been used")
+	}
+	if _, getErr := cacheInstance.Get(ctx, cacheKey); getErr == nil {
+		return fmt.Errorf("download grant has already been used")
+	}
+	redeemedDownloadGrants.expiries[grant.Id] = grant.ExpiresAt
+
+	// - Keep the marker a little longer than the grant itself, expired grants are rejected anyway
+	ttl := time.Until(time.Unix(grant.ExpiresAt, 0)) + time.Minute
+	if err = cacheInstance.Set(ctx, cacheKey, []byte{1}, store.WithExpiration(ttl)); err != nil {
+		return fmt.Errorf("failed to mark download grant as used: %w", err)
+	}
+
+	return nil
+}
+
+// RedeemDownloadGrant validates the grant token for the operation and marks it as used.
+func RedeemDownloadGrant(ctx context.Context, sessionManager SessionManager, token string, operation string) (*DownloadGrant, error) {
+	if sessionManager == nil {
+		return nil, fmt.Errorf("session manager is nil")
+	}
+
+	grant, err := decodeDownloadGrant(sessionManager, token, operation)
+	if err == nil {
+		err = markDownloadGrantUsed(ctx, sessionManager, grant)
+	}
+
+	auditDownloadGrant(ctx, "redeemed", grant, err)
+	if err != nil {
+		return nil, err
+	}
+	return grant, nil
+}
+
+// IssueDownloadGrant mints a single-use grant for this session's subject, see core.IssueDownloadGrant.
+func (h *Handler[BaseRoute]) IssueDownloadGrant(operation string, resource string, ttl time.Duration) (string, error) {
+	return IssueDownloadGrant(h.Context, h.SessionManager, h.Claims, operation, resource, ttl, nil)
+}
+
+// ExecuteDownloadRoute redeems the grant passed in the DownloadGrantQueryParam and hands it to the handler,
+// which streams the resource. No session is required, the grant is the only authorization, so it is bound to
+// the operation and can only be used once. Of sessionConfig, only the route level options apply, e.g.,
+// MaintenanceExempt or ErrorFormat.
+func ExecuteDownloadRoute[BaseRoute helpers.BaseRouteComponents](
+	ctx *gin.Context,
+	baseRoute BaseRoute,
+	sessionConfig *APIConfiguration,
+	sessionManager SessionManager,
+	operation string,
+	handlerFunc func(grant *DownloadGrant, data *Handler[BaseRoute]) *errors.AppError,
+) {
+	helpers.EnsureRequestID(ctx)
+	helpers.SetResponseFormat(ctx, sessionConfig.ErrorFormat)
+
+	// - Before redeeming, so the grant can still be used once maintenance is over
+	if appErr := checkMaintenance(ctx, sessionConfig); appErr != nil {
+		helpers.ErrorResponse(ctx, appErr)
+		return
+	}
+
+	// - Feature flags, evaluated without a session as the grant is the only authorization
+	if flagErr := processFeatureFlag(ctx, sessionManager, sessionConfig, nil, ""); flagErr != nil {
+		helpers.ErrorResponse(ctx, flagErr)
+		return
+	}
+
+	grant, err := RedeemDownloadGrant(ctx, sessionManager, ctx.Query(DownloadGrantQueryParam), operation)
+	if err != nil {
+		helpers.Logger(ctx).Debug("Download grant rejected", zap.Error(err))
+		helpers.ErrorResponse(ctx, errors.NewUnauthorized("Download grant is invalid, expired or already used", err))
+		return
+	}
+
+	handlerData := newHandler(ctx, baseRoute, sessionConfig, sessionManager, nil, nil, nil, "")
+	defer handlerData.closeTasks()
+
+	if appErr := handlerFunc(grant, handlerData); appErr != nil {
+		helpers.Logger(ctx).Debug("Error returned from download handler", zap.Error(appErr))
+		if !ctx.Writer.Written() {
+			helpers.ErrorResponse(ctx, appErr)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/errors"
+)
+
+func TestDownloadGrant(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mgr := newMockSessionManager(t)
+	claims := &SessionClaims{Claims: map[string]string{"subject": "user-1"}, HasSession: true}
+
+	issue := func(t *testing.T, operation string, ttl time.Duration) string {
+		t.Helper()
+		token, err := IssueDownloadGrant(context.Background(), mgr, claims, operation, "exports/1.csv", ttl, nil)
+		if err != nil {
+			t.Fatalf("Failed to issue download grant: %v", err)
+		}
+		return token
+	}
+
+	download := func(token string) (*httptest.ResponseRecorder, *DownloadGrant) {
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+		ctx.Request = httptest.NewRequest(http.MethodGet, "/download?"+DownloadGrantQueryParam+"="+url.QueryEscape(token), nil)
+
+		var redeemed *DownloadGrant
+		ExecuteDownloadRoute(ctx, testBaseRoute{}, PublicRoute(), mgr, "export", func(grant *DownloadGrant, data *Handler[testBaseRoute]) *errors.AppError {
+			redeemed = grant
+			data.Context.String(http.StatusOK, grant.Resource)
+			return nil
+		})
+		return recorder, redeemed
+	}
+
+	t.Run("Grant is redeemed once", func(t *testing.T) {
+		token := issue(t, "export", time.Minute)
+		if !strings.HasPrefix(token, DownloadGrantVersion+DownloadGrantDelimiter) {
+			t.Errorf("Expected the token to start with %s, got %s", DownloadGrantVersion, token)
+		}
+
+		recorder, grant := download(token)
+		if recorder.Code != http.StatusOK || grant == nil {
+			t.Fatalf("Expected 200 with a grant, got %d", recorder.Code)
+		}
+		if grant.Subject != "user-1" || recorder.Body.String() != "exports/1.csv" {
+			t.Errorf("Expected the grant for user-1 on exports/1.csv, got %s on %s", grant.Subject, recorder.Body.String())
+		}
+
+		recorder, grant = download(token)
+		if recorder.Code != http.StatusUnauthorized || grant != nil {
+			t.Errorf("Expected a reused grant to be rejected, got %d", recorder.Code)
+		}
+	})
+
+	t.Run("Grant for another operation is rejected", func(t *testing.T) {
+		recorder, grant := download(issue(t, "backup", time.Minute))
+		if recorder.Code != http.StatusUnauthorized || grant != nil {
+			t.Errorf("Expected 401, got %d", recorder.Code)
+		}
+	})
+
+	t.Run("Expired grant is rejected", func(t *testing.T) {
+		token, err := encodeDownloadGrant(mgr, &DownloadGrant{Id: "expired", Operation: "export", ExpiresAt: time.Now().Add(-time.Minute).Unix()})
+		if err != nil {
+			t.Fatalf("Failed to encode download grant: %v", err)
+		}
+
+		recorder, grant := download(token)
+		if recorder.Code != http.StatusUnauthorized || grant != nil {
+			t.Errorf("Expected 401, got %d", recorder.Code)
+		}
+	})
+
+	t.Run("Tampered or missing grant is rejected", func(t *testing.T) {
+		token := issue(t, "export", time.Minute)
+		for _, candidate := range []string{"", token[:len(token)-2] + "AA", "DG2" + token[3:]} {
+			if recorder, _ := download(candidate); recorder.Code != http.StatusUnauthorized {
+				t.Errorf("Expected 401 for %q, got %d", candidate, recorder.Code)
+			}
+		}
+	})
+
+	t.Run("Issuing requires a session and a bounded ttl", func(t *testing.T) {
+		if _, err := IssueDownloadGrant(context.Background(), mgr, &SessionClaims{}, "export", "r", 0, nil); err == nil {
+			t.Error("Expected an error without a session")
+		}
+		if _, err := IssueDownloadGrant(context.Background(), mgr, claims, "export", "r", MaximumDownloadGrantTTL+time.Hour, nil); err == nil {
+			t.Error("Expected an error for a ttl above the maximum")
+		}
+	})
+
+	t.Run("Audit hook sees every event", func(t *testing.T) {
+		original := DownloadGrantAudit
+		defer func() { DownloadGrantAudit = original }()
+
+		var actions []string
+		DownloadGrantAudit = func(_ context.Context, event DownloadGrantEvent) {
+			actions = append(actions, event.Action)
+		}
+
+		download(issue(t, "export", time.Minute))
+		if strings.Join(actions, ",") != "issued,redeemed" {
+			t.Errorf("Expected issued,redeemed, got %v", actions)
+		}
+	})
+}
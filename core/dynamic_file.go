@@ -0,0 +1,115 @@
+package core
+
+import (
+	stderrors "errors"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/errors"
+	"github.com/grzegorzmaniak/gothic/helpers"
+	"github.com/grzegorzmaniak/gothic/validation"
+	"go.uber.org/zap"
+)
+
+// dynamicRulesFile holds the FieldRules loaded from a file, reloading them when the file changes.
+type dynamicRulesFile struct {
+	sync.Mutex
+	path    string
+	rules   validation.FieldRules
+	modTime time.Time
+	version int
+}
+
+func loadDynamicRulesFile(path string) (*dynamicRulesFile, error) {
+	if path == "" {
+		return nil, nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat field rules: %w", err)
+	}
+	rules, err := validation.LoadFieldRules(path)
+	if err != nil {
+		return nil, err
+	}
+	return &dynamicRulesFile{path: path, rules: rules, modTime: info.ModTime()}, nil
+}
+
+// current returns the rules and their version, the version changes with every reload so the reflected struct of
+// the previous rules is never reused. Rules that fail to reload are returned with the error, the previous ones
+// are kept.
+func (f *dynamicRulesFile) current(reload bool) (validation.FieldRules, string, error) {
+	if f == nil {
+		return nil, "", nil
+	}
+	f.Lock()
+	defer f.Unlock()
+
+	if !reload {
+		return f.rules, strconv.Itoa(f.version), nil
+	}
+
+	var err error
+	if info, statErr := os.Stat(f.path); statErr == nil && !info.ModTime().Equal(f.modTime) {
+		// - The new modification time is kept even on failure, so a broken file is not re-read on every request
+		f.modTime = info.ModTime()
+		var rules validation.FieldRules
+		if rules, err = validation.LoadFieldRules(f.path); err == nil {
+			f.rules = rules
+			f.version++
+		}
+	}
+	return f.rules, strconv.Itoa(f.version), err
+}
+
+// DynamicFromFile registers a DYNAMIC route whose input and output FieldRules are loaded from YAML or JSON files,
+// see validation.LoadFieldRules. outputFile may be empty to skip output validation. Files that can not be loaded
+// fail the registration. In gin's debug mode the files are reloaded when they change, so rules can be edited
+// without a restart, e.g.:
+//
+//	err := ctor.DynamicFromFile(http.MethodPost, "/notes", core.AuthenticatedJSONAPI(), "rules/notes.in.yaml", "", handler)
+func (ctor *RouteConstructor[BaseRoute]) DynamicFromFile(
+	method string,
+	path string,
+	sessionConfig *APIConfiguration,
+	inputFile string,
+	outputFile string,
+	handlerFunc func(input map[string]interface{}, data *Handler[BaseRoute]) (map[string]any, *errors.AppError),
+) error {
+	input, err := loadDynamicRulesFile(inputFile)
+	if err != nil {
+		return fmt.Errorf("dynamic route %s %s: %w", method, path, err)
+	}
+	output, err := loadDynamicRulesFile(outputFile)
+	if err != nil {
+		return fmt.Errorf("dynamic route %s %s: %w", method, path, err)
+	}
+
+	info := RouteInfo{Method: method, Path: path, Config: sessionConfig, Dynamic: true}
+	inputRules, _, _ := input.current(false)
+	info.InputType, _ = validation.DynamicStructType(inputRules)
+	if outputRules, _, _ := output.current(false); outputRules != nil {
+		info.OutputType, _ = validation.DynamicStructType(outputRules)
+	}
+	ctor.routes.record(info)
+
+	cacheId := method + " " + path
+	ctor.router.Handle(method, path, func(ctx *gin.Context) {
+		ctor.bindHooks(ctx)
+		reload := gin.IsDebugging()
+		inputRules, inputVersion, inputErr := input.current(reload)
+		outputRules, outputVersion, outputErr := output.current(reload)
+		if err := stderrors.Join(inputErr, outputErr); err != nil {
+			helpers.Logger(ctx).Warn("Failed to reload field rules, keeping the previous ones", zap.Error(err))
+		}
+		ExecuteDynamicRoute(
+			ctx, ctor.baseRoute, sessionConfig, ctor.sessionManager, ctor.validationEngine,
+			cacheId+"|input@"+inputVersion, inputRules, cacheId+"|output@"+outputVersion, outputRules, handlerFunc,
+		)
+	})
+	return nil
+}
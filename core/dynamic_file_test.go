@@ -0,0 +1,94 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/errors"
+)
+
+func TestDynamicFromFile(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dir := t.TempDir()
+	write := func(name string, content string, modTime time.Time) string {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("Failed to write rules: %v", err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatalf("Failed to set modification time: %v", err)
+		}
+		return path
+	}
+
+	handler := func(input map[string]interface{}, _ *Handler[testBaseRoute]) (map[string]any, *errors.AppError) {
+		return map[string]any{"Title": input["Title"]}, nil
+	}
+
+	post := func(router *gin.Engine, body string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodPost, "/notes", strings.NewReader(body))
+		request.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	start := time.Now().Add(-time.Hour)
+	input := write("notes.in.yaml", "Title:\n  tags: required,max=5\n", start)
+	output := write("notes.out.json", `{"Title": {"tags": "required"}}`, start)
+
+	router := gin.New()
+	ctor := NewRouteConstructor(router, testBaseRoute{}, newMockSessionManager(t), nil)
+	if err := ctor.DynamicFromFile(http.MethodPost, "/notes", PublicRoute(), input, output, handler); err != nil {
+		t.Fatalf("Failed to register the route: %v", err)
+	}
+
+	t.Run("Rules are applied", func(t *testing.T) {
+		if recorder := post(router, `{"title":"short"}`); recorder.Code != http.StatusOK || !strings.Contains(recorder.Body.String(), "short") {
+			t.Errorf("Expected 200, got %d: %s", recorder.Code, recorder.Body.String())
+		}
+		if recorder := post(router, `{"title":"too long"}`); recorder.Code != http.StatusUnprocessableEntity {
+			t.Errorf("Expected 422, got %d", recorder.Code)
+		}
+		routes := ctor.Routes()
+		if len(routes) != 1 || !routes[0].Dynamic || routes[0].InputType == nil || routes[0].OutputType == nil {
+			t.Errorf("Expected the route to be recorded, got %+v", routes)
+		}
+	})
+
+	t.Run("Rules are reloaded in debug mode", func(t *testing.T) {
+		write("notes.in.yaml", "Title:\n  tags: required,max=10\n", start.Add(time.Minute))
+		if recorder := post(router, `{"title":"too long"}`); recorder.Code != http.StatusUnprocessableEntity {
+			t.Errorf("Expected the rules not to be reloaded outside debug mode, got %d", recorder.Code)
+		}
+
+		gin.SetMode(gin.DebugMode)
+		defer gin.SetMode(gin.TestMode)
+		if recorder := post(router, `{"title":"too long"}`); recorder.Code != http.StatusOK {
+			t.Errorf("Expected the reloaded rules to apply, got %d: %s", recorder.Code, recorder.Body.String())
+		}
+
+		// - Broken rules keep the previous ones
+		write("notes.in.yaml", "Title:\n  tag: required\n", start.Add(2*time.Minute))
+		if recorder := post(router, `{"title":"too long"}`); recorder.Code != http.StatusOK {
+			t.Errorf("Expected the previous rules to be kept, got %d", recorder.Code)
+		}
+	})
+
+	t.Run("Invalid files fail the registration", func(t *testing.T) {
+		broken := write("broken.yaml", "title:\n  tags: required\n", start)
+		if err := ctor.DynamicFromFile(http.MethodPost, "/broken", PublicRoute(), broken, "", handler); err == nil {
+			t.Error("Expected an error for invalid rules")
+		}
+		if err := ctor.DynamicFromFile(http.MethodPost, "/missing", PublicRoute(), filepath.Join(dir, "missing.yaml"), "", handler); err == nil {
+			t.Error("Expected an error for a missing file")
+		}
+	})
+}
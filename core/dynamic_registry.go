@@ -0,0 +1,227 @@
+package core
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/errors"
+	"github.com/grzegorzmaniak/gothic/helpers"
+	"github.com/grzegorzmaniak/gothic/validation"
+)
+
+const dynamicRegistryPathParam = "dynamicPath"
+
+// DynamicRouteDefinition describes a route served by a DynamicRouteRegistry, e.g., as received from a config
+// service.
+type DynamicRouteDefinition struct {
+	Method string `json:"method" yaml:"method"`
+
+	// Path is relative to the registry's mount prefix, segments starting with ':' are path parameters, e.g.,
+	// /notes/:id.
+	Path string `json:"path" yaml:"path"`
+
+	// Handler is the name the handler was registered under with RegisterHandler.
+	Handler string `json:"handler" yaml:"handler"`
+
+	Input  validation.FieldRules `json:"input,omitempty" yaml:"input,omitempty"`
+	Output validation.FieldRules `json:"output,omitempty" yaml:"output,omitempty"`
+}
+
+type dynamicRegistryHandler[BaseRoute helpers.BaseRouteComponents] struct {
+	config      *APIConfiguration
+	handlerFunc func(input map[string]interface{}, data *Handler[BaseRoute]) (map[string]any, *errors.AppError)
+}
+
+type dynamicRegistryRoute[BaseRoute helpers.BaseRouteComponents] struct {
+	definition    DynamicRouteDefinition
+	segments      []string
+	handler       *dynamicRegistryHandler[BaseRoute]
+	inputCacheId  string
+	outputCacheId string
+}
+
+// dynamicRegistryTable is never modified once published, Replace builds a new one.
+type dynamicRegistryTable[BaseRoute helpers.BaseRouteComponents] struct {
+	routes map[string]*dynamicRegistryRoute[BaseRoute]   // Key: <METHOD> <path>
+	params map[string][]*dynamicRegistryRoute[BaseRoute] // Key: <METHOD>, routes with path parameters
+}
+
+// DynamicRouteRegistry serves dynamic routes that can be replaced at runtime without a restart. Handlers are
+// registered by name at startup, the routes mapping a method and path to FieldRules and a handler name can then be
+// swapped atomically with Replace, e.g., whenever a config service publishes new definitions.
+type DynamicRouteRegistry[BaseRoute helpers.BaseRouteComponents] struct {
+	ctor       *RouteConstructor[BaseRoute]
+	mu         sync.Mutex // Serializes RegisterHandler and Replace
+	handlers   map[string]*dynamicRegistryHandler[BaseRoute]
+	table      atomic.Pointer[dynamicRegistryTable[BaseRoute]]
+	generation uint64
+}
+
+// NewDynamicRouteRegistry creates an empty DynamicRouteRegistry serving through the constructor's router, session
+// manager and validation engine, see Mount.
+func NewDynamicRouteRegistry[BaseRoute helpers.BaseRouteComponents](ctor *RouteConstructor[BaseRoute]) *DynamicRouteRegistry[BaseRoute] {
+	registry := &DynamicRouteRegistry[BaseRoute]{
+		ctor:     ctor,
+		handlers: map[string]*dynamicRegistryHandler[BaseRoute]{},
+	}
+	registry.table.Store(&dynamicRegistryTable[BaseRoute]{})
+	return registry
+}
+
+// RegisterHandler makes a handler available to route definitions under name, routes using it are executed with
+// sessionConfig.
+func (r *DynamicRouteRegistry[BaseRoute]) RegisterHandler(
+	name string,
+	sessionConfig *APIConfiguration,
+	handlerFunc func(input map[string]interface{}, data *Handler[BaseRoute]) (map[string]any, *errors.AppError),
+) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handlers[name] = &dynamicRegistryHandler[BaseRoute]{config: sessionConfig, handlerFunc: handlerFunc}
+}
+
+// Mount serves the registry under prefix, e.g., /dynamic. The registry owns every path below the prefix, requests
+// without a matching definition are answered with 404.
+func (r *DynamicRouteRegistry[BaseRoute]) Mount(prefix string) {
+	r.ctor.router.Any(strings.TrimSuffix(prefix, "/")+"/*"+dynamicRegistryPathParam, r.serve)
+}
+
+// Replace atomically swaps the served routes for definitions. Nothing is changed when a definition is invalid,
+// e.g., it names an unknown handler or its FieldRules can not be built. The cached structs of changed and removed
+// rules are invalidated, requests already running finish with the rules they started with.
+func (r *DynamicRouteRegistry[BaseRoute]) Replace(definitions []DynamicRouteDefinition) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	previous := r.table.Load()
+	r.generation++
+	generation := strconv.FormatUint(r.generation, 10)
+	next := &dynamicRegistryTable[BaseRoute]{
+		routes: make(map[string]*dynamicRegistryRoute[BaseRoute], len(definitions)),
+		params: map[string][]*dynamicRegistryRoute[BaseRoute]{},
+	}
+
+	kept := map[string]bool{}
+	for _, definition := range definitions {
+		definition.Method = strings.ToUpper(strings.TrimSpace(definition.Method))
+		key := definition.Method + " " + definition.Path
+		if definition.Method == "" || !strings.HasPrefix(definition.Path, "/") {
+			return fmt.Errorf("dynamic route %q: a method and a path starting with '/' are required", key)
+		}
+		if _, exists := next.routes[key]; exists {
+			return fmt.Errorf("dynamic route %q is defined more than once", key)
+		}
+		handler, ok := r.handlers[definition.Handler]
+		if !ok {
+			return fmt.Errorf("dynamic route %q: unknown handler %q", key, definition.Handler)
+		}
+		if _, err := validation.DynamicStructType(definition.Input); err != nil {
+			return fmt.Errorf("dynamic route %q: invalid input rules: %w", key, err)
+		}
+		if _, err := validation.DynamicStructType(definition.Output); err != nil {
+			return fmt.Errorf("dynamic route %q: invalid output rules: %w", key, err)
+		}
+
+		// - Unchanged rules keep their cached struct, changed ones get a new cache id so a request still running
+		//   with the old rules can't cache its struct for the new ones
+		route := &dynamicRegistryRoute[BaseRoute]{
+			definition:    definition,
+			segments:      strings.Split(strings.Trim(definition.Path, "/"), "/"),
+			handler:       handler,
+			inputCacheId:  "dynamic " + key + "|input@" + generation,
+			outputCacheId: "dynamic " + key + "|output@" + generation,
+		}
+		if old, ok := previous.routes[key]; ok {
+			if reflect.DeepEqual(old.definition.Input, definition.Input) {
+				route.inputCacheId = old.inputCacheId
+				kept[route.inputCacheId] = true
+			}
+			if reflect.DeepEqual(old.definition.Output, definition.Output) {
+				route.outputCacheId = old.outputCacheId
+				kept[route.outputCacheId] = true
+			}
+		}
+
+		next.routes[key] = route
+		if strings.Contains(definition.Path, "/:") {
+			next.params[definition.Method] = append(next.params[definition.Method], route)
+		}
+	}
+
+	r.table.Store(next)
+
+	stale := make([]string, 0, len(previous.routes)*2)
+	for _, old := range previous.routes {
+		for _, cacheId := range []string{old.inputCacheId, old.outputCacheId} {
+			if !kept[cacheId] {
+				stale = append(stale, cacheId)
+			}
+		}
+	}
+	r.ctor.validationEngine.InvalidateDynamicStructs(stale...)
+	return nil
+}
+
+// Routes returns the definitions currently served, sorted by path and method.
+func (r *DynamicRouteRegistry[BaseRoute]) Routes() []DynamicRouteDefinition {
+	table := r.table.Load()
+	definitions := make([]DynamicRouteDefinition, 0, len(table.routes))
+	for _, route := range table.routes {
+		definitions = append(definitions, route.definition)
+	}
+	sort.Slice(definitions, func(i, j int) bool {
+		if definitions[i].Path != definitions[j].Path {
+			return definitions[i].Path < definitions[j].Path
+		}
+		return definitions[i].Method < definitions[j].Method
+	})
+	return definitions
+}
+
+// match finds the route of a request, static paths win over paths with parameters.
+func (t *dynamicRegistryTable[BaseRoute]) match(method string, path string) (*dynamicRegistryRoute[BaseRoute], gin.Params) {
+	if route, ok := t.routes[method+" "+path]; ok {
+		return route, nil
+	}
+
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for _, route := range t.params[method] {
+		if len(route.segments) != len(segments) {
+			continue
+		}
+		params := gin.Params{}
+		for i, segment := range route.segments {
+			if strings.HasPrefix(segment, ":") && segments[i] != "" {
+				params = append(params, gin.Param{Key: segment[1:], Value: segments[i]})
+			} else if segment != segments[i] {
+				params = nil
+				break
+			}
+		}
+		if params != nil {
+			return route, params
+		}
+	}
+	return nil, nil
+}
+
+func (r *DynamicRouteRegistry[BaseRoute]) serve(ctx *gin.Context) {
+	route, params := r.table.Load().match(ctx.Request.Method, ctx.Param(dynamicRegistryPathParam))
+	if route == nil {
+		helpers.ErrorResponse(ctx, errors.NewNotFound("", nil))
+		return
+	}
+	ctx.Params = params
+
+	r.ctor.bindHooks(ctx)
+	ExecuteDynamicRoute(
+		ctx, r.ctor.baseRoute, route.handler.config, r.ctor.sessionManager, r.ctor.validationEngine,
+		route.inputCacheId, route.definition.Input, route.outputCacheId, route.definition.Output, route.handler.handlerFunc,
+	)
+}
@@ -0,0 +1,99 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/errors"
+	"github.com/grzegorzmaniak/gothic/validation"
+)
+
+func TestDynamicRouteRegistry(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	ctor := NewRouteConstructor(router, testBaseRoute{}, newMockSessionManager(t), nil)
+	registry := NewDynamicRouteRegistry(ctor)
+	registry.RegisterHandler("echo", PublicRoute(), func(input map[string]interface{}, data *Handler[testBaseRoute]) (map[string]any, *errors.AppError) {
+		return map[string]any{"Title": input["Title"], "Id": data.Context.Param("id")}, nil
+	})
+	registry.Mount("/dynamic")
+
+	request := func(method string, path string, body string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(method, path, strings.NewReader(body))
+		request.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	output := validation.FieldRules{"Title": {}, "Id": {}}
+	notes := func(tags string) DynamicRouteDefinition {
+		return DynamicRouteDefinition{
+			Method: "post", Path: "/notes/:id", Handler: "echo",
+			Input: validation.FieldRules{"Title": {Tags: tags}}, Output: output,
+		}
+	}
+
+	if recorder := request(http.MethodPost, "/dynamic/notes/1", `{"title":"hello"}`); recorder.Code != http.StatusNotFound {
+		t.Fatalf("Expected 404 before any definition, got %d", recorder.Code)
+	}
+
+	if err := registry.Replace([]DynamicRouteDefinition{notes("required,max=5")}); err != nil {
+		t.Fatalf("Failed to replace the routes: %v", err)
+	}
+
+	t.Run("Definitions are served", func(t *testing.T) {
+		recorder := request(http.MethodPost, "/dynamic/notes/42", `{"title":"hello"}`)
+		if recorder.Code != http.StatusOK || !strings.Contains(recorder.Body.String(), `"id":"42"`) {
+			t.Fatalf("Expected 200 with the path parameter, got %d: %s", recorder.Code, recorder.Body.String())
+		}
+		if recorder := request(http.MethodPost, "/dynamic/notes/42", `{"title":"hello world"}`); recorder.Code != http.StatusUnprocessableEntity {
+			t.Errorf("Expected 422, got %d", recorder.Code)
+		}
+		if recorder := request(http.MethodGet, "/dynamic/notes/42", ""); recorder.Code != http.StatusNotFound {
+			t.Errorf("Expected 404 for another method, got %d", recorder.Code)
+		}
+	})
+
+	t.Run("Replaced rules apply without a restart", func(t *testing.T) {
+		if err := registry.Replace([]DynamicRouteDefinition{notes("required,max=20")}); err != nil {
+			t.Fatalf("Failed to replace the routes: %v", err)
+		}
+		if recorder := request(http.MethodPost, "/dynamic/notes/42", `{"title":"hello world"}`); recorder.Code != http.StatusOK {
+			t.Errorf("Expected the new rules to apply, got %d: %s", recorder.Code, recorder.Body.String())
+		}
+		routes := registry.Routes()
+		if len(routes) != 1 || routes[0].Method != http.MethodPost || routes[0].Input["Title"].Tags != "required,max=20" {
+			t.Errorf("Unexpected routes %+v", routes)
+		}
+	})
+
+	t.Run("Invalid definitions change nothing", func(t *testing.T) {
+		invalid := [][]DynamicRouteDefinition{
+			{{Method: http.MethodPost, Path: "/other", Handler: "missing"}},
+			{{Method: http.MethodPost, Path: "/other", Handler: "echo", Input: validation.FieldRules{"title": {}}}},
+			{{Method: http.MethodPost, Path: "other", Handler: "echo"}},
+			{notes("required"), notes("required")},
+		}
+		for _, definitions := range invalid {
+			if err := registry.Replace(definitions); err == nil {
+				t.Errorf("Expected %+v to be rejected", definitions)
+			}
+		}
+		if recorder := request(http.MethodPost, "/dynamic/notes/42", `{"title":"hello world"}`); recorder.Code != http.StatusOK {
+			t.Errorf("Expected the previous routes to be kept, got %d", recorder.Code)
+		}
+	})
+
+	t.Run("Removed routes are no longer served", func(t *testing.T) {
+		if err := registry.Replace(nil); err != nil {
+			t.Fatalf("Failed to replace the routes: %v", err)
+		}
+		if recorder := request(http.MethodPost, "/dynamic/notes/42", `{"title":"hello"}`); recorder.Code != http.StatusNotFound {
+			t.Errorf("Expected 404, got %d", recorder.Code)
+		}
+	})
+}
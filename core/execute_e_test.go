@@ -0,0 +1,84 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/errors"
+	"github.com/grzegorzmaniak/gothic/helpers"
+)
+
+func TestExecuteRouteE(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mgr := newMockSessionManager(t)
+
+	type output struct {
+		Message string `json:"message"`
+	}
+	execute := func(config *APIConfiguration, handler func(*struct{}, *Handler[testBaseRoute]) (*output, *errors.AppError)) (*httptest.ResponseRecorder, *gin.Context, *output, *errors.AppError) {
+		t.Helper()
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+		ctx.Request = httptest.NewRequest(http.MethodGet, "/job", nil)
+		result, appErr := ExecuteRouteE(ctx, testBaseRoute{}, config, mgr, nil, handler)
+		return recorder, ctx, result, appErr
+	}
+
+	t.Run("The output is returned without writing it", func(t *testing.T) {
+		recorder, ctx, result, appErr := execute(PublicRoute(), func(_ *struct{}, _ *Handler[testBaseRoute]) (*output, *errors.AppError) {
+			return &output{Message: "done"}, nil
+		})
+		if appErr != nil || result == nil || result.Message != "done" {
+			t.Fatalf("Expected the handler's output, got %v: %v", result, appErr)
+		}
+		if ctx.Writer.Written() || recorder.Body.Len() != 0 {
+			t.Errorf("Expected nothing to be written, got %s", recorder.Body.String())
+		}
+		if helpers.ResponseCaptured(ctx) {
+			t.Error("Expected the capture to be reset")
+		}
+	})
+
+	t.Run("Handler errors are returned", func(t *testing.T) {
+		recorder, _, result, appErr := execute(PublicRoute(), func(_ *struct{}, _ *Handler[testBaseRoute]) (*output, *errors.AppError) {
+			return nil, errors.NewConflict("Already done", nil)
+		})
+		if result != nil || appErr == nil || appErr.Code != http.StatusConflict || recorder.Body.Len() != 0 {
+			t.Errorf("Expected the 409 to be returned unwritten, got %v: %s", appErr, recorder.Body.String())
+		}
+	})
+
+	t.Run("Session checks are returned", func(t *testing.T) {
+		called := false
+		_, _, _, appErr := execute(AuthenticatedJSONAPI(), func(_ *struct{}, _ *Handler[testBaseRoute]) (*output, *errors.AppError) {
+			called = true
+			return &output{}, nil
+		})
+		if called || appErr == nil || appErr.Code != http.StatusUnauthorized {
+			t.Errorf("Expected a 401 before the handler, got %v", appErr)
+		}
+	})
+
+	t.Run("Panics are returned as 500", func(t *testing.T) {
+		recorder, _, _, appErr := execute(PublicRoute(), func(_ *struct{}, _ *Handler[testBaseRoute]) (*output, *errors.AppError) {
+			panic("boom")
+		})
+		if appErr == nil || appErr.Code != http.StatusInternalServerError || recorder.Body.Len() != 0 {
+			t.Errorf("Expected an unwritten 500, got %v: %s", appErr, recorder.Body.String())
+		}
+	})
+
+	t.Run("Timeouts are returned as 504", func(t *testing.T) {
+		recorder, _, result, appErr := execute(PublicRoute().WithHandlerTimeout(20*time.Millisecond), func(_ *struct{}, data *Handler[testBaseRoute]) (*output, *errors.AppError) {
+			<-data.Context.Request.Context().Done()
+			time.Sleep(10 * time.Millisecond)
+			return &output{Message: "late"}, nil
+		})
+		if result != nil || appErr == nil || appErr.Code != http.StatusGatewayTimeout || recorder.Body.Len() != 0 {
+			t.Errorf("Expected an unwritten 504, got %v: %s", appErr, recorder.Body.String())
+		}
+	})
+}
@@ -0,0 +1,268 @@
+package core
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/grzegorzmaniak/gothic/helpers"
+	"github.com/grzegorzmaniak/gothic/rbac"
+	"go.uber.org/zap"
+)
+
+// ExperimentStage identifies which check an experiment is evaluated in.
+type ExperimentStage string
+
+const (
+	ExperimentStageClaims ExperimentStage = "claims"
+	ExperimentStageRbac   ExperimentStage = "rbac"
+)
+
+// experimentBuckets is the resolution of AuthExperiment.Percentage, 10000 buckets allow steps of 0.01%.
+const experimentBuckets = 10000
+
+// AuthExperiment routes a deterministic percentage of subjects through alternative claims verification and/or
+// RBAC requirements. Subjects in the experiment have both the control (the route's normal checks) and the
+// treatment evaluated, only one of them is enforced, and the outcomes are recorded for comparison.
+type AuthExperiment struct {
+	// Name identifies the experiment in the metrics, it is also part of the bucketing hash so different
+	// experiments select different subjects.
+	Name string
+
+	// Percentage of subjects (0 - 100) that take part in the experiment.
+	Percentage float64
+
+	// Enforce applies the treatment result to subjects in the experiment, when false the treatment is only
+	// evaluated in shadow mode and the control result is enforced (Default: false)
+	Enforce bool
+
+	// VerifyClaims is the alternative claims verification, nil keeps the control for this stage.
+	VerifyClaims func(ctx context.Context, claims *SessionClaims, sessionConfig *APIConfiguration) (bool, error)
+
+	// AccessRequirements derives the alternative RBAC requirements from the route's, nil keeps the control
+	// for this stage.
+	AccessRequirements func(control rbac.AccessRequirements) rbac.AccessRequirements
+
+	// Observer is called with every comparison, in addition to the built-in counters.
+	Observer func(result ExperimentResult)
+}
+
+// ExperimentResult is the outcome of a single control / treatment comparison.
+type ExperimentResult struct {
+	Experiment   string          `json:"experiment"`
+	Stage        ExperimentStage `json:"stage"`
+	Subject      string          `json:"subject"`
+	Control      bool            `json:"control"`
+	Treatment    bool            `json:"treatment"`
+	TreatmentErr error           `json:"-"`
+	Enforced     bool            `json:"enforced"`
+}
+
+// includes reports whether the subject falls into the experiment's deterministic bucket.
+func (e *AuthExperiment) includes(subjectIdentifier string) bool {
+	if e == nil || subjectIdentifier == "" || e.Percentage <= 0 {
+		return false
+	}
+
+	sum := sha256.Sum256([]byte(e.Name + ":" + subjectIdentifier))
+	bucket := binary.BigEndian.Uint64(sum[:8]) % experimentBuckets
+	return float64(bucket) < e.Percentage*experimentBuckets/100
+}
+
+// experimentSubject returns the subject identifier if the session takes part in the experiment.
+func experimentSubject(experiment *AuthExperiment, sessionManager SessionManager, claims *SessionClaims) (string, bool) {
+	if experiment == nil || claims == nil || !claims.HasSession {
+		return "", false
+	}
+
+	subjectIdentifier, err := sessionManager.GetSubjectIdentifier(claims)
+	if err != nil {
+		return "", false
+	}
+	return subjectIdentifier, experiment.includes(subjectIdentifier)
+}
+
+// compare records both outcomes and returns the one that has to be enforced, along with its error.
+func (e *AuthExperiment) compare(
+	ctx context.Context,
+	stage ExperimentStage,
+	subjectIdentifier string,
+	control bool,
+	controlErr error,
+	treatment bool,
+	treatmentErr error,
+) (bool, error) {
+	result := ExperimentResult{
+		Experiment:   e.Name,
+		Stage:        stage,
+		Subject:      subjectIdentifier,
+		Control:      control && controlErr == nil,
+		Treatment:    treatment && treatmentErr == nil,
+		TreatmentErr: treatmentErr,
+		Enforced:     e.Enforce,
+	}
+
+	experimentMetrics.record(result)
+	if e.Observer != nil {
+		e.Observer(result)
+	}
+
+	if result.Control != result.Treatment {
+		helpers.Logger(ctx).Debug("Auth experiment outcome differs from control",
+			zap.String("experiment", e.Name),
+			zap.String("stage", string(stage)),
+			zap.Bool("control", result.Control),
+			zap.Bool("treatment", result.Treatment))
+	}
+
+	if e.Enforce {
+		return treatment, treatmentErr
+	}
+	return control, controlErr
+}
+
+// verifyClaimsWithExperiment runs the session manager's VerifyClaims, and the experiment's alternative if the
+// subject is part of it.
+func verifyClaimsWithExperiment(
+	ctx context.Context,
+	sessionManager SessionManager,
+	sessionConfig *APIConfiguration,
+	claims *SessionClaims,
+) (bool, error) {
+	control, controlErr := sessionManager.VerifyClaims(ctx, claims, sessionConfig)
+
+	experiment := sessionConfig.Experiment
+	if experiment == nil || experiment.VerifyClaims == nil {
+		return control, controlErr
+	}
+
+	subjectIdentifier, included := experimentSubject(experiment, sessionManager, claims)
+	if !included {
+		return control, controlErr
+	}
+
+	treatment, treatmentErr := experiment.VerifyClaims(ctx, claims, sessionConfig)
+	return experiment.compare(ctx, ExperimentStageClaims, subjectIdentifier, control, controlErr, treatment, treatmentErr)
+}
+
+// checkAccessWithExperiment runs rbac.CheckAccess with the route's requirements, and the experiment's alternative
+// requirements if the subject is part of it.
+func checkAccessWithExperiment(
+	ctx context.Context,
+	rbacManager rbac.Manager,
+	sessionManager SessionManager,
+	sessionConfig *APIConfiguration,
+	claims *SessionClaims,
+	subjectIdentifier string,
+	rbacCacheId string,
+	requirements rbac.AccessRequirements,
+) (bool, error) {
+	control, controlErr := rbac.CheckAccess(ctx, rbacManager, subjectIdentifier, rbacCacheId, requirements)
+
+	experiment := sessionConfig.Experiment
+	if experiment == nil || experiment.AccessRequirements == nil {
+		return control, controlErr
+	}
+
+	if _, included := experimentSubject(experiment, sessionManager, claims); !included {
+		return control, controlErr
+	}
+
+	treatment, treatmentErr := rbac.CheckAccess(ctx, rbacManager, subjectIdentifier, rbacCacheId, experiment.AccessRequirements(requirements))
+	return experiment.compare(ctx, ExperimentStageRbac, subjectIdentifier, control, controlErr, treatment, treatmentErr)
+}
+
+type experimentCounters struct {
+	evaluated       atomic.Uint64
+	agreed          atomic.Uint64
+	controlOnly     atomic.Uint64
+	treatmentOnly   atomic.Uint64
+	treatmentErrors atomic.Uint64
+}
+
+// experimentRecorder keeps per experiment and stage counters for this process.
+type experimentRecorder struct {
+	counters sync.Map // map[experimentKey]*experimentCounters
+}
+
+type experimentKey struct {
+	name  string
+	stage ExperimentStage
+}
+
+var experimentMetrics = &experimentRecorder{}
+
+func (r *experimentRecorder) record(result ExperimentResult) {
+	key := experimentKey{name: result.Experiment, stage: result.Stage}
+	existing, ok := r.counters.Load(key)
+	if !ok {
+		existing, _ = r.counters.LoadOrStore(key, &experimentCounters{})
+	}
+	c := existing.(*experimentCounters)
+
+	c.evaluated.Add(1)
+	switch {
+	case result.Control == result.Treatment:
+		c.agreed.Add(1)
+	case result.Control:
+		c.controlOnly.Add(1)
+	default:
+		c.treatmentOnly.Add(1)
+	}
+	if result.TreatmentErr != nil {
+		c.treatmentErrors.Add(1)
+	}
+}
+
+// ExperimentStats holds the comparison counters of an experiment stage.
+type ExperimentStats struct {
+	Experiment string          `json:"experiment"`
+	Stage      ExperimentStage `json:"stage"`
+	Evaluated  uint64          `json:"evaluated"`
+	Agreed     uint64          `json:"agreed"`
+
+	// ControlOnly counts subjects allowed by the control but denied by the treatment.
+	ControlOnly uint64 `json:"control_only"`
+
+	// TreatmentOnly counts subjects denied by the control but allowed by the treatment.
+	TreatmentOnly   uint64 `json:"treatment_only"`
+	TreatmentErrors uint64 `json:"treatment_errors"`
+}
+
+// GetExperimentStats returns the counters of every experiment stage recorded by this process, sorted by name.
+func GetExperimentStats() []ExperimentStats {
+	stats := make([]ExperimentStats, 0)
+	experimentMetrics.counters.Range(func(key, value interface{}) bool {
+		k := key.(experimentKey)
+		c := value.(*experimentCounters)
+		stats = append(stats, ExperimentStats{
+			Experiment:      k.name,
+			Stage:           k.stage,
+			Evaluated:       c.evaluated.Load(),
+			Agreed:          c.agreed.Load(),
+			ControlOnly:     c.controlOnly.Load(),
+			TreatmentOnly:   c.treatmentOnly.Load(),
+			TreatmentErrors: c.treatmentErrors.Load(),
+		})
+		return true
+	})
+
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Experiment != stats[j].Experiment {
+			return stats[i].Experiment < stats[j].Experiment
+		}
+		return stats[i].Stage < stats[j].Stage
+	})
+	return stats
+}
+
+// ResetExperimentStats clears the experiment counters, e.g., when starting a new rollout step.
+func ResetExperimentStats() {
+	experimentMetrics.counters.Range(func(key, _ interface{}) bool {
+		experimentMetrics.counters.Delete(key)
+		return true
+	})
+}
@@ -0,0 +1,84 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestAuthExperiment_Includes(t *testing.T) {
+	experiment := &AuthExperiment{Name: "new-claims", Percentage: 25}
+
+	t.Run("Bucketing is deterministic", func(t *testing.T) {
+		for i := 0; i < 100; i++ {
+			subject := fmt.Sprintf("user-%d", i)
+			if experiment.includes(subject) != experiment.includes(subject) {
+				t.Fatalf("Subject %s was bucketed inconsistently", subject)
+			}
+		}
+	})
+
+	t.Run("Roughly the configured percentage is included", func(t *testing.T) {
+		included := 0
+		for i := 0; i < 10000; i++ {
+			if experiment.includes(fmt.Sprintf("user-%d", i)) {
+				included++
+			}
+		}
+		if included < 2200 || included > 2800 {
+			t.Errorf("Expected about 2500 included subjects, got %d", included)
+		}
+	})
+
+	t.Run("Zero and full rollout", func(t *testing.T) {
+		if (&AuthExperiment{Name: "off", Percentage: 0}).includes("user") {
+			t.Error("Expected no subject to be included at 0%")
+		}
+		if !(&AuthExperiment{Name: "on", Percentage: 100}).includes("user") {
+			t.Error("Expected every subject to be included at 100%")
+		}
+	})
+}
+
+func TestVerifyClaimsWithExperiment(t *testing.T) {
+	mgr := newMockSessionManager(t)
+	claims := &SessionClaims{HasSession: true, Claims: map[string]string{SessionModeClaim: "user", "subject": "user-1"}}
+	denyAll := func(context.Context, *SessionClaims, *APIConfiguration) (bool, error) { return false, nil }
+
+	tests := []struct {
+		name    string
+		enforce bool
+		want    bool
+	}{
+		{name: "Shadow mode enforces the control", enforce: false, want: true},
+		{name: "Enforced mode applies the treatment", enforce: true, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ResetExperimentStats()
+			var observed []ExperimentResult
+			config := &APIConfiguration{Experiment: &AuthExperiment{
+				Name:         "strict-claims",
+				Percentage:   100,
+				Enforce:      tt.enforce,
+				VerifyClaims: denyAll,
+				Observer:     func(result ExperimentResult) { observed = append(observed, result) },
+			}}
+
+			got, _ := verifyClaimsWithExperiment(context.Background(), mgr, config, claims)
+			if got != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+
+			if len(observed) != 1 || !observed[0].Control || observed[0].Treatment || observed[0].Subject != "user-1" {
+				t.Errorf("Unexpected observed results: %+v", observed)
+			}
+
+			stats := GetExperimentStats()
+			if len(stats) != 1 || stats[0].Evaluated != 1 || stats[0].ControlOnly != 1 || stats[0].Stage != ExperimentStageClaims {
+				t.Errorf("Unexpected stats: %+v", stats)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package core
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/errors"
+	"github.com/grzegorzmaniak/gothic/helpers"
+	"go.uber.org/zap"
+)
+
+// FlagContext is what a FlagProvider knows of the request a flag is evaluated for, e.g., to roll a feature out to
+// some subjects or tenants first.
+type FlagContext struct {
+	// Subject is the subject identifier of the session, empty for sessionless requests
+	Subject string
+
+	// Claims are the claims of the session, nil for sessionless requests
+	Claims *SessionClaims
+
+	// SessionGroup is the mode of the session, e.g., "user"
+	SessionGroup string
+
+	// Tenant is the tenant the session is scoped to (TenantClaim), the TenantResolver of the route runs later
+	Tenant string
+}
+
+// FlagProvider evaluates feature flags, e.g., by asking a LaunchDarkly style backend. Errors deny the route.
+type FlagProvider interface {
+	IsEnabled(ctx context.Context, flag string, evaluation FlagContext) (bool, error)
+}
+
+// FlagProviderFunc adapts a function to a FlagProvider.
+type FlagProviderFunc func(ctx context.Context, flag string, evaluation FlagContext) (bool, error)
+
+func (f FlagProviderFunc) IsEnabled(ctx context.Context, flag string, evaluation FlagContext) (bool, error) {
+	return f(ctx, flag, evaluation)
+}
+
+// StaticFlags is a FlagProvider of fixed flags, flags it doesn't list are disabled.
+type StaticFlags map[string]bool
+
+func (f StaticFlags) IsEnabled(_ context.Context, flag string, _ FlagContext) (bool, error) {
+	return f[flag], nil
+}
+
+// FlagProviderSource is implemented by session managers that evaluate the feature flags of routes, see
+// APIConfiguration.FeatureFlag and SessionManagerBuilder.WithFlagProvider.
+type FlagProviderSource interface {
+	GetFlagProvider() FlagProvider
+}
+
+// processFeatureFlag hides the route while its feature flag is disabled for the request, with a 404 (or a 403
+// with FeatureFlagForbidden) as if it wasn't there.
+func processFeatureFlag(ctx *gin.Context, sessionManager SessionManager, sessionConfig *APIConfiguration, claims *SessionClaims, group string) *errors.AppError {
+	if sessionConfig.FeatureFlag == "" {
+		return nil
+	}
+
+	source, ok := sessionManager.(FlagProviderSource)
+	if !ok || source.GetFlagProvider() == nil {
+		return errors.NewInternalServerError("Feature flags are not configured", fmt.Errorf("session manager has no FlagProvider"))
+	}
+
+	evaluation := FlagContext{SessionGroup: group}
+	if claims != nil && claims.HasSession {
+		evaluation.Claims = claims
+		evaluation.Subject, _ = sessionManager.GetSubjectIdentifier(claims)
+		evaluation.Tenant, _ = claims.GetClaim(TenantClaim)
+	}
+
+	enabled, err := source.GetFlagProvider().IsEnabled(ctx, sessionConfig.FeatureFlag, evaluation)
+	if err != nil {
+		helpers.Logger(ctx).Warn("Failed to evaluate a feature flag, the route is hidden", zap.String("flag", sessionConfig.FeatureFlag), zap.Error(err))
+	}
+	if err == nil && enabled {
+		return nil
+	}
+
+	if sessionConfig.FeatureFlagForbidden {
+		return errors.NewForbidden("", nil)
+	}
+	return errors.NewNotFound("", nil)
+}
+
+// hiddenByFeatureFlag returns the error of a request whose session could not be established. Routes behind a
+// disabled feature flag answer as hidden instead, so the session checks don't tell they exist.
+func hiddenByFeatureFlag(ctx *gin.Context, sessionManager SessionManager, sessionConfig *APIConfiguration, appErr *errors.AppError) *errors.AppError {
+	if sessionConfig.FeatureFlag == "" {
+		return appErr
+	}
+	if flagErr := processFeatureFlag(ctx, sessionManager, sessionConfig, nil, ""); flagErr != nil {
+		return flagErr
+	}
+	return appErr
+}
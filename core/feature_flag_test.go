@@ -0,0 +1,139 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/errors"
+)
+
+// flaggedSessionManager evaluates feature flags with its provider.
+type flaggedSessionManager struct {
+	*mockSessionManager
+	provider FlagProvider
+}
+
+func (m *flaggedSessionManager) GetFlagProvider() FlagProvider { return m.provider }
+
+func TestFeatureFlags(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mgr := &flaggedSessionManager{mockSessionManager: newMockSessionManager(t)}
+	mgr.provider = FlagProviderFunc(func(_ context.Context, flag string, evaluation FlagContext) (bool, error) {
+		switch flag {
+		case "beta":
+			return evaluation.Subject == "user-1" && evaluation.SessionGroup == "user", nil
+		case "launched":
+			return StaticFlags{"launched": true}.IsEnabled(context.Background(), flag, evaluation)
+		}
+		return false, fmt.Errorf("unknown flag '%s'", flag)
+	})
+
+	router := gin.New()
+	ctor := NewRouteConstructor(router, testBaseRoute{}, mgr, nil)
+	ok := func(_ *struct{}, _ *Handler[testBaseRoute]) (*struct{}, *errors.AppError) {
+		return &struct{}{}, nil
+	}
+	GET(ctor, "/beta", PublicRoute().WithOptionalSession().WithFeatureFlag("beta"), ok)
+	GET(ctor, "/beta-admin", AuthenticatedJSONAPI().WithFeatureFlag("beta").WithRoles("admin"), ok)
+	GET(ctor, "/launched", PublicRoute().WithFeatureFlag("launched"), ok)
+	GET(ctor, "/hidden", PublicRoute().WithFeatureFlag("unknown"), ok)
+	hidden := PublicRoute().WithFeatureFlag("unlaunched")
+	hidden.FeatureFlagForbidden = true
+	GET(ctor, "/forbidden", hidden, ok)
+
+	claims := &SessionClaims{Claims: map[string]string{"subject": "user-1"}}
+	if err := ensureBasicClaims("user", claims, mgr); err != nil {
+		t.Fatalf("Failed to set the basic claims: %v", err)
+	}
+	header := NewSessionHeader(false, time.Hour, 30*time.Minute)
+	authorization, err := CreateAuthorization("user", &header, *mgr.authorizationData, claims, mgr)
+	if err != nil {
+		t.Fatalf("Failed to create the authorization: %v", err)
+	}
+	session := &http.Cookie{Name: DefaultSessionAuthorizationName, Value: authorization}
+
+	request := func(path string, cookie *http.Cookie) int {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		cookie   *http.Cookie
+		expected int
+	}{
+		{name: "Enabled for the subject", path: "/beta", cookie: session, expected: http.StatusOK},
+		{name: "Hidden without a session", path: "/beta", expected: http.StatusNotFound},
+		{name: "Hidden before the session and RBAC checks", path: "/beta-admin", expected: http.StatusNotFound},
+		{name: "Static flags", path: "/launched", expected: http.StatusOK},
+		{name: "Provider errors hide the route", path: "/hidden", expected: http.StatusNotFound},
+		{name: "Forbidden instead of hidden", path: "/forbidden", expected: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := request(tt.path, tt.cookie); code != tt.expected {
+				t.Errorf("Expected %d, got %d", tt.expected, code)
+			}
+		})
+	}
+
+	t.Run("Downloads and dispatched handlers are hidden", func(t *testing.T) {
+		download := func(grant *DownloadGrant, data *Handler[testBaseRoute]) *errors.AppError {
+			data.Context.String(http.StatusOK, grant.Resource)
+			return nil
+		}
+		DOWNLOAD(ctor, "/download/unlaunched", PublicRoute().WithFeatureFlag("unlaunched"), "export", download)
+		DOWNLOAD(ctor, "/download/launched", PublicRoute().WithFeatureFlag("launched"), "export", download)
+		user := &SessionClaims{HasSession: true, Claims: claims.Claims}
+		token, err := IssueDownloadGrant(context.Background(), mgr, user, "export", "exports/1.csv", time.Minute, nil)
+		if err != nil {
+			t.Fatalf("Failed to issue download grant: %v", err)
+		}
+		if code := request("/download/unlaunched?"+DownloadGrantQueryParam+"="+url.QueryEscape(token), nil); code != http.StatusNotFound {
+			t.Errorf("Expected the unlaunched download to be hidden, got %d", code)
+		}
+		if code := request("/download/launched?"+DownloadGrantQueryParam+"="+url.QueryEscape(token), nil); code != http.StatusOK {
+			t.Errorf("Expected the grant to be redeemed on the launched download, got %d", code)
+		}
+
+		if err = DISPATCH(ctor, "flags.beta", AuthenticatedJSONAPI().WithFeatureFlag("beta"), ok); err != nil {
+			t.Fatalf("Failed to register dispatch handler: %v", err)
+		}
+		defer UnregisterDispatch("flags.beta")
+		if _, appErr := Dispatch(context.Background(), "flags.beta", nil, user); appErr != nil {
+			t.Errorf("Expected the beta handler to run for user-1, got %v", appErr)
+		}
+		other := &SessionClaims{HasSession: true, Claims: map[string]string{"subject": "user-2"}}
+		if err = ensureBasicClaims("user", other, mgr); err != nil {
+			t.Fatalf("Failed to set the basic claims: %v", err)
+		}
+		if _, appErr := Dispatch(context.Background(), "flags.beta", nil, other); appErr == nil || appErr.Code != http.StatusNotFound {
+			t.Errorf("Expected the beta handler to be hidden from user-2, got %v", appErr)
+		}
+		if _, appErr := Dispatch(context.Background(), "flags.beta", nil, nil); appErr == nil || appErr.Code != http.StatusNotFound {
+			t.Errorf("Expected the beta handler to be hidden without a session, got %v", appErr)
+		}
+	})
+
+	t.Run("Routes need a provider", func(t *testing.T) {
+		router := gin.New()
+		GET(NewRouteConstructor(router, testBaseRoute{}, newMockSessionManager(t), nil), "/beta", PublicRoute().WithFeatureFlag("beta"), ok)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/beta", nil))
+		if recorder.Code != http.StatusInternalServerError {
+			t.Errorf("Expected a 500 without a provider, got %d", recorder.Code)
+		}
+	})
+}
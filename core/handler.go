@@ -1,6 +1,9 @@
 package core
 
 import (
+	"sync"
+	"time"
+
 	"github.com/gin-gonic/gin"
 	"github.com/grzegorzmaniak/gothic/helpers"
 	"github.com/grzegorzmaniak/gothic/rbac"
@@ -15,6 +18,49 @@ type Handler[BaseRoute helpers.BaseRouteComponents] struct {
 	CsrfToken      *CompleteCsrfToken
 	HasSession     bool
 	SessionManager SessionManager
+
+	// RequestID correlates the request across logs and responses, see helpers.EnsureRequestID
+	RequestID string
+
+	// ReplicaHint tells the data layer whether queries may go to a read replica, it is also available on the
+	// request context through ReplicaHintFromContext.
+	ReplicaHint ReplicaHint
+
+	// Values holds the values set for the request, e.g., by hooks (see HookContext.Values). Read them with Value.
+	Values *Values
+
+	// tasks holds the sub-tasks started with Go, they are cancelled once the response is written.
+	tasks *taskGroup
+
+	// permissions memoizes the HasPermission and HasNamedPermission checks of the request.
+	permissionsMu sync.Mutex
+	permissions   map[string]bool
+}
+
+func newHandler[BaseRoute helpers.BaseRouteComponents](
+	ctx *gin.Context,
+	baseRoute BaseRoute,
+	sessionConfig *APIConfiguration,
+	sessionManager SessionManager,
+	header *SessionHeader,
+	claims *SessionClaims,
+	csrfToken *CompleteCsrfToken,
+	group string,
+) *Handler[BaseRoute] {
+	return &Handler[BaseRoute]{
+		BaseRoute:      baseRoute,
+		Context:        ctx,
+		SessionHeader:  header,
+		Claims:         claims,
+		HasSession:     claims != nil && claims.HasSession,
+		SessionManager: sessionManager,
+		SessionGroup:   group,
+		CsrfToken:      csrfToken,
+		RequestID:      helpers.GetRequestID(ctx),
+		ReplicaHint:    ReplicaHintFromContext(ctx),
+		Values:         &Values{},
+		tasks:          newTaskGroup(ctx.Request.Context(), sessionConfig.MaxHandlerTasks),
+	}
 }
 
 // APIConfiguration defines the configuration for an API route.
@@ -41,6 +87,11 @@ type APIConfiguration struct {
 	// Permissions is a list of permissions required for the session (PBAC)
 	Permissions rbac.Permissions
 
+	// NamedPermissions is a list of namespaced permissions required for the session, e.g., "billing.invoices.read".
+	// They are checked alongside Permissions and follow the same RbacPolicy, subjects can be granted wildcards
+	// such as "billing.*" through the rbac.Manager.
+	NamedPermissions []string
+
 	// Roles is a list of roles required for the session (PBAC)
 	Roles *[]string
 
@@ -55,15 +106,170 @@ type APIConfiguration struct {
 	// defaults to false
 	ManualResponse bool
 
-	// RequireCsrf is a flag to indicate if CSRF is required (Default: true)
+	// RequireCsrf is a flag to indicate if CSRF is required (Default: true). Safe methods are exempt, see
+	// CsrfCookieData.SafeMethods
 	RequireCsrf bool
 
+	// ExemptMethods are methods of this route that skip the CSRF check on top of the safe methods, e.g., "POST"
+	// for a search route that only reads (Default: none)
+	ExemptMethods []string
+
+	// PolicyFunc is an optional attribute based access control (ABAC) check. It runs after the RBAC check and
+	// input validation, receiving the validated input so it can compare subject and resource attributes.
+	PolicyFunc func(ctx *gin.Context, claims *SessionClaims, input interface{}) (*rbac.AttributeDecision, error)
+
+	// AttributeEvaluator is an optional, reusable ABAC policy. It is evaluated alongside PolicyFunc with the
+	// session claims as the subject attributes and the validated input as the resource.
+	AttributeEvaluator rbac.AttributeEvaluator
+
+	// PartialInput binds dynamic routes with validation.DynamicPartialInputData, the input map only holds the
+	// fields present in the request, e.g., for PATCH routes. Typed routes use validation.Optional fields instead
+	// (Default: false)
+	PartialInput bool
+
+	// MaxHandlerTasks limits the number of concurrently running Handler.Go sub-tasks (Default: 16)
+	MaxHandlerTasks int
+
+	// Coalesce enables request coalescing for GET requests, identical concurrent requests execute the
+	// handler once and share the validated response. Only use this on read-only routes that do not write
+	// to the context directly (Default: false)
+	Coalesce bool
+
+	// CoalesceKeyFunc overrides how requests are grouped when Coalesce is enabled (Default: DefaultCoalesceKey)
+	CoalesceKeyFunc CoalesceKeyFunc
+
+	// ErrorFormat overrides the global error response format for this route, e.g., helpers.ResponseFormatProblem
+	// for RFC 7807 problem+json (Default: helpers.ResponseFormatDefault, inherits the global format)
+	ErrorFormat helpers.ResponseFormat
+
+	// ResponseEncoding overrides the global compression / ETag settings of the success response for this route
+	// (Default: unset fields inherit helpers.SetDefaultResponseEncoding)
+	ResponseEncoding helpers.ResponseEncoding
+
+	// Experiment optionally evaluates alternative claims verification / RBAC requirements for a deterministic
+	// percentage of subjects, see AuthExperiment.
+	Experiment *AuthExperiment
+
+	// StreamRecheckInterval is how often ExecuteStreamRoute re-verifies the session and claims of a long-lived
+	// stream, zero only closes the stream once the session expires (Default: 0)
+	StreamRecheckInterval time.Duration
+
+	// Localize formats the output's `localize` tagged money / date fields for the subject's LocaleClaim and
+	// TimezoneClaim, either replacing them or adding formatted companions (Default: LocalizeOff)
+	Localize LocalizeMode
+
+	// SkipTravelCheck excludes the route from the impossible travel detector, e.g., for the step-up
+	// verification route itself (Default: false)
+	SkipTravelCheck bool
+
+	// MinimumAuthLevel is the AuthLevel a session needs for this route, weaker sessions get a 401 with the
+	// StepUpRequiredError detail until they are raised with ElevateSession (Default: AuthLevelNone)
+	MinimumAuthLevel AuthLevel
+
+	// MaxBodyBytes caps the request body, larger bodies are rejected with 413 before they are read into memory,
+	// a negative value disables the limit (Default: DefaultMaxBodyBytes)
+	MaxBodyBytes int64
+
+	// BodyReadTimeout is how long the client has to send the request body, slower bodies are rejected with
+	// 408, a negative value disables the timeout (Default: DefaultBodyReadTimeout)
+	BodyReadTimeout time.Duration
+
+	// Idempotent makes the route honour the Idempotency-Key header on POST, PUT, PATCH and DELETE requests, the
+	// successful response of a subject's key is stored in the cache and replayed to retries (Default: false)
+	Idempotent bool
+
+	// IdempotencyTTL is how long stored responses are replayed for (Default: DefaultIdempotencyTTL)
+	IdempotencyTTL time.Duration
+
+	// CacheTTL caches the successful responses of GET (and HEAD) requests for this long, the handler only runs
+	// on a miss. Cached responses are served after the session, RBAC and input checks (Default: 0, disabled)
+	CacheTTL time.Duration
+
+	// CacheVaryBy selects what cached responses are keyed by on top of the route and request path, e.g.,
+	// CacheVaryQuery | CacheVaryGroup to share them between subjects (Default: CacheVaryAll)
+	CacheVaryBy ResponseCacheVary
+
+	// InvalidatesCache lists the cached routes a successful request to this route invalidates, each given as its
+	// method and registered path, e.g., "GET /notes/:id" (Default: none)
+	InvalidatesCache []string
+
+	// Audit selects whether the route's requests are recorded to the audit sink, see audit.SetSink
+	// (Default: AuditProtected)
+	Audit AuditMode
+
+	// ReadOnly marks the route as not writing, so its queries may be routed to a read replica (see ReplicaHint).
+	// Successful state changing requests on other routes keep the subject on the primary for a short window.
+	ReadOnly bool
+
+	// TenantResolver returns the tenant the request acts on, the RBAC checks are then scoped to it (see
+	// rbac.TenantManager). Sessions carrying a TenantClaim are scoped to that tenant without a resolver, and are
+	// rejected when the resolver returns another one (Default: nil, the TenantClaim if set)
+	TenantResolver TenantResolver
+
+	// RbacDryRun evaluates the route's RBAC requirements without enforcing them, would-be denials are logged with
+	// their rbac.Decision trace and the request continues, e.g., while rolling out a new permission (Default: false)
+	RbacDryRun bool
+
+	// OnDenied returns the error sent when the RBAC check denies a request, given the DecisionContext with the
+	// missing permissions and roles, e.g., to answer 402 with an upgrade offer. Returning nil sends the default
+	// error, the handler never runs (Default: nil, 401 Insufficient permissions)
+	OnDenied DeniedFunc
+
+	// AllowAPIKeys accepts API keys (see APIKeyStore) on this route next to sessions. Requests with a key act with
+	// the key's claims and grants, their session mode is APIKeySessionGroup and they carry no CSRF token
+	// (Default: false)
+	AllowAPIKeys bool
+
+	// AllowClientCertificates accepts verified TLS client certificates (see ClientCertificateResolver) on this route
+	// when the request carries no session token. Their session mode is ClientCertificateSessionGroup and they carry
+	// no CSRF token, so only enable it on routes for service clients (Default: false)
+	AllowClientCertificates bool
+
+	// HandlerTimeout is the deadline of the handler, set on Context.Request.Context(). Past it the client gets a
+	// 504 Gateway Timeout and the handler's later output is discarded, it should return once the context is done
+	// (Default: 0, no deadline)
+	HandlerTimeout time.Duration
+
+	// Hooks run inside the route's lifecycle, after the ones of the RouteConstructor (see AddHooks) (Default: nil)
+	Hooks *Hooks
+
+	// MaxConcurrent is the largest number of requests running the handler at once, e.g., for expensive reports and
+	// exports. Requests past it wait up to MaxConcurrentQueueTimeout for a slot, then get a 503 Service Unavailable.
+	// Each route using the configuration has its own slots. Cached, coalesced and replayed responses don't take a
+	// slot (Default: 0, unlimited)
+	MaxConcurrent int
+
+	// MaxConcurrentQueueTimeout is how long a request waits for a slot when MaxConcurrent are running
+	// (Default: 0, it is shed at once)
+	MaxConcurrentQueueTimeout time.Duration
+
+	// FeatureFlag hides the route unless the flag is enabled for the request by the session manager's FlagProvider
+	// (see FlagProviderSource). It is evaluated after the session is established, before the RBAC checks
+	// (Default: "", no flag)
+	FeatureFlag string
+
+	// FeatureFlagForbidden answers 403 Forbidden instead of 404 Not Found while the FeatureFlag is disabled
+	// (Default: false)
+	FeatureFlagForbidden bool
+
+	// MaintenanceExempt keeps the route available in maintenance mode (see SetMaintenanceMode), e.g., for health
+	// checks or the routes operators use (Default: false)
+	MaintenanceExempt bool
+
+	// Cors is the CORS policy of the route, overriding the one of the RouteConstructor (see SetCorsPolicy). Routes
+	// with a policy answer their own preflight requests (Default: nil, the constructor's policy)
+	Cors *CorsPolicy
+
 	// flatRoles is a cached map of roles for this configuration, It provides a quick lookup for roles
 	flatRoles map[string]bool
 
 	// flatPermissions is a cached map of permissions for this configuration, It provides a quick lookup for permissions
 	flatPermissions            rbac.Permission
 	flatPermissionsInitialized bool
+
+	// flatNamedPermissions is a cached set of the named permissions for this configuration
+	flatNamedPermissions    rbac.PermissionSet
+	flatNamedPermissionsErr error
 }
 
 func (config *APIConfiguration) GetFlatRoles() map[string]bool {
@@ -85,3 +291,11 @@ func (config *APIConfiguration) GetFlatPermissions() *rbac.Permission {
 	}
 	return &config.flatPermissions
 }
+
+// GetFlatNamedPermissions returns the NamedPermissions as a set, an error is returned if any name is malformed.
+func (config *APIConfiguration) GetFlatNamedPermissions() (rbac.PermissionSet, error) {
+	if config.flatNamedPermissions == nil && config.flatNamedPermissionsErr == nil {
+		config.flatNamedPermissions, config.flatNamedPermissionsErr = rbac.NewPermissionSet(config.NamedPermissions...)
+	}
+	return config.flatNamedPermissions, config.flatNamedPermissionsErr
+}
@@ -0,0 +1,88 @@
+package core
+
+import (
+	"fmt"
+
+	"github.com/grzegorzmaniak/gothic/helpers"
+	"github.com/grzegorzmaniak/gothic/rbac"
+	"go.uber.org/zap"
+)
+
+// SubjectID returns the subject identifier of the session (see SessionManager.GetSubjectIdentifier), or an empty
+// string for sessionless requests and sessions without one.
+func (h *Handler[BaseRoute]) SubjectID() string {
+	if !h.HasSession || h.Claims == nil || h.SessionManager == nil {
+		return ""
+	}
+
+	subjectIdentifier, err := h.SessionManager.GetSubjectIdentifier(h.Claims)
+	if err != nil {
+		helpers.Logger(h.Context).Debug("Failed to get the subject identifier of the session", zap.Error(err))
+		return ""
+	}
+	return subjectIdentifier
+}
+
+// MustClaim returns a claim of the session, panicking when it is missing. Use it for claims every session of the
+// route is issued with, the executor recovers the panic and answers 500.
+func (h *Handler[BaseRoute]) MustClaim(claim string) string {
+	if h.Claims != nil {
+		if value, ok := h.Claims.GetClaim(claim); ok {
+			return value
+		}
+	}
+	panic(fmt.Sprintf("session has no '%s' claim", claim))
+}
+
+// HasPermission reports whether the session is granted every bit of permission, e.g., to show extra fields to
+// admins. The checks read the subject's roles and permissions from the RBAC cache the route's own check filled, and
+// are memoized for the request. Sessionless requests and failed checks are denied.
+func (h *Handler[BaseRoute]) HasPermission(permission *rbac.Permission) bool {
+	if permission == nil {
+		return true
+	}
+	return h.checkPermission("bits:"+permission.Serialize(), rbac.AccessRequirements{Permissions: permission})
+}
+
+// HasNamedPermission is like HasPermission for a named permission, e.g., data.HasNamedPermission("billing.read").
+func (h *Handler[BaseRoute]) HasNamedPermission(name string) bool {
+	required, err := rbac.NewPermissionSet(name)
+	if err != nil {
+		helpers.Logger(h.Context).Debug("Invalid permission name", zap.String("permission", name), zap.Error(err))
+		return false
+	}
+	return h.checkPermission("named:"+name, rbac.AccessRequirements{NamedPermissions: required})
+}
+
+// checkPermission checks the requirements of the session once per key.
+func (h *Handler[BaseRoute]) checkPermission(key string, requirements rbac.AccessRequirements) bool {
+	if !h.HasSession || h.Claims == nil || h.SessionManager == nil {
+		return false
+	}
+
+	h.permissionsMu.Lock()
+	defer h.permissionsMu.Unlock()
+	if allowed, ok := h.permissions[key]; ok {
+		return allowed
+	}
+
+	allowed, err := h.checkRequirements(requirements)
+	if err != nil {
+		helpers.Logger(h.Context).Debug("Error checking a handler permission, it is denied", zap.String("permission", key), zap.Error(err))
+		return false
+	}
+	if h.permissions == nil {
+		h.permissions = make(map[string]bool)
+	}
+	h.permissions[key] = allowed
+	return allowed
+}
+
+func (h *Handler[BaseRoute]) checkRequirements(requirements rbac.AccessRequirements) (bool, error) {
+	rbacManager, subjectIdentifier, rbacCacheId, err := sessionRbacSubject(h.SessionManager, h.Claims)
+	if err != nil {
+		return false, err
+	}
+	requirements.Policy = rbac.PermissionsOnly
+	return rbac.CheckAccess(rbacContext(h.Context), rbacManager, subjectIdentifier, rbacCacheId, requirements)
+}
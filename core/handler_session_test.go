@@ -0,0 +1,92 @@
+package core
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	internalcache "github.com/grzegorzmaniak/gothic/cache"
+	"github.com/grzegorzmaniak/gothic/rbac"
+)
+
+// bitsetRbacManager grants the named permissions of namedRbacManager and a permission bitset per subject.
+type bitsetRbacManager struct {
+	*namedRbacManager
+	bits map[string]*rbac.Permission
+}
+
+func (m *bitsetRbacManager) GetSubjectRolesAndPermissions(_ context.Context, subjectIdentifier string) (rbac.Permissions, []string, error) {
+	if bits, ok := m.bits[subjectIdentifier]; ok {
+		return rbac.Permissions{bits}, nil, nil
+	}
+	return nil, nil, nil
+}
+
+func TestHandlerSessionHelpers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mgr := newMockSessionManager(t)
+	rbacManager := &bitsetRbacManager{
+		namedRbacManager: &namedRbacManager{
+			cacheManager: internalcache.BuildDefaultCacheManager(nil),
+			named:        map[string]rbac.PermissionSet{"admin-1": rbac.MustPermissionSet("billing.*")},
+		},
+		bits: map[string]*rbac.Permission{"admin-1": rbac.NewPermission(0).Or(rbac.NewPermission(1))},
+	}
+	mgr.rbacManager = rbacManager
+
+	handler := func(claims *SessionClaims) *Handler[testBaseRoute] {
+		ctx, _ := gin.CreateTestContext(nil)
+		return &Handler[testBaseRoute]{Context: ctx, Claims: claims, HasSession: claims != nil && claims.HasSession, SessionManager: mgr}
+	}
+	admin := handler(&SessionClaims{HasSession: true, Claims: map[string]string{
+		"subject":           "admin-1",
+		"tenant":            "acme",
+		RbacCacheIdentifier: strings.Repeat("a", 32),
+	}})
+	anonymous := handler(nil)
+
+	t.Run("SubjectID", func(t *testing.T) {
+		if subject := admin.SubjectID(); subject != "admin-1" {
+			t.Errorf("Expected 'admin-1', got '%s'", subject)
+		}
+		if subject := anonymous.SubjectID(); subject != "" {
+			t.Errorf("Expected no subject without a session, got '%s'", subject)
+		}
+	})
+
+	t.Run("MustClaim", func(t *testing.T) {
+		if tenant := admin.MustClaim("tenant"); tenant != "acme" {
+			t.Errorf("Expected 'acme', got '%s'", tenant)
+		}
+		defer func() {
+			if recover() == nil {
+				t.Error("Expected a missing claim to panic")
+			}
+		}()
+		admin.MustClaim("missing")
+	})
+
+	t.Run("HasPermission", func(t *testing.T) {
+		if !admin.HasPermission(rbac.NewPermission(1)) || admin.HasPermission(rbac.NewPermission(2)) {
+			t.Error("Expected only the granted bits to be allowed")
+		}
+		if !admin.HasNamedPermission("billing.read") || admin.HasNamedPermission("users.read") {
+			t.Error("Expected only the granted named permissions to be allowed")
+		}
+		if anonymous.HasPermission(rbac.NewPermission(0)) || anonymous.HasNamedPermission("billing.read") {
+			t.Error("Expected sessionless requests to be denied")
+		}
+	})
+
+	t.Run("Checks are memoized for the request", func(t *testing.T) {
+		mgr.rbacManager = nil
+		defer func() { mgr.rbacManager = rbacManager }()
+		if !admin.HasNamedPermission("billing.read") {
+			t.Error("Expected the earlier decision to be reused")
+		}
+		if admin.HasNamedPermission("billing.write") {
+			t.Error("Expected new checks without an RBAC manager to be denied")
+		}
+	})
+}
@@ -0,0 +1,130 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+
+	"github.com/grzegorzmaniak/gothic/helpers"
+	"go.uber.org/zap"
+)
+
+const (
+	DefaultHandlerTaskLimit = 16
+)
+
+// taskGroup is a bounded group of sub-tasks tied to a request. The first task to fail cancels the
+// group's context, and the whole group is cancelled once the response has been written.
+type taskGroup struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	sem    chan struct{}
+	wg     sync.WaitGroup
+
+	errOnce sync.Once
+	err     error
+}
+
+func newTaskGroup(parent context.Context, limit int) *taskGroup {
+	if parent == nil {
+		parent = context.Background()
+	}
+	if limit <= 0 {
+		limit = DefaultHandlerTaskLimit
+	}
+
+	ctx, cancel := context.WithCancel(parent)
+	return &taskGroup{
+		ctx:    ctx,
+		cancel: cancel,
+		sem:    make(chan struct{}, limit),
+	}
+}
+
+func (g *taskGroup) fail(err error) {
+	g.errOnce.Do(func() {
+		g.err = err
+		g.cancel()
+	})
+}
+
+func (g *taskGroup) run(fn func(ctx context.Context) error) {
+	// - Acquire a slot, giving up if the group has been cancelled while waiting.
+	select {
+	case g.sem <- struct{}{}:
+	case <-g.ctx.Done():
+		g.fail(g.ctx.Err())
+		return
+	}
+
+	g.wg.Add(1)
+	go func() {
+		defer func() {
+			<-g.sem
+			g.wg.Done()
+		}()
+
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				helpers.Logger(g.ctx).Error("Handler sub-task panicked", zap.Any("panic", recovered), zap.ByteString("stack", debug.Stack()))
+				g.fail(fmt.Errorf("handler sub-task panicked: %v", recovered))
+			}
+		}()
+
+		if err := fn(g.ctx); err != nil {
+			g.fail(err)
+		}
+	}()
+}
+
+func (g *taskGroup) wait() error {
+	g.wg.Wait()
+	return g.err
+}
+
+// close cancels the group's context, signalling every still running task to stop.
+func (g *taskGroup) close() {
+	g.cancel()
+}
+
+func (h *Handler[BaseRoute]) taskGroup() *taskGroup {
+	if h.tasks == nil {
+		var parent context.Context
+		if h.Context != nil && h.Context.Request != nil {
+			parent = h.Context.Request.Context()
+		}
+		h.tasks = newTaskGroup(parent, DefaultHandlerTaskLimit)
+	}
+	return h.tasks
+}
+
+// Go runs fn as a sub-task of the current request. The context passed to fn is cancelled when:
+//   - the request context is cancelled (client disconnects),
+//   - another sub-task returns an error or panics,
+//   - or the response has been written by the executor.
+//
+// Panics are recovered and converted into errors. The number of concurrently running sub-tasks is bounded
+// by APIConfiguration.MaxHandlerTasks, Go blocks until a slot is available.
+// Go and Wait must be called from the handler's goroutine.
+func (h *Handler[BaseRoute]) Go(fn func(ctx context.Context) error) {
+	if fn == nil {
+		return
+	}
+	h.taskGroup().run(fn)
+}
+
+// Wait blocks until every sub-task started with Go has finished, returning the first error (if any).
+func (h *Handler[BaseRoute]) Wait() error {
+	if h.tasks == nil {
+		return nil
+	}
+	return h.tasks.wait()
+}
+
+// closeTasks cancels any still running sub-tasks, called by the executor once the response is written.
+func (h *Handler[BaseRoute]) closeTasks() {
+	if h.tasks != nil {
+		h.tasks.close()
+	}
+}
@@ -0,0 +1,105 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type testBaseRoute struct{}
+
+func TestHandler_Go(t *testing.T) {
+	t.Run("Runs tasks and waits for them", func(t *testing.T) {
+		h := &Handler[*testBaseRoute]{}
+		var count int32
+		for i := 0; i < 10; i++ {
+			h.Go(func(ctx context.Context) error {
+				atomic.AddInt32(&count, 1)
+				return nil
+			})
+		}
+
+		if err := h.Wait(); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if atomic.LoadInt32(&count) != 10 {
+			t.Errorf("Expected 10 tasks to run, got %d", count)
+		}
+	})
+
+	t.Run("First error cancels the remaining tasks", func(t *testing.T) {
+		h := &Handler[*testBaseRoute]{}
+		expected := errors.New("backend failed")
+		cancelled := make(chan struct{})
+
+		h.Go(func(ctx context.Context) error {
+			<-ctx.Done()
+			close(cancelled)
+			return nil
+		})
+		h.Go(func(ctx context.Context) error {
+			return expected
+		})
+
+		if err := h.Wait(); !errors.Is(err, expected) {
+			t.Fatalf("Expected '%v', got '%v'", expected, err)
+		}
+		select {
+		case <-cancelled:
+		case <-time.After(time.Second):
+			t.Fatal("Expected the sibling task to be cancelled")
+		}
+	})
+
+	t.Run("Panics are converted into errors", func(t *testing.T) {
+		h := &Handler[*testBaseRoute]{}
+		h.Go(func(ctx context.Context) error {
+			panic("boom")
+		})
+
+		if err := h.Wait(); err == nil {
+			t.Fatal("Expected the panic to be returned as an error")
+		}
+	})
+
+	t.Run("Concurrency is bounded", func(t *testing.T) {
+		h := &Handler[*testBaseRoute]{tasks: newTaskGroup(context.Background(), 2)}
+		var running, peak int32
+		for i := 0; i < 8; i++ {
+			h.Go(func(ctx context.Context) error {
+				current := atomic.AddInt32(&running, 1)
+				for {
+					old := atomic.LoadInt32(&peak)
+					if current <= old || atomic.CompareAndSwapInt32(&peak, old, current) {
+						break
+					}
+				}
+				time.Sleep(5 * time.Millisecond)
+				atomic.AddInt32(&running, -1)
+				return nil
+			})
+		}
+
+		if err := h.Wait(); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if peak > 2 {
+			t.Errorf("Expected at most 2 concurrent tasks, got %d", peak)
+		}
+	})
+
+	t.Run("Closing the handler cancels running tasks", func(t *testing.T) {
+		h := &Handler[*testBaseRoute]{}
+		h.Go(func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		})
+
+		h.closeTasks()
+		if err := h.Wait(); !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+	})
+}
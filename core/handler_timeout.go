@@ -0,0 +1,251 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/errors"
+	"github.com/grzegorzmaniak/gothic/helpers"
+	"go.uber.org/zap"
+)
+
+// handlerTimeout runs a route's handler against its HandlerTimeout. The deadline is set on the request context
+// before the Handler is created, so the handler, its sub-tasks and everything it calls with
+// Context.Request.Context() see it.
+type handlerTimeout struct {
+	timeout time.Duration
+	cancel  context.CancelFunc
+	writer  *timeoutWriter
+}
+
+// startHandlerTimeout sets the route's deadline on the request, it returns nil when the route has none.
+func startHandlerTimeout(ctx *gin.Context, sessionConfig *APIConfiguration) *handlerTimeout {
+	if sessionConfig.HandlerTimeout <= 0 {
+		return nil
+	}
+
+	deadlineCtx, cancel := context.WithTimeout(ctx.Request.Context(), sessionConfig.HandlerTimeout)
+	ctx.Request = ctx.Request.WithContext(deadlineCtx)
+	writer := &timeoutWriter{ResponseWriter: ctx.Writer, header: ctx.Writer.Header().Clone()}
+	ctx.Writer = writer
+	return &handlerTimeout{timeout: sessionConfig.HandlerTimeout, cancel: cancel, writer: writer}
+}
+
+// stop releases the deadline, it is safe to call on nil.
+func (t *handlerTimeout) stop() {
+	if t != nil {
+		t.cancel()
+	}
+}
+
+// run calls handle, answering 504 Gateway Timeout once the deadline passes. The response is written exactly once:
+// after the 504 the handler's writes are discarded, and when a ManualResponse handler already started its
+// response it is left to finish it. The handler is still waited for, so the request's gin.Context is never
+// reused under it, but the client has its complete 504 at the deadline.
+func (t *handlerTimeout) run(ctx *gin.Context, handle func() (*routeResponse, *errors.AppError)) (*routeResponse, *errors.AppError) {
+	if t == nil {
+		return handle()
+	}
+
+	type result struct {
+		response *routeResponse
+		appErr   *errors.AppError
+	}
+	done := make(chan result, 1)
+	go func() {
+		response, appErr := handle()
+		done <- result{response, appErr}
+	}()
+
+	select {
+	case finished := <-done:
+		return finished.response, finished.appErr
+	case <-ctx.Request.Context().Done():
+	}
+
+	// - The handler may have finished at the deadline, its result wins
+	select {
+	case finished := <-done:
+		return finished.response, finished.appErr
+	default:
+	}
+
+	appErr := errors.NewGatewayTimeout("", ctx.Request.Context().Err())
+	if t.writer.expire(ctx, appErr) {
+		helpers.Logger(ctx).Warn("Route handler timed out", zap.String("path", ctx.FullPath()), zap.Duration("timeout", t.timeout))
+	} else {
+		helpers.Logger(ctx).Warn("Route handler timed out after it started its response", zap.String("path", ctx.FullPath()), zap.Duration("timeout", t.timeout))
+	}
+	<-done
+	return nil, appErr
+}
+
+// timeoutWriter passes the handler's writes through until the deadline, then discards them. Like
+// http.TimeoutHandler, the handler sets its headers on a map of its own, copied to the response when it starts
+// writing: the 504 is sent while the handler may still be setting headers.
+type timeoutWriter struct {
+	gin.ResponseWriter
+
+	mu       sync.Mutex
+	header   http.Header
+	copied   bool
+	timedOut bool
+	started  bool
+}
+
+// Header returns the handler's header map, it is only sent if the handler writes before the deadline.
+func (w *timeoutWriter) Header() http.Header {
+	return w.header
+}
+
+// copyHeader replaces the response's headers with the handler's before its first write, the caller holds the lock.
+func (w *timeoutWriter) copyHeader() {
+	if w.copied {
+		return
+	}
+	w.copied = true
+	header := w.ResponseWriter.Header()
+	for name := range header {
+		if _, ok := w.header[name]; !ok {
+			delete(header, name)
+		}
+	}
+	for name, values := range w.header {
+		header[name] = values
+	}
+}
+
+// expire sends the 504 unless the handler started its response, the handler's later writes are discarded.
+func (w *timeoutWriter) expire(ctx *gin.Context, appErr *errors.AppError) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.timedOut = true
+	ctx.Set(helpers.ResponseErrorContextKey, appErr)
+	if w.started || w.ResponseWriter.Written() {
+		return false
+	}
+	if helpers.ResponseCaptured(ctx) {
+		return true
+	}
+
+	// - The error is rendered on a copy, the handler may still use the request's context
+	rendered := &bufferedWriter{ResponseWriter: w.ResponseWriter, header: http.Header{}, status: http.StatusOK}
+	errorCtx := ctx.Copy()
+	errorCtx.Writer = rendered
+	helpers.ErrorResponse(errorCtx, appErr)
+
+	// - With a Content-Length the client has the whole response now, not when the handler returns. Only the
+	//   response's own headers are written, the handler may still be setting its map
+	header := w.ResponseWriter.Header()
+	for name, values := range rendered.header {
+		header[name] = values
+	}
+	header.Set("Content-Length", strconv.Itoa(rendered.body.Len()))
+	w.ResponseWriter.WriteHeader(rendered.status)
+	_, _ = w.ResponseWriter.Write(rendered.body.Bytes())
+	w.ResponseWriter.Flush()
+	return true
+}
+
+func (w *timeoutWriter) WriteHeader(code int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.timedOut {
+		w.ResponseWriter.WriteHeader(code)
+	}
+}
+
+func (w *timeoutWriter) WriteHeaderNow() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.timedOut {
+		w.started = true
+		w.copyHeader()
+		w.ResponseWriter.WriteHeaderNow()
+	}
+}
+
+func (w *timeoutWriter) Write(data []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	w.started = true
+	w.copyHeader()
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *timeoutWriter) WriteString(data string) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	w.started = true
+	w.copyHeader()
+	return w.ResponseWriter.WriteString(data)
+}
+
+func (w *timeoutWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.timedOut {
+		w.started = true
+		w.copyHeader()
+		w.ResponseWriter.Flush()
+	}
+}
+
+func (w *timeoutWriter) Written() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.timedOut || w.ResponseWriter.Written()
+}
+
+func (w *timeoutWriter) Status() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.ResponseWriter.Status()
+}
+
+func (w *timeoutWriter) Size() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.ResponseWriter.Size()
+}
+
+// bufferedWriter captures a rendered response, the embedded writer is never written to.
+type bufferedWriter struct {
+	gin.ResponseWriter
+
+	header  http.Header
+	status  int
+	written bool
+	body    bytes.Buffer
+}
+
+func (w *bufferedWriter) Header() http.Header { return w.header }
+func (w *bufferedWriter) WriteHeader(code int) {
+	if !w.written {
+		w.status = code
+	}
+}
+func (w *bufferedWriter) WriteHeaderNow() { w.written = true }
+func (w *bufferedWriter) Write(data []byte) (int, error) {
+	w.written = true
+	return w.body.Write(data)
+}
+func (w *bufferedWriter) WriteString(data string) (int, error) {
+	w.written = true
+	return w.body.WriteString(data)
+}
+func (w *bufferedWriter) Flush()        {}
+func (w *bufferedWriter) Written() bool { return w.written }
+func (w *bufferedWriter) Status() int   { return w.status }
+func (w *bufferedWriter) Size() int     { return w.body.Len() }
@@ -0,0 +1,115 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/errors"
+)
+
+func TestHandlerTimeout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mgr := newMockSessionManager(t)
+
+	type output struct {
+		Message string `json:"message"`
+	}
+	var sawDeadline atomic.Bool
+	release := make(chan struct{})
+
+	router := gin.New()
+	ctor := NewRouteConstructor(router, testBaseRoute{}, mgr, nil)
+	GET(ctor, "/slow", PublicRoute().WithHandlerTimeout(20*time.Millisecond), func(_ *struct{}, data *Handler[testBaseRoute]) (*output, *errors.AppError) {
+		<-data.Context.Request.Context().Done()
+		sawDeadline.Store(true)
+		time.Sleep(10 * time.Millisecond)
+		return &output{Message: "late"}, nil
+	})
+	GET(ctor, "/fast", PublicRoute().WithHandlerTimeout(time.Second), func(_ *struct{}, _ *Handler[testBaseRoute]) (*output, *errors.AppError) {
+		return &output{Message: "fast"}, nil
+	})
+	GET(ctor, "/manual-late", PublicRoute().WithManualResponse().WithHandlerTimeout(20*time.Millisecond), func(_ *struct{}, data *Handler[testBaseRoute]) (*struct{}, *errors.AppError) {
+		<-data.Context.Request.Context().Done()
+		time.Sleep(10 * time.Millisecond)
+		data.Context.String(http.StatusOK, "late write")
+		return nil, nil
+	})
+	GET(ctor, "/manual-started", PublicRoute().WithManualResponse().WithHandlerTimeout(20*time.Millisecond), func(_ *struct{}, data *Handler[testBaseRoute]) (*struct{}, *errors.AppError) {
+		data.Context.Status(http.StatusAccepted)
+		_, _ = data.Context.Writer.WriteString("started")
+		<-release
+		return nil, nil
+	})
+
+	GET(ctor, "/slow-headers", PublicRoute().WithHandlerTimeout(5*time.Millisecond), func(_ *struct{}, data *Handler[testBaseRoute]) (*output, *errors.AppError) {
+		// - Keeps setting headers while the 504 is sent, the race detector catches a shared header map
+		var stop <-chan time.Time
+		for i := 0; ; i++ {
+			data.Context.Header("X-Progress", strconv.Itoa(i))
+			select {
+			case <-stop:
+				return &output{Message: "late"}, nil
+			case <-data.Context.Request.Context().Done():
+				if stop == nil {
+					stop = time.After(20 * time.Millisecond)
+				}
+			default:
+			}
+		}
+	})
+	request := func(path string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		return recorder
+	}
+
+	t.Run("Slow handlers get a 504", func(t *testing.T) {
+		recorder := request("/slow")
+		if recorder.Code != http.StatusGatewayTimeout || strings.Contains(recorder.Body.String(), "late") {
+			t.Fatalf("Expected a 504 without the handler's output, got %d: %s", recorder.Code, recorder.Body.String())
+		}
+		if !sawDeadline.Load() {
+			t.Error("Expected the deadline to be set on the request context")
+		}
+		if recorder.Header().Get("Content-Length") != strconv.Itoa(recorder.Body.Len()) || recorder.Body.Len() == 0 {
+			t.Errorf("Expected the 504 to be sent with its Content-Length, got '%s'", recorder.Header().Get("Content-Length"))
+		}
+	})
+
+	t.Run("Fast handlers are not affected", func(t *testing.T) {
+		if recorder := request("/fast"); recorder.Code != http.StatusOK || !strings.Contains(recorder.Body.String(), "fast") {
+			t.Errorf("Expected the handler's output, got %d: %s", recorder.Code, recorder.Body.String())
+		}
+	})
+
+	t.Run("Headers set after the deadline are not sent", func(t *testing.T) {
+		recorder := request("/slow-headers")
+		if recorder.Code != http.StatusGatewayTimeout || recorder.Header().Get("X-Progress") != "" {
+			t.Errorf("Expected a 504 without the handler's headers, got %d: %v", recorder.Code, recorder.Header())
+		}
+		if recorder.Header().Get("X-Request-ID") == "" {
+			t.Error("Expected the headers set before the handler to be sent")
+		}
+	})
+
+	t.Run("Late manual writes are discarded", func(t *testing.T) {
+		recorder := request("/manual-late")
+		if recorder.Code != http.StatusGatewayTimeout || strings.Contains(recorder.Body.String(), "late write") {
+			t.Errorf("Expected only the 504 to be written, got %d: %s", recorder.Code, recorder.Body.String())
+		}
+	})
+
+	t.Run("Started manual responses are left to the handler", func(t *testing.T) {
+		time.AfterFunc(50*time.Millisecond, func() { close(release) })
+		recorder := request("/manual-started")
+		if recorder.Code != http.StatusAccepted || recorder.Body.String() != "started" {
+			t.Errorf("Expected the handler's response alone, got %d: %s", recorder.Code, recorder.Body.String())
+		}
+	})
+}
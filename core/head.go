@@ -0,0 +1,59 @@
+package core
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// headWriter discards the body of a HEAD response, counting it for the Content-Length.
+type headWriter struct {
+	gin.ResponseWriter
+	size  int
+	wrote bool
+}
+
+func (w *headWriter) Write(data []byte) (int, error) {
+	w.wrote = true
+	w.size += len(data)
+	return len(data), nil
+}
+
+func (w *headWriter) WriteString(data string) (int, error) {
+	w.wrote = true
+	w.size += len(data)
+	return len(data), nil
+}
+
+func (w *headWriter) Written() bool {
+	return w.wrote || w.ResponseWriter.Written()
+}
+
+func (w *headWriter) Size() int {
+	if w.wrote {
+		return w.size
+	}
+	return w.ResponseWriter.Size()
+}
+
+// finish sends the status and headers the GET response would have had.
+func (w *headWriter) finish() {
+	if w.ResponseWriter.Written() {
+		return
+	}
+	if w.size > 0 && w.Header().Get("Content-Length") == "" {
+		w.Header().Set("Content-Length", strconv.Itoa(w.size))
+	}
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+// serveHead runs the handler of a GET route for a HEAD request, so it goes through the same session, RBAC and
+// output checks, and answers with the status and headers of the GET response without its body.
+func serveHead(handler gin.HandlerFunc) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		writer := &headWriter{ResponseWriter: ctx.Writer}
+		ctx.Writer = writer
+		handler(ctx)
+		writer.finish()
+	}
+}
@@ -0,0 +1,64 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/errors"
+)
+
+func TestHeadRequests(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mgr := newMockSessionManager(t)
+
+	type output struct {
+		Message string `json:"message" validate:"required"`
+	}
+	calls := 0
+
+	router := gin.New()
+	ctor := NewRouteConstructor(router, testBaseRoute{}, mgr, nil)
+	GET(ctor, "/status", PublicRoute(), func(_ *struct{}, _ *Handler[testBaseRoute]) (*output, *errors.AppError) {
+		calls++
+		return &output{Message: "up"}, nil
+	})
+	GET(ctor, "/invalid", PublicRoute(), func(_ *struct{}, _ *Handler[testBaseRoute]) (*output, *errors.AppError) {
+		return &output{}, nil
+	})
+	GET(ctor, "/me", AuthenticatedJSONAPI(), func(_ *struct{}, _ *Handler[testBaseRoute]) (*output, *errors.AppError) {
+		return &output{Message: "me"}, nil
+	})
+
+	request := func(method string, path string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(method, path, nil))
+		return recorder
+	}
+
+	t.Run("HEAD answers like GET without the body", func(t *testing.T) {
+		get := request(http.MethodGet, "/status")
+		head := request(http.MethodHead, "/status")
+		if head.Code != http.StatusOK || head.Body.Len() != 0 || calls != 2 {
+			t.Fatalf("Expected a 200 without a body from the handler, got %d: %s", head.Code, head.Body.String())
+		}
+		if head.Header().Get("Content-Length") != strconv.Itoa(get.Body.Len()) {
+			t.Errorf("Expected the Content-Length of the GET response, got '%s'", head.Header().Get("Content-Length"))
+		}
+		if head.Header().Get("Content-Type") != get.Header().Get("Content-Type") {
+			t.Errorf("Expected the Content-Type of the GET response, got '%s'", head.Header().Get("Content-Type"))
+		}
+	})
+
+	t.Run("HEAD goes through the session and output checks", func(t *testing.T) {
+		if head := request(http.MethodHead, "/me"); head.Code != http.StatusUnauthorized || head.Body.Len() != 0 {
+			t.Errorf("Expected a 401 without a body, got %d: %s", head.Code, head.Body.String())
+		}
+		get, head := request(http.MethodGet, "/invalid"), request(http.MethodHead, "/invalid")
+		if head.Code == http.StatusOK || head.Code != get.Code || head.Body.Len() != 0 {
+			t.Errorf("Expected the invalid output to fail like GET without a body, got %d: %s", head.Code, head.Body.String())
+		}
+	})
+}
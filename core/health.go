@@ -0,0 +1,200 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/eko/gocache/lib/v4/store"
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/helpers"
+	"go.uber.org/zap"
+)
+
+const (
+	HealthzPath = "/healthz"
+	ReadyzPath  = "/readyz"
+
+	HealthStatusOk   = "ok"
+	HealthStatusFail = "fail"
+
+	HealthComponentCache       = "cache"
+	HealthComponentSessionKey  = "session_key"
+	HealthComponentRbacCache   = "rbac_cache"
+	HealthComponentRbacManager = "rbac_manager"
+
+	DefaultHealthCheckTimeout = 2 * time.Second
+	HealthProbeCacheKey       = "health:probe"
+)
+
+// HealthChecker is implemented by session managers, RBAC managers and caches that can check their own
+// dependencies, e.g., ping the database the roles are loaded from. NewHealthHandlers calls it on readiness checks.
+type HealthChecker interface {
+	CheckHealth(ctx context.Context) error
+}
+
+// ComponentHealth is the status of a dependency checked by the readiness endpoint.
+type ComponentHealth struct {
+	Status     string `json:"status"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+// HealthReport is the response of the health endpoints.
+type HealthReport struct {
+	Status     string                     `json:"status"`
+	Components map[string]ComponentHealth `json:"components,omitempty"`
+}
+
+// HealthHandlers serves the liveness and readiness endpoints of a service, see NewHealthHandlers.
+type HealthHandlers struct {
+	SessionManager SessionManager
+
+	// Timeout bounds each dependency check, checks past it fail (Default: DefaultHealthCheckTimeout)
+	Timeout time.Duration
+}
+
+// NewHealthHandlers returns the health endpoints of the session manager's dependencies. Liveness always answers
+// 200 while the process serves requests, Readiness answers 503 unless the cache accepts writes, the session key
+// is available and the RBAC manager (if any) is reachable. Mount them with Register, or on your own paths, e.g.:
+//
+//	core.NewHealthHandlers(sessionManager).Register(router)
+func NewHealthHandlers(sessionManager SessionManager) *HealthHandlers {
+	return &HealthHandlers{SessionManager: sessionManager, Timeout: DefaultHealthCheckTimeout}
+}
+
+// Register mounts Liveness on HealthzPath and Readiness on ReadyzPath, for GET and HEAD requests.
+func (h *HealthHandlers) Register(router gin.IRoutes) {
+	router.GET(HealthzPath, h.Liveness)
+	router.HEAD(HealthzPath, h.Liveness)
+	router.GET(ReadyzPath, h.Readiness)
+	router.HEAD(ReadyzPath, h.Readiness)
+}
+
+// Liveness answers 200, it checks no dependencies so a failing cache never gets the process restarted.
+func (h *HealthHandlers) Liveness(ctx *gin.Context) {
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(http.StatusOK, HealthReport{Status: HealthStatusOk})
+}
+
+// Readiness checks the dependencies concurrently and answers 200 if they all pass, 503 otherwise. The response
+// lists each component's status, the failures themselves are logged rather than sent.
+func (h *HealthHandlers) Readiness(ctx *gin.Context) {
+	ctx.Header("Cache-Control", "no-store")
+	report := h.Check(ctx)
+	status := http.StatusOK
+	if report.Status != HealthStatusOk {
+		status = http.StatusServiceUnavailable
+	}
+	ctx.JSON(status, report)
+}
+
+// Check runs the readiness checks, see Readiness.
+func (h *HealthHandlers) Check(ctx *gin.Context) HealthReport {
+	checks := h.checks()
+	report := HealthReport{Status: HealthStatusOk, Components: make(map[string]ComponentHealth, len(checks))}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			component := h.run(ctx, name, check)
+			mu.Lock()
+			defer mu.Unlock()
+			report.Components[name] = component
+			if component.Status != HealthStatusOk {
+				report.Status = HealthStatusFail
+			}
+		}()
+	}
+	wg.Wait()
+	return report
+}
+
+// checks returns the checks of the configured dependencies by component.
+func (h *HealthHandlers) checks() map[string]func(ctx context.Context) error {
+	sessionManager := h.SessionManager
+	if sessionManager == nil {
+		return map[string]func(ctx context.Context) error{
+			HealthComponentSessionKey: func(context.Context) error { return fmt.Errorf("session manager is nil") },
+		}
+	}
+
+	checks := map[string]func(ctx context.Context) error{
+		HealthComponentCache: func(ctx context.Context) error {
+			cache, err := sessionManager.GetCache()
+			if err != nil || cache == nil {
+				return fmt.Errorf("failed to get cache: %w", err)
+			}
+			if err = cache.Set(ctx, HealthProbeCacheKey, []byte(HealthStatusOk), store.WithExpiration(time.Minute)); err != nil {
+				return fmt.Errorf("failed to write to the cache: %w", err)
+			}
+			return checkHealth(ctx, cache)
+		},
+		HealthComponentSessionKey: func(ctx context.Context) error {
+			key, keyId, err := sessionManager.GetSessionKey()
+			if err != nil {
+				return fmt.Errorf("failed to get the session key: %w", err)
+			}
+			if len(key) == 0 || len(keyId) < MinimumSessionKeyIdSize || len(keyId) > MaximumSessionKeyIdSize {
+				return fmt.Errorf("session key is invalid")
+			}
+			return checkHealth(ctx, sessionManager)
+		},
+	}
+
+	if rbacManager := sessionManager.GetRbacManager(); rbacManager != nil {
+		checks[HealthComponentRbacCache] = func(ctx context.Context) error {
+			cache, err := rbacManager.GetCache()
+			if err != nil || cache == nil {
+				return fmt.Errorf("failed to get the RBAC cache: %w", err)
+			}
+			return checkHealth(ctx, cache)
+		}
+		checks[HealthComponentRbacManager] = func(ctx context.Context) error {
+			return checkHealth(ctx, rbacManager)
+		}
+	}
+	return checks
+}
+
+// run runs a check with the timeout, a check that doesn't return in time is left running and reported failed.
+func (h *HealthHandlers) run(ctx *gin.Context, name string, check func(ctx context.Context) error) ComponentHealth {
+	timeout := helpers.DefaultTimeDuration(h.Timeout, DefaultHealthCheckTimeout)
+	checkCtx, cancel := context.WithTimeout(ctx.Request.Context(), timeout)
+	defer cancel()
+
+	started := time.Now()
+	done := make(chan error, 1)
+	go func() { done <- check(checkCtx) }()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-checkCtx.Done():
+		err = fmt.Errorf("timed out after %s", timeout)
+	}
+
+	component := ComponentHealth{Status: HealthStatusOk, DurationMs: time.Since(started).Milliseconds()}
+	if err != nil {
+		helpers.Logger(ctx).Warn("Health check failed", zap.String("component", name), zap.Error(err))
+		component.Status = HealthStatusFail
+		component.Error = "unavailable"
+		if checkCtx.Err() != nil {
+			component.Error = "timed out"
+		}
+	}
+	return component
+}
+
+// checkHealth calls CheckHealth when the dependency implements HealthChecker.
+func checkHealth(ctx context.Context, dependency interface{}) error {
+	if checker, ok := dependency.(HealthChecker); ok {
+		return checker.CheckHealth(ctx)
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	internalcache "github.com/grzegorzmaniak/gothic/cache"
+	"github.com/grzegorzmaniak/gothic/rbac"
+)
+
+// checkedRbacManager reports the health of its source, blocking until the context is done when hang is set.
+type checkedRbacManager struct {
+	*namedRbacManager
+	failing atomic.Bool
+	hang    atomic.Bool
+}
+
+func (m *checkedRbacManager) CheckHealth(ctx context.Context) error {
+	if m.hang.Load() {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	if m.failing.Load() {
+		return fmt.Errorf("database is down")
+	}
+	return nil
+}
+
+func TestHealthHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mgr := newMockSessionManager(t)
+	rbacManager := &checkedRbacManager{namedRbacManager: &namedRbacManager{
+		cacheManager: internalcache.BuildDefaultCacheManager(nil),
+		named:        map[string]rbac.PermissionSet{},
+	}}
+	mgr.rbacManager = rbacManager
+
+	health := NewHealthHandlers(mgr)
+	health.Timeout = 250 * time.Millisecond
+	router := gin.New()
+	health.Register(router)
+
+	ready := func() (int, HealthReport) {
+		t.Helper()
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, ReadyzPath, nil))
+		var report HealthReport
+		if err := json.Unmarshal(recorder.Body.Bytes(), &report); err != nil {
+			t.Fatalf("Failed to decode the report: %v", err)
+		}
+		return recorder.Code, report
+	}
+
+	t.Run("Liveness checks nothing", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, HealthzPath, nil))
+		if recorder.Code != http.StatusOK || recorder.Body.String() != `{"status":"ok"}` {
+			t.Errorf("Expected a 200 ok, got %d: %s", recorder.Code, recorder.Body.String())
+		}
+	})
+
+	t.Run("Readiness reports every component", func(t *testing.T) {
+		code, report := ready()
+		if code != http.StatusOK || report.Status != HealthStatusOk {
+			t.Fatalf("Expected a 200 ok, got %d: %+v", code, report)
+		}
+		for _, component := range []string{HealthComponentCache, HealthComponentSessionKey, HealthComponentRbacCache, HealthComponentRbacManager} {
+			if report.Components[component].Status != HealthStatusOk {
+				t.Errorf("Expected %s to be ok, got %+v", component, report.Components[component])
+			}
+		}
+	})
+
+	t.Run("Failing components fail the readiness", func(t *testing.T) {
+		rbacManager.failing.Store(true)
+		defer rbacManager.failing.Store(false)
+		code, report := ready()
+		if code != http.StatusServiceUnavailable || report.Status != HealthStatusFail {
+			t.Fatalf("Expected a 503, got %d: %+v", code, report)
+		}
+		if component := report.Components[HealthComponentRbacManager]; component.Status != HealthStatusFail || component.Error != "unavailable" {
+			t.Errorf("Expected the RBAC manager to fail without its error, got %+v", component)
+		}
+		if report.Components[HealthComponentCache].Status != HealthStatusOk {
+			t.Errorf("Expected the cache to stay ok, got %+v", report.Components[HealthComponentCache])
+		}
+	})
+
+	t.Run("Slow components time out", func(t *testing.T) {
+		rbacManager.hang.Store(true)
+		defer rbacManager.hang.Store(false)
+		code, report := ready()
+		if code != http.StatusServiceUnavailable || report.Components[HealthComponentRbacManager].Error != "timed out" {
+			t.Errorf("Expected the RBAC manager to time out, got %d: %+v", code, report)
+		}
+	})
+
+	t.Run("A missing session key fails the readiness", func(t *testing.T) {
+		mgr.currentKeyId = "missing"
+		defer func() { mgr.currentKeyId = "key-1" }()
+		if code, report := ready(); code != http.StatusServiceUnavailable || report.Components[HealthComponentSessionKey].Status != HealthStatusFail {
+			t.Errorf("Expected the session key to fail, got %d: %+v", code, report)
+		}
+	})
+}
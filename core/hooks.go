@@ -0,0 +1,144 @@
+package core
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/errors"
+)
+
+// HookStage is the point of the route lifecycle a Hook runs at.
+type HookStage string
+
+const (
+	HookPreSession  HookStage = "pre_session"  // Before the session is extracted, Claims is nil
+	HookPostSession HookStage = "post_session" // After the session is established, before the risk, auth level and RBAC checks
+	HookPreHandler  HookStage = "pre_handler"  // After the input is validated and the policy passed, before the handler
+	HookPostHandler HookStage = "post_handler" // After the handler returned, before its output is validated and sent
+
+	constructorHooksContextKey = "gothic_constructor_hooks"
+)
+
+// HookContext is what a Hook sees of the request. Claims may be changed in place from HookPostSession on, e.g.,
+// to resolve a tenant, the checks and the handler see the change.
+type HookContext struct {
+	Stage          HookStage
+	Context        *gin.Context
+	Config         *APIConfiguration
+	SessionManager SessionManager
+	SessionHeader  *SessionHeader
+	Claims         *SessionClaims
+	SessionGroup   string
+
+	// Input is the validated input, from HookPreHandler on (*InputType, or the map of dynamic routes).
+	Input interface{}
+
+	// Output and Error are what the handler returned, at HookPostHandler.
+	Output interface{}
+	Error  *errors.AppError
+
+	// Values are shared by the hooks of the request and its handler (Handler.Values), e.g., to pass on a record
+	// a HookPreHandler hook loaded.
+	Values *Values
+}
+
+// Hook runs cross-cutting logic inside the route lifecycle, e.g., feature flags that need the claims. A returned
+// error stops the request and is sent instead of the response.
+type Hook func(hook *HookContext) *errors.AppError
+
+// Hooks are the hooks of each stage, they run in order. Hooks of the RouteConstructor (AddHooks) run before the
+// ones of the route (APIConfiguration.Hooks).
+type Hooks struct {
+	PreSession  []Hook
+	PostSession []Hook
+	PreHandler  []Hook
+	PostHandler []Hook
+}
+
+func (h *Hooks) stage(stage HookStage) []Hook {
+	if h == nil {
+		return nil
+	}
+	switch stage {
+	case HookPreSession:
+		return h.PreSession
+	case HookPostSession:
+		return h.PostSession
+	case HookPreHandler:
+		return h.PreHandler
+	case HookPostHandler:
+		return h.PostHandler
+	}
+	return nil
+}
+
+// merge returns the hooks of h followed by the ones of other.
+func (h *Hooks) merge(other Hooks) *Hooks {
+	merged := &Hooks{}
+	if h != nil {
+		*merged = Hooks{
+			PreSession:  append([]Hook(nil), h.PreSession...),
+			PostSession: append([]Hook(nil), h.PostSession...),
+			PreHandler:  append([]Hook(nil), h.PreHandler...),
+			PostHandler: append([]Hook(nil), h.PostHandler...),
+		}
+	}
+	merged.PreSession = append(merged.PreSession, other.PreSession...)
+	merged.PostSession = append(merged.PostSession, other.PostSession...)
+	merged.PreHandler = append(merged.PreHandler, other.PreHandler...)
+	merged.PostHandler = append(merged.PostHandler, other.PostHandler...)
+	return merged
+}
+
+// AddHooks adds hooks run for every route of the constructor, including routes registered before. Add them
+// while setting up the routes.
+func (ctor *RouteConstructor[BaseRoute]) AddHooks(hooks Hooks) {
+	for {
+		current := ctor.hooks.Load()
+		if ctor.hooks.CompareAndSwap(current, current.merge(hooks)) {
+			return
+		}
+	}
+}
+
+// bindHooks hands the constructor's hooks to the executor of the request.
+func (ctor *RouteConstructor[BaseRoute]) bindHooks(ctx *gin.Context) {
+	if hooks := ctor.hooks.Load(); hooks != nil {
+		ctx.Set(constructorHooksContextKey, hooks)
+	}
+}
+
+// runHooks runs the constructor's, then the route's hooks of the stage, stopping at the first error.
+func runHooks(hookCtx *HookContext) *errors.AppError {
+	var ctorHooks *Hooks
+	if value, ok := hookCtx.Context.Get(constructorHooksContextKey); ok {
+		ctorHooks, _ = value.(*Hooks)
+	}
+
+	for _, hooks := range [][]Hook{ctorHooks.stage(hookCtx.Stage), hookCtx.Config.Hooks.stage(hookCtx.Stage)} {
+		for _, hook := range hooks {
+			if appErr := hook(hookCtx); appErr != nil {
+				return appErr
+			}
+		}
+	}
+	return nil
+}
+
+// run runs the hooks of stage.
+func (h *HookContext) run(stage HookStage) *errors.AppError {
+	h.Stage = stage
+	return runHooks(h)
+}
+
+// established records the established session for the stages after HookPreSession.
+func (h *HookContext) established(header *SessionHeader, claims *SessionClaims, group string) {
+	h.SessionHeader, h.Claims, h.SessionGroup = header, claims, group
+}
+
+// handled runs the HookPostHandler hooks on the handler's result, a hook's error replaces it.
+func (h *HookContext) handled(output interface{}, appErr *errors.AppError) *errors.AppError {
+	h.Output, h.Error = output, appErr
+	if hookErr := h.run(HookPostHandler); hookErr != nil {
+		return hookErr
+	}
+	return appErr
+}
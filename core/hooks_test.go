@@ -0,0 +1,131 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/errors"
+	"github.com/grzegorzmaniak/gothic/validation"
+)
+
+func TestHooks(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mgr := newMockSessionManager(t)
+
+	type input struct {
+		Name string `form:"name"`
+	}
+	type output struct {
+		Message string `json:"message"`
+	}
+
+	var stages []string
+	record := func(name string) Hook {
+		return func(hook *HookContext) *errors.AppError {
+			stages = append(stages, name+":"+string(hook.Stage))
+			return nil
+		}
+	}
+
+	router := gin.New()
+	ctor := NewRouteConstructor(router, testBaseRoute{}, mgr, nil)
+	ctor.AddHooks(Hooks{
+		PreSession:  []Hook{record("ctor")},
+		PostSession: []Hook{record("ctor")},
+		PreHandler:  []Hook{record("ctor")},
+		PostHandler: []Hook{record("ctor")},
+	})
+
+	GET(ctor, "/ordered", PublicRoute().WithHooks(Hooks{
+		PreSession:  []Hook{record("route")},
+		PostSession: []Hook{record("route")},
+		PreHandler: []Hook{record("route"), func(hook *HookContext) *errors.AppError {
+			if in, ok := hook.Input.(*input); !ok || in.Name != "gothic" {
+				t.Errorf("Expected the validated input, got %#v", hook.Input)
+			}
+			return nil
+		}},
+		PostHandler: []Hook{record("route"), func(hook *HookContext) *errors.AppError {
+			if out, ok := hook.Output.(*output); ok && out != nil {
+				out.Message += " (hooked)"
+			}
+			return nil
+		}},
+	}), func(in *input, _ *Handler[testBaseRoute]) (*output, *errors.AppError) {
+		stages = append(stages, "handler")
+		return &output{Message: "hello " + in.Name}, nil
+	})
+
+	GET(ctor, "/denied", PublicRoute().WithHooks(Hooks{
+		PostSession: []Hook{func(*HookContext) *errors.AppError {
+			return errors.NewForbidden("feature disabled", nil)
+		}},
+	}), func(_ *struct{}, _ *Handler[testBaseRoute]) (*output, *errors.AppError) {
+		t.Error("Expected the handler not to run after a hook failed")
+		return &output{}, nil
+	})
+
+	GET(ctor, "/replaced", PublicRoute().WithHooks(Hooks{
+		PostHandler: []Hook{func(hook *HookContext) *errors.AppError {
+			if hook.Error == nil {
+				t.Error("Expected the handler's error")
+			}
+			return errors.NewConflict("replaced", nil)
+		}},
+	}), func(_ *struct{}, _ *Handler[testBaseRoute]) (*output, *errors.AppError) {
+		return nil, errors.NewBadRequest("original", nil)
+	})
+
+	DYNAMIC(ctor, http.MethodGet, "/dynamic", PublicRoute().WithHooks(Hooks{
+		PreHandler: []Hook{func(hook *HookContext) *errors.AppError {
+			if _, ok := hook.Input.(map[string]interface{}); !ok {
+				t.Errorf("Expected the dynamic input, got %#v", hook.Input)
+			}
+			return nil
+		}},
+	}), validation.FieldRules{}, validation.FieldRules{
+		"Message": {Tags: "required"},
+	}, func(_ map[string]interface{}, _ *Handler[testBaseRoute]) (map[string]any, *errors.AppError) {
+		return map[string]any{"Message": "dynamic"}, nil
+	})
+
+	request := func(path string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		return recorder
+	}
+
+	t.Run("Stages run in order, constructor hooks first", func(t *testing.T) {
+		stages = nil
+		recorder := request("/ordered?name=gothic")
+		if recorder.Code != http.StatusOK || !strings.Contains(recorder.Body.String(), "hello gothic (hooked)") {
+			t.Fatalf("Expected the hooked output, got %d: %s", recorder.Code, recorder.Body.String())
+		}
+		expected := "ctor:pre_session route:pre_session ctor:post_session route:post_session ctor:pre_handler " +
+			"route:pre_handler handler ctor:post_handler route:post_handler"
+		if got := strings.Join(stages, " "); got != expected {
+			t.Errorf("Expected '%s', got '%s'", expected, got)
+		}
+	})
+
+	t.Run("A hook error stops the request", func(t *testing.T) {
+		if recorder := request("/denied"); recorder.Code != http.StatusForbidden {
+			t.Errorf("Expected a 403, got %d", recorder.Code)
+		}
+	})
+
+	t.Run("A post handler error replaces the handler's", func(t *testing.T) {
+		if recorder := request("/replaced"); recorder.Code != http.StatusConflict {
+			t.Errorf("Expected a 409, got %d", recorder.Code)
+		}
+	})
+
+	t.Run("Dynamic routes run the hooks", func(t *testing.T) {
+		if recorder := request("/dynamic"); recorder.Code != http.StatusOK || !strings.Contains(recorder.Body.String(), "dynamic") {
+			t.Errorf("Expected the dynamic output, got %d: %s", recorder.Code, recorder.Body.String())
+		}
+	})
+}
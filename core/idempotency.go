@@ -0,0 +1,210 @@
+package core
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/eko/gocache/lib/v4/store"
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/errors"
+	"github.com/grzegorzmaniak/gothic/helpers"
+	"go.uber.org/zap"
+)
+
+const (
+	IdempotencyKeyHeader      = "Idempotency-Key"
+	IdempotentReplayedHeader  = "Idempotent-Replayed"
+	IdempotencyCacheKeyPrefix = "idempotency:" // Key: idempotency:<subjectIdentifier>|<idempotencyKey>
+
+	DefaultIdempotencyTTL          = 24 * time.Hour
+	DefaultMaxIdempotencyKeyLength = 255
+
+	// IdempotencyKeyReusedError is the "error" detail when a key is reused with a different request.
+	IdempotencyKeyReusedError = "idempotency_key_reused"
+
+	// IdempotencyKeyInFlightError is the "error" detail when a request with the same key is still running.
+	IdempotencyKeyInFlightError = "idempotency_key_in_flight"
+)
+
+// inFlightIdempotencyKeys holds the keys of requests that are currently executing, so a retry that arrives
+// before the first attempt has finished does not run the handler a second time.
+var inFlightIdempotencyKeys sync.Map
+
+// idempotentResponse is the response stored for an idempotency key.
+type idempotentResponse struct {
+	Fingerprint string            `json:"fingerprint"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	Body        json.RawMessage   `json:"body"`
+}
+
+func shouldApplyIdempotency(ctx *gin.Context, sessionConfig *APIConfiguration) bool {
+	if !sessionConfig.Idempotent || sessionConfig.ManualResponse || ctx.Request == nil || helpers.ResponseCaptured(ctx) {
+		return false
+	}
+
+	switch ctx.Request.Method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// idempotencyFingerprint identifies the request a key was first used with, the method, route and the
+// validated input.
+func idempotencyFingerprint(ctx *gin.Context, input interface{}) (string, error) {
+	body, err := json.Marshal(input)
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.New()
+	hash.Write([]byte(ctx.Request.Method + " " + ctx.FullPath() + "\n"))
+	hash.Write(body)
+	return base64.RawURLEncoding.EncodeToString(hash.Sum(nil)), nil
+}
+
+// replayIdempotentResponse decodes a stored response, flagged with the Idempotent-Replayed header.
+func replayIdempotentResponse(stored *idempotentResponse) (*routeResponse, error) {
+	response, err := decodeStoredResponse(stored.Headers, stored.Body)
+	if err != nil {
+		return nil, err
+	}
+	response.Headers[IdempotentReplayedHeader] = "true"
+	return response, nil
+}
+
+// decodeStoredResponse decodes a response stored in the cache, numbers are kept as json.Number so they are
+// replayed as sent. The headers are copied, so they can be extended.
+func decodeStoredResponse(storedHeaders map[string]string, storedBody json.RawMessage) (*routeResponse, error) {
+	var body interface{}
+	decoder := json.NewDecoder(bytes.NewReader(storedBody))
+	decoder.UseNumber()
+	if err := decoder.Decode(&body); err != nil {
+		return nil, err
+	}
+
+	headers := make(map[string]string, len(storedHeaders)+1)
+	for name, value := range storedHeaders {
+		headers[name] = value
+	}
+	return &routeResponse{Headers: headers, Body: body}, nil
+}
+
+// executeIdempotent runs fn once per Idempotency-Key, successful responses are stored for the route's
+// IdempotencyTTL and replayed to retries of the same request. Reusing a key for a different request is
+// rejected with 422, and a retry that arrives while the first attempt is still running with 409.
+//
+// Keys are scoped to the subject, requests without a session (or without the header) are executed as usual.
+func executeIdempotent(
+	ctx *gin.Context,
+	sessionManager SessionManager,
+	sessionConfig *APIConfiguration,
+	claims *SessionClaims,
+	input interface{},
+	fn func() (*routeResponse, *errors.AppError),
+) (*routeResponse, *errors.AppError) {
+	if !shouldApplyIdempotency(ctx, sessionConfig) {
+		return fn()
+	}
+
+	idempotencyKey := ctx.GetHeader(IdempotencyKeyHeader)
+	if idempotencyKey == "" {
+		return fn()
+	}
+	if len(idempotencyKey) > DefaultMaxIdempotencyKeyLength {
+		return nil, errors.NewBadRequest("Idempotency-Key is too long", nil)
+	}
+
+	if claims == nil || !claims.HasSession {
+		helpers.Logger(ctx).Debug("Idempotency-Key ignored, request has no session")
+		return fn()
+	}
+
+	subjectIdentifier, err := sessionManager.GetSubjectIdentifier(claims)
+	if err != nil || subjectIdentifier == "" {
+		// - Never risk replaying a response to another subject
+		helpers.Logger(ctx).Debug("Unable to get subject identifier, Idempotency-Key ignored", zap.Error(err))
+		return fn()
+	}
+
+	fingerprint, err := idempotencyFingerprint(ctx, input)
+	if err != nil {
+		return nil, errors.NewInternalServerError("Failed to fingerprint the request", err)
+	}
+
+	cacheInstance, err := sessionManager.GetCache()
+	if err != nil || cacheInstance == nil {
+		helpers.Logger(ctx).Warn("Cache is not available, Idempotency-Key ignored", zap.Error(err))
+		return fn()
+	}
+
+	// - Replay the stored response of a previous attempt
+	cacheKey := IdempotencyCacheKeyPrefix + subjectIdentifier + "|" + idempotencyKey
+	replay := func() (*routeResponse, *errors.AppError, bool) {
+		cached, getErr := cacheInstance.Get(ctx, cacheKey)
+		if getErr != nil {
+			return nil, nil, false
+		}
+		var stored idempotentResponse
+		if json.Unmarshal(cached, &stored) != nil {
+			return nil, nil, false
+		}
+		if stored.Fingerprint != fingerprint {
+			return nil, errors.NewAppError(http.StatusUnprocessableEntity, "Idempotency-Key was already used for a different request", nil, map[string]interface{}{
+				"error": IdempotencyKeyReusedError,
+			}), true
+		}
+
+		response, replayErr := replayIdempotentResponse(&stored)
+		if replayErr != nil {
+			helpers.Logger(ctx).Warn("Failed to decode stored idempotent response", zap.Error(replayErr))
+			return nil, nil, false
+		}
+		helpers.Logger(ctx).Debug("Replaying stored response for Idempotency-Key", zap.String("idempotency_key", idempotencyKey))
+		return response, nil, true
+	}
+	if response, appErr, replayed := replay(); replayed {
+		return response, appErr
+	}
+
+	if _, running := inFlightIdempotencyKeys.LoadOrStore(cacheKey, struct{}{}); running {
+		return nil, errors.NewConflict("A request with this Idempotency-Key is still being processed", nil, map[string]interface{}{
+			"error": IdempotencyKeyInFlightError,
+		})
+	}
+	defer inFlightIdempotencyKeys.Delete(cacheKey)
+
+	// - The first attempt may have stored its response and released the key since the lookup above
+	if response, appErr, replayed := replay(); replayed {
+		return response, appErr
+	}
+
+	// - Only successful responses are stored, failed attempts can be retried
+	response, appErr := fn()
+	if appErr != nil || response == nil {
+		return response, appErr
+	}
+
+	body, err := json.Marshal(response.Body)
+	if err != nil {
+		helpers.Logger(ctx).Warn("Failed to serialize response for Idempotency-Key", zap.Error(err))
+		return response, nil
+	}
+
+	marshaled, err := json.Marshal(idempotentResponse{Fingerprint: fingerprint, Headers: response.Headers, Body: body})
+	if err == nil {
+		ttl := helpers.DefaultTimeDuration(sessionConfig.IdempotencyTTL, DefaultIdempotencyTTL)
+		err = cacheInstance.Set(ctx, cacheKey, marshaled, store.WithExpiration(ttl))
+	}
+	if err != nil {
+		helpers.Logger(ctx).Warn("Failed to store response for Idempotency-Key", zap.Error(err))
+	}
+
+	return response, nil
+}
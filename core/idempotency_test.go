@@ -0,0 +1,156 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/eko/gocache/lib/v4/cache"
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/errors"
+)
+
+// missingOnceCache misses the first lookup, as if the first attempt stored its response right after it.
+type missingOnceCache struct {
+	cache.CacheInterface[[]byte]
+	missed bool
+}
+
+func (c *missingOnceCache) Get(ctx context.Context, key any) ([]byte, error) {
+	if !c.missed {
+		c.missed = true
+		return nil, fmt.Errorf("not found")
+	}
+	return c.CacheInterface.Get(ctx, key)
+}
+
+type missingOnceSessionManager struct {
+	*mockSessionManager
+	cache *missingOnceCache
+}
+
+func (m *missingOnceSessionManager) GetCache() (cache.CacheInterface[[]byte], error) {
+	return m.cache, nil
+}
+
+type idempotencyInput struct {
+	Amount int `json:"amount"`
+}
+
+type idempotencyOutput struct {
+	OrderNumber int64 `json:"order_number"`
+}
+
+func TestIdempotentRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mgr := newMockSessionManager(t)
+	router := gin.New()
+	ctor := NewRouteConstructor(router, testBaseRoute{}, mgr, nil)
+
+	var executions atomic.Int64
+	handler := func(_ *idempotencyInput, _ *Handler[testBaseRoute]) (*idempotencyOutput, *errors.AppError) {
+		return &idempotencyOutput{OrderNumber: executions.Add(1)}, nil
+	}
+	POST(ctor, "/orders", AuthenticatedJSONAPI().WithIdempotency(time.Minute), handler)
+	POST(ctor, "/plain", AuthenticatedJSONAPI(), handler)
+
+	issue := func(subject string) string {
+		ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+		ctx.Request = httptest.NewRequest(http.MethodPost, "/login", nil)
+		token, err := IssueBearerToken(ctx, mgr, "default", &SessionClaims{Claims: map[string]string{"subject": subject}})
+		if err != nil {
+			t.Fatalf("Failed to issue the bearer token: %v", err)
+		}
+		return token
+	}
+	userOne, userTwo := issue("user-1"), issue("user-2")
+
+	post := func(path, token, key, body string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		request.Header.Set("Content-Type", "application/json")
+		request.Header.Set(DefaultSessionAuthorizationHeaderName, token)
+		if key != "" {
+			request.Header.Set(IdempotencyKeyHeader, key)
+		}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		time.Sleep(10 * time.Millisecond) // - Let the cache settle
+		return recorder
+	}
+
+	first := post("/orders", userOne, "key-1", `{"amount":10}`)
+	if first.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", first.Code, first.Body.String())
+	}
+
+	t.Run("Retries replay the stored response", func(t *testing.T) {
+		retry := post("/orders", userOne, "key-1", `{"amount":10}`)
+		if retry.Code != http.StatusOK || retry.Body.String() != first.Body.String() {
+			t.Errorf("Expected the first response to be replayed, got %d: %s", retry.Code, retry.Body.String())
+		}
+		if retry.Header().Get(IdempotentReplayedHeader) != "true" || executions.Load() != 1 {
+			t.Errorf("Expected the handler to run once, ran %d times", executions.Load())
+		}
+	})
+
+	t.Run("Reusing a key for a different request is rejected", func(t *testing.T) {
+		if recorder := post("/orders", userOne, "key-1", `{"amount":20}`); recorder.Code != http.StatusUnprocessableEntity {
+			t.Errorf("Expected 422, got %d", recorder.Code)
+		}
+	})
+
+	t.Run("Keys are scoped to the subject", func(t *testing.T) {
+		recorder := post("/orders", userTwo, "key-1", `{"amount":10}`)
+		if recorder.Code != http.StatusOK || recorder.Header().Get(IdempotentReplayedHeader) != "" || executions.Load() != 2 {
+			t.Errorf("Expected another subject's request to execute, got %d: %s", recorder.Code, recorder.Body.String())
+		}
+	})
+
+	t.Run("Requests without a key or on other routes execute", func(t *testing.T) {
+		before := executions.Load()
+		post("/orders", userOne, "", `{"amount":10}`)
+		post("/plain", userOne, "key-1", `{"amount":10}`)
+		if executions.Load() != before+2 {
+			t.Errorf("Expected both requests to execute, ran %d", executions.Load()-before)
+		}
+	})
+
+	t.Run("Keys in flight are rejected", func(t *testing.T) {
+		inFlightIdempotencyKeys.Store(IdempotencyCacheKeyPrefix+"user-1|key-2", struct{}{})
+		defer inFlightIdempotencyKeys.Delete(IdempotencyCacheKeyPrefix + "user-1|key-2")
+		if recorder := post("/orders", userOne, "key-2", `{"amount":10}`); recorder.Code != http.StatusConflict {
+			t.Errorf("Expected 409, got %d", recorder.Code)
+		}
+	})
+
+	t.Run("Attempts finishing before the key is claimed are replayed", func(t *testing.T) {
+		cacheInstance, _ := mgr.GetCache()
+		racing := &missingOnceSessionManager{mockSessionManager: mgr, cache: &missingOnceCache{CacheInterface: cacheInstance}}
+		claims := &SessionClaims{HasSession: true, Claims: map[string]string{"subject": "user-1"}}
+		config := AuthenticatedJSONAPI().WithIdempotency(time.Minute)
+
+		run := func(manager SessionManager) (*routeResponse, *errors.AppError) {
+			ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+			ctx.Request = httptest.NewRequest(http.MethodPost, "/orders", nil)
+			ctx.Request.Header.Set(IdempotencyKeyHeader, "key-3")
+			return executeIdempotent(ctx, manager, config, claims, &idempotencyInput{Amount: 10}, func() (*routeResponse, *errors.AppError) {
+				return &routeResponse{Body: &idempotencyOutput{OrderNumber: executions.Add(1)}}, nil
+			})
+		}
+		if _, appErr := run(mgr); appErr != nil {
+			t.Fatalf("Expected the first attempt to succeed, got %v", appErr)
+		}
+		time.Sleep(10 * time.Millisecond) // - Let the cache settle
+
+		before := executions.Load()
+		response, appErr := run(racing)
+		if appErr != nil || response == nil || executions.Load() != before {
+			t.Errorf("Expected the stored response to be replayed, got %+v (%v) after %d executions", response, appErr, executions.Load()-before)
+		}
+	})
+}
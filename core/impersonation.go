@@ -0,0 +1,161 @@
+package core
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/errors"
+	"github.com/grzegorzmaniak/gothic/helpers"
+	"go.uber.org/zap"
+)
+
+const (
+	ImpersonationClaim        = "___ip" // Set on impersonated sessions
+	ImpersonatorClaim         = "___ia" // Subject identifier of the impersonating subject
+	ImpersonatorSessionClaim  = "___is" // Session identifier of the impersonating session
+	ImpersonationReasonClaim  = "___ir" // Optional reason, e.g., a support ticket
+	ImpersonationStartedClaim = "___it" // Unix seconds the impersonation started at
+
+	DefaultImpersonationPermission = "sessions.impersonate"
+	DefaultImpersonationLifetime   = 15 * time.Minute
+	MaximumImpersonationLifetime   = time.Hour
+)
+
+// reservedClaims are generated for every session and never copied from the target's claims.
+var reservedClaims = []string{SessionIdentifier, SessionModeClaim, RbacCacheIdentifier, CsrfTokenTie, VersionClaim, AuthLevelClaim, AuthTimeClaim, BindingIPClaim, BindingUserAgentClaim}
+
+// ImpersonationOptions configures ImpersonateSession.
+type ImpersonationOptions struct {
+	// TargetClaims are the claims the target subject would get at login, GetSubjectIdentifier must resolve them
+	// to the target subject (Required)
+	TargetClaims *SessionClaims
+
+	// Group is the session mode of the minted session (Default: the group of the impersonating session)
+	Group string
+
+	// Permission is the named permission the impersonating subject needs (Default: DefaultImpersonationPermission)
+	Permission string
+
+	// Lifetime of the minted session, capped at MaximumImpersonationLifetime (Default: DefaultImpersonationLifetime)
+	Lifetime time.Duration
+
+	// Reason is recorded in the ImpersonationReasonClaim claim and the audit log
+	Reason string
+
+	// Bearer returns a bearer token instead of setting the session cookie
+	Bearer bool
+}
+
+// IsImpersonated reports whether the session was minted by ImpersonateSession.
+func (d *SessionClaims) IsImpersonated() bool {
+	return d.HasClaim(ImpersonationClaim)
+}
+
+// GetImpersonator returns the subject identifier of the impersonating subject.
+func (d *SessionClaims) GetImpersonator() (string, bool) {
+	if !d.IsImpersonated() {
+		return "", false
+	}
+	return d.GetClaim(ImpersonatorClaim)
+}
+
+// ImpersonateSession mints a session for targetSubject on behalf of the subject of adminClaims. The admin needs
+// the impersonation permission, the session carries both identities (ImpersonatorClaim next to the target's
+// claims) and its lifetime is capped. Impersonated sessions can not impersonate again. The session cookie is
+// set, or with Bearer the token is returned instead.
+func ImpersonateSession(
+	ctx *gin.Context,
+	sessionManager SessionManager,
+	adminClaims *SessionClaims,
+	targetSubject string,
+	opts ImpersonationOptions,
+) (string, error) {
+	if ctx == nil {
+		return "", errors.NewInternalServerError("Context is nil", nil)
+	}
+	if sessionManager == nil {
+		return "", errors.NewInternalServerError("Session manager is nil", nil)
+	}
+	if adminClaims == nil || !adminClaims.HasSession {
+		return "", errors.NewUnauthorized("A session is required to impersonate", nil)
+	}
+	if opts.TargetClaims == nil || targetSubject == "" {
+		return "", errors.NewInternalServerError("Target subject and claims are required", nil)
+	}
+
+	// - Chained impersonation would hide who is acting
+	if adminClaims.IsImpersonated() {
+		return "", errors.NewForbidden("Impersonated sessions can not impersonate", nil)
+	}
+
+	adminSubject, err := sessionManager.GetSubjectIdentifier(adminClaims)
+	if err != nil {
+		return "", errors.NewInternalServerError("Failed to get subject identifier", err)
+	}
+
+	permission := helpers.DefaultString(opts.Permission, DefaultImpersonationPermission)
+	allowed, err := checkSessionPermission(ctx, sessionManager, adminClaims, permission)
+	if err != nil || !allowed {
+		helpers.Logger(ctx).Debug("Impersonation denied", zap.String("subject", adminSubject), zap.Error(err))
+		return "", errors.NewForbidden("Impersonation is not allowed", err)
+	}
+
+	// - Copy the target's claims, the session specific claims are generated fresh
+	claims := &SessionClaims{Claims: make(map[string]string, len(opts.TargetClaims.Claims)+5)}
+	for name, value := range opts.TargetClaims.Claims {
+		claims.Claims[name] = value
+	}
+	for _, name := range reservedClaims {
+		delete(claims.Claims, name)
+	}
+
+	resolvedSubject, err := sessionManager.GetSubjectIdentifier(claims)
+	if err != nil || resolvedSubject != targetSubject {
+		return "", errors.NewInternalServerError("Target claims do not resolve to the target subject", err)
+	}
+	if targetSubject == adminSubject {
+		return "", errors.NewBadRequest("Subjects can not impersonate themselves", nil)
+	}
+
+	claims.SetBool(ImpersonationClaim, true)
+	claims.SetClaim(ImpersonatorClaim, adminSubject)
+	claims.SetTime(ImpersonationStartedClaim, time.Now())
+	if adminSessionId, ok := adminClaims.GetClaim(SessionIdentifier); ok {
+		claims.SetClaim(ImpersonatorSessionClaim, adminSessionId)
+	}
+	if opts.Reason != "" {
+		claims.SetClaim(ImpersonationReasonClaim, opts.Reason)
+	}
+
+	adminGroup, _ := adminClaims.GetClaim(SessionModeClaim)
+	group := helpers.DefaultString(opts.Group, adminGroup)
+
+	lifetime := helpers.DefaultTimeDuration(opts.Lifetime, DefaultImpersonationLifetime)
+	if lifetime > MaximumImpersonationLifetime {
+		lifetime = MaximumImpersonationLifetime
+	}
+
+	authorizationData := sessionManager.GetAuthorizationConfiguration()
+	if authorizationData == nil {
+		return "", errors.NewInternalServerError("Authorization data is nil", nil)
+	}
+	impersonationData := *authorizationData
+	impersonationData.Expiration = lifetime
+
+	token := ""
+	if opts.Bearer {
+		token, err = IssueCustomBearerToken(ctx, sessionManager, group, claims, &impersonationData)
+	} else {
+		err = SetCustomSessionCookie(ctx, sessionManager, group, claims, &impersonationData)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	helpers.Logger(ctx).Info("Session impersonated",
+		zap.String("impersonator", adminSubject),
+		zap.String("subject", targetSubject),
+		zap.String("reason", opts.Reason),
+		zap.Duration("lifetime", lifetime))
+	return token, nil
+}
@@ -0,0 +1,140 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	internalcache "github.com/grzegorzmaniak/gothic/cache"
+	"github.com/grzegorzmaniak/gothic/errors"
+	"github.com/grzegorzmaniak/gothic/rbac"
+)
+
+func TestImpersonateSession(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mgr := newMockSessionManager(t)
+	mgr.rbacManager = &namedRbacManager{
+		cacheManager: internalcache.BuildDefaultCacheManager(nil),
+		named:        map[string]rbac.PermissionSet{"admin-1": rbac.MustPermissionSet("sessions.*")},
+	}
+
+	session := func(subject string) *SessionClaims {
+		return &SessionClaims{HasSession: true, Claims: map[string]string{
+			"subject":           subject,
+			SessionModeClaim:    "staff",
+			SessionIdentifier:   subject + "-session",
+			RbacCacheIdentifier: strings.Repeat(subject[:1], 32),
+		}}
+	}
+	target := func() ImpersonationOptions {
+		return ImpersonationOptions{TargetClaims: &SessionClaims{Claims: map[string]string{
+			"subject":         "user-1",
+			"tenant":          "acme",
+			SessionIdentifier: "must-not-be-reused",
+		}}}
+	}
+	newContext := func() (*gin.Context, *httptest.ResponseRecorder) {
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+		ctx.Request = httptest.NewRequest(http.MethodPost, "/impersonate", nil)
+		return ctx, recorder
+	}
+
+	t.Run("Mints a cookie session carrying both identities", func(t *testing.T) {
+		ctx, recorder := newContext()
+		opts := target()
+		opts.Reason = "TICKET-42"
+		if _, err := ImpersonateSession(ctx, mgr, session("admin-1"), "user-1", opts); err != nil {
+			t.Fatalf("Expected impersonation to succeed, got %v", err)
+		}
+
+		request := httptest.NewRequest(http.MethodGet, "/", nil)
+		for _, cookie := range recorder.Result().Cookies() {
+			if cookie.Name == DefaultSessionAuthorizationName {
+				request.AddCookie(cookie)
+				if cookie.MaxAge != int(DefaultImpersonationLifetime.Seconds()) {
+					t.Errorf("Expected the cookie to live %v, got %ds", DefaultImpersonationLifetime, cookie.MaxAge)
+				}
+			}
+		}
+		extractCtx, _ := gin.CreateTestContext(httptest.NewRecorder())
+		extractCtx.Request = request
+		header, claims, group, _, err := extractSession(extractCtx, mgr)
+		if err != nil || claims == nil {
+			t.Fatalf("Failed to extract the impersonated session: %v", err)
+		}
+
+		impersonator, ok := claims.GetImpersonator()
+		if !ok || impersonator != "admin-1" || claims.Claims["subject"] != "user-1" || claims.Claims["tenant"] != "acme" {
+			t.Errorf("Unexpected impersonation claims %v", claims.Claims)
+		}
+		if claims.Claims[ImpersonatorSessionClaim] != "admin-1-session" || claims.Claims[ImpersonationReasonClaim] != "TICKET-42" {
+			t.Errorf("Expected the audit claims, got %v", claims.Claims)
+		}
+		if claims.Claims[SessionIdentifier] == "must-not-be-reused" || group != "staff" {
+			t.Errorf("Expected a fresh session in group 'staff', got %v in %q", claims.Claims[SessionIdentifier], group)
+		}
+		if header.LifetimeSec != int64(DefaultImpersonationLifetime.Seconds()) {
+			t.Errorf("Expected a lifetime of %v, got %ds", DefaultImpersonationLifetime, header.LifetimeSec)
+		}
+	})
+
+	t.Run("Bearer lifetime is capped", func(t *testing.T) {
+		ctx, _ := newContext()
+		opts := target()
+		opts.Bearer = true
+		opts.Lifetime = 24 * time.Hour
+		token, err := ImpersonateSession(ctx, mgr, session("admin-1"), "user-1", opts)
+		if err != nil || token == "" {
+			t.Fatalf("Expected a bearer token, got %v", err)
+		}
+
+		headerStr, _, err := extractSessionAuthorizationParts(mgr.authorizationData, mgr, token)
+		if err != nil {
+			t.Fatalf("Failed to open the bearer: %v", err)
+		}
+		if header, _ := Decode(headerStr); !header.Bearer || header.LifetimeSec != int64(MaximumImpersonationLifetime.Seconds()) {
+			t.Errorf("Expected a bearer capped at %v, got %+v", MaximumImpersonationLifetime, header)
+		}
+	})
+
+	t.Run("Rejections", func(t *testing.T) {
+		impersonated := session("admin-1")
+		impersonated.SetBool(ImpersonationClaim, true)
+
+		mismatched := target()
+		mismatched.TargetClaims.Claims["subject"] = "user-2"
+
+		tests := []struct {
+			name    string
+			claims  *SessionClaims
+			subject string
+			opts    ImpersonationOptions
+			code    int
+		}{
+			{name: "Missing permission", claims: session("user-9"), subject: "user-1", opts: target(), code: http.StatusForbidden},
+			{name: "Chained impersonation", claims: impersonated, subject: "user-1", opts: target(), code: http.StatusForbidden},
+			{name: "No session", claims: nil, subject: "user-1", opts: target(), code: http.StatusUnauthorized},
+			{name: "Claims of another subject", claims: session("admin-1"), subject: "user-1", opts: mismatched, code: http.StatusInternalServerError},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				ctx, recorder := newContext()
+				_, err := ImpersonateSession(ctx, mgr, tt.claims, tt.subject, tt.opts)
+				appErr, ok := err.(*errors.AppError)
+				if !ok {
+					t.Fatalf("Expected an AppError, got %v", err)
+				}
+				if appErr.Code != tt.code {
+					t.Errorf("Expected %d, got %d (%v)", tt.code, appErr.Code, err)
+				}
+				if len(recorder.Result().Cookies()) != 0 {
+					t.Error("Expected no cookies to be set")
+				}
+			})
+		}
+	})
+}
@@ -0,0 +1,136 @@
+package core
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/helpers"
+	"go.uber.org/zap"
+)
+
+const (
+	IntrospectionTokenParameter = "token"
+	ScopeClaim                  = "scope" // Space separated scopes, reported by the introspection endpoint
+
+	TokenTypeBearer  = "bearer"
+	TokenTypeSession = "session"
+)
+
+// IntrospectionResponse is the RFC 7662 introspection response. Inactive tokens only report Active, so the
+// endpoint does not leak why a token was rejected.
+type IntrospectionResponse struct {
+	Active       bool   `json:"active"`
+	Scope        string `json:"scope,omitempty"`
+	Subject      string `json:"sub,omitempty"`
+	TokenType    string `json:"token_type,omitempty"`
+	ExpiresAt    int64  `json:"exp,omitempty"`
+	IssuedAt     int64  `json:"iat,omitempty"`
+	SessionGroup string `json:"session_group,omitempty"`
+}
+
+func verifyIntrospectedSession(ctx *gin.Context, sessionManager SessionManager, claims *SessionClaims, header *SessionHeader) error {
+	ok, err := sessionManager.VerifySession(ctx, claims, header)
+	if err != nil {
+		return fmt.Errorf("failed to verify session: %w", err)
+	}
+	if !ok {
+		return fmt.Errorf("session is no longer valid")
+	}
+	return nil
+}
+
+// introspect verifies the token the same way the executor does and describes it.
+func introspect(ctx *gin.Context, sessionManager SessionManager, token string) (*IntrospectionResponse, error) {
+	authorizationData := sessionManager.GetAuthorizationConfiguration()
+	if authorizationData == nil {
+		return nil, fmt.Errorf("authorization data is not configured")
+	}
+
+	buffer := getAuthorizationBuffer()
+	defer putAuthorizationBuffer(buffer)
+
+	headerBytes, payloadBytes, err := extractSessionAuthorizationBytes(authorizationData, sessionManager, token, buffer)
+	if err != nil {
+		return nil, err
+	}
+
+	header, claims, group, err := decodeAuthorizationBytes(headerBytes, payloadBytes, buffer)
+	if err != nil {
+		return nil, err
+	}
+
+	if header.IsExpired() || !header.IsValid() {
+		return nil, fmt.Errorf("token header is invalid or expired")
+	}
+
+	// - Bearers are revalidated once per verify period, like in establishBearerSession
+	tokenType := TokenTypeSession
+	if header.Bearer {
+		tokenType = TokenTypeBearer
+		cacheKey, needsRefresh, err := BearerNeedsValidation(ctx, sessionManager, claims)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check bearer validation: %w", err)
+		}
+		if needsRefresh {
+			if err = verifyIntrospectedSession(ctx, sessionManager, claims, header); err != nil {
+				return nil, err
+			}
+			if err = BearerSetCache(ctx, sessionManager, cacheKey, header); err != nil {
+				return nil, fmt.Errorf("failed to set bearer cache: %w", err)
+			}
+		}
+	} else if err = verifyIntrospectedSession(ctx, sessionManager, claims, header); err != nil {
+		return nil, err
+	}
+
+	subject, err := sessionManager.GetSubjectIdentifier(claims)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get subject identifier: %w", err)
+	}
+
+	scope, _ := claims.GetClaim(ScopeClaim)
+	return &IntrospectionResponse{
+		Active:       true,
+		Scope:        scope,
+		Subject:      subject,
+		TokenType:    tokenType,
+		ExpiresAt:    header.IssuedAt + header.LifetimeSec,
+		IssuedAt:     header.IssuedAt,
+		SessionGroup: group,
+	}, nil
+}
+
+// NewIntrospectionHandler returns an RFC 7662 style introspection endpoint, other internal services POST the
+// token as the form parameter "token" and get back whether it is active, with its subject, scope, expiry and
+// session group, without holding the session key.
+//
+// Note: The endpoint tells anyone who can reach it whether a token is valid, mount it behind client
+// authentication or keep it on an internal network, e.g.:
+//
+//	internal.POST("/oauth/introspect", core.NewIntrospectionHandler(sessionManager))
+func NewIntrospectionHandler(sessionManager SessionManager) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Header("Cache-Control", "no-store")
+
+		token := ctx.PostForm(IntrospectionTokenParameter)
+		if token == "" {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request"})
+			return
+		}
+
+		if sessionManager == nil {
+			helpers.Logger(ctx).Error("Introspection endpoint has no session manager")
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
+			return
+		}
+
+		response, err := introspect(ctx, sessionManager, token)
+		if err != nil {
+			helpers.Logger(ctx).Debug("Introspected token is inactive", zap.Error(err))
+			ctx.JSON(http.StatusOK, IntrospectionResponse{Active: false})
+			return
+		}
+		ctx.JSON(http.StatusOK, response)
+	}
+}
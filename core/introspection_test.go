@@ -0,0 +1,94 @@
+package core
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestIntrospectionHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mgr := newMockSessionManager(t)
+
+	introspectToken := func(form url.Values) (int, map[string]interface{}) {
+		t.Helper()
+		router := gin.New()
+		router.POST("/introspect", NewIntrospectionHandler(mgr))
+
+		request := httptest.NewRequest(http.MethodPost, "/introspect", strings.NewReader(form.Encode()))
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+
+		if recorder.Header().Get("Cache-Control") != "no-store" {
+			t.Error("Expected the response not to be cached")
+		}
+		body := map[string]interface{}{}
+		_ = json.Unmarshal(recorder.Body.Bytes(), &body)
+		return recorder.Code, body
+	}
+
+	issueCtx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	issueCtx.Request = httptest.NewRequest(http.MethodPost, "/login", nil)
+	bearer, err := IssueBearerToken(issueCtx, mgr, "service", &SessionClaims{Claims: map[string]string{
+		"subject":  "user-1",
+		ScopeClaim: "invoices:read invoices:write",
+	}})
+	if err != nil {
+		t.Fatalf("Failed to issue bearer: %v", err)
+	}
+
+	t.Run("Active bearer", func(t *testing.T) {
+		code, body := introspectToken(url.Values{"token": {bearer}})
+		if code != http.StatusOK || body["active"] != true {
+			t.Fatalf("Expected an active token, got %d %v", code, body)
+		}
+		if body["sub"] != "user-1" || body["scope"] != "invoices:read invoices:write" ||
+			body["session_group"] != "service" || body["token_type"] != TokenTypeBearer {
+			t.Errorf("Unexpected introspection response %v", body)
+		}
+		if exp, _ := body["exp"].(float64); int64(exp) <= time.Now().Unix() {
+			t.Errorf("Expected exp in the future, got %v", body["exp"])
+		}
+	})
+
+	t.Run("Revoked session is inactive", func(t *testing.T) {
+		mgr.verifySession = false
+		defer func() { mgr.verifySession = true }()
+
+		header := NewSessionHeader(false, time.Hour, time.Minute)
+		token, err := CreateAuthorization("user", &header, *mgr.authorizationData, &SessionClaims{Claims: map[string]string{"subject": "user-2"}}, mgr)
+		if err != nil {
+			t.Fatalf("Failed to create authorization: %v", err)
+		}
+		if _, body := introspectToken(url.Values{"token": {token}}); body["active"] != false || len(body) != 1 {
+			t.Errorf("Expected only active=false, got %v", body)
+		}
+	})
+
+	t.Run("Invalid and expired tokens are inactive", func(t *testing.T) {
+		expiredHeader := SessionHeader{Bearer: true, LifetimeSec: 60, RefreshPeriodSec: 30, IssuedAt: time.Now().Add(-time.Hour).Unix()}
+		expired, err := CreateAuthorization("service", &expiredHeader, *mgr.authorizationData, &SessionClaims{Claims: map[string]string{"subject": "user-1"}}, mgr)
+		if err != nil {
+			t.Fatalf("Failed to create authorization: %v", err)
+		}
+
+		for _, token := range []string{expired, bearer[:len(bearer)-4] + "AAAA", "not-a-token"} {
+			if code, body := introspectToken(url.Values{"token": {token}}); code != http.StatusOK || body["active"] != false {
+				t.Errorf("Expected an inactive token, got %d %v", code, body)
+			}
+		}
+	})
+
+	t.Run("Missing token", func(t *testing.T) {
+		if code, body := introspectToken(url.Values{}); code != http.StatusBadRequest || body["error"] != "invalid_request" {
+			t.Errorf("Expected invalid_request, got %d %v", code, body)
+		}
+	})
+}
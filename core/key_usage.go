@@ -0,0 +1,203 @@
+package core
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/grzegorzmaniak/gothic/errors"
+	"github.com/grzegorzmaniak/gothic/helpers"
+)
+
+const (
+	// UnknownKeyId is the bucket failures are recorded under when the token references a key id that the
+	// session manager does not know about. Attacker controlled key ids are never used as map keys.
+	UnknownKeyId = "<unknown>"
+)
+
+type keyCounters struct {
+	issued          atomic.Uint64
+	validated       atomic.Uint64
+	failures        atomic.Uint64
+	lastIssuedAt    atomic.Int64
+	lastValidatedAt atomic.Int64
+}
+
+// keyUsageRecorder keeps per key id usage counters for this process.
+type keyUsageRecorder struct {
+	keys          sync.Map // map[string]*keyCounters
+	trackingSince atomic.Int64
+}
+
+var keyUsage = newKeyUsageRecorder()
+
+func newKeyUsageRecorder() *keyUsageRecorder {
+	r := &keyUsageRecorder{}
+	r.trackingSince.Store(time.Now().Unix())
+	return r
+}
+
+func (r *keyUsageRecorder) counters(keyId string) *keyCounters {
+	if existing, ok := r.keys.Load(keyId); ok {
+		return existing.(*keyCounters)
+	}
+	actual, _ := r.keys.LoadOrStore(keyId, &keyCounters{})
+	return actual.(*keyCounters)
+}
+
+func (r *keyUsageRecorder) issued(keyId string) {
+	c := r.counters(keyId)
+	c.issued.Add(1)
+	c.lastIssuedAt.Store(time.Now().Unix())
+}
+
+func (r *keyUsageRecorder) validated(keyId string) {
+	c := r.counters(keyId)
+	c.validated.Add(1)
+	c.lastValidatedAt.Store(time.Now().Unix())
+}
+
+func (r *keyUsageRecorder) failed(keyId string) {
+	r.counters(keyId).failures.Add(1)
+}
+
+func (r *keyUsageRecorder) reset() {
+	r.keys.Range(func(key, _ interface{}) bool {
+		r.keys.Delete(key)
+		return true
+	})
+	r.trackingSince.Store(time.Now().Unix())
+}
+
+// KeyUsage holds the usage counters of a single session key id.
+type KeyUsage struct {
+	KeyId           string  `json:"key_id"`
+	Current         bool    `json:"current"`
+	Issued          uint64  `json:"issued"`
+	Validated       uint64  `json:"validated"`
+	Failures        uint64  `json:"failures"`
+	LastIssuedAt    int64   `json:"last_issued_at,omitempty"`
+	LastValidatedAt int64   `json:"last_validated_at,omitempty"`
+	ValidatedShare  float64 `json:"validated_share"`
+	SafeToRetire    bool    `json:"safe_to_retire"`
+}
+
+// KeyUsageReport describes how much traffic depends on each session key, so operators know when a key can be
+// removed from GetOldSessionKey.
+//
+// Note: Counters are kept in memory per process, so deployments with multiple instances need to aggregate the
+// reports of every instance before retiring a key.
+type KeyUsageReport struct {
+	CurrentKeyId   string     `json:"current_key_id"`
+	GeneratedAt    int64      `json:"generated_at"`
+	TrackingSince  int64      `json:"tracking_since"`
+	RetireAfterSec int64      `json:"retire_after_sec"`
+	TotalValidated uint64     `json:"total_validated"`
+	OldKeyShare    float64    `json:"old_key_share"`
+	Keys           []KeyUsage `json:"keys"`
+}
+
+// ResetKeyUsage clears every key usage counter and restarts the tracking window.
+func ResetKeyUsage() {
+	keyUsage.reset()
+}
+
+// defaultRetireAfter is the longest lifetime a token signed with a key could still have.
+func defaultRetireAfter(sessionManager SessionManager) time.Duration {
+	authorizationData := sessionManager.GetAuthorizationConfiguration()
+	if authorizationData == nil {
+		return DefaultAuthorizationExpiration
+	}
+
+	sessionExpiration := helpers.DefaultTimeDuration(authorizationData.Expiration, DefaultSessionExpiration)
+	bearerExpiration := helpers.DefaultTimeDuration(authorizationData.Expiration, DefaultAuthorizationExpiration)
+	if sessionExpiration > bearerExpiration {
+		return sessionExpiration
+	}
+	return bearerExpiration
+}
+
+// GetKeyUsageReport builds a KeyUsageReport. A key is considered safe to retire once it is not the current key
+// and nothing has been validated with it for retireAfter (tracking must also have been running for at least that
+// long). If retireAfter is 0, the longest configured token lifetime is used.
+func GetKeyUsageReport(sessionManager SessionManager, retireAfter time.Duration) (*KeyUsageReport, error) {
+	if sessionManager == nil {
+		return nil, errors.NewInternalServerError("Session manager is nil", nil)
+	}
+
+	_, currentKeyId, err := sessionManager.GetSessionKey()
+	if err != nil {
+		return nil, errors.NewInternalServerError("Failed to get session key", err)
+	}
+
+	if retireAfter <= 0 {
+		retireAfter = defaultRetireAfter(sessionManager)
+	}
+
+	now := time.Now().Unix()
+	report := &KeyUsageReport{
+		CurrentKeyId:   currentKeyId,
+		GeneratedAt:    now,
+		TrackingSince:  keyUsage.trackingSince.Load(),
+		RetireAfterSec: int64(retireAfter.Seconds()),
+		Keys:           []KeyUsage{},
+	}
+
+	var oldKeyValidated uint64
+	keyUsage.keys.Range(func(key, value interface{}) bool {
+		keyId := key.(string)
+		c := value.(*keyCounters)
+
+		usage := KeyUsage{
+			KeyId:           keyId,
+			Current:         keyId == currentKeyId,
+			Issued:          c.issued.Load(),
+			Validated:       c.validated.Load(),
+			Failures:        c.failures.Load(),
+			LastIssuedAt:    c.lastIssuedAt.Load(),
+			LastValidatedAt: c.lastValidatedAt.Load(),
+		}
+
+		lastSeen := usage.LastValidatedAt
+		if report.TrackingSince > lastSeen {
+			lastSeen = report.TrackingSince
+		}
+		usage.SafeToRetire = !usage.Current && keyId != UnknownKeyId && now-lastSeen >= report.RetireAfterSec
+
+		report.TotalValidated += usage.Validated
+		if !usage.Current && keyId != UnknownKeyId {
+			oldKeyValidated += usage.Validated
+		}
+
+		report.Keys = append(report.Keys, usage)
+		return true
+	})
+
+	for i := range report.Keys {
+		if report.TotalValidated > 0 {
+			report.Keys[i].ValidatedShare = float64(report.Keys[i].Validated) / float64(report.TotalValidated)
+		}
+	}
+	if report.TotalValidated > 0 {
+		report.OldKeyShare = float64(oldKeyValidated) / float64(report.TotalValidated)
+	}
+
+	sort.Slice(report.Keys, func(i, j int) bool {
+		return report.Keys[i].KeyId < report.Keys[j].KeyId
+	})
+
+	return report, nil
+}
+
+// KeyUsageReportHandler is a ready-made route handler exposing the key usage report, register it with an
+// APIConfiguration that restricts it to operators, e.g.:
+//
+//	core.GET(routeCtor, "/internal/keys/usage", adminOnlyConfig, core.KeyUsageReportHandler[*AppBaseRoute])
+func KeyUsageReportHandler[BaseRoute helpers.BaseRouteComponents](_ *struct{}, data *Handler[BaseRoute]) (*KeyUsageReport, *errors.AppError) {
+	report, err := GetKeyUsageReport(data.SessionManager, 0)
+	if err != nil {
+		return nil, errors.NewInternalServerError("Failed to build key usage report", err)
+	}
+	return report, nil
+}
@@ -0,0 +1,93 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func findKeyUsage(report *KeyUsageReport, keyId string) *KeyUsage {
+	for i := range report.Keys {
+		if report.Keys[i].KeyId == keyId {
+			return &report.Keys[i]
+		}
+	}
+	return nil
+}
+
+func TestKeyUsageReport(t *testing.T) {
+	mgr := newMockSessionManager(t)
+	oldKey := mgr.keys["key-1"]
+	mgr.keys["key-0"] = oldKey
+
+	t.Run("Issued and validated tokens are counted per key", func(t *testing.T) {
+		ResetKeyUsage()
+
+		header := NewSessionHeader(false, time.Hour, time.Minute)
+		token, err := CreateAuthorization("user", &header, *mgr.authorizationData, &SessionClaims{}, mgr)
+		if err != nil {
+			t.Fatalf("Failed to create authorization: %v", err)
+		}
+		if _, _, err = extractSessionAuthorizationParts(mgr.authorizationData, mgr, token); err != nil {
+			t.Fatalf("Failed to extract authorization: %v", err)
+		}
+
+		// - Simulate traffic on the old key
+		keyUsage.validated("key-0")
+		keyUsage.validated("key-0")
+		keyUsage.validated("key-0")
+
+		report, err := GetKeyUsageReport(mgr, time.Hour)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		current := findKeyUsage(report, "key-1")
+		if current == nil || current.Issued != 1 || current.Validated != 1 || !current.Current {
+			t.Fatalf("Unexpected usage for the current key: %+v", current)
+		}
+
+		old := findKeyUsage(report, "key-0")
+		if old == nil || old.Validated != 3 || old.Current {
+			t.Fatalf("Unexpected usage for the old key: %+v", old)
+		}
+		if old.SafeToRetire {
+			t.Error("Old key is still in use and should not be safe to retire")
+		}
+		if report.OldKeyShare != 0.75 {
+			t.Errorf("Expected old key share of 0.75, got %v", report.OldKeyShare)
+		}
+	})
+
+	t.Run("Unknown key ids are recorded in a single bucket", func(t *testing.T) {
+		ResetKeyUsage()
+
+		header := NewSessionHeader(false, time.Hour, time.Minute)
+		otherMgr := newMockSessionManager(t)
+		otherMgr.currentKeyId = "unknown-key"
+		otherMgr.keys["unknown-key"] = oldKey
+		token, err := CreateAuthorization("user", &header, *mgr.authorizationData, &SessionClaims{}, otherMgr)
+		if err != nil {
+			t.Fatalf("Failed to create authorization: %v", err)
+		}
+
+		if _, _, err = extractSessionAuthorizationParts(mgr.authorizationData, mgr, token); err == nil {
+			t.Fatal("Expected extraction to fail for an unknown key id")
+		}
+
+		report, _ := GetKeyUsageReport(mgr, time.Hour)
+		if unknown := findKeyUsage(report, UnknownKeyId); unknown == nil || unknown.Failures != 1 {
+			t.Errorf("Expected one failure in the unknown bucket, got %+v", unknown)
+		}
+	})
+
+	t.Run("Idle old keys are safe to retire", func(t *testing.T) {
+		ResetKeyUsage()
+		keyUsage.trackingSince.Store(time.Now().Add(-2 * time.Hour).Unix())
+		keyUsage.counters("key-0").lastValidatedAt.Store(time.Now().Add(-90 * time.Minute).Unix())
+
+		report, _ := GetKeyUsageReport(mgr, time.Hour)
+		if old := findKeyUsage(report, "key-0"); old == nil || !old.SafeToRetire {
+			t.Errorf("Expected the idle old key to be safe to retire, got %+v", old)
+		}
+	})
+}
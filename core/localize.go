@@ -0,0 +1,280 @@
+package core
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grzegorzmaniak/gothic/helpers"
+)
+
+// LocalizeMode selects how tagged money / date fields of the output are localized.
+type LocalizeMode int
+
+const (
+	// LocalizeOff returns the output as is (Default)
+	LocalizeOff LocalizeMode = iota
+
+	// LocalizeCompanion keeps the raw values and adds a "<field>_formatted" string next to each tagged field.
+	LocalizeCompanion
+
+	// LocalizeReplace replaces the tagged fields with their formatted string.
+	LocalizeReplace
+)
+
+const (
+	// LocaleClaim holds the subject's BCP 47 locale, e.g., "de-AT" (Default: helpers.DefaultLocale)
+	LocaleClaim = "locale"
+
+	// TimezoneClaim holds the subject's IANA timezone, e.g., "Europe/Warsaw" (Default: UTC)
+	TimezoneClaim = "timezone"
+
+	// LocalizeTag marks output fields to format, e.g.:
+	//
+	//	Total    int64     `json:"total" localize:"money,currency_field=Currency,minor"`
+	//	Fee      float64   `json:"fee" localize:"money,currency=EUR"`
+	//	Created  time.Time `json:"created" localize:"datetime"`
+	//
+	// Kinds are "money", "date", "time" and "datetime". Money fields take their currency from "currency=" or
+	// from a string field named by "currency_field=", "minor" marks integer amounts in minor units (cents).
+	LocalizeTag = "localize"
+
+	LocalizedCompanionSuffix = "_formatted"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// outputLocale is the subject's locale, resolved once per response.
+type outputLocale struct {
+	locale    string
+	location  *time.Location
+	localizer helpers.Localizer
+	mode      LocalizeMode
+}
+
+// resolveOutputLocale reads the locale and timezone claims, falling back to the defaults.
+func resolveOutputLocale(claims *SessionClaims, mode LocalizeMode) *outputLocale {
+	resolved := &outputLocale{
+		locale:    helpers.DefaultLocale,
+		location:  time.UTC,
+		localizer: helpers.GetDefaultLocalizer(),
+		mode:      mode,
+	}
+
+	if claims == nil {
+		return resolved
+	}
+	if locale, ok := claims.GetClaim(LocaleClaim); ok && locale != "" {
+		resolved.locale = locale
+	}
+	if timezone, ok := claims.GetClaim(TimezoneClaim); ok && timezone != "" {
+		if location, err := time.LoadLocation(timezone); err == nil {
+			resolved.location = location
+		}
+	}
+	return resolved
+}
+
+// localizeOutput returns the output with its tagged fields localized, outputs without tagged fields are
+// returned unchanged.
+func localizeOutput(output interface{}, claims *SessionClaims, mode LocalizeMode) interface{} {
+	if mode == LocalizeOff || output == nil || !hasLocalizedFields(reflect.TypeOf(output)) {
+		return output
+	}
+	return resolveOutputLocale(claims, mode).value(reflect.ValueOf(output))
+}
+
+// localizedTypes caches whether a type (transitively) has localize tagged fields.
+var localizedTypes sync.Map // map[reflect.Type]bool
+
+func hasLocalizedFields(typ reflect.Type) bool {
+	for typ.Kind() == reflect.Pointer || typ.Kind() == reflect.Slice || typ.Kind() == reflect.Array || typ.Kind() == reflect.Map {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct || typ == timeType {
+		return false
+	}
+
+	if cached, ok := localizedTypes.Load(typ); ok {
+		return cached.(bool)
+	}
+
+	// - Recursive types are resolved as not localized until the walk finishes
+	localizedTypes.Store(typ, false)
+	found := false
+	for i := 0; i < typ.NumField() && !found; i++ {
+		field := typ.Field(i)
+		_, tagged := field.Tag.Lookup(LocalizeTag)
+		found = tagged || hasLocalizedFields(field.Type)
+	}
+	localizedTypes.Store(typ, found)
+	return found
+}
+
+// value converts the value into its JSON shape, structs with tagged fields become maps.
+func (l *outputLocale) value(v reflect.Value) interface{} {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if !hasLocalizedFields(v.Type()) {
+		return v.Interface()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		result := make(map[string]interface{}, v.NumField())
+		l.structFields(v, result)
+		return result
+
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil
+		}
+		result := make([]interface{}, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			result[i] = l.value(v.Index(i))
+		}
+		return result
+
+	case reflect.Map:
+		if v.IsNil() || v.Type().Key().Kind() != reflect.String {
+			return v.Interface()
+		}
+		result := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			result[iter.Key().String()] = l.value(iter.Value())
+		}
+		return result
+
+	default:
+		return v.Interface()
+	}
+}
+
+// structFields writes the struct's fields into the result following the encoding/json naming rules,
+// embedded structs without a name are flattened.
+func (l *outputLocale) structFields(v reflect.Value, result map[string]interface{}) {
+	typ := v.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if !field.IsExported() && !field.Anonymous {
+			continue
+		}
+
+		jsonTag := field.Tag.Get("json")
+		if jsonTag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(jsonTag, ",")
+		fieldValue := v.Field(i)
+
+		// - Embedded structs are promoted into the parent
+		if field.Anonymous && name == "" {
+			embedded := fieldValue
+			if embedded.Kind() == reflect.Pointer {
+				if embedded.IsNil() {
+					continue
+				}
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				l.structFields(embedded, result)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+		if strings.Contains(options, "omitempty") && fieldValue.IsZero() {
+			continue
+		}
+
+		tag, tagged := field.Tag.Lookup(LocalizeTag)
+		if !tagged {
+			result[name] = l.value(fieldValue)
+			continue
+		}
+
+		formatted, ok := l.format(v, fieldValue, tag)
+		switch {
+		case !ok:
+			result[name] = l.value(fieldValue)
+		case l.mode == LocalizeReplace:
+			result[name] = formatted
+		default:
+			result[name] = l.value(fieldValue)
+			result[name+LocalizedCompanionSuffix] = formatted
+		}
+	}
+}
+
+// format formats a single tagged field, it returns false for nil / zero values and unsupported types.
+func (l *outputLocale) format(parent reflect.Value, v reflect.Value, tag string) (string, bool) {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return "", false
+		}
+		v = v.Elem()
+	}
+
+	kind, optionList, _ := strings.Cut(tag, ",")
+	options := make(map[string]string)
+	for _, option := range strings.Split(optionList, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(option), "=")
+		if key != "" {
+			options[key] = value
+		}
+	}
+
+	switch kind {
+	case "money":
+		currency := options["currency"]
+		if currencyField := options["currency_field"]; currencyField != "" {
+			if field := parent.FieldByName(currencyField); field.IsValid() && field.Kind() == reflect.String {
+				currency = field.String()
+			}
+		}
+		if currency == "" {
+			return "", false
+		}
+
+		var amount float64
+		switch {
+		case v.CanInt():
+			amount = float64(v.Int())
+		case v.CanUint():
+			amount = float64(v.Uint())
+		case v.CanFloat():
+			amount = v.Float()
+		default:
+			return "", false
+		}
+		if _, minor := options["minor"]; minor {
+			amount /= math.Pow10(helpers.GetCurrencyFormat(currency).Digits)
+		}
+		return l.localizer.FormatMoney(l.locale, amount, currency), true
+
+	case string(helpers.DateStyleDate), string(helpers.DateStyleTime), string(helpers.DateStyleDateTime):
+		if v.Type() != timeType {
+			return "", false
+		}
+		t := v.Interface().(time.Time)
+		if t.IsZero() {
+			return "", false
+		}
+		return l.localizer.FormatDate(l.locale, t.In(l.location), helpers.DateStyle(kind)), true
+
+	default:
+		return "", false
+	}
+}
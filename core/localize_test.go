@@ -0,0 +1,89 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+type localizedLine struct {
+	Amount   int64  `json:"amount" localize:"money,currency_field=Currency,minor"`
+	Currency string `json:"currency"`
+}
+
+type localizedAudit struct {
+	At time.Time `json:"at" localize:"datetime"`
+}
+
+type localizedOutput struct {
+	localizedAudit
+	Total   float64         `json:"total" localize:"money,currency=EUR"`
+	Created time.Time       `json:"created" localize:"date"`
+	Lines   []localizedLine `json:"lines"`
+	Note    string          `json:"note,omitempty"`
+	Token   string          `json:"-"`
+}
+
+func TestLocalizeOutput(t *testing.T) {
+	created := time.Date(2024, time.March, 5, 23, 30, 0, 0, time.UTC)
+	output := &localizedOutput{
+		localizedAudit: localizedAudit{At: created},
+		Total:          1234.5,
+		Created:        created,
+		Lines:          []localizedLine{{Amount: 199, Currency: "USD"}},
+		Token:          "secret",
+	}
+	claims := &SessionClaims{HasSession: true, Claims: map[string]string{LocaleClaim: "de-DE", TimezoneClaim: "Europe/Berlin"}}
+
+	t.Run("Off returns the output unchanged", func(t *testing.T) {
+		if got := localizeOutput(output, claims, LocalizeOff); got != output {
+			t.Errorf("Expected the original output, got %v", got)
+		}
+	})
+
+	t.Run("Companion keeps raw values", func(t *testing.T) {
+		got, ok := localizeOutput(output, claims, LocalizeCompanion).(map[string]interface{})
+		if !ok {
+			t.Fatalf("Expected a map, got %T", got)
+		}
+
+		if got["total"] != 1234.5 || got["total_formatted"] != "1.234,50 €" {
+			t.Errorf("Expected raw and formatted total, got %v / %v", got["total"], got["total_formatted"])
+		}
+		// - 23:30 UTC is the next day in Berlin
+		if got["created_formatted"] != "06.03.2024" {
+			t.Errorf("Expected '06.03.2024', got %v", got["created_formatted"])
+		}
+		if got["at_formatted"] != "06.03.2024, 00:30" {
+			t.Errorf("Expected the embedded field to be promoted, got %v", got["at_formatted"])
+		}
+
+		lines := got["lines"].([]interface{})
+		if line := lines[0].(map[string]interface{}); line["amount_formatted"] != "1,99 $" || line["amount"] != int64(199) {
+			t.Errorf("Expected '1,99 $' for 199 minor units, got %v", line)
+		}
+
+		if _, exists := got["note"]; exists {
+			t.Error("Expected the empty omitempty field to be skipped")
+		}
+		if _, exists := got["Token"]; exists {
+			t.Error("Expected the json:\"-\" field to be skipped")
+		}
+	})
+
+	t.Run("Replace swaps the values", func(t *testing.T) {
+		got := localizeOutput(output, nil, LocalizeReplace).(map[string]interface{})
+		if got["total"] != "€1,234.50" {
+			t.Errorf("Expected the default locale, got %v", got["total"])
+		}
+		if _, exists := got["total_formatted"]; exists {
+			t.Error("Expected no companion field in replace mode")
+		}
+	})
+
+	t.Run("Outputs without tags are untouched", func(t *testing.T) {
+		plain := &struct{ Name string }{Name: "x"}
+		if got := localizeOutput(plain, claims, LocalizeReplace); got != plain {
+			t.Errorf("Expected the original output, got %v", got)
+		}
+	})
+}
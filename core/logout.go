@@ -0,0 +1,145 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/errors"
+	"github.com/grzegorzmaniak/gothic/helpers"
+	"go.uber.org/zap"
+)
+
+// SessionRevoker is implemented by session managers that keep server-side sessions, NewLogoutHandler calls it so
+// a copy of the logged out token stops verifying once VerifySession runs again.
+type SessionRevoker interface {
+	RevokeSession(ctx context.Context, claims *SessionClaims) error
+}
+
+// EndSessionFunc returns where to send the browser after the local logout, e.g., the RP-initiated logout URL of
+// an OpenID provider (see oidc.Provider.EndSessionRedirect). An empty URL falls back to LogoutConfiguration.RedirectTo.
+type EndSessionFunc func(ctx *gin.Context, claims *SessionClaims) (string, error)
+
+// LogoutConfiguration configures NewLogoutHandler.
+type LogoutConfiguration struct {
+	// SessionManager the session was issued by (Required)
+	SessionManager SessionManager
+
+	// RedirectTo is where the browser is sent after logging out (303 See Other), empty responds with 204 No Content
+	RedirectTo string
+
+	// EndSession optionally continues the logout at an identity provider
+	EndSession EndSessionFunc
+
+	// SkipCsrf disables the CSRF check of cookie sessions, without it a third party page can log users out
+	// (Default: false)
+	SkipCsrf bool
+}
+
+// checkSessionCsrf validates the CSRF token of a cookie session the same way a route with RequireCsrf does.
+func checkSessionCsrf(ctx *gin.Context, sessionManager SessionManager, claims *SessionClaims) error {
+	if csrfModeFor(ctx, sessionManager.GetCsrfData()) == CsrfModeHeaderPolicy {
+		_, err := establishHeaderPolicyCsrf(ctx, sessionManager.GetCsrfData(), claims, true)
+		return err
+	}
+
+	csrfToken, err := extractCsrf(ctx, sessionManager)
+	if err != nil {
+		return err
+	}
+	return validateCsrf(ctx, sessionManager, claims, csrfToken)
+}
+
+// invalidateSession revokes the server-side session and drops the cached bearer validation, so the next use of
+// the token goes through VerifySession.
+func invalidateSession(ctx *gin.Context, sessionManager SessionManager, claims *SessionClaims) error {
+	if revoker, ok := sessionManager.(SessionRevoker); ok {
+		if err := revoker.RevokeSession(ctx, claims); err != nil {
+			return fmt.Errorf("failed to revoke session: %w", err)
+		}
+	}
+
+	sessionId, ok := claims.GetClaim(SessionIdentifier)
+	if !ok || sessionId == "" {
+		return nil
+	}
+	cacheKey, err := formatCacheKey(sessionId)
+	if err != nil {
+		return err
+	}
+
+	cache, err := sessionManager.GetCache()
+	if err != nil || cache == nil {
+		return fmt.Errorf("failed to get cache: %w", err)
+	}
+	if err = cache.Delete(ctx, cacheKey); err != nil {
+		helpers.Logger(ctx).Debug("Failed to delete bearer cache entry", zap.Error(err))
+	}
+	return nil
+}
+
+// NewLogoutHandler returns a ready-made logout route: it checks the CSRF token of cookie sessions, revokes the
+// server-side session (see SessionRevoker), drops the cached bearer validation, clears the session and CSRF
+// cookies, revokes the remember-me token (see SetRememberMeCookie), and finally redirects to EndSession or
+// RedirectTo. Requests without a valid session are still logged out locally, so the route can be called
+// repeatedly. Mount it on POST, e.g.:
+//
+//	router.POST("/logout", core.NewLogoutHandler(core.LogoutConfiguration{SessionManager: sessionManager}))
+func NewLogoutHandler(config LogoutConfiguration) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		sessionManager := config.SessionManager
+		if sessionManager == nil {
+			helpers.ErrorResponse(ctx, errors.NewInternalServerError("Session manager is nil", nil))
+			return
+		}
+		ctx.Header("Cache-Control", "no-store")
+
+		_, claims, _, source, err := extractSession(ctx, sessionManager)
+		if err != nil {
+			helpers.Logger(ctx).Debug("Logging out an invalid session", zap.Error(err))
+			claims = nil
+		}
+
+		if claims != nil && source == SourceCookie && !config.SkipCsrf {
+			if err = checkSessionCsrf(ctx, sessionManager, claims); err != nil {
+				publishSessionEvent(ctx, sessionManager, SessionEventCsrfFailure, claims, err.Error())
+				helpers.ErrorResponse(ctx, errors.NewUnauthorized(csrfFailureMessage, err))
+				return
+			}
+		}
+
+		if claims != nil {
+			if err = invalidateSession(ctx, sessionManager, claims); err != nil {
+				helpers.ErrorResponse(ctx, errors.NewInternalServerError("Failed to invalidate session", err))
+				return
+			}
+		}
+
+		if err = ClearSessionCookie(ctx, sessionManager); err != nil {
+			helpers.ErrorResponse(ctx, errors.NewInternalServerError("Failed to clear session", err))
+			return
+		}
+		if err = ClearRememberMeCookie(ctx, sessionManager); err != nil {
+			helpers.ErrorResponse(ctx, errors.NewInternalServerError("Failed to clear remember-me token", err))
+			return
+		}
+
+		redirectTo := config.RedirectTo
+		if config.EndSession != nil {
+			endSessionURL, err := config.EndSession(ctx, claims)
+			if err != nil {
+				helpers.Logger(ctx).Warn("Failed to build the end session URL", zap.Error(err))
+			} else if endSessionURL != "" {
+				redirectTo = endSessionURL
+			}
+		}
+
+		if redirectTo == "" {
+			ctx.Status(http.StatusNoContent)
+			ctx.Writer.WriteHeaderNow()
+			return
+		}
+		ctx.Redirect(http.StatusSeeOther, redirectTo)
+	}
+}
@@ -0,0 +1,148 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// revokingSessionManager records revoked sessions.
+type revokingSessionManager struct {
+	*mockSessionManager
+	revoked []string
+}
+
+func (m *revokingSessionManager) RevokeSession(_ context.Context, claims *SessionClaims) error {
+	sessionId, _ := claims.GetClaim(SessionIdentifier)
+	m.revoked = append(m.revoked, sessionId)
+	return nil
+}
+
+func TestLogoutHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mgr := &revokingSessionManager{mockSessionManager: newMockSessionManager(t)}
+
+	login := func() []*http.Cookie {
+		t.Helper()
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+		ctx.Request = httptest.NewRequest(http.MethodPost, "/login", nil)
+		if err := SetSessionCookie(ctx, mgr, "user", &SessionClaims{Claims: map[string]string{"subject": "user-1"}}); err != nil {
+			t.Fatalf("Failed to set session cookie: %v", err)
+		}
+		return recorder.Result().Cookies()
+	}
+
+	logout := func(config LogoutConfiguration, cookies []*http.Cookie, headers map[string]string) *httptest.ResponseRecorder {
+		router := gin.New()
+		config.SessionManager = mgr
+		router.POST("/logout", NewLogoutHandler(config))
+
+		request := httptest.NewRequest(http.MethodPost, "/logout", nil)
+		for _, cookie := range cookies {
+			request.AddCookie(cookie)
+		}
+		for name, value := range headers {
+			request.Header.Set(name, value)
+		}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	csrfHeader := func(cookies []*http.Cookie) map[string]string {
+		for _, cookie := range cookies {
+			if cookie.Name == DefaultCsrfCookieName {
+				return map[string]string{DefaultCsrfCookieName: cookie.Value}
+			}
+		}
+		t.Fatal("Expected a CSRF cookie")
+		return nil
+	}
+
+	t.Run("Cookie session is revoked and cleared", func(t *testing.T) {
+		mgr.revoked = nil
+		cookies := login()
+		recorder := logout(LogoutConfiguration{}, cookies, csrfHeader(cookies))
+		if recorder.Code != http.StatusNoContent {
+			t.Fatalf("Expected 204, got %d: %s", recorder.Code, recorder.Body.String())
+		}
+		if len(mgr.revoked) != 1 || mgr.revoked[0] == "" {
+			t.Errorf("Expected the session to be revoked, got %v", mgr.revoked)
+		}
+
+		cleared := map[string]bool{}
+		for _, cookie := range recorder.Result().Cookies() {
+			if cookie.MaxAge < 0 {
+				cleared[cookie.Name] = true
+			}
+		}
+		if !cleared[DefaultSessionAuthorizationName] || !cleared[DefaultCsrfCookieName] {
+			t.Errorf("Expected the session and CSRF cookies to be cleared, got %v", cleared)
+		}
+	})
+
+	t.Run("Missing CSRF token is rejected", func(t *testing.T) {
+		mgr.revoked = nil
+		if recorder := logout(LogoutConfiguration{}, login(), nil); recorder.Code != http.StatusUnauthorized {
+			t.Errorf("Expected 401, got %d", recorder.Code)
+		}
+		if len(mgr.revoked) != 0 {
+			t.Error("Expected the session to stay valid")
+		}
+	})
+
+	t.Run("Bearer validation cache is dropped", func(t *testing.T) {
+		mgr.revoked = nil
+		ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+		ctx.Request = httptest.NewRequest(http.MethodPost, "/token", nil)
+		claims := &SessionClaims{Claims: map[string]string{"subject": "user-1"}}
+		bearer, err := IssueBearerToken(ctx, mgr, "service", claims)
+		if err != nil {
+			t.Fatalf("Failed to issue bearer: %v", err)
+		}
+
+		cacheKey, _ := formatCacheKey(claims.Claims[SessionIdentifier])
+		header := NewSessionHeader(true, time.Hour, time.Hour)
+		if err = BearerSetCache(ctx, mgr, cacheKey, &header); err != nil {
+			t.Fatalf("Failed to set bearer cache: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+		if _, needsRefresh, _ := BearerNeedsValidation(ctx, mgr, claims); needsRefresh {
+			t.Fatal("Expected the bearer validation to be cached")
+		}
+
+		recorder := logout(LogoutConfiguration{}, nil, map[string]string{DefaultSessionAuthorizationHeaderName: bearer})
+		if recorder.Code != http.StatusNoContent || len(mgr.revoked) != 1 {
+			t.Fatalf("Expected the bearer to be revoked, got %d %v", recorder.Code, mgr.revoked)
+		}
+		if _, needsRefresh, _ := BearerNeedsValidation(ctx, mgr, claims); !needsRefresh {
+			t.Error("Expected the bearer to be revalidated on its next use")
+		}
+	})
+
+	t.Run("Redirects to the end session URL", func(t *testing.T) {
+		cookies := login()
+		recorder := logout(LogoutConfiguration{
+			RedirectTo: "/goodbye",
+			EndSession: func(_ *gin.Context, claims *SessionClaims) (string, error) {
+				if claims == nil {
+					return "", nil
+				}
+				return "https://idp.example.com/logout", nil
+			},
+		}, cookies, csrfHeader(cookies))
+		if recorder.Code != http.StatusSeeOther || recorder.Header().Get("Location") != "https://idp.example.com/logout" {
+			t.Errorf("Expected a redirect to the provider, got %d %q", recorder.Code, recorder.Header().Get("Location"))
+		}
+
+		// - Without a session only the local redirect applies
+		if recorder = logout(LogoutConfiguration{RedirectTo: "/goodbye"}, nil, nil); recorder.Header().Get("Location") != "/goodbye" {
+			t.Errorf("Expected a redirect to /goodbye, got %q", recorder.Header().Get("Location"))
+		}
+	})
+}
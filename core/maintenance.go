@@ -0,0 +1,49 @@
+package core
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/errors"
+)
+
+// DefaultMaintenanceRetryAfter is the Retry-After of maintenance responses when SetMaintenanceMode got none.
+const DefaultMaintenanceRetryAfter = time.Minute
+
+var maintenance = struct {
+	sync.RWMutex
+	enabled    bool
+	retryAfter time.Duration
+}{}
+
+// SetMaintenanceMode turns maintenance mode on or off at runtime. While it is on, every route executed by GoThic
+// answers 503 Service Unavailable with a Retry-After header of retryAfter (Default: DefaultMaintenanceRetryAfter),
+// except the routes with MaintenanceExempt. Requests already being handled are not interrupted.
+func SetMaintenanceMode(enabled bool, retryAfter time.Duration) {
+	maintenance.Lock()
+	defer maintenance.Unlock()
+	maintenance.enabled = enabled
+	maintenance.retryAfter = retryAfter
+}
+
+// MaintenanceMode reports whether maintenance mode is on, and the Retry-After it answers with.
+func MaintenanceMode() (bool, time.Duration) {
+	maintenance.RLock()
+	defer maintenance.RUnlock()
+	if maintenance.retryAfter <= 0 {
+		return maintenance.enabled, DefaultMaintenanceRetryAfter
+	}
+	return maintenance.enabled, maintenance.retryAfter
+}
+
+// checkMaintenance returns the 503 to respond with while maintenance mode is on, setting its Retry-After.
+func checkMaintenance(ctx *gin.Context, sessionConfig *APIConfiguration) *errors.AppError {
+	enabled, retryAfter := MaintenanceMode()
+	if !enabled || sessionConfig.MaintenanceExempt {
+		return nil
+	}
+	ctx.Header("Retry-After", strconv.Itoa(int(retryAfter.Seconds())))
+	return errors.NewServiceUnavailable("The service is undergoing maintenance, please try again later.", nil)
+}
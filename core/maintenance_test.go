@@ -0,0 +1,84 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/errors"
+)
+
+func TestMaintenanceMode(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mgr := newMockSessionManager(t)
+	defer SetMaintenanceMode(false, 0)
+
+	router := gin.New()
+	ctor := NewRouteConstructor(router, testBaseRoute{}, mgr, nil)
+	ok := func(_ *struct{}, _ *Handler[testBaseRoute]) (*struct{}, *errors.AppError) {
+		return &struct{}{}, nil
+	}
+	GET(ctor, "/orders", PublicRoute(), ok)
+	GET(ctor, "/status", PublicRoute().AsMaintenanceExempt(), ok)
+	download := func(grant *DownloadGrant, data *Handler[testBaseRoute]) *errors.AppError {
+		data.Context.String(http.StatusOK, grant.Resource)
+		return nil
+	}
+	DOWNLOAD(ctor, "/download", PublicRoute(), "export", download)
+	DOWNLOAD(ctor, "/download/status", PublicRoute().AsMaintenanceExempt(), "export", download)
+	if err := DISPATCH(ctor, "maintenance.orders", PublicRoute(), ok); err != nil {
+		t.Fatalf("Failed to register dispatch handler: %v", err)
+	}
+	defer UnregisterDispatch("maintenance.orders")
+
+	request := func(path string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		return recorder
+	}
+
+	if recorder := request("/orders"); recorder.Code != http.StatusOK {
+		t.Fatalf("Expected routes to be served outside maintenance, got %d", recorder.Code)
+	}
+
+	SetMaintenanceMode(true, 2*time.Minute)
+	recorder := request("/orders")
+	if recorder.Code != http.StatusServiceUnavailable || recorder.Header().Get("Retry-After") != "120" {
+		t.Errorf("Expected a 503 with Retry-After, got %d: %v", recorder.Code, recorder.Header())
+	}
+	if recorder = request("/status"); recorder.Code != http.StatusOK {
+		t.Errorf("Expected exempt routes to be served, got %d", recorder.Code)
+	}
+
+	t.Run("Downloads and dispatched handlers are unavailable", func(t *testing.T) {
+		claims := &SessionClaims{HasSession: true, Claims: map[string]string{"subject": "user-1"}}
+		token, err := IssueDownloadGrant(context.Background(), mgr, claims, "export", "exports/1.csv", time.Minute, nil)
+		if err != nil {
+			t.Fatalf("Failed to issue download grant: %v", err)
+		}
+		if recorder = request("/download?" + DownloadGrantQueryParam + "=" + url.QueryEscape(token)); recorder.Code != http.StatusServiceUnavailable {
+			t.Errorf("Expected downloads to be unavailable, got %d", recorder.Code)
+		}
+		if recorder = request("/download/status?" + DownloadGrantQueryParam + "=" + url.QueryEscape(token)); recorder.Code != http.StatusOK {
+			t.Errorf("Expected the grant to survive maintenance on an exempt route, got %d", recorder.Code)
+		}
+
+		if _, appErr := Dispatch(context.Background(), "maintenance.orders", nil, nil); appErr == nil || appErr.Code != http.StatusServiceUnavailable {
+			t.Errorf("Expected dispatched handlers to be unavailable, got %v", appErr)
+		}
+	})
+
+	SetMaintenanceMode(true, 0)
+	if recorder = request("/orders"); recorder.Header().Get("Retry-After") != "60" {
+		t.Errorf("Expected the default Retry-After, got '%s'", recorder.Header().Get("Retry-After"))
+	}
+
+	SetMaintenanceMode(false, 0)
+	if recorder = request("/orders"); recorder.Code != http.StatusOK {
+		t.Errorf("Expected routes to be served after maintenance, got %d", recorder.Code)
+	}
+}
@@ -0,0 +1,661 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/grzegorzmaniak/gothic/errors"
+	"github.com/grzegorzmaniak/gothic/helpers"
+	"github.com/grzegorzmaniak/gothic/validation"
+)
+
+const (
+	OpenAPIVersion        = "3.1.0"
+	DefaultOpenAPIVersion = "1.0.0" // Version of the documented API
+
+	// Security scheme names used in the generated document.
+	OpenAPISessionCookieScheme = "sessionCookie"
+	OpenAPICsrfTokenScheme     = "csrfToken"
+	OpenAPIBearerScheme        = "bearerToken"
+
+	// OpenAPIErrorSchema is the component describing the error body written by helpers.ErrorResponse.
+	OpenAPIErrorSchema = "Error"
+)
+
+// OpenAPIConfig configures the generated document.
+type OpenAPIConfig struct {
+	// Title of the API (Default: DefaultDocsTitle)
+	Title string
+
+	// Version of the API (Default: DefaultOpenAPIVersion)
+	Version string
+
+	Description string
+
+	// Servers are the base URLs the API is served from, e.g., https://api.example.com
+	Servers []string
+
+	// RouteConfig protects the route registered by RegisterOpenAPI (Default: a required session without CSRF)
+	RouteConfig *APIConfiguration
+}
+
+type OpenAPIDocument struct {
+	OpenAPI    string                     `json:"openapi"`
+	Info       OpenAPIInfo                `json:"info"`
+	Servers    []OpenAPIServer            `json:"servers,omitempty"`
+	Paths      map[string]OpenAPIPathItem `json:"paths"`
+	Components OpenAPIComponents          `json:"components"`
+}
+
+type OpenAPIInfo struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+type OpenAPIServer struct {
+	URL string `json:"url"`
+}
+
+// OpenAPIPathItem maps the lower case HTTP method to its operation.
+type OpenAPIPathItem map[string]*OpenAPIOperation
+
+type OpenAPIOperation struct {
+	OperationID string                      `json:"operationId"`
+	Parameters  []*OpenAPIParameter         `json:"parameters,omitempty"`
+	RequestBody *OpenAPIRequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*OpenAPIResponse `json:"responses"`
+	Security    []map[string][]string       `json:"security,omitempty"`
+}
+
+type OpenAPIParameter struct {
+	Name     string         `json:"name"`
+	In       string         `json:"in"` // path, query or header
+	Required bool           `json:"required,omitempty"`
+	Schema   *OpenAPISchema `json:"schema"`
+}
+
+type OpenAPIRequestBody struct {
+	Required bool                         `json:"required,omitempty"`
+	Content  map[string]*OpenAPIMediaType `json:"content"`
+}
+
+type OpenAPIResponse struct {
+	Description string                       `json:"description"`
+	Headers     map[string]*OpenAPIHeader    `json:"headers,omitempty"`
+	Content     map[string]*OpenAPIMediaType `json:"content,omitempty"`
+}
+
+type OpenAPIHeader struct {
+	Description string         `json:"description,omitempty"`
+	Schema      *OpenAPISchema `json:"schema"`
+}
+
+type OpenAPIMediaType struct {
+	Schema *OpenAPISchema `json:"schema"`
+}
+
+type OpenAPIComponents struct {
+	Schemas         map[string]*OpenAPISchema         `json:"schemas,omitempty"`
+	SecuritySchemes map[string]*OpenAPISecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type OpenAPISecurityScheme struct {
+	Type        string `json:"type"`
+	In          string `json:"in,omitempty"`
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// OpenAPISchema is the subset of JSON Schema the generator emits.
+type OpenAPISchema struct {
+	Ref                  string                    `json:"$ref,omitempty"`
+	Type                 string                    `json:"type,omitempty"`
+	Format               string                    `json:"format,omitempty"`
+	Properties           map[string]*OpenAPISchema `json:"properties,omitempty"`
+	Required             []string                  `json:"required,omitempty"`
+	Items                *OpenAPISchema            `json:"items,omitempty"`
+	AdditionalProperties *OpenAPISchema            `json:"additionalProperties,omitempty"`
+	Enum                 []interface{}             `json:"enum,omitempty"`
+	MinLength            *int                      `json:"minLength,omitempty"`
+	MaxLength            *int                      `json:"maxLength,omitempty"`
+	MinItems             *int                      `json:"minItems,omitempty"`
+	MaxItems             *int                      `json:"maxItems,omitempty"`
+	Minimum              *float64                  `json:"minimum,omitempty"`
+	Maximum              *float64                  `json:"maximum,omitempty"`
+	ExclusiveMinimum     *float64                  `json:"exclusiveMinimum,omitempty"`
+	ExclusiveMaximum     *float64                  `json:"exclusiveMaximum,omitempty"`
+}
+
+// GenerateOpenAPI documents the routes registered through the constructor. Schemas are derived from the input
+// and output structs (or the FieldRules of dynamic routes): `uri`, `header` and `form` tagged fields become
+// parameters, the remaining input fields the JSON body, and `validate` tags become constraints. Routes that
+// require a session list the cookie (with the CSRF header) and bearer security schemes.
+func GenerateOpenAPI[BaseRoute helpers.BaseRouteComponents](ctor *RouteConstructor[BaseRoute], config OpenAPIConfig) *OpenAPIDocument {
+	schemas := newOpenAPISchemas()
+	if ctor.sessionManager != nil {
+		schemas.csrfData = ctor.sessionManager.GetCsrfData()
+	}
+	document := &OpenAPIDocument{
+		OpenAPI: OpenAPIVersion,
+		Info: OpenAPIInfo{
+			Title:       helpers.DefaultString(config.Title, DefaultDocsTitle),
+			Version:     helpers.DefaultString(config.Version, DefaultOpenAPIVersion),
+			Description: config.Description,
+		},
+		Paths: make(map[string]OpenAPIPathItem),
+		Components: OpenAPIComponents{
+			Schemas:         schemas.components,
+			SecuritySchemes: openAPISecuritySchemes(ctor.sessionManager),
+		},
+	}
+	for _, server := range config.Servers {
+		document.Servers = append(document.Servers, OpenAPIServer{URL: server})
+	}
+
+	schemas.components[OpenAPIErrorSchema] = &OpenAPISchema{
+		Type:       "object",
+		Properties: map[string]*OpenAPISchema{"error": {Type: "string"}, "details": {}},
+		Required:   []string{"error"},
+	}
+
+	for _, route := range ctor.Routes() {
+		path, pathParams := openAPIPath(route.Path)
+		item, ok := document.Paths[path]
+		if !ok {
+			item = make(OpenAPIPathItem)
+			document.Paths[path] = item
+		}
+		item[strings.ToLower(route.Method)] = schemas.operation(route, path, pathParams)
+	}
+
+	return document
+}
+
+// OpenAPISpecProvider returns a DocsConfig.SpecProvider that generates the document on every call.
+func OpenAPISpecProvider[BaseRoute helpers.BaseRouteComponents](ctor *RouteConstructor[BaseRoute], config OpenAPIConfig) func() ([]byte, error) {
+	return func() ([]byte, error) {
+		return json.Marshal(GenerateOpenAPI(ctor, config))
+	}
+}
+
+// RegisterOpenAPI serves the generated document at path, e.g., DefaultDocsSpecPath. The route goes through the
+// executor with config.RouteConfig, and is not part of the document itself.
+func RegisterOpenAPI[BaseRoute helpers.BaseRouteComponents](ctor *RouteConstructor[BaseRoute], path string, config OpenAPIConfig) {
+	specProvider := OpenAPISpecProvider(ctor, config)
+	routeConfig := docsRouteConfig(DocsConfig{RouteConfig: config.RouteConfig})
+
+	handleRoute(ctor, http.MethodGet, path, routeConfig, func(_ *struct{}, data *Handler[BaseRoute]) (*struct{}, *errors.AppError) {
+		spec, err := specProvider()
+		if err != nil {
+			return nil, errors.NewInternalServerError("Failed to generate the API specification", err)
+		}
+		data.Context.Data(http.StatusOK, "application/json", spec)
+		return nil, nil
+	})
+}
+
+// openAPISecuritySchemes describes the session cookie, its CSRF header and the bearer header.
+func openAPISecuritySchemes(sessionManager SessionManager) map[string]*OpenAPISecurityScheme {
+	cookieName, headerName, csrfName := DefaultSessionAuthorizationName, DefaultSessionAuthorizationHeaderName, DefaultCsrfCookieName
+	if sessionManager != nil {
+		if authData := sessionManager.GetAuthorizationConfiguration(); authData != nil {
+			cookieName = sessionCookieName(authData)
+			headerName = helpers.DefaultString(authData.AuthorizationHeaderName, headerName)
+		}
+		if csrfData := sessionManager.GetCsrfData(); csrfData != nil {
+			csrfName = helpers.DefaultString(csrfData.Name, csrfName)
+		}
+	}
+
+	return map[string]*OpenAPISecurityScheme{
+		OpenAPISessionCookieScheme: {Type: "apiKey", In: "cookie", Name: cookieName, Description: "Browser session cookie"},
+		OpenAPICsrfTokenScheme:     {Type: "apiKey", In: "header", Name: csrfName, Description: "Echo of the CSRF cookie, required with the session cookie"},
+		OpenAPIBearerScheme:        {Type: "apiKey", In: "header", Name: headerName, Description: "Bearer session token"},
+	}
+}
+
+// openAPIPath converts a Gin path to an OpenAPI path, returning its parameter names.
+func openAPIPath(path string) (string, []string) {
+	segments := strings.Split(path, "/")
+	var params []string
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			params = append(params, segment[1:])
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/"), params
+}
+
+// openAPIOperationID builds an identifier from the method and path, e.g., GET /orders/{id} -> getOrdersId.
+func openAPIOperationID(method, path string) string {
+	var builder strings.Builder
+	builder.WriteString(strings.ToLower(method))
+	upper := true
+	for _, r := range path {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		builder.WriteRune(r)
+	}
+	return builder.String()
+}
+
+type openAPISchemas struct {
+	components map[string]*OpenAPISchema
+	names      map[reflect.Type]string
+	csrfData   *CsrfCookieData
+}
+
+func newOpenAPISchemas() *openAPISchemas {
+	return &openAPISchemas{components: make(map[string]*OpenAPISchema), names: make(map[reflect.Type]string)}
+}
+
+func (s *openAPISchemas) operation(route RouteInfo, path string, pathParams []string) *OpenAPIOperation {
+	config := route.Config
+	if config == nil {
+		config = &APIConfiguration{}
+	}
+
+	operation := &OpenAPIOperation{
+		OperationID: openAPIOperationID(route.Method, path),
+		Responses:   make(map[string]*OpenAPIResponse),
+	}
+	errorResponse := func(description string) *OpenAPIResponse {
+		return &OpenAPIResponse{
+			Description: description,
+			Content:     map[string]*OpenAPIMediaType{"application/json": {Schema: &OpenAPISchema{Ref: "#/components/schemas/" + OpenAPIErrorSchema}}},
+		}
+	}
+
+	// - Input: path, header and query parameters, the rest is the body
+	hasBody := route.Method != http.MethodGet && route.Method != http.MethodDelete && route.Method != http.MethodHead
+	documented := make(map[string]bool)
+	body := &OpenAPISchema{Type: "object", Properties: make(map[string]*OpenAPISchema)}
+	for _, field := range openAPIFields(route.InputType) {
+		schema, required := s.fieldSchema(field)
+		uriName := tagName(field, "uri")
+		switch {
+		case uriName != "" && containsString(pathParams, uriName):
+			if !documented["path:"+uriName] {
+				documented["path:"+uriName] = true
+				operation.Parameters = append(operation.Parameters, &OpenAPIParameter{Name: uriName, In: "path", Required: true, Schema: schema})
+			}
+		case tagName(field, "header") != "":
+			operation.Parameters = append(operation.Parameters, &OpenAPIParameter{Name: tagName(field, "header"), In: "header", Required: required, Schema: schema})
+		case hasBody && tagName(field, "json") != "":
+			body.Properties[tagName(field, "json")] = schema
+			if required {
+				body.Required = append(body.Required, tagName(field, "json"))
+			}
+		case tagName(field, "form") != "" || !hasBody:
+			name := helpers.DefaultString(tagName(field, "form"), field.Name)
+			if field.Tag.Get("form") != "-" {
+				operation.Parameters = append(operation.Parameters, &OpenAPIParameter{Name: name, In: "query", Required: required, Schema: schema})
+			}
+		}
+	}
+
+	inputParams := len(operation.Parameters)
+
+	// - Path parameters the input does not bind are still required by the router
+	for _, param := range pathParams {
+		if !documented["path:"+param] {
+			operation.Parameters = append(operation.Parameters, &OpenAPIParameter{Name: param, In: "path", Required: true, Schema: &OpenAPISchema{Type: "string"}})
+		}
+	}
+
+	if len(body.Properties) > 0 {
+		sort.Strings(body.Required)
+		bodySchema := body
+		// - Named inputs are only shared as a component if they are bound entirely from the body
+		if route.InputType.Name() != "" && !route.Dynamic && inputParams == 0 {
+			bodySchema = s.register(route.InputType, body)
+		}
+		operation.RequestBody = &OpenAPIRequestBody{
+			Required: len(body.Required) > 0,
+			Content:  map[string]*OpenAPIMediaType{"application/json": {Schema: bodySchema}},
+		}
+	}
+
+	// - Output: header tagged fields are response headers, the rest is the body
+	success := &OpenAPIResponse{Description: "Successful response"}
+	if !config.ManualResponse && route.OutputType != nil {
+		responseBody := &OpenAPISchema{Type: "object", Properties: make(map[string]*OpenAPISchema)}
+		for _, field := range openAPIFields(route.OutputType) {
+			schema, required := s.fieldSchema(field)
+			if header := tagName(field, "header"); header != "" {
+				if success.Headers == nil {
+					success.Headers = make(map[string]*OpenAPIHeader)
+				}
+				success.Headers[header] = &OpenAPIHeader{Schema: schema}
+				continue
+			}
+			if name := tagName(field, "json"); name != "" || field.Tag.Get("json") == "" {
+				name = helpers.DefaultString(name, field.Name)
+				responseBody.Properties[name] = schema
+				if required {
+					responseBody.Required = append(responseBody.Required, name)
+				}
+			}
+		}
+		sort.Strings(responseBody.Required)
+
+		if reflect.PointerTo(route.OutputType).Implements(reflect.TypeOf((*helpers.Paginated)(nil)).Elem()) {
+			if success.Headers == nil {
+				success.Headers = make(map[string]*OpenAPIHeader)
+			}
+			success.Headers["Link"] = &OpenAPIHeader{Description: "The next page, when there is one", Schema: &OpenAPISchema{Type: "string"}}
+			success.Headers[helpers.TotalCountHeader] = &OpenAPIHeader{Description: "The total number of items, when it is known", Schema: &OpenAPISchema{Type: "integer"}}
+		}
+
+		responseSchema := responseBody
+		if route.OutputType.Name() != "" && !route.Dynamic {
+			responseSchema = s.register(route.OutputType, responseBody)
+		}
+		success.Content = map[string]*OpenAPIMediaType{"application/json": {Schema: responseSchema}}
+	}
+	operation.Responses["200"] = success
+
+	if route.InputType != nil && route.InputType.NumField() > 0 {
+		operation.Responses["400"] = errorResponse("The input failed validation")
+	}
+
+	// - Security: the cookie needs the CSRF header, bearer tokens do not
+	if config.SessionRequired {
+		cookie := map[string][]string{OpenAPISessionCookieScheme: {}}
+		if config.RequireCsrf && !csrfExempt(s.csrfData, config, route.Method) {
+			cookie[OpenAPICsrfTokenScheme] = []string{}
+		}
+		operation.Security = []map[string][]string{cookie, {OpenAPIBearerScheme: {}}}
+		operation.Responses["401"] = errorResponse("The session is missing or invalid")
+	}
+	if config.Roles != nil || config.Permissions != nil || len(config.NamedPermissions) > 0 {
+		// - RBAC denials are reported as 401 as well
+		operation.Responses["401"] = errorResponse("The session is missing, invalid or lacks the required roles or permissions")
+	}
+	operation.Responses["default"] = errorResponse("Error response")
+
+	return operation
+}
+
+// register stores a named struct schema as a component and returns a reference to it.
+func (s *openAPISchemas) register(t reflect.Type, schema *OpenAPISchema) *OpenAPISchema {
+	name, ok := s.names[t]
+	if !ok {
+		name = s.componentName(t)
+		s.names[t] = name
+	}
+	s.components[name] = schema
+	return &OpenAPISchema{Ref: "#/components/schemas/" + name}
+}
+
+// componentName sanitizes the type name, e.g., for generics, and qualifies it on collisions.
+func (s *openAPISchemas) componentName(t reflect.Type) string {
+	sanitize := func(name string) string {
+		return strings.Map(func(r rune) rune {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' {
+				return r
+			}
+			return '_'
+		}, name)
+	}
+
+	name := sanitize(t.Name())
+	if _, taken := s.components[name]; taken || name == OpenAPIErrorSchema {
+		name = sanitize(t.PkgPath()) + "_" + name
+	}
+	return name
+}
+
+// schemaFor returns the schema of a type, named structs are referenced as components.
+func (s *openAPISchemas) schemaFor(t reflect.Type) *OpenAPISchema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if inner, ok := validation.OptionalValueType(t); ok {
+		return s.schemaFor(inner)
+	}
+
+	switch t {
+	case reflect.TypeOf(time.Time{}):
+		return &OpenAPISchema{Type: "string", Format: "date-time"}
+	case reflect.TypeOf(time.Duration(0)):
+		return &OpenAPISchema{Type: "integer", Format: "int64"}
+	case reflect.TypeOf(json.RawMessage{}):
+		return &OpenAPISchema{}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &OpenAPISchema{Type: "string"}
+	case reflect.Bool:
+		return &OpenAPISchema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &OpenAPISchema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &OpenAPISchema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &OpenAPISchema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &OpenAPISchema{Type: "number", Format: "double"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &OpenAPISchema{Type: "string", Format: "byte"}
+		}
+		return &OpenAPISchema{Type: "array", Items: s.schemaFor(t.Elem())}
+	case reflect.Map:
+		return &OpenAPISchema{Type: "object", AdditionalProperties: s.schemaFor(t.Elem())}
+	case reflect.Struct:
+		if name, ok := s.names[t]; ok {
+			return &OpenAPISchema{Ref: "#/components/schemas/" + name}
+		}
+
+		// - Named structs are registered before their fields, so recursive types terminate
+		if t.Name() != "" {
+			s.names[t] = s.componentName(t)
+			s.components[s.names[t]] = &OpenAPISchema{}
+		}
+
+		schema := &OpenAPISchema{Type: "object", Properties: make(map[string]*OpenAPISchema)}
+		for _, field := range openAPIFields(t) {
+			name := tagName(field, "json")
+			if name == "" {
+				if field.Tag.Get("json") == "-" {
+					continue
+				}
+				name = field.Name
+			}
+			fieldSchema, required := s.fieldSchema(field)
+			schema.Properties[name] = fieldSchema
+			if required {
+				schema.Required = append(schema.Required, name)
+			}
+		}
+		sort.Strings(schema.Required)
+
+		if t.Name() == "" {
+			return schema
+		}
+		return s.register(t, schema)
+	default:
+		return &OpenAPISchema{}
+	}
+}
+
+// fieldSchema returns the schema of a struct field with its `validate` constraints applied.
+func (s *openAPISchemas) fieldSchema(field reflect.StructField) (*OpenAPISchema, bool) {
+	schema := s.schemaFor(field.Type)
+	rules := field.Tag.Get("validate")
+	if rules == "" || rules == "-" {
+		return schema, false
+	}
+
+	// - Constraints can not be added next to a $ref, the referenced schema is left untouched
+	if schema.Ref != "" {
+		return schema, containsString(strings.Split(rules, ","), "required")
+	}
+
+	// - A copy is constrained, so the same type can carry different rules per field
+	constrained := *schema
+	target := &constrained
+	required := false
+	for _, rule := range strings.Split(rules, ",") {
+		name, param, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		switch name {
+		case "required":
+			if target == &constrained {
+				required = true
+			}
+		case "dive":
+			if target.Items == nil {
+				return &constrained, required
+			}
+			items := *target.Items
+			target.Items = &items
+			target = &items
+		default:
+			applyOpenAPIConstraint(target, name, param)
+		}
+	}
+	return &constrained, required
+}
+
+// applyOpenAPIConstraint maps a validator tag to the JSON Schema keyword, unknown tags are ignored.
+func applyOpenAPIConstraint(schema *OpenAPISchema, name, param string) {
+	number, numberErr := strconv.ParseFloat(param, 64)
+	length, lengthErr := strconv.Atoi(param)
+
+	switch name {
+	case "min", "max", "len":
+		switch {
+		case schema.Type == "string" && lengthErr == nil:
+			if name != "max" {
+				schema.MinLength = &length
+			}
+			if name != "min" {
+				schema.MaxLength = &length
+			}
+		case schema.Type == "array" && lengthErr == nil:
+			if name != "max" {
+				schema.MinItems = &length
+			}
+			if name != "min" {
+				schema.MaxItems = &length
+			}
+		case numberErr == nil:
+			if name != "max" {
+				schema.Minimum = &number
+			}
+			if name != "min" {
+				schema.Maximum = &number
+			}
+		}
+	case "gte":
+		if numberErr == nil {
+			schema.Minimum = &number
+		}
+	case "lte":
+		if numberErr == nil {
+			schema.Maximum = &number
+		}
+	case "gt":
+		if numberErr == nil {
+			schema.ExclusiveMinimum = &number
+		}
+	case "lt":
+		if numberErr == nil {
+			schema.ExclusiveMaximum = &number
+		}
+	case "oneof":
+		for _, value := range strings.Fields(param) {
+			if schema.Type == "integer" || schema.Type == "number" {
+				if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+					schema.Enum = append(schema.Enum, parsed)
+					continue
+				}
+			}
+			schema.Enum = append(schema.Enum, value)
+		}
+	case "email":
+		schema.Format = "email"
+	case "url", "uri", "http_url":
+		schema.Format = "uri"
+	case "uuid", "uuid4", "uuid_rfc4122", "uuid4_rfc4122":
+		schema.Format = "uuid"
+	case "ipv4":
+		schema.Format = "ipv4"
+	case "ipv6":
+		schema.Format = "ipv6"
+	case "hostname", "hostname_rfc1123":
+		schema.Format = "hostname"
+	case "datetime":
+		schema.Format = "date-time"
+	}
+}
+
+// openAPIFields returns the exported fields of a struct, flattening embedded structs like encoding/json.
+func openAPIFields(t reflect.Type) []reflect.StructField {
+	if t == nil {
+		return nil
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var fields []reflect.StructField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Tag.Get("json") == "" {
+			fields = append(fields, openAPIFields(field.Type)...)
+			continue
+		}
+		if field.IsExported() {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// tagName returns the name part of a struct tag, or "" if it is unset or "-".
+func tagName(field reflect.StructField, key string) string {
+	name, _, _ := strings.Cut(field.Tag.Get(key), ",")
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
+func containsString(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
+
+// String renders the document as indented JSON, e.g., to commit it next to the code.
+func (document *OpenAPIDocument) String() string {
+	encoded, err := json.MarshalIndent(document, "", "  ")
+	if err != nil {
+		return fmt.Sprintf("invalid OpenAPI document: %v", err)
+	}
+	return string(encoded)
+}
@@ -0,0 +1,145 @@
+package core
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/errors"
+	"github.com/grzegorzmaniak/gothic/validation"
+)
+
+type openAPIOrderInput struct {
+	ID       string   `uri:"id" validate:"required,uuid"`
+	Tenant   string   `header:"X-Tenant"`
+	Quantity int      `json:"quantity" validate:"required,gte=1,lte=100"`
+	Status   string   `json:"status" validate:"omitempty,oneof=open closed"`
+	Tags     []string `json:"tags" validate:"max=5,dive,min=2"`
+}
+
+type openAPIOrder struct {
+	ID       string            `json:"id"`
+	Quantity int               `json:"quantity"`
+	Items    []openAPIOrder    `json:"items,omitempty"`
+	ETag     string            `header:"ETag" json:"-"`
+	Labels   map[string]string `json:"labels"`
+}
+
+type openAPIOrderList struct {
+	Orders []openAPIOrder `json:"orders"`
+}
+
+type openAPIListInput struct {
+	Page  int    `form:"page" validate:"min=1"`
+	Query string `form:"q"`
+}
+
+func TestGenerateOpenAPI(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	ctor := NewRouteConstructor(router, testBaseRoute{}, newMockSessionManager(t), nil)
+
+	PUT(ctor, "/orders/:id", AuthenticatedJSONAPI().WithRoles("editor"), func(_ *openAPIOrderInput, _ *Handler[testBaseRoute]) (*openAPIOrder, *errors.AppError) {
+		return nil, nil
+	})
+	GET(ctor, "/orders", PublicRoute(), func(_ *openAPIListInput, _ *Handler[testBaseRoute]) (*openAPIOrderList, *errors.AppError) {
+		return nil, nil
+	})
+	DYNAMIC(ctor, http.MethodPost, "/notes", AuthenticatedJSONAPI().WithoutCsrf(),
+		validation.FieldRules{"Title": {Tags: "required,max=64"}},
+		validation.FieldRules{"Id": {Type: "int64"}},
+		func(_ map[string]interface{}, _ *Handler[testBaseRoute]) (map[string]any, *errors.AppError) {
+			return nil, nil
+		},
+	)
+	RegisterOpenAPI(ctor, DefaultDocsSpecPath, OpenAPIConfig{Title: "Orders", RouteConfig: PublicRoute()})
+
+	document := GenerateOpenAPI(ctor, OpenAPIConfig{Title: "Orders"})
+	if document.OpenAPI != OpenAPIVersion || document.Info.Title != "Orders" || len(document.Paths) != 3 {
+		t.Fatalf("Unexpected document %s", document)
+	}
+
+	t.Run("Typed inputs are split into parameters and the body", func(t *testing.T) {
+		operation := document.Paths["/orders/{id}"]["put"]
+		if operation == nil || operation.OperationID != "putOrdersId" {
+			t.Fatalf("Expected the PUT operation, got %s", document)
+		}
+
+		in := map[string]string{}
+		for _, parameter := range operation.Parameters {
+			in[parameter.Name] = parameter.In
+		}
+		if in["id"] != "path" || in["X-Tenant"] != "header" || len(in) != 2 {
+			t.Errorf("Unexpected parameters %v", in)
+		}
+
+		body := operation.RequestBody.Content["application/json"].Schema
+		quantity := body.Properties["quantity"]
+		if quantity == nil || *quantity.Minimum != 1 || *quantity.Maximum != 100 || len(body.Required) != 1 {
+			t.Errorf("Expected the validate constraints, got %+v / %v", quantity, body.Required)
+		}
+		if status := body.Properties["status"]; len(status.Enum) != 2 {
+			t.Errorf("Expected the oneof enum, got %+v", status)
+		}
+		if tags := body.Properties["tags"]; *tags.MaxItems != 5 || *tags.Items.MinLength != 2 {
+			t.Errorf("Expected dive to constrain the items, got %+v", tags)
+		}
+
+		for _, status := range []string{"200", "400", "401"} {
+			if operation.Responses[status] == nil {
+				t.Errorf("Expected a %s response", status)
+			}
+		}
+		if operation.Responses["403"] != nil {
+			t.Error("Expected RBAC denials to be documented as 401")
+		}
+		if len(operation.Security) != 2 || len(operation.Security[0]) != 2 {
+			t.Errorf("Expected the cookie with CSRF and bearer requirements, got %v", operation.Security)
+		}
+	})
+
+	t.Run("Outputs are components with response headers", func(t *testing.T) {
+		response := document.Paths["/orders/{id}"]["put"].Responses["200"]
+		if response.Content["application/json"].Schema.Ref != "#/components/schemas/openAPIOrder" || response.Headers["ETag"] == nil {
+			t.Fatalf("Expected a referenced schema and the ETag header, got %+v", response)
+		}
+
+		order := document.Components.Schemas["openAPIOrder"]
+		if order.Properties["items"].Items.Ref != "#/components/schemas/openAPIOrder" || order.Properties["labels"].AdditionalProperties.Type != "string" {
+			t.Errorf("Unexpected order schema %+v", order)
+		}
+	})
+
+	t.Run("GET inputs are query parameters and public routes have no security", func(t *testing.T) {
+		operation := document.Paths["/orders"]["get"]
+		if len(operation.Parameters) != 2 || operation.Parameters[0].In != "query" || operation.Security != nil {
+			t.Errorf("Unexpected list operation %+v", operation)
+		}
+		if list := document.Components.Schemas["openAPIOrderList"]; list == nil || list.Properties["orders"].Type != "array" {
+			t.Errorf("Expected the list component, got %+v", list)
+		}
+	})
+
+	t.Run("Dynamic routes are described by their rules", func(t *testing.T) {
+		operation := document.Paths["/notes"]["post"]
+		body := operation.RequestBody.Content["application/json"].Schema
+		if body.Properties["title"] == nil || *body.Properties["title"].MaxLength != 64 {
+			t.Errorf("Unexpected dynamic body %+v", body)
+		}
+		if len(operation.Security[0]) != 1 {
+			t.Errorf("Expected no CSRF requirement, got %v", operation.Security)
+		}
+	})
+
+	t.Run("The document is served", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, DefaultDocsSpecPath, nil))
+
+		var served OpenAPIDocument
+		if recorder.Code != http.StatusOK || json.Unmarshal(recorder.Body.Bytes(), &served) != nil || len(served.Paths) != 3 {
+			t.Errorf("Expected the document to be served, got %d: %s", recorder.Code, recorder.Body.String())
+		}
+	})
+}
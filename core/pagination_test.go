@@ -0,0 +1,97 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/errors"
+	"github.com/grzegorzmaniak/gothic/helpers"
+)
+
+type paginatedNotesInput struct {
+	helpers.PageParams
+}
+
+type paginatedNote struct {
+	Id    int    `json:"id"`
+	Title string `json:"title" validate:"required"`
+}
+
+func TestPaginatedRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	ctor := NewRouteConstructor(router, testBaseRoute{}, newMockSessionManager(t), nil)
+
+	notes := make([]paginatedNote, 5)
+	for i := range notes {
+		notes[i] = paginatedNote{Id: i, Title: "Note " + strconv.Itoa(i)}
+	}
+
+	GET(ctor, "/notes", PublicRoute(), func(input *paginatedNotesInput, _ *Handler[testBaseRoute]) (*helpers.PaginatedResponse[paginatedNote], *errors.AppError) {
+		start, _ := strconv.Atoi(input.Cursor)
+		end := min(start+input.PageLimit(), len(notes))
+		next := ""
+		if end < len(notes) {
+			next = strconv.Itoa(end)
+		}
+		return helpers.NewPage(notes[start:end], next).WithTotal(int64(len(notes))), nil
+	})
+	GET(ctor, "/broken", PublicRoute(), func(_ *paginatedNotesInput, _ *Handler[testBaseRoute]) (*helpers.PaginatedResponse[paginatedNote], *errors.AppError) {
+		return helpers.NewPage([]paginatedNote{{Id: 1}}, ""), nil
+	})
+
+	get := func(path string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		return recorder
+	}
+
+	t.Run("Pages link to the next one", func(t *testing.T) {
+		recorder := get("/notes?limit=2&cursor=2")
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", recorder.Code, recorder.Body.String())
+		}
+		expected := `{"items":[{"id":2,"title":"Note 2"},{"id":3,"title":"Note 3"}],"next_cursor":"4","total":5}`
+		if recorder.Body.String() != expected {
+			t.Errorf("Unexpected page %s", recorder.Body.String())
+		}
+		if link := recorder.Header().Get("Link"); link != `</notes?cursor=4&limit=2>; rel="next"` {
+			t.Errorf("Unexpected Link %q", link)
+		}
+		if total := recorder.Header().Get(helpers.TotalCountHeader); total != "5" {
+			t.Errorf("Expected a total of 5, got %q", total)
+		}
+	})
+
+	t.Run("The last page has no link", func(t *testing.T) {
+		recorder := get("/notes?cursor=4")
+		if recorder.Code != http.StatusOK || recorder.Header().Get("Link") != "" {
+			t.Errorf("Expected the last page without a Link, got %d %q", recorder.Code, recorder.Header().Get("Link"))
+		}
+	})
+
+	t.Run("Page parameters are validated", func(t *testing.T) {
+		for _, path := range []string{"/notes?limit=-1", "/notes?limit=500", "/notes?limit=abc"} {
+			if recorder := get(path); recorder.Code != http.StatusUnprocessableEntity {
+				t.Errorf("Expected 422 for %s, got %d", path, recorder.Code)
+			}
+		}
+	})
+
+	t.Run("Items are validated", func(t *testing.T) {
+		if recorder := get("/broken"); recorder.Code == http.StatusOK {
+			t.Errorf("Expected an invalid item to fail the output validation, got %d", recorder.Code)
+		}
+	})
+
+	t.Run("OpenAPI documents the headers", func(t *testing.T) {
+		document := GenerateOpenAPI(ctor, OpenAPIConfig{Title: "Notes"})
+		headers := document.Paths["/notes"]["get"].Responses["200"].Headers
+		if headers["Link"] == nil || headers[helpers.TotalCountHeader] == nil {
+			t.Errorf("Expected the pagination headers to be documented, got %v", headers)
+		}
+	})
+}
@@ -0,0 +1,66 @@
+package core
+
+import (
+	"fmt"
+	"runtime/debug"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/errors"
+	"github.com/grzegorzmaniak/gothic/helpers"
+	"go.uber.org/zap"
+)
+
+// PanicError is the underlying error of the 500 sent for a panicking route.
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("route panicked: %v", e.Value)
+}
+
+// panicAppError logs the panic with its stack, reports it to the SessionEventListener and returns the 500 sent
+// to the client. The request ID is the correlation ID, it is in the details and the RequestIDHeader.
+func panicAppError(ctx *gin.Context, sessionManager SessionManager, claims *SessionClaims, recovered interface{}, stack []byte) *errors.AppError {
+	requestID := helpers.GetRequestID(ctx)
+	helpers.Logger(ctx).Error("Route panicked",
+		zap.Any("panic", recovered),
+		zap.String("path", ctx.FullPath()),
+		zap.String("correlation_id", requestID),
+		zap.ByteString("stack", stack),
+	)
+	publishSessionEvent(ctx, sessionManager, SessionEventPanic, claims, fmt.Sprint(recovered))
+
+	return errors.NewInternalServerError("", &PanicError{Value: recovered, Stack: stack}, map[string]interface{}{
+		"correlation_id": requestID,
+	})
+}
+
+// recoverHandler turns a panic of handle into a 500, it covers handlers run on other goroutines, e.g., with a
+// HandlerTimeout.
+func recoverHandler(
+	ctx *gin.Context,
+	sessionManager SessionManager,
+	claims *SessionClaims,
+	handle func() (*routeResponse, *errors.AppError),
+) func() (*routeResponse, *errors.AppError) {
+	return func() (response *routeResponse, appErr *errors.AppError) {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				response, appErr = nil, panicAppError(ctx, sessionManager, claims, recovered, debug.Stack())
+			}
+		}()
+		return handle()
+	}
+}
+
+// respondToPanic sends the 500 of a panic in the stages around the handler, e.g., a Policy or TenantResolver.
+// Nothing is sent when the response was already started.
+func respondToPanic(ctx *gin.Context, sessionManager SessionManager, claims *SessionClaims, recovered interface{}) {
+	appErr := panicAppError(ctx, sessionManager, claims, recovered, debug.Stack())
+	if ctx.Writer.Written() {
+		return
+	}
+	helpers.ErrorResponse(ctx, appErr)
+}
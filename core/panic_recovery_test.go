@@ -0,0 +1,127 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/errors"
+	"github.com/grzegorzmaniak/gothic/helpers"
+	"github.com/grzegorzmaniak/gothic/rbac"
+	"github.com/grzegorzmaniak/gothic/validation"
+)
+
+// panicListener forwards panic events to a channel.
+type panicListener struct {
+	BaseSessionEventListener
+	events chan SessionEvent
+}
+
+func (l *panicListener) OnPanic(_ context.Context, event SessionEvent) { l.events <- event }
+
+func TestPanicRecovery(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	listener := &panicListener{events: make(chan SessionEvent, 8)}
+	SetSessionEventListener(listener)
+	defer SetSessionEventListener(nil)
+
+	mgr := newMockSessionManager(t)
+	panicking := func(_ *struct{}, _ *Handler[testBaseRoute]) (*struct{}, *errors.AppError) {
+		panic("boom")
+	}
+
+	router := gin.New()
+	ctor := NewRouteConstructor(router, testBaseRoute{}, mgr, nil)
+	GET(ctor, "/handler", PublicRoute(), panicking)
+	GET(ctor, "/timeout", PublicRoute().WithHandlerTimeout(time.Second), panicking)
+	GET(ctor, "/policy", PublicRoute().WithPolicy(func(*gin.Context, *SessionClaims, interface{}) (*rbac.AttributeDecision, error) {
+		panic("policy boom")
+	}), func(_ *struct{}, _ *Handler[testBaseRoute]) (*struct{}, *errors.AppError) {
+		return &struct{}{}, nil
+	})
+	GET(ctor, "/started", PublicRoute().WithManualResponse(), func(_ *struct{}, data *Handler[testBaseRoute]) (*struct{}, *errors.AppError) {
+		data.Context.String(http.StatusOK, "partial")
+		panic("late boom")
+	})
+
+	panickingHook := PublicRoute().WithHooks(Hooks{PreSession: []Hook{func(*HookContext) *errors.AppError {
+		panic("hook boom")
+	}}})
+	GET(ctor, "/hook", panickingHook, func(_ *struct{}, _ *Handler[testBaseRoute]) (*struct{}, *errors.AppError) {
+		return &struct{}{}, nil
+	})
+	DYNAMIC(ctor, http.MethodGet, "/dynamic-hook", panickingHook, validation.FieldRules{}, validation.FieldRules{},
+		func(_ map[string]interface{}, _ *Handler[testBaseRoute]) (map[string]any, *errors.AppError) {
+			return map[string]any{}, nil
+		})
+
+	// - The session verifier panics while the session is established
+	verified, err := NewSessionManagerBuilder().
+		WithKey("k1", newBuilderKey(t)).
+		WithSessionVerifier(func(context.Context, *SessionClaims, *SessionHeader) (bool, error) {
+			panic("verifier boom")
+		}).
+		Build()
+	if err != nil {
+		t.Fatalf("Expected Build to succeed, got %v", err)
+	}
+	defer verified.Close()
+	verifiedRouter := gin.New()
+	GET(NewRouteConstructor(verifiedRouter, testBaseRoute{}, verified, nil), "/verified/session", AuthenticatedJSONAPI(), func(_ *struct{}, _ *Handler[testBaseRoute]) (*struct{}, *errors.AppError) {
+		return &struct{}{}, nil
+	})
+	issueCtx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	issueCtx.Request = httptest.NewRequest(http.MethodPost, "/login", nil)
+	token, err := IssueBearerToken(issueCtx, verified, "default", &SessionClaims{Claims: map[string]string{DefaultBuilderSubjectClaim: "user-1"}})
+	if err != nil {
+		t.Fatalf("Failed to issue the bearer token: %v", err)
+	}
+
+	request := func(path string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if strings.HasPrefix(path, "/verified") {
+			req.Header.Set(DefaultSessionAuthorizationHeaderName, token)
+			verifiedRouter.ServeHTTP(recorder, req)
+		} else {
+			router.ServeHTTP(recorder, req)
+		}
+		return recorder
+	}
+
+	for _, path := range []string{"/handler", "/timeout", "/policy", "/hook", "/dynamic-hook", "/verified/session"} {
+		recorder := request(path)
+		if recorder.Code != http.StatusInternalServerError {
+			t.Fatalf("%s: expected a 500, got %d", path, recorder.Code)
+		}
+
+		var body struct {
+			Details map[string]string `json:"details"`
+		}
+		if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil {
+			t.Fatalf("%s: expected a GoThic error response, got %s", path, recorder.Body.String())
+		}
+		requestID := recorder.Header().Get(helpers.RequestIDHeader)
+		if requestID == "" || body.Details["correlation_id"] != requestID {
+			t.Errorf("%s: expected the request ID as correlation ID, got '%s' and '%s'", path, body.Details["correlation_id"], requestID)
+		}
+
+		select {
+		case event := <-listener.events:
+			if event.Type != SessionEventPanic || event.RequestID != requestID || event.Path != path {
+				t.Errorf("%s: unexpected panic event %+v", path, event)
+			}
+		case <-time.After(time.Second):
+			t.Errorf("%s: expected a panic event", path)
+		}
+	}
+
+	if recorder := request("/started"); recorder.Code != http.StatusOK || recorder.Body.String() != "partial" {
+		t.Errorf("Expected a started response to be left alone, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+}
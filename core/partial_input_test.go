@@ -0,0 +1,84 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/errors"
+	"github.com/grzegorzmaniak/gothic/validation"
+)
+
+type partialProfileInput struct {
+	Name validation.Optional[string] `json:"name" validate:"omitempty,min=3"`
+}
+
+type partialProfileOutput struct {
+	Name    string `json:"name"`
+	Changed bool   `json:"changed"`
+}
+
+func TestPartialInput(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	ctor := NewRouteConstructor(router, testBaseRoute{}, newMockSessionManager(t), nil)
+
+	PATCH(ctor, "/profile", PublicRoute(), func(input *partialProfileInput, _ *Handler[testBaseRoute]) (*partialProfileOutput, *errors.AppError) {
+		name, changed := input.Name.Get()
+		return &partialProfileOutput{Name: name, Changed: changed}, nil
+	})
+	DYNAMIC(ctor, http.MethodPatch, "/settings", PublicRoute().WithPartialInput(),
+		validation.FieldRules{"Theme": {Tags: "omitempty,oneof=light dark"}, "Beta": {Type: "bool"}},
+		validation.FieldRules{"Fields": {Type: "[]string"}},
+		func(input map[string]interface{}, _ *Handler[testBaseRoute]) (map[string]any, *errors.AppError) {
+			fields := make([]string, 0, len(input))
+			for name := range input {
+				fields = append(fields, name)
+			}
+			return map[string]any{"Fields": fields}, nil
+		},
+	)
+
+	patch := func(path string, body string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodPatch, path, strings.NewReader(body))
+		request.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	t.Run("Typed Optional fields", func(t *testing.T) {
+		if recorder := patch("/profile", `{}`); recorder.Code != http.StatusOK || !strings.Contains(recorder.Body.String(), `"changed":false`) {
+			t.Errorf("Expected an unchanged name, got %d: %s", recorder.Code, recorder.Body.String())
+		}
+		if recorder := patch("/profile", `{"name":"Alice"}`); !strings.Contains(recorder.Body.String(), `"changed":true`) {
+			t.Errorf("Expected a changed name, got %s", recorder.Body.String())
+		}
+		if recorder := patch("/profile", `{"name":"Al"}`); recorder.Code != http.StatusUnprocessableEntity {
+			t.Errorf("Expected 422, got %d", recorder.Code)
+		}
+	})
+
+	t.Run("Dynamic partial input", func(t *testing.T) {
+		recorder := patch("/settings", `{"beta":false}`)
+		if recorder.Code != http.StatusOK || recorder.Body.String() != `{"fields":["Beta"]}` {
+			t.Errorf("Expected only the present field, got %d: %s", recorder.Code, recorder.Body.String())
+		}
+		if recorder := patch("/settings", `{"theme":"blue"}`); recorder.Code != http.StatusUnprocessableEntity {
+			t.Errorf("Expected 422, got %d", recorder.Code)
+		}
+	})
+
+	t.Run("OpenAPI documents the wrapped type", func(t *testing.T) {
+		document := GenerateOpenAPI(ctor, OpenAPIConfig{Title: "Profile"})
+		schema := document.Paths["/profile"]["patch"].RequestBody.Content["application/json"].Schema
+		if schema.Ref != "" {
+			schema = document.Components.Schemas[strings.TrimPrefix(schema.Ref, "#/components/schemas/")]
+		}
+		if name := schema.Properties["name"]; name == nil || name.Type != "string" {
+			t.Errorf("Expected name to be a string, got %+v", schema.Properties["name"])
+		}
+	})
+}
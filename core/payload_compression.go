@@ -0,0 +1,119 @@
+package core
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// PayloadCompression selects how the claims are compressed before encryption.
+type PayloadCompression int
+
+const (
+	// PayloadCompressionNone stores the claims as base64 JSON (Default)
+	PayloadCompressionNone PayloadCompression = iota
+
+	// PayloadCompressionDeflate uses raw DEFLATE, the smallest option for cookies.
+	PayloadCompressionDeflate
+
+	// PayloadCompressionGzip uses gzip, which adds a header and checksum on top of DEFLATE.
+	PayloadCompressionGzip
+)
+
+const (
+	// Compressed payloads start with a marker that is not part of the base64url alphabet, so payloads written
+	// before compression was enabled keep decoding.
+	deflatePayloadMarker = "~d"
+	gzipPayloadMarker    = "~g"
+
+	// MaximumDecompressedClaimsSize bounds the decompressed claims.
+	MaximumDecompressedClaimsSize = 64 * 1024
+)
+
+// ErrAuthorizationTooLarge is returned by CreateAuthorization when the token would exceed MaxAuthorizationSize,
+// browsers silently drop cookies above 4KB.
+var ErrAuthorizationTooLarge = errors.New("authorization token is too large")
+
+// EncodeCompressedPayload encodes the claims like EncodePayload, compressing them first. The uncompressed
+// payload is used when compression does not make it smaller.
+func (d *SessionClaims) EncodeCompressedPayload(compression PayloadCompression) (string, error) {
+	if compression == PayloadCompressionNone {
+		return d.EncodePayload()
+	}
+
+	jsonBytes, err := json.Marshal(d.Claims)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal claims: %w", err)
+	}
+	plain := base64.RawURLEncoding.EncodeToString(jsonBytes)
+
+	var (
+		buffer bytes.Buffer
+		writer io.WriteCloser
+		marker string
+	)
+	switch compression {
+	case PayloadCompressionDeflate:
+		writer, err = flate.NewWriter(&buffer, flate.BestCompression)
+		marker = deflatePayloadMarker
+	case PayloadCompressionGzip:
+		writer, err = gzip.NewWriterLevel(&buffer, gzip.BestCompression)
+		marker = gzipPayloadMarker
+	default:
+		return "", fmt.Errorf("unknown payload compression %d", compression)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to create compressor: %w", err)
+	}
+
+	if _, err = writer.Write(jsonBytes); err != nil {
+		return "", fmt.Errorf("failed to compress claims: %w", err)
+	}
+	if err = writer.Close(); err != nil {
+		return "", fmt.Errorf("failed to compress claims: %w", err)
+	}
+
+	compressed := marker + base64.RawURLEncoding.EncodeToString(buffer.Bytes())
+	if len(compressed) >= len(plain) {
+		return plain, nil
+	}
+	return compressed, nil
+}
+
+// decompressPayload returns the JSON of a compressed payload, ok is false for uncompressed payloads.
+func decompressPayload(payload []byte) (jsonBytes []byte, ok bool, err error) {
+	var newReader func(io.Reader) (io.ReadCloser, error)
+	switch {
+	case bytes.HasPrefix(payload, stringBytes(deflatePayloadMarker)):
+		newReader = func(r io.Reader) (io.ReadCloser, error) { return flate.NewReader(r), nil }
+	case bytes.HasPrefix(payload, stringBytes(gzipPayloadMarker)):
+		newReader = func(r io.Reader) (io.ReadCloser, error) { return gzip.NewReader(r) }
+	default:
+		return nil, false, nil
+	}
+
+	decoded, err := decodeBase64Into(nil, payload[len(deflatePayloadMarker):])
+	if err != nil {
+		return nil, true, fmt.Errorf("failed to decode payload: %w", err)
+	}
+
+	reader, err := newReader(bytes.NewReader(decoded))
+	if err != nil {
+		return nil, true, fmt.Errorf("failed to open compressed payload: %w", err)
+	}
+	defer reader.Close()
+
+	jsonBytes, err = io.ReadAll(io.LimitReader(reader, MaximumDecompressedClaimsSize+1))
+	if err != nil {
+		return nil, true, fmt.Errorf("failed to decompress payload: %w", err)
+	}
+	if len(jsonBytes) > MaximumDecompressedClaimsSize {
+		return nil, true, fmt.Errorf("decompressed payload exceeds %d bytes", MaximumDecompressedClaimsSize)
+	}
+	return jsonBytes, true, nil
+}
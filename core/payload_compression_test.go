@@ -0,0 +1,89 @@
+package core
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEncodeCompressedPayload(t *testing.T) {
+	large := &SessionClaims{Claims: map[string]string{
+		"permissions": strings.Repeat("billing.invoices.read,", 40),
+		"tenant":      "acme",
+	}}
+	plain, err := large.EncodePayload()
+	if err != nil {
+		t.Fatalf("Failed to encode payload: %v", err)
+	}
+
+	for _, tt := range []struct {
+		name        string
+		compression PayloadCompression
+		marker      string
+	}{
+		{name: "Deflate", compression: PayloadCompressionDeflate, marker: deflatePayloadMarker},
+		{name: "Gzip", compression: PayloadCompressionGzip, marker: gzipPayloadMarker},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			payload, err := large.EncodeCompressedPayload(tt.compression)
+			if err != nil {
+				t.Fatalf("Failed to encode payload: %v", err)
+			}
+			if !strings.HasPrefix(payload, tt.marker) || len(payload) >= len(plain) {
+				t.Fatalf("Expected a smaller %s payload, got %d bytes vs %d", tt.marker, len(payload), len(plain))
+			}
+
+			decoded := &SessionClaims{}
+			if err = decoded.DecodePayload(payload); err != nil {
+				t.Fatalf("Failed to decode payload: %v", err)
+			}
+			if decoded.Claims["permissions"] != large.Claims["permissions"] {
+				t.Error("Decoded claims do not match")
+			}
+		})
+	}
+
+	t.Run("Small payloads stay uncompressed", func(t *testing.T) {
+		small := &SessionClaims{Claims: map[string]string{"a": "b"}}
+		payload, _ := small.EncodeCompressedPayload(PayloadCompressionDeflate)
+		if expected, _ := small.EncodePayload(); payload != expected {
+			t.Errorf("Expected the plain payload %q, got %q", expected, payload)
+		}
+	})
+
+	t.Run("Corrupt compressed payloads are rejected", func(t *testing.T) {
+		if err := (&SessionClaims{}).DecodePayload(deflatePayloadMarker + "AAAA"); err == nil {
+			t.Error("Expected an error for a corrupt payload")
+		}
+	})
+}
+
+func TestCreateAuthorization_SizeBudget(t *testing.T) {
+	mgr := newMockSessionManager(t)
+	header := NewSessionHeader(false, time.Hour, time.Minute)
+	claims := func() *SessionClaims {
+		return &SessionClaims{Claims: map[string]string{"permissions": strings.Repeat("billing.invoices.read,", 200)}}
+	}
+
+	_, err := CreateAuthorization("user", &header, *mgr.authorizationData, claims(), mgr)
+	if !errors.Is(err, ErrAuthorizationTooLarge) {
+		t.Fatalf("Expected ErrAuthorizationTooLarge, got %v", err)
+	}
+
+	config := *mgr.authorizationData
+	config.PayloadCompression = PayloadCompressionDeflate
+	token, err := CreateAuthorization("user", &header, config, claims(), mgr)
+	if err != nil {
+		t.Fatalf("Expected compression to fit the budget, got %v", err)
+	}
+
+	_, payload, err := extractSessionAuthorizationParts(&config, mgr, token)
+	if err != nil {
+		t.Fatalf("Failed to extract authorization: %v", err)
+	}
+	decoded := &SessionClaims{}
+	if err = decoded.DecodePayload(payload); err != nil || decoded.Claims["permissions"] != claims().Claims["permissions"] {
+		t.Errorf("Expected the compressed claims to round trip, got %v", err)
+	}
+}
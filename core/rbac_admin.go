@@ -0,0 +1,168 @@
+package core
+
+import (
+	stderrors "errors"
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/errors"
+	"github.com/grzegorzmaniak/gothic/helpers"
+	"github.com/grzegorzmaniak/gothic/rbac"
+	"go.uber.org/zap"
+)
+
+const (
+	RbacAdminAssignRole       = "assign_role"
+	RbacAdminRevokeRole       = "revoke_role"
+	RbacAdminGrantPermission  = "grant_permission"
+	RbacAdminRevokePermission = "revoke_permission"
+)
+
+// RbacAdminConfiguration configures RegisterRbacAdminRoutes.
+type RbacAdminConfiguration struct {
+	// Permission guards the admin routes (Required unless NamedPermission is set)
+	Permission *rbac.Permission
+
+	// NamedPermission guards the admin routes, e.g., "rbac.admin" (Required unless Permission is set)
+	NamedPermission string
+
+	// Registry resolves the permission names in the paths to bits (Required)
+	Registry *rbac.PermissionRegistry
+}
+
+// RbacAdminRoleInput is the input of the role admin routes.
+type RbacAdminRoleInput struct {
+	Subject string `uri:"subject" validate:"required"`
+	Role    string `uri:"role" validate:"required"`
+}
+
+// RbacAdminSubjectPermissionInput is the input of the subject permission admin routes.
+type RbacAdminSubjectPermissionInput struct {
+	Subject    string `uri:"subject" validate:"required"`
+	Permission string `uri:"permission" validate:"required"`
+}
+
+// RbacAdminRolePermissionInput is the input of the role permission admin routes.
+type RbacAdminRolePermissionInput struct {
+	Role       string `uri:"role" validate:"required"`
+	Permission string `uri:"permission" validate:"required"`
+}
+
+// RbacAdminChange is the response of the admin routes, describing the change that was made.
+type RbacAdminChange struct {
+	Action     string `json:"action"`
+	Subject    string `json:"subject,omitempty"`
+	Role       string `json:"role,omitempty"`
+	Permission string `json:"permission,omitempty"`
+}
+
+// routeConfig returns the configuration of the admin routes, sessions need the admin permission and a CSRF token.
+func (config RbacAdminConfiguration) routeConfig() (*APIConfiguration, error) {
+	if config.Registry == nil {
+		return nil, fmt.Errorf("rbac admin routes need a permission registry")
+	}
+
+	routeConfig := AuthenticatedJSONAPI().WithRbacPolicy(rbac.PermissionsOnly)
+	if config.Permission != nil {
+		routeConfig.WithPermissions(config.Permission)
+	}
+	if config.NamedPermission != "" {
+		routeConfig.WithNamedPermissions(config.NamedPermission)
+	}
+	if config.Permission == nil && config.NamedPermission == "" {
+		return nil, fmt.Errorf("rbac admin routes need a Permission or NamedPermission guarding them")
+	}
+	if _, err := routeConfig.GetFlatNamedPermissions(); err != nil {
+		return nil, fmt.Errorf("invalid rbac admin permission: %w", err)
+	}
+	return routeConfig, nil
+}
+
+// applyRbacAdminChange runs a change, converting its error into the response.
+func applyRbacAdminChange(ctx *gin.Context, change func() error) *errors.AppError {
+	err := change()
+	if err == nil {
+		return nil
+	}
+
+	helpers.Logger(ctx).Debug("Error changing roles or permissions", zap.Error(err))
+	if stderrors.Is(err, rbac.ErrAdminUnsupported) {
+		return errors.NewInternalServerError("Roles and permissions cannot be changed", err)
+	}
+	return errors.NewInternalServerError("Failed to change roles or permissions", err)
+}
+
+// RegisterRbacAdminRoutes registers ready-made routes changing roles and permissions through rbac.ManagerAdmin,
+// guarded by the configured admin permission. Every change invalidates the affected cache entries and is scoped
+// to the request's tenant:
+//
+//	POST / DELETE <basePath>/subjects/:subject/roles/:role
+//	POST / DELETE <basePath>/subjects/:subject/permissions/:permission
+//	POST / DELETE <basePath>/roles/:role/permissions/:permission
+//
+// Permissions are given by their registered name, e.g., "billing.invoices.read". Nothing is registered if the
+// configuration has no guard or no registry.
+func RegisterRbacAdminRoutes[BaseRoute helpers.BaseRouteComponents](
+	ctor *RouteConstructor[BaseRoute],
+	basePath string,
+	config RbacAdminConfiguration,
+) error {
+	routeConfig, err := config.routeConfig()
+	if err != nil {
+		return err
+	}
+	basePath = strings.TrimSuffix(basePath, "/")
+
+	roleChange := func(action string) func(*RbacAdminRoleInput, *Handler[BaseRoute]) (*RbacAdminChange, *errors.AppError) {
+		return func(input *RbacAdminRoleInput, data *Handler[BaseRoute]) (*RbacAdminChange, *errors.AppError) {
+			change := func() error {
+				if action == RbacAdminAssignRole {
+					return rbac.AssignRole(rbacContext(data.Context), data.SessionManager.GetRbacManager(), input.Subject, input.Role)
+				}
+				return rbac.RevokeRole(rbacContext(data.Context), data.SessionManager.GetRbacManager(), input.Subject, input.Role)
+			}
+			if appErr := applyRbacAdminChange(data.Context, change); appErr != nil {
+				return nil, appErr
+			}
+			return &RbacAdminChange{Action: action, Subject: input.Subject, Role: input.Role}, nil
+		}
+	}
+
+	permissionChange := func(action string, target rbac.PermissionTarget, name string, data *Handler[BaseRoute]) (*RbacAdminChange, *errors.AppError) {
+		permission, err := config.Registry.Resolve(name)
+		if err != nil {
+			return nil, errors.NewBadRequest("Unknown permission", err)
+		}
+		change := func() error {
+			if action == RbacAdminGrantPermission {
+				return rbac.GrantPermission(rbacContext(data.Context), data.SessionManager.GetRbacManager(), target, permission)
+			}
+			return rbac.RevokePermission(rbacContext(data.Context), data.SessionManager.GetRbacManager(), target, permission)
+		}
+		if appErr := applyRbacAdminChange(data.Context, change); appErr != nil {
+			return nil, appErr
+		}
+		return &RbacAdminChange{Action: action, Subject: target.Subject, Role: target.Role, Permission: name}, nil
+	}
+
+	subjectPermission := func(action string) func(*RbacAdminSubjectPermissionInput, *Handler[BaseRoute]) (*RbacAdminChange, *errors.AppError) {
+		return func(input *RbacAdminSubjectPermissionInput, data *Handler[BaseRoute]) (*RbacAdminChange, *errors.AppError) {
+			return permissionChange(action, rbac.SubjectTarget(input.Subject), input.Permission, data)
+		}
+	}
+
+	rolePermission := func(action string) func(*RbacAdminRolePermissionInput, *Handler[BaseRoute]) (*RbacAdminChange, *errors.AppError) {
+		return func(input *RbacAdminRolePermissionInput, data *Handler[BaseRoute]) (*RbacAdminChange, *errors.AppError) {
+			return permissionChange(action, rbac.RoleTarget(input.Role), input.Permission, data)
+		}
+	}
+
+	POST(ctor, basePath+"/subjects/:subject/roles/:role", routeConfig, roleChange(RbacAdminAssignRole))
+	DELETE(ctor, basePath+"/subjects/:subject/roles/:role", routeConfig, roleChange(RbacAdminRevokeRole))
+	POST(ctor, basePath+"/subjects/:subject/permissions/:permission", routeConfig, subjectPermission(RbacAdminGrantPermission))
+	DELETE(ctor, basePath+"/subjects/:subject/permissions/:permission", routeConfig, subjectPermission(RbacAdminRevokePermission))
+	POST(ctor, basePath+"/roles/:role/permissions/:permission", routeConfig, rolePermission(RbacAdminGrantPermission))
+	DELETE(ctor, basePath+"/roles/:role/permissions/:permission", routeConfig, rolePermission(RbacAdminRevokePermission))
+	return nil
+}
@@ -0,0 +1,140 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	internalcache "github.com/grzegorzmaniak/gothic/cache"
+	"github.com/grzegorzmaniak/gothic/rbac"
+)
+
+// adminRbacManager grants named permissions like namedRbacManager and records the changes made through it.
+type adminRbacManager struct {
+	namedRbacManager
+	changes []string
+}
+
+func (m *adminRbacManager) AssignRole(_ context.Context, subjectIdentifier string, roleIdentifier string) error {
+	m.changes = append(m.changes, "assign "+roleIdentifier+" to "+subjectIdentifier)
+	return nil
+}
+
+func (m *adminRbacManager) RevokeRole(_ context.Context, subjectIdentifier string, roleIdentifier string) error {
+	m.changes = append(m.changes, "revoke "+roleIdentifier+" from "+subjectIdentifier)
+	return nil
+}
+
+func (m *adminRbacManager) GrantPermission(_ context.Context, target rbac.PermissionTarget, permission *rbac.Permission) error {
+	m.changes = append(m.changes, "grant "+permission.Serialize()+" to "+target.Subject+target.Role)
+	return nil
+}
+
+func (m *adminRbacManager) RevokePermission(_ context.Context, target rbac.PermissionTarget, permission *rbac.Permission) error {
+	m.changes = append(m.changes, "revoke "+permission.Serialize()+" from "+target.Subject+target.Role)
+	return nil
+}
+
+func TestRegisterRbacAdminRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mgr := newMockSessionManager(t)
+	manager := &adminRbacManager{namedRbacManager: namedRbacManager{
+		cacheManager: internalcache.BuildDefaultCacheManager(nil),
+		named:        map[string]rbac.PermissionSet{"root": rbac.MustPermissionSet("rbac.admin")},
+	}}
+	mgr.rbacManager = manager
+
+	registry := rbac.NewPermissionRegistry()
+	articlesWrite := registry.MustRegister("articles.write", 3)
+
+	router := gin.New()
+	ctor := NewRouteConstructor(router, testBaseRoute{}, mgr, nil)
+	if err := RegisterRbacAdminRoutes(ctor, "/admin/rbac/", RbacAdminConfiguration{NamedPermission: "rbac.admin", Registry: registry}); err != nil {
+		t.Fatalf("RegisterRbacAdminRoutes() error = %v", err)
+	}
+
+	request := func(method, path, subject string) *httptest.ResponseRecorder {
+		ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+		ctx.Request = httptest.NewRequest(http.MethodPost, "/login", nil)
+		token, err := IssueBearerToken(ctx, mgr, "default", &SessionClaims{Claims: map[string]string{"subject": subject}})
+		if err != nil {
+			t.Fatalf("Failed to issue the bearer token: %v", err)
+		}
+
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set(DefaultSessionAuthorizationHeaderName, token)
+		router.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	t.Run("Applies the changes", func(t *testing.T) {
+		manager.changes = nil
+		for _, tt := range []struct {
+			method string
+			path   string
+			action string
+		}{
+			{http.MethodPost, "/admin/rbac/subjects/alice/roles/editor", RbacAdminAssignRole},
+			{http.MethodDelete, "/admin/rbac/subjects/alice/roles/editor", RbacAdminRevokeRole},
+			{http.MethodPost, "/admin/rbac/subjects/alice/permissions/articles.write", RbacAdminGrantPermission},
+			{http.MethodDelete, "/admin/rbac/roles/editor/permissions/articles.write", RbacAdminRevokePermission},
+		} {
+			recorder := request(tt.method, tt.path, "root")
+			if recorder.Code != http.StatusOK {
+				t.Fatalf("%s %s: expected 200, got %d: %s", tt.method, tt.path, recorder.Code, recorder.Body.String())
+			}
+			var change RbacAdminChange
+			if err := json.Unmarshal(recorder.Body.Bytes(), &change); err != nil {
+				t.Fatalf("Failed to decode the response: %v", err)
+			}
+			if change.Action != tt.action {
+				t.Errorf("%s %s: expected action %q, got %q", tt.method, tt.path, tt.action, change.Action)
+			}
+		}
+
+		expected := []string{
+			"assign editor to alice",
+			"revoke editor from alice",
+			"grant " + articlesWrite.Serialize() + " to alice",
+			"revoke " + articlesWrite.Serialize() + " from editor",
+		}
+		if len(manager.changes) != len(expected) {
+			t.Fatalf("Expected changes %v, got %v", expected, manager.changes)
+		}
+		for i := range expected {
+			if manager.changes[i] != expected[i] {
+				t.Errorf("Expected change %q, got %q", expected[i], manager.changes[i])
+			}
+		}
+	})
+
+	t.Run("Requires the admin permission", func(t *testing.T) {
+		manager.changes = nil
+		if recorder := request(http.MethodPost, "/admin/rbac/subjects/alice/roles/editor", "alice"); recorder.Code != http.StatusUnauthorized {
+			t.Errorf("Expected 401, got %d", recorder.Code)
+		}
+		if len(manager.changes) != 0 {
+			t.Errorf("Expected no changes, got %v", manager.changes)
+		}
+	})
+
+	t.Run("Rejects unknown permissions", func(t *testing.T) {
+		if recorder := request(http.MethodPost, "/admin/rbac/roles/editor/permissions/articles.publish", "root"); recorder.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400, got %d", recorder.Code)
+		}
+	})
+
+	t.Run("Needs a guard and a registry", func(t *testing.T) {
+		ctor := NewRouteConstructor(gin.New(), testBaseRoute{}, mgr, nil)
+		if err := RegisterRbacAdminRoutes(ctor, "/admin", RbacAdminConfiguration{Registry: registry}); err == nil {
+			t.Error("Expected an unguarded configuration to be rejected")
+		}
+		if err := RegisterRbacAdminRoutes(ctor, "/admin", RbacAdminConfiguration{NamedPermission: "rbac.admin"}); err == nil {
+			t.Error("Expected a configuration without a registry to be rejected")
+		}
+	})
+}
@@ -0,0 +1,60 @@
+package core
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/errors"
+	"github.com/grzegorzmaniak/gothic/helpers"
+	"github.com/grzegorzmaniak/gothic/rbac"
+	"go.uber.org/zap"
+)
+
+// DecisionContext describes an RBAC denial to the route's OnDenied callback, e.g., to answer with the plan that
+// unlocks the missing permissions.
+type DecisionContext struct {
+	Subject string
+	Claims  *SessionClaims
+
+	// APIKey is the key the request acted with, or nil for sessions.
+	APIKey *APIKey
+
+	// Requirements are the route's RBAC requirements that were not met.
+	Requirements rbac.AccessRequirements
+
+	// Decision is the trace of the denied check, see rbac.ExplainAccess. It is nil for API keys, or when the
+	// trace could not be built.
+	Decision *rbac.Decision
+
+	// MissingPermissions, MissingNamedPermissions and MissingRoles are the ones of Decision, empty without it.
+	MissingPermissions      *rbac.Permission
+	MissingNamedPermissions []string
+	MissingRoles            []string
+}
+
+// DeniedFunc returns the error sent when the route's RBAC check denies a request, nil sends the default 401
+// Insufficient permissions error. The request is denied either way, the handler never runs.
+type DeniedFunc func(ctx *gin.Context, denial *DecisionContext) *errors.AppError
+
+// deniedError returns the error of an RBAC denial, the one of the route's OnDenied callback when it returns one.
+// The decision trace is only built for the callback, it costs more fetches than the check itself.
+func deniedError(ctx *gin.Context, rbacManager rbac.Manager, sessionConfig *APIConfiguration, denial *DecisionContext, rbacCacheId string) *errors.AppError {
+	if sessionConfig.OnDenied == nil {
+		return newInsufficientPermissionsError(sessionConfig)
+	}
+
+	if denial.APIKey == nil {
+		decision, err := rbac.ExplainAccess(rbacContext(ctx), rbacManager, denial.Subject, rbacCacheId, denial.Requirements)
+		if err != nil {
+			helpers.Logger(ctx).Debug("Failed to explain the RBAC denial", zap.String("subject", denial.Subject), zap.Error(err))
+		} else {
+			denial.Decision = decision
+			denial.MissingPermissions = decision.MissingPermissions
+			denial.MissingNamedPermissions = decision.MissingNamedPermissions
+			denial.MissingRoles = decision.MissingRoles
+		}
+	}
+
+	if appErr := sessionConfig.OnDenied(ctx, denial); appErr != nil {
+		return appErr
+	}
+	return newInsufficientPermissionsError(sessionConfig)
+}
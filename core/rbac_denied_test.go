@@ -0,0 +1,86 @@
+package core
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	internalcache "github.com/grzegorzmaniak/gothic/cache"
+	"github.com/grzegorzmaniak/gothic/errors"
+	"github.com/grzegorzmaniak/gothic/rbac"
+)
+
+func TestOnDenied(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mgr := newMockSessionManager(t)
+	mgr.rbacManager = &namedRbacManager{
+		cacheManager: internalcache.BuildDefaultCacheManager(nil),
+		named:        map[string]rbac.PermissionSet{"viewer-1": rbac.MustPermissionSet("reports.read")},
+	}
+
+	var denials []*DecisionContext
+	upsell := func(_ *gin.Context, denial *DecisionContext) *errors.AppError {
+		denials = append(denials, denial)
+		return errors.NewAppError(http.StatusPaymentRequired, "Upgrade to export reports", nil, map[string]interface{}{
+			"missing": denial.MissingNamedPermissions,
+		})
+	}
+	fallback := func(_ *gin.Context, denial *DecisionContext) *errors.AppError {
+		denials = append(denials, denial)
+		return nil
+	}
+
+	router := gin.New()
+	ctor := NewRouteConstructor(router, testBaseRoute{}, mgr, nil)
+	handled := 0
+	handler := func(_ *struct{}, _ *Handler[testBaseRoute]) (*benchmarkOutput, *errors.AppError) {
+		handled++
+		return &benchmarkOutput{Message: "ok"}, nil
+	}
+	route := func(permission string) *APIConfiguration {
+		return AuthenticatedJSONAPI().WithoutCsrf().WithNamedPermissions(permission).WithRbacPolicy(rbac.PermissionsOnly)
+	}
+	POST(ctor, "/reports/export", route("reports.export").WithOnDenied(upsell), handler)
+	POST(ctor, "/reports/share", route("reports.share").WithOnDenied(fallback), handler)
+	POST(ctor, "/reports/view", route("reports.read").WithOnDenied(upsell), handler)
+
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/login", nil)
+	token, err := IssueBearerToken(ctx, mgr, "default", &SessionClaims{Claims: map[string]string{"subject": "viewer-1"}})
+	if err != nil {
+		t.Fatalf("Failed to issue the bearer token: %v", err)
+	}
+	request := func(path string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		req.Header.Set(DefaultSessionAuthorizationHeaderName, token)
+		router.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	recorder := request("/reports/export")
+	if recorder.Code != http.StatusPaymentRequired {
+		t.Fatalf("Expected the callback's error, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	var body struct {
+		Details map[string][]string `json:"details"`
+	}
+	if err = json.Unmarshal(recorder.Body.Bytes(), &body); err != nil || len(body.Details["missing"]) != 1 || body.Details["missing"][0] != "reports.export" {
+		t.Errorf("Expected the missing permission in the details, got %s", recorder.Body.String())
+	}
+	if len(denials) != 1 || denials[0].Subject != "viewer-1" || denials[0].Decision == nil || denials[0].Decision.Reason != rbac.DecisionMissingNamedPermissions {
+		t.Fatalf("Expected the callback to get the decision, got %+v", denials)
+	}
+
+	if recorder = request("/reports/share"); recorder.Code != http.StatusUnauthorized {
+		t.Errorf("Expected the default error when the callback returns nil, got %d", recorder.Code)
+	}
+	if recorder = request("/reports/view"); recorder.Code != http.StatusOK || len(denials) != 2 {
+		t.Errorf("Expected allowed requests not to call the callback, got %d after %d denials", recorder.Code, len(denials))
+	}
+	if handled != 1 {
+		t.Errorf("Expected denied requests never to reach the handler, got %d calls", handled)
+	}
+}
@@ -0,0 +1,75 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	internalcache "github.com/grzegorzmaniak/gothic/cache"
+	"github.com/grzegorzmaniak/gothic/errors"
+	"github.com/grzegorzmaniak/gothic/rbac"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestRbacDryRun(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mgr := newMockSessionManager(t)
+	mgr.rbacManager = &namedRbacManager{
+		cacheManager: internalcache.BuildDefaultCacheManager(nil),
+		named:        map[string]rbac.PermissionSet{"editor-1": rbac.MustPermissionSet("articles.read")},
+	}
+
+	core, logs := observer.New(zap.WarnLevel)
+	defer zap.ReplaceGlobals(zap.New(core))()
+
+	router := gin.New()
+	ctor := NewRouteConstructor(router, testBaseRoute{}, mgr, nil)
+	handler := func(_ *struct{}, _ *Handler[testBaseRoute]) (*benchmarkOutput, *errors.AppError) {
+		return &benchmarkOutput{Message: "published"}, nil
+	}
+	POST(ctor, "/articles/publish", AuthenticatedJSONAPI().WithoutCsrf().WithNamedPermissions("articles.publish").WithRbacPolicy(rbac.PermissionsOnly), handler)
+	POST(ctor, "/articles/publish/dry-run", AuthenticatedJSONAPI().WithoutCsrf().WithNamedPermissions("articles.publish").WithRbacPolicy(rbac.PermissionsOnly).WithRbacDryRun(), handler)
+
+	request := func(path string) int {
+		ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+		ctx.Request = httptest.NewRequest(http.MethodPost, "/login", nil)
+		token, err := IssueBearerToken(ctx, mgr, "default", &SessionClaims{Claims: map[string]string{"subject": "editor-1"}})
+		if err != nil {
+			t.Fatalf("Failed to issue the bearer token: %v", err)
+		}
+
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		req.Header.Set(DefaultSessionAuthorizationHeaderName, token)
+		router.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	if code := request("/articles/publish"); code != http.StatusUnauthorized {
+		t.Errorf("Expected the enforced route to deny the request, got %d", code)
+	}
+	dryRunLogs := func() []observer.LoggedEntry {
+		return logs.FilterMessage("RBAC dry run: request would have been denied").All()
+	}
+	if len(dryRunLogs()) != 0 {
+		t.Errorf("Expected enforced denials not to be logged as dry runs")
+	}
+
+	if code := request("/articles/publish/dry-run"); code != http.StatusOK {
+		t.Errorf("Expected the dry run route to let the request through, got %d", code)
+	}
+
+	entries := dryRunLogs()
+	if len(entries) != 1 {
+		t.Fatalf("Expected the would-be denial to be logged once, got %d entries", len(entries))
+	}
+	decision, ok := entries[0].ContextMap()["decision"].(*rbac.Decision)
+	if !ok {
+		t.Fatalf("Expected the decision to be logged, got %v", entries[0].ContextMap())
+	}
+	if decision.Reason != rbac.DecisionMissingNamedPermissions || len(decision.MissingNamedPermissions) != 1 {
+		t.Errorf("Expected the missing named permission to be traced, got %+v", decision)
+	}
+}
@@ -0,0 +1,58 @@
+package core
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/helpers"
+	"github.com/grzegorzmaniak/gothic/rbac"
+	"github.com/grzegorzmaniak/gothic/validation"
+	"go.uber.org/zap"
+)
+
+// outputPermissionChecker returns the checker used to redact the output of a session, see validation.RedactTag.
+// Permissions are looked up lazily, once per name, and any failure to resolve them redacts the field.
+func outputPermissionChecker(ctx *gin.Context, sessionManager SessionManager, claims *SessionClaims) validation.PermissionChecker {
+	if sessionManager == nil || claims == nil || !claims.HasSession {
+		return nil
+	}
+
+	granted := make(map[string]bool)
+	return func(permission string) bool {
+		if allowed, ok := granted[permission]; ok {
+			return allowed
+		}
+		allowed := checkOutputPermission(ctx, sessionManager, claims, permission)
+		granted[permission] = allowed
+		return allowed
+	}
+}
+
+// checkOutputPermission checks a single named permission of the session, failures redact the field.
+func checkOutputPermission(ctx *gin.Context, sessionManager SessionManager, claims *SessionClaims, permission string) bool {
+	allowed, err := checkSessionPermission(rbacContext(ctx), sessionManager, claims, permission)
+	if err != nil {
+		helpers.Logger(ctx).Debug("Error checking redact permission, field is redacted", zap.String("permission", permission), zap.Error(err))
+		return false
+	}
+	return allowed
+}
+
+// checkSessionPermission checks a single named permission of the session against its RBAC manager.
+func checkSessionPermission(ctx context.Context, sessionManager SessionManager, claims *SessionClaims, permission string) (bool, error) {
+	rbacManager, subjectIdentifier, rbacCacheId, err := sessionRbacSubject(sessionManager, claims)
+	if err != nil {
+		return false, err
+	}
+
+	required, err := rbac.NewPermissionSet(permission)
+	if err != nil {
+		return false, fmt.Errorf("invalid permission '%s': %w", permission, err)
+	}
+
+	return rbac.CheckAccess(ctx, rbacManager, subjectIdentifier, rbacCacheId, rbac.AccessRequirements{
+		NamedPermissions: required,
+		Policy:           rbac.PermissionsOnly,
+	})
+}
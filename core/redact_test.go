@@ -0,0 +1,97 @@
+package core
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eko/gocache/lib/v4/cache"
+	"github.com/gin-gonic/gin"
+	internalcache "github.com/grzegorzmaniak/gothic/cache"
+	"github.com/grzegorzmaniak/gothic/rbac"
+	"github.com/grzegorzmaniak/gothic/validation"
+)
+
+// namedRbacManager grants named permissions per subject.
+type namedRbacManager struct {
+	cacheManager *internalcache.DefaultCacheManager
+	named        map[string]rbac.PermissionSet
+}
+
+func (m *namedRbacManager) GetSubjectRolesAndPermissions(context.Context, string) (rbac.Permissions, []string, error) {
+	return nil, nil, nil
+}
+
+func (m *namedRbacManager) GetRolePermissions(context.Context, string) (rbac.Permissions, error) {
+	return nil, nil
+}
+
+func (m *namedRbacManager) GetSubjectNamedPermissions(_ context.Context, subjectIdentifier string) (rbac.PermissionSet, error) {
+	return m.named[subjectIdentifier], nil
+}
+
+func (m *namedRbacManager) GetRoleNamedPermissions(context.Context, string) (rbac.PermissionSet, error) {
+	return rbac.PermissionSet{}, nil
+}
+
+func (m *namedRbacManager) GetCache() (cache.CacheInterface[[]byte], error) {
+	return m.cacheManager.GetCache()
+}
+
+func (m *namedRbacManager) GetSubjectPermissionsCacheTtl() time.Duration { return time.Minute }
+func (m *namedRbacManager) GetSubjectRolesCacheTtl() time.Duration       { return time.Minute }
+func (m *namedRbacManager) GetRolePermissionsCacheTtl() time.Duration    { return time.Minute }
+
+type redactedProfile struct {
+	Name  string `json:"name"`
+	Email string `json:"email,omitempty" redact:"perm=users.read_pii"`
+	Phone string `json:"phone" redact:"perm=users.read_pii,mask"`
+}
+
+func TestProcessHandlerOutput_Redaction(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mgr := newMockSessionManager(t)
+	mgr.rbacManager = &namedRbacManager{
+		cacheManager: internalcache.BuildDefaultCacheManager(nil),
+		named:        map[string]rbac.PermissionSet{"admin-1": rbac.MustPermissionSet("users.*")},
+	}
+
+	session := func(subject string) *SessionClaims {
+		return &SessionClaims{HasSession: true, Claims: map[string]string{
+			"subject":           subject,
+			RbacCacheIdentifier: strings.Repeat(subject[:1], 32),
+		}}
+	}
+
+	tests := []struct {
+		name   string
+		claims *SessionClaims
+		email  string
+		phone  string
+	}{
+		{name: "Granted by a wildcard", claims: session("admin-1"), email: "john@example.com", phone: "555-0100"},
+		{name: "Missing permission", claims: session("user-1"), email: "", phone: validation.DefaultRedactionMask},
+		{name: "Anonymous", claims: nil, email: "", phone: validation.DefaultRedactionMask},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, _ := gin.CreateTestContext(nil)
+			output := &redactedProfile{Name: "John", Email: "john@example.com", Phone: "555-0100"}
+
+			response, appErr := processHandlerOutput(ctx, output, &APIConfiguration{}, mgr, nil, tt.claims)
+			if appErr != nil {
+				t.Fatalf("Expected no error, got %v", appErr)
+			}
+
+			body := response.Body.(*redactedProfile)
+			if body.Email != tt.email || body.Phone != tt.phone || body.Name != "John" {
+				t.Errorf("Expected email %q and phone %q, got %+v", tt.email, tt.phone, body)
+			}
+			if output.Email != "john@example.com" {
+				t.Error("Expected the handler output to be untouched")
+			}
+		})
+	}
+}
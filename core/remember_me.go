@@ -0,0 +1,194 @@
+package core
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/errors"
+	"github.com/grzegorzmaniak/gothic/helpers"
+	"go.uber.org/zap"
+)
+
+const (
+	RememberMeClaim = "___rm" // Set on remember-me tokens, they are never accepted as sessions
+	RememberedClaim = "___rd" // Set on sessions resumed from a remember-me token
+)
+
+// rememberMeDroppedClaims are not carried by remember-me tokens, a resumed session has to reach an AuthLevel again.
+var rememberMeDroppedClaims = []string{AuthLevelClaim, AuthTimeClaim}
+
+// IsRemembered reports whether the session was resumed from a remember-me token rather than created by a login,
+// handlers of sensitive actions can ask for the password again.
+func (d *SessionClaims) IsRemembered() bool {
+	value, ok := d.GetClaim(RememberedClaim)
+	return ok && value != ""
+}
+
+// IsRememberMeToken reports whether the claims are those of a remember-me token.
+func (d *SessionClaims) IsRememberMeToken() bool {
+	value, ok := d.GetClaim(RememberMeClaim)
+	return ok && value != ""
+}
+
+// rememberMeCookieName returns the name of the remember-me cookie, without the CookiePrefix.
+func rememberMeCookieName(authorizationData *SessionAuthorizationConfiguration) string {
+	return helpers.DefaultString(authorizationData.RememberMeCookieName, DefaultRememberMeCookieName)
+}
+
+// setRememberMeCookiePart sets the remember-me cookie, a negative maxAge expires it.
+func setRememberMeCookiePart(ctx *gin.Context, authorizationData *SessionAuthorizationConfiguration, value string, maxAge int) {
+	setSessionCookiePart(ctx, authorizationData, rememberMeCookieName(authorizationData), value, maxAge)
+}
+
+// SetRememberMeCookie issues a long-lived remember-me token next to the session cookie of claims, use it at login
+// when the user asks to be remembered. The token carries the claims of the session without its identifiers and
+// AuthLevel, it is never accepted as a session itself: once the short-lived session cookie expires, the next
+// request silently resumes a new session from it, with AuthLevelNone and IsRemembered set, so routes with a
+// MinimumAuthLevel ask for a step-up. The token is stored with StoreSession and revoked by ClearRememberMeCookie.
+func SetRememberMeCookie(ctx *gin.Context, sessionManager SessionManager, claims *SessionClaims) error {
+	if ctx == nil {
+		return errors.NewInternalServerError("Context is nil", nil)
+	}
+	if sessionManager == nil {
+		return errors.NewInternalServerError("Session manager is nil", nil)
+	}
+	if claims == nil || !claims.HasSession {
+		return errors.NewUnauthorized("A session is required to remember it", nil)
+	}
+	if impersonated, _ := claims.GetClaim(ImpersonationClaim); impersonated != "" {
+		return errors.NewForbidden("Impersonated sessions cannot be remembered", nil)
+	}
+
+	authorizationData := sessionManager.GetAuthorizationConfiguration()
+	if authorizationData == nil {
+		return errors.NewInternalServerError("Authorization data is nil", nil)
+	}
+	if err := authorizationData.ValidateCookies(); err != nil {
+		return errors.NewInternalServerError("Invalid session cookie configuration", err)
+	}
+
+	group, ok := claims.GetClaim(SessionModeClaim)
+	if !ok || group == "" {
+		return errors.NewInternalServerError("Session has no session mode", nil)
+	}
+
+	remembered := rotateSessionClaims(claims)
+	for _, name := range rememberMeDroppedClaims {
+		delete(remembered.Claims, name)
+	}
+	delete(remembered.Claims, RememberedClaim)
+	remembered.SetClaim(RememberMeClaim, "1")
+
+	// - The token is never refreshed, it is replaced by the next login
+	expiration := helpers.DefaultTimeDuration(authorizationData.RememberMeExpiration, DefaultRememberMeExpiration)
+	rememberHeader := NewSessionHeader(false, expiration, expiration)
+	rememberData := *authorizationData
+	rememberData.SplitCookies = false
+	token, err := CreateAuthorization(group, &rememberHeader, rememberData, remembered, sessionManager)
+	if err != nil {
+		return errors.NewInternalServerError("Failed to create remember-me token", err)
+	}
+
+	if err = sessionManager.StoreSession(ctx, remembered, nil); err != nil {
+		return errors.NewInternalServerError("Failed to store remember-me token", err)
+	}
+
+	setRememberMeCookiePart(ctx, authorizationData, token, int(expiration.Seconds()))
+	return nil
+}
+
+// extractRememberMe decodes the remember-me token of the request, returning nil claims when there is none.
+func extractRememberMe(ctx *gin.Context, sessionManager SessionManager, authorizationData *SessionAuthorizationConfiguration) (*SessionHeader, *SessionClaims, string, error) {
+	token, ok := requestCookie(ctx.Request, string(authorizationData.cookiePrefix())+rememberMeCookieName(authorizationData))
+	if !ok || token == "" {
+		return nil, nil, "", nil
+	}
+
+	buffer := getAuthorizationBuffer()
+	defer putAuthorizationBuffer(buffer)
+
+	rememberData := *authorizationData
+	rememberData.SplitCookies = false
+	headerBytes, payloadBytes, err := extractSessionAuthorizationBytes(&rememberData, sessionManager, token, buffer)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	header, claims, group, err := decodeAuthorizationBytes(headerBytes, payloadBytes, buffer)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	if !claims.IsRememberMeToken() {
+		return nil, nil, "", errors.NewUnauthorized("Token is not a remember-me token", nil)
+	}
+	return header, claims, group, nil
+}
+
+// resumeRememberedSession issues a new session cookie from the remember-me token of the request, it returns nil
+// claims when there is no token. Invalid, expired or revoked tokens have their cookie expired.
+func resumeRememberedSession(ctx *gin.Context, sessionManager SessionManager) (*SessionHeader, *SessionClaims, string, error) {
+	authorizationData := sessionManager.GetAuthorizationConfiguration()
+	if authorizationData == nil {
+		return nil, nil, "", nil
+	}
+
+	header, remembered, group, err := extractRememberMe(ctx, sessionManager, authorizationData)
+	if err == nil && remembered == nil {
+		return nil, nil, "", nil
+	}
+	if err == nil && (header.IsExpired() || !header.IsValid()) {
+		err = errors.NewUnauthorized("Remember-me token is expired", nil)
+	}
+	if err == nil {
+		if ok, verifyErr := sessionManager.VerifySession(ctx, remembered, header); verifyErr != nil || !ok {
+			err = errors.NewUnauthorized("Remember-me token is not valid", verifyErr)
+		}
+	}
+	if err != nil {
+		setRememberMeCookiePart(ctx, authorizationData, "", -1)
+		return nil, nil, "", err
+	}
+
+	// - The resumed session is a new session, the token keeps its own identifiers
+	claims := rotateSessionClaims(remembered)
+	delete(claims.Claims, RememberMeClaim)
+	claims.SetClaim(RememberedClaim, "1")
+	if err = SetCustomSessionCookie(ctx, sessionManager, group, claims, authorizationData); err != nil {
+		return nil, nil, "", err
+	}
+
+	sessionExpiration := helpers.DefaultTimeDuration(authorizationData.Expiration, DefaultSessionExpiration)
+	sessionRefreshTime := helpers.DefaultTimeDuration(authorizationData.RefreshTime, DefaultSessionRefreshTime)
+	sessionHeader := NewSessionHeader(false, sessionExpiration, sessionRefreshTime)
+
+	helpers.Logger(ctx).Debug("Session resumed from remember-me token", zap.String("group", group))
+	return &sessionHeader, claims, group, nil
+}
+
+// ClearRememberMeCookie revokes the remember-me token of the request (see SessionRevoker) and expires its cookie,
+// NewLogoutHandler calls it. Requests without the cookie are left untouched.
+func ClearRememberMeCookie(ctx *gin.Context, sessionManager SessionManager) error {
+	if ctx == nil {
+		return errors.NewInternalServerError("Context is nil", nil)
+	}
+	if sessionManager == nil {
+		return errors.NewInternalServerError("Session manager is nil", nil)
+	}
+
+	authorizationData := sessionManager.GetAuthorizationConfiguration()
+	if authorizationData == nil {
+		return errors.NewInternalServerError("Authorization data is nil", nil)
+	}
+
+	if _, ok := requestCookie(ctx.Request, string(authorizationData.cookiePrefix())+rememberMeCookieName(authorizationData)); !ok {
+		return nil
+	}
+
+	// - A token that no longer decodes has nothing left to revoke
+	if _, remembered, _, err := extractRememberMe(ctx, sessionManager, authorizationData); err == nil && remembered != nil {
+		if err = invalidateSession(ctx, sessionManager, remembered); err != nil {
+			return errors.NewInternalServerError("Failed to revoke the remember-me token", err)
+		}
+	}
+
+	setRememberMeCookiePart(ctx, authorizationData, "", -1)
+	return nil
+}
@@ -0,0 +1,155 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRememberMe(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mgr := &revokingSessionManager{mockSessionManager: newMockSessionManager(t)}
+
+	login := func() (session *http.Cookie, remember *http.Cookie) {
+		t.Helper()
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+		ctx.Request = httptest.NewRequest(http.MethodPost, "/login", nil)
+		claims := &SessionClaims{Claims: map[string]string{"subject": "user-1"}}
+		claims.SetAuthLevel(AuthLevelMultiFactor)
+		if err := SetSessionCookie(ctx, mgr, "user_session", claims); err != nil {
+			t.Fatalf("Failed to set session cookie: %v", err)
+		}
+		claims.HasSession = true
+		if err := SetRememberMeCookie(ctx, mgr, claims); err != nil {
+			t.Fatalf("SetRememberMeCookie() error = %v", err)
+		}
+
+		for _, cookie := range recorder.Result().Cookies() {
+			switch cookie.Name {
+			case DefaultSessionAuthorizationName:
+				session = cookie
+			case DefaultRememberMeCookieName:
+				remember = cookie
+			}
+		}
+		if session == nil || remember == nil {
+			t.Fatal("Expected the session and remember-me cookies")
+		}
+		return session, remember
+	}
+
+	newContext := func(cookies ...*http.Cookie) (*gin.Context, *httptest.ResponseRecorder) {
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+		ctx.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+		for _, cookie := range cookies {
+			ctx.Request.AddCookie(cookie)
+		}
+		return ctx, recorder
+	}
+
+	t.Run("Remember-me cookies outlive the session", func(t *testing.T) {
+		session, remember := login()
+		if remember.MaxAge != int(DefaultRememberMeExpiration.Seconds()) || remember.MaxAge <= session.MaxAge {
+			t.Errorf("Expected the remember-me cookie to outlive the session, got %d and %d", remember.MaxAge, session.MaxAge)
+		}
+		if !remember.HttpOnly {
+			t.Error("Expected the remember-me cookie to be HttpOnly")
+		}
+	})
+
+	t.Run("Missing sessions are resumed", func(t *testing.T) {
+		_, remember := login()
+		ctx, recorder := newContext(remember)
+		_, claims, _, group, appErr := _establishSessionContext(ctx, mgr, &APIConfiguration{SessionRequired: true})
+		if appErr != nil {
+			t.Fatalf("Expected the session to be resumed, got %v", appErr)
+		}
+
+		if subject, _ := claims.GetClaim("subject"); subject != "user-1" || group != "user_session" {
+			t.Errorf("Expected the remembered session, got subject %q in %q", subject, group)
+		}
+		if !claims.IsRemembered() || claims.IsRememberMeToken() {
+			t.Error("Expected a remembered session")
+		}
+		if claims.GetAuthLevel() != AuthLevelNone {
+			t.Errorf("Expected the AuthLevel to be dropped, got %d", claims.GetAuthLevel())
+		}
+
+		_, rememberClaims, _, _ := extractRememberMe(ctx, mgr, mgr.GetAuthorizationConfiguration())
+		tokenId, _ := rememberClaims.GetClaim(SessionIdentifier)
+		if sessionId, _ := claims.GetClaim(SessionIdentifier); sessionId == "" || sessionId == tokenId {
+			t.Errorf("Expected a new session identifier, got %q", sessionId)
+		}
+
+		issued := map[string]bool{}
+		for _, cookie := range recorder.Result().Cookies() {
+			issued[cookie.Name] = cookie.MaxAge > 0
+		}
+		if !issued[DefaultSessionAuthorizationName] || !issued[DefaultCsrfCookieName] {
+			t.Errorf("Expected a new session and CSRF cookie, got %v", issued)
+		}
+	})
+
+	t.Run("Valid sessions are kept", func(t *testing.T) {
+		session, remember := login()
+		ctx, _ := newContext(session, remember)
+		_, claims, _, _, appErr := _establishSessionContext(ctx, mgr, &APIConfiguration{SessionRequired: true})
+		if appErr != nil || claims.IsRemembered() {
+			t.Errorf("Expected the session cookie to be used, got %v", appErr)
+		}
+	})
+
+	t.Run("Remember-me tokens are not sessions", func(t *testing.T) {
+		_, remember := login()
+		ctx, _ := newContext(&http.Cookie{Name: DefaultSessionAuthorizationName, Value: remember.Value})
+		if _, _, _, _, appErr := _establishSessionContext(ctx, mgr, &APIConfiguration{}); appErr == nil {
+			t.Error("Expected the remember-me token to be rejected as a session")
+		}
+	})
+
+	t.Run("Invalid tokens are cleared", func(t *testing.T) {
+		ctx, recorder := newContext(&http.Cookie{Name: DefaultRememberMeCookieName, Value: "not-a-token"})
+		if _, _, _, _, appErr := _establishSessionContext(ctx, mgr, &APIConfiguration{SessionRequired: true}); appErr == nil {
+			t.Error("Expected the request to be rejected")
+		}
+
+		cleared := false
+		for _, cookie := range recorder.Result().Cookies() {
+			cleared = cleared || (cookie.Name == DefaultRememberMeCookieName && cookie.MaxAge < 0)
+		}
+		if !cleared {
+			t.Error("Expected the remember-me cookie to be cleared")
+		}
+	})
+
+	t.Run("Logout revokes the remember-me token", func(t *testing.T) {
+		_, remember := login()
+		mgr.revoked = nil
+		ctx, recorder := newContext(remember)
+		_, rememberClaims, _, _ := extractRememberMe(ctx, mgr, mgr.GetAuthorizationConfiguration())
+		tokenId, _ := rememberClaims.GetClaim(SessionIdentifier)
+
+		if err := ClearRememberMeCookie(ctx, mgr); err != nil {
+			t.Fatalf("ClearRememberMeCookie() error = %v", err)
+		}
+		if !slices.Contains(mgr.revoked, tokenId) {
+			t.Errorf("Expected the remember-me token to be revoked, got %v", mgr.revoked)
+		}
+		if cookies := recorder.Result().Cookies(); len(cookies) != 1 || cookies[0].MaxAge >= 0 {
+			t.Errorf("Expected the remember-me cookie to be expired, got %v", cookies)
+		}
+	})
+
+	t.Run("Impersonated sessions cannot be remembered", func(t *testing.T) {
+		ctx, _ := newContext()
+		claims := &SessionClaims{Claims: map[string]string{SessionModeClaim: "user_session", ImpersonationClaim: "1"}, HasSession: true}
+		if err := SetRememberMeCookie(ctx, mgr, claims); err == nil {
+			t.Error("Expected impersonated sessions to be refused")
+		}
+	})
+}
@@ -0,0 +1,142 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/eko/gocache/lib/v4/store"
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/helpers"
+	"go.uber.org/zap"
+)
+
+// ReplicaHint tells the application's data layer where the request's queries may be routed.
+type ReplicaHint int
+
+const (
+	// ReplicaHintPrimary routes queries to the primary, it is the zero value so nothing is routed to a replica
+	// unless the route is marked ReadOnly.
+	ReplicaHintPrimary ReplicaHint = iota
+
+	// ReplicaHintReplica allows queries to be served by a (possibly lagging) read replica.
+	ReplicaHintReplica
+)
+
+const (
+	// ReplicaHintContextKey is the gin context key holding the request's ReplicaHint.
+	ReplicaHintContextKey = "gothic_replica_hint"
+
+	ReplicaStickyCacheKeyPrefix = "replica_sticky:" // Key: replica_sticky:<subjectIdentifier>
+
+	DefaultReplicaStickiness = 5 * time.Second
+)
+
+type replicaHintKey struct{}
+
+var replicaStickiness atomic.Int64
+
+func init() {
+	replicaStickiness.Store(int64(DefaultReplicaStickiness))
+}
+
+// SetReplicaStickiness sets how long a subject's read-only requests stay on the primary after one of its writes,
+// so it reads its own writes despite replication lag. Zero restores DefaultReplicaStickiness.
+func SetReplicaStickiness(window time.Duration) {
+	replicaStickiness.Store(int64(helpers.DefaultTimeDuration(window, DefaultReplicaStickiness)))
+}
+
+// ReplicaHintFromContext returns the hint of a gin context, or of a context derived from the request context.
+// Contexts without a hint use the primary.
+func ReplicaHintFromContext(ctx context.Context) ReplicaHint {
+	if ctx == nil {
+		return ReplicaHintPrimary
+	}
+	if ginCtx, ok := ctx.(*gin.Context); ok {
+		if hint, exists := ginCtx.Get(ReplicaHintContextKey); exists {
+			return hint.(ReplicaHint)
+		}
+		return ReplicaHintPrimary
+	}
+
+	hint, _ := ctx.Value(replicaHintKey{}).(ReplicaHint)
+	return hint
+}
+
+// setReplicaHint stores the hint on the gin context and the request context.
+func setReplicaHint(ctx *gin.Context, hint ReplicaHint) {
+	ctx.Set(ReplicaHintContextKey, hint)
+	if ctx.Request != nil {
+		ctx.Request = ctx.Request.WithContext(context.WithValue(ctx.Request.Context(), replicaHintKey{}, hint))
+	}
+}
+
+// replicaStickySubject returns the subject whose writes are tracked, or an empty string for anonymous requests.
+func replicaStickySubject(sessionManager SessionManager, claims *SessionClaims) string {
+	if sessionManager == nil || claims == nil || !claims.HasSession {
+		return ""
+	}
+	subjectIdentifier, err := sessionManager.GetSubjectIdentifier(claims)
+	if err != nil {
+		return ""
+	}
+	return subjectIdentifier
+}
+
+// processReplicaHint resolves the request's hint: ReadOnly routes use a replica, unless the subject wrote within
+// the stickiness window.
+func processReplicaHint(ctx *gin.Context, sessionManager SessionManager, sessionConfig *APIConfiguration, claims *SessionClaims) {
+	hint := ReplicaHintPrimary
+	if sessionConfig.ReadOnly {
+		hint = ReplicaHintReplica
+		if subjectIdentifier := replicaStickySubject(sessionManager, claims); subjectIdentifier != "" {
+			if cacheInstance, err := sessionManager.GetCache(); err == nil && cacheInstance != nil {
+				if _, getErr := cacheInstance.Get(ctx, ReplicaStickyCacheKeyPrefix+subjectIdentifier); getErr == nil {
+					hint = ReplicaHintPrimary
+				}
+			}
+		}
+	}
+
+	setReplicaHint(ctx, hint)
+}
+
+// recordReplicaWrite starts the subject's stickiness window.
+func recordReplicaWrite(ctx context.Context, sessionManager SessionManager, claims *SessionClaims) {
+	subjectIdentifier := replicaStickySubject(sessionManager, claims)
+	if subjectIdentifier == "" {
+		return
+	}
+
+	cacheInstance, err := sessionManager.GetCache()
+	if err != nil || cacheInstance == nil {
+		return
+	}
+
+	window := time.Duration(replicaStickiness.Load())
+	if err = cacheInstance.Set(ctx, ReplicaStickyCacheKeyPrefix+subjectIdentifier, []byte{1}, store.WithExpiration(window)); err != nil {
+		helpers.Logger(ctx).Debug("Failed to record write for replica stickiness", zap.Error(err))
+	}
+}
+
+// processReplicaWrite records a write after a successful state changing request on a route that is not ReadOnly.
+func processReplicaWrite(ctx *gin.Context, sessionManager SessionManager, sessionConfig *APIConfiguration, claims *SessionClaims) {
+	if sessionConfig.ReadOnly {
+		return
+	}
+
+	switch ctx.Request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return
+	}
+	recordReplicaWrite(ctx, sessionManager, claims)
+}
+
+// MarkWrite starts the stickiness window for this session, e.g., when a ReadOnly route or a GET wrote after all.
+// Later reads in this request use the primary too.
+func (h *Handler[BaseRoute]) MarkWrite() {
+	h.ReplicaHint = ReplicaHintPrimary
+	setReplicaHint(h.Context, ReplicaHintPrimary)
+	recordReplicaWrite(h.Context, h.SessionManager, h.Claims)
+}
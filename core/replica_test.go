@@ -0,0 +1,110 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestReplicaHint(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mgr := newMockSessionManager(t)
+
+	newContext := func(method string) *gin.Context {
+		ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+		ctx.Request = httptest.NewRequest(method, "/", nil)
+		return ctx
+	}
+	claimsFor := func(subject string) *SessionClaims {
+		return &SessionClaims{HasSession: true, Claims: map[string]string{"subject": subject}}
+	}
+	// - The default cache applies writes asynchronously
+	settle := func() { time.Sleep(10 * time.Millisecond) }
+
+	t.Run("Contexts without a hint use the primary", func(t *testing.T) {
+		if hint := ReplicaHintFromContext(context.Background()); hint != ReplicaHintPrimary {
+			t.Errorf("Expected the primary, got %v", hint)
+		}
+		if hint := ReplicaHintFromContext(newContext(http.MethodGet)); hint != ReplicaHintPrimary {
+			t.Errorf("Expected the primary, got %v", hint)
+		}
+	})
+
+	t.Run("Read-only routes use a replica", func(t *testing.T) {
+		ctx := newContext(http.MethodGet)
+		processReplicaHint(ctx, mgr, PublicRoute().AsReadOnly(), claimsFor("user-1"))
+
+		if hint := ReplicaHintFromContext(ctx); hint != ReplicaHintReplica {
+			t.Errorf("Expected a replica on the gin context, got %v", hint)
+		}
+		if hint := ReplicaHintFromContext(ctx.Request.Context()); hint != ReplicaHintReplica {
+			t.Errorf("Expected a replica on the request context, got %v", hint)
+		}
+
+		handler := newHandler(ctx, testBaseRoute{}, &APIConfiguration{}, mgr, nil, nil, nil, "")
+		defer handler.closeTasks()
+		if handler.ReplicaHint != ReplicaHintReplica {
+			t.Errorf("Expected the handler to carry the hint, got %v", handler.ReplicaHint)
+		}
+	})
+
+	t.Run("Other routes use the primary", func(t *testing.T) {
+		ctx := newContext(http.MethodGet)
+		processReplicaHint(ctx, mgr, PublicRoute(), claimsFor("user-1"))
+		if hint := ReplicaHintFromContext(ctx); hint != ReplicaHintPrimary {
+			t.Errorf("Expected the primary, got %v", hint)
+		}
+	})
+
+	t.Run("Reads after a write stick to the primary", func(t *testing.T) {
+		processReplicaWrite(newContext(http.MethodPost), mgr, PublicRoute(), claimsFor("user-2"))
+		settle()
+
+		ctx := newContext(http.MethodGet)
+		processReplicaHint(ctx, mgr, PublicRoute().AsReadOnly(), claimsFor("user-2"))
+		if hint := ReplicaHintFromContext(ctx); hint != ReplicaHintPrimary {
+			t.Errorf("Expected the writer to stay on the primary, got %v", hint)
+		}
+
+		ctx = newContext(http.MethodGet)
+		processReplicaHint(ctx, mgr, PublicRoute().AsReadOnly(), claimsFor("user-3"))
+		if hint := ReplicaHintFromContext(ctx); hint != ReplicaHintReplica {
+			t.Errorf("Expected other subjects to use a replica, got %v", hint)
+		}
+	})
+
+	t.Run("Safe methods are not writes", func(t *testing.T) {
+		processReplicaWrite(newContext(http.MethodGet), mgr, PublicRoute(), claimsFor("user-4"))
+		settle()
+
+		ctx := newContext(http.MethodGet)
+		processReplicaHint(ctx, mgr, PublicRoute().AsReadOnly(), claimsFor("user-4"))
+		if hint := ReplicaHintFromContext(ctx); hint != ReplicaHintReplica {
+			t.Errorf("Expected a replica, got %v", hint)
+		}
+	})
+
+	t.Run("MarkWrite switches to the primary", func(t *testing.T) {
+		ctx := newContext(http.MethodGet)
+		processReplicaHint(ctx, mgr, PublicRoute().AsReadOnly(), claimsFor("user-5"))
+
+		handler := newHandler(ctx, testBaseRoute{}, &APIConfiguration{}, mgr, nil, claimsFor("user-5"), nil, "")
+		defer handler.closeTasks()
+		handler.MarkWrite()
+		settle()
+
+		if handler.ReplicaHint != ReplicaHintPrimary || ReplicaHintFromContext(ctx) != ReplicaHintPrimary {
+			t.Error("Expected the handler and context to switch to the primary")
+		}
+
+		next := newContext(http.MethodGet)
+		processReplicaHint(next, mgr, PublicRoute().AsReadOnly(), claimsFor("user-5"))
+		if hint := ReplicaHintFromContext(next); hint != ReplicaHintPrimary {
+			t.Errorf("Expected the next read to stick to the primary, got %v", hint)
+		}
+	})
+}
@@ -0,0 +1,64 @@
+package core
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/errors"
+	"github.com/grzegorzmaniak/gothic/helpers"
+	"github.com/grzegorzmaniak/gothic/rbac"
+	"go.uber.org/zap"
+)
+
+// CheckResourcePermission checks whether the session's subject may perform an action on a single resource, see
+// rbac.CheckResourcePermission. The check is scoped to the request's tenant, sessionless requests are denied.
+func CheckResourcePermission(
+	ctx *gin.Context,
+	sessionManager SessionManager,
+	claims *SessionClaims,
+	resourceType string,
+	resourceID string,
+	action string,
+) (bool, error) {
+	if claims == nil || !claims.HasSession {
+		return false, nil
+	}
+
+	rbacManager, subjectIdentifier, _, err := sessionRbacSubject(sessionManager, claims)
+	if err != nil {
+		return false, err
+	}
+
+	allowed, err := rbac.CheckResourcePermission(rbacContext(ctx), rbacManager, subjectIdentifier, resourceType, resourceID, action)
+	if err != nil {
+		return false, fmt.Errorf("failed to check the resource permission: %w", err)
+	}
+	return allowed, nil
+}
+
+// CheckResourcePermission checks whether this session may perform an action on a resource, e.g.,
+// data.CheckResourcePermission("document", ctx.Param("id"), "edit").
+func (h *Handler[BaseRoute]) CheckResourcePermission(resourceType string, resourceID string, action string) (bool, error) {
+	return CheckResourcePermission(h.Context, h.SessionManager, h.Claims, resourceType, resourceID, action)
+}
+
+// RequireResourcePermission is like CheckResourcePermission, but returns the error to respond with: the same
+// 401 "Insufficient permissions" as a failed route level check, or a 500 if the check failed.
+func (h *Handler[BaseRoute]) RequireResourcePermission(resourceType string, resourceID string, action string) *errors.AppError {
+	allowed, err := h.CheckResourcePermission(resourceType, resourceID, action)
+	if err != nil {
+		helpers.Logger(h.Context).Debug("Error checking resource permission", zap.Error(err))
+		return errors.NewInternalServerError("Failed to check permissions", err)
+	}
+
+	if !allowed {
+		publishSessionEvent(h.Context, h.SessionManager, SessionEventRbacDenied, h.Claims, "insufficient resource permissions")
+		insufficientPermsErr := errors.NewUnauthorized("Insufficient permissions", nil)
+		insufficientPermsErr.Details = map[string]interface{}{
+			"resource_type": resourceType,
+			"action":        action,
+		}
+		return insufficientPermsErr
+	}
+	return nil
+}
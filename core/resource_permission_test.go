@@ -0,0 +1,72 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	internalcache "github.com/grzegorzmaniak/gothic/cache"
+	"github.com/grzegorzmaniak/gothic/errors"
+)
+
+// documentRbacManager makes "owner" the owner of every document.
+type documentRbacManager struct {
+	namedRbacManager
+}
+
+func (m *documentRbacManager) GetSubjectResourceActions(_ context.Context, subjectIdentifier string, resourceType string, _ string) ([]string, error) {
+	if subjectIdentifier == "owner" && resourceType == "document" {
+		return []string{"read", "edit"}, nil
+	}
+	return []string{"read"}, nil
+}
+
+func TestRequireResourcePermission(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mgr := newMockSessionManager(t)
+	mgr.rbacManager = &documentRbacManager{namedRbacManager{cacheManager: internalcache.BuildDefaultCacheManager(nil)}}
+
+	router := gin.New()
+	ctor := NewRouteConstructor(router, testBaseRoute{}, mgr, nil)
+	handler := func(_ *struct{}, data *Handler[testBaseRoute]) (*benchmarkOutput, *errors.AppError) {
+		if appErr := data.RequireResourcePermission("document", data.Context.Param("id"), "edit"); appErr != nil {
+			return nil, appErr
+		}
+		return &benchmarkOutput{Message: "edited"}, nil
+	}
+	POST(ctor, "/documents/:id", AuthenticatedJSONAPI().WithoutCsrf(), handler)
+	POST(ctor, "/public/documents/:id", PublicRoute(), handler)
+
+	request := func(path, subject string) int {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		if subject != "" {
+			ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+			ctx.Request = httptest.NewRequest(http.MethodPost, "/login", nil)
+			token, err := IssueBearerToken(ctx, mgr, "default", &SessionClaims{Claims: map[string]string{"subject": subject}})
+			if err != nil {
+				t.Fatalf("Failed to issue the bearer token: %v", err)
+			}
+			req.Header.Set(DefaultSessionAuthorizationHeaderName, token)
+		}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	if code := request("/documents/42", "owner"); code != http.StatusOK {
+		t.Errorf("Expected the owner to edit the document, got %d", code)
+	}
+	if code := request("/documents/42", "viewer"); code != http.StatusUnauthorized {
+		t.Errorf("Expected the viewer to be denied, got %d", code)
+	}
+	if code := request("/public/documents/42", ""); code != http.StatusUnauthorized {
+		t.Errorf("Expected sessionless requests to be denied, got %d", code)
+	}
+
+	mgr.rbacManager = &namedRbacManager{cacheManager: internalcache.BuildDefaultCacheManager(nil)}
+	if code := request("/documents/42", "owner"); code != http.StatusInternalServerError {
+		t.Errorf("Expected managers without resource permissions to fail the request, got %d", code)
+	}
+}
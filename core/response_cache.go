@@ -0,0 +1,232 @@
+package core
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/eko/gocache/lib/v4/store"
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/errors"
+	"github.com/grzegorzmaniak/gothic/helpers"
+	"go.uber.org/zap"
+)
+
+const (
+	ResponseCacheKeyPrefix        = "response_cache:"     // Key: response_cache:<method> <route>@<generation>|<vary hash>
+	ResponseCacheGenerationPrefix = "response_cache_gen:" // Key: response_cache_gen:<method> <route>
+	ResponseCacheHeader           = "X-Cache"
+
+	ResponseCacheGenerationSize = 8
+)
+
+// ResponseCacheVary selects what cached responses are keyed by on top of the route and request path.
+type ResponseCacheVary int
+
+const (
+	// CacheVarySubject keeps a cached response per subject, requests without a session share theirs.
+	CacheVarySubject ResponseCacheVary = 1 << iota
+
+	// CacheVaryGroup keeps a cached response per session group.
+	CacheVaryGroup
+
+	// CacheVaryQuery keeps a cached response per (normalized) query string.
+	CacheVaryQuery
+
+	// CacheVaryAll is used when no vary option is set, so responses are never shared between subjects by accident.
+	CacheVaryAll = CacheVarySubject | CacheVaryGroup | CacheVaryQuery
+)
+
+// cachedRouteResponse is the response stored for a cached route.
+type cachedRouteResponse struct {
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body"`
+}
+
+func shouldCacheResponse(ctx *gin.Context, sessionConfig *APIConfiguration) bool {
+	return sessionConfig.CacheTTL > 0 &&
+		!helpers.ResponseCaptured(ctx) &&
+		!sessionConfig.ManualResponse &&
+		ctx.Request != nil &&
+		(ctx.Request.Method == http.MethodGet || ctx.Request.Method == http.MethodHead)
+}
+
+// responseCacheRoute identifies a route, e.g., "GET /notes/:id".
+func responseCacheRoute(method string, path string) string {
+	return strings.ToUpper(method) + " " + path
+}
+
+// responseCacheGeneration returns the current generation of a route's cached responses. A missing generation,
+// e.g., evicted from the cache, is replaced by a new one, so responses stored before an invalidation can never
+// come back.
+func responseCacheGeneration(ctx context.Context, sessionManager SessionManager, route string) (string, error) {
+	cacheInstance, err := sessionManager.GetCache()
+	if err != nil || cacheInstance == nil {
+		return "", fmt.Errorf("cache is not available: %w", err)
+	}
+
+	if generation, getErr := cacheInstance.Get(ctx, ResponseCacheGenerationPrefix+route); getErr == nil && len(generation) > 0 {
+		return string(generation), nil
+	}
+	return newResponseCacheGeneration(ctx, sessionManager, route)
+}
+
+func newResponseCacheGeneration(ctx context.Context, sessionManager SessionManager, route string) (string, error) {
+	cacheInstance, err := sessionManager.GetCache()
+	if err != nil || cacheInstance == nil {
+		return "", fmt.Errorf("cache is not available: %w", err)
+	}
+
+	generation, err := helpers.GenerateID(ResponseCacheGenerationSize)
+	if err != nil {
+		return "", err
+	}
+	if err = cacheInstance.Set(ctx, ResponseCacheGenerationPrefix+route, []byte(generation)); err != nil {
+		return "", err
+	}
+	return generation, nil
+}
+
+// InvalidateResponseCache drops the cached responses of the given routes, each given as its method and
+// registered path, e.g., "GET /notes/:id". Every variant is dropped at once, for every subject and query.
+func InvalidateResponseCache(ctx context.Context, sessionManager SessionManager, routes ...string) error {
+	if sessionManager == nil {
+		return fmt.Errorf("session manager is not set")
+	}
+
+	var lastErr error
+	for _, route := range routes {
+		method, path, found := strings.Cut(strings.TrimSpace(route), " ")
+		if !found {
+			method, path = http.MethodGet, route
+		}
+		if _, err := newResponseCacheGeneration(ctx, sessionManager, responseCacheRoute(method, strings.TrimSpace(path))); err != nil {
+			lastErr = fmt.Errorf("failed to invalidate %q: %w", route, err)
+		}
+	}
+	return lastErr
+}
+
+// responseCacheKey builds the key of the request's cached response, or "" when it must not be cached.
+func responseCacheKey(ctx *gin.Context, sessionManager SessionManager, sessionConfig *APIConfiguration, claims *SessionClaims, group string) string {
+	vary := sessionConfig.CacheVaryBy
+	if vary == 0 {
+		vary = CacheVaryAll
+	}
+
+	var sb strings.Builder
+	sb.WriteString(ctx.Request.URL.Path)
+
+	// - The tenant is always part of the key, a subject of several tenants must never see another tenant's response
+	sb.WriteString("|tenant:")
+	sb.WriteString(RequestTenant(ctx))
+	if vary&CacheVaryQuery != 0 {
+		sb.WriteString("?")
+		sb.WriteString(ctx.Request.URL.Query().Encode()) // - Encode sorts by key
+	}
+	if vary&CacheVaryGroup != 0 {
+		sb.WriteString("|group:")
+		sb.WriteString(group)
+	}
+	if vary&CacheVarySubject != 0 && claims != nil && claims.HasSession {
+		subjectIdentifier, err := sessionManager.GetSubjectIdentifier(claims)
+		if err != nil || subjectIdentifier == "" {
+			// - Never risk sharing a response between subjects, just don't cache
+			helpers.Logger(ctx).Debug("Unable to get subject identifier, response will not be cached", zap.Error(err))
+			return ""
+		}
+		sb.WriteString("|subject:")
+		sb.WriteString(subjectIdentifier)
+	}
+
+	route := responseCacheRoute(ctx.Request.Method, ctx.FullPath())
+	if ctx.Request.Method == http.MethodHead {
+		// - HEAD shares the responses of GET
+		route = responseCacheRoute(http.MethodGet, ctx.FullPath())
+	}
+	generation, err := responseCacheGeneration(ctx, sessionManager, route)
+	if err != nil {
+		helpers.Logger(ctx).Warn("Response cache is not available", zap.Error(err))
+		return ""
+	}
+
+	hash := sha256.Sum256([]byte(sb.String()))
+	return ResponseCacheKeyPrefix + route + "@" + generation + "|" + base64.RawURLEncoding.EncodeToString(hash[:])
+}
+
+// executeCached serves GET requests of routes with a CacheTTL from the cache, fn only runs on a miss and its
+// successful response is stored. Responses are keyed by the route, the request path, the resolved tenant and
+// the route's CacheVaryBy options, and run after the session, RBAC and input checks, so a cached response is only
+// served to requests allowed to see it.
+func executeCached(
+	ctx *gin.Context,
+	sessionManager SessionManager,
+	sessionConfig *APIConfiguration,
+	claims *SessionClaims,
+	group string,
+	fn func() (*routeResponse, *errors.AppError),
+) (*routeResponse, *errors.AppError) {
+	if !shouldCacheResponse(ctx, sessionConfig) {
+		return fn()
+	}
+
+	cacheKey := responseCacheKey(ctx, sessionManager, sessionConfig, claims, group)
+	if cacheKey == "" {
+		return fn()
+	}
+	cacheInstance, err := sessionManager.GetCache()
+	if err != nil || cacheInstance == nil {
+		return fn()
+	}
+
+	if cached, getErr := cacheInstance.Get(ctx, cacheKey); getErr == nil {
+		var stored cachedRouteResponse
+		if json.Unmarshal(cached, &stored) == nil {
+			response, replayErr := decodeStoredResponse(stored.Headers, stored.Body)
+			if replayErr == nil {
+				response.Headers[ResponseCacheHeader] = "HIT"
+				return response, nil
+			}
+			helpers.Logger(ctx).Warn("Failed to decode cached response", zap.Error(replayErr))
+		}
+	}
+
+	// - Only successful responses are cached
+	response, appErr := fn()
+	if appErr != nil || response == nil {
+		return response, appErr
+	}
+
+	body, err := json.Marshal(response.Body)
+	if err == nil {
+		var marshaled []byte
+		if marshaled, err = json.Marshal(cachedRouteResponse{Headers: response.Headers, Body: body}); err == nil {
+			err = cacheInstance.Set(ctx, cacheKey, marshaled, store.WithExpiration(sessionConfig.CacheTTL))
+		}
+	}
+	if err != nil {
+		helpers.Logger(ctx).Warn("Failed to cache response", zap.Error(err))
+	}
+
+	headers := make(map[string]string, len(response.Headers)+1)
+	for name, value := range response.Headers {
+		headers[name] = value
+	}
+	headers[ResponseCacheHeader] = "MISS"
+	return &routeResponse{Headers: headers, Body: response.Body}, nil
+}
+
+// invalidateCachedRoutes drops the cached responses listed in the route's InvalidatesCache after a successful
+// request.
+func invalidateCachedRoutes(ctx *gin.Context, sessionManager SessionManager, sessionConfig *APIConfiguration, appErr *errors.AppError) {
+	if len(sessionConfig.InvalidatesCache) == 0 || appErr != nil {
+		return
+	}
+	if err := InvalidateResponseCache(ctx, sessionManager, sessionConfig.InvalidatesCache...); err != nil {
+		helpers.Logger(ctx).Warn("Failed to invalidate cached responses", zap.Error(err))
+	}
+}
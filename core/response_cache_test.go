@@ -0,0 +1,144 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/errors"
+)
+
+type cachedNoteInput struct {
+	Id string `uri:"id" json:"-"`
+}
+
+type cachedNoteOutput struct {
+	Id        string `json:"id"`
+	Execution int64  `json:"execution"`
+}
+
+func TestResponseCache(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mgr := newMockSessionManager(t)
+	router := gin.New()
+	ctor := NewRouteConstructor(router, testBaseRoute{}, mgr, nil)
+
+	var executions atomic.Int64
+	handler := func(input *cachedNoteInput, _ *Handler[testBaseRoute]) (*cachedNoteOutput, *errors.AppError) {
+		return &cachedNoteOutput{Id: input.Id, Execution: executions.Add(1)}, nil
+	}
+	GET(ctor, "/notes/:id", AuthenticatedJSONAPI().WithResponseCache(time.Minute, 0), handler)
+	GET(ctor, "/shared/:id", AuthenticatedJSONAPI().WithResponseCache(time.Minute, CacheVaryGroup), handler)
+	POST(ctor, "/notes/:id", AuthenticatedJSONAPI().WithCacheInvalidation("GET /notes/:id"), handler)
+	GET(ctor, "/tenant/notes/:id", AuthenticatedJSONAPI().WithTenantResolver(TenantFromHeader("X-Tenant-ID")).WithResponseCache(time.Minute, CacheVaryAll), func(_ *cachedNoteInput, data *Handler[testBaseRoute]) (*cachedNoteOutput, *errors.AppError) {
+		return &cachedNoteOutput{Id: RequestTenant(data.Context), Execution: executions.Add(1)}, nil
+	})
+	GET(ctor, "/failing", AuthenticatedJSONAPI().WithResponseCache(time.Minute, 0), func(_ *cachedNoteInput, _ *Handler[testBaseRoute]) (*cachedNoteOutput, *errors.AppError) {
+		executions.Add(1)
+		return nil, errors.NewNotFound("", nil)
+	})
+
+	issue := func(subject string) string {
+		ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+		ctx.Request = httptest.NewRequest(http.MethodPost, "/login", nil)
+		token, err := IssueBearerToken(ctx, mgr, "default", &SessionClaims{Claims: map[string]string{"subject": subject}})
+		if err != nil {
+			t.Fatalf("Failed to issue the bearer token: %v", err)
+		}
+		return token
+	}
+	userOne, userTwo := issue("user-1"), issue("user-2")
+
+	request := func(method, path, token string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(method, path, nil)
+		request.Header.Set(DefaultSessionAuthorizationHeaderName, token)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		time.Sleep(10 * time.Millisecond) // - Let the cache settle
+		return recorder
+	}
+
+	// - Warm the cache
+	request(http.MethodGet, "/notes/1", userOne)
+	first := request(http.MethodGet, "/notes/1", userOne)
+	if first.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", first.Code, first.Body.String())
+	}
+
+	t.Run("Repeated requests are served from the cache", func(t *testing.T) {
+		before := executions.Load()
+		hit := request(http.MethodGet, "/notes/1", userOne)
+		if hit.Body.String() != first.Body.String() || hit.Header().Get(ResponseCacheHeader) != "HIT" || executions.Load() != before {
+			t.Errorf("Expected a cache hit, got %s (%s)", hit.Body.String(), hit.Header().Get(ResponseCacheHeader))
+		}
+	})
+
+	t.Run("Responses vary by path and subject", func(t *testing.T) {
+		if recorder := request(http.MethodGet, "/notes/2", userOne); strings.Contains(recorder.Body.String(), `"id":"1"`) {
+			t.Errorf("Expected another path to miss, got %s", recorder.Body.String())
+		}
+		if recorder := request(http.MethodGet, "/notes/1", userTwo); recorder.Header().Get(ResponseCacheHeader) == "HIT" {
+			t.Error("Expected another subject to miss the cache")
+		}
+	})
+
+	t.Run("Vary options can share responses between subjects", func(t *testing.T) {
+		request(http.MethodGet, "/shared/1", userOne)
+		shared := request(http.MethodGet, "/shared/1", userOne)
+		if recorder := request(http.MethodGet, "/shared/1", userTwo); recorder.Body.String() != shared.Body.String() {
+			t.Errorf("Expected the response to be shared, got %s and %s", shared.Body.String(), recorder.Body.String())
+		}
+	})
+
+	t.Run("Responses vary by tenant", func(t *testing.T) {
+		tenantRequest := func(tenant string) *httptest.ResponseRecorder {
+			request := httptest.NewRequest(http.MethodGet, "/tenant/notes/1", nil)
+			request.Header.Set(DefaultSessionAuthorizationHeaderName, userOne)
+			request.Header.Set("X-Tenant-ID", tenant)
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+			time.Sleep(10 * time.Millisecond) // - Let the cache settle
+			return recorder
+		}
+		tenantRequest("acme")
+		if recorder := tenantRequest("acme"); recorder.Header().Get(ResponseCacheHeader) != "HIT" || !strings.Contains(recorder.Body.String(), `"id":"acme"`) {
+			t.Fatalf("Expected the tenant's response to be cached, got %s (%s)", recorder.Body.String(), recorder.Header().Get(ResponseCacheHeader))
+		}
+		if recorder := tenantRequest("globex"); recorder.Header().Get(ResponseCacheHeader) == "HIT" || !strings.Contains(recorder.Body.String(), `"id":"globex"`) {
+			t.Errorf("Expected another tenant to miss the cache, got %s (%s)", recorder.Body.String(), recorder.Header().Get(ResponseCacheHeader))
+		}
+	})
+
+	t.Run("Failed responses are not cached", func(t *testing.T) {
+		request(http.MethodGet, "/failing", userOne)
+		before := executions.Load()
+		if recorder := request(http.MethodGet, "/failing", userOne); recorder.Code != http.StatusNotFound || executions.Load() != before+1 {
+			t.Errorf("Expected the handler to run again, got %d", recorder.Code)
+		}
+	})
+
+	t.Run("Successful writes invalidate the route", func(t *testing.T) {
+		if recorder := request(http.MethodPost, "/notes/1", userOne); recorder.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", recorder.Code, recorder.Body.String())
+		}
+		if recorder := request(http.MethodGet, "/notes/1", userOne); recorder.Header().Get(ResponseCacheHeader) != "MISS" || recorder.Body.String() == first.Body.String() {
+			t.Errorf("Expected a fresh response, got %s (%s)", recorder.Body.String(), recorder.Header().Get(ResponseCacheHeader))
+		}
+	})
+
+	t.Run("Manual invalidation", func(t *testing.T) {
+		request(http.MethodGet, "/shared/1", userOne)
+		ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+		if err := InvalidateResponseCache(ctx, mgr, "GET /shared/:id"); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		time.Sleep(10 * time.Millisecond) // - Let the cache settle
+		if recorder := request(http.MethodGet, "/shared/1", userOne); recorder.Header().Get(ResponseCacheHeader) != "MISS" {
+			t.Error("Expected the invalidated route to miss the cache")
+		}
+	})
+}
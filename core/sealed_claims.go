@@ -0,0 +1,159 @@
+package core
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/grzegorzmaniak/gothic/helpers"
+)
+
+const (
+	SealedClaimsVersion   = "SC1"
+	SealedClaimsDelimiter = "." // Sealed claims are <version>.<keyId>.<wrapped data key>.<ciphertext>
+	sealedClaimsParts     = 4
+)
+
+// SealClaims envelope encrypts claims for a server-side session store (Redis, SQL, ...), so a dump of the store does
+// not expose the PII held in the claims. The claims are encrypted with a fresh data key, which is encrypted with the
+// current session key and tagged with its key id, so blobs stay readable while the key ring rotates.
+//
+// associatedData binds the blob to its record, e.g., the session or subject id, a blob copied to another record
+// fails to open. Pass the same value to OpenClaims.
+func SealClaims(sessionManager SessionManager, claims *SessionClaims, associatedData []byte) (string, error) {
+	if sessionManager == nil {
+		return "", fmt.Errorf("session manager is nil")
+	}
+	if claims == nil {
+		return "", fmt.Errorf("claims are nil")
+	}
+
+	payload, err := json.Marshal(claims.Claims)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal claims: %w", err)
+	}
+
+	dataKey, err := helpers.GenerateSymmetricKey(helpers.AESKeySize32)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate data key: %w", err)
+	}
+	ciphertext, err := helpers.SymmetricEncrypt(dataKey, payload, sealedClaimsData(associatedData))
+	if err != nil {
+		return "", fmt.Errorf("failed to encrypt claims: %w", err)
+	}
+
+	return wrapDataKey(sessionManager, dataKey, ciphertext, associatedData)
+}
+
+// OpenClaims decrypts claims sealed by SealClaims, with the current or an older session key (GetOldSessionKey).
+func OpenClaims(sessionManager SessionManager, sealed string, associatedData []byte) (*SessionClaims, error) {
+	if sessionManager == nil {
+		return nil, fmt.Errorf("session manager is nil")
+	}
+
+	dataKey, ciphertext, err := unwrapDataKey(sessionManager, sealed, associatedData)
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := helpers.SymmetricDecrypt(dataKey, ciphertext, sealedClaimsData(associatedData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt claims: %w", err)
+	}
+
+	claims := &SessionClaims{}
+	if err = json.Unmarshal(payload, &claims.Claims); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal claims: %w", err)
+	}
+	return claims, nil
+}
+
+// ResealClaims re-encrypts the data key of sealed claims with the current session key, the claims themselves are
+// not decrypted. Run it over the store before a key is removed from the ring, see SealedClaimsKeyId.
+func ResealClaims(sessionManager SessionManager, sealed string, associatedData []byte) (string, error) {
+	if sessionManager == nil {
+		return "", fmt.Errorf("session manager is nil")
+	}
+
+	dataKey, ciphertext, err := unwrapDataKey(sessionManager, sealed, associatedData)
+	if err != nil {
+		return "", err
+	}
+	return wrapDataKey(sessionManager, dataKey, ciphertext, associatedData)
+}
+
+// SealedClaimsKeyId returns the id of the session key sealed claims were encrypted with.
+func SealedClaimsKeyId(sealed string) (string, error) {
+	parts := strings.Split(sealed, SealedClaimsDelimiter)
+	if len(parts) != sealedClaimsParts || parts[0] != SealedClaimsVersion {
+		return "", fmt.Errorf("invalid sealed claims format")
+	}
+	return parts[1], nil
+}
+
+// sealedClaimsData is the associated data of the claims ciphertext.
+func sealedClaimsData(associatedData []byte) []byte {
+	return append([]byte(SealedClaimsVersion), associatedData...)
+}
+
+// wrappedKeyData is the associated data of the wrapped data key, binding it to the key id.
+func wrappedKeyData(keyId string, associatedData []byte) []byte {
+	return append([]byte(SealedClaimsVersion+keyId), associatedData...)
+}
+
+func wrapDataKey(sessionManager SessionManager, dataKey []byte, ciphertext []byte, associatedData []byte) (string, error) {
+	sessionKey, keyId, err := sessionManager.GetSessionKey()
+	if err != nil {
+		return "", fmt.Errorf("failed to get session key: %w", err)
+	}
+	if len(keyId) < MinimumSessionKeyIdSize || len(keyId) > MaximumSessionKeyIdSize || strings.Contains(keyId, SealedClaimsDelimiter) {
+		return "", fmt.Errorf("invalid keyId: must be between %d and %d characters without '%s'", MinimumSessionKeyIdSize, MaximumSessionKeyIdSize, SealedClaimsDelimiter)
+	}
+
+	wrappedKey, err := helpers.SymmetricEncrypt(sessionKey, dataKey, wrappedKeyData(keyId, associatedData))
+	if err != nil {
+		return "", fmt.Errorf("failed to encrypt data key: %w", err)
+	}
+
+	return strings.Join([]string{
+		SealedClaimsVersion,
+		keyId,
+		base64.RawURLEncoding.EncodeToString(wrappedKey),
+		base64.RawURLEncoding.EncodeToString(ciphertext),
+	}, SealedClaimsDelimiter), nil
+}
+
+func unwrapDataKey(sessionManager SessionManager, sealed string, associatedData []byte) ([]byte, []byte, error) {
+	parts := strings.Split(sealed, SealedClaimsDelimiter)
+	if len(parts) != sealedClaimsParts || parts[0] != SealedClaimsVersion {
+		return nil, nil, fmt.Errorf("invalid sealed claims format")
+	}
+	keyId := parts[1]
+
+	wrappedKey, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decode data key: %w", err)
+	}
+	ciphertext, err := base64.RawURLEncoding.DecodeString(parts[3])
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decode claims: %w", err)
+	}
+
+	// - Same lookup as the session tokens, the current key first, then the older ones
+	sessionKey, currentKeyId, err := sessionManager.GetSessionKey()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get session key: %w", err)
+	}
+	if keyId != currentKeyId {
+		if sessionKey, err = sessionManager.GetOldSessionKey(keyId); err != nil {
+			return nil, nil, fmt.Errorf("failed to get session key '%s': %w", keyId, err)
+		}
+	}
+
+	dataKey, err := helpers.SymmetricDecrypt(sessionKey, wrappedKey, wrappedKeyData(keyId, associatedData))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decrypt data key: %w", err)
+	}
+	return dataKey, ciphertext, nil
+}
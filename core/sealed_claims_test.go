@@ -0,0 +1,122 @@
+package core
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/helpers"
+)
+
+func TestSealClaimsRoundTrip(t *testing.T) {
+	manager := newMockSessionManager(t)
+	claims := &SessionClaims{Claims: map[string]string{"subject": "user-1", "email": "jane@example.com"}}
+
+	sealed, err := SealClaims(manager, claims, []byte("user-1"))
+	if err != nil {
+		t.Fatalf("SealClaims() error = %v", err)
+	}
+	if strings.Contains(sealed, "jane") || !strings.HasPrefix(sealed, SealedClaimsVersion+SealedClaimsDelimiter+"key-1"+SealedClaimsDelimiter) {
+		t.Fatalf("Expected an opaque blob tagged with the key id, got %s", sealed)
+	}
+
+	opened, err := OpenClaims(manager, sealed, []byte("user-1"))
+	if err != nil {
+		t.Fatalf("OpenClaims() error = %v", err)
+	}
+	if email, _ := opened.GetClaim("email"); email != "jane@example.com" {
+		t.Errorf("Expected the email claim, got '%s'", email)
+	}
+
+	if _, err = OpenClaims(manager, sealed, []byte("user-2")); err == nil {
+		t.Error("Expected claims bound to another record to fail")
+	}
+
+	parts := strings.Split(sealed, SealedClaimsDelimiter)
+	tampered := []byte(parts[3])
+	if tampered[0] == 'A' {
+		tampered[0] = 'B'
+	} else {
+		tampered[0] = 'A'
+	}
+	parts[3] = string(tampered)
+	if _, err = OpenClaims(manager, strings.Join(parts, SealedClaimsDelimiter), []byte("user-1")); err == nil {
+		t.Error("Expected tampered claims to fail")
+	}
+	if _, err = OpenClaims(manager, "SC1.key-1.abc", []byte("user-1")); err == nil {
+		t.Error("Expected a malformed blob to fail")
+	}
+}
+
+func TestSealedClaimsKeyRotation(t *testing.T) {
+	manager := newMockSessionManager(t)
+	claims := &SessionClaims{Claims: map[string]string{"subject": "user-1"}}
+
+	sealed, err := SealClaims(manager, claims, nil)
+	if err != nil {
+		t.Fatalf("SealClaims() error = %v", err)
+	}
+
+	// - Rotate, the old key stays in the ring
+	newKey, _ := helpers.GenerateSymmetricKey(helpers.AESKeySize32)
+	manager.keys["key-2"] = newKey
+	manager.currentKeyId = "key-2"
+
+	if _, err = OpenClaims(manager, sealed, nil); err != nil {
+		t.Fatalf("Expected claims sealed with the old key to open, got %v", err)
+	}
+
+	resealed, err := ResealClaims(manager, sealed, nil)
+	if err != nil {
+		t.Fatalf("ResealClaims() error = %v", err)
+	}
+	if keyId, _ := SealedClaimsKeyId(resealed); keyId != "key-2" {
+		t.Errorf("Expected the resealed claims to use key-2, got '%s'", keyId)
+	}
+	if strings.Split(resealed, SealedClaimsDelimiter)[3] != strings.Split(sealed, SealedClaimsDelimiter)[3] {
+		t.Error("Expected resealing to keep the claims ciphertext")
+	}
+
+	// - Once the old key is retired only the resealed claims open
+	delete(manager.keys, "key-1")
+	if _, err = OpenClaims(manager, sealed, nil); err == nil {
+		t.Error("Expected claims sealed with a removed key to fail")
+	}
+	if _, err = OpenClaims(manager, resealed, nil); err != nil {
+		t.Errorf("Expected the resealed claims to open, got %v", err)
+	}
+}
+
+func TestBuiltSessionManagerSealedStore(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var storedSubject, storedSealed string
+	manager, err := NewSessionManagerBuilder().
+		WithKey("key-1", newBuilderKey(t)).
+		WithSealedSessionStore(func(_ context.Context, subject string, sealed string, _ *SessionHeader) error {
+			storedSubject, storedSealed = subject, sealed
+			return nil
+		}).
+		Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	defer manager.Close()
+
+	claims := &SessionClaims{Claims: map[string]string{"subject": "user-1", "email": "jane@example.com"}}
+	if err = manager.StoreSession(context.Background(), claims, nil); err != nil {
+		t.Fatalf("StoreSession() error = %v", err)
+	}
+	if storedSubject != "user-1" || strings.Contains(storedSealed, "jane") {
+		t.Fatalf("Expected sealed claims stored for user-1, got '%s' / '%s'", storedSubject, storedSealed)
+	}
+
+	opened, err := OpenClaims(manager, storedSealed, []byte(storedSubject))
+	if err != nil {
+		t.Fatalf("OpenClaims() error = %v", err)
+	}
+	if email, _ := opened.GetClaim("email"); email != "jane@example.com" {
+		t.Errorf("Expected the email claim, got '%s'", email)
+	}
+}
@@ -0,0 +1,200 @@
+package core
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/grzegorzmaniak/gothic/helpers"
+	"go.uber.org/zap"
+)
+
+// SecuritySeverity is how serious a SecurityFinding is.
+type SecuritySeverity string
+
+const (
+	// SecuritySeverityWarning findings are weaker than recommended but may be intended, e.g., CSRF disabled on a
+	// bearer token only API.
+	SecuritySeverityWarning SecuritySeverity = "warning"
+
+	// SecuritySeverityCritical findings leave the service open to an attack, SecurityReport.Err fails on them.
+	SecuritySeverityCritical SecuritySeverity = "critical"
+)
+
+const (
+	SecurityCheckConfiguration      = "configuration"        // ValidateConfiguration rejects the session manager
+	SecurityCheckKeySize            = "key_size"             // The session key is not a 256 bit AES key
+	SecurityCheckInsecureCookie     = "insecure_cookie"      // A cookie is sent without Secure / HttpOnly or with SameSite=None
+	SecurityCheckCsrfDisabled       = "csrf_disabled"        // A state changing route with a session skips the CSRF check
+	SecurityCheckRbacWithoutSession = "rbac_without_session" // A route requires roles or permissions but no session
+	SecurityCheckLongExpiration     = "long_expiration"      // A session or CSRF token lives longer than recommended
+)
+
+const (
+	SecurityAuditMaxSessionExpiration = time.Hour * 24 * 30 // Longest session / bearer lifetime not reported
+	SecurityAuditMaxRememberMe        = time.Hour * 24 * 90 // Longest remember-me lifetime not reported
+	SecurityAuditMaxCsrfExpiration    = time.Hour * 24      // Longest CSRF token lifetime not reported
+	securityAuditRecommendedKeySize   = helpers.AESKeySize32
+)
+
+// stateChangingMethods are the methods checked by SecurityCheckCsrfDisabled.
+var stateChangingMethods = []string{http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+// SecurityFinding is a single issue found by SecurityAudit.
+type SecurityFinding struct {
+	Severity SecuritySeverity `json:"severity"`
+	Check    string           `json:"check"`
+	Route    string           `json:"route,omitempty"` // Method and path, e.g., "POST /orders", empty for the session manager
+	Message  string           `json:"message"`
+	Fix      string           `json:"fix"`
+}
+
+func (f SecurityFinding) String() string {
+	if f.Route != "" {
+		return fmt.Sprintf("[%s] %s: %s: %s (fix: %s)", f.Severity, f.Check, f.Route, f.Message, f.Fix)
+	}
+	return fmt.Sprintf("[%s] %s: %s (fix: %s)", f.Severity, f.Check, f.Message, f.Fix)
+}
+
+// SecurityReport holds the findings of SecurityAudit, in the order they were found.
+type SecurityReport struct {
+	Findings []SecurityFinding `json:"findings"`
+}
+
+func (r *SecurityReport) add(severity SecuritySeverity, check string, route string, message string, fix string) {
+	r.Findings = append(r.Findings, SecurityFinding{Severity: severity, Check: check, Route: route, Message: message, Fix: fix})
+}
+
+// Critical returns the SecuritySeverityCritical findings.
+func (r *SecurityReport) Critical() []SecurityFinding {
+	var critical []SecurityFinding
+	for _, finding := range r.Findings {
+		if finding.Severity == SecuritySeverityCritical {
+			critical = append(critical, finding)
+		}
+	}
+	return critical
+}
+
+// Log logs every finding, critical ones as errors and the others as warnings.
+func (r *SecurityReport) Log() {
+	for _, finding := range r.Findings {
+		fields := []zap.Field{zap.String("check", finding.Check), zap.String("route", finding.Route), zap.String("fix", finding.Fix)}
+		if finding.Severity == SecuritySeverityCritical {
+			zap.L().Error(finding.Message, fields...)
+		} else {
+			zap.L().Warn(finding.Message, fields...)
+		}
+	}
+}
+
+// Err returns an error listing the critical findings, nil when there are none.
+func (r *SecurityReport) Err() error {
+	critical := r.Critical()
+	if len(critical) == 0 {
+		return nil
+	}
+
+	messages := make([]string, len(critical))
+	for i, finding := range critical {
+		messages[i] = finding.String()
+	}
+	return fmt.Errorf("core: security audit failed with %d critical finding(s):\n%s", len(critical), strings.Join(messages, "\n"))
+}
+
+// SecurityAudit inspects the session manager and the routes for insecure settings at startup: invalid cookie
+// configurations, session keys shorter than 256 bits, cookies without Secure / HttpOnly, state changing routes
+// that skip the CSRF check, routes requiring roles or permissions without a session (anonymous requests skip the
+// RBAC check) and overly long expirations. It does not change anything, log or fail on the report:
+//
+//	report := core.SecurityAudit(sessionManager, ctor.Routes())
+//	report.Log()
+//	if err := report.Err(); err != nil {
+//		log.Fatal(err)
+//	}
+func SecurityAudit(sessionManager SessionManager, routes []RouteInfo) *SecurityReport {
+	report := &SecurityReport{}
+	if sessionManager == nil {
+		report.add(SecuritySeverityCritical, SecurityCheckConfiguration, "", "session manager is nil", "pass the session manager the routes are registered with")
+		return report
+	}
+
+	auditSessionManager(report, sessionManager)
+	csrfData := sessionManager.GetCsrfData()
+	for _, route := range routes {
+		auditRoute(report, csrfData, route)
+	}
+	return report
+}
+
+// SecurityAudit runs SecurityAudit on the constructor's session manager and the routes registered so far.
+func (ctor *RouteConstructor[BaseRoute]) SecurityAudit() *SecurityReport {
+	return SecurityAudit(ctor.sessionManager, ctor.Routes())
+}
+
+func auditSessionManager(report *SecurityReport, sessionManager SessionManager) {
+	if err := ValidateConfiguration(sessionManager); err != nil {
+		report.add(SecuritySeverityCritical, SecurityCheckConfiguration, "", err.Error(), "correct the cookie settings, see ValidateConfiguration")
+		return
+	}
+
+	// - Keys of an unsupported size fail every request, shorter AES keys still work
+	key, keyId, err := sessionManager.GetSessionKey()
+	switch {
+	case err != nil:
+		report.add(SecuritySeverityCritical, SecurityCheckKeySize, "", fmt.Sprintf("failed to get the session key: %v", err), "make GetSessionKey return the current key")
+	case len(key) != helpers.AESKeySize16 && len(key) != helpers.AESKeySize24 && len(key) != helpers.AESKeySize32:
+		report.add(SecuritySeverityCritical, SecurityCheckKeySize, "", fmt.Sprintf("session key '%s' is %d bytes, not a valid AES key size", keyId, len(key)), "use a 32 byte key, e.g., from helpers.GenerateSymmetricKey(helpers.AESKeySize32)")
+	case len(key) < securityAuditRecommendedKeySize:
+		report.add(SecuritySeverityWarning, SecurityCheckKeySize, "", fmt.Sprintf("session key '%s' is %d bytes, %d are recommended", keyId, len(key), securityAuditRecommendedKeySize), "rotate to a 32 byte key, keeping the current one in GetOldSessionKey")
+	}
+
+	authorizationData := sessionManager.GetAuthorizationConfiguration()
+	csrfData := sessionManager.GetCsrfData()
+	sessionCookie := sessionCookieConfig(authorizationData, helpers.DefaultString(authorizationData.CookieName, DefaultSessionAuthorizationName), "", 0)
+	if !sessionCookie.Secure {
+		report.add(SecuritySeverityCritical, SecurityCheckInsecureCookie, "", "session cookie is sent without Secure", "set CookieSecure, sessions are readable on plain HTTP")
+	}
+	if !sessionCookie.HttpOnly {
+		report.add(SecuritySeverityCritical, SecurityCheckInsecureCookie, "", "session cookie is sent without HttpOnly", "set CookieHttpOnly, sessions are readable by injected scripts")
+	}
+	if strings.EqualFold(sessionCookie.SameSite, "None") {
+		report.add(SecuritySeverityWarning, SecurityCheckInsecureCookie, "", "session cookie is sent with SameSite=None", "use Lax or Strict unless the session is embedded cross-site, CSRF tokens are then the only protection")
+	}
+	if !csrfCookieConfig(csrfData, authorizationData.HostOnlyCookies, "", 0).Secure {
+		report.add(SecuritySeverityCritical, SecurityCheckInsecureCookie, "", "CSRF cookie is sent without Secure", "set CsrfCookieData.Secure")
+	}
+
+	if expiration := helpers.DefaultTimeDuration(authorizationData.Expiration, DefaultSessionExpiration); expiration > SecurityAuditMaxSessionExpiration {
+		report.add(SecuritySeverityWarning, SecurityCheckLongExpiration, "", fmt.Sprintf("sessions and bearer tokens expire after %s", expiration), fmt.Sprintf("keep Expiration at most %s, use remember-me for long logins", SecurityAuditMaxSessionExpiration))
+	}
+	if expiration := helpers.DefaultTimeDuration(authorizationData.RememberMeExpiration, DefaultRememberMeExpiration); authorizationData.RememberMeCookieName != "" && expiration > SecurityAuditMaxRememberMe {
+		report.add(SecuritySeverityWarning, SecurityCheckLongExpiration, "", fmt.Sprintf("remember-me tokens expire after %s", expiration), fmt.Sprintf("keep RememberMeExpiration at most %s", SecurityAuditMaxRememberMe))
+	}
+	if expiration := helpers.DefaultTimeDuration(csrfData.Expiration, DefaultCsrfExpiration); expiration > SecurityAuditMaxCsrfExpiration {
+		report.add(SecuritySeverityWarning, SecurityCheckLongExpiration, "", fmt.Sprintf("CSRF tokens expire after %s", expiration), fmt.Sprintf("keep CsrfCookieData.Expiration at most %s", SecurityAuditMaxCsrfExpiration))
+	}
+}
+
+func auditRoute(report *SecurityReport, csrfData *CsrfCookieData, route RouteInfo) {
+	config := route.Config
+	if config == nil {
+		return
+	}
+	name := route.Method + " " + route.Path
+
+	hasRbac := config.Roles != nil || config.Permissions != nil || len(config.NamedPermissions) > 0
+	if hasRbac && !config.SessionRequired {
+		report.add(SecuritySeverityCritical, SecurityCheckRbacWithoutSession, name, "route requires roles or permissions but no session, anonymous requests skip the RBAC check", "set SessionRequired, e.g., with AuthenticatedJSONAPI()")
+	}
+
+	// - Routes without a session have nothing to forge
+	if !config.SessionRequired && !hasRbac {
+		return
+	}
+	if slices.Contains(stateChangingMethods, route.Method) && (!config.RequireCsrf || csrfExempt(csrfData, config, route.Method)) {
+		report.add(SecuritySeverityWarning, SecurityCheckCsrfDisabled, name, "state changing route accepts cookie sessions without a CSRF token", "set RequireCsrf (and drop the method from ExemptMethods), or only accept bearer tokens on the route")
+	}
+}
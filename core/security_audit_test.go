@@ -0,0 +1,132 @@
+package core
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/errors"
+)
+
+func findingsOf(report *SecurityReport, check string) []SecurityFinding {
+	var findings []SecurityFinding
+	for _, finding := range report.Findings {
+		if finding.Check == check {
+			findings = append(findings, finding)
+		}
+	}
+	return findings
+}
+
+func TestSecurityAuditDefaults(t *testing.T) {
+	manager := newMockSessionManager(t)
+	routes := []RouteInfo{
+		{Method: http.MethodPost, Path: "/orders", Config: AuthenticatedJSONAPI()},
+		{Method: http.MethodGet, Path: "/orders", Config: AuthenticatedJSONAPI().WithoutCsrf()},
+		{Method: http.MethodPost, Path: "/login", Config: PublicRoute()},
+	}
+
+	report := SecurityAudit(manager, routes)
+	if len(report.Findings) != 0 {
+		t.Fatalf("Expected no findings for the defaults, got %v", report.Findings)
+	}
+	if err := report.Err(); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+}
+
+func TestSecurityAuditKeySize(t *testing.T) {
+	tests := []struct {
+		name     string
+		size     int
+		severity SecuritySeverity
+	}{
+		{"AES-128", 16, SecuritySeverityWarning},
+		{"Invalid", 20, SecuritySeverityCritical},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager := newMockSessionManager(t)
+			manager.keys[manager.currentKeyId] = make([]byte, tt.size)
+
+			findings := findingsOf(SecurityAudit(manager, nil), SecurityCheckKeySize)
+			if len(findings) != 1 || findings[0].Severity != tt.severity {
+				t.Fatalf("Expected one %s key size finding, got %v", tt.severity, findings)
+			}
+		})
+	}
+}
+
+func TestSecurityAuditRoutes(t *testing.T) {
+	manager := newMockSessionManager(t)
+	routes := []RouteInfo{
+		{Method: http.MethodDelete, Path: "/orders/:id", Config: AuthenticatedJSONAPI().WithoutCsrf()},
+		{Method: http.MethodPost, Path: "/search", Config: AuthenticatedJSONAPI().WithCsrfExemptMethods(http.MethodPost)},
+		{Method: http.MethodGet, Path: "/admin", Config: PublicRoute().WithRoles("admin")},
+	}
+
+	report := SecurityAudit(manager, routes)
+	csrf := findingsOf(report, SecurityCheckCsrfDisabled)
+	if len(csrf) != 2 || csrf[0].Route != "DELETE /orders/:id" || csrf[1].Route != "POST /search" {
+		t.Errorf("Expected CSRF findings for both state changing routes, got %v", csrf)
+	}
+
+	rbacFindings := findingsOf(report, SecurityCheckRbacWithoutSession)
+	if len(rbacFindings) != 1 || rbacFindings[0].Severity != SecuritySeverityCritical || rbacFindings[0].Route != "GET /admin" {
+		t.Fatalf("Expected a critical RBAC finding for GET /admin, got %v", rbacFindings)
+	}
+
+	err := report.Err()
+	if err == nil || !strings.Contains(err.Error(), "GET /admin") || strings.Contains(err.Error(), "/search") {
+		t.Errorf("Expected the error to only list the critical finding, got %v", err)
+	}
+}
+
+func TestSecurityAuditExpirations(t *testing.T) {
+	manager := newMockSessionManager(t)
+	manager.authorizationData.Expiration = 90 * 24 * time.Hour
+	manager.authorizationData.RememberMeCookieName = "remember"
+	manager.authorizationData.RememberMeExpiration = 365 * 24 * time.Hour
+	manager.csrfData.Expiration = 7 * 24 * time.Hour
+
+	findings := findingsOf(SecurityAudit(manager, nil), SecurityCheckLongExpiration)
+	if len(findings) != 3 {
+		t.Fatalf("Expected the session, remember-me and CSRF expirations to be reported, got %v", findings)
+	}
+	for _, finding := range findings {
+		if finding.Severity != SecuritySeverityWarning || finding.Fix == "" {
+			t.Errorf("Expected an actionable warning, got %v", finding)
+		}
+	}
+}
+
+func TestSecurityAuditConfiguration(t *testing.T) {
+	manager := newMockSessionManager(t)
+	manager.authorizationData.CookieSameSite = "Sideways"
+
+	report := SecurityAudit(manager, nil)
+	if findings := findingsOf(report, SecurityCheckConfiguration); len(findings) != 1 {
+		t.Fatalf("Expected a configuration finding, got %v", report.Findings)
+	}
+	if report.Err() == nil {
+		t.Error("Expected an invalid configuration to fail the audit")
+	}
+	if SecurityAudit(nil, nil).Err() == nil {
+		t.Error("Expected a nil session manager to fail the audit")
+	}
+}
+
+func TestRouteConstructorSecurityAudit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctor := NewRouteConstructor(gin.New(), testBaseRoute{}, newMockSessionManager(t), nil)
+	POST(ctor, "/notes", AuthenticatedJSONAPI().WithoutCsrf(), func(_ *struct{}, _ *Handler[testBaseRoute]) (*struct{}, *errors.AppError) {
+		return &struct{}{}, nil
+	})
+
+	findings := findingsOf(ctor.SecurityAudit(), SecurityCheckCsrfDisabled)
+	if len(findings) != 1 || findings[0].Route != "POST /notes" {
+		t.Errorf("Expected the registered route to be audited, got %v", findings)
+	}
+}
@@ -0,0 +1,154 @@
+package core
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"net"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/errors"
+	"github.com/grzegorzmaniak/gothic/helpers"
+	"go.uber.org/zap"
+)
+
+// BindingPolicy is what happens when a session is used from another client than it was issued to.
+type BindingPolicy int
+
+const (
+	// BindingDisabled neither stores nor checks the binding (Default)
+	BindingDisabled BindingPolicy = iota
+
+	// BindingLogOnly logs a warning on a mismatch but accepts the request, useful to measure false positives
+	BindingLogOnly
+
+	// BindingStrict treats the session as invalid on a mismatch
+	BindingStrict
+)
+
+const (
+	BindingIPClaim        = "___bi" // Hash of the client IP prefix the session was issued to
+	BindingUserAgentClaim = "___bu" // Hash of the User-Agent the session was issued to
+
+	DefaultBindingIPv4Prefix = 24 // Tolerates NAT pools and DHCP churn within a /24
+	DefaultBindingIPv6Prefix = 64 // A single IPv6 subnet, privacy extensions rotate the host part
+	bindingHashSize          = 16
+)
+
+// SessionBinding ties sessions to the client they were issued to, so a stolen cookie or bearer can not be
+// replayed from elsewhere. The IP prefix and User-Agent are stored as salted hashes in the claims when the
+// session is issued, and compared on every request. Sessions issued without a binding are not checked.
+type SessionBinding struct {
+	// Policy enables the binding (Default: BindingDisabled)
+	Policy BindingPolicy
+
+	// IPv4Prefix is the number of leading bits of an IPv4 address that must match (Default: DefaultBindingIPv4Prefix)
+	IPv4Prefix int
+
+	// IPv6Prefix is the number of leading bits of an IPv6 address that must match (Default: DefaultBindingIPv6Prefix)
+	IPv6Prefix int
+
+	// SkipIP does not bind the IP address, e.g., for mobile clients roaming between networks
+	SkipIP bool
+
+	// SkipUserAgent does not bind the User-Agent
+	SkipUserAgent bool
+}
+
+// bindingIPPrefix masks the client IP to the configured prefix, an unparsable IP is used as is.
+func bindingIPPrefix(binding *SessionBinding, clientIP string) string {
+	ip := net.ParseIP(clientIP)
+	if ip == nil {
+		return clientIP
+	}
+	if ipv4 := ip.To4(); ipv4 != nil {
+		prefix := helpers.DefaultInt(binding.IPv4Prefix, DefaultBindingIPv4Prefix)
+		return ipv4.Mask(net.CIDRMask(min(prefix, 32), 32)).String()
+	}
+	prefix := helpers.DefaultInt(binding.IPv6Prefix, DefaultBindingIPv6Prefix)
+	return ip.Mask(net.CIDRMask(min(prefix, 128), 128)).String()
+}
+
+// bindingHash salts the value with the session identifier, so equal prefixes do not produce equal claims
+// across sessions. Signed tokens are readable, the hash keeps the address out of the clear text.
+func bindingHash(sessionId string, kind string, value string) string {
+	sum := sha256.Sum256([]byte(sessionId + "\x00" + kind + "\x00" + value))
+	return base64.RawURLEncoding.EncodeToString(sum[:bindingHashSize])
+}
+
+// bindSession stores the binding claims for the requesting client, it is called when a session is issued.
+func bindSession(ctx *gin.Context, authorizationData *SessionAuthorizationConfiguration, claims *SessionClaims) error {
+	binding := &authorizationData.Binding
+	if binding.Policy == BindingDisabled || ctx == nil || ctx.Request == nil {
+		return nil
+	}
+
+	// - The hash is salted with the session identifier, so generate it ahead of ensureBasicClaims
+	if !claims.HasClaim(SessionIdentifier) {
+		sessionId, err := helpers.GenerateID(helpers.AESKeySize32)
+		if err != nil {
+			return fmt.Errorf("failed to generate session ID: %w", err)
+		}
+		claims.SetClaim(SessionIdentifier, sessionId)
+	}
+	sessionId, _ := claims.GetClaim(SessionIdentifier)
+
+	if !binding.SkipIP {
+		claims.SetClaim(BindingIPClaim, bindingHash(sessionId, "ip", bindingIPPrefix(binding, ctx.ClientIP())))
+	}
+	if !binding.SkipUserAgent {
+		claims.SetClaim(BindingUserAgentClaim, bindingHash(sessionId, "ua", ctx.Request.UserAgent()))
+	}
+	return nil
+}
+
+// checkSessionBinding compares the binding claims with the requesting client, the IP and User-Agent are only
+// compared when the session carries their claim.
+func checkSessionBinding(ctx *gin.Context, authorizationData *SessionAuthorizationConfiguration, claims *SessionClaims) error {
+	binding := &authorizationData.Binding
+	sessionId, _ := claims.GetClaim(SessionIdentifier)
+
+	if expected, ok := claims.GetClaim(BindingIPClaim); ok && !binding.SkipIP {
+		actual := bindingHash(sessionId, "ip", bindingIPPrefix(binding, ctx.ClientIP()))
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(actual)) != 1 {
+			return fmt.Errorf("client IP does not match the session binding")
+		}
+	}
+
+	if expected, ok := claims.GetClaim(BindingUserAgentClaim); ok && !binding.SkipUserAgent {
+		actual := bindingHash(sessionId, "ua", ctx.Request.UserAgent())
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(actual)) != 1 {
+			return fmt.Errorf("user agent does not match the session binding")
+		}
+	}
+	return nil
+}
+
+// processSessionBinding applies the binding policy to an extracted session. Under BindingStrict a mismatched
+// session is rejected when the route requires one, otherwise the request continues without it.
+func processSessionBinding(
+	ctx *gin.Context,
+	sessionManager SessionManager,
+	sessionConfig *APIConfiguration,
+	claims *SessionClaims,
+) (bool, *errors.AppError) {
+	authorizationData := sessionManager.GetAuthorizationConfiguration()
+	if claims == nil || authorizationData == nil || authorizationData.Binding.Policy == BindingDisabled {
+		return true, nil
+	}
+
+	err := checkSessionBinding(ctx, authorizationData, claims)
+	if err == nil {
+		return true, nil
+	}
+
+	helpers.Logger(ctx).Warn("Session used from another client", zap.Error(err), zap.String("ip", ctx.ClientIP()))
+	if authorizationData.Binding.Policy == BindingLogOnly {
+		return true, nil
+	}
+	if sessionConfig.SessionRequired {
+		return false, errors.NewUnauthorized("", err)
+	}
+	return false, nil
+}
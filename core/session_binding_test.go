@@ -0,0 +1,101 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestBindingIPPrefix(t *testing.T) {
+	binding := &SessionBinding{}
+	tests := map[string]string{
+		"203.0.113.77":            "203.0.113.0",
+		"2001:db8:1:2:3:4:5:6":    "2001:db8:1:2::",
+		"::ffff:203.0.113.77":     "203.0.113.0",
+		"not-an-ip":               "not-an-ip",
+		"2001:db8:1:2:ffff::1234": "2001:db8:1:2::",
+	}
+	for ip, expected := range tests {
+		if prefix := bindingIPPrefix(binding, ip); prefix != expected {
+			t.Errorf("bindingIPPrefix(%q) = %q, expected %q", ip, prefix, expected)
+		}
+	}
+
+	if prefix := bindingIPPrefix(&SessionBinding{IPv4Prefix: 32}, "203.0.113.77"); prefix != "203.0.113.77" {
+		t.Errorf("Expected a /32 prefix to keep the address, got %q", prefix)
+	}
+}
+
+func TestSessionBinding(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mgr := newMockSessionManager(t)
+	mgr.authorizationData.Binding = SessionBinding{Policy: BindingStrict}
+
+	newContext := func(remoteAddr string, userAgent string, token string) *gin.Context {
+		ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+		ctx.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+		ctx.Request.RemoteAddr = remoteAddr
+		ctx.Request.Header.Set("User-Agent", userAgent)
+		if token != "" {
+			ctx.Request.Header.Set(DefaultSessionAuthorizationHeaderName, token)
+		}
+		return ctx
+	}
+
+	token, err := IssueBearerToken(newContext("203.0.113.10:1234", "browser/1.0", ""), mgr, "default",
+		&SessionClaims{Claims: map[string]string{"subject": "user-1"}})
+	if err != nil {
+		t.Fatalf("Failed to issue the bearer token: %v", err)
+	}
+
+	required := &APIConfiguration{SessionRequired: true}
+	tests := []struct {
+		name       string
+		remoteAddr string
+		userAgent  string
+		wantErr    bool
+	}{
+		{"Same client", "203.0.113.10:1234", "browser/1.0", false},
+		{"Same IP prefix", "203.0.113.99:4321", "browser/1.0", false},
+		{"Other network", "198.51.100.10:1234", "browser/1.0", true},
+		{"Other User-Agent", "203.0.113.10:1234", "curl/8.0", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, claims, _, _, appErr := _establishSessionContext(newContext(tt.remoteAddr, tt.userAgent, token), mgr, required)
+			if (appErr != nil) != tt.wantErr {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, appErr)
+			}
+			if !tt.wantErr && (claims == nil || !claims.HasClaim(BindingIPClaim) || !claims.HasClaim(BindingUserAgentClaim)) {
+				t.Errorf("Expected the binding claims on the session")
+			}
+		})
+	}
+
+	t.Run("Optional sessions continue without the session", func(t *testing.T) {
+		_, claims, _, _, appErr := _establishSessionContext(newContext("198.51.100.10:1234", "browser/1.0", token), mgr, &APIConfiguration{})
+		if appErr != nil || claims != nil {
+			t.Errorf("Expected the request to continue anonymously, got %v / %v", claims, appErr)
+		}
+	})
+
+	t.Run("Log only accepts the mismatch", func(t *testing.T) {
+		mgr.authorizationData.Binding.Policy = BindingLogOnly
+		defer func() { mgr.authorizationData.Binding.Policy = BindingStrict }()
+
+		if _, _, _, _, appErr := _establishSessionContext(newContext("198.51.100.10:1234", "curl/8.0", token), mgr, required); appErr != nil {
+			t.Errorf("Expected the request to be accepted, got %v", appErr)
+		}
+	})
+
+	t.Run("Skipped parts are not compared", func(t *testing.T) {
+		mgr.authorizationData.Binding.SkipUserAgent = true
+		defer func() { mgr.authorizationData.Binding.SkipUserAgent = false }()
+
+		if _, _, _, _, appErr := _establishSessionContext(newContext("203.0.113.10:1234", "curl/8.0", token), mgr, required); appErr != nil {
+			t.Errorf("Expected the User-Agent to be ignored, got %v", appErr)
+		}
+	})
+}
@@ -3,9 +3,15 @@ package core
 import (
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strconv"
+	"time"
 )
 
+// ErrClaimNotFound is returned by the typed claim getters when the claim is not set.
+var ErrClaimNotFound = errors.New("claim not found")
+
 type SessionClaims struct {
 	// Claims is a map of claims that are stored in the session, please use the
 	// SetClaim and GetClaim methods to set and get claims.
@@ -48,6 +54,83 @@ func (d *SessionClaims) SetIfNotSet(claim string, value string) {
 	}
 }
 
+// GetInt returns the claim parsed as a base 10 integer.
+func (d *SessionClaims) GetInt(claim string) (int64, error) {
+	value, ok := d.GetClaim(claim)
+	if !ok {
+		return 0, fmt.Errorf("claim '%s': %w", claim, ErrClaimNotFound)
+	}
+	parsed, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("claim '%s' is not an integer: %w", claim, err)
+	}
+	return parsed, nil
+}
+
+// GetBool returns the claim parsed with strconv.ParseBool.
+func (d *SessionClaims) GetBool(claim string) (bool, error) {
+	value, ok := d.GetClaim(claim)
+	if !ok {
+		return false, fmt.Errorf("claim '%s': %w", claim, ErrClaimNotFound)
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("claim '%s' is not a boolean: %w", claim, err)
+	}
+	return parsed, nil
+}
+
+// GetTime returns the claim parsed as unix seconds (as written by SetTime), or as RFC 3339.
+func (d *SessionClaims) GetTime(claim string) (time.Time, error) {
+	value, ok := d.GetClaim(claim)
+	if !ok {
+		return time.Time{}, fmt.Errorf("claim '%s': %w", claim, ErrClaimNotFound)
+	}
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0), nil
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("claim '%s' is not a time: %w", claim, err)
+	}
+	return parsed, nil
+}
+
+// GetJSON unmarshals the claim into the target.
+func (d *SessionClaims) GetJSON(claim string, target interface{}) error {
+	value, ok := d.GetClaim(claim)
+	if !ok {
+		return fmt.Errorf("claim '%s': %w", claim, ErrClaimNotFound)
+	}
+	if err := json.Unmarshal([]byte(value), target); err != nil {
+		return fmt.Errorf("claim '%s' is not valid JSON: %w", claim, err)
+	}
+	return nil
+}
+
+func (d *SessionClaims) SetInt(claim string, value int64) {
+	d.SetClaim(claim, strconv.FormatInt(value, 10))
+}
+
+func (d *SessionClaims) SetBool(claim string, value bool) {
+	d.SetClaim(claim, strconv.FormatBool(value))
+}
+
+// SetTime stores the time as unix seconds, which keeps the cookie small.
+func (d *SessionClaims) SetTime(claim string, value time.Time) {
+	d.SetClaim(claim, strconv.FormatInt(value.Unix(), 10))
+}
+
+// SetJSON stores the value marshaled as JSON.
+func (d *SessionClaims) SetJSON(claim string, value interface{}) error {
+	marshaled, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal claim '%s': %w", claim, err)
+	}
+	d.SetClaim(claim, string(marshaled))
+	return nil
+}
+
 func (d *SessionClaims) EncodePayload() (string, error) {
 	jsonBytes, err := json.Marshal(d.Claims)
 	if err != nil {
@@ -57,16 +140,30 @@ func (d *SessionClaims) EncodePayload() (string, error) {
 	return encoded, nil
 }
 
+// DecodePayload decodes a payload written by EncodePayload or EncodeCompressedPayload.
 func (d *SessionClaims) DecodePayload(payload string) error {
-	decoded, err := base64.RawURLEncoding.DecodeString(payload)
+	_, err := d.decodePayload(stringBytes(payload), nil)
+	return err
+}
+
+// decodePayload decodes an encoded payload, using (and returning) scratch for the base64 decoding.
+func (d *SessionClaims) decodePayload(payload []byte, scratch []byte) ([]byte, error) {
+	decoded, compressed, err := decompressPayload(payload)
 	if err != nil {
-		return fmt.Errorf("failed to decode payload: %w", err)
+		return scratch, err
+	}
+	if !compressed {
+		scratch, err = decodeBase64Into(scratch, payload)
+		if err != nil {
+			return scratch, fmt.Errorf("failed to decode payload: %w", err)
+		}
+		decoded = scratch
 	}
 
 	err = json.Unmarshal(decoded, &d.Claims)
 	if err != nil {
-		return fmt.Errorf("failed to unmarshal claims: %w", err)
+		return scratch, fmt.Errorf("failed to unmarshal claims: %w", err)
 	}
 
-	return nil
+	return scratch, nil
 }
@@ -1,8 +1,10 @@
 package core
 
 import (
+	"errors"
 	"reflect"
 	"testing"
+	"time"
 )
 
 // TestSessionClaims_HasClaim tests the HasClaim method.
@@ -112,3 +114,47 @@ func TestSessionClaims_DecodePayload_Errors(t *testing.T) {
 		t.Error("Expected an error for invalid json, but got nil")
 	}
 }
+
+// TestSessionClaims_TypedAccessors tests the typed getters and setters.
+func TestSessionClaims_TypedAccessors(t *testing.T) {
+	claims := &SessionClaims{}
+	claims.SetInt("age", 42)
+	claims.SetBool("admin", true)
+	claims.SetTime("verified_at", time.Unix(1700000000, 0))
+	if err := claims.SetJSON("tenants", []string{"a", "b"}); err != nil {
+		t.Fatalf("Failed to set JSON claim: %v", err)
+	}
+
+	if age, err := claims.GetInt("age"); err != nil || age != 42 {
+		t.Errorf("Expected 42, got %d (%v)", age, err)
+	}
+	if admin, err := claims.GetBool("admin"); err != nil || !admin {
+		t.Errorf("Expected true, got %v (%v)", admin, err)
+	}
+	if verifiedAt, err := claims.GetTime("verified_at"); err != nil || verifiedAt.Unix() != 1700000000 {
+		t.Errorf("Expected 1700000000, got %v (%v)", verifiedAt, err)
+	}
+	var tenants []string
+	if err := claims.GetJSON("tenants", &tenants); err != nil || !reflect.DeepEqual(tenants, []string{"a", "b"}) {
+		t.Errorf("Expected [a b], got %v (%v)", tenants, err)
+	}
+
+	claims.SetClaim("rfc3339", "2024-01-02T03:04:05Z")
+	if parsed, err := claims.GetTime("rfc3339"); err != nil || parsed.Year() != 2024 {
+		t.Errorf("Expected an RFC 3339 time to parse, got %v (%v)", parsed, err)
+	}
+
+	if _, err := claims.GetInt("missing"); !errors.Is(err, ErrClaimNotFound) {
+		t.Errorf("Expected ErrClaimNotFound, got %v", err)
+	}
+	claims.SetClaim("corrupt", "not-a-number")
+	if _, err := claims.GetInt("corrupt"); err == nil || errors.Is(err, ErrClaimNotFound) {
+		t.Errorf("Expected a parse error, got %v", err)
+	}
+	if _, err := claims.GetBool("corrupt"); err == nil {
+		t.Error("Expected a parse error for a boolean")
+	}
+	if err := claims.GetJSON("corrupt", &tenants); err == nil {
+		t.Error("Expected a parse error for JSON")
+	}
+}
@@ -0,0 +1,172 @@
+package core
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/errors"
+	"github.com/grzegorzmaniak/gothic/helpers"
+	"go.uber.org/zap"
+)
+
+// SessionEventType identifies a SessionEvent.
+type SessionEventType string
+
+const (
+	SessionEventIssued      SessionEventType = "issued"       // A session cookie or bearer token was issued
+	SessionEventRefreshed   SessionEventType = "refreshed"    // A session was re-issued with its original expiration
+	SessionEventRejected    SessionEventType = "rejected"     // A presented session was invalid, expired or revoked
+	SessionEventCsrfFailure SessionEventType = "csrf_failure" // A request failed the CSRF check
+	SessionEventRbacDenied  SessionEventType = "rbac_denied"  // A session lacked the permissions or roles of a route
+	SessionEventPanic       SessionEventType = "panic"        // A route panicked, see SessionPanicListener
+
+	DefaultSessionEventTimeout = 10 * time.Second
+)
+
+// csrfFailureMessage is the client message of every CSRF rejection, it also tells them apart from other rejections.
+const csrfFailureMessage = "CSRF token is invalid or expired"
+
+// SessionEvent describes a security relevant event of a session, it is captured on the request and delivered
+// to the SessionEventListener in the background.
+type SessionEvent struct {
+	Type      SessionEventType `json:"type"`
+	Subject   string           `json:"subject,omitempty"`
+	Group     string           `json:"group,omitempty"`
+	SessionID string           `json:"session_id,omitempty"`
+	IP        string           `json:"ip,omitempty"`
+	UserAgent string           `json:"user_agent,omitempty"`
+	Method    string           `json:"method,omitempty"`
+	Path      string           `json:"path,omitempty"`
+	RequestID string           `json:"request_id,omitempty"`
+
+	// Reason is the cause of rejections and denials, it is meant for logs and may contain internal details.
+	Reason string    `json:"reason,omitempty"`
+	At     time.Time `json:"at"`
+}
+
+// SessionEventListener receives session events, e.g., to feed a SIEM or to alert on anomalies. The callbacks
+// run in the background, outside the request, and are bounded by DefaultSessionEventTimeout. Embed
+// BaseSessionEventListener to only implement some of them.
+type SessionEventListener interface {
+	OnIssued(ctx context.Context, event SessionEvent)
+	OnRefreshed(ctx context.Context, event SessionEvent)
+	OnRejected(ctx context.Context, event SessionEvent)
+	OnCsrfFailure(ctx context.Context, event SessionEvent)
+	OnRbacDenied(ctx context.Context, event SessionEvent)
+}
+
+// SessionPanicListener is implemented by listeners that want to hear about panicking routes, the event's
+// RequestID is the correlation ID sent to the client.
+type SessionPanicListener interface {
+	OnPanic(ctx context.Context, event SessionEvent)
+}
+
+// BaseSessionEventListener ignores every event.
+type BaseSessionEventListener struct{}
+
+func (BaseSessionEventListener) OnIssued(context.Context, SessionEvent)      {}
+func (BaseSessionEventListener) OnRefreshed(context.Context, SessionEvent)   {}
+func (BaseSessionEventListener) OnRejected(context.Context, SessionEvent)    {}
+func (BaseSessionEventListener) OnCsrfFailure(context.Context, SessionEvent) {}
+func (BaseSessionEventListener) OnRbacDenied(context.Context, SessionEvent)  {}
+func (BaseSessionEventListener) OnPanic(context.Context, SessionEvent)       {}
+
+var sessionEvents = struct {
+	sync.RWMutex
+	listener SessionEventListener
+}{}
+
+// SetSessionEventListener sets the listener of every session manager, nil disables it.
+func SetSessionEventListener(listener SessionEventListener) {
+	sessionEvents.Lock()
+	defer sessionEvents.Unlock()
+	sessionEvents.listener = listener
+}
+
+func getSessionEventListener() SessionEventListener {
+	sessionEvents.RLock()
+	defer sessionEvents.RUnlock()
+	return sessionEvents.listener
+}
+
+// publishSessionEvent captures the event from the request and delivers it in the background, it outlives the
+// request but not the session manager's shutdown.
+func publishSessionEvent(
+	ctx *gin.Context,
+	sessionManager SessionManager,
+	eventType SessionEventType,
+	claims *SessionClaims,
+	reason string,
+) {
+	listener := getSessionEventListener()
+	if listener == nil || ctx == nil || ctx.Request == nil {
+		return
+	}
+
+	event := SessionEvent{
+		Type:      eventType,
+		IP:        ctx.ClientIP(),
+		UserAgent: ctx.Request.UserAgent(),
+		Method:    ctx.Request.Method,
+		Path:      ctx.Request.URL.Path,
+		RequestID: helpers.GetRequestID(ctx),
+		Reason:    reason,
+		At:        time.Now(),
+	}
+	if claims != nil && claims.Claims != nil {
+		event.Group, _ = claims.GetClaim(SessionModeClaim)
+		event.SessionID, _ = claims.GetClaim(SessionIdentifier)
+		if subject, err := sessionManager.GetSubjectIdentifier(claims); err == nil {
+			event.Subject = subject
+		}
+	}
+
+	requestCtx := context.WithoutCancel(ctx.Request.Context())
+	started := helpers.LifecycleOf(sessionManager).Go(func(backgroundCtx context.Context) {
+		eventCtx, cancel := context.WithTimeout(requestCtx, DefaultSessionEventTimeout)
+		defer cancel()
+		stop := context.AfterFunc(backgroundCtx, cancel)
+		defer stop()
+
+		switch eventType {
+		case SessionEventIssued:
+			listener.OnIssued(eventCtx, event)
+		case SessionEventRefreshed:
+			listener.OnRefreshed(eventCtx, event)
+		case SessionEventRejected:
+			listener.OnRejected(eventCtx, event)
+		case SessionEventCsrfFailure:
+			listener.OnCsrfFailure(eventCtx, event)
+		case SessionEventRbacDenied:
+			listener.OnRbacDenied(eventCtx, event)
+		case SessionEventPanic:
+			if panicListener, ok := listener.(SessionPanicListener); ok {
+				panicListener.OnPanic(eventCtx, event)
+			}
+		}
+	})
+	if !started {
+		helpers.Logger(ctx).Warn("Session event dropped, session manager is shutting down", zap.String("type", string(eventType)))
+	}
+}
+
+// publishSessionRejection reports a failed session context as a CSRF failure or a rejection, internal errors
+// are not reported.
+func publishSessionRejection(ctx *gin.Context, sessionManager SessionManager, claims *SessionClaims, appErr *errors.AppError) {
+	if appErr == nil || appErr.Code >= 500 {
+		return
+	}
+
+	reason := appErr.Message
+	if appErr.Err != nil {
+		reason = appErr.Err.Error()
+	}
+
+	if appErr.Message == csrfFailureMessage {
+		publishSessionEvent(ctx, sessionManager, SessionEventCsrfFailure, claims, reason)
+		return
+	}
+	publishSessionEvent(ctx, sessionManager, SessionEventRejected, claims, reason)
+}
@@ -0,0 +1,119 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	internalcache "github.com/grzegorzmaniak/gothic/cache"
+	"github.com/grzegorzmaniak/gothic/rbac"
+)
+
+// recordingListener forwards every event to a channel.
+type recordingListener struct {
+	events chan SessionEvent
+}
+
+func (l *recordingListener) OnIssued(_ context.Context, event SessionEvent)      { l.events <- event }
+func (l *recordingListener) OnRefreshed(_ context.Context, event SessionEvent)   { l.events <- event }
+func (l *recordingListener) OnRejected(_ context.Context, event SessionEvent)    { l.events <- event }
+func (l *recordingListener) OnCsrfFailure(_ context.Context, event SessionEvent) { l.events <- event }
+func (l *recordingListener) OnRbacDenied(_ context.Context, event SessionEvent)  { l.events <- event }
+
+func (l *recordingListener) next(t *testing.T, expected SessionEventType) SessionEvent {
+	t.Helper()
+	select {
+	case event := <-l.events:
+		if event.Type != expected {
+			t.Fatalf("Expected a %q event, got %q", expected, event.Type)
+		}
+		return event
+	case <-time.After(time.Second):
+		t.Fatalf("Expected a %q event, got none", expected)
+		return SessionEvent{}
+	}
+}
+
+func TestSessionEvents(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	listener := &recordingListener{events: make(chan SessionEvent, 16)}
+	SetSessionEventListener(listener)
+	defer SetSessionEventListener(nil)
+
+	mgr := newMockSessionManager(t)
+	newContext := func(token string) (*gin.Context, *httptest.ResponseRecorder) {
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+		ctx.Request = httptest.NewRequest(http.MethodPost, "/orders", nil)
+		ctx.Request.Header.Set("User-Agent", "browser/1.0")
+		if token != "" {
+			ctx.Request.Header.Set(DefaultSessionAuthorizationHeaderName, token)
+		}
+		return ctx, recorder
+	}
+
+	ctx, _ := newContext("")
+	token, err := IssueBearerToken(ctx, mgr, "default", &SessionClaims{Claims: map[string]string{"subject": "user-1"}})
+	if err != nil {
+		t.Fatalf("Failed to issue the bearer token: %v", err)
+	}
+	issued := listener.next(t, SessionEventIssued)
+	if issued.Subject != "user-1" || issued.Group != "default" || issued.SessionID == "" || issued.UserAgent != "browser/1.0" {
+		t.Errorf("Unexpected issued event %+v", issued)
+	}
+
+	t.Run("Rejected sessions", func(t *testing.T) {
+		mgr.verifySession = false
+		defer func() { mgr.verifySession = true }()
+
+		ctx, _ := newContext(token)
+		if _, _, _, _, appErr := _establishSessionContext(ctx, mgr, &APIConfiguration{SessionRequired: true}); appErr == nil {
+			t.Fatal("Expected the session to be rejected")
+		}
+		if event := listener.next(t, SessionEventRejected); event.Subject != "user-1" || event.Path != "/orders" {
+			t.Errorf("Unexpected rejected event %+v", event)
+		}
+	})
+
+	t.Run("Requests without a session are not reported", func(t *testing.T) {
+		ctx, _ := newContext("")
+		_, _, _, _, _ = _establishSessionContext(ctx, mgr, &APIConfiguration{SessionRequired: true})
+		select {
+		case event := <-listener.events:
+			t.Errorf("Expected no event, got %+v", event)
+		case <-time.After(20 * time.Millisecond):
+		}
+	})
+
+	t.Run("CSRF failures", func(t *testing.T) {
+		ctx, _ := newContext("")
+		if _, _, _, _, appErr := _establishSessionContext(ctx, mgr, &APIConfiguration{RequireCsrf: true}); appErr == nil {
+			t.Fatal("Expected the CSRF check to fail")
+		}
+		listener.next(t, SessionEventCsrfFailure)
+	})
+
+	t.Run("RBAC denials", func(t *testing.T) {
+		mgr.rbacManager = &namedRbacManager{
+			cacheManager: internalcache.BuildDefaultCacheManager(nil),
+			named:        map[string]rbac.PermissionSet{},
+		}
+		defer func() { mgr.rbacManager = nil }()
+
+		claims := &SessionClaims{HasSession: true, Claims: map[string]string{
+			"subject":           "user-1",
+			RbacCacheIdentifier: strings.Repeat("r", 32),
+		}}
+		ctx, _ := newContext("")
+		if appErr := processRbac(ctx, mgr, &APIConfiguration{NamedPermissions: []string{"orders.write"}}, claims); appErr == nil {
+			t.Fatal("Expected the RBAC check to fail")
+		}
+		if event := listener.next(t, SessionEventRbacDenied); event.Subject != "user-1" {
+			t.Errorf("Unexpected rbac event %+v", event)
+		}
+	})
+}
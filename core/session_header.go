@@ -29,18 +29,20 @@ func NewSessionHeader(bearer bool, expiresAt time.Duration, refreshAt time.Durat
 }
 
 func Decode(header string) (SessionHeader, error) {
-	decoded, err := base64.RawURLEncoding.DecodeString(header)
-	if err != nil {
+	var h SessionHeader
+	if _, err := decodeSessionHeader(stringBytes(header), nil, &h); err != nil {
 		return SessionHeader{}, err
 	}
+	return h, nil
+}
 
-	var h SessionHeader
-	err = json.Unmarshal(decoded, &h)
+// decodeSessionHeader decodes an encoded header into h, using (and returning) scratch for the base64 decoding.
+func decodeSessionHeader(header []byte, scratch []byte, h *SessionHeader) ([]byte, error) {
+	scratch, err := decodeBase64Into(scratch, header)
 	if err != nil {
-		return SessionHeader{}, err
+		return scratch, err
 	}
-
-	return h, nil
+	return scratch, json.Unmarshal(scratch, h)
 }
 
 func (h SessionHeader) Encode() (string, error) {
@@ -0,0 +1,112 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/helpers"
+	"go.uber.org/zap"
+)
+
+// SessionLimitAnyGroup keys the session limit of the groups without their own in SessionLimits.
+const SessionLimitAnyGroup = "*"
+
+// StoredSession is a session kept by the session store, as listed by a SessionLister.
+type StoredSession struct {
+	Claims *SessionClaims
+
+	// LastUsedAt orders the sessions for eviction, the least recently used is revoked first. Stores that don't
+	// track use may return the issue time.
+	LastUsedAt time.Time
+}
+
+// SessionLister is implemented by session managers whose store can list the active sessions of a subject, the
+// SessionLimits need it together with SessionRevoker.
+type SessionLister interface {
+	ListSessions(ctx context.Context, subject string) ([]StoredSession, error)
+}
+
+// sessionLimit returns the largest number of concurrent sessions of the group, 0 when it is unlimited.
+func sessionLimit(authorizationData *SessionAuthorizationConfiguration, group string) int {
+	if limit, ok := authorizationData.SessionLimits[group]; ok {
+		return limit
+	}
+	return authorizationData.SessionLimits[SessionLimitAnyGroup]
+}
+
+// validateSessionLimits checks the limits can be enforced by the session manager.
+func validateSessionLimits(sessionManager SessionManager, authorizationData *SessionAuthorizationConfiguration) error {
+	if len(authorizationData.SessionLimits) == 0 {
+		return nil
+	}
+	for group, limit := range authorizationData.SessionLimits {
+		if limit < 1 {
+			return fmt.Errorf("session limit of group '%s' must be at least 1, got %d", group, limit)
+		}
+	}
+	if _, ok := sessionManager.(SessionLister); !ok {
+		return fmt.Errorf("SessionLimits need a session manager implementing SessionLister")
+	}
+	if _, ok := sessionManager.(SessionRevoker); !ok {
+		return fmt.Errorf("SessionLimits need a session manager implementing SessionRevoker")
+	}
+	return nil
+}
+
+// enforceSessionLimit revokes the least recently used sessions of the subject in the group of claims, so with
+// the session just stored for claims there are no more than its limit. Remember-me tokens are not counted.
+func enforceSessionLimit(
+	ctx *gin.Context,
+	sessionManager SessionManager,
+	authorizationData *SessionAuthorizationConfiguration,
+	group string,
+	claims *SessionClaims,
+) error {
+	limit := sessionLimit(authorizationData, group)
+	if limit <= 0 {
+		return nil
+	}
+
+	lister, ok := sessionManager.(SessionLister)
+	if !ok {
+		return fmt.Errorf("session manager does not implement SessionLister")
+	}
+	subject, err := sessionManager.GetSubjectIdentifier(claims)
+	if err != nil {
+		return fmt.Errorf("failed to get subject identifier: %w", err)
+	}
+	sessions, err := lister.ListSessions(ctx, subject)
+	if err != nil {
+		return fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	// - Only the other sessions of the group count, the store may already list the new one
+	sessionId, _ := claims.GetClaim(SessionIdentifier)
+	others := make([]StoredSession, 0, len(sessions))
+	for _, session := range sessions {
+		if session.Claims == nil || session.Claims.IsRememberMeToken() {
+			continue
+		}
+		otherId, _ := session.Claims.GetClaim(SessionIdentifier)
+		otherGroup, _ := session.Claims.GetClaim(SessionModeClaim)
+		if otherId != sessionId && otherGroup == group {
+			others = append(others, session)
+		}
+	}
+	if len(others) < limit {
+		return nil
+	}
+
+	sort.SliceStable(others, func(i, j int) bool { return others[i].LastUsedAt.Before(others[j].LastUsedAt) })
+	for _, evicted := range others[:len(others)-limit+1] {
+		if err = invalidateSession(ctx, sessionManager, evicted.Claims); err != nil {
+			return err
+		}
+	}
+	helpers.Logger(ctx).Debug("Revoked sessions over the session limit",
+		zap.String("group", group), zap.Int("limit", limit), zap.Int("revoked", len(others)-limit+1))
+	return nil
+}
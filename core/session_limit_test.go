@@ -0,0 +1,121 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// listingSessionManager keeps the stored sessions in memory, in issue order.
+type listingSessionManager struct {
+	*mockSessionManager
+	sessions []StoredSession
+	revoked  []string
+}
+
+func (m *listingSessionManager) StoreSession(_ context.Context, claims *SessionClaims, _ *SessionHeader) error {
+	stored := &SessionClaims{Claims: make(map[string]string, len(claims.Claims))}
+	for name, value := range claims.Claims {
+		stored.Claims[name] = value
+	}
+	m.sessions = append(m.sessions, StoredSession{Claims: stored, LastUsedAt: time.Now()})
+	return nil
+}
+
+func (m *listingSessionManager) ListSessions(_ context.Context, subject string) ([]StoredSession, error) {
+	var listed []StoredSession
+	for _, session := range m.sessions {
+		if owner, _ := session.Claims.GetClaim("subject"); owner == subject {
+			listed = append(listed, session)
+		}
+	}
+	return listed, nil
+}
+
+func (m *listingSessionManager) RevokeSession(_ context.Context, claims *SessionClaims) error {
+	sessionId, _ := claims.GetClaim(SessionIdentifier)
+	m.revoked = append(m.revoked, sessionId)
+	for i, session := range m.sessions {
+		if id, _ := session.Claims.GetClaim(SessionIdentifier); id == sessionId {
+			m.sessions = append(m.sessions[:i], m.sessions[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func TestSessionLimits(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mgr := &listingSessionManager{mockSessionManager: newMockSessionManager(t)}
+	mgr.authorizationData.SessionLimits = map[string]int{"user": 1, SessionLimitAnyGroup: 2}
+	if err := ValidateConfiguration(mgr); err != nil {
+		t.Fatalf("Expected the limits to be valid, got %v", err)
+	}
+
+	issue := func(group string, subject string) string {
+		t.Helper()
+		ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+		ctx.Request = httptest.NewRequest(http.MethodPost, "/login", nil)
+		claims := &SessionClaims{Claims: map[string]string{"subject": subject}}
+		if err := SetSessionCookie(ctx, mgr, group, claims); err != nil {
+			t.Fatalf("Failed to issue the session: %v", err)
+		}
+		sessionId, _ := claims.GetClaim(SessionIdentifier)
+		return sessionId
+	}
+	active := func(group string) int {
+		count := 0
+		for _, session := range mgr.sessions {
+			if mode, _ := session.Claims.GetClaim(SessionModeClaim); mode == group {
+				count++
+			}
+		}
+		return count
+	}
+
+	t.Run("A single session group revokes the previous session", func(t *testing.T) {
+		first := issue("user", "user-1")
+		issue("user", "user-2")
+		issue("user", "user-1")
+		if len(mgr.revoked) != 1 || mgr.revoked[0] != first {
+			t.Errorf("Expected only the first session of user-1 to be revoked, got %v", mgr.revoked)
+		}
+		if active("user") != 2 {
+			t.Errorf("Expected one session per subject, got %d", active("user"))
+		}
+	})
+
+	t.Run("Other groups evict the least recently used session", func(t *testing.T) {
+		mgr.revoked = nil
+		oldest := issue("device", "user-1")
+		issue("device", "user-1")
+		mgr.sessions[len(mgr.sessions)-2].LastUsedAt = time.Now().Add(time.Minute)
+		second := mgr.sessions[len(mgr.sessions)-1]
+		issue("device", "user-1")
+
+		secondId, _ := second.Claims.GetClaim(SessionIdentifier)
+		if len(mgr.revoked) != 1 || mgr.revoked[0] != secondId || mgr.revoked[0] == oldest {
+			t.Errorf("Expected the least recently used session to be revoked, got %v", mgr.revoked)
+		}
+		if active("device") != 2 || active("user") != 2 {
+			t.Errorf("Expected the groups to be limited separately, got %d and %d", active("device"), active("user"))
+		}
+	})
+
+	t.Run("Limits need a lister and a revoker", func(t *testing.T) {
+		plain := newMockSessionManager(t)
+		plain.authorizationData.SessionLimits = map[string]int{"user": 1}
+		if err := ValidateConfiguration(plain); err == nil {
+			t.Error("Expected a manager without SessionLister to be rejected")
+		}
+		mgr.authorizationData.SessionLimits = map[string]int{"user": 0}
+		defer func() { mgr.authorizationData.SessionLimits = map[string]int{"user": 1, SessionLimitAnyGroup: 2} }()
+		if err := ValidateConfiguration(mgr); err == nil {
+			t.Error("Expected a limit below 1 to be rejected")
+		}
+	})
+}
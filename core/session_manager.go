@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/eko/gocache/lib/v4/cache"
+	"github.com/grzegorzmaniak/gothic/helpers"
 	"github.com/grzegorzmaniak/gothic/rbac"
 )
 
@@ -55,7 +56,14 @@ type SessionManager interface {
 	GetCache() (cache.CacheInterface[[]byte], error)
 }
 
-type DefaultSessionManager struct{}
+type DefaultSessionManager struct {
+	// Lifecycle tracks coalesced route executions and background notifications, Shutdown drains them and
+	// rejects new ones.
+	helpers.Lifecycle
+
+	// decodeCache holds the decoded tokens when SessionAuthorizationConfiguration.DecodeCacheTTL is set.
+	decodeCache decodeCache
+}
 
 // VerifyClaims barebones implementation of the VerifyClaims method
 func (m *DefaultSessionManager) VerifyClaims(ctx context.Context, claimsToVerify *SessionClaims, sessionConfig *APIConfiguration) (bool, error) {
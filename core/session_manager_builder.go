@@ -0,0 +1,453 @@
+package core
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"strings"
+
+	"github.com/eko/gocache/lib/v4/cache"
+	internalcache "github.com/grzegorzmaniak/gothic/cache"
+	"github.com/grzegorzmaniak/gothic/helpers"
+	"github.com/grzegorzmaniak/gothic/rbac"
+)
+
+const (
+	// DefaultBuilderSubjectClaim is the claim a built session manager reads the subject identifier from.
+	DefaultBuilderSubjectClaim = "subject"
+)
+
+// SessionVerifyFunc decides whether a session is still valid, e.g., by looking it up in a session store.
+type SessionVerifyFunc func(ctx context.Context, claims *SessionClaims, sessionHeader *SessionHeader) (bool, error)
+
+// SessionStoreFunc stores a newly issued session, e.g., in a session store so it can be revoked later.
+type SessionStoreFunc func(ctx context.Context, claims *SessionClaims, sessionHeader *SessionHeader) error
+
+// SessionListFunc lists the active sessions of a subject, see SessionLister.
+type SessionListFunc func(ctx context.Context, subject string) ([]StoredSession, error)
+
+// SessionRevokeFunc revokes a stored session, see SessionRevoker.
+type SessionRevokeFunc func(ctx context.Context, claims *SessionClaims) error
+
+// SealedSessionStoreFunc stores a newly issued session with its claims sealed by SealClaims, bound to the subject.
+// Read them back with OpenClaims(manager, sealed, []byte(subject)).
+type SealedSessionStoreFunc func(ctx context.Context, subject string, sealed string, sessionHeader *SessionHeader) error
+
+// CacheProvider is implemented by cache managers, e.g., cache.DefaultCacheManager and cache.ChainCacheManager.
+type CacheProvider interface {
+	GetCache() (cache.CacheInterface[[]byte], error)
+}
+
+// SessionManagerBuilder builds a SessionManager from a few options instead of implementing the interface:
+//
+//	manager, err := core.NewSessionManagerBuilder().
+//		WithKey("2024-06", key).
+//		WithRbac(rbacManager).
+//		WithSessionVerifier(sessions.Verify).
+//		Build()
+//
+// The returned configurations state the effective cookie settings (Secure, HttpOnly session cookie, SameSite), and
+// Build rejects missing or invalid keys and the settings ValidateConfiguration rejects. Options are applied in
+// order, and invalid ones are reported by Build.
+type SessionManagerBuilder struct {
+	authorization SessionAuthorizationConfiguration
+	csrf          CsrfCookieData
+	keys          map[string][]byte
+	currentKeyId  string
+	keyProvider   helpers.KeyProvider
+	rbacManager   rbac.Manager
+	cacheProvider CacheProvider
+	subjectClaim  string
+	verify        SessionVerifyFunc
+	store         SessionStoreFunc
+	sealedStore   SealedSessionStoreFunc
+	list          SessionListFunc
+	revoke        SessionRevokeFunc
+	apiKeyStore   APIKeyStore
+	certResolver  ClientCertificateResolver
+	geoResolver   GeoResolver
+	flagProvider  FlagProvider
+	retryPolicy   *helpers.RetryPolicy
+	errs          []error
+}
+
+// NewSessionManagerBuilder returns a builder with the default cookie configurations and no key.
+func NewSessionManagerBuilder() *SessionManagerBuilder {
+	return &SessionManagerBuilder{keys: make(map[string][]byte)}
+}
+
+// WithKey sets the only session key, see WithKeyRing to keep older keys valid while rotating.
+func (b *SessionManagerBuilder) WithKey(keyId string, key []byte) *SessionManagerBuilder {
+	return b.WithKeyRing(keyId, map[string][]byte{keyId: key})
+}
+
+// WithKeyRing sets the session keys by key id. New sessions are encrypted with the current key, sessions
+// encrypted with the others are still accepted (and re-encrypted with the current key when refreshed).
+func (b *SessionManagerBuilder) WithKeyRing(currentKeyId string, keys map[string][]byte) *SessionManagerBuilder {
+	if _, ok := keys[currentKeyId]; !ok {
+		b.errs = append(b.errs, fmt.Errorf("current key id '%s' is not in the key ring", currentKeyId))
+		return b
+	}
+
+	b.keys = make(map[string][]byte, len(keys))
+	for keyId, key := range keys {
+		b.keys[keyId] = append([]byte(nil), key...)
+	}
+	b.currentKeyId = currentKeyId
+	b.keyProvider = nil
+	return b
+}
+
+// WithKeyProvider serves the session keys from a helpers.KeyProvider instead of raw bytes, e.g., a
+// helpers.KMSKeyRing over AWS KMS, Cloud KMS or Vault transit. It replaces WithKey and WithKeyRing, and Build
+// fetches the current key once so an unreachable service fails at startup.
+func (b *SessionManagerBuilder) WithKeyProvider(provider helpers.KeyProvider) *SessionManagerBuilder {
+	if provider == nil {
+		b.errs = append(b.errs, fmt.Errorf("key provider is nil"))
+		return b
+	}
+	b.keyProvider = provider
+	b.keys, b.currentKeyId = make(map[string][]byte), ""
+	return b
+}
+
+// WithRbac sets the RBAC manager routes with RBAC requirements are checked against (Default: nil, RBAC is not used)
+func (b *SessionManagerBuilder) WithRbac(manager rbac.Manager) *SessionManagerBuilder {
+	b.rbacManager = manager
+	return b
+}
+
+// WithCache sets the cache of bearer validations and the other core caches (Default: a cache.DefaultCacheManager
+// owned by the session manager and closed on Shutdown)
+func (b *SessionManagerBuilder) WithCache(provider CacheProvider) *SessionManagerBuilder {
+	if provider == nil {
+		b.errs = append(b.errs, fmt.Errorf("cache provider is nil"))
+		return b
+	}
+	b.cacheProvider = provider
+	return b
+}
+
+// WithCookieConfig sets the session and CSRF cookie configurations, either may be nil to keep the defaults.
+func (b *SessionManagerBuilder) WithCookieConfig(authorization *SessionAuthorizationConfiguration, csrf *CsrfCookieData) *SessionManagerBuilder {
+	if authorization != nil {
+		b.authorization = *authorization
+	}
+	if csrf != nil {
+		b.csrf = *csrf
+	}
+	return b
+}
+
+// WithSubjectClaim sets the claim holding the subject identifier (Default: DefaultBuilderSubjectClaim)
+func (b *SessionManagerBuilder) WithSubjectClaim(claim string) *SessionManagerBuilder {
+	if claim == "" {
+		b.errs = append(b.errs, fmt.Errorf("subject claim is empty"))
+		return b
+	}
+	b.subjectClaim = claim
+	return b
+}
+
+// WithSessionVerifier sets how sessions are verified (Default: every decrypted, unexpired session is valid, so
+// sessions can not be revoked before they expire)
+func (b *SessionManagerBuilder) WithSessionVerifier(verify SessionVerifyFunc) *SessionManagerBuilder {
+	b.verify = verify
+	return b
+}
+
+// WithSessionStore sets how issued sessions are stored (Default: they are not stored)
+func (b *SessionManagerBuilder) WithSessionStore(store SessionStoreFunc) *SessionManagerBuilder {
+	b.store, b.sealedStore = store, nil
+	return b
+}
+
+// WithSealedSessionStore stores issued sessions with their claims encrypted at rest, see SealClaims. It replaces
+// WithSessionStore.
+func (b *SessionManagerBuilder) WithSealedSessionStore(store SealedSessionStoreFunc) *SessionManagerBuilder {
+	b.store, b.sealedStore = nil, store
+	return b
+}
+
+// WithSessionLister sets how the sessions of a subject are listed, for the session limits (Default: nil)
+func (b *SessionManagerBuilder) WithSessionLister(list SessionListFunc) *SessionManagerBuilder {
+	b.list = list
+	return b
+}
+
+// WithSessionRevoker sets how stored sessions are revoked, e.g., on logout or past a session limit
+// (Default: sessions are not revoked)
+func (b *SessionManagerBuilder) WithSessionRevoker(revoke SessionRevokeFunc) *SessionManagerBuilder {
+	b.revoke = revoke
+	return b
+}
+
+// WithSessionLimit caps the concurrent sessions of a subject in the group, SessionLimitAnyGroup for every group
+// without its own limit. It needs WithSessionLister and WithSessionRevoker, see
+// SessionAuthorizationConfiguration.SessionLimits.
+func (b *SessionManagerBuilder) WithSessionLimit(group string, limit int) *SessionManagerBuilder {
+	if limit < 1 {
+		b.errs = append(b.errs, fmt.Errorf("session limit of group '%s' must be at least 1, got %d", group, limit))
+		return b
+	}
+	limits := make(map[string]int, len(b.authorization.SessionLimits)+1)
+	for name, value := range b.authorization.SessionLimits {
+		limits[name] = value
+	}
+	limits[group] = limit
+	b.authorization.SessionLimits = limits
+	return b
+}
+
+// WithAPIKeyStore sets where API keys are looked up, they are accepted on routes with AllowAPIKeys
+// (Default: no API keys are accepted)
+func (b *SessionManagerBuilder) WithAPIKeyStore(store APIKeyStore) *SessionManagerBuilder {
+	b.apiKeyStore = store
+	return b
+}
+
+// WithClientCertificateResolver maps verified TLS client certificates to claims, they are accepted on routes with
+// AllowClientCertificates (Default: no client certificates are accepted)
+func (b *SessionManagerBuilder) WithClientCertificateResolver(resolver ClientCertificateResolver) *SessionManagerBuilder {
+	b.certResolver = resolver
+	return b
+}
+
+// WithGeoResolver locates the issuing IP of new sessions when SessionMetadata is enabled (Default: nil, no
+// location is written)
+func (b *SessionManagerBuilder) WithGeoResolver(resolver GeoResolver) *SessionManagerBuilder {
+	b.geoResolver = resolver
+	return b
+}
+
+// WithFlagProvider evaluates the FeatureFlag of routes, e.g., StaticFlags or an adapter of a feature flag service
+// (Default: nil, routes with a FeatureFlag fail)
+func (b *SessionManagerBuilder) WithFlagProvider(provider FlagProvider) *SessionManagerBuilder {
+	b.flagProvider = provider
+	return b
+}
+
+// WithRetryPolicy retries the bearer cache writes failing with transient errors, e.g., during a momentary Redis
+// blip (Default: nil, no retries)
+func (b *SessionManagerBuilder) WithRetryPolicy(policy *helpers.RetryPolicy) *SessionManagerBuilder {
+	b.retryPolicy = policy
+	return b
+}
+
+// Build validates the options and returns the session manager.
+func (b *SessionManagerBuilder) Build() (*BuiltSessionManager, error) {
+	errs := append([]error(nil), b.errs...)
+
+	keys := b.keys
+	if b.keyProvider != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), helpers.DefaultKMSTimeout)
+		key, keyId, err := b.keyProvider.CurrentKey(ctx)
+		cancel()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to get the current key: %w", err))
+		} else {
+			keys = map[string][]byte{keyId: key}
+		}
+	} else if len(keys) == 0 {
+		errs = append(errs, fmt.Errorf("a session key is required, use WithKey, WithKeyRing or WithKeyProvider"))
+	}
+	delimiter := helpers.DefaultString(b.authorization.Delimiter, DefaultSessionAuthorizationDelimiter)
+	for keyId, key := range keys {
+		if len(keyId) < MinimumSessionKeyIdSize || len(keyId) > MaximumSessionKeyIdSize {
+			errs = append(errs, fmt.Errorf("key id '%s' must be between %d and %d characters", keyId, MinimumSessionKeyIdSize, MaximumSessionKeyIdSize))
+		}
+		if strings.Contains(keyId, delimiter) {
+			errs = append(errs, fmt.Errorf("key id '%s' must not contain the delimiter '%s'", keyId, delimiter))
+		}
+		if len(key) != helpers.AESKeySize16 && len(key) != helpers.AESKeySize24 && len(key) != helpers.AESKeySize32 {
+			errs = append(errs, fmt.Errorf("key '%s' must be %d, %d or %d bytes, got %d", keyId, helpers.AESKeySize16, helpers.AESKeySize24, helpers.AESKeySize32, len(key)))
+		}
+	}
+	if len(b.authorization.SessionLimits) > 0 && (b.list == nil || b.revoke == nil) {
+		errs = append(errs, fmt.Errorf("session limits need WithSessionLister and WithSessionRevoker"))
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("core: invalid session manager: %w", stderrors.Join(errs...))
+	}
+
+	// - Unset cookie fields already resolve to these, spell them out so the configuration shows what is sent
+	authorization, csrf := b.authorization, b.csrf
+	authorization.CookieSecure = helpers.DefaultBool(authorization.CookieSecure, DefaultSessionAuthorizationSecure)
+	authorization.CookieHttpOnly = helpers.DefaultBool(authorization.CookieHttpOnly, DefaultSessionAuthorizationHttpOnly)
+	authorization.CookieSameSite = helpers.DefaultString(authorization.CookieSameSite, DefaultSessionAuthorizationSameSite)
+	csrf.Secure = helpers.DefaultBool(csrf.Secure, DefaultCsrfCookieSecure)
+	csrf.SameSite = helpers.DefaultString(csrf.SameSite, DefaultCsrfCookieSameSite)
+
+	manager := &BuiltSessionManager{
+		authorization: &authorization,
+		csrf:          &csrf,
+		keys:          b.keys,
+		currentKeyId:  b.currentKeyId,
+		keyProvider:   b.keyProvider,
+		rbacManager:   b.rbacManager,
+		cacheProvider: b.cacheProvider,
+		subjectClaim:  helpers.DefaultString(b.subjectClaim, DefaultBuilderSubjectClaim),
+		verify:        b.verify,
+		store:         b.store,
+		sealedStore:   b.sealedStore,
+		list:          b.list,
+		revoke:        b.revoke,
+		apiKeyStore:   b.apiKeyStore,
+		certResolver:  b.certResolver,
+		geoResolver:   b.geoResolver,
+		flagProvider:  b.flagProvider,
+		retryPolicy:   b.retryPolicy,
+	}
+	if manager.cacheProvider == nil {
+		manager.ownedCache = internalcache.BuildDefaultCacheManager(nil)
+		manager.cacheProvider = manager.ownedCache
+	}
+
+	if err := ValidateConfiguration(manager); err != nil {
+		return nil, fmt.Errorf("core: invalid session manager: %w", err)
+	}
+	return manager, nil
+}
+
+// BuiltSessionManager is the SessionManager returned by SessionManagerBuilder.Build.
+type BuiltSessionManager struct {
+	DefaultSessionManager
+
+	authorization *SessionAuthorizationConfiguration
+	csrf          *CsrfCookieData
+	keys          map[string][]byte
+	currentKeyId  string
+	keyProvider   helpers.KeyProvider
+	rbacManager   rbac.Manager
+	cacheProvider CacheProvider
+	ownedCache    *internalcache.DefaultCacheManager
+	subjectClaim  string
+	verify        SessionVerifyFunc
+	store         SessionStoreFunc
+	sealedStore   SealedSessionStoreFunc
+	list          SessionListFunc
+	revoke        SessionRevokeFunc
+	apiKeyStore   APIKeyStore
+	certResolver  ClientCertificateResolver
+	geoResolver   GeoResolver
+	flagProvider  FlagProvider
+	retryPolicy   *helpers.RetryPolicy
+}
+
+func (m *BuiltSessionManager) GetAuthorizationConfiguration() *SessionAuthorizationConfiguration {
+	return m.authorization
+}
+
+func (m *BuiltSessionManager) GetCsrfData() *CsrfCookieData {
+	return m.csrf
+}
+
+func (m *BuiltSessionManager) GetSessionKey() ([]byte, string, error) {
+	if m.keyProvider != nil {
+		return m.keyProvider.CurrentKey(context.Background())
+	}
+	return m.keys[m.currentKeyId], m.currentKeyId, nil
+}
+
+func (m *BuiltSessionManager) GetOldSessionKey(keyId string) ([]byte, error) {
+	if m.keyProvider != nil {
+		return m.keyProvider.Key(context.Background(), keyId)
+	}
+	key, ok := m.keys[keyId]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id '%s'", keyId)
+	}
+	return key, nil
+}
+
+func (m *BuiltSessionManager) VerifySession(ctx context.Context, claims *SessionClaims, sessionHeader *SessionHeader) (bool, error) {
+	if m.verify == nil {
+		return true, nil
+	}
+	return m.verify(ctx, claims, sessionHeader)
+}
+
+func (m *BuiltSessionManager) StoreSession(ctx context.Context, claims *SessionClaims, sessionHeader *SessionHeader) error {
+	if m.sealedStore != nil {
+		subject, err := m.GetSubjectIdentifier(claims)
+		if err != nil {
+			return err
+		}
+		sealed, err := SealClaims(m, claims, []byte(subject))
+		if err != nil {
+			return fmt.Errorf("failed to seal claims: %w", err)
+		}
+		return m.sealedStore(ctx, subject, sealed, sessionHeader)
+	}
+	if m.store == nil {
+		return nil
+	}
+	return m.store(ctx, claims, sessionHeader)
+}
+
+func (m *BuiltSessionManager) ListSessions(ctx context.Context, subject string) ([]StoredSession, error) {
+	if m.list == nil {
+		return nil, fmt.Errorf("no session lister is configured")
+	}
+	return m.list(ctx, subject)
+}
+
+func (m *BuiltSessionManager) RevokeSession(ctx context.Context, claims *SessionClaims) error {
+	if m.revoke == nil {
+		return nil
+	}
+	return m.revoke(ctx, claims)
+}
+
+func (m *BuiltSessionManager) GetRbacManager() rbac.Manager {
+	return m.rbacManager
+}
+
+func (m *BuiltSessionManager) GetSubjectIdentifier(claims *SessionClaims) (string, error) {
+	if claims == nil {
+		return "", fmt.Errorf("claims are nil")
+	}
+	subject, ok := claims.GetClaim(m.subjectClaim)
+	if !ok || subject == "" {
+		return "", fmt.Errorf("%s claim is missing", m.subjectClaim)
+	}
+	return subject, nil
+}
+
+func (m *BuiltSessionManager) GetAPIKeyStore() APIKeyStore {
+	return m.apiKeyStore
+}
+
+func (m *BuiltSessionManager) GetClientCertificateResolver() ClientCertificateResolver {
+	return m.certResolver
+}
+
+func (m *BuiltSessionManager) GetGeoResolver() GeoResolver {
+	return m.geoResolver
+}
+
+func (m *BuiltSessionManager) GetFlagProvider() FlagProvider {
+	return m.flagProvider
+}
+
+func (m *BuiltSessionManager) GetRetryPolicy() *helpers.RetryPolicy {
+	return m.retryPolicy
+}
+
+func (m *BuiltSessionManager) GetCache() (cache.CacheInterface[[]byte], error) {
+	return m.cacheProvider.GetCache()
+}
+
+// Shutdown drains the manager's background work, then closes its cache unless it was given with WithCache.
+func (m *BuiltSessionManager) Shutdown(ctx context.Context) error {
+	err := m.DefaultSessionManager.Shutdown(ctx)
+	if m.ownedCache != nil {
+		err = stderrors.Join(err, m.ownedCache.Shutdown(ctx))
+	}
+	return err
+}
+
+// Close shuts down the manager without a deadline.
+func (m *BuiltSessionManager) Close() error {
+	return m.Shutdown(context.Background())
+}
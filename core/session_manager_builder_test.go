@@ -0,0 +1,219 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/eko/gocache/lib/v4/cache"
+	"github.com/eko/gocache/lib/v4/store"
+	"github.com/gin-gonic/gin"
+	internalcache "github.com/grzegorzmaniak/gothic/cache"
+	"github.com/grzegorzmaniak/gothic/errors"
+	"github.com/grzegorzmaniak/gothic/helpers"
+)
+
+func newBuilderKey(t *testing.T) []byte {
+	t.Helper()
+	key, err := helpers.GenerateSymmetricKey(helpers.AESKeySize32)
+	if err != nil {
+		t.Fatalf("Failed to generate session key: %v", err)
+	}
+	return key
+}
+
+func TestSessionManagerBuilderValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	key := newBuilderKey(t)
+
+	cases := map[string]*SessionManagerBuilder{
+		"missing key":          NewSessionManagerBuilder(),
+		"short key":            NewSessionManagerBuilder().WithKey("k1", key[:10]),
+		"key id too long":      NewSessionManagerBuilder().WithKey(strings.Repeat("k", MaximumSessionKeyIdSize+1), key),
+		"key id delimiter":     NewSessionManagerBuilder().WithKey("k.1", key),
+		"unknown current id":   NewSessionManagerBuilder().WithKeyRing("k2", map[string][]byte{"k1": key}),
+		"nil cache":            NewSessionManagerBuilder().WithKey("k1", key).WithCache(nil),
+		"unknown SameSite":     NewSessionManagerBuilder().WithKey("k1", key).WithCookieConfig(&SessionAuthorizationConfiguration{CookieSameSite: "Sideways"}, nil),
+		"nil key provider":     NewSessionManagerBuilder().WithKeyProvider(nil),
+		"unreachable key":      NewSessionManagerBuilder().WithKeyProvider(&helpers.StaticKeyProvider{CurrentKeyId: "k1"}),
+		"short provided key":   NewSessionManagerBuilder().WithKeyProvider(&helpers.StaticKeyProvider{CurrentKeyId: "k1", Keys: map[string][]byte{"k1": key[:10]}}),
+		"limit without lister": NewSessionManagerBuilder().WithKey("k1", key).WithSessionLimit("user", 1),
+		"zero session limit":   NewSessionManagerBuilder().WithKey("k1", key).WithSessionLimit("user", 0),
+	}
+	for name, builder := range cases {
+		if _, err := builder.Build(); err == nil {
+			t.Errorf("%s: expected Build to fail", name)
+		}
+	}
+
+	t.Run("Keys are served by the key provider", func(t *testing.T) {
+		oldKey := newBuilderKey(t)
+		provider := &helpers.StaticKeyProvider{CurrentKeyId: "k2", Keys: map[string][]byte{"k1": oldKey, "k2": key}}
+		manager, err := NewSessionManagerBuilder().WithKey("raw", key).WithKeyProvider(provider).Build()
+		if err != nil {
+			t.Fatalf("Expected Build to succeed, got %v", err)
+		}
+		defer manager.Close()
+
+		if current, keyId, err := manager.GetSessionKey(); err != nil || keyId != "k2" || string(current) != string(key) {
+			t.Errorf("Expected the provider's current key, got '%s', %v", keyId, err)
+		}
+		if old, err := manager.GetOldSessionKey("k1"); err != nil || string(old) != string(oldKey) {
+			t.Errorf("Expected the provider's old key, got %v", err)
+		}
+		if _, err := manager.GetOldSessionKey("raw"); err == nil {
+			t.Error("Expected WithKeyProvider to replace the raw keys")
+		}
+	})
+
+	t.Run("Effective cookie settings are filled in", func(t *testing.T) {
+		manager, err := NewSessionManagerBuilder().WithKey("k1", key).WithCookieConfig(&SessionAuthorizationConfiguration{CookieName: "sid"}, &CsrfCookieData{}).Build()
+		if err != nil {
+			t.Fatalf("Expected Build to succeed, got %v", err)
+		}
+		authorization := manager.GetAuthorizationConfiguration()
+		if !authorization.CookieSecure || !authorization.CookieHttpOnly || authorization.CookieSameSite != DefaultSessionAuthorizationSameSite || authorization.CookieName != "sid" || !manager.GetCsrfData().Secure {
+			t.Errorf("Expected Secure and HttpOnly cookies, got %+v and %+v", authorization, manager.GetCsrfData())
+		}
+	})
+}
+
+func TestBuiltSessionManager(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	oldKey, currentKey := newBuilderKey(t), newBuilderKey(t)
+
+	issueWith := func(manager SessionManager, subject string) string {
+		ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+		ctx.Request = httptest.NewRequest(http.MethodPost, "/login", nil)
+		token, err := IssueBearerToken(ctx, manager, "default", &SessionClaims{Claims: map[string]string{"subject": subject}})
+		if err != nil {
+			t.Fatalf("Failed to issue the bearer token: %v", err)
+		}
+		return token
+	}
+
+	previous, err := NewSessionManagerBuilder().WithKey("old", oldKey).Build()
+	if err != nil {
+		t.Fatalf("Expected Build to succeed, got %v", err)
+	}
+	defer previous.Close()
+
+	var stored []string
+	cacheManager := internalcache.BuildDefaultCacheManager(nil)
+	manager, err := NewSessionManagerBuilder().
+		WithKeyRing("current", map[string][]byte{"old": oldKey, "current": currentKey}).
+		WithCache(cacheManager).
+		WithSessionVerifier(func(_ context.Context, claims *SessionClaims, _ *SessionHeader) (bool, error) {
+			subject, _ := claims.GetClaim(DefaultBuilderSubjectClaim)
+			return subject != "revoked", nil
+		}).
+		WithSessionStore(func(_ context.Context, claims *SessionClaims, _ *SessionHeader) error {
+			subject, _ := claims.GetClaim(DefaultBuilderSubjectClaim)
+			stored = append(stored, subject)
+			return nil
+		}).
+		Build()
+	if err != nil {
+		t.Fatalf("Expected Build to succeed, got %v", err)
+	}
+
+	router := gin.New()
+	ctor := NewRouteConstructor(router, testBaseRoute{}, manager, nil)
+	GET(ctor, "/me", AuthenticatedJSONAPI(), func(_ *struct{}, data *Handler[testBaseRoute]) (*struct {
+		Subject string `json:"subject"`
+	}, *errors.AppError) {
+		subject, _ := data.Claims.GetClaim(DefaultBuilderSubjectClaim)
+		return &struct {
+			Subject string `json:"subject"`
+		}{Subject: subject}, nil
+	})
+
+	request := func(token string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodGet, "/me", nil)
+		request.Header.Set(DefaultSessionAuthorizationHeaderName, token)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	if recorder := request(issueWith(manager, "user-1")); recorder.Code != http.StatusOK || !strings.Contains(recorder.Body.String(), "user-1") {
+		t.Errorf("Expected the session to be accepted, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	if len(stored) != 1 || stored[0] != "user-1" {
+		t.Errorf("Expected the issued session to be stored, got %v", stored)
+	}
+	if recorder := request(issueWith(previous, "user-2")); recorder.Code != http.StatusOK {
+		t.Errorf("Expected a session encrypted with an older key to be accepted, got %d", recorder.Code)
+	}
+	if recorder := request(issueWith(manager, "revoked")); recorder.Code != http.StatusUnauthorized {
+		t.Errorf("Expected the verifier to reject the session, got %d", recorder.Code)
+	}
+
+	// - The cache given with WithCache is owned by the caller
+	if err = manager.Close(); err != nil {
+		t.Fatalf("Expected a clean shutdown, got %v", err)
+	}
+	if _, err = cacheManager.GetCache(); err != nil {
+		t.Errorf("Expected the given cache to stay open, got %v", err)
+	}
+	if _, err = previous.GetCache(); err != nil {
+		t.Errorf("Expected the other manager's cache to be open, got %v", err)
+	}
+	if previous.Close() != nil {
+		t.Error("Expected the owned cache to close")
+	}
+	if _, err = previous.GetCache(); err == nil {
+		t.Error("Expected the owned cache to be closed on Shutdown")
+	}
+}
+
+// flakyCache fails the next failures writes.
+type flakyCache struct {
+	cache.CacheInterface[[]byte]
+	failures atomic.Int32
+}
+
+func (c *flakyCache) Set(ctx context.Context, key any, object []byte, options ...store.Option) error {
+	if c.failures.Add(-1) >= 0 {
+		return fmt.Errorf("connection reset")
+	}
+	return c.CacheInterface.Set(ctx, key, object, options...)
+}
+
+func (c *flakyCache) GetCache() (cache.CacheInterface[[]byte], error) {
+	return c, nil
+}
+
+func TestBuiltSessionManagerRetryPolicy(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	instance, err := internalcache.BuildDefaultCacheManager(nil).GetCache()
+	if err != nil {
+		t.Fatalf("Failed to initialize cache: %v", err)
+	}
+	flaky := &flakyCache{CacheInterface: instance}
+	manager, err := NewSessionManagerBuilder().
+		WithKey("current", newBuilderKey(t)).
+		WithCache(flaky).
+		WithRetryPolicy(&helpers.RetryPolicy{Attempts: 3, BaseDelay: time.Millisecond}).
+		Build()
+	if err != nil {
+		t.Fatalf("Expected Build to succeed, got %v", err)
+	}
+	defer manager.Close()
+
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	header := NewSessionHeader(false, time.Hour, 30*time.Minute)
+	flaky.failures.Store(2)
+	if err = BearerSetCache(ctx, manager, "bearer:retried", &header); err != nil {
+		t.Errorf("Expected the cache write to be retried, got %v", err)
+	}
+	flaky.failures.Store(3)
+	if err = BearerSetCache(ctx, manager, "bearer:failed", &header); err == nil {
+		t.Error("Expected the error once the attempts run out")
+	}
+}
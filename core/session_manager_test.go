@@ -0,0 +1,118 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/eko/gocache/lib/v4/cache"
+	internalcache "github.com/grzegorzmaniak/gothic/cache"
+	"github.com/grzegorzmaniak/gothic/helpers"
+	"github.com/grzegorzmaniak/gothic/rbac"
+)
+
+// mockSessionManager is a minimal, fully functional SessionManager used across the core tests.
+type mockSessionManager struct {
+	DefaultSessionManager
+	authorizationData *SessionAuthorizationConfiguration
+	csrfData          *CsrfCookieData
+	keys              map[string][]byte
+	currentKeyId      string
+	rbacManager       rbac.Manager
+	cacheManager      *internalcache.DefaultCacheManager
+	verifySession     bool
+}
+
+func newMockSessionManager(t testing.TB) *mockSessionManager {
+	t.Helper()
+	key, err := helpers.GenerateSymmetricKey(helpers.AESKeySize32)
+	if err != nil {
+		t.Fatalf("Failed to generate session key: %v", err)
+	}
+
+	return &mockSessionManager{
+		authorizationData: &SessionAuthorizationConfiguration{},
+		csrfData:          &CsrfCookieData{},
+		keys:              map[string][]byte{"key-1": key},
+		currentKeyId:      "key-1",
+		cacheManager:      internalcache.BuildDefaultCacheManager(nil),
+		verifySession:     true,
+	}
+}
+
+func (m *mockSessionManager) GetAuthorizationConfiguration() *SessionAuthorizationConfiguration {
+	return m.authorizationData
+}
+
+func (m *mockSessionManager) GetCsrfData() *CsrfCookieData {
+	return m.csrfData
+}
+
+func (m *mockSessionManager) GetSessionKey() ([]byte, string, error) {
+	return m.keys[m.currentKeyId], m.currentKeyId, nil
+}
+
+func (m *mockSessionManager) GetOldSessionKey(keyId string) ([]byte, error) {
+	key, ok := m.keys[keyId]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id '%s'", keyId)
+	}
+	return key, nil
+}
+
+func (m *mockSessionManager) VerifySession(context.Context, *SessionClaims, *SessionHeader) (bool, error) {
+	return m.verifySession, nil
+}
+
+func (m *mockSessionManager) StoreSession(context.Context, *SessionClaims, *SessionHeader) error {
+	return nil
+}
+
+func (m *mockSessionManager) GetRbacManager() rbac.Manager {
+	return m.rbacManager
+}
+
+func (m *mockSessionManager) GetSubjectIdentifier(claims *SessionClaims) (string, error) {
+	subject, ok := claims.GetClaim("subject")
+	if !ok {
+		return "", fmt.Errorf("subject claim is missing")
+	}
+	return subject, nil
+}
+
+func (m *mockSessionManager) GetCache() (cache.CacheInterface[[]byte], error) {
+	return m.cacheManager.GetCache()
+}
+
+// TestDefaultSessionManager_VerifyClaims tests the Allow / Block handling of the default VerifyClaims.
+func TestDefaultSessionManager_VerifyClaims(t *testing.T) {
+	mgr := &DefaultSessionManager{}
+	claims := &SessionClaims{Claims: map[string]string{SessionModeClaim: "user"}}
+
+	tests := []struct {
+		name   string
+		config *APIConfiguration
+		want   bool
+	}{
+		{name: "No lists allows everything", config: &APIConfiguration{}, want: true},
+		{name: "Allowed mode", config: &APIConfiguration{Allow: []string{"user"}}, want: true},
+		{name: "Mode not in allow list", config: &APIConfiguration{Allow: []string{"admin"}}, want: false},
+		{name: "Blocked mode", config: &APIConfiguration{Block: []string{"user"}}, want: false},
+		{name: "Allow takes precedence over block", config: &APIConfiguration{Allow: []string{"user"}, Block: []string{"user"}}, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _ := mgr.VerifyClaims(context.Background(), claims, tt.config)
+			if got != tt.want {
+				t.Errorf("VerifyClaims() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("Missing session mode claim is rejected", func(t *testing.T) {
+		if ok, err := mgr.VerifyClaims(context.Background(), &SessionClaims{}, &APIConfiguration{}); ok || err == nil {
+			t.Errorf("Expected rejection with error, got %v, %v", ok, err)
+		}
+	})
+}
@@ -0,0 +1,169 @@
+package core
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/errors"
+	"github.com/grzegorzmaniak/gothic/helpers"
+	"go.uber.org/zap"
+)
+
+const (
+	SessionIssuedAtClaim = "___oa" // Unix seconds the session was issued at
+	SessionIssuedIPClaim = "___oi" // Client IP the session was issued to
+	SessionDeviceClaim   = "___od" // Device label parsed from the User-Agent, see DeviceLabel
+	SessionGeoClaim      = "___og" // Approximate location of the issuing IP, e.g., "Warsaw, PL"
+
+	UnknownDeviceLabel = "Unknown device"
+)
+
+// GeoResolverProvider is implemented by session managers that locate the issuing IP of new sessions, see
+// SessionManagerBuilder.WithGeoResolver.
+type GeoResolverProvider interface {
+	GetGeoResolver() GeoResolver
+}
+
+// ActiveSession describes a session of a subject for "your devices" pages, see ListActiveSessions.
+type ActiveSession struct {
+	SessionID  string    `json:"session_id"`
+	Group      string    `json:"group"`
+	IssuedAt   time.Time `json:"issued_at,omitempty"`
+	LastUsedAt time.Time `json:"last_used_at,omitempty"`
+	IP         string    `json:"ip,omitempty"`
+	Device     string    `json:"device,omitempty"`
+	Location   string    `json:"location,omitempty"`
+
+	// Current is set for the session the listing was requested with.
+	Current bool `json:"current"`
+}
+
+// userAgentMarkers are matched in order, the first marker found names the operating system or browser.
+var (
+	operatingSystemMarkers = [][2]string{
+		{"iPhone", "iOS"}, {"iPad", "iPadOS"}, {"Android", "Android"}, {"CrOS", "ChromeOS"},
+		{"Windows", "Windows"}, {"Macintosh", "macOS"}, {"Mac OS X", "macOS"}, {"Linux", "Linux"},
+	}
+	browserMarkers = [][2]string{
+		{"Edg", "Edge"}, {"OPR/", "Opera"}, {"SamsungBrowser/", "Samsung Internet"}, {"Firefox/", "Firefox"},
+		{"FxiOS/", "Firefox"}, {"CriOS/", "Chrome"}, {"Chrome/", "Chrome"}, {"Safari/", "Safari"},
+		{"curl/", "curl"}, {"okhttp/", "OkHttp"}, {"Go-http-client/", "Go HTTP client"},
+	}
+)
+
+// DeviceLabel returns a readable label for a User-Agent, e.g., "Chrome on macOS". It only tells common
+// browsers and operating systems apart, anything else is UnknownDeviceLabel.
+func DeviceLabel(userAgent string) string {
+	find := func(markers [][2]string) string {
+		for _, marker := range markers {
+			if strings.Contains(userAgent, marker[0]) {
+				return marker[1]
+			}
+		}
+		return ""
+	}
+
+	browser, operatingSystem := find(browserMarkers), find(operatingSystemMarkers)
+	switch {
+	case browser != "" && operatingSystem != "":
+		return browser + " on " + operatingSystem
+	case browser != "":
+		return browser
+	case operatingSystem != "":
+		return operatingSystem
+	}
+	return UnknownDeviceLabel
+}
+
+// geoHint formats a location as "City, Country", leaving out the parts the resolver didn't know.
+func geoHint(location *GeoLocation) string {
+	if location == nil {
+		return ""
+	}
+	parts := make([]string, 0, 2)
+	for _, part := range []string{location.City, location.Country} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// setSessionMetadata writes the metadata claims of a session being issued, when SessionMetadata is enabled. A
+// failing GeoResolver only leaves out the location.
+func setSessionMetadata(ctx *gin.Context, sessionManager SessionManager, authorizationData *SessionAuthorizationConfiguration, claims *SessionClaims) {
+	if !authorizationData.SessionMetadata || ctx == nil || ctx.Request == nil {
+		return
+	}
+
+	ip := ctx.ClientIP()
+	claims.SetClaim(SessionIssuedAtClaim, strconv.FormatInt(time.Now().Unix(), 10))
+	claims.SetClaim(SessionIssuedIPClaim, ip)
+	claims.SetClaim(SessionDeviceClaim, DeviceLabel(ctx.Request.UserAgent()))
+	delete(claims.Claims, SessionGeoClaim)
+
+	provider, ok := sessionManager.(GeoResolverProvider)
+	if !ok || provider.GetGeoResolver() == nil {
+		return
+	}
+	location, err := provider.GetGeoResolver()(ctx, ip)
+	if err != nil {
+		helpers.Logger(ctx).Debug("Failed to resolve the location of a new session", zap.Error(err))
+		return
+	}
+	if hint := geoHint(location); hint != "" {
+		claims.SetClaim(SessionGeoClaim, hint)
+	}
+}
+
+// ListActiveSessions lists the sessions of the subject of claims with their metadata (see
+// SessionAuthorizationConfiguration.SessionMetadata), most recently used first. The session of claims is marked
+// Current. It needs a session manager implementing SessionLister, remember-me tokens are left out.
+func ListActiveSessions(ctx *gin.Context, sessionManager SessionManager, claims *SessionClaims) ([]ActiveSession, error) {
+	if sessionManager == nil {
+		return nil, errors.NewInternalServerError("Session manager is nil", nil)
+	}
+	if claims == nil || !claims.HasSession {
+		return nil, errors.NewUnauthorized("A session is required to list the sessions", nil)
+	}
+	lister, ok := sessionManager.(SessionLister)
+	if !ok {
+		return nil, errors.NewInternalServerError("Session manager does not implement SessionLister", nil)
+	}
+
+	subject, err := sessionManager.GetSubjectIdentifier(claims)
+	if err != nil {
+		return nil, errors.NewInternalServerError("Failed to get subject identifier", err)
+	}
+	stored, err := lister.ListSessions(ctx, subject)
+	if err != nil {
+		return nil, errors.NewInternalServerError("Failed to list sessions", err)
+	}
+
+	currentId, _ := claims.GetClaim(SessionIdentifier)
+	sessions := make([]ActiveSession, 0, len(stored))
+	for _, session := range stored {
+		if session.Claims == nil || session.Claims.IsRememberMeToken() {
+			continue
+		}
+		active := ActiveSession{LastUsedAt: session.LastUsedAt}
+		active.SessionID, _ = session.Claims.GetClaim(SessionIdentifier)
+		active.Group, _ = session.Claims.GetClaim(SessionModeClaim)
+		active.IP, _ = session.Claims.GetClaim(SessionIssuedIPClaim)
+		active.Device, _ = session.Claims.GetClaim(SessionDeviceClaim)
+		active.Location, _ = session.Claims.GetClaim(SessionGeoClaim)
+		if issuedAt, _ := session.Claims.GetClaim(SessionIssuedAtClaim); issuedAt != "" {
+			if seconds, err := strconv.ParseInt(issuedAt, 10, 64); err == nil {
+				active.IssuedAt = time.Unix(seconds, 0)
+			}
+		}
+		active.Current = active.SessionID != "" && active.SessionID == currentId
+		sessions = append(sessions, active)
+	}
+
+	sort.SliceStable(sessions, func(i, j int) bool { return sessions[i].LastUsedAt.After(sessions[j].LastUsedAt) })
+	return sessions, nil
+}
@@ -0,0 +1,91 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestDeviceLabel(t *testing.T) {
+	cases := map[string]string{
+		"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36":                   "Chrome on macOS",
+		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36 Edg/124.0.0.0":           "Edge on Windows",
+		"Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Mobile/15E148 Safari/604.1": "Safari on iOS",
+		"Mozilla/5.0 (X11; Linux x86_64; rv:125.0) Gecko/20100101 Firefox/125.0":                                                                  "Firefox on Linux",
+		"Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Mobile Safari/537.36":                   "Chrome on Android",
+		"curl/8.5.0": "curl",
+		"":           UnknownDeviceLabel,
+	}
+	for userAgent, expected := range cases {
+		if label := DeviceLabel(userAgent); label != expected {
+			t.Errorf("Expected '%s' for '%s', got '%s'", expected, userAgent, label)
+		}
+	}
+}
+
+// geoSessionManager locates every IP in Warsaw, except 10.0.0.2 which fails.
+type geoSessionManager struct {
+	*listingSessionManager
+}
+
+func (m *geoSessionManager) GetGeoResolver() GeoResolver {
+	return func(_ context.Context, ip string) (*GeoLocation, error) {
+		if ip == "10.0.0.2" {
+			return nil, fmt.Errorf("unknown ip")
+		}
+		return &GeoLocation{City: "Warsaw", Country: "PL"}, nil
+	}
+}
+
+func TestListActiveSessions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mgr := &geoSessionManager{&listingSessionManager{mockSessionManager: newMockSessionManager(t)}}
+	mgr.authorizationData.SessionMetadata = true
+
+	issue := func(ip string, userAgent string) *SessionClaims {
+		t.Helper()
+		ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+		ctx.Request = httptest.NewRequest(http.MethodPost, "/login", nil)
+		ctx.Request.RemoteAddr = ip + ":1234"
+		ctx.Request.Header.Set("User-Agent", userAgent)
+		claims := &SessionClaims{Claims: map[string]string{"subject": "user-1"}, HasSession: true}
+		if err := SetSessionCookie(ctx, mgr, "user", claims); err != nil {
+			t.Fatalf("Failed to issue the session: %v", err)
+		}
+		return claims
+	}
+
+	laptop := issue("10.0.0.1", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) Gecko/20100101 Firefox/125.0")
+	issue("10.0.0.2", "curl/8.5.0")
+	mgr.sessions[0].LastUsedAt = time.Now().Add(time.Minute)
+
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	sessions, err := ListActiveSessions(ctx, mgr, laptop)
+	if err != nil || len(sessions) != 2 {
+		t.Fatalf("Expected two sessions, got %d: %v", len(sessions), err)
+	}
+
+	first, second := sessions[0], sessions[1]
+	if !first.Current || first.Device != "Firefox on macOS" || first.IP != "10.0.0.1" || first.Location != "Warsaw, PL" || first.Group != "user" {
+		t.Errorf("Expected the current laptop session first with its metadata, got %+v", first)
+	}
+	if time.Since(first.IssuedAt) > time.Minute {
+		t.Errorf("Expected the issue time, got %v", first.IssuedAt)
+	}
+	if second.Current || second.Device != "curl" || second.Location != "" {
+		t.Errorf("Expected the curl session without a location, got %+v", second)
+	}
+
+	t.Run("Metadata is only written when enabled", func(t *testing.T) {
+		mgr.authorizationData.SessionMetadata = false
+		defer func() { mgr.authorizationData.SessionMetadata = true }()
+		if claims := issue("10.0.0.3", "curl/8.5.0"); claims.HasClaim(SessionDeviceClaim) || claims.HasClaim(SessionIssuedIPClaim) {
+			t.Errorf("Expected no metadata claims, got %v", claims.Claims)
+		}
+	})
+}
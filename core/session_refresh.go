@@ -0,0 +1,150 @@
+package core
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/eko/gocache/lib/v4/store"
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/errors"
+	"github.com/grzegorzmaniak/gothic/helpers"
+	"go.uber.org/zap"
+)
+
+// SessionRefreshDueHeader is set on responses to cookie sessions that need a refresh when SignalRefresh is
+// enabled, its value is the Unix time the session expires at. Cross-origin clients need it in
+// Access-Control-Expose-Headers to read it.
+const SessionRefreshDueHeader = "X-Session-Refresh-Due"
+
+// SessionRefreshCacheKeyPrefix tracks the last refresh of each session under RefreshGracePeriod.
+const SessionRefreshCacheKeyPrefix = "session_refresh:" // Key: session_refresh:<sessionIdentifier>
+
+// RefreshConfiguration configures NewRefreshHandler.
+type RefreshConfiguration struct {
+	// SessionManager the session was issued by (Required)
+	SessionManager SessionManager
+
+	// SkipCsrf disables the CSRF check of the session, the same as LogoutConfiguration.SkipCsrf (Default: false)
+	SkipCsrf bool
+}
+
+// refreshSessionCookie refreshes the session cookie of the request, or only signals that it is due when
+// SignalRefresh is set.
+func refreshSessionCookie(ctx *gin.Context, sessionManager SessionManager, claims *SessionClaims, header *SessionHeader) error {
+	if authorizationData := sessionManager.GetAuthorizationConfiguration(); authorizationData != nil && authorizationData.SignalRefresh {
+		ctx.Header(SessionRefreshDueHeader, strconv.FormatInt(header.IssuedAt+header.LifetimeSec, 10))
+		return nil
+	}
+	return SetRefreshSessionCookie(ctx, sessionManager, claims, header)
+}
+
+// recordSessionRefresh remembers when the session of claims was refreshed, cookies issued before are superseded
+// once the RefreshGracePeriod passes. The record lives as long as the session.
+func recordSessionRefresh(
+	ctx *gin.Context,
+	sessionManager SessionManager,
+	authorizationData *SessionAuthorizationConfiguration,
+	claims *SessionClaims,
+	header *SessionHeader,
+	refreshedAt time.Time,
+) {
+	sessionId, _ := claims.GetClaim(SessionIdentifier)
+	if authorizationData.RefreshGracePeriod <= 0 || sessionId == "" {
+		return
+	}
+
+	cache, err := sessionManager.GetCache()
+	if err == nil && cache != nil {
+		ttl := time.Until(time.Unix(header.IssuedAt+header.LifetimeSec, 0))
+		err = cache.Set(ctx, SessionRefreshCacheKeyPrefix+sessionId, []byte(strconv.FormatInt(refreshedAt.Unix(), 10)), store.WithExpiration(ttl))
+	}
+	if err != nil {
+		helpers.Logger(ctx).Debug("Failed to record the session refresh", zap.Error(err))
+	}
+}
+
+// supersededSession reports whether the cookie of header was replaced by a refresh, and whether the grace period
+// of that refresh has passed. Sessions without a recorded refresh are current, e.g., when the cache lost it.
+func supersededSession(ctx *gin.Context, sessionManager SessionManager, claims *SessionClaims, header *SessionHeader) (superseded bool, stale bool) {
+	authorizationData := sessionManager.GetAuthorizationConfiguration()
+	sessionId, _ := claims.GetClaim(SessionIdentifier)
+	if authorizationData == nil || authorizationData.RefreshGracePeriod <= 0 || sessionId == "" {
+		return false, false
+	}
+
+	cache, err := sessionManager.GetCache()
+	if err != nil || cache == nil {
+		return false, false
+	}
+	value, err := cache.Get(ctx, SessionRefreshCacheKeyPrefix+sessionId)
+	if err != nil {
+		return false, false
+	}
+	refreshedAt, err := strconv.ParseInt(string(value), 10, 64)
+	if err != nil || header.IssuedAt >= refreshedAt {
+		return false, false
+	}
+	return true, time.Since(time.Unix(refreshedAt, 0)) > authorizationData.RefreshGracePeriod
+}
+
+// NewRefreshHandler returns a ready-made route that refreshes the session cookie of the request, for clients that
+// got SessionRefreshDueHeader (see SessionAuthorizationConfiguration.SignalRefresh). Only the client decides when
+// the cookie is replaced, so one tab refreshing can't be overwritten by another tab's request still carrying the
+// old cookie. Sessions that aren't due yet are left as they are, so calling it again is harmless. It responds
+// with 204 No Content, and 401 without a valid cookie session. Mount it on POST, e.g.:
+//
+//	router.POST("/session/refresh", core.NewRefreshHandler(core.RefreshConfiguration{SessionManager: sessionManager}))
+func NewRefreshHandler(config RefreshConfiguration) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		sessionManager := config.SessionManager
+		if sessionManager == nil {
+			helpers.ErrorResponse(ctx, errors.NewInternalServerError("Session manager is nil", nil))
+			return
+		}
+		ctx.Header("Cache-Control", "no-store")
+
+		// - Bearer tokens are refreshed by issuing a new one, not through this route
+		header, claims, _, source, err := extractSession(ctx, sessionManager)
+		if err != nil || source != SourceCookie || claims == nil || header == nil || header.IsExpired() || !header.IsValid() {
+			helpers.Logger(ctx).Debug("Refresh requested without a valid cookie session", zap.Error(err))
+			helpers.ErrorResponse(ctx, errors.NewUnauthorized("", err))
+			return
+		}
+		if claims.IsRememberMeToken() {
+			helpers.ErrorResponse(ctx, errors.NewUnauthorized("Remember-me tokens are not sessions", nil))
+			return
+		}
+		if _, appErr := processSessionBinding(ctx, sessionManager, &APIConfiguration{SessionRequired: true}, claims); appErr != nil {
+			publishSessionRejection(ctx, sessionManager, claims, appErr)
+			helpers.ErrorResponse(ctx, appErr)
+			return
+		}
+
+		if !config.SkipCsrf {
+			if err = checkSessionCsrf(ctx, sessionManager, claims); err != nil {
+				publishSessionEvent(ctx, sessionManager, SessionEventCsrfFailure, claims, err.Error())
+				helpers.ErrorResponse(ctx, errors.NewUnauthorized(csrfFailureMessage, err))
+				return
+			}
+		}
+
+		// - Cookies superseded by a refresh within the grace period are left, the client has the new one
+		superseded, stale := supersededSession(ctx, sessionManager, claims, header)
+		if stale {
+			helpers.ErrorResponse(ctx, errors.NewUnauthorized("", nil))
+			return
+		}
+
+		if !superseded && header.NeedsRefresh() {
+			if err = SetRefreshSessionCookie(ctx, sessionManager, claims, header); err != nil {
+				helpers.Logger(ctx).Debug("Error attempting to refresh session cookie", zap.Error(err))
+				helpers.ErrorResponse(ctx, errors.NewInternalServerError("Failed to refresh session", err))
+				return
+			}
+		}
+
+		ctx.Status(http.StatusNoContent)
+		ctx.Writer.WriteHeaderNow()
+	}
+}
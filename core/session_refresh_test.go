@@ -0,0 +1,182 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/errors"
+)
+
+func TestSignalRefresh(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mgr := newMockSessionManager(t)
+
+	// - Sessions issued an hour ago with a 30 minute refresh period are due
+	dueHeader := SessionHeader{LifetimeSec: 7200, RefreshPeriodSec: 1800, IssuedAt: time.Now().Add(-time.Hour).Unix()}
+	login := func(header SessionHeader) []*http.Cookie {
+		t.Helper()
+		claims := &SessionClaims{Claims: map[string]string{"subject": "user-1"}}
+		if err := ensureBasicClaims("user", claims, mgr); err != nil {
+			t.Fatalf("Failed to set the basic claims: %v", err)
+		}
+		authorization, err := CreateAuthorization("user", &header, *mgr.authorizationData, claims, mgr)
+		if err != nil {
+			t.Fatalf("Failed to create the authorization: %v", err)
+		}
+
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+		ctx.Request = httptest.NewRequest(http.MethodGet, "/login", nil)
+		tie, _ := claims.GetClaim(CsrfTokenTie)
+		if err = SetCsrfCookie(ctx, mgr, tie); err != nil {
+			t.Fatalf("Failed to set the CSRF cookie: %v", err)
+		}
+		return append(recorder.Result().Cookies(), &http.Cookie{Name: DefaultSessionAuthorizationName, Value: authorization})
+	}
+
+	router := gin.New()
+	ctor := NewRouteConstructor(router, testBaseRoute{}, mgr, nil)
+	GET(ctor, "/me", AuthenticatedJSONAPI(), func(_ *struct{}, _ *Handler[testBaseRoute]) (*struct{}, *errors.AppError) {
+		return &struct{}{}, nil
+	})
+	router.POST("/refresh", NewRefreshHandler(RefreshConfiguration{SessionManager: mgr}))
+
+	request := func(method string, path string, cookies []*http.Cookie, withCsrf bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+			if withCsrf && cookie.Name == DefaultCsrfCookieName {
+				req.Header.Set(DefaultCsrfCookieName, cookie.Value)
+			}
+		}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder
+	}
+	refreshed := func(recorder *httptest.ResponseRecorder) bool {
+		for _, cookie := range recorder.Result().Cookies() {
+			if cookie.Name == DefaultSessionAuthorizationName && cookie.MaxAge > 0 {
+				return true
+			}
+		}
+		return false
+	}
+
+	t.Run("Routes refresh due cookies by default", func(t *testing.T) {
+		recorder := request(http.MethodGet, "/me", login(dueHeader), false)
+		if recorder.Code != http.StatusOK || !refreshed(recorder) || recorder.Header().Get(SessionRefreshDueHeader) != "" {
+			t.Errorf("Expected the cookie to be refreshed, got %d: %v", recorder.Code, recorder.Header())
+		}
+	})
+
+	mgr.authorizationData.SignalRefresh = true
+	defer func() { mgr.authorizationData.SignalRefresh = false }()
+
+	t.Run("Routes only signal due refreshes", func(t *testing.T) {
+		recorder := request(http.MethodGet, "/me", login(dueHeader), false)
+		if recorder.Code != http.StatusOK || refreshed(recorder) {
+			t.Fatalf("Expected the cookie to be left as it is, got %d: %v", recorder.Code, recorder.Header())
+		}
+		expected := strconv.FormatInt(dueHeader.IssuedAt+dueHeader.LifetimeSec, 10)
+		if due := recorder.Header().Get(SessionRefreshDueHeader); due != expected {
+			t.Errorf("Expected the refresh to be signalled with '%s', got '%s'", expected, due)
+		}
+
+		fresh := NewSessionHeader(false, time.Hour, 30*time.Minute)
+		if recorder = request(http.MethodGet, "/me", login(fresh), false); recorder.Header().Get(SessionRefreshDueHeader) != "" {
+			t.Error("Expected no signal for sessions that aren't due")
+		}
+	})
+
+	t.Run("The refresh handler refreshes due cookies", func(t *testing.T) {
+		recorder := request(http.MethodPost, "/refresh", login(dueHeader), true)
+		if recorder.Code != http.StatusNoContent || !refreshed(recorder) {
+			t.Errorf("Expected the cookie to be refreshed, got %d: %s", recorder.Code, recorder.Body.String())
+		}
+	})
+
+	t.Run("The refresh handler leaves fresh cookies", func(t *testing.T) {
+		recorder := request(http.MethodPost, "/refresh", login(NewSessionHeader(false, time.Hour, 30*time.Minute)), true)
+		if recorder.Code != http.StatusNoContent || refreshed(recorder) {
+			t.Errorf("Expected nothing to be refreshed, got %d: %v", recorder.Code, recorder.Header())
+		}
+	})
+
+	t.Run("The refresh handler rejects invalid requests", func(t *testing.T) {
+		if recorder := request(http.MethodPost, "/refresh", nil, false); recorder.Code != http.StatusUnauthorized {
+			t.Errorf("Expected 401 without a session, got %d", recorder.Code)
+		}
+		if recorder := request(http.MethodPost, "/refresh", login(dueHeader), false); recorder.Code != http.StatusUnauthorized || refreshed(recorder) {
+			t.Errorf("Expected 401 without the CSRF token, got %d", recorder.Code)
+		}
+	})
+}
+
+func TestRefreshGracePeriod(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mgr := newMockSessionManager(t)
+	mgr.authorizationData.RefreshGracePeriod = time.Hour
+
+	claims := &SessionClaims{Claims: map[string]string{"subject": "user-1"}}
+	if err := ensureBasicClaims("user", claims, mgr); err != nil {
+		t.Fatalf("Failed to set the basic claims: %v", err)
+	}
+	dueHeader := SessionHeader{LifetimeSec: 7200, RefreshPeriodSec: 1800, IssuedAt: time.Now().Add(-time.Hour).Unix()}
+	authorization, err := CreateAuthorization("user", &dueHeader, *mgr.authorizationData, claims, mgr)
+	if err != nil {
+		t.Fatalf("Failed to create the authorization: %v", err)
+	}
+	oldCookie := &http.Cookie{Name: DefaultSessionAuthorizationName, Value: authorization}
+
+	router := gin.New()
+	ctor := NewRouteConstructor(router, testBaseRoute{}, mgr, nil)
+	GET(ctor, "/me", AuthenticatedJSONAPI(), func(_ *struct{}, _ *Handler[testBaseRoute]) (*struct{}, *errors.AppError) {
+		return &struct{}{}, nil
+	})
+	request := func(cookie *http.Cookie) (*httptest.ResponseRecorder, *http.Cookie) {
+		req := httptest.NewRequest(http.MethodGet, "/me", nil)
+		req.AddCookie(cookie)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		for _, set := range recorder.Result().Cookies() {
+			if set.Name == DefaultSessionAuthorizationName && set.MaxAge > 0 {
+				return recorder, set
+			}
+		}
+		return recorder, nil
+	}
+
+	recorder, newCookie := request(oldCookie)
+	if recorder.Code != http.StatusOK || newCookie == nil {
+		t.Fatalf("Expected the cookie to be refreshed, got %d", recorder.Code)
+	}
+
+	// - The refresh is recorded asynchronously, until then the old cookie may be refreshed again
+	deadline := time.Now().Add(time.Second)
+	for {
+		recorder, refreshed := request(oldCookie)
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("Expected the old cookie to be accepted within the grace period, got %d", recorder.Code)
+		}
+		if refreshed == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the old cookie not to be refreshed again within the grace period")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	mgr.authorizationData.RefreshGracePeriod = time.Millisecond
+	time.Sleep(10 * time.Millisecond)
+	if recorder, _ = request(oldCookie); recorder.Code != http.StatusUnauthorized {
+		t.Errorf("Expected the old cookie to be rejected after the grace period, got %d", recorder.Code)
+	}
+	if recorder, _ = request(newCookie); recorder.Code != http.StatusOK {
+		t.Errorf("Expected the refreshed cookie to be accepted, got %d", recorder.Code)
+	}
+}
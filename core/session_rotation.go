@@ -0,0 +1,87 @@
+package core
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/errors"
+	"github.com/grzegorzmaniak/gothic/helpers"
+	"go.uber.org/zap"
+)
+
+// sessionIdentityClaims tie a token to one session, they are regenerated whenever its privileges change.
+var sessionIdentityClaims = []string{SessionIdentifier, SessionModeClaim, RbacCacheIdentifier, CsrfTokenTie, VersionClaim, BindingIPClaim, BindingUserAgentClaim}
+
+// rotateSessionClaims copies the claims without the claims identifying the session, so the next token issued for
+// them gets a new SessionIdentifier, CSRF tie and RBAC cache identifier.
+func rotateSessionClaims(claims *SessionClaims) *SessionClaims {
+	rotated := &SessionClaims{Claims: make(map[string]string, len(claims.Claims)), HasSession: true}
+	for name, value := range claims.Claims {
+		rotated.Claims[name] = value
+	}
+	for _, name := range sessionIdentityClaims {
+		delete(rotated.Claims, name)
+	}
+	return rotated
+}
+
+// reissueSession revokes the session of claims and sets a session cookie for a rotated copy of them. The old
+// session is revoked first, a failure leaves the client without a session rather than with two.
+func reissueSession(ctx *gin.Context, sessionManager SessionManager, claims *SessionClaims, group string) (*SessionClaims, error) {
+	rotated := rotateSessionClaims(claims)
+	if err := invalidateSession(ctx, sessionManager, claims); err != nil {
+		return nil, errors.NewInternalServerError("Failed to revoke the previous session", err)
+	}
+	if err := SetCustomSessionCookie(ctx, sessionManager, group, rotated, sessionManager.GetAuthorizationConfiguration()); err != nil {
+		return nil, err
+	}
+	return rotated, nil
+}
+
+// revokePreviousSessions revokes the session the request arrived with and the session the claims were issued
+// for, before SetSessionCookie issues a new one under RotateOnIssue.
+func revokePreviousSessions(ctx *gin.Context, sessionManager SessionManager, claims *SessionClaims) error {
+	revoked := map[string]bool{}
+	revoke := func(previous *SessionClaims) error {
+		sessionId, _ := previous.GetClaim(SessionIdentifier)
+		if sessionId == "" || revoked[sessionId] {
+			return nil
+		}
+		revoked[sessionId] = true
+		return invalidateSession(ctx, sessionManager, previous)
+	}
+
+	// - An invalid or missing session has nothing to revoke
+	if _, current, _, _, err := extractSession(ctx, sessionManager); err == nil && current != nil {
+		if err = revoke(current); err != nil {
+			return err
+		}
+	}
+	return revoke(claims)
+}
+
+// RegenerateSession reissues the cookie session of claims with a new SessionIdentifier, CSRF tie and RBAC cache
+// identifier, keeping its group and other claims, and revokes the old session (see SessionRevoker). Call it
+// whenever a session gains privileges, e.g., after a login or a password change, so an identifier planted or
+// leaked earlier (session fixation) is worthless. Use the returned claims for the rest of the request.
+func RegenerateSession(ctx *gin.Context, sessionManager SessionManager, claims *SessionClaims) (*SessionClaims, error) {
+	if ctx == nil {
+		return nil, errors.NewInternalServerError("Context is nil", nil)
+	}
+	if sessionManager == nil {
+		return nil, errors.NewInternalServerError("Session manager is nil", nil)
+	}
+	if claims == nil || !claims.HasSession {
+		return nil, errors.NewUnauthorized("A session is required to regenerate it", nil)
+	}
+
+	group, ok := claims.GetClaim(SessionModeClaim)
+	if !ok || group == "" {
+		return nil, errors.NewInternalServerError("Session has no session mode", nil)
+	}
+
+	regenerated, err := reissueSession(ctx, sessionManager, claims, group)
+	if err != nil {
+		return nil, err
+	}
+	helpers.Logger(ctx).Debug("Session regenerated", zap.String("group", group))
+	return regenerated, nil
+}
@@ -0,0 +1,99 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	internalcache "github.com/grzegorzmaniak/gothic/cache"
+)
+
+func TestSessionRotation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	base := newMockSessionManager(t)
+	base.rbacManager = &namedRbacManager{cacheManager: internalcache.BuildDefaultCacheManager(nil)}
+	mgr := &revokingSessionManager{mockSessionManager: base}
+
+	issue := func(request *http.Request, group string, claims *SessionClaims) []*http.Cookie {
+		t.Helper()
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+		ctx.Request = request
+		if err := SetSessionCookie(ctx, mgr, group, claims); err != nil {
+			t.Fatalf("Failed to set session cookie: %v", err)
+		}
+		claims.HasSession = true
+		return recorder.Result().Cookies()
+	}
+
+	t.Run("RegenerateSession rotates the identifiers", func(t *testing.T) {
+		mgr.revoked = nil
+		claims := &SessionClaims{Claims: map[string]string{"subject": "user-1"}}
+		issue(httptest.NewRequest(http.MethodPost, "/login", nil), "user_session", claims)
+
+		ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+		ctx.Request = httptest.NewRequest(http.MethodPost, "/password", nil)
+		regenerated, err := RegenerateSession(ctx, mgr, claims)
+		if err != nil {
+			t.Fatalf("RegenerateSession() error = %v", err)
+		}
+
+		for _, name := range []string{SessionIdentifier, CsrfTokenTie, RbacCacheIdentifier} {
+			before, _ := claims.GetClaim(name)
+			after, _ := regenerated.GetClaim(name)
+			if after == "" || after == before {
+				t.Errorf("Expected %s to be regenerated", name)
+			}
+		}
+		if mode, _ := regenerated.GetClaim(SessionModeClaim); mode != "user_session" {
+			t.Errorf("Expected the group to be kept, got %q", mode)
+		}
+		oldSessionId, _ := claims.GetClaim(SessionIdentifier)
+		if !slices.Equal(mgr.revoked, []string{oldSessionId}) {
+			t.Errorf("Expected the old session to be revoked, got %v", mgr.revoked)
+		}
+	})
+
+	t.Run("Without RotateOnIssue the identifiers are kept", func(t *testing.T) {
+		mgr.revoked = nil
+		claims := &SessionClaims{Claims: map[string]string{"subject": "user-1"}}
+		issue(httptest.NewRequest(http.MethodPost, "/login", nil), "user_session", claims)
+		sessionId, _ := claims.GetClaim(SessionIdentifier)
+
+		issue(httptest.NewRequest(http.MethodPost, "/login", nil), "user_session", claims)
+		if reissued, _ := claims.GetClaim(SessionIdentifier); reissued != sessionId {
+			t.Errorf("Expected the session identifier to be kept")
+		}
+		if len(mgr.revoked) != 0 {
+			t.Errorf("Expected nothing to be revoked, got %v", mgr.revoked)
+		}
+	})
+
+	t.Run("RotateOnIssue revokes the previous sessions", func(t *testing.T) {
+		base.authorizationData.RotateOnIssue = true
+		defer func() { base.authorizationData.RotateOnIssue = false }()
+		mgr.revoked = nil
+
+		guest := &SessionClaims{Claims: map[string]string{"subject": "visitor-1"}}
+		guestCookies := issue(httptest.NewRequest(http.MethodPost, "/visit", nil), "guest_session", guest)
+		guestSessionId, _ := guest.GetClaim(SessionIdentifier)
+		mgr.revoked = nil
+
+		// - The login reuses claims of an earlier session and arrives with the guest cookie
+		claims := &SessionClaims{Claims: map[string]string{"subject": "user-1", SessionIdentifier: "planted-session-id"}}
+		login := httptest.NewRequest(http.MethodPost, "/login", nil)
+		for _, cookie := range guestCookies {
+			login.AddCookie(cookie)
+		}
+		issue(login, "user_session", claims)
+
+		if sessionId, _ := claims.GetClaim(SessionIdentifier); sessionId == "planted-session-id" || sessionId == guestSessionId {
+			t.Errorf("Expected a new session identifier, got %q", sessionId)
+		}
+		if !slices.Contains(mgr.revoked, guestSessionId) || !slices.Contains(mgr.revoked, "planted-session-id") {
+			t.Errorf("Expected the guest and planted sessions to be revoked, got %v", mgr.revoked)
+		}
+	})
+}
@@ -0,0 +1,67 @@
+package core
+
+import (
+	"slices"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/errors"
+	"github.com/grzegorzmaniak/gothic/helpers"
+	"go.uber.org/zap"
+)
+
+// SessionTransitions is the table of allowed session mode (group) changes, mapping a group to the groups its
+// sessions may be upgraded to, e.g., SessionTransitions{"guest_session": {"user_session"}}.
+type SessionTransitions map[string][]string
+
+// Allows reports whether sessions of fromGroup may be upgraded to toGroup.
+func (t SessionTransitions) Allows(fromGroup string, toGroup string) bool {
+	return slices.Contains(t[fromGroup], toGroup)
+}
+
+// SessionTransitionProvider is implemented by session managers that allow UpgradeSessionGroup, managers without
+// it allow no transitions.
+type SessionTransitionProvider interface {
+	GetSessionTransitions() SessionTransitions
+}
+
+// UpgradeSessionGroup moves the cookie session of claims from fromGroup to toGroup, e.g., from "guest_session" to
+// "user_session" once the visitor logs in. The transition has to be allowed by the manager's SessionTransitions.
+// The session is reissued with a new SessionIdentifier, CSRF tie and RBAC cache identifier and the old session
+// is revoked first (see SessionRevoker), so the old token never carries the new privileges. Use the returned
+// claims for the rest of the request.
+func UpgradeSessionGroup(
+	ctx *gin.Context,
+	sessionManager SessionManager,
+	claims *SessionClaims,
+	fromGroup string,
+	toGroup string,
+) (*SessionClaims, error) {
+	if ctx == nil {
+		return nil, errors.NewInternalServerError("Context is nil", nil)
+	}
+	if sessionManager == nil {
+		return nil, errors.NewInternalServerError("Session manager is nil", nil)
+	}
+	if claims == nil || !claims.HasSession {
+		return nil, errors.NewUnauthorized("A session is required to change its mode", nil)
+	}
+
+	currentGroup, _ := claims.GetClaim(SessionModeClaim)
+	if currentGroup != fromGroup {
+		return nil, errors.NewForbidden("Session is not in the expected mode", nil)
+	}
+
+	provider, ok := sessionManager.(SessionTransitionProvider)
+	if !ok || !provider.GetSessionTransitions().Allows(fromGroup, toGroup) {
+		helpers.Logger(ctx).Debug("Session mode transition denied", zap.String("from", fromGroup), zap.String("to", toGroup))
+		return nil, errors.NewForbidden("Session mode transition is not allowed", nil)
+	}
+
+	upgraded, err := reissueSession(ctx, sessionManager, claims, toGroup)
+	if err != nil {
+		return nil, err
+	}
+
+	helpers.Logger(ctx).Info("Session mode changed", zap.String("from", fromGroup), zap.String("to", toGroup))
+	return upgraded, nil
+}
@@ -0,0 +1,104 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	internalcache "github.com/grzegorzmaniak/gothic/cache"
+)
+
+// transitionSessionManager allows guest sessions to become user sessions.
+type transitionSessionManager struct {
+	*revokingSessionManager
+}
+
+func (m *transitionSessionManager) GetSessionTransitions() SessionTransitions {
+	return SessionTransitions{"guest_session": {"user_session"}}
+}
+
+func TestUpgradeSessionGroup(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	base := newMockSessionManager(t)
+	base.rbacManager = &namedRbacManager{cacheManager: internalcache.BuildDefaultCacheManager(nil)}
+	mgr := &transitionSessionManager{&revokingSessionManager{mockSessionManager: base}}
+
+	guestSession := func() *SessionClaims {
+		t.Helper()
+		ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+		ctx.Request = httptest.NewRequest(http.MethodPost, "/visit", nil)
+		claims := &SessionClaims{Claims: map[string]string{"subject": "visitor-1"}}
+		if err := SetSessionCookie(ctx, mgr, "guest_session", claims); err != nil {
+			t.Fatalf("Failed to set session cookie: %v", err)
+		}
+		claims.HasSession = true
+		return claims
+	}
+
+	t.Run("Allowed transitions reissue the session", func(t *testing.T) {
+		mgr.revoked = nil
+		claims := guestSession()
+
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+		ctx.Request = httptest.NewRequest(http.MethodPost, "/login", nil)
+		upgraded, err := UpgradeSessionGroup(ctx, mgr, claims, "guest_session", "user_session")
+		if err != nil {
+			t.Fatalf("UpgradeSessionGroup() error = %v", err)
+		}
+
+		if mode, _ := upgraded.GetClaim(SessionModeClaim); mode != "user_session" {
+			t.Errorf("Expected the user_session mode, got %q", mode)
+		}
+		if subject, _ := upgraded.GetClaim("subject"); subject != "visitor-1" {
+			t.Errorf("Expected the other claims to be kept, got subject %q", subject)
+		}
+		for _, name := range []string{SessionIdentifier, CsrfTokenTie, RbacCacheIdentifier} {
+			before, _ := claims.GetClaim(name)
+			after, _ := upgraded.GetClaim(name)
+			if after == "" || after == before {
+				t.Errorf("Expected %s to be regenerated", name)
+			}
+		}
+
+		oldSessionId, _ := claims.GetClaim(SessionIdentifier)
+		if len(mgr.revoked) != 1 || mgr.revoked[0] != oldSessionId {
+			t.Errorf("Expected the previous session to be revoked, got %v", mgr.revoked)
+		}
+
+		names := map[string]bool{}
+		for _, cookie := range recorder.Result().Cookies() {
+			names[cookie.Name] = true
+		}
+		if !names[DefaultSessionAuthorizationName] || !names[DefaultCsrfCookieName] {
+			t.Errorf("Expected the session and CSRF cookies to be reissued, got %v", names)
+		}
+	})
+
+	t.Run("Rejects other transitions", func(t *testing.T) {
+		mgr.revoked = nil
+		claims := guestSession()
+		for _, tt := range []struct{ from, to string }{
+			{"guest_session", "admin_session"},
+			{"user_session", "guest_session"},
+		} {
+			ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+			ctx.Request = httptest.NewRequest(http.MethodPost, "/login", nil)
+			if _, err := UpgradeSessionGroup(ctx, mgr, claims, tt.from, tt.to); err == nil {
+				t.Errorf("Expected %s -> %s to be rejected", tt.from, tt.to)
+			}
+		}
+		if len(mgr.revoked) != 0 {
+			t.Errorf("Expected rejected transitions to keep the session, got %v", mgr.revoked)
+		}
+	})
+
+	t.Run("Managers without transitions allow none", func(t *testing.T) {
+		ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+		ctx.Request = httptest.NewRequest(http.MethodPost, "/login", nil)
+		if _, err := UpgradeSessionGroup(ctx, base, guestSession(), "guest_session", "user_session"); err == nil {
+			t.Error("Expected the transition to be rejected")
+		}
+	})
+}
@@ -1,6 +1,13 @@
 package core
 
 import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sync"
+	"sync/atomic"
+
 	"github.com/gin-gonic/gin"
 	"github.com/grzegorzmaniak/gothic/errors"
 	"github.com/grzegorzmaniak/gothic/helpers"
@@ -13,9 +20,46 @@ type RouteConstructor[BaseRoute helpers.BaseRouteComponents] struct {
 	baseRoute        BaseRoute
 	sessionManager   SessionManager
 	validationEngine *validation.Engine
+	routes           *routeRegistry
+	hooks            atomic.Pointer[Hooks]      // See AddHooks
+	cors             atomic.Pointer[CorsPolicy] // See SetCorsPolicy
+}
+
+// RouteInfo describes a route registered through a RouteConstructor, it is what GenerateOpenAPI documents.
+type RouteInfo struct {
+	Method string
+	Path   string // Gin path, e.g., /orders/:id
+	Config *APIConfiguration
+
+	// InputType / OutputType are the handler's struct types, for dynamic routes they are built from the
+	// FieldRules. Either is nil if it is unknown.
+	InputType  reflect.Type
+	OutputType reflect.Type
+
+	// Dynamic is set for routes registered with DYNAMIC.
+	Dynamic bool
+
+	// Cors is the CORS policy the route was registered with, its own or the RouteConstructor's.
+	Cors *CorsPolicy
+}
+
+type routeRegistry struct {
+	sync.RWMutex
+	routes     []RouteInfo
+	preflights map[string]bool // Paths with a preflight route, see registerPreflight
 }
 
-// NewRouteConstructor creates a new RouteConstructor. If validationEngine is nil, a default Engine is used.
+func (r *routeRegistry) record(info RouteInfo) {
+	if r == nil {
+		return
+	}
+	r.Lock()
+	defer r.Unlock()
+	r.routes = append(r.routes, info)
+}
+
+// NewRouteConstructor creates a new RouteConstructor. If validationEngine is nil, a default Engine is used. It
+// panics when the session manager fails ValidateConfiguration.
 func NewRouteConstructor[BaseRoute helpers.BaseRouteComponents](
 	router *gin.Engine,
 	baseRoute BaseRoute,
@@ -25,34 +69,93 @@ func NewRouteConstructor[BaseRoute helpers.BaseRouteComponents](
 	if validationEngine == nil {
 		validationEngine = validation.NewEngine(nil)
 	}
+	if sessionManager != nil {
+		if err := ValidateConfiguration(sessionManager); err != nil {
+			panic(fmt.Sprintf("core: invalid configuration: %v", err))
+		}
+	}
 
 	return &RouteConstructor[BaseRoute]{
 		router:           router,
 		baseRoute:        baseRoute,
 		sessionManager:   sessionManager,
 		validationEngine: validationEngine,
+		routes:           &routeRegistry{},
+	}
+}
+
+// Shutdown shuts down the session manager and its RBAC manager, when they implement helpers.Shutdowner (e.g.,
+// DefaultSessionManager and rbac.DefaultRBACManager), draining their in-flight work and background jobs and
+// closing their caches. Call it after the HTTP server stopped accepting requests (http.Server.Shutdown). If ctx
+// expires first, the remaining work is cancelled and the error returned.
+func (ctor *RouteConstructor[BaseRoute]) Shutdown(ctx context.Context) error {
+	if ctor.sessionManager == nil {
+		return nil
 	}
+	return helpers.ShutdownAll(ctx, ctor.sessionManager, ctor.sessionManager.GetRbacManager())
+}
+
+// Routes returns the routes registered through the constructor, in registration order.
+func (ctor *RouteConstructor[BaseRoute]) Routes() []RouteInfo {
+	if ctor.routes == nil {
+		return nil
+	}
+	ctor.routes.RLock()
+	defer ctor.routes.RUnlock()
+	return append([]RouteInfo(nil), ctor.routes.routes...)
 }
 
 func registerRoute[InputType any, OutputType any, BaseRoute helpers.BaseRouteComponents](
 	ctor *RouteConstructor[BaseRoute],
-	method func(string, ...gin.HandlerFunc) gin.IRoutes,
+	method string,
+	path string,
+	sessionConfig *APIConfiguration,
+	handlerFunc func(input *InputType, data *Handler[BaseRoute]) (*OutputType, *errors.AppError),
+) {
+	cors := ctor.corsPolicy(sessionConfig)
+	ctor.routes.record(RouteInfo{
+		Method:     method,
+		Path:       path,
+		Config:     sessionConfig,
+		InputType:  reflect.TypeOf((*InputType)(nil)).Elem(),
+		OutputType: reflect.TypeOf((*OutputType)(nil)).Elem(),
+		Cors:       cors,
+	})
+	ctor.registerPreflight(path, cors)
+	handleRoute(ctor, method, path, sessionConfig, handlerFunc)
+}
+
+// handleRoute registers a route without recording it, e.g., the documentation routes.
+func handleRoute[InputType any, OutputType any, BaseRoute helpers.BaseRouteComponents](
+	ctor *RouteConstructor[BaseRoute],
+	method string,
 	path string,
 	sessionConfig *APIConfiguration,
 	handlerFunc func(input *InputType, data *Handler[BaseRoute]) (*OutputType, *errors.AppError),
 ) {
-	method(path, func(ctx *gin.Context) {
+	cors := ctor.corsPolicy(sessionConfig)
+	ctor.handle(method, path, func(ctx *gin.Context) {
+		applyCors(ctx, ctor.sessionManager, cors)
+		ctor.bindHooks(ctx)
 		ExecuteRoute(ctx, ctor.baseRoute, sessionConfig, ctor.sessionManager, ctor.validationEngine, handlerFunc)
 	})
 }
 
+// handle registers a route's handler, GET routes answer HEAD requests as well.
+func (ctor *RouteConstructor[BaseRoute]) handle(method string, path string, handler gin.HandlerFunc) {
+	ctor.router.Handle(method, path, handler)
+	if method == http.MethodGet {
+		ctor.router.Handle(http.MethodHead, path, serveHead(handler))
+	}
+}
+
 func GET[InputType any, OutputType any, BaseRoute helpers.BaseRouteComponents](
 	ctor *RouteConstructor[BaseRoute],
 	path string,
 	sessionConfig *APIConfiguration,
 	handlerFunc func(input *InputType, data *Handler[BaseRoute]) (*OutputType, *errors.AppError),
 ) {
-	registerRoute(ctor, ctor.router.GET, path, sessionConfig, handlerFunc)
+	registerRoute(ctor, http.MethodGet, path, sessionConfig, handlerFunc)
 }
 
 func POST[InputType any, OutputType any, BaseRoute helpers.BaseRouteComponents](
@@ -61,7 +164,7 @@ func POST[InputType any, OutputType any, BaseRoute helpers.BaseRouteComponents](
 	sessionConfig *APIConfiguration,
 	handlerFunc func(input *InputType, data *Handler[BaseRoute]) (*OutputType, *errors.AppError),
 ) {
-	registerRoute(ctor, ctor.router.POST, path, sessionConfig, handlerFunc)
+	registerRoute(ctor, http.MethodPost, path, sessionConfig, handlerFunc)
 }
 
 func PUT[InputType any, OutputType any, BaseRoute helpers.BaseRouteComponents](
@@ -70,7 +173,7 @@ func PUT[InputType any, OutputType any, BaseRoute helpers.BaseRouteComponents](
 	sessionConfig *APIConfiguration,
 	handlerFunc func(input *InputType, data *Handler[BaseRoute]) (*OutputType, *errors.AppError),
 ) {
-	registerRoute(ctor, ctor.router.PUT, path, sessionConfig, handlerFunc)
+	registerRoute(ctor, http.MethodPut, path, sessionConfig, handlerFunc)
 }
 
 func DELETE[InputType any, OutputType any, BaseRoute helpers.BaseRouteComponents](
@@ -79,7 +182,7 @@ func DELETE[InputType any, OutputType any, BaseRoute helpers.BaseRouteComponents
 	sessionConfig *APIConfiguration,
 	handlerFunc func(input *InputType, data *Handler[BaseRoute]) (*OutputType, *errors.AppError),
 ) {
-	registerRoute(ctor, ctor.router.DELETE, path, sessionConfig, handlerFunc)
+	registerRoute(ctor, http.MethodDelete, path, sessionConfig, handlerFunc)
 }
 
 func PATCH[InputType any, OutputType any, BaseRoute helpers.BaseRouteComponents](
@@ -88,5 +191,73 @@ func PATCH[InputType any, OutputType any, BaseRoute helpers.BaseRouteComponents]
 	sessionConfig *APIConfiguration,
 	handlerFunc func(input *InputType, data *Handler[BaseRoute]) (*OutputType, *errors.AppError),
 ) {
-	registerRoute(ctor, ctor.router.PATCH, path, sessionConfig, handlerFunc)
+	registerRoute(ctor, http.MethodPatch, path, sessionConfig, handlerFunc)
+}
+
+// DYNAMIC registers a route whose input and output are described by FieldRules, see ExecuteDynamicRoute.
+// The reflected structs are cached per method and path.
+func DYNAMIC[BaseRoute helpers.BaseRouteComponents](
+	ctor *RouteConstructor[BaseRoute],
+	method string,
+	path string,
+	sessionConfig *APIConfiguration,
+	inputFieldRules validation.FieldRules,
+	outputFieldRules validation.FieldRules,
+	handlerFunc func(input map[string]interface{}, data *Handler[BaseRoute]) (map[string]any, *errors.AppError),
+) {
+	// - Rules that can not be built are still registered, the executor reports them on every request
+	cors := ctor.corsPolicy(sessionConfig)
+	info := RouteInfo{Method: method, Path: path, Config: sessionConfig, Dynamic: true, Cors: cors}
+	info.InputType, _ = validation.DynamicStructType(inputFieldRules)
+	if outputFieldRules != nil {
+		info.OutputType, _ = validation.DynamicStructType(outputFieldRules)
+	}
+	ctor.routes.record(info)
+	ctor.registerPreflight(path, cors)
+
+	cacheId := method + " " + path
+	ctor.handle(method, path, func(ctx *gin.Context) {
+		applyCors(ctx, ctor.sessionManager, cors)
+		ctor.bindHooks(ctx)
+		ExecuteDynamicRoute(
+			ctx, ctor.baseRoute, sessionConfig, ctor.sessionManager, ctor.validationEngine,
+			cacheId+"|input", inputFieldRules, cacheId+"|output", outputFieldRules, handlerFunc,
+		)
+	})
+}
+
+// WS registers a WebSocket route, the handshake is a GET request gated like any other route.
+func WS[Conn any, BaseRoute helpers.BaseRouteComponents](
+	ctor *RouteConstructor[BaseRoute],
+	path string,
+	sessionConfig *APIConfiguration,
+	upgrader WebSocketUpgrader[Conn],
+	handlerFunc func(conn Conn, data *Handler[BaseRoute]),
+) {
+	ctor.router.GET(path, func(ctx *gin.Context) {
+		ExecuteWebSocketRoute(ctx, ctor.baseRoute, sessionConfig, ctor.sessionManager, upgrader, handlerFunc)
+	})
+}
+
+// DOWNLOAD registers a GET route that redeems single-use download grants for the operation.
+func DOWNLOAD[BaseRoute helpers.BaseRouteComponents](
+	ctor *RouteConstructor[BaseRoute],
+	path string,
+	sessionConfig *APIConfiguration,
+	operation string,
+	handlerFunc func(grant *DownloadGrant, data *Handler[BaseRoute]) *errors.AppError,
+) {
+	ctor.router.GET(path, func(ctx *gin.Context) {
+		ExecuteDownloadRoute(ctx, ctor.baseRoute, sessionConfig, ctor.sessionManager, operation, handlerFunc)
+	})
+}
+
+// DISPATCH registers a handler that can only be invoked internally through Dispatch, see RegisterDispatch.
+func DISPATCH[InputType any, OutputType any, BaseRoute helpers.BaseRouteComponents](
+	ctor *RouteConstructor[BaseRoute],
+	name string,
+	sessionConfig *APIConfiguration,
+	handlerFunc func(input *InputType, data *Handler[BaseRoute]) (*OutputType, *errors.AppError),
+) error {
+	return RegisterDispatch(name, ctor.baseRoute, sessionConfig, ctor.sessionManager, ctor.validationEngine, handlerFunc)
 }
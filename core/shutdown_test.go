@@ -0,0 +1,39 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	internalcache "github.com/grzegorzmaniak/gothic/cache"
+	"github.com/grzegorzmaniak/gothic/helpers"
+)
+
+type lifecycleRbacManager struct {
+	namedRbacManager
+	helpers.Lifecycle
+}
+
+func TestRouteConstructorShutdown(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mgr := newMockSessionManager(t)
+	rbacManager := &lifecycleRbacManager{namedRbacManager: namedRbacManager{cacheManager: internalcache.BuildDefaultCacheManager(nil)}}
+	mgr.rbacManager = rbacManager
+
+	ctor := NewRouteConstructor(gin.New(), testBaseRoute{}, mgr, nil)
+	if err := ctor.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Expected a clean shutdown, got %v", err)
+	}
+
+	if !mgr.Closed() || !rbacManager.Closed() {
+		t.Error("Expected the session and RBAC managers to be shut down")
+	}
+	if _, _, err := mgr.Begin(context.Background()); !errors.Is(err, helpers.ErrShutdown) {
+		t.Errorf("Expected new work to be rejected, got %v", err)
+	}
+
+	if err := NewRouteConstructor(gin.New(), testBaseRoute{}, nil, nil).Shutdown(context.Background()); err != nil {
+		t.Errorf("Expected a constructor without a session manager to shut down, got %v", err)
+	}
+}
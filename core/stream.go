@@ -0,0 +1,274 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/errors"
+	"github.com/grzegorzmaniak/gothic/helpers"
+	"github.com/grzegorzmaniak/gothic/validation"
+	"go.uber.org/zap"
+)
+
+const (
+	// StreamClosedEvent is sent to the client when the framework closes the stream because the session ended.
+	StreamClosedEvent = "session_closed"
+)
+
+var (
+	ErrStreamSessionExpired = fmt.Errorf("session expired")
+	ErrStreamSessionRevoked = fmt.Errorf("session is no longer valid")
+	ErrStreamClosed         = fmt.Errorf("stream closed")
+	ErrInvalidStreamEvent   = fmt.Errorf("stream event name contains a line break")
+)
+
+// streamLineBreaks normalizes the line breaks of SSE data, every one of them ends a field.
+var streamLineBreaks = strings.NewReplacer("\r\n", "\n", "\r", "\n")
+
+// Stream is a Server-Sent Events writer handed to stream handlers. It is safe for concurrent use.
+// Handlers should return once Done is closed, Err then reports why the stream ended.
+type Stream struct {
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+	writer gin.ResponseWriter
+	mu     sync.Mutex
+	opened bool
+}
+
+func newStream(ctx *gin.Context) *Stream {
+	streamCtx, cancel := context.WithCancelCause(ctx.Request.Context())
+	return &Stream{ctx: streamCtx, cancel: cancel, writer: ctx.Writer}
+}
+
+// Context returns a context that is cancelled when the stream is closed.
+func (s *Stream) Context() context.Context {
+	return s.ctx
+}
+
+// Done is closed when the client disconnects or the session is no longer valid.
+func (s *Stream) Done() <-chan struct{} {
+	return s.ctx.Done()
+}
+
+// Err returns the reason the stream was closed, or nil while it is open.
+func (s *Stream) Err() error {
+	if s.ctx.Err() == nil {
+		return nil
+	}
+	return context.Cause(s.ctx)
+}
+
+// Send writes a single event, multi-line data is split into multiple data fields on any line break (CRLF, CR or
+// LF). An empty event name sends an unnamed ("message") event, names with a line break are rejected with
+// ErrInvalidStreamEvent as they could inject fields or events.
+func (s *Stream) Send(event string, data string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ctx.Err() != nil {
+		return ErrStreamClosed
+	}
+	return s.write(event, data)
+}
+
+// SendJSON marshals the value and sends it as an event.
+func (s *Stream) SendJSON(event string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal stream event: %w", err)
+	}
+	return s.Send(event, string(data))
+}
+
+// write must be called with the lock held, the stream headers are written with the first event so the
+// handler can still return a normal error response before it starts streaming.
+func (s *Stream) write(event string, data string) error {
+	if strings.ContainsAny(event, "\r\n") {
+		return ErrInvalidStreamEvent
+	}
+
+	if !s.opened {
+		s.opened = true
+		header := s.writer.Header()
+		header.Set("Content-Type", "text/event-stream")
+		header.Set("Cache-Control", "no-cache")
+		header.Set("Connection", "keep-alive")
+		header.Set("X-Accel-Buffering", "no")
+		s.writer.WriteHeader(http.StatusOK)
+	}
+
+	var builder strings.Builder
+	if event != "" {
+		builder.WriteString("event: " + event + "\n")
+	}
+	for _, line := range strings.Split(streamLineBreaks.Replace(data), "\n") {
+		builder.WriteString("data: " + line + "\n")
+	}
+	builder.WriteString("\n")
+
+	if _, err := s.writer.WriteString(builder.String()); err != nil {
+		return fmt.Errorf("failed to write stream event: %w", err)
+	}
+	s.writer.Flush()
+	return nil
+}
+
+// close notifies the client and closes the stream with the given reason.
+func (s *Stream) close(reason error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ctx.Err() != nil {
+		return
+	}
+
+	if err := s.write(StreamClosedEvent, reason.Error()); err != nil {
+		helpers.Logger(s.ctx).Debug("Failed to notify client of stream closure", zap.Error(err))
+	}
+	s.cancel(reason)
+}
+
+// watchStreamSession closes the stream when the session expires, and if StreamRecheckInterval is set, periodically
+// re-verifies the session and claims. It returns once the stream is closed.
+func watchStreamSession(
+	stream *Stream,
+	sessionManager SessionManager,
+	sessionConfig *APIConfiguration,
+	header *SessionHeader,
+	claims *SessionClaims,
+) {
+	if header == nil || claims == nil {
+		<-stream.Done()
+		return
+	}
+
+	expiry := time.NewTimer(time.Until(time.Unix(header.IssuedAt+header.LifetimeSec, 0)))
+	defer expiry.Stop()
+
+	var recheck <-chan time.Time
+	if sessionConfig.StreamRecheckInterval > 0 {
+		ticker := time.NewTicker(sessionConfig.StreamRecheckInterval)
+		defer ticker.Stop()
+		recheck = ticker.C
+	}
+
+	for {
+		select {
+		case <-stream.Done():
+			return
+
+		case <-expiry.C:
+			stream.close(ErrStreamSessionExpired)
+			return
+
+		case <-recheck:
+			if ok, err := sessionManager.VerifySession(stream.ctx, claims, header); err != nil || !ok {
+				helpers.Logger(stream.ctx).Debug("Stream session failed re-verification", zap.Error(err))
+				stream.close(ErrStreamSessionRevoked)
+				return
+			}
+			if ok, err := sessionManager.VerifyClaims(stream.ctx, claims, sessionConfig); err != nil || !ok {
+				helpers.Logger(stream.ctx).Debug("Stream claims failed re-verification", zap.Error(err))
+				stream.close(ErrStreamSessionRevoked)
+				return
+			}
+		}
+	}
+}
+
+// ExecuteStreamRoute runs the same session, RBAC, input and policy checks as ExecuteRoute, then opens a
+// Server-Sent Events stream. The stream is closed when the session expires, or when it fails re-verification
+// every StreamRecheckInterval. Errors returned before anything was sent are written as a normal error response.
+func ExecuteStreamRoute[InputType any, BaseRoute helpers.BaseRouteComponents](
+	ctx *gin.Context,
+	baseRoute BaseRoute,
+	sessionConfig *APIConfiguration,
+	sessionManager SessionManager,
+	validationEngine *validation.Engine,
+	handlerFunc func(input *InputType, stream *Stream, data *Handler[BaseRoute]) *errors.AppError,
+) {
+	if validationEngine == nil {
+		validationEngine = validation.NewEngine(nil)
+	}
+
+	started := time.Now()
+	helpers.EnsureRequestID(ctx)
+	helpers.SetResponseFormat(ctx, sessionConfig.ErrorFormat)
+	if appErr := checkMaintenance(ctx, sessionConfig); appErr != nil {
+		helpers.ErrorResponse(ctx, appErr)
+		return
+	}
+
+	// - Stage 1: Establish Session Context
+	header, claims, csrfToken, group, appErr := _establishSessionContext(ctx, sessionManager, sessionConfig)
+	defer recordRouteAudit(ctx, sessionManager, sessionConfig, claims, started)
+	if appErr != nil {
+		helpers.ErrorResponse(ctx, hiddenByFeatureFlag(ctx, sessionManager, sessionConfig, appErr))
+		return
+	}
+
+	// - Feature flags, before anything tells the route exists
+	if flagErr := processFeatureFlag(ctx, sessionManager, sessionConfig, claims, group); flagErr != nil {
+		helpers.ErrorResponse(ctx, flagErr)
+		return
+	}
+
+	// - Risk checks, e.g., impossible travel
+	if riskErr := processTravelCheck(ctx, sessionManager, sessionConfig, claims); riskErr != nil {
+		helpers.ErrorResponse(ctx, riskErr)
+		return
+	}
+
+	// - Step-up authentication
+	if levelErr := processAuthLevel(ctx, sessionConfig, claims); levelErr != nil {
+		helpers.ErrorResponse(ctx, levelErr)
+		return
+	}
+
+	// - Rbac
+	if rbacErr := processRbac(ctx, sessionManager, sessionConfig, claims); rbacErr != nil {
+		helpers.Logger(ctx).Debug("RBAC processing failed", zap.Error(rbacErr))
+		helpers.ErrorResponse(ctx, rbacErr)
+		return
+	}
+
+	// - Stage 2: Prepare Handler Input
+	input, appErr := prepareHandlerData[InputType](ctx, sessionConfig, validationEngine)
+	if appErr != nil {
+		helpers.ErrorResponse(ctx, appErr)
+		return
+	}
+
+	// - Attribute based policies (evaluated after RBAC, as they need the validated input)
+	if policyErr := processPolicy(ctx, sessionConfig, claims, input); policyErr != nil {
+		helpers.ErrorResponse(ctx, policyErr)
+		return
+	}
+
+	// - Stage 3: Open the stream and watch the session for as long as the handler runs
+	stream := newStream(ctx)
+	watcherDone := make(chan struct{})
+	go func() {
+		defer close(watcherDone)
+		watchStreamSession(stream, sessionManager, sessionConfig, header, claims)
+	}()
+	defer func() {
+		stream.cancel(ErrStreamClosed)
+		<-watcherDone
+	}()
+
+	processReplicaHint(ctx, sessionManager, sessionConfig, claims)
+	handlerData := newHandler(ctx, baseRoute, sessionConfig, sessionManager, header, claims, csrfToken, group)
+	defer handlerData.closeTasks()
+
+	if handlerAppErr := handlerFunc(input, stream, handlerData); handlerAppErr != nil {
+		helpers.Logger(ctx).Debug("Error returned from stream handler", zap.Error(handlerAppErr))
+		if !ctx.Writer.Written() {
+			helpers.ErrorResponse(ctx, handlerAppErr)
+		}
+	}
+}
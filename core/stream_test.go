@@ -0,0 +1,110 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/errors"
+)
+
+type streamTestInput struct{}
+
+func newStreamTestContext() (*gin.Context, *httptest.ResponseRecorder) {
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+	ctx.Request = httptest.NewRequest(http.MethodGet, "/events", nil)
+	return ctx, recorder
+}
+
+func TestExecuteStreamRoute(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mgr := newMockSessionManager(t)
+
+	t.Run("Events are written in the SSE format", func(t *testing.T) {
+		ctx, recorder := newStreamTestContext()
+		ExecuteStreamRoute(ctx, testBaseRoute{}, &APIConfiguration{}, mgr, nil, func(_ *streamTestInput, stream *Stream, _ *Handler[testBaseRoute]) *errors.AppError {
+			_ = stream.Send("greeting", "hello\nworld")
+			_ = stream.SendJSON("", map[string]int{"count": 1})
+			return nil
+		})
+
+		if contentType := recorder.Header().Get("Content-Type"); contentType != "text/event-stream" {
+			t.Errorf("Expected text/event-stream, got '%s'", contentType)
+		}
+		expected := "event: greeting\ndata: hello\ndata: world\n\ndata: {\"count\":1}\n\n"
+		if recorder.Body.String() != expected {
+			t.Errorf("Unexpected body: %q", recorder.Body.String())
+		}
+	})
+
+	t.Run("Line breaks can not inject fields or events", func(t *testing.T) {
+		ctx, recorder := newStreamTestContext()
+		var sendErr error
+		ExecuteStreamRoute(ctx, testBaseRoute{}, &APIConfiguration{}, mgr, nil, func(_ *streamTestInput, stream *Stream, _ *Handler[testBaseRoute]) *errors.AppError {
+			sendErr = stream.Send("greeting\ndata: injected", "hello")
+			_ = stream.Send("", "one\rid: 1\r\ntwo\r\rthree")
+			return nil
+		})
+
+		if sendErr != ErrInvalidStreamEvent {
+			t.Errorf("Expected ErrInvalidStreamEvent, got %v", sendErr)
+		}
+		expected := "data: one\ndata: id: 1\ndata: two\ndata: \ndata: three\n\n"
+		if recorder.Body.String() != expected {
+			t.Errorf("Unexpected body: %q", recorder.Body.String())
+		}
+	})
+
+	t.Run("Errors before the first event are returned as normal responses", func(t *testing.T) {
+		ctx, recorder := newStreamTestContext()
+		ExecuteStreamRoute(ctx, testBaseRoute{}, &APIConfiguration{}, mgr, nil, func(*streamTestInput, *Stream, *Handler[testBaseRoute]) *errors.AppError {
+			return errors.NewBadRequest("no topic", nil)
+		})
+
+		if recorder.Code != http.StatusBadRequest || strings.Contains(recorder.Header().Get("Content-Type"), "event-stream") {
+			t.Errorf("Expected a JSON 400 response, got %d (%s)", recorder.Code, recorder.Header().Get("Content-Type"))
+		}
+	})
+}
+
+func TestWatchStreamSession(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	claims := &SessionClaims{HasSession: true, Claims: map[string]string{SessionModeClaim: "user"}}
+
+	t.Run("Stream is closed once the session expires", func(t *testing.T) {
+		mgr := newMockSessionManager(t)
+		ctx, recorder := newStreamTestContext()
+		stream := newStream(ctx)
+		header := &SessionHeader{IssuedAt: time.Now().Unix() - 10, LifetimeSec: 10, RefreshPeriodSec: 5}
+
+		watchStreamSession(stream, mgr, &APIConfiguration{}, header, claims)
+
+		if stream.Err() != ErrStreamSessionExpired {
+			t.Errorf("Expected ErrStreamSessionExpired, got %v", stream.Err())
+		}
+		if !strings.Contains(recorder.Body.String(), "event: "+StreamClosedEvent) {
+			t.Errorf("Expected the client to be notified, got %q", recorder.Body.String())
+		}
+		if err := stream.Send("late", "data"); err != ErrStreamClosed {
+			t.Errorf("Expected ErrStreamClosed, got %v", err)
+		}
+	})
+
+	t.Run("Revoked sessions are closed on re-check", func(t *testing.T) {
+		mgr := newMockSessionManager(t)
+		mgr.verifySession = false
+		ctx, _ := newStreamTestContext()
+		stream := newStream(ctx)
+		header := &SessionHeader{IssuedAt: time.Now().Unix(), LifetimeSec: 3600, RefreshPeriodSec: 60}
+
+		watchStreamSession(stream, mgr, &APIConfiguration{StreamRecheckInterval: 10 * time.Millisecond}, header, claims)
+
+		if stream.Err() != ErrStreamSessionRevoked {
+			t.Errorf("Expected ErrStreamSessionRevoked, got %v", stream.Err())
+		}
+	})
+}
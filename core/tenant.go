@@ -0,0 +1,84 @@
+package core
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/errors"
+	"github.com/grzegorzmaniak/gothic/helpers"
+	"github.com/grzegorzmaniak/gothic/rbac"
+	"go.uber.org/zap"
+)
+
+const (
+	TenantClaim = "___tn" // Tenant the session is scoped to
+
+	// TenantContextKey is the gin context key holding the tenant the request's RBAC checks were scoped to.
+	TenantContextKey = "gothic_tenant"
+)
+
+// TenantResolver returns the tenant a request acts on, e.g., from the path of /tenants/:tenant/invoices.
+// The RBAC checks of the route are scoped to it, see rbac.TenantManager.
+type TenantResolver func(ctx *gin.Context, claims *SessionClaims) (string, error)
+
+// TenantFromParam resolves the tenant from a path parameter.
+func TenantFromParam(name string) TenantResolver {
+	return func(ctx *gin.Context, _ *SessionClaims) (string, error) {
+		tenant := ctx.Param(name)
+		if tenant == "" {
+			return "", fmt.Errorf("path parameter '%s' is empty", name)
+		}
+		return tenant, nil
+	}
+}
+
+// TenantFromHeader resolves the tenant from a request header, e.g., "X-Tenant-ID".
+func TenantFromHeader(name string) TenantResolver {
+	return func(ctx *gin.Context, _ *SessionClaims) (string, error) {
+		tenant := ctx.GetHeader(name)
+		if tenant == "" {
+			return "", fmt.Errorf("header '%s' is empty", name)
+		}
+		return tenant, nil
+	}
+}
+
+// RequestTenant returns the tenant the request's RBAC checks were scoped to, or an empty string.
+func RequestTenant(ctx *gin.Context) string {
+	return ctx.GetString(TenantContextKey)
+}
+
+// rbacContext returns a context scoping RBAC fetches to the request's tenant.
+func rbacContext(ctx *gin.Context) context.Context {
+	return rbac.WithTenant(ctx, RequestTenant(ctx))
+}
+
+// processTenant resolves the tenant of the request, using the route's TenantResolver or else the session's
+// TenantClaim. A session scoped to a tenant is never accepted for another one.
+func processTenant(ctx *gin.Context, sessionConfig *APIConfiguration, claims *SessionClaims) *errors.AppError {
+	if claims == nil {
+		return nil
+	}
+
+	sessionTenant, _ := claims.GetClaim(TenantClaim)
+	tenant := sessionTenant
+	if sessionConfig.TenantResolver != nil {
+		resolved, err := sessionConfig.TenantResolver(ctx, claims)
+		if err != nil || resolved == "" {
+			helpers.Logger(ctx).Debug("Failed to resolve the tenant", zap.Error(err))
+			return errors.NewBadRequest("Tenant could not be resolved", err)
+		}
+		tenant = resolved
+	}
+
+	if sessionTenant != "" && tenant != sessionTenant {
+		helpers.Logger(ctx).Debug("Session is scoped to another tenant", zap.String("tenant", tenant))
+		return errors.NewUnauthorized("Session is not valid for this tenant", nil)
+	}
+
+	if tenant != "" {
+		ctx.Set(TenantContextKey, tenant)
+	}
+	return nil
+}
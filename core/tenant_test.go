@@ -0,0 +1,99 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	internalcache "github.com/grzegorzmaniak/gothic/cache"
+	"github.com/grzegorzmaniak/gothic/errors"
+	"github.com/grzegorzmaniak/gothic/rbac"
+)
+
+var tenantEditPermission = rbac.NewPermission(4)
+
+// tenantRbacManager makes every subject an editor of the "acme" tenant, and "bob" an editor of every tenant.
+type tenantRbacManager struct {
+	namedRbacManager
+}
+
+func (m *tenantRbacManager) GetTenantSubjectRolesAndPermissions(_ context.Context, tenantIdentifier string, subjectIdentifier string) (rbac.Permissions, []string, error) {
+	if tenantIdentifier == "acme" || subjectIdentifier == "bob" {
+		return nil, []string{"editor"}, nil
+	}
+	return nil, nil, nil
+}
+
+func (m *tenantRbacManager) GetTenantRolePermissions(_ context.Context, _ string, roleIdentifier string) (rbac.Permissions, error) {
+	if roleIdentifier == "editor" {
+		return rbac.Permissions{tenantEditPermission}, nil
+	}
+	return nil, nil
+}
+
+func (m *tenantRbacManager) GetTenantSubjectNamedPermissions(context.Context, string, string) (rbac.PermissionSet, error) {
+	return rbac.PermissionSet{}, nil
+}
+
+func (m *tenantRbacManager) GetTenantRoleNamedPermissions(context.Context, string, string) (rbac.PermissionSet, error) {
+	return rbac.PermissionSet{}, nil
+}
+
+func TestTenantResolver(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mgr := newMockSessionManager(t)
+	mgr.rbacManager = &tenantRbacManager{namedRbacManager{cacheManager: internalcache.BuildDefaultCacheManager(nil)}}
+
+	router := gin.New()
+	ctor := NewRouteConstructor(router, testBaseRoute{}, mgr, nil)
+	handler := func(_ *struct{}, data *Handler[testBaseRoute]) (*benchmarkOutput, *errors.AppError) {
+		return &benchmarkOutput{Message: RequestTenant(data.Context)}, nil
+	}
+	POST(ctor, "/tenants/:tenant/articles", AdminRoute(tenantEditPermission).WithoutCsrf().WithTenantResolver(TenantFromParam("tenant")), handler)
+	POST(ctor, "/articles", AdminRoute(tenantEditPermission).WithoutCsrf(), handler)
+
+	request := func(path string, claims map[string]string) int {
+		ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+		ctx.Request = httptest.NewRequest(http.MethodPost, "/login", nil)
+		token, err := IssueBearerToken(ctx, mgr, "default", &SessionClaims{Claims: claims})
+		if err != nil {
+			t.Fatalf("Failed to issue the bearer token: %v", err)
+		}
+
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		req.Header.Set(DefaultSessionAuthorizationHeaderName, token)
+		router.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	t.Run("Roles only authorize their tenant", func(t *testing.T) {
+		if code := request("/tenants/acme/articles", map[string]string{"subject": "alice"}); code != http.StatusOK {
+			t.Errorf("Expected the acme editor to be allowed, got %d", code)
+		}
+		if code := request("/tenants/globex/articles", map[string]string{"subject": "alice"}); code != http.StatusUnauthorized {
+			t.Errorf("Expected the acme role to be denied in globex, got %d", code)
+		}
+	})
+
+	t.Run("The tenant claim scopes sessions", func(t *testing.T) {
+		if code := request("/articles", map[string]string{"subject": "alice", TenantClaim: "acme"}); code != http.StatusOK {
+			t.Errorf("Expected the claim's tenant to be used, got %d", code)
+		}
+		if code := request("/tenants/globex/articles", map[string]string{"subject": "bob"}); code != http.StatusOK {
+			t.Errorf("Expected an unscoped session of a globex editor to be allowed, got %d", code)
+		}
+		if code := request("/tenants/globex/articles", map[string]string{"subject": "bob", TenantClaim: "acme"}); code != http.StatusUnauthorized {
+			t.Errorf("Expected a session scoped to acme to be rejected for globex, got %d", code)
+		}
+	})
+
+	t.Run("Managers without tenant support fail closed", func(t *testing.T) {
+		mgr.rbacManager = &namedRbacManager{cacheManager: internalcache.BuildDefaultCacheManager(nil)}
+		if code := request("/tenants/acme/articles", map[string]string{"subject": "alice"}); code < http.StatusInternalServerError {
+			t.Errorf("Expected the tenant scoped check to fail, got %d", code)
+		}
+	})
+}
@@ -0,0 +1,309 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/eko/gocache/lib/v4/store"
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/errors"
+	"github.com/grzegorzmaniak/gothic/helpers"
+	"go.uber.org/zap"
+)
+
+// TravelAction is what happens when the impossible travel heuristic fires.
+type TravelAction int
+
+const (
+	// TravelActionNotify only calls the detector's Notify hook, the request continues (Default)
+	TravelActionNotify TravelAction = iota
+
+	// TravelActionStepUp rejects the request until the subject re-verifies, the error carries the
+	// ImpossibleTravelReason so clients can start the verification flow.
+	TravelActionStepUp
+
+	// TravelActionRevoke calls the detector's Revoke hook and rejects the request.
+	TravelActionRevoke
+)
+
+const (
+	SessionUseCacheKeyPrefix = "session_use:" // Key: session_use:<subjectIdentifier>
+
+	// ImpossibleTravelReason is set as the "reason" detail of the errors returned by the detector.
+	ImpossibleTravelReason = "impossible_travel"
+
+	DefaultTravelMaxSpeedKmh    = 1000.0 // A little faster than a commercial flight
+	DefaultTravelMinDistanceKm  = 200.0  // GeoIP databases are rarely more accurate than this
+	DefaultTravelHistoryTTL     = 30 * 24 * time.Hour
+	DefaultTravelNotifyTimeout  = 10 * time.Second
+	minimumTravelElapsedSeconds = 60.0
+	earthRadiusKm               = 6371.0
+)
+
+// GeoLocation is the approximate location of an IP address.
+type GeoLocation struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Country   string  `json:"country,omitempty"`
+	City      string  `json:"city,omitempty"`
+}
+
+// GeoResolver resolves an IP address, e.g., with a MaxMind database. A nil location skips the check.
+type GeoResolver func(ctx context.Context, ip string) (*GeoLocation, error)
+
+// SessionUse is a single use of a session, the last one of each subject is kept in the cache.
+type SessionUse struct {
+	IP        string       `json:"ip"`
+	UserAgent string       `json:"user_agent,omitempty"`
+	Location  *GeoLocation `json:"location,omitempty"`
+	At        time.Time    `json:"at"`
+}
+
+// TravelEvent describes two consecutive session uses that are too far apart to be made by one person.
+type TravelEvent struct {
+	Subject    string       `json:"subject"`
+	Previous   SessionUse   `json:"previous"`
+	Current    SessionUse   `json:"current"`
+	DistanceKm float64      `json:"distance_km"`
+	SpeedKmh   float64      `json:"speed_kmh"`
+	Action     TravelAction `json:"action"`
+	RequestID  string       `json:"request_id,omitempty"`
+}
+
+// ImpossibleTravelDetector compares each session use with the subject's previous one, and acts when the
+// implied travel speed is not humanly possible.
+type ImpossibleTravelDetector struct {
+	// Resolver locates the client IP, required.
+	Resolver GeoResolver
+
+	// MaxSpeedKmh is the fastest plausible travel speed (Default: DefaultTravelMaxSpeedKmh)
+	MaxSpeedKmh float64
+
+	// MinDistanceKm ignores jumps shorter than this, to absorb GeoIP inaccuracy (Default: DefaultTravelMinDistanceKm)
+	MinDistanceKm float64
+
+	// Action taken when the heuristic fires (Default: TravelActionNotify)
+	Action TravelAction
+
+	// Notify is called with every event in the background, regardless of the action, e.g., NewTravelWebhook.
+	Notify func(ctx context.Context, event TravelEvent) error
+
+	// NotifyTimeout bounds each Notify call (Default: DefaultTravelNotifyTimeout)
+	NotifyTimeout time.Duration
+
+	// Revoke invalidates the session for TravelActionRevoke, the request is rejected even if it is nil.
+	Revoke func(ctx context.Context, claims *SessionClaims, event TravelEvent) error
+
+	// HistoryTTL is how long the last use of a subject is remembered (Default: DefaultTravelHistoryTTL)
+	HistoryTTL time.Duration
+}
+
+var travelDetector = struct {
+	sync.RWMutex
+	detector *ImpossibleTravelDetector
+}{}
+
+// SetImpossibleTravelDetector enables the detector for every route (except those with SkipTravelCheck),
+// nil disables it.
+func SetImpossibleTravelDetector(detector *ImpossibleTravelDetector) {
+	travelDetector.Lock()
+	defer travelDetector.Unlock()
+	travelDetector.detector = detector
+}
+
+func getImpossibleTravelDetector() *ImpossibleTravelDetector {
+	travelDetector.RLock()
+	defer travelDetector.RUnlock()
+	return travelDetector.detector
+}
+
+// haversineKm returns the great-circle distance between two locations.
+func haversineKm(a *GeoLocation, b *GeoLocation) float64 {
+	toRadians := func(degrees float64) float64 { return degrees * math.Pi / 180 }
+
+	latitudeDelta := toRadians(b.Latitude - a.Latitude)
+	longitudeDelta := toRadians(b.Longitude - a.Longitude)
+	h := math.Sin(latitudeDelta/2)*math.Sin(latitudeDelta/2) +
+		math.Cos(toRadians(a.Latitude))*math.Cos(toRadians(b.Latitude))*math.Sin(longitudeDelta/2)*math.Sin(longitudeDelta/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(h))
+}
+
+// Evaluate compares the current use with the previous one, it returns nil if the travel is plausible.
+func (d *ImpossibleTravelDetector) Evaluate(previous SessionUse, current SessionUse) *TravelEvent {
+	if previous.Location == nil || current.Location == nil || previous.IP == current.IP {
+		return nil
+	}
+
+	distance := haversineKm(previous.Location, current.Location)
+	if distance < helpers.DefaultFloat64(d.MinDistanceKm, DefaultTravelMinDistanceKm) {
+		return nil
+	}
+
+	// - Uses seconds apart are treated as a minute, so the speed stays finite
+	elapsed := math.Max(current.At.Sub(previous.At).Seconds(), minimumTravelElapsedSeconds)
+	speed := distance / (elapsed / 3600)
+	if speed <= helpers.DefaultFloat64(d.MaxSpeedKmh, DefaultTravelMaxSpeedKmh) {
+		return nil
+	}
+
+	return &TravelEvent{
+		Previous:   previous,
+		Current:    current,
+		DistanceKm: distance,
+		SpeedKmh:   speed,
+		Action:     d.Action,
+	}
+}
+
+// Check records the session use and acts on impossible travel.
+func (d *ImpossibleTravelDetector) Check(ctx *gin.Context, sessionManager SessionManager, claims *SessionClaims) *errors.AppError {
+	if d.Resolver == nil || claims == nil || !claims.HasSession {
+		return nil
+	}
+
+	subjectIdentifier, err := sessionManager.GetSubjectIdentifier(claims)
+	if err != nil || subjectIdentifier == "" {
+		return nil
+	}
+
+	current := SessionUse{IP: ctx.ClientIP(), UserAgent: ctx.Request.UserAgent(), At: time.Now()}
+	current.Location, err = d.Resolver(ctx, current.IP)
+	if err != nil || current.Location == nil {
+		helpers.Logger(ctx).Debug("Unable to resolve client location, skipping travel check", zap.Error(err))
+		return nil
+	}
+
+	cacheInstance, err := sessionManager.GetCache()
+	if err != nil || cacheInstance == nil {
+		helpers.Logger(ctx).Debug("Cache is not available, skipping travel check", zap.Error(err))
+		return nil
+	}
+
+	cacheKey := SessionUseCacheKeyPrefix + subjectIdentifier
+	var event *TravelEvent
+	if cached, getErr := cacheInstance.Get(ctx, cacheKey); getErr == nil {
+		var previous SessionUse
+		if json.Unmarshal(cached, &previous) == nil {
+			event = d.Evaluate(previous, current)
+		}
+	}
+
+	if marshaled, marshalErr := json.Marshal(current); marshalErr == nil {
+		ttl := helpers.DefaultTimeDuration(d.HistoryTTL, DefaultTravelHistoryTTL)
+		if setErr := cacheInstance.Set(ctx, cacheKey, marshaled, store.WithExpiration(ttl)); setErr != nil {
+			helpers.Logger(ctx).Debug("Failed to store session use", zap.Error(setErr))
+		}
+	}
+
+	if event == nil {
+		return nil
+	}
+
+	event.Subject = subjectIdentifier
+	event.RequestID = helpers.GetRequestID(ctx)
+	helpers.Logger(ctx).Warn("Impossible travel detected",
+		zap.String("subject", subjectIdentifier),
+		zap.Float64("distance_km", event.DistanceKm),
+		zap.Float64("speed_kmh", event.SpeedKmh))
+	d.notify(ctx, sessionManager, *event)
+
+	details := map[string]interface{}{"reason": ImpossibleTravelReason}
+	switch d.Action {
+	case TravelActionStepUp:
+		details["step_up"] = true
+		return errors.NewUnauthorized("Additional verification is required", nil, details)
+
+	case TravelActionRevoke:
+		if d.Revoke != nil {
+			if revokeErr := d.Revoke(ctx, claims, *event); revokeErr != nil {
+				helpers.Logger(ctx).Error("Failed to revoke session after impossible travel", zap.Error(revokeErr))
+			}
+		}
+		return errors.NewUnauthorized("Session has been revoked", nil, details)
+
+	default:
+		return nil
+	}
+}
+
+// notify calls the Notify hook in the background, it outlives the request but not the timeout, nor the session
+// manager's shutdown.
+func (d *ImpossibleTravelDetector) notify(ctx *gin.Context, sessionManager SessionManager, event TravelEvent) {
+	if d.Notify == nil {
+		return
+	}
+
+	requestCtx := context.WithoutCancel(ctx.Request.Context())
+	started := helpers.LifecycleOf(sessionManager).Go(func(backgroundCtx context.Context) {
+		notifyCtx, cancel := context.WithTimeout(requestCtx, helpers.DefaultTimeDuration(d.NotifyTimeout, DefaultTravelNotifyTimeout))
+		defer cancel()
+		stop := context.AfterFunc(backgroundCtx, cancel)
+		defer stop()
+
+		if err := d.Notify(notifyCtx, event); err != nil {
+			helpers.Logger(notifyCtx).Warn("Impossible travel notification failed", zap.Error(err))
+		}
+	})
+	if !started {
+		helpers.Logger(ctx).Warn("Impossible travel notification dropped, session manager is shutting down")
+	}
+}
+
+// NewTravelWebhook returns a Notify hook that POSTs the event as JSON to the URL, a nil client uses
+// http.DefaultClient.
+func NewTravelWebhook(url string, client *http.Client) func(ctx context.Context, event TravelEvent) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return func(ctx context.Context, event TravelEvent) error {
+		body, err := json.Marshal(event)
+		if err != nil {
+			return fmt.Errorf("failed to marshal travel event: %w", err)
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return fmt.Errorf("failed to create webhook request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if event.RequestID != "" {
+			req.Header.Set(helpers.RequestIDHeader, event.RequestID)
+		}
+
+		res, err := client.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to call webhook: %w", err)
+		}
+		defer res.Body.Close()
+
+		if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusMultipleChoices {
+			return fmt.Errorf("webhook responded with status %d", res.StatusCode)
+		}
+		return nil
+	}
+}
+
+// processTravelCheck runs the global impossible travel detector, if one is set.
+func processTravelCheck(
+	ctx *gin.Context,
+	sessionManager SessionManager,
+	sessionConfig *APIConfiguration,
+	claims *SessionClaims,
+) *errors.AppError {
+	if sessionConfig.SkipTravelCheck {
+		return nil
+	}
+
+	detector := getImpossibleTravelDetector()
+	if detector == nil {
+		return nil
+	}
+	return detector.Check(ctx, sessionManager, claims)
+}
@@ -0,0 +1,169 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	travelWarsaw = &GeoLocation{Latitude: 52.2297, Longitude: 21.0122}
+	travelBerlin = &GeoLocation{Latitude: 52.5200, Longitude: 13.4050}
+	travelTokyo  = &GeoLocation{Latitude: 35.6762, Longitude: 139.6503}
+)
+
+func TestImpossibleTravelDetector_Evaluate(t *testing.T) {
+	detector := &ImpossibleTravelDetector{}
+	now := time.Now()
+
+	tests := []struct {
+		name     string
+		previous SessionUse
+		current  SessionUse
+		fires    bool
+	}{
+		{
+			name:     "Warsaw to Tokyo in an hour",
+			previous: SessionUse{IP: "a", Location: travelWarsaw, At: now.Add(-time.Hour)},
+			current:  SessionUse{IP: "b", Location: travelTokyo, At: now},
+			fires:    true,
+		},
+		{
+			name:     "Warsaw to Tokyo in a day",
+			previous: SessionUse{IP: "a", Location: travelWarsaw, At: now.Add(-24 * time.Hour)},
+			current:  SessionUse{IP: "b", Location: travelTokyo, At: now},
+			fires:    false,
+		},
+		{
+			name:     "Warsaw to Berlin in an hour is a plausible flight",
+			previous: SessionUse{IP: "a", Location: travelWarsaw, At: now.Add(-time.Hour)},
+			current:  SessionUse{IP: "b", Location: travelBerlin, At: now},
+			fires:    false,
+		},
+		{
+			name:     "Same IP is never travel",
+			previous: SessionUse{IP: "a", Location: travelWarsaw, At: now.Add(-time.Second)},
+			current:  SessionUse{IP: "a", Location: travelTokyo, At: now},
+			fires:    false,
+		},
+		{
+			name:     "Unknown location",
+			previous: SessionUse{IP: "a", At: now.Add(-time.Second)},
+			current:  SessionUse{IP: "b", Location: travelTokyo, At: now},
+			fires:    false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event := detector.Evaluate(tt.previous, tt.current)
+			if (event != nil) != tt.fires {
+				t.Errorf("Expected fires=%v, got %+v", tt.fires, event)
+			}
+		})
+	}
+}
+
+func TestImpossibleTravelDetector_Check(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	locations := map[string]*GeoLocation{"192.0.2.1": travelWarsaw, "198.51.100.1": travelTokyo}
+	resolver := func(_ context.Context, ip string) (*GeoLocation, error) {
+		return locations[ip], nil
+	}
+
+	use := func(detector *ImpossibleTravelDetector, mgr *mockSessionManager, subject string, ip string) (int, bool) {
+		ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+		ctx.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+		ctx.Request.RemoteAddr = ip + ":1234"
+
+		claims := &SessionClaims{HasSession: true, Claims: map[string]string{"subject": subject}}
+		appErr := detector.Check(ctx, mgr, claims)
+
+		// - The default cache applies writes asynchronously
+		time.Sleep(10 * time.Millisecond)
+		if appErr != nil {
+			return appErr.Code, appErr.Details != nil
+		}
+		return 0, false
+	}
+
+	t.Run("Notify lets the request through", func(t *testing.T) {
+		notified := make(chan TravelEvent, 1)
+		detector := &ImpossibleTravelDetector{Resolver: resolver, Notify: func(_ context.Context, event TravelEvent) error {
+			notified <- event
+			return nil
+		}}
+		mgr := newMockSessionManager(t)
+
+		if code, _ := use(detector, mgr, "user-1", "192.0.2.1"); code != 0 {
+			t.Fatalf("Expected the first use to pass, got %d", code)
+		}
+		if code, _ := use(detector, mgr, "user-1", "198.51.100.1"); code != 0 {
+			t.Fatalf("Expected notify to let the request through, got %d", code)
+		}
+
+		select {
+		case event := <-notified:
+			if event.Subject != "user-1" || event.DistanceKm < 8000 {
+				t.Errorf("Expected an event for user-1 over ~8600km, got %+v", event)
+			}
+		case <-time.After(time.Second):
+			t.Error("Expected the Notify hook to be called")
+		}
+	})
+
+	t.Run("Step-up rejects the request", func(t *testing.T) {
+		detector := &ImpossibleTravelDetector{Resolver: resolver, Action: TravelActionStepUp}
+		mgr := newMockSessionManager(t)
+
+		use(detector, mgr, "user-2", "192.0.2.1")
+		if code, hasDetails := use(detector, mgr, "user-2", "198.51.100.1"); code != http.StatusUnauthorized || !hasDetails {
+			t.Errorf("Expected 401 with details, got %d", code)
+		}
+	})
+
+	t.Run("Revoke calls the hook", func(t *testing.T) {
+		revoked := ""
+		detector := &ImpossibleTravelDetector{Resolver: resolver, Action: TravelActionRevoke, Revoke: func(_ context.Context, _ *SessionClaims, event TravelEvent) error {
+			revoked = event.Subject
+			return nil
+		}}
+		mgr := newMockSessionManager(t)
+
+		use(detector, mgr, "user-3", "192.0.2.1")
+		if code, _ := use(detector, mgr, "user-3", "198.51.100.1"); code != http.StatusUnauthorized || revoked != "user-3" {
+			t.Errorf("Expected 401 and a revoked session, got %d (revoked: %q)", code, revoked)
+		}
+	})
+
+	t.Run("Subjects are tracked separately", func(t *testing.T) {
+		detector := &ImpossibleTravelDetector{Resolver: resolver, Action: TravelActionStepUp}
+		mgr := newMockSessionManager(t)
+
+		use(detector, mgr, "user-4", "192.0.2.1")
+		if code, _ := use(detector, mgr, "user-5", "198.51.100.1"); code != 0 {
+			t.Errorf("Expected another subject to pass, got %d", code)
+		}
+	})
+}
+
+func TestNewTravelWebhook(t *testing.T) {
+	var received TravelEvent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&received)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	if err := NewTravelWebhook(server.URL, nil)(context.Background(), TravelEvent{Subject: "user-1"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if received.Subject != "user-1" {
+		t.Errorf("Expected the event to be posted, got %+v", received)
+	}
+}
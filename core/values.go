@@ -0,0 +1,47 @@
+package core
+
+import "sync"
+
+// Values is a request scoped store of values, e.g., a record loaded by a HookPreHandler hook for the handler. It is
+// kept apart from the gin.Context keys, so values never leak to middleware and other routes. Read them with Value
+// for type safety. The zero value is ready to use and safe for concurrent use.
+type Values struct {
+	mu     sync.RWMutex
+	values map[string]interface{}
+}
+
+// Set stores a value under key, replacing any earlier one.
+func (v *Values) Set(key string, value interface{}) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.values == nil {
+		v.values = make(map[string]interface{})
+	}
+	v.values[key] = value
+}
+
+// Get returns the value stored under key, see Value for a typed read.
+func (v *Values) Get(key string) (interface{}, bool) {
+	if v == nil {
+		return nil, false
+	}
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	value, ok := v.values[key]
+	return value, ok
+}
+
+// Delete removes the value stored under key.
+func (v *Values) Delete(key string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	delete(v.values, key)
+}
+
+// Value returns the value stored under key as T, ok is false when it is missing or of another type, e.g.,
+// user, ok := core.Value[*User](data.Values, "user").
+func Value[T any](values *Values, key string) (T, bool) {
+	value, found := values.Get(key)
+	typed, ok := value.(T)
+	return typed, found && ok
+}
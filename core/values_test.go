@@ -0,0 +1,66 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/errors"
+)
+
+func TestValues(t *testing.T) {
+	type user struct {
+		Name string
+	}
+
+	values := &Values{}
+	values.Set("user", &user{Name: "ada"})
+	values.Set("count", 3)
+
+	if loaded, ok := Value[*user](values, "user"); !ok || loaded.Name != "ada" {
+		t.Errorf("Expected the stored user, got %v", loaded)
+	}
+	if _, ok := Value[string](values, "count"); ok {
+		t.Error("Expected a value of another type not to be returned")
+	}
+	if _, ok := Value[int](values, "missing"); ok {
+		t.Error("Expected a missing value not to be returned")
+	}
+	values.Delete("count")
+	if _, ok := values.Get("count"); ok {
+		t.Error("Expected the deleted value to be gone")
+	}
+	if _, ok := Value[int](nil, "count"); ok {
+		t.Error("Expected nil values to hold nothing")
+	}
+}
+
+func TestValuesFromHooks(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mgr := newMockSessionManager(t)
+
+	type output struct {
+		Tenant string `json:"tenant"`
+	}
+
+	router := gin.New()
+	ctor := NewRouteConstructor(router, testBaseRoute{}, mgr, nil)
+	ctor.AddHooks(Hooks{PreSession: []Hook{func(hook *HookContext) *errors.AppError {
+		hook.Values.Set("tenant", "acme")
+		return nil
+	}}})
+	GET(ctor, "/tenant", PublicRoute(), func(_ *struct{}, data *Handler[testBaseRoute]) (*output, *errors.AppError) {
+		tenant, ok := Value[string](data.Values, "tenant")
+		if !ok {
+			return nil, errors.NewInternalServerError("Tenant not resolved", nil)
+		}
+		return &output{Tenant: tenant}, nil
+	})
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/tenant", nil))
+	if recorder.Code != http.StatusOK || recorder.Body.String() != `{"tenant":"acme"}` {
+		t.Errorf("Expected the hook's value in the handler, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+}
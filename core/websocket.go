@@ -0,0 +1,148 @@
+package core
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/errors"
+	"github.com/grzegorzmaniak/gothic/helpers"
+	"go.uber.org/zap"
+)
+
+const (
+	// WebSocketCsrfQueryParam is read when the CSRF header is missing, browsers cannot set custom headers on the
+	// WebSocket handshake. The double-submit check against the cookie still applies.
+	WebSocketCsrfQueryParam = "csrf_token"
+
+	// WebSocketCsrfNonceQueryParam is read when the nonce header is missing, see CsrfCookieData.Nonces.
+	WebSocketCsrfNonceQueryParam = "csrf_nonce"
+
+	webSocketHandshakeContextKey = "gothic_websocket_handshake"
+)
+
+// WebSocketUpgrader upgrades an HTTP connection, it is library agnostic, e.g., *websocket.Upgrader from
+// gorilla/websocket satisfies WebSocketUpgrader[*websocket.Conn].
+type WebSocketUpgrader[Conn any] interface {
+	Upgrade(w http.ResponseWriter, r *http.Request, responseHeader http.Header) (Conn, error)
+}
+
+// WebSocketUpgraderFunc is an adapter to allow the use of ordinary functions as a WebSocketUpgrader.
+type WebSocketUpgraderFunc[Conn any] func(w http.ResponseWriter, r *http.Request, responseHeader http.Header) (Conn, error)
+
+func (f WebSocketUpgraderFunc[Conn]) Upgrade(w http.ResponseWriter, r *http.Request, responseHeader http.Header) (Conn, error) {
+	return f(w, r, responseHeader)
+}
+
+// promoteWebSocketCsrf copies the CSRF token and nonce from the query string into their headers, if the headers
+// are not set.
+func promoteWebSocketCsrf(ctx *gin.Context, sessionManager SessionManager) {
+	csrfData := sessionManager.GetCsrfData()
+	if csrfData == nil {
+		return
+	}
+
+	promote := func(header string, param string) {
+		if ctx.GetHeader(header) != "" {
+			return
+		}
+		if value := ctx.Query(param); value != "" {
+			ctx.Request.Header.Set(header, value)
+		}
+	}
+	promote(helpers.DefaultString(csrfData.Name, DefaultCsrfCookieName), WebSocketCsrfQueryParam)
+	promote(helpers.DefaultString(csrfData.NonceHeaderName, DefaultCsrfNonceHeaderName), WebSocketCsrfNonceQueryParam)
+}
+
+// pendingResponseHeaders returns the headers (e.g., refreshed session or CSRF cookies) written to the context
+// before the upgrade, as the upgrader writes its own response and would otherwise drop them.
+func pendingResponseHeaders(ctx *gin.Context) http.Header {
+	header := http.Header{}
+	for _, cookie := range ctx.Writer.Header().Values("Set-Cookie") {
+		header.Add("Set-Cookie", cookie)
+	}
+	return header
+}
+
+// ExecuteWebSocketRoute runs the same session, CSRF (for cookie sessions), RBAC and policy checks as ExecuteRoute
+// before upgrading the connection. Handshakes are always GET requests, so the CSRF check of routes with
+// RequireCsrf ignores ExemptMethods and the safe methods, otherwise cookie sessions would be open to cross-site
+// WebSocket hijacking. The handler owns the connection and is responsible for closing it, any
+// sub-tasks it started are cancelled once it returns.
+func ExecuteWebSocketRoute[Conn any, BaseRoute helpers.BaseRouteComponents](
+	ctx *gin.Context,
+	baseRoute BaseRoute,
+	sessionConfig *APIConfiguration,
+	sessionManager SessionManager,
+	upgrader WebSocketUpgrader[Conn],
+	handlerFunc func(conn Conn, data *Handler[BaseRoute]),
+) {
+	started := time.Now()
+	helpers.EnsureRequestID(ctx)
+	helpers.SetResponseFormat(ctx, sessionConfig.ErrorFormat)
+	if appErr := checkMaintenance(ctx, sessionConfig); appErr != nil {
+		helpers.ErrorResponse(ctx, appErr)
+		return
+	}
+
+	if upgrader == nil {
+		helpers.ErrorResponse(ctx, errors.NewInternalServerError("WebSocket upgrader is not set", nil))
+		return
+	}
+
+	ctx.Set(webSocketHandshakeContextKey, true)
+	promoteWebSocketCsrf(ctx, sessionManager)
+
+	// - Stage 1: Establish Session Context
+	header, claims, csrfToken, group, appErr := _establishSessionContext(ctx, sessionManager, sessionConfig)
+	defer recordRouteAudit(ctx, sessionManager, sessionConfig, claims, started)
+	if appErr != nil {
+		helpers.ErrorResponse(ctx, hiddenByFeatureFlag(ctx, sessionManager, sessionConfig, appErr))
+		return
+	}
+
+	// - Feature flags, before anything tells the route exists
+	if flagErr := processFeatureFlag(ctx, sessionManager, sessionConfig, claims, group); flagErr != nil {
+		helpers.ErrorResponse(ctx, flagErr)
+		return
+	}
+
+	// - Risk checks, e.g., impossible travel
+	if riskErr := processTravelCheck(ctx, sessionManager, sessionConfig, claims); riskErr != nil {
+		helpers.ErrorResponse(ctx, riskErr)
+		return
+	}
+
+	// - Step-up authentication
+	if levelErr := processAuthLevel(ctx, sessionConfig, claims); levelErr != nil {
+		helpers.ErrorResponse(ctx, levelErr)
+		return
+	}
+
+	// - Rbac
+	if rbacErr := processRbac(ctx, sessionManager, sessionConfig, claims); rbacErr != nil {
+		helpers.Logger(ctx).Debug("RBAC processing failed", zap.Error(rbacErr))
+		helpers.ErrorResponse(ctx, rbacErr)
+		return
+	}
+
+	// - Attribute based policies, there is no input on a handshake
+	if policyErr := processPolicy(ctx, sessionConfig, claims, nil); policyErr != nil {
+		helpers.ErrorResponse(ctx, policyErr)
+		return
+	}
+
+	// - Stage 2: Upgrade, the upgrader writes the error response itself on failure
+	conn, err := upgrader.Upgrade(ctx.Writer, ctx.Request, pendingResponseHeaders(ctx))
+	if err != nil {
+		helpers.Logger(ctx).Debug("WebSocket upgrade failed", zap.Error(err))
+		return
+	}
+
+	// - Stage 3: Hand the connection over to the handler
+	processReplicaHint(ctx, sessionManager, sessionConfig, claims)
+	handlerData := newHandler(ctx, baseRoute, sessionConfig, sessionManager, header, claims, csrfToken, group)
+	defer handlerData.closeTasks()
+
+	handlerFunc(conn, handlerData)
+}
@@ -0,0 +1,109 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+type fakeWebSocketConn struct {
+	responseHeader http.Header
+}
+
+func TestExecuteWebSocketRoute(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mgr := newMockSessionManager(t)
+
+	upgrader := WebSocketUpgraderFunc[*fakeWebSocketConn](func(w http.ResponseWriter, r *http.Request, responseHeader http.Header) (*fakeWebSocketConn, error) {
+		w.WriteHeader(http.StatusSwitchingProtocols)
+		return &fakeWebSocketConn{responseHeader: responseHeader}, nil
+	})
+
+	newContext := func(target string) (*gin.Context, *httptest.ResponseRecorder) {
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+		ctx.Request = httptest.NewRequest(http.MethodGet, target, nil)
+		return ctx, recorder
+	}
+
+	t.Run("Anonymous handshake is upgraded with the pending CSRF cookie", func(t *testing.T) {
+		ctx, _ := newContext("/ws")
+		var conn *fakeWebSocketConn
+		ExecuteWebSocketRoute(ctx, testBaseRoute{}, &APIConfiguration{}, mgr, upgrader, func(c *fakeWebSocketConn, data *Handler[testBaseRoute]) {
+			conn = c
+		})
+
+		if ctx.Writer.Status() != http.StatusSwitchingProtocols || conn == nil {
+			t.Fatalf("Expected the connection to be upgraded, got status %d", ctx.Writer.Status())
+		}
+		if len(conn.responseHeader.Values("Set-Cookie")) == 0 {
+			t.Error("Expected the CSRF cookie to be passed to the upgrader")
+		}
+	})
+
+	t.Run("Gate failures are rejected before upgrading", func(t *testing.T) {
+		ctx, recorder := newContext("/ws")
+		called := false
+		ExecuteWebSocketRoute(ctx, testBaseRoute{}, &APIConfiguration{SessionRequired: true}, mgr, upgrader, func(*fakeWebSocketConn, *Handler[testBaseRoute]) {
+			called = true
+		})
+
+		if called || recorder.Code != http.StatusUnauthorized {
+			t.Errorf("Expected 401 without upgrading, got %d (handler called: %v)", recorder.Code, called)
+		}
+	})
+
+	t.Run("Cookie sessions need a valid CSRF token on the handshake", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		loginCtx, _ := gin.CreateTestContext(recorder)
+		loginCtx.Request = httptest.NewRequest(http.MethodPost, "/login", nil)
+		if err := SetSessionCookie(loginCtx, mgr, "user", &SessionClaims{Claims: map[string]string{"subject": "user-1"}}); err != nil {
+			t.Fatalf("Failed to set session cookie: %v", err)
+		}
+		cookies := recorder.Result().Cookies()
+		var csrf string
+		for _, cookie := range cookies {
+			if cookie.Name == DefaultCsrfCookieName {
+				csrf = cookie.Value
+			}
+		}
+
+		for _, tt := range []struct {
+			name   string
+			target string
+			config *APIConfiguration
+			want   int
+		}{
+			{"Missing token", "/ws", AuthenticatedJSONAPI(), http.StatusUnauthorized},
+			{"Forged token", "/ws?" + WebSocketCsrfQueryParam + "=" + strings.Repeat("a", MinimumCsrfHeaderSize), AuthenticatedJSONAPI(), http.StatusUnauthorized},
+			{"Exempt GET", "/ws", AuthenticatedJSONAPI().WithCsrfExemptMethods(http.MethodGet), http.StatusUnauthorized},
+			{"Valid token", "/ws?" + WebSocketCsrfQueryParam + "=" + url.QueryEscape(csrf), AuthenticatedJSONAPI(), http.StatusSwitchingProtocols},
+		} {
+			t.Run(tt.name, func(t *testing.T) {
+				ctx, _ := newContext(tt.target)
+				for _, cookie := range cookies {
+					ctx.Request.AddCookie(cookie)
+				}
+				called := false
+				ExecuteWebSocketRoute(ctx, testBaseRoute{}, tt.config, mgr, upgrader, func(*fakeWebSocketConn, *Handler[testBaseRoute]) {
+					called = true
+				})
+				if ctx.Writer.Status() != tt.want || called != (tt.want == http.StatusSwitchingProtocols) {
+					t.Errorf("Expected %d, got %d (handler called: %v)", tt.want, ctx.Writer.Status(), called)
+				}
+			})
+		}
+	})
+
+	t.Run("CSRF token is read from the query string", func(t *testing.T) {
+		ctx, _ := newContext("/ws?" + WebSocketCsrfQueryParam + "=token")
+		promoteWebSocketCsrf(ctx, mgr)
+		if got := ctx.GetHeader(DefaultCsrfCookieName); got != "token" {
+			t.Errorf("Expected the CSRF header to be 'token', got '%s'", got)
+		}
+	})
+}
@@ -23,6 +23,15 @@ type AppError struct {
 	// Details can hold any additional structured information about the error
 	// that might be useful for the client to consume.
 	Details interface{} `json:"details,omitempty"`
+
+	// Type is the RFC 7807 problem type URI, only used by problem+json responses.
+	Type string `json:"-"`
+
+	// Instance identifies this occurrence of the problem, only used by problem+json responses.
+	Instance string `json:"-"`
+
+	// Extensions are additional RFC 7807 members, only used by problem+json responses.
+	Extensions map[string]interface{} `json:"-"`
 }
 
 // Error implements the standard error interface.
@@ -39,19 +48,105 @@ func (e *AppError) Unwrap() error {
 	return e.Err
 }
 
-// FormatValidationErrors converts validator.ValidationErrors into a map for structured client responses.
+// ValidationErrorsFormat selects how FormatValidationErrors describes validation failures.
+type ValidationErrorsFormat int
+
+const (
+	// ValidationErrorsStructured emits a []ValidationErrorDetail.
+	ValidationErrorsStructured ValidationErrorsFormat = iota
+
+	// ValidationErrorsMap emits the previous map of field namespace to "failed on validation tag '<tag>'", for
+	// clients that still parse it.
+	ValidationErrorsMap
+)
+
+// ValidationErrorFormat is the format used by FormatValidationErrors and NewValidationFailed, set it at startup
+// (Default: ValidationErrorsStructured)
+var ValidationErrorFormat = ValidationErrorsStructured
+
+// ValidationErrorDetail describes the failed validation of a field, so clients can map errors to form fields.
+type ValidationErrorDetail struct {
+	// Field is the namespace of the field, e.g., "User.Address.City".
+	Field string `json:"field"`
+
+	// Tag is the failed validate tag, e.g., "min", and Param its parameter, e.g., "3".
+	Tag   string `json:"tag"`
+	Param string `json:"param,omitempty"`
+
+	// Code is a stable machine-readable reason, see ValidationErrorCode.
+	Code string `json:"code"`
+
+	// Message is a human-readable description.
+	Message string `json:"message"`
+}
+
+// ValidationErrorCode returns the code of a failed validate tag: "required", "too_small", "too_large",
+// "invalid_length", "not_allowed", "mismatch", "invalid_format", or "invalid" for any other tag, custom ones
+// included.
+func ValidationErrorCode(tag string) string {
+	switch tag {
+	case "required", "required_if", "required_unless", "required_with", "required_with_all", "required_without",
+		"required_without_all":
+		return "required"
+	case "min", "gt", "gte":
+		return "too_small"
+	case "max", "lt", "lte":
+		return "too_large"
+	case "len":
+		return "invalid_length"
+	case "oneof":
+		return "not_allowed"
+	case "eqfield", "eqcsfield", "nefield", "necsfield":
+		return "mismatch"
+	case "email", "url", "uri", "uuid", "uuid4", "alpha", "alphanum", "numeric", "number", "hexadecimal", "ip",
+		"ipv4", "ipv6", "datetime", "e164", "jwt":
+		return "invalid_format"
+	default:
+		return "invalid"
+	}
+}
+
+// FormatValidationErrors converts validator.ValidationErrors into structured details for client responses, see
+// ValidationErrorFormat.
 // If the error is not a validator.ValidationErrors but is still non-nil, it returns the error message string.
 // If the error is nil, it returns nil.
 func FormatValidationErrors(err error) interface{} {
+	return FormatTranslatedValidationErrors(err, nil)
+}
+
+// FormatTranslatedValidationErrors is FormatValidationErrors with the messages produced by translate, e.g., with
+// FieldError.Translate. A nil translate keeps the default messages.
+func FormatTranslatedValidationErrors(err error, translate func(validator.FieldError) string) interface{} {
 	if err == nil {
 		return nil
 	}
 
 	var ves validator.ValidationErrors
 	if errors.As(err, &ves) {
-		out := make(map[string]string)
+		message := func(fe validator.FieldError) string {
+			if translate != nil {
+				return translate(fe)
+			}
+			return fmt.Sprintf("failed on validation tag '%s'", fe.Tag())
+		}
+
+		if ValidationErrorFormat == ValidationErrorsMap {
+			out := make(map[string]string)
+			for _, fe := range ves {
+				out[fe.Namespace()] = message(fe)
+			}
+			return out
+		}
+
+		out := make([]ValidationErrorDetail, 0, len(ves))
 		for _, fe := range ves {
-			out[fe.Namespace()] = fmt.Sprintf("failed on validation tag '%s'", fe.Tag())
+			out = append(out, ValidationErrorDetail{
+				Field:   fe.Namespace(),
+				Tag:     fe.Tag(),
+				Param:   fe.Param(),
+				Code:    ValidationErrorCode(fe.Tag()),
+				Message: message(fe),
+			})
 		}
 		return out
 	}
@@ -56,6 +56,40 @@ func TestFormatValidationErrors(t *testing.T) {
 		user := User{}
 		err := validate.Struct(user)
 
+		formatted := FormatValidationErrors(err)
+		expected := []ValidationErrorDetail{{
+			Field:   "User.Username",
+			Tag:     "required",
+			Code:    "required",
+			Message: "failed on validation tag 'required'",
+		}}
+
+		if !reflect.DeepEqual(formatted, expected) {
+			t.Errorf("Expected formatted validation errors '%v', got '%v'", expected, formatted)
+		}
+	})
+
+	t.Run("with a tag parameter", func(t *testing.T) {
+		type User struct {
+			Username string `validate:"min=3"`
+		}
+		formatted, ok := FormatValidationErrors(validator.New().Struct(User{Username: "a"})).([]ValidationErrorDetail)
+		if !ok || len(formatted) != 1 || formatted[0].Param != "3" || formatted[0].Code != "too_small" {
+			t.Errorf("Expected a too_small detail with param 3, got '%v'", formatted)
+		}
+	})
+
+	t.Run("with the map compatibility format", func(t *testing.T) {
+		ValidationErrorFormat = ValidationErrorsMap
+		defer func() { ValidationErrorFormat = ValidationErrorsStructured }()
+
+		validate := validator.New()
+		type User struct {
+			Username string `validate:"required"`
+		}
+		user := User{}
+		err := validate.Struct(user)
+
 		formatted := FormatValidationErrors(err)
 		expected := map[string]string{
 			"User.Username": "failed on validation tag 'required'",
@@ -66,6 +100,18 @@ func TestFormatValidationErrors(t *testing.T) {
 		}
 	})
 
+	t.Run("with translated messages", func(t *testing.T) {
+		type User struct {
+			Username string `validate:"required"`
+		}
+		formatted, ok := FormatTranslatedValidationErrors(validator.New().Struct(User{}), func(fe validator.FieldError) string {
+			return fe.Field() + " is required"
+		}).([]ValidationErrorDetail)
+		if !ok || len(formatted) != 1 || formatted[0].Message != "Username is required" {
+			t.Errorf("Expected the translated message, got '%v'", formatted)
+		}
+	})
+
 	t.Run("with other non-nil error", func(t *testing.T) {
 		err := errors.New("a simple error")
 		formatted := FormatValidationErrors(err)
@@ -75,6 +121,26 @@ func TestFormatValidationErrors(t *testing.T) {
 	})
 }
 
+// TestValidationErrorCode tests the codes of validate tags.
+func TestValidationErrorCode(t *testing.T) {
+	tests := map[string]string{
+		"required":    "required",
+		"required_if": "required",
+		"gte":         "too_small",
+		"max":         "too_large",
+		"len":         "invalid_length",
+		"oneof":       "not_allowed",
+		"eqfield":     "mismatch",
+		"email":       "invalid_format",
+		"slug":        "invalid",
+	}
+	for tag, expected := range tests {
+		if code := ValidationErrorCode(tag); code != expected {
+			t.Errorf("Expected code '%s' for tag '%s', got '%s'", expected, tag, code)
+		}
+	}
+}
+
 // TestNewAppError tests the constructor for AppError.
 func TestNewAppError(t *testing.T) {
 	underlyingErr := errors.New("underlying")
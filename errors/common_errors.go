@@ -34,6 +34,14 @@ func NewNotFound(message string, underlyingErr error, details ...interface{}) *A
 	return NewAppError(http.StatusNotFound, message, underlyingErr, details...)
 }
 
+// NewMethodNotAllowed creates a new 405 Method Not Allowed AppError, the response should carry an Allow header.
+func NewMethodNotAllowed(message string, underlyingErr error, details ...interface{}) *AppError {
+	if message == "" {
+		message = "The method is not allowed for the requested resource."
+	}
+	return NewAppError(http.StatusMethodNotAllowed, message, underlyingErr, details...)
+}
+
 // NewConflict creates a new 409 Conflict AppError.
 func NewConflict(message string, underlyingErr error, details ...interface{}) *AppError {
 	if message == "" {
@@ -42,6 +50,22 @@ func NewConflict(message string, underlyingErr error, details ...interface{}) *A
 	return NewAppError(http.StatusConflict, message, underlyingErr, details...)
 }
 
+// NewRequestTimeout creates a new 408 Request Timeout AppError, e.g., when the request body is sent too slowly.
+func NewRequestTimeout(message string, underlyingErr error, details ...interface{}) *AppError {
+	if message == "" {
+		message = "The server timed out waiting for the request."
+	}
+	return NewAppError(http.StatusRequestTimeout, message, underlyingErr, details...)
+}
+
+// NewPayloadTooLarge creates a new 413 Content Too Large AppError.
+func NewPayloadTooLarge(message string, underlyingErr error, details ...interface{}) *AppError {
+	if message == "" {
+		message = "The request body is larger than the server is willing to process."
+	}
+	return NewAppError(http.StatusRequestEntityTooLarge, message, underlyingErr, details...)
+}
+
 // NewInternalServerError creates a new 500 Internal Server Error AppError.
 func NewInternalServerError(message string, underlyingErr error, details ...interface{}) *AppError {
 	if message == "" {
@@ -50,6 +74,22 @@ func NewInternalServerError(message string, underlyingErr error, details ...inte
 	return NewAppError(http.StatusInternalServerError, message, underlyingErr, details...)
 }
 
+// NewServiceUnavailable creates a new 503 Service Unavailable AppError, e.g., while the service is in maintenance.
+func NewServiceUnavailable(message string, underlyingErr error, details ...interface{}) *AppError {
+	if message == "" {
+		message = "The service is temporarily unavailable."
+	}
+	return NewAppError(http.StatusServiceUnavailable, message, underlyingErr, details...)
+}
+
+// NewGatewayTimeout creates a new 504 Gateway Timeout AppError, e.g., when a handler runs past its deadline.
+func NewGatewayTimeout(message string, underlyingErr error, details ...interface{}) *AppError {
+	if message == "" {
+		message = "The server did not complete the request in time."
+	}
+	return NewAppError(http.StatusGatewayTimeout, message, underlyingErr, details...)
+}
+
 // NewValidationFailed creates a 422 Unprocessable Entity AppError, used for validation errors.
 func NewValidationFailed(message string, underlyingErr error, details ...interface{}) *AppError {
 	formattedValidationErrors := FormatValidationErrors(underlyingErr)
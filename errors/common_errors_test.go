@@ -83,6 +83,65 @@ func TestNewConflict(t *testing.T) {
 	}
 }
 
+// TestNewRequestTimeout tests the NewRequestTimeout function.
+func TestNewRequestTimeout(t *testing.T) {
+	appErr := NewRequestTimeout("", nil)
+	if appErr.Code != http.StatusRequestTimeout {
+		t.Errorf("Expected code %d, got %d", http.StatusRequestTimeout, appErr.Code)
+	}
+	expectedMessage := "The server timed out waiting for the request."
+	if appErr.Message != expectedMessage {
+		t.Errorf("Expected default message '%s', got '%s'", expectedMessage, appErr.Message)
+	}
+}
+
+// TestNewMethodNotAllowed tests the NewMethodNotAllowed function.
+func TestNewMethodNotAllowed(t *testing.T) {
+	appErr := NewMethodNotAllowed("", nil)
+	if appErr.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected code %d, got %d", http.StatusMethodNotAllowed, appErr.Code)
+	}
+	expectedMessage := "The method is not allowed for the requested resource."
+	if appErr.Message != expectedMessage {
+		t.Errorf("Expected default message '%s', got '%s'", expectedMessage, appErr.Message)
+	}
+}
+
+// TestNewPayloadTooLarge tests the NewPayloadTooLarge function.
+func TestNewPayloadTooLarge(t *testing.T) {
+	appErr := NewPayloadTooLarge("custom too large", nil)
+	if appErr.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected code %d, got %d", http.StatusRequestEntityTooLarge, appErr.Code)
+	}
+	if appErr.Message != "custom too large" {
+		t.Errorf("Expected message 'custom too large', got '%s'", appErr.Message)
+	}
+}
+
+// TestNewServiceUnavailable tests the NewServiceUnavailable function.
+func TestNewServiceUnavailable(t *testing.T) {
+	appErr := NewServiceUnavailable("", nil)
+	if appErr.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected code %d, got %d", http.StatusServiceUnavailable, appErr.Code)
+	}
+	expectedMessage := "The service is temporarily unavailable."
+	if appErr.Message != expectedMessage {
+		t.Errorf("Expected default message '%s', got '%s'", expectedMessage, appErr.Message)
+	}
+}
+
+// TestNewGatewayTimeout tests the NewGatewayTimeout function.
+func TestNewGatewayTimeout(t *testing.T) {
+	appErr := NewGatewayTimeout("", nil)
+	if appErr.Code != http.StatusGatewayTimeout {
+		t.Errorf("Expected code %d, got %d", http.StatusGatewayTimeout, appErr.Code)
+	}
+	expectedMessage := "The server did not complete the request in time."
+	if appErr.Message != expectedMessage {
+		t.Errorf("Expected default message '%s', got '%s'", expectedMessage, appErr.Message)
+	}
+}
+
 // TestNewInternalServerError tests the NewInternalServerError function.
 func TestNewInternalServerError(t *testing.T) {
 	appErr := NewInternalServerError("", errors.New("db error"))
@@ -116,7 +175,7 @@ func TestNewValidationFailed(t *testing.T) {
 			t.Errorf("Expected message 'Input validation failed.', got '%s'", appErr.Message)
 		}
 
-		expectedDetails := map[string]string{"User.Name": "failed on validation tag 'required'"}
+		expectedDetails := []ValidationErrorDetail{{Field: "User.Name", Tag: "required", Code: "required", Message: "failed on validation tag 'required'"}}
 		if !reflect.DeepEqual(appErr.Details, expectedDetails) {
 			t.Errorf("Expected details '%v', got '%v'", expectedDetails, appErr.Details)
 		}
@@ -0,0 +1,80 @@
+package errors
+
+import "net/http"
+
+const (
+	// ProblemContentType is the media type of RFC 7807 problem details responses.
+	ProblemContentType = "application/problem+json"
+
+	// DefaultProblemType is used when no type URI is set, RFC 7807 defines it as "no additional semantics".
+	DefaultProblemType = "about:blank"
+)
+
+// reservedProblemMembers can not be overwritten by extension members.
+var reservedProblemMembers = map[string]bool{
+	"type":     true,
+	"title":    true,
+	"status":   true,
+	"detail":   true,
+	"instance": true,
+}
+
+// WithType sets the RFC 7807 type URI identifying the problem type, e.g., "https://example.com/probs/out-of-credit".
+func (e *AppError) WithType(typeURI string) *AppError {
+	e.Type = typeURI
+	return e
+}
+
+// WithInstance sets the RFC 7807 instance identifying this specific occurrence of the problem.
+func (e *AppError) WithInstance(instance string) *AppError {
+	e.Instance = instance
+	return e
+}
+
+// WithExtension adds an RFC 7807 extension member, members using a reserved name are ignored.
+func (e *AppError) WithExtension(name string, value interface{}) *AppError {
+	if reservedProblemMembers[name] {
+		return e
+	}
+	if e.Extensions == nil {
+		e.Extensions = make(map[string]interface{})
+	}
+	e.Extensions[name] = value
+	return e
+}
+
+// ToProblemResponse prepares the AppError as an RFC 7807 problem details object. Details are sent as the
+// "details" extension member, and the underlying error is only included outside of production.
+func (e *AppError) ToProblemResponse(production bool) map[string]interface{} {
+	problemType := e.Type
+	if problemType == "" {
+		problemType = DefaultProblemType
+	}
+
+	response := map[string]interface{}{
+		"type":   problemType,
+		"title":  http.StatusText(e.Code),
+		"status": e.Code,
+		"detail": e.Message,
+	}
+
+	if e.Instance != "" {
+		response["instance"] = e.Instance
+	}
+
+	for name, value := range e.Extensions {
+		if !reservedProblemMembers[name] {
+			response[name] = value
+		}
+	}
+
+	if e.Details != nil {
+		response["details"] = e.Details
+	}
+
+	if e.Err != nil && !production {
+		response["underlying_error"] = e.Err.Error()
+	}
+
+	return response
+}
@@ -0,0 +1,52 @@
+package errors
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestAppError_ToProblemResponse(t *testing.T) {
+	t.Run("Defaults", func(t *testing.T) {
+		response := NewNotFound("Invoice not found", nil).ToProblemResponse(true)
+
+		if response["type"] != DefaultProblemType {
+			t.Errorf("Expected type '%s', got '%v'", DefaultProblemType, response["type"])
+		}
+		if response["title"] != http.StatusText(http.StatusNotFound) || response["status"] != http.StatusNotFound {
+			t.Errorf("Unexpected title / status: %v / %v", response["title"], response["status"])
+		}
+		if response["detail"] != "Invoice not found" {
+			t.Errorf("Expected detail 'Invoice not found', got '%v'", response["detail"])
+		}
+		if _, ok := response["instance"]; ok {
+			t.Error("Expected no instance member")
+		}
+	})
+
+	t.Run("Type, instance and extensions", func(t *testing.T) {
+		appErr := NewForbidden("Out of credit", errors.New("balance 30"), map[string]int{"balance": 30}).
+			WithType("https://example.com/probs/out-of-credit").
+			WithInstance("/account/12345/msgs/abc").
+			WithExtension("balance", 30).
+			WithExtension("status", 200)
+
+		response := appErr.ToProblemResponse(false)
+		if response["type"] != "https://example.com/probs/out-of-credit" || response["instance"] != "/account/12345/msgs/abc" {
+			t.Errorf("Unexpected type / instance: %v / %v", response["type"], response["instance"])
+		}
+		if response["balance"] != 30 {
+			t.Errorf("Expected the balance extension, got %v", response["balance"])
+		}
+		if response["status"] != http.StatusForbidden {
+			t.Errorf("Reserved members must not be overwritten, got status %v", response["status"])
+		}
+		if response["details"] == nil || response["underlying_error"] != "balance 30" {
+			t.Errorf("Expected details and the underlying error outside of production, got %v", response)
+		}
+
+		if _, ok := appErr.ToProblemResponse(true)["underlying_error"]; ok {
+			t.Error("Underlying error must not be exposed in production")
+		}
+	})
+}
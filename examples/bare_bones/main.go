@@ -4,9 +4,7 @@ import (
 	"fmt"
 
 	"github.com/gin-gonic/gin"
-	"github.com/grzegorzmaniak/gothic/cache"
 	"github.com/grzegorzmaniak/gothic/core"
-	"github.com/grzegorzmaniak/gothic/helpers"
 	"github.com/grzegorzmaniak/gothic/validation"
 )
 
@@ -19,18 +17,11 @@ type AppSpecificBaseRoute struct {
 
 // the main function initializes the application, sets up routes, and starts the server.
 func main() {
-	var sessionKey, _ = helpers.GenerateSymmetricKey(helpers.AESKeySize32)
-
 	baseRoute := &AppSpecificBaseRoute{
 		AppName: "MyApp",
 	}
 
-	mySessionManager := &AppSessionManager{
-		SessionKeyValue:                   sessionKey,
-		SessionAuthorizationConfiguration: &core.SessionAuthorizationConfiguration{CookieSecure: false},
-		CsrfCookieData:                    &core.CsrfCookieData{Secure: false},
-		Cache:                             cache.BuildDefaultCacheManager(nil),
-	}
+	mySessionManager := newSessionManager()
 
 	router := gin.Default()
 	validationEngine := validation.NewEngine(nil)
@@ -39,6 +30,14 @@ func main() {
 	core.GET(routeCtor, "/noAuth", BasicActionHandlerConfig, BasicActionHandler)
 	core.GET(routeCtor, "/auth", AuthenticatedResourceHandlerConfig, AuthenticatedResourceHandler)
 
+	// - Refuse to serve with insecure settings, warnings are only logged
+	report := routeCtor.SecurityAudit()
+	report.Log()
+	if err := report.Err(); err != nil {
+		fmt.Printf("Security audit failed: %v\n", err)
+		return
+	}
+
 	httpAddr := fmt.Sprintf("%s:%s", "localhost", "8080")
 	if err := router.Run(httpAddr); err != nil {
 		fmt.Printf("Failed to start server: %v\n", err)
@@ -41,10 +41,11 @@ type ExampleOutput struct {
 // potentially interacts with session state (here, it issues a new one),
 // and returns a structured output.
 func BasicActionHandler(input *ExampleInput, data *AppHandlerContext) (*ExampleOutput, *errors.AppError) {
-	// For demonstration, we'll create a new, empty claims object.
+	// For demonstration, we'll create a new claims object for the demo subject.
 	// In a real login handler, you would populate claims with user ID, roles, etc.
 	// In other handlers, you might refresh existing claims or modify them.
 	newSessionClaims := &core.SessionClaims{}
+	newSessionClaims.SetClaim(core.DefaultBuilderSubjectClaim, DemoSubject)
 
 	// Attempt to set/issue a new session cookie.
 	// "Guest_session" is an example session mode/group.
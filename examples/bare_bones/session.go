@@ -2,68 +2,33 @@ package main // Or 'examples' if this is part of that demo package
 
 import (
 	"context"
+	"log"
 
-	"github.com/eko/gocache/lib/v4/cache"
-	gothicCache "github.com/grzegorzmaniak/gothic/cache"
-
-	// Ensure these are the correct import paths for your actual 'gothic' library
 	"github.com/grzegorzmaniak/gothic/core"
+	"github.com/grzegorzmaniak/gothic/helpers"
 )
 
-// AppSessionManager is a mock implementation of core.SessionManager,
-type AppSessionManager struct {
-	core.DefaultSessionManager
-
-	// Configuration for this session manager instance.
-	SessionAuthorizationConfiguration *core.SessionAuthorizationConfiguration // Holds the cookie settings.
-	CsrfCookieData                    *core.CsrfCookieData                    // Holds the CSRF settings.
-	SessionKeyValue                   []byte                                  // Holds the secret key for session operations.
-	Cache                             *gothicCache.DefaultCacheManager        // Holds the cache for this session manager.
-}
-
-// GetCache returns the cache for this session manager.
-// This method is part of the core.SessionManager interface.
-func (m *AppSessionManager) GetCache() (cache.CacheInterface[[]byte], error) {
-	return m.Cache.GetCache()
-}
-
-// VerifySession is overridden for the demo. It always returns true,
-// indicating that any session presented is considered valid without actual checks.
-func (m *AppSessionManager) VerifySession(ctx context.Context, claims *core.SessionClaims, sessionHeader *core.SessionHeader) (bool, error) {
-	return true, nil
-}
-
-// StoreSession is overridden for the demo. It performs no actual storage operation
-// and always returns nil, indicating success.
-func (m *AppSessionManager) StoreSession(ctx context.Context, claims *core.SessionClaims, sessionHeader *core.SessionHeader) error {
-	return nil
-}
-
-// GetAuthorizationConfiguration returns the SessionAuthorizationConfiguration for this session manager.
-// This method is part of the core.SessionManager interface.
-func (m *AppSessionManager) GetAuthorizationConfiguration() *core.SessionAuthorizationConfiguration {
-	return m.SessionAuthorizationConfiguration
-}
-
-// GetSessionKey returns the session key used for cryptographic operations.
-// This method is part of the core.SessionManager interface.
-// This is the newest key in rotation.
-func (m *AppSessionManager) GetSessionKey() ([]byte, string, error) {
-	return m.SessionKeyValue, "some-key-id", nil
-}
-
-// GetOldSessionKey returns an old session key for the given key ID.
-// This method is part of the core.SessionManager interface.
-func (m *AppSessionManager) GetOldSessionKey(keyID string) ([]byte, error) {
-	return m.SessionKeyValue, nil
-}
-
-// GetSubjectIdentifier returns the identifier for the given subject.
-func (m *AppSessionManager) GetSubjectIdentifier(subject *core.SessionClaims) (string, error) {
-	return "user-007", nil
-}
-
-// GetCsrfData returns the CSRF cookie data configuration for this session manager.
-func (m *AppSessionManager) GetCsrfData() *core.CsrfCookieData {
-	return m.CsrfCookieData
+// DemoSubject is the subject the demo sessions are issued for.
+const DemoSubject = "user-007"
+
+// newSessionManager builds the demo's session manager, it only accepts the sessions of DemoSubject. A real
+// application loads its key from a secret store (a key generated on startup invalidates every session on restart)
+// and verifies sessions against its own session store.
+func newSessionManager() *core.BuiltSessionManager {
+	key, err := helpers.GenerateSymmetricKey(helpers.AESKeySize32)
+	if err != nil {
+		log.Fatalf("Failed to generate a session key: %v", err)
+	}
+
+	sessionManager, err := core.NewSessionManagerBuilder().
+		WithKey("demo-1", key).
+		WithSessionVerifier(func(_ context.Context, claims *core.SessionClaims, _ *core.SessionHeader) (bool, error) {
+			subject, _ := claims.GetClaim(core.DefaultBuilderSubjectClaim)
+			return subject == DemoSubject, nil
+		}).
+		Build()
+	if err != nil {
+		log.Fatalf("Failed to build the session manager: %v", err)
+	}
+	return sessionManager
 }
@@ -4,9 +4,7 @@ import (
 	"fmt"
 
 	"github.com/gin-gonic/gin"
-	"github.com/grzegorzmaniak/gothic/cache"
 	"github.com/grzegorzmaniak/gothic/core"
-	"github.com/grzegorzmaniak/gothic/helpers"
 	"github.com/grzegorzmaniak/gothic/validation"
 )
 
@@ -20,20 +18,11 @@ type AppSpecificBaseRoute struct {
 // the main function initializes the application, sets up routes, and starts the server.
 func main() {
 
-	// - You would typically load this from a secure location or environment variable.
-	var sessionKey, _ = helpers.GenerateSymmetricKey(helpers.AESKeySize32)
-
 	baseRoute := &AppSpecificBaseRoute{
 		AppName: "MyApp",
 	}
 
-	mySessionManager := &AppSessionManager{
-		SessionKeyValue:                   sessionKey,
-		SessionAuthorizationConfiguration: &core.SessionAuthorizationConfiguration{CookieSecure: false},
-		CsrfCookieData:                    &core.CsrfCookieData{Secure: false},
-		RbacManager:                       &MyRbacManager{},
-		Cache:                             cache.BuildDefaultCacheManager(nil),
-	}
+	mySessionManager := newSessionManager()
 
 	router := gin.Default()
 	validationEngine := validation.NewEngine(nil)
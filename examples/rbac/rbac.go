@@ -1,8 +1,7 @@
 package main
 
 import (
-	"context"
-
+	"github.com/grzegorzmaniak/gothic/gothictest"
 	"github.com/grzegorzmaniak/gothic/rbac"
 )
 
@@ -11,19 +10,12 @@ var (
 	ReadOnlySessionData  = rbac.NewPermission(1)
 )
 
-type MyRbacManager struct {
-	rbac.DefaultRBACManager
-}
-
-func (rm *MyRbacManager) GetSubjectRolesAndPermissions(ctx context.Context, subjectIdentifier string) (permissions rbac.Permissions, roles []string, err error) {
-	return rbac.Permissions{
-		ReadWriteSessionData,
-		ReadOnlySessionData,
-	}, []string{"test"}, nil
-}
-
-func (rm *MyRbacManager) GetRolePermissions(ctx context.Context, roleIdentifier string) (rbac.Permissions, error) {
-	return rbac.Permissions{
-		ReadWriteSessionData,
-	}, nil
+// newRbacManager returns the in-memory RBAC manager used by the demo: DemoSubject has both permissions and the
+// "test" role, which grants ReadWriteSessionData.
+func newRbacManager() *gothictest.FakeRbacManager {
+	rbacManager := gothictest.NewFakeRbacManager()
+	rbacManager.GrantSubject(DemoSubject, ReadWriteSessionData, ReadOnlySessionData)
+	rbacManager.AssignRoles(DemoSubject, "test")
+	rbacManager.GrantRole("test", ReadWriteSessionData)
+	return rbacManager
 }
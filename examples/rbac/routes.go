@@ -6,6 +6,7 @@ import (
 
 	"github.com/grzegorzmaniak/gothic/core"
 	"github.com/grzegorzmaniak/gothic/errors"
+	"github.com/grzegorzmaniak/gothic/gothictest"
 	"github.com/grzegorzmaniak/gothic/rbac"
 	"github.com/grzegorzmaniak/gothic/validation"
 )
@@ -68,10 +69,11 @@ var DynamicProfileOutputRules = validation.FieldRules{
 // potentially interacts with session state (here, it issues a new one),
 // and returns a structured output.
 func BasicActionHandler(input *ExampleInput, data *AppHandlerContext) (*ExampleOutput, *errors.AppError) {
-	// For demonstration, we'll create a new, empty claims object.
+	// For demonstration, we'll create a new claims object for the demo subject.
 	// In a real login handler, you would populate claims with user ID, roles, etc.
 	// In other handlers, you might refresh existing claims or modify them.
 	newSessionClaims := &core.SessionClaims{}
+	newSessionClaims.SetClaim(gothictest.DefaultSubjectClaim, DemoSubject)
 	newSessionClaims.SetClaim("session_data", "some_session_data")
 
 	// Attempt to set/issue a new session cookie.
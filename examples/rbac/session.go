@@ -1,83 +1,20 @@
 package main // Or 'examples' if this is part of that demo package
 
 import (
-	"context"
-
-	"github.com/eko/gocache/lib/v4/cache"
-	gothicCache "github.com/grzegorzmaniak/gothic/cache"
-	"github.com/grzegorzmaniak/gothic/rbac"
-
-	// Ensure these are the correct import paths for your actual 'gothic' library
 	"github.com/grzegorzmaniak/gothic/core"
+	"github.com/grzegorzmaniak/gothic/gothictest"
 )
 
-// AppSessionManager is a mock implementation of core.SessionManager,
-type AppSessionManager struct {
-	core.DefaultSessionManager
-
-	// Configuration for this session manager instance.
-	SessionAuthorizationConfiguration *core.SessionAuthorizationConfiguration // Holds the cookie settings.
-	CsrfCookieData                    *core.CsrfCookieData                    // Holds the CSRF settings.
-	SessionKeyValue                   []byte                                  // Holds the secret key for session operations.
-	RbacManager                       *MyRbacManager                          // Holds the RBAC manager for this session manager.
-	Cache                             *gothicCache.DefaultCacheManager        // Holds the cache for this session manager.
-}
-
-// GetCache returns the cache for this session manager.
-// This method is part of the core.SessionManager interface.
-func (m *AppSessionManager) GetCache() (cache.CacheInterface[[]byte], error) {
-	return m.Cache.GetCache()
-}
-
-// VerifySession is overridden for the demo. It always returns true,
-// indicating that any session presented is considered valid without actual checks.
-func (m *AppSessionManager) VerifySession(ctx context.Context, claims *core.SessionClaims, sessionHeader *core.SessionHeader) (bool, error) {
-	// fmt.Println("[AppSessionManager Mock] VerifySession called: always returning true (valid).")
-	return true, nil
-}
-
-// StoreSession is overridden for the demo. It performs no actual storage operation
-// and always returns nil, indicating success.
-func (m *AppSessionManager) StoreSession(ctx context.Context, claims *core.SessionClaims, sessionHeader *core.SessionHeader) error {
-	// fmt.Println("[AppSessionManager Mock] StoreSession called: no operation, always success.")
-	return nil
-}
-
-// GetAuthorizationConfiguration returns the SessionAuthorizationConfiguration for this session manager.
-// This method is part of the core.SessionManager interface.
-func (m *AppSessionManager) GetAuthorizationConfiguration() *core.SessionAuthorizationConfiguration {
-	return m.SessionAuthorizationConfiguration
-}
-
-// GetSessionKey returns the session key used for cryptographic operations.
-// This method is part of the core.SessionManager interface.
-// This is the newest key in rotation.
-func (m *AppSessionManager) GetSessionKey() ([]byte, string, error) {
-	return m.SessionKeyValue, "some-key-id", nil
-}
-
-// GetOldSessionKey returns an old session key for the given key ID.
-// This method is part of the core.SessionManager interface.
-func (m *AppSessionManager) GetOldSessionKey(keyID string) ([]byte, error) {
-	// In a real implementation, this would fetch the key from a secure store.
-	// For this demo, we return the same key as the current one.
-	return m.SessionKeyValue, nil
-}
-
-// GetSubjectIdentifier returns the identifier for the given subject.
-func (m *AppSessionManager) GetSubjectIdentifier(subject *core.SessionClaims) (string, error) {
-	// In a real implementation, this would extract the identifier from the claims.
-	// For this demo, we return a static string.
-	return "user-007", nil
-}
-
-// GetRbacManager returns the RBAC manager for this session manager.
-// This can be nil if RBAC is not used.
-func (m *AppSessionManager) GetRbacManager() rbac.Manager {
-	return m.RbacManager
-}
-
-// GetCsrfData returns the CSRF cookie data configuration for this session manager.
-func (m *AppSessionManager) GetCsrfData() *core.CsrfCookieData {
-	return m.CsrfCookieData
+// DemoSubject is the subject the demo sessions are issued for.
+const DemoSubject = "user-007"
+
+// newSessionManager returns the in-memory session manager used by the demo, it only accepts the sessions of
+// DemoSubject and uses newRbacManager for RBAC. A real application implements core.SessionManager on top of its
+// own session store.
+func newSessionManager() *gothictest.FakeSessionManager {
+	sessionManager := gothictest.NewFakeSessionManager(DemoSubject)
+	sessionManager.AuthorizationConfiguration = &core.SessionAuthorizationConfiguration{CookieSecure: false}
+	sessionManager.CsrfData = &core.CsrfCookieData{Secure: false}
+	sessionManager.RbacManager = newRbacManager()
+	return sessionManager
 }
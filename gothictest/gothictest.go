@@ -0,0 +1,61 @@
+// Package gothictest provides fully functional in-memory SessionManager and rbac.Manager implementations for
+// tests and examples. Subjects, roles and permissions are programmed on the fakes, and the calls made to them are
+// recorded so tests can assert on them:
+//
+//	rbacManager := gothictest.NewFakeRbacManager()
+//	rbacManager.AssignRoles("user-1", "editor")
+//	rbacManager.GrantRole("editor", EditPermission)
+//
+//	sessionManager := gothictest.NewFakeSessionManager("user-1")
+//	sessionManager.RbacManager = rbacManager
+package gothictest
+
+import "sync"
+
+// Call is a call recorded by a fake.
+type Call struct {
+	// Method is the name of the called method, e.g., "VerifySession"
+	Method string
+
+	// Identifier is the subject or role the call was made for, empty if it has none
+	Identifier string
+}
+
+// recorder records the calls made to a fake, it is safe for concurrent use.
+type recorder struct {
+	callsMu sync.Mutex
+	calls   []Call
+}
+
+func (r *recorder) record(method, identifier string) {
+	r.callsMu.Lock()
+	defer r.callsMu.Unlock()
+	r.calls = append(r.calls, Call{Method: method, Identifier: identifier})
+}
+
+// Calls returns the recorded calls in the order they were made.
+func (r *recorder) Calls() []Call {
+	r.callsMu.Lock()
+	defer r.callsMu.Unlock()
+	return append([]Call(nil), r.calls...)
+}
+
+// CallCount returns how many times method was called.
+func (r *recorder) CallCount(method string) int {
+	r.callsMu.Lock()
+	defer r.callsMu.Unlock()
+	count := 0
+	for _, call := range r.calls {
+		if call.Method == method {
+			count++
+		}
+	}
+	return count
+}
+
+// ResetCalls forgets the recorded calls.
+func (r *recorder) ResetCalls() {
+	r.callsMu.Lock()
+	defer r.callsMu.Unlock()
+	r.calls = nil
+}
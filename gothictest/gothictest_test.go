@@ -0,0 +1,137 @@
+package gothictest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/core"
+	"github.com/grzegorzmaniak/gothic/errors"
+	"github.com/grzegorzmaniak/gothic/rbac"
+)
+
+type testRoute struct{}
+
+type testOutput struct {
+	Subject string `json:"subject"`
+}
+
+var editPermission = rbac.NewPermission(3)
+
+func TestFakeManagers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	rbacManager := NewFakeRbacManager()
+	sessionManager := NewFakeSessionManager("alice", "bob")
+	sessionManager.RbacManager = rbacManager
+
+	router := gin.New()
+	ctor := core.NewRouteConstructor(router, testRoute{}, sessionManager, nil)
+	handler := func(_ *struct{}, data *core.Handler[testRoute]) (*testOutput, *errors.AppError) {
+		subject, _ := data.Claims.GetClaim(DefaultSubjectClaim)
+		return &testOutput{Subject: subject}, nil
+	}
+	core.GET(ctor, "/me", core.AuthenticatedJSONAPI(), handler)
+	core.GET(ctor, "/edit", core.AdminRoute(editPermission), handler)
+	core.GET(ctor, "/named", core.AuthenticatedJSONAPI().WithNamedPermissions("notes.read").WithRbacPolicy(rbac.PermissionsOnly), handler)
+
+	issue := func(subject string) string {
+		ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+		ctx.Request = httptest.NewRequest(http.MethodPost, "/login", nil)
+		token, err := core.IssueBearerToken(ctx, sessionManager, "default", &core.SessionClaims{Claims: map[string]string{DefaultSubjectClaim: subject}})
+		if err != nil {
+			t.Fatalf("Failed to issue the bearer token: %v", err)
+		}
+		return token
+	}
+	request := func(path, token string) int {
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set(core.DefaultSessionAuthorizationHeaderName, token)
+		router.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	alice, bob, mallory := issue("alice"), issue("bob"), issue("mallory")
+
+	t.Run("Only added subjects have valid sessions", func(t *testing.T) {
+		if code := request("/me", alice); code != http.StatusOK {
+			t.Errorf("Expected alice to be accepted, got %d", code)
+		}
+		if code := request("/me", mallory); code != http.StatusUnauthorized {
+			t.Errorf("Expected the unknown subject to be rejected, got %d", code)
+		}
+		if sessionManager.StoredSessions("alice") != 1 {
+			t.Errorf("Expected one stored session, got %d", sessionManager.StoredSessions("alice"))
+		}
+	})
+
+	t.Run("Permissions are granted through roles", func(t *testing.T) {
+		if code := request("/edit", bob); code != http.StatusUnauthorized {
+			t.Errorf("Expected bob to be denied, got %d", code)
+		}
+		rbacManager.AssignRoles("bob", "editor")
+		rbacManager.GrantRole("editor", editPermission)
+		if code := request("/edit", bob); code != http.StatusOK {
+			t.Errorf("Expected the granted role to apply immediately, got %d", code)
+		}
+
+		if err := rbacManager.GrantSubjectNamed("bob", "notes.*"); err != nil {
+			t.Fatalf("Failed to grant the named permission: %v", err)
+		}
+		if code := request("/named", bob); code != http.StatusOK {
+			t.Errorf("Expected the named permission to apply, got %d", code)
+		}
+	})
+
+	t.Run("Failures are returned from the fetches", func(t *testing.T) {
+		rbacManager.FailWith("bob", fmt.Errorf("database is down"))
+		defer rbacManager.FailWith("bob", nil)
+		if code := request("/edit", bob); code < http.StatusInternalServerError {
+			t.Errorf("Expected the fetch failure to fail the request, got %d", code)
+		}
+	})
+
+	t.Run("Calls are recorded", func(t *testing.T) {
+		if rbacManager.CallCount("GetRolePermissions") == 0 {
+			t.Errorf("Expected role permission fetches, got %v", rbacManager.Calls())
+		}
+
+		verified := map[string]bool{}
+		for _, call := range sessionManager.Calls() {
+			if call.Method == "VerifySession" {
+				verified[call.Identifier] = true
+			}
+		}
+		if !verified["alice"] || !verified["mallory"] {
+			t.Errorf("Expected alice and mallory to be verified, got %v", sessionManager.Calls())
+		}
+
+		sessionManager.ResetCalls()
+		if len(sessionManager.Calls()) != 0 {
+			t.Error("Expected the calls to be reset")
+		}
+	})
+
+	t.Run("Revoked subjects are rejected", func(t *testing.T) {
+		if err := sessionManager.RevokeSubject(context.Background(), "alice"); err != nil {
+			t.Fatalf("Failed to revoke the subject: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+		if code := request("/me", alice); code != http.StatusUnauthorized {
+			t.Errorf("Expected the revoked subject to be rejected, got %d", code)
+		}
+	})
+
+	t.Run("Rotated keys keep older tokens valid", func(t *testing.T) {
+		if _, err := sessionManager.RotateKey(); err != nil {
+			t.Fatalf("Failed to rotate the key: %v", err)
+		}
+		if code := request("/me", bob); code != http.StatusOK {
+			t.Errorf("Expected the token of the previous key to be accepted, got %d", code)
+		}
+	})
+}
@@ -0,0 +1,151 @@
+package gothictest
+
+import (
+	"context"
+	"sync"
+
+	"github.com/eko/gocache/lib/v4/cache"
+	"github.com/grzegorzmaniak/gothic/rbac"
+)
+
+// FakeRbacManager is an in-memory rbac.Manager with programmable roles and permissions. It has no cache, so
+// changes apply to the next request.
+//
+// Calls to the Get*Permissions methods are recorded with the subject or role they were made for.
+type FakeRbacManager struct {
+	rbac.DefaultRBACManager
+	recorder
+
+	mu                      sync.RWMutex
+	subjectRoles            map[string][]string
+	subjectPermissions      map[string]rbac.Permissions
+	rolePermissions         map[string]rbac.Permissions
+	subjectNamedPermissions map[string]rbac.PermissionSet
+	roleNamedPermissions    map[string]rbac.PermissionSet
+	failures                map[string]error
+}
+
+// NewFakeRbacManager returns a FakeRbacManager without any roles or permissions.
+func NewFakeRbacManager() *FakeRbacManager {
+	return &FakeRbacManager{
+		subjectRoles:            make(map[string][]string),
+		subjectPermissions:      make(map[string]rbac.Permissions),
+		rolePermissions:         make(map[string]rbac.Permissions),
+		subjectNamedPermissions: make(map[string]rbac.PermissionSet),
+		roleNamedPermissions:    make(map[string]rbac.PermissionSet),
+		failures:                make(map[string]error),
+	}
+}
+
+// AssignRoles adds roles to a subject.
+func (m *FakeRbacManager) AssignRoles(subject string, roles ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.subjectRoles[subject] = append(m.subjectRoles[subject], roles...)
+}
+
+// GrantSubject grants permissions directly to a subject.
+func (m *FakeRbacManager) GrantSubject(subject string, permissions ...*rbac.Permission) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.subjectPermissions[subject] = append(m.subjectPermissions[subject], permissions...)
+}
+
+// GrantRole grants permissions to a role.
+func (m *FakeRbacManager) GrantRole(role string, permissions ...*rbac.Permission) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rolePermissions[role] = append(m.rolePermissions[role], permissions...)
+}
+
+// GrantSubjectNamed grants named permissions, e.g., "billing.invoices.read", directly to a subject.
+func (m *FakeRbacManager) GrantSubjectNamed(subject string, names ...string) error {
+	return m.grantNamed(m.subjectNamedPermissions, subject, names)
+}
+
+// GrantRoleNamed grants named permissions to a role.
+func (m *FakeRbacManager) GrantRoleNamed(role string, names ...string) error {
+	return m.grantNamed(m.roleNamedPermissions, role, names)
+}
+
+// FailWith makes the fetches for a subject or role return err, a nil err removes the failure.
+func (m *FakeRbacManager) FailWith(identifier string, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err == nil {
+		delete(m.failures, identifier)
+		return
+	}
+	m.failures[identifier] = err
+}
+
+// Reset removes all roles, permissions and failures, the recorded calls are kept.
+func (m *FakeRbacManager) Reset() {
+	fresh := NewFakeRbacManager()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.subjectRoles = fresh.subjectRoles
+	m.subjectPermissions = fresh.subjectPermissions
+	m.rolePermissions = fresh.rolePermissions
+	m.subjectNamedPermissions = fresh.subjectNamedPermissions
+	m.roleNamedPermissions = fresh.roleNamedPermissions
+	m.failures = fresh.failures
+}
+
+func (m *FakeRbacManager) GetSubjectRolesAndPermissions(_ context.Context, subjectIdentifier string) (rbac.Permissions, []string, error) {
+	m.record("GetSubjectRolesAndPermissions", subjectIdentifier)
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if err := m.failures[subjectIdentifier]; err != nil {
+		return nil, nil, err
+	}
+	return append(rbac.Permissions{}, m.subjectPermissions[subjectIdentifier]...), append([]string{}, m.subjectRoles[subjectIdentifier]...), nil
+}
+
+func (m *FakeRbacManager) GetRolePermissions(_ context.Context, roleIdentifier string) (rbac.Permissions, error) {
+	m.record("GetRolePermissions", roleIdentifier)
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if err := m.failures[roleIdentifier]; err != nil {
+		return nil, err
+	}
+	return append(rbac.Permissions{}, m.rolePermissions[roleIdentifier]...), nil
+}
+
+func (m *FakeRbacManager) GetSubjectNamedPermissions(_ context.Context, subjectIdentifier string) (rbac.PermissionSet, error) {
+	m.record("GetSubjectNamedPermissions", subjectIdentifier)
+	return m.namedPermissions(m.subjectNamedPermissions, subjectIdentifier)
+}
+
+func (m *FakeRbacManager) GetRoleNamedPermissions(_ context.Context, roleIdentifier string) (rbac.PermissionSet, error) {
+	m.record("GetRoleNamedPermissions", roleIdentifier)
+	return m.namedPermissions(m.roleNamedPermissions, roleIdentifier)
+}
+
+// GetCache returns no cache, the roles and permissions are always read from the fake.
+func (m *FakeRbacManager) GetCache() (cache.CacheInterface[[]byte], error) {
+	return nil, nil
+}
+
+func (m *FakeRbacManager) grantNamed(grants map[string]rbac.PermissionSet, identifier string, names []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if grants[identifier] == nil {
+		grants[identifier] = rbac.PermissionSet{}
+	}
+	for _, name := range names {
+		if err := grants[identifier].Add(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *FakeRbacManager) namedPermissions(grants map[string]rbac.PermissionSet, identifier string) (rbac.PermissionSet, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if err := m.failures[identifier]; err != nil {
+		return nil, err
+	}
+	return rbac.PermissionSet{}.Merge(grants[identifier]), nil
+}
@@ -0,0 +1,215 @@
+package gothictest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/eko/gocache/lib/v4/cache"
+	internalcache "github.com/grzegorzmaniak/gothic/cache"
+	"github.com/grzegorzmaniak/gothic/core"
+	"github.com/grzegorzmaniak/gothic/helpers"
+	"github.com/grzegorzmaniak/gothic/rbac"
+)
+
+const (
+	// DefaultSubjectClaim is the claim GetSubjectIdentifier reads the subject from
+	DefaultSubjectClaim = "subject"
+
+	fakeKeyIdPrefix = "fake-key-"
+)
+
+// FakeSessionManager is an in-memory core.SessionManager. Sessions are only valid for subjects that were added
+// and not revoked, keys are generated in memory and can be rotated.
+//
+// Calls to VerifySession, StoreSession, VerifyClaims and GetSubjectIdentifier are recorded.
+type FakeSessionManager struct {
+	core.DefaultSessionManager
+	recorder
+
+	// AuthorizationConfiguration is returned by GetAuthorizationConfiguration (Default: the zero configuration)
+	AuthorizationConfiguration *core.SessionAuthorizationConfiguration
+
+	// CsrfData is returned by GetCsrfData (Default: the zero configuration)
+	CsrfData *core.CsrfCookieData
+
+	// RbacManager is returned by GetRbacManager, e.g., a FakeRbacManager (Default: nil, RBAC is not used)
+	RbacManager rbac.Manager
+
+	// SubjectClaim is the claim holding the subject identifier (Default: DefaultSubjectClaim)
+	SubjectClaim string
+
+	// AllowUnknownSubjects accepts the sessions of subjects that were never added, revoked subjects are still
+	// rejected (Default: false)
+	AllowUnknownSubjects bool
+
+	mu             sync.RWMutex
+	keys           map[string][]byte
+	currentKeyId   string
+	subjects       map[string]bool // subject -> active
+	storedSessions map[string]int
+	cacheManager   *internalcache.DefaultCacheManager
+}
+
+// NewFakeSessionManager returns a FakeSessionManager with a fresh key and the given subjects added.
+// It panics if no key can be generated.
+func NewFakeSessionManager(subjects ...string) *FakeSessionManager {
+	m := &FakeSessionManager{
+		AuthorizationConfiguration: &core.SessionAuthorizationConfiguration{},
+		CsrfData:                   &core.CsrfCookieData{},
+		keys:                       make(map[string][]byte),
+		subjects:                   make(map[string]bool),
+		storedSessions:             make(map[string]int),
+		cacheManager:               internalcache.BuildDefaultCacheManager(nil),
+	}
+	if _, err := m.RotateKey(); err != nil {
+		panic(fmt.Sprintf("gothictest: %v", err))
+	}
+	m.AddSubject(subjects...)
+	return m
+}
+
+// Shutdown drains the manager's background work and closes its cache, call it (or Close) from t.Cleanup so tests
+// do not leak the cache's goroutines.
+func (m *FakeSessionManager) Shutdown(ctx context.Context) error {
+	return errors.Join(m.DefaultSessionManager.Shutdown(ctx), m.cacheManager.Shutdown(ctx))
+}
+
+// Close shuts down the manager without a deadline.
+func (m *FakeSessionManager) Close() error {
+	return m.Shutdown(context.Background())
+}
+
+// AddSubject adds (or reinstates) subjects, their sessions are valid from now on.
+func (m *FakeSessionManager) AddSubject(subjects ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, subject := range subjects {
+		m.subjects[subject] = true
+	}
+}
+
+// RevokeSubject makes the sessions of a subject invalid. The cache is cleared as well, so bearer tokens are
+// verified again on their next request, cookie sessions are verified again once their VerifyTime elapsed.
+func (m *FakeSessionManager) RevokeSubject(ctx context.Context, subject string) error {
+	m.mu.Lock()
+	m.subjects[subject] = false
+	m.mu.Unlock()
+
+	cacheInstance, err := m.GetCache()
+	if err != nil {
+		return err
+	}
+	return cacheInstance.Clear(ctx)
+}
+
+// RotateKey generates a new session key and makes it the current one, tokens issued with older keys stay valid.
+func (m *FakeSessionManager) RotateKey() (string, error) {
+	key, err := helpers.GenerateSymmetricKey(helpers.AESKeySize32)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate a session key: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	keyId := fmt.Sprintf("%s%d", fakeKeyIdPrefix, len(m.keys)+1)
+	m.keys[keyId] = key
+	m.currentKeyId = keyId
+	return keyId, nil
+}
+
+// StoredSessions returns how many sessions were stored for a subject.
+func (m *FakeSessionManager) StoredSessions(subject string) int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.storedSessions[subject]
+}
+
+func (m *FakeSessionManager) GetAuthorizationConfiguration() *core.SessionAuthorizationConfiguration {
+	return m.AuthorizationConfiguration
+}
+
+func (m *FakeSessionManager) GetCsrfData() *core.CsrfCookieData {
+	return m.CsrfData
+}
+
+func (m *FakeSessionManager) GetSessionKey() ([]byte, string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.keys[m.currentKeyId], m.currentKeyId, nil
+}
+
+func (m *FakeSessionManager) GetOldSessionKey(keyId string) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	key, ok := m.keys[keyId]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id '%s'", keyId)
+	}
+	return key, nil
+}
+
+// VerifySession accepts the sessions of active subjects.
+func (m *FakeSessionManager) VerifySession(_ context.Context, claims *core.SessionClaims, _ *core.SessionHeader) (bool, error) {
+	subject, err := m.subjectOf(claims)
+	m.record("VerifySession", subject)
+	if err != nil {
+		return false, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	active, known := m.subjects[subject]
+	if !known {
+		return m.AllowUnknownSubjects, nil
+	}
+	return active, nil
+}
+
+// StoreSession counts the stored sessions of a subject, see StoredSessions.
+func (m *FakeSessionManager) StoreSession(_ context.Context, claims *core.SessionClaims, _ *core.SessionHeader) error {
+	subject, err := m.subjectOf(claims)
+	m.record("StoreSession", subject)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.storedSessions[subject]++
+	return nil
+}
+
+// VerifyClaims records the call and uses the default Allow / Block verification.
+func (m *FakeSessionManager) VerifyClaims(ctx context.Context, claims *core.SessionClaims, sessionConfig *core.APIConfiguration) (bool, error) {
+	subject, _ := m.subjectOf(claims)
+	m.record("VerifyClaims", subject)
+	return m.DefaultSessionManager.VerifyClaims(ctx, claims, sessionConfig)
+}
+
+func (m *FakeSessionManager) GetRbacManager() rbac.Manager {
+	return m.RbacManager
+}
+
+func (m *FakeSessionManager) GetSubjectIdentifier(claims *core.SessionClaims) (string, error) {
+	subject, err := m.subjectOf(claims)
+	m.record("GetSubjectIdentifier", subject)
+	return subject, err
+}
+
+func (m *FakeSessionManager) GetCache() (cache.CacheInterface[[]byte], error) {
+	return m.cacheManager.GetCache()
+}
+
+func (m *FakeSessionManager) subjectOf(claims *core.SessionClaims) (string, error) {
+	if claims == nil {
+		return "", fmt.Errorf("claims are nil")
+	}
+	claim := helpers.DefaultString(m.SubjectClaim, DefaultSubjectClaim)
+	subject, ok := claims.GetClaim(claim)
+	if !ok || subject == "" {
+		return "", fmt.Errorf("%s claim is missing", claim)
+	}
+	return subject, nil
+}
@@ -0,0 +1,145 @@
+package helpers
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"fmt"
+)
+
+// SigningAlgorithm is the algorithm of a key pair created by GenerateSigningKeyPair.
+type SigningAlgorithm string
+
+const (
+	SigningAlgorithmEd25519 SigningAlgorithm = "Ed25519"
+	SigningAlgorithmES256   SigningAlgorithm = "ES256" // ECDSA P-256 with SHA-256
+	SigningAlgorithmES384   SigningAlgorithm = "ES384" // ECDSA P-384 with SHA-384
+)
+
+// GenerateSigningKeyPair creates a new key pair for the given algorithm. The private key is PKCS #8 DER encoded
+// and the public key PKIX DER encoded, so they can be stored as-is or wrapped in PEM blocks.
+func GenerateSigningKeyPair(algorithm SigningAlgorithm) (privateKey []byte, publicKey []byte, err error) {
+	var private crypto.Signer
+	switch algorithm {
+	case SigningAlgorithmEd25519:
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	case SigningAlgorithmES256:
+		private, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case SigningAlgorithmES384:
+		private, err = ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	default:
+		return nil, nil, fmt.Errorf("unsupported signing algorithm '%s'", algorithm)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate %s key pair: %w", algorithm, err)
+	}
+
+	privateKey, err = x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode private key: %w", err)
+	}
+	publicKey, err = x509.MarshalPKIXPublicKey(private.Public())
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode public key: %w", err)
+	}
+	return privateKey, publicKey, nil
+}
+
+// parseSigningKey parses a PKCS #8 DER encoded Ed25519 or ECDSA private key.
+func parseSigningKey(privateKey []byte) (crypto.Signer, error) {
+	parsed, err := x509.ParsePKCS8PrivateKey(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+	switch key := parsed.(type) {
+	case ed25519.PrivateKey:
+		return key, nil
+	case *ecdsa.PrivateKey:
+		return key, nil
+	default:
+		return nil, fmt.Errorf("unsupported private key type %T", parsed)
+	}
+}
+
+// parseVerificationKey parses a PKIX DER encoded public key, or derives it from a PKCS #8 private key, so the
+// issuing service can verify with the same key bytes it signs with.
+func parseVerificationKey(key []byte) (crypto.PublicKey, error) {
+	if parsed, err := x509.ParsePKIXPublicKey(key); err == nil {
+		switch publicKey := parsed.(type) {
+		case ed25519.PublicKey, *ecdsa.PublicKey:
+			return publicKey, nil
+		default:
+			return nil, fmt.Errorf("unsupported public key type %T", parsed)
+		}
+	}
+
+	signer, err := parseSigningKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("key is neither a public nor a private key: %w", err)
+	}
+	return signer.Public(), nil
+}
+
+// ecdsaDigest hashes the message with the hash matching the curve size.
+func ecdsaDigest(curve elliptic.Curve, message []byte) []byte {
+	switch curve.Params().BitSize {
+	case 384:
+		digest := sha512.Sum384(message)
+		return digest[:]
+	case 521:
+		digest := sha512.Sum512(message)
+		return digest[:]
+	default:
+		digest := sha256.Sum256(message)
+		return digest[:]
+	}
+}
+
+// AsymmetricSign signs the message with a PKCS #8 DER encoded Ed25519 or ECDSA private key. ECDSA signatures
+// are ASN.1 encoded.
+func AsymmetricSign(privateKey []byte, message []byte) ([]byte, error) {
+	signer, err := parseSigningKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	switch key := signer.(type) {
+	case ed25519.PrivateKey:
+		return ed25519.Sign(key, message), nil
+	case *ecdsa.PrivateKey:
+		signature, err := ecdsa.SignASN1(rand.Reader, key, ecdsaDigest(key.Curve, message))
+		if err != nil {
+			return nil, fmt.Errorf("failed to sign message: %w", err)
+		}
+		return signature, nil
+	default:
+		return nil, fmt.Errorf("unsupported private key type %T", signer)
+	}
+}
+
+// AsymmetricVerify checks a signature created by AsymmetricSign. The key is the PKIX DER encoded public key,
+// or the PKCS #8 private key it belongs to.
+func AsymmetricVerify(key []byte, message []byte, signature []byte) error {
+	publicKey, err := parseVerificationKey(key)
+	if err != nil {
+		return err
+	}
+
+	valid := false
+	switch publicKey := publicKey.(type) {
+	case ed25519.PublicKey:
+		valid = ed25519.Verify(publicKey, message, signature)
+	case *ecdsa.PublicKey:
+		valid = ecdsa.VerifyASN1(publicKey, ecdsaDigest(publicKey.Curve, message), signature)
+	}
+
+	if !valid {
+		return fmt.Errorf("signature is invalid")
+	}
+	return nil
+}
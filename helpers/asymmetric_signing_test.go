@@ -0,0 +1,50 @@
+package helpers
+
+import (
+	"testing"
+)
+
+func TestAsymmetricSigning(t *testing.T) {
+	message := []byte("SS1.key-1.payload")
+
+	for _, algorithm := range []SigningAlgorithm{SigningAlgorithmEd25519, SigningAlgorithmES256, SigningAlgorithmES384} {
+		t.Run(string(algorithm), func(t *testing.T) {
+			privateKey, publicKey, err := GenerateSigningKeyPair(algorithm)
+			if err != nil {
+				t.Fatalf("Failed to generate key pair: %v", err)
+			}
+
+			signature, err := AsymmetricSign(privateKey, message)
+			if err != nil {
+				t.Fatalf("Failed to sign: %v", err)
+			}
+
+			if err = AsymmetricVerify(publicKey, message, signature); err != nil {
+				t.Errorf("Expected the public key to verify, got %v", err)
+			}
+			if err = AsymmetricVerify(privateKey, message, signature); err != nil {
+				t.Errorf("Expected the private key to verify, got %v", err)
+			}
+			if err = AsymmetricVerify(publicKey, []byte("SS1.key-1.tampered"), signature); err == nil {
+				t.Error("Expected a tampered message to be rejected")
+			}
+
+			_, otherPublicKey, _ := GenerateSigningKeyPair(algorithm)
+			if err = AsymmetricVerify(otherPublicKey, message, signature); err == nil {
+				t.Error("Expected another key to be rejected")
+			}
+		})
+	}
+
+	t.Run("Rejects invalid input", func(t *testing.T) {
+		if _, _, err := GenerateSigningKeyPair("RS256"); err == nil {
+			t.Error("Expected an unsupported algorithm to fail")
+		}
+		if _, err := AsymmetricSign([]byte("not a key"), message); err == nil {
+			t.Error("Expected an invalid private key to fail")
+		}
+		if err := AsymmetricVerify([]byte("not a key"), message, []byte("signature")); err == nil {
+			t.Error("Expected an invalid public key to fail")
+		}
+	})
+}
@@ -0,0 +1,314 @@
+package helpers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// CompressionMode selects whether SuccessResponse compresses its body.
+type CompressionMode int
+
+const (
+	// CompressionDefault inherits the global mode, it is the zero value so routes inherit by default.
+	CompressionDefault CompressionMode = iota
+
+	// CompressionOff never compresses the body.
+	CompressionOff
+
+	// CompressionOn compresses bodies of at least MinCompressSize bytes with the best encoding the client accepts.
+	CompressionOn
+)
+
+// ETagMode selects whether SuccessResponse generates an ETag for its body.
+type ETagMode int
+
+const (
+	// ETagDefault inherits the global mode, it is the zero value so routes inherit by default.
+	ETagDefault ETagMode = iota
+
+	// ETagOff does not generate ETags.
+	ETagOff
+
+	// ETagStrong generates a strong ETag, which differs per content encoding.
+	ETagStrong
+
+	// ETagWeak generates a weak ETag (W/"..."), shared by all content encodings.
+	ETagWeak
+)
+
+const (
+	// ResponseEncodingContextKey is the gin context key holding the per-request ResponseEncoding.
+	ResponseEncodingContextKey = "gothic_response_encoding"
+
+	// GzipEncoding is the built-in content encoding, others (e.g., "br") can be added with RegisterCompressor.
+	GzipEncoding = "gzip"
+
+	DefaultMinCompressSize = 1024
+	eTagHashLength         = 32
+)
+
+// ResponseEncoding configures the compression and ETags of SuccessResponse bodies.
+type ResponseEncoding struct {
+	// Compression compresses the body with the best encoding of the Accept-Encoding header (Default: inherit)
+	Compression CompressionMode
+
+	// MinCompressSize is the smallest body that is compressed (Default: DefaultMinCompressSize)
+	MinCompressSize int
+
+	// ETag generates an ETag from the body and answers a matching If-None-Match with 304 Not Modified
+	// (Default: inherit)
+	ETag ETagMode
+}
+
+// merge fills the unset fields from the fallback.
+func (e ResponseEncoding) merge(fallback ResponseEncoding) ResponseEncoding {
+	if e.Compression == CompressionDefault {
+		e.Compression = fallback.Compression
+	}
+	if e.ETag == ETagDefault {
+		e.ETag = fallback.ETag
+	}
+	e.MinCompressSize = DefaultInt(e.MinCompressSize, fallback.MinCompressSize)
+	return e
+}
+
+var builtinResponseEncoding = ResponseEncoding{
+	Compression:     CompressionOff,
+	MinCompressSize: DefaultMinCompressSize,
+	ETag:            ETagOff,
+}
+
+var defaultResponseEncoding = struct {
+	sync.RWMutex
+	encoding ResponseEncoding
+}{encoding: builtinResponseEncoding}
+
+// SetDefaultResponseEncoding sets the global compression / ETag settings, used when no per-request settings
+// are set. Unset fields restore the built-in defaults (no compression, no ETags).
+func SetDefaultResponseEncoding(encoding ResponseEncoding) {
+	defaultResponseEncoding.Lock()
+	defer defaultResponseEncoding.Unlock()
+	defaultResponseEncoding.encoding = encoding.merge(builtinResponseEncoding)
+}
+
+// SetResponseEncoding overrides the compression / ETag settings for a single request, unset fields inherit
+// the global settings.
+func SetResponseEncoding(ctx *gin.Context, encoding ResponseEncoding) {
+	if ctx == nil || encoding == (ResponseEncoding{}) {
+		return
+	}
+	ctx.Set(ResponseEncodingContextKey, encoding)
+}
+
+// GetResponseEncoding returns the compression / ETag settings for the request.
+func GetResponseEncoding(ctx *gin.Context) ResponseEncoding {
+	defaultResponseEncoding.RLock()
+	encoding := defaultResponseEncoding.encoding
+	defaultResponseEncoding.RUnlock()
+
+	if ctx != nil {
+		if value, ok := ctx.Get(ResponseEncodingContextKey); ok {
+			if override, ok := value.(ResponseEncoding); ok {
+				return override.merge(encoding)
+			}
+		}
+	}
+	return encoding
+}
+
+// Compressor wraps the writer with a content encoding, e.g., an adapter around andybalholm/brotli:
+//
+//	helpers.RegisterCompressor("br", func(w io.Writer) (io.WriteCloser, error) {
+//		return brotli.NewWriter(w), nil
+//	})
+type Compressor func(w io.Writer) (io.WriteCloser, error)
+
+var compressors = struct {
+	sync.RWMutex
+	preference []string
+	encoders   map[string]Compressor
+}{
+	preference: []string{GzipEncoding},
+	encoders: map[string]Compressor{
+		GzipEncoding: func(w io.Writer) (io.WriteCloser, error) { return gzip.NewWriter(w), nil },
+	},
+}
+
+// RegisterCompressor adds or replaces a content encoding, newly added encodings are preferred over the existing
+// ones when the client accepts them equally. A nil compressor removes the encoding.
+func RegisterCompressor(encoding string, compressor Compressor) {
+	encoding = strings.ToLower(encoding)
+
+	compressors.Lock()
+	defer compressors.Unlock()
+
+	_, exists := compressors.encoders[encoding]
+	if compressor == nil {
+		delete(compressors.encoders, encoding)
+		preference := make([]string, 0, len(compressors.preference))
+		for _, name := range compressors.preference {
+			if name != encoding {
+				preference = append(preference, name)
+			}
+		}
+		compressors.preference = preference
+		return
+	}
+
+	compressors.encoders[encoding] = compressor
+	if !exists {
+		compressors.preference = append([]string{encoding}, compressors.preference...)
+	}
+}
+
+// selectCompressor returns the registered encoding with the highest Accept-Encoding quality, ties go to the
+// preferred encoding. Encodings the client does not mention (or rejects with q=0) are never used.
+func selectCompressor(acceptEncoding string) (string, Compressor) {
+	if acceptEncoding == "" {
+		return "", nil
+	}
+
+	accepted := make(map[string]float64)
+	for _, entry := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(entry, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		quality := 1.0
+		for _, param := range strings.Split(params, ";") {
+			key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.EqualFold(key, "q") {
+				if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+					quality = parsed
+				}
+			}
+		}
+		accepted[name] = quality
+	}
+
+	compressors.RLock()
+	defer compressors.RUnlock()
+
+	var (
+		best        string
+		bestQuality float64
+	)
+	for _, name := range compressors.preference {
+		quality, ok := accepted[name]
+		if !ok {
+			quality = accepted["*"]
+		}
+		if quality > bestQuality {
+			best, bestQuality = name, quality
+		}
+	}
+
+	if best == "" {
+		return "", nil
+	}
+	return best, compressors.encoders[best]
+}
+
+// compressBody runs the body through the compressor.
+func compressBody(compressor Compressor, body []byte) ([]byte, error) {
+	var buffer bytes.Buffer
+	writer, err := compressor(&buffer)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = writer.Write(body); err != nil {
+		_ = writer.Close()
+		return nil, err
+	}
+	if err = writer.Close(); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// eTagMatches reports whether the If-None-Match header matches the body hash, using the weak comparison so
+// both weak tags and strong tags of any content encoding ("<hash>-gzip") match.
+func eTagMatches(ifNoneMatch string, hash string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+
+		opaque := strings.Trim(strings.TrimPrefix(candidate, "W/"), `"`)
+		if opaque == hash || strings.HasPrefix(opaque, hash+"-") {
+			return true
+		}
+	}
+	return false
+}
+
+// writeEncodedBody writes the rendered body, applying the request's ETag and compression settings.
+func writeEncodedBody(ctx *gin.Context, statusCode int, contentType string, body []byte) {
+	encoding := GetResponseEncoding(ctx)
+
+	// - Pick the encoding first, a 304 has to carry the same Vary header as the full response
+	var (
+		encodingName string
+		compressor   Compressor
+	)
+	if encoding.Compression == CompressionOn && len(body) >= encoding.MinCompressSize &&
+		ctx.Writer.Header().Get("Content-Encoding") == "" {
+		ctx.Writer.Header().Add("Vary", "Accept-Encoding")
+		encodingName, compressor = selectCompressor(ctx.GetHeader("Accept-Encoding"))
+	}
+
+	var hash string
+	if encoding.ETag != ETagOff && statusCode == http.StatusOK {
+		sum := sha256.Sum256(body)
+		hash = hex.EncodeToString(sum[:])[:eTagHashLength]
+
+		method := ctx.Request.Method
+		if (method == http.MethodGet || method == http.MethodHead) && eTagMatches(ctx.GetHeader("If-None-Match"), hash) {
+			ctx.Header("ETag", formatETag(hash, encoding.ETag, encodingName))
+			ctx.Status(http.StatusNotModified)
+			ctx.Writer.WriteHeaderNow()
+			return
+		}
+	}
+
+	if compressor != nil {
+		compressed, err := compressBody(compressor, body)
+		if err != nil {
+			Logger(ctx).Debug("Failed to compress response, sending it uncompressed",
+				zap.String("encoding", encodingName), zap.Error(err))
+			encodingName = ""
+		} else {
+			body = compressed
+			ctx.Header("Content-Encoding", encodingName)
+		}
+	}
+
+	if hash != "" {
+		ctx.Header("ETag", formatETag(hash, encoding.ETag, encodingName))
+	}
+	ctx.Data(statusCode, contentType, body)
+}
+
+// formatETag quotes the hash, strong tags of compressed bodies carry the encoding as they are different bytes.
+func formatETag(hash string, mode ETagMode, encodingName string) string {
+	if mode == ETagWeak {
+		return `W/"` + hash + `"`
+	}
+	if encodingName != "" {
+		return `"` + hash + "-" + encodingName + `"`
+	}
+	return `"` + hash + `"`
+}
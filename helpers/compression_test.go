@@ -0,0 +1,167 @@
+package helpers
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+func TestSuccessResponse_Compression(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	SetDefaultResponseEncoding(ResponseEncoding{Compression: CompressionOn, MinCompressSize: 64})
+	defer SetDefaultResponseEncoding(ResponseEncoding{})
+
+	large := map[string]string{"payload": strings.Repeat("gothic ", 100)}
+	respond := func(acceptEncoding string, data interface{}, override ResponseEncoding) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+		ctx.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+		if acceptEncoding != "" {
+			ctx.Request.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		SetResponseEncoding(ctx, override)
+		SuccessResponse(ctx, http.StatusOK, data, nil)
+		return recorder
+	}
+
+	t.Run("Large bodies are gzipped", func(t *testing.T) {
+		recorder := respond("gzip, deflate", large, ResponseEncoding{})
+		if encoding := recorder.Header().Get("Content-Encoding"); encoding != GzipEncoding {
+			t.Fatalf("Expected gzip, got %q", encoding)
+		}
+		if vary := recorder.Header().Values("Vary"); !strings.Contains(strings.Join(vary, ","), "Accept-Encoding") {
+			t.Errorf("Expected Vary: Accept-Encoding, got %v", vary)
+		}
+
+		reader, err := gzip.NewReader(recorder.Body)
+		if err != nil {
+			t.Fatalf("Failed to open gzip body: %v", err)
+		}
+		body, _ := io.ReadAll(reader)
+		if !strings.Contains(string(body), `"payload":"gothic gothic`) {
+			t.Errorf("Unexpected decompressed body %q", body)
+		}
+	})
+
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		data           interface{}
+		override       ResponseEncoding
+	}{
+		{name: "Small bodies are not compressed", acceptEncoding: "gzip", data: map[string]string{"a": "b"}},
+		{name: "Client without Accept-Encoding", acceptEncoding: "", data: large},
+		{name: "Unsupported encodings only", acceptEncoding: "compress", data: large},
+		{name: "Rejected with q=0", acceptEncoding: "gzip;q=0, identity", data: large},
+		{name: "Route overrides the global mode", acceptEncoding: "gzip", data: large, override: ResponseEncoding{Compression: CompressionOff}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := respond(tt.acceptEncoding, tt.data, tt.override)
+			if encoding := recorder.Header().Get("Content-Encoding"); encoding != "" {
+				t.Errorf("Expected no content encoding, got %q", encoding)
+			}
+			if !strings.HasPrefix(recorder.Body.String(), "{") {
+				t.Errorf("Expected a plain JSON body, got %q", recorder.Body.String())
+			}
+		})
+	}
+
+	t.Run("Registered encodings are preferred", func(t *testing.T) {
+		RegisterCompressor("test", func(w io.Writer) (io.WriteCloser, error) { return nopWriteCloser{w}, nil })
+		defer RegisterCompressor("test", nil)
+
+		if recorder := respond("gzip, test", large, ResponseEncoding{}); recorder.Header().Get("Content-Encoding") != "test" {
+			t.Errorf("Expected the registered encoding, got %q", recorder.Header().Get("Content-Encoding"))
+		}
+		if recorder := respond("gzip, test;q=0.5", large, ResponseEncoding{}); recorder.Header().Get("Content-Encoding") != GzipEncoding {
+			t.Errorf("Expected the higher quality encoding, got %q", recorder.Header().Get("Content-Encoding"))
+		}
+	})
+}
+
+func TestSuccessResponse_ETag(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	data := map[string]string{"name": "John"}
+
+	respond := func(method string, ifNoneMatch string, encoding ResponseEncoding) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+		ctx.Request = httptest.NewRequest(method, "/", nil)
+		if ifNoneMatch != "" {
+			ctx.Request.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		SetResponseEncoding(ctx, encoding)
+		SuccessResponse(ctx, http.StatusOK, data, nil)
+		return recorder
+	}
+
+	t.Run("ETags are off by default", func(t *testing.T) {
+		if tag := respond(http.MethodGet, "", ResponseEncoding{}).Header().Get("ETag"); tag != "" {
+			t.Errorf("Expected no ETag, got %q", tag)
+		}
+	})
+
+	strong := respond(http.MethodGet, "", ResponseEncoding{ETag: ETagStrong}).Header().Get("ETag")
+	if !strings.HasPrefix(strong, `"`) || len(strong) != eTagHashLength+2 {
+		t.Fatalf("Expected a strong ETag, got %q", strong)
+	}
+	weak := respond(http.MethodGet, "", ResponseEncoding{ETag: ETagWeak}).Header().Get("ETag")
+	if weak != "W/"+strong {
+		t.Fatalf("Expected weak ETag W/%s, got %q", strong, weak)
+	}
+
+	tests := []struct {
+		name        string
+		method      string
+		ifNoneMatch string
+		mode        ETagMode
+		status      int
+	}{
+		{name: "Matching strong tag", method: http.MethodGet, ifNoneMatch: strong, mode: ETagStrong, status: http.StatusNotModified},
+		{name: "Weak tag matches a strong one", method: http.MethodGet, ifNoneMatch: weak, mode: ETagStrong, status: http.StatusNotModified},
+		{name: "Compressed tag matches", method: http.MethodGet, ifNoneMatch: strings.TrimSuffix(strong, `"`) + `-gzip"`, mode: ETagWeak, status: http.StatusNotModified},
+		{name: "Tag in a list", method: http.MethodGet, ifNoneMatch: `"other", ` + strong, mode: ETagStrong, status: http.StatusNotModified},
+		{name: "Wildcard", method: http.MethodHead, ifNoneMatch: "*", mode: ETagStrong, status: http.StatusNotModified},
+		{name: "Different tag", method: http.MethodGet, ifNoneMatch: `"other"`, mode: ETagStrong, status: http.StatusOK},
+		{name: "Unsafe methods are not short-circuited", method: http.MethodPost, ifNoneMatch: strong, mode: ETagStrong, status: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := respond(tt.method, tt.ifNoneMatch, ResponseEncoding{ETag: tt.mode})
+			if recorder.Code != tt.status {
+				t.Fatalf("Expected %d, got %d", tt.status, recorder.Code)
+			}
+			if tt.status == http.StatusNotModified && recorder.Body.Len() != 0 {
+				t.Errorf("Expected an empty body, got %q", recorder.Body.String())
+			}
+			if recorder.Header().Get("ETag") == "" {
+				t.Error("Expected the ETag header")
+			}
+		})
+	}
+
+	t.Run("Strong tags differ per content encoding", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+		ctx.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+		ctx.Request.Header.Set("Accept-Encoding", "gzip")
+		SetResponseEncoding(ctx, ResponseEncoding{ETag: ETagStrong, Compression: CompressionOn, MinCompressSize: 1})
+		SuccessResponse(ctx, http.StatusOK, data, nil)
+
+		if tag := recorder.Header().Get("ETag"); tag != strings.TrimSuffix(strong, `"`)+`-gzip"` {
+			t.Errorf("Expected the gzip variant of %s, got %q", strong, tag)
+		}
+	})
+}
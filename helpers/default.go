@@ -54,3 +54,12 @@ func DefaultTimeDuration(value time.Duration, defaultValue time.Duration) time.D
 	}
 	return value
 }
+
+// DefaultFloat64 returns the defaultValue if the provided value is 0 (the zero value for float64).
+// Otherwise, it returns the original value.
+func DefaultFloat64(value float64, defaultValue float64) float64 {
+	if value == 0 {
+		return defaultValue
+	}
+	return value
+}
@@ -0,0 +1,178 @@
+package helpers
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	DefaultKMSKeyCacheTTL = 15 * time.Minute // How long unwrapped keys are kept in memory
+	DefaultKMSTimeout     = 5 * time.Second  // Deadline of a KMS call made without one
+)
+
+// KeyProvider supplies the session keys, e.g., to core.SessionManagerBuilder.WithKeyProvider, so they can be kept
+// in an HSM / KMS instead of being raw bytes in the configuration.
+type KeyProvider interface {
+	// CurrentKey returns the key new sessions are encrypted with and its id.
+	CurrentKey(ctx context.Context) ([]byte, string, error)
+
+	// Key returns the key with the given id, e.g., an older key of the ring.
+	Key(ctx context.Context, keyId string) ([]byte, error)
+}
+
+// KeyManagementService wraps data keys with a master key that never leaves the service (AWS KMS, GCP KMS, Vault
+// transit, an HSM, ...).
+type KeyManagementService interface {
+	// GenerateDataKey returns a new data key of size bytes, in plaintext and wrapped by the master key.
+	GenerateDataKey(ctx context.Context, size int) (plaintext []byte, wrapped []byte, err error)
+
+	// DecryptDataKey unwraps a data key returned by GenerateDataKey.
+	DecryptDataKey(ctx context.Context, wrapped []byte) ([]byte, error)
+}
+
+// StaticKeyProvider serves keys held in memory, e.g., in tests or when the keys come from a secret store.
+type StaticKeyProvider struct {
+	CurrentKeyId string
+	Keys         map[string][]byte
+}
+
+func (p *StaticKeyProvider) CurrentKey(ctx context.Context) ([]byte, string, error) {
+	key, err := p.Key(ctx, p.CurrentKeyId)
+	return key, p.CurrentKeyId, err
+}
+
+func (p *StaticKeyProvider) Key(_ context.Context, keyId string) ([]byte, error) {
+	key, ok := p.Keys[keyId]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id '%s'", keyId)
+	}
+	return key, nil
+}
+
+// KMSKeyRing is a KeyProvider over data keys wrapped by a KeyManagementService (envelope encryption). Only the
+// wrapped keys are configured, e.g., from the environment, and they are unwrapped by the service on first use. The
+// plaintext keys are cached for CacheTTL, so sessions are not slowed down by a KMS call each, and revoking the
+// service's access takes effect within it.
+//
+// New keys are created with GenerateWrappedKey, add the wrapped key to the ring and make it current once every
+// instance has it.
+type KMSKeyRing struct {
+	kms          KeyManagementService
+	currentKeyId string
+	wrappedKeys  map[string][]byte
+	cacheTTL     time.Duration
+	timeout      time.Duration
+
+	mu     sync.RWMutex
+	cached map[string]cachedKey
+	group  singleflight.Group
+}
+
+type cachedKey struct {
+	key       []byte
+	expiresAt time.Time
+}
+
+// KMSKeyRingConfig configures NewKMSKeyRing.
+type KMSKeyRingConfig struct {
+	// CurrentKeyId is the id of the key new sessions are encrypted with, it has to be in WrappedKeys.
+	CurrentKeyId string
+
+	// WrappedKeys are the data keys by key id, as returned by GenerateWrappedKey.
+	WrappedKeys map[string][]byte
+
+	// CacheTTL is how long unwrapped keys are kept in memory (Default: DefaultKMSKeyCacheTTL)
+	CacheTTL time.Duration
+
+	// Timeout is the deadline of KMS calls made with a context without one (Default: DefaultKMSTimeout)
+	Timeout time.Duration
+}
+
+// NewKMSKeyRing returns a KMSKeyRing, it does not call the service until a key is needed.
+func NewKMSKeyRing(kms KeyManagementService, config KMSKeyRingConfig) (*KMSKeyRing, error) {
+	if kms == nil {
+		return nil, fmt.Errorf("key management service is nil")
+	}
+	if _, ok := config.WrappedKeys[config.CurrentKeyId]; !ok {
+		return nil, fmt.Errorf("current key id '%s' is not in the wrapped keys", config.CurrentKeyId)
+	}
+
+	return &KMSKeyRing{
+		kms:          kms,
+		currentKeyId: config.CurrentKeyId,
+		wrappedKeys:  maps.Clone(config.WrappedKeys),
+		cacheTTL:     DefaultTimeDuration(config.CacheTTL, DefaultKMSKeyCacheTTL),
+		timeout:      DefaultTimeDuration(config.Timeout, DefaultKMSTimeout),
+		cached:       make(map[string]cachedKey),
+	}, nil
+}
+
+// GenerateWrappedKey creates a new session key of size bytes with the service and returns it wrapped, the
+// plaintext key is discarded.
+func GenerateWrappedKey(ctx context.Context, kms KeyManagementService, size int) ([]byte, error) {
+	if size != AESKeySize16 && size != AESKeySize24 && size != AESKeySize32 {
+		return nil, fmt.Errorf("invalid key size: must be %d, %d, or %d bytes", AESKeySize16, AESKeySize24, AESKeySize32)
+	}
+	_, wrapped, err := kms.GenerateDataKey(ctx, size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate data key: %w", err)
+	}
+	return wrapped, nil
+}
+
+func (r *KMSKeyRing) CurrentKey(ctx context.Context) ([]byte, string, error) {
+	key, err := r.Key(ctx, r.currentKeyId)
+	return key, r.currentKeyId, err
+}
+
+func (r *KMSKeyRing) Key(ctx context.Context, keyId string) ([]byte, error) {
+	r.mu.RLock()
+	cached, ok := r.cached[keyId]
+	r.mu.RUnlock()
+	if ok && time.Now().Before(cached.expiresAt) {
+		return cached.key, nil
+	}
+
+	wrapped, ok := r.wrappedKeys[keyId]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id '%s'", keyId)
+	}
+
+	// - Concurrent misses of a key share one KMS call
+	key, err, _ := r.group.Do(keyId, func() (interface{}, error) {
+		if _, hasDeadline := ctx.Deadline(); !hasDeadline {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, r.timeout)
+			defer cancel()
+		}
+
+		key, err := r.kms.DecryptDataKey(ctx, wrapped)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unwrap key '%s': %w", keyId, err)
+		}
+		if len(key) != AESKeySize16 && len(key) != AESKeySize24 && len(key) != AESKeySize32 {
+			return nil, fmt.Errorf("unwrapped key '%s' is %d bytes, not a valid AES key size", keyId, len(key))
+		}
+
+		r.mu.Lock()
+		r.cached[keyId] = cachedKey{key: key, expiresAt: time.Now().Add(r.cacheTTL)}
+		r.mu.Unlock()
+		return key, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return key.([]byte), nil
+}
+
+// Forget drops the unwrapped keys from memory, they are unwrapped again on their next use.
+func (r *KMSKeyRing) Forget() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	clear(r.cached)
+}
@@ -0,0 +1,154 @@
+package helpers
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeKMS wraps data keys by xor-ing them with a fixed pad and counts the unwraps.
+type fakeKMS struct {
+	decrypts atomic.Int32
+	delay    time.Duration
+	fail     error
+}
+
+func (k *fakeKMS) pad(data []byte) []byte {
+	out := make([]byte, len(data))
+	for i, b := range data {
+		out[i] = b ^ 0x5a
+	}
+	return out
+}
+
+func (k *fakeKMS) GenerateDataKey(_ context.Context, size int) ([]byte, []byte, error) {
+	key, err := GenerateSymmetricKey(size)
+	if err != nil {
+		return nil, nil, err
+	}
+	return key, k.pad(key), nil
+}
+
+func (k *fakeKMS) DecryptDataKey(ctx context.Context, wrapped []byte) ([]byte, error) {
+	k.decrypts.Add(1)
+	if k.fail != nil {
+		return nil, k.fail
+	}
+	select {
+	case <-time.After(k.delay):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return k.pad(wrapped), nil
+}
+
+func TestKMSKeyRing(t *testing.T) {
+	kms := &fakeKMS{}
+	ctx := context.Background()
+	wrappedOld, err := GenerateWrappedKey(ctx, kms, AESKeySize32)
+	if err != nil {
+		t.Fatalf("GenerateWrappedKey() error = %v", err)
+	}
+	wrappedNew, _ := GenerateWrappedKey(ctx, kms, AESKeySize32)
+
+	ring, err := NewKMSKeyRing(kms, KMSKeyRingConfig{
+		CurrentKeyId: "2024-06",
+		WrappedKeys:  map[string][]byte{"2024-01": wrappedOld, "2024-06": wrappedNew},
+	})
+	if err != nil {
+		t.Fatalf("NewKMSKeyRing() error = %v", err)
+	}
+
+	key, keyId, err := ring.CurrentKey(ctx)
+	if err != nil || keyId != "2024-06" || len(key) != AESKeySize32 {
+		t.Fatalf("CurrentKey() = %d bytes, '%s', %v", len(key), keyId, err)
+	}
+	if string(key) != string(kms.pad(wrappedNew)) {
+		t.Error("Expected the unwrapped current key")
+	}
+	if _, err = ring.Key(ctx, "2024-01"); err != nil {
+		t.Errorf("Key() error = %v", err)
+	}
+	if _, err = ring.Key(ctx, "2023-01"); err == nil {
+		t.Error("Expected an unknown key id to fail")
+	}
+
+	// - Cached keys are not unwrapped again until forgotten
+	for range 10 {
+		_, _, _ = ring.CurrentKey(ctx)
+	}
+	if got := kms.decrypts.Load(); got != 2 {
+		t.Errorf("Expected 2 unwraps, got %d", got)
+	}
+	ring.Forget()
+	_, _, _ = ring.CurrentKey(ctx)
+	if got := kms.decrypts.Load(); got != 3 {
+		t.Errorf("Expected the forgotten key to be unwrapped again, got %d unwraps", got)
+	}
+
+	if _, err = NewKMSKeyRing(kms, KMSKeyRingConfig{CurrentKeyId: "missing", WrappedKeys: map[string][]byte{"2024-06": wrappedNew}}); err == nil {
+		t.Error("Expected a current key id outside the ring to fail")
+	}
+	if _, err = GenerateWrappedKey(ctx, kms, 20); err == nil {
+		t.Error("Expected an invalid key size to fail")
+	}
+}
+
+func TestKMSKeyRingConcurrentMisses(t *testing.T) {
+	kms := &fakeKMS{delay: 20 * time.Millisecond}
+	wrapped, _ := GenerateWrappedKey(context.Background(), kms, AESKeySize32)
+	ring, _ := NewKMSKeyRing(kms, KMSKeyRingConfig{CurrentKeyId: "k", WrappedKeys: map[string][]byte{"k": wrapped}})
+
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, _, err := ring.CurrentKey(context.Background()); err != nil {
+				t.Errorf("CurrentKey() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := kms.decrypts.Load(); got != 1 {
+		t.Errorf("Expected concurrent misses to share one unwrap, got %d", got)
+	}
+}
+
+func TestKMSKeyRingFailures(t *testing.T) {
+	kms := &fakeKMS{delay: time.Second}
+	wrapped, _ := GenerateWrappedKey(context.Background(), kms, AESKeySize32)
+	ring, _ := NewKMSKeyRing(kms, KMSKeyRingConfig{
+		CurrentKeyId: "k",
+		WrappedKeys:  map[string][]byte{"k": wrapped, "short": kms.pad(make([]byte, 20))},
+		Timeout:      10 * time.Millisecond,
+	})
+
+	if _, _, err := ring.CurrentKey(context.Background()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the KMS call to time out, got %v", err)
+	}
+
+	kms.delay, kms.fail = 0, errors.New("access denied")
+	if _, _, err := ring.CurrentKey(context.Background()); err == nil {
+		t.Error("Expected a KMS failure to be returned")
+	}
+
+	kms.fail = nil
+	if _, err := ring.Key(context.Background(), "short"); err == nil {
+		t.Error("Expected an invalid unwrapped key size to fail")
+	}
+}
+
+func TestStaticKeyProvider(t *testing.T) {
+	provider := &StaticKeyProvider{CurrentKeyId: "a", Keys: map[string][]byte{"a": make([]byte, 32)}}
+	if _, keyId, err := provider.CurrentKey(context.Background()); err != nil || keyId != "a" {
+		t.Errorf("CurrentKey() = '%s', %v", keyId, err)
+	}
+	if _, err := provider.Key(context.Background(), "b"); err == nil {
+		t.Error("Expected an unknown key id to fail")
+	}
+}
@@ -0,0 +1,188 @@
+package helpers
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	DefaultVaultTransitMount = "transit"
+	VaultTokenHeader         = "X-Vault-Token"
+	VaultNamespaceHeader     = "X-Vault-Namespace"
+	maxVaultResponseSize     = 1 << 20
+)
+
+// AWSKMSClient is the part of the AWS KMS API used by AWSKMS, adapt the SDK's *kms.Client to it, e.g.:
+//
+//	func (c awsClient) GenerateDataKey(ctx context.Context, keyId string, size int32) ([]byte, []byte, error) {
+//		out, err := c.client.GenerateDataKey(ctx, &kms.GenerateDataKeyInput{KeyId: &keyId, NumberOfBytes: &size})
+//		if err != nil {
+//			return nil, nil, err
+//		}
+//		return out.Plaintext, out.CiphertextBlob, nil
+//	}
+type AWSKMSClient interface {
+	GenerateDataKey(ctx context.Context, keyId string, numberOfBytes int32) (plaintext []byte, ciphertextBlob []byte, err error)
+	Decrypt(ctx context.Context, keyId string, ciphertextBlob []byte) ([]byte, error)
+}
+
+// AWSKMS is a KeyManagementService over an AWS KMS key, data keys are created with GenerateDataKey.
+type AWSKMS struct {
+	Client AWSKMSClient
+
+	// KeyId is the id, ARN or alias of the KMS key, e.g., "alias/sessions".
+	KeyId string
+}
+
+func (k *AWSKMS) GenerateDataKey(ctx context.Context, size int) ([]byte, []byte, error) {
+	return k.Client.GenerateDataKey(ctx, k.KeyId, int32(size))
+}
+
+func (k *AWSKMS) DecryptDataKey(ctx context.Context, wrapped []byte) ([]byte, error) {
+	return k.Client.Decrypt(ctx, k.KeyId, wrapped)
+}
+
+// GCPKMSClient is the part of the Cloud KMS API used by GCPKMS, adapt the SDK's *kms.KeyManagementClient to it,
+// e.g., by calling Encrypt / Decrypt with an EncryptRequest / DecryptRequest of the key name.
+type GCPKMSClient interface {
+	Encrypt(ctx context.Context, keyName string, plaintext []byte, additionalData []byte) ([]byte, error)
+	Decrypt(ctx context.Context, keyName string, ciphertext []byte, additionalData []byte) ([]byte, error)
+}
+
+// GCPKMS is a KeyManagementService over a Cloud KMS key. Cloud KMS does not generate data keys, they are
+// generated locally and encrypted with the key.
+type GCPKMS struct {
+	Client GCPKMSClient
+
+	// KeyName is the resource name of the key, e.g., "projects/p/locations/global/keyRings/r/cryptoKeys/sessions".
+	KeyName string
+
+	// AdditionalData is authenticated along with the data keys (Default: none)
+	AdditionalData []byte
+}
+
+func (k *GCPKMS) GenerateDataKey(ctx context.Context, size int) ([]byte, []byte, error) {
+	plaintext, err := GenerateSymmetricKey(size)
+	if err != nil {
+		return nil, nil, err
+	}
+	wrapped, err := k.Client.Encrypt(ctx, k.KeyName, plaintext, k.AdditionalData)
+	if err != nil {
+		return nil, nil, err
+	}
+	return plaintext, wrapped, nil
+}
+
+func (k *GCPKMS) DecryptDataKey(ctx context.Context, wrapped []byte) ([]byte, error) {
+	return k.Client.Decrypt(ctx, k.KeyName, wrapped, k.AdditionalData)
+}
+
+// VaultTransit is a KeyManagementService over a HashiCorp Vault transit key, it calls the HTTP API directly. The
+// wrapped keys are Vault ciphertexts, e.g., "vault:v1:...", so they keep working after the transit key is rotated.
+type VaultTransit struct {
+	// Address of the Vault server, e.g., "https://vault.internal:8200".
+	Address string
+
+	// Token authenticates the requests, it needs the transit datakey and decrypt capabilities on KeyName.
+	Token string
+
+	// KeyName is the name of the transit key.
+	KeyName string
+
+	// Mount is the path the transit engine is mounted at (Default: DefaultVaultTransitMount)
+	Mount string
+
+	// Namespace is the Vault Enterprise namespace (Default: none)
+	Namespace string
+
+	// Client sends the requests (Default: http.DefaultClient)
+	Client *http.Client
+}
+
+func (v *VaultTransit) GenerateDataKey(ctx context.Context, size int) ([]byte, []byte, error) {
+	var response struct {
+		Plaintext  string `json:"plaintext"`
+		Ciphertext string `json:"ciphertext"`
+	}
+	if err := v.call(ctx, "datakey/plaintext", map[string]any{"bits": size * 8}, &response); err != nil {
+		return nil, nil, err
+	}
+
+	plaintext, err := base64.StdEncoding.DecodeString(response.Plaintext)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decode data key: %w", err)
+	}
+	return plaintext, []byte(response.Ciphertext), nil
+}
+
+func (v *VaultTransit) DecryptDataKey(ctx context.Context, wrapped []byte) ([]byte, error) {
+	var response struct {
+		Plaintext string `json:"plaintext"`
+	}
+	if err := v.call(ctx, "decrypt", map[string]any{"ciphertext": string(wrapped)}, &response); err != nil {
+		return nil, err
+	}
+
+	plaintext, err := base64.StdEncoding.DecodeString(response.Plaintext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode data key: %w", err)
+	}
+	return plaintext, nil
+}
+
+// call posts body to the transit endpoint and decodes the "data" of the response into target.
+func (v *VaultTransit) call(ctx context.Context, endpoint string, body any, target any) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to encode vault request: %w", err)
+	}
+
+	mount := strings.Trim(DefaultString(v.Mount, DefaultVaultTransitMount), "/")
+	requestUrl := strings.TrimRight(v.Address, "/") + "/v1/" + mount + "/" + endpoint + "/" + url.PathEscape(v.KeyName)
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, requestUrl, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create vault request: %w", err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(VaultTokenHeader, v.Token)
+	if v.Namespace != "" {
+		request.Header.Set(VaultNamespaceHeader, v.Namespace)
+	}
+
+	client := v.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return fmt.Errorf("vault request failed: %w", err)
+	}
+	defer response.Body.Close()
+
+	responseBody, err := io.ReadAll(io.LimitReader(response.Body, maxVaultResponseSize))
+	if err != nil {
+		return fmt.Errorf("failed to read vault response: %w", err)
+	}
+
+	var decoded struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []string        `json:"errors"`
+	}
+	if err = json.Unmarshal(responseBody, &decoded); err != nil {
+		return fmt.Errorf("failed to decode vault response (status %d): %w", response.StatusCode, err)
+	}
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("vault %s failed with status %d: %s", endpoint, response.StatusCode, strings.Join(decoded.Errors, ", "))
+	}
+	if err = json.Unmarshal(decoded.Data, target); err != nil {
+		return fmt.Errorf("failed to decode vault response data: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package helpers
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type fakeAWSKMSClient struct {
+	keyId string
+	kms   fakeKMS
+}
+
+func (c *fakeAWSKMSClient) GenerateDataKey(ctx context.Context, keyId string, numberOfBytes int32) ([]byte, []byte, error) {
+	c.keyId = keyId
+	return c.kms.GenerateDataKey(ctx, int(numberOfBytes))
+}
+
+func (c *fakeAWSKMSClient) Decrypt(ctx context.Context, keyId string, ciphertextBlob []byte) ([]byte, error) {
+	c.keyId = keyId
+	return c.kms.DecryptDataKey(ctx, ciphertextBlob)
+}
+
+type fakeGCPKMSClient struct {
+	kms fakeKMS
+}
+
+func (c *fakeGCPKMSClient) Encrypt(_ context.Context, keyName string, plaintext []byte, additionalData []byte) ([]byte, error) {
+	return append([]byte(keyName+string(additionalData)+"|"), c.kms.pad(plaintext)...), nil
+}
+
+func (c *fakeGCPKMSClient) Decrypt(_ context.Context, keyName string, ciphertext []byte, additionalData []byte) ([]byte, error) {
+	prefix := keyName + string(additionalData) + "|"
+	if !strings.HasPrefix(string(ciphertext), prefix) {
+		return nil, context.Canceled
+	}
+	return c.kms.pad(ciphertext[len(prefix):]), nil
+}
+
+func roundTrip(t *testing.T, kms KeyManagementService) {
+	t.Helper()
+	plaintext, wrapped, err := kms.GenerateDataKey(context.Background(), AESKeySize32)
+	if err != nil {
+		t.Fatalf("GenerateDataKey() error = %v", err)
+	}
+	if len(plaintext) != AESKeySize32 || string(wrapped) == string(plaintext) {
+		t.Fatalf("Expected a 32 byte key and a distinct wrapped key, got %d bytes", len(plaintext))
+	}
+	unwrapped, err := kms.DecryptDataKey(context.Background(), wrapped)
+	if err != nil {
+		t.Fatalf("DecryptDataKey() error = %v", err)
+	}
+	if string(unwrapped) != string(plaintext) {
+		t.Error("Expected the unwrapped key to match the generated one")
+	}
+}
+
+func TestAWSKMS(t *testing.T) {
+	client := &fakeAWSKMSClient{}
+	roundTrip(t, &AWSKMS{Client: client, KeyId: "alias/sessions"})
+	if client.keyId != "alias/sessions" {
+		t.Errorf("Expected the calls to use the key id, got '%s'", client.keyId)
+	}
+}
+
+func TestGCPKMS(t *testing.T) {
+	roundTrip(t, &GCPKMS{Client: &fakeGCPKMSClient{}, KeyName: "projects/p/cryptoKeys/sessions", AdditionalData: []byte("app")})
+}
+
+func TestVaultTransit(t *testing.T) {
+	key := make([]byte, AESKeySize32)
+	key[0] = 7
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(VaultTokenHeader) != "s.token" || r.Header.Get(VaultNamespaceHeader) != "team" {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		plaintext := base64.StdEncoding.EncodeToString(key)
+		switch r.URL.Path {
+		case "/v1/transit/datakey/plaintext/sessions":
+			if body["bits"] != float64(256) {
+				t.Errorf("Expected 256 bits, got %v", body["bits"])
+			}
+			_, _ = w.Write([]byte(`{"data":{"plaintext":"` + plaintext + `","ciphertext":"vault:v1:abc"}}`))
+		case "/v1/transit/decrypt/sessions":
+			if body["ciphertext"] != "vault:v1:abc" {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"errors":["invalid ciphertext"]}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"data":{"plaintext":"` + plaintext + `"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"errors":[]}`))
+		}
+	}))
+	defer server.Close()
+
+	vault := &VaultTransit{Address: server.URL + "/", Token: "s.token", KeyName: "sessions", Namespace: "team", Client: server.Client()}
+	roundTrip(t, vault)
+
+	if _, err := vault.DecryptDataKey(context.Background(), []byte("vault:v1:other")); err == nil || !strings.Contains(err.Error(), "invalid ciphertext") {
+		t.Errorf("Expected the vault error to be returned, got %v", err)
+	}
+
+	vault.Token = "wrong"
+	if _, _, err := vault.GenerateDataKey(context.Background(), AESKeySize32); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("Expected a permission error, got %v", err)
+	}
+}
@@ -0,0 +1,189 @@
+package helpers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// ErrShutdown is returned when work is started on a Lifecycle that has been shut down.
+var ErrShutdown = errors.New("shut down, no new work is accepted")
+
+// Lifecycle tracks the in-flight and background work of a manager so it can be shut down cleanly. It is meant to
+// be embedded (e.g., in rbac.DefaultRBACManager or core.DefaultSessionManager), the zero value is ready to use and
+// a nil Lifecycle accepts all work and never shuts down.
+type Lifecycle struct {
+	mu       sync.Mutex
+	started  bool
+	closed   bool
+	inflight sync.WaitGroup
+	workers  sync.WaitGroup
+
+	// workCtx is cancelled when draining the in-flight work times out, backgroundCtx as soon as Shutdown starts.
+	workCtx          context.Context
+	cancelWork       context.CancelFunc
+	backgroundCtx    context.Context
+	cancelBackground context.CancelFunc
+}
+
+// LifecycleProvider is implemented by managers embedding a Lifecycle.
+type LifecycleProvider interface {
+	GetLifecycle() *Lifecycle
+}
+
+// LifecycleOf returns the Lifecycle of a manager, or nil if it does not provide one.
+func LifecycleOf(manager interface{}) *Lifecycle {
+	// - A typed nil manager would panic when reaching into its embedded Lifecycle
+	if value := reflect.ValueOf(manager); value.Kind() == reflect.Pointer && value.IsNil() {
+		return nil
+	}
+	if provider, ok := manager.(LifecycleProvider); ok {
+		return provider.GetLifecycle()
+	}
+	return nil
+}
+
+// GetLifecycle returns the Lifecycle itself, so embedding it implements LifecycleProvider.
+func (l *Lifecycle) GetLifecycle() *Lifecycle {
+	return l
+}
+
+// start initializes the contexts, the caller holds the lock.
+func (l *Lifecycle) start() {
+	if l.started {
+		return
+	}
+	l.started = true
+	l.workCtx, l.cancelWork = context.WithCancel(context.Background())
+	l.backgroundCtx, l.cancelBackground = context.WithCancel(context.Background())
+}
+
+// Begin registers a unit of in-flight work, e.g., a singleflight fetch. The returned context is cancelled with
+// the parent, or when Shutdown gives up draining, and done must be called once the work is finished.
+func (l *Lifecycle) Begin(parent context.Context) (context.Context, func(), error) {
+	if l == nil {
+		return parent, func() {}, nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return parent, func() {}, ErrShutdown
+	}
+	l.start()
+	l.inflight.Add(1)
+
+	ctx, cancel := context.WithCancel(parent)
+	stop := context.AfterFunc(l.workCtx, cancel)
+	return ctx, func() {
+		stop()
+		cancel()
+		l.inflight.Done()
+	}, nil
+}
+
+// Go runs background work, e.g., an asynchronous cache refresh, its context is cancelled as soon as Shutdown is
+// called. It returns false, without running fn, once the Lifecycle is shut down.
+func (l *Lifecycle) Go(fn func(ctx context.Context)) bool {
+	if l == nil {
+		go fn(context.Background())
+		return true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return false
+	}
+	l.start()
+	l.workers.Add(1)
+
+	ctx := l.backgroundCtx
+	go func() {
+		defer l.workers.Done()
+		fn(ctx)
+	}()
+	return true
+}
+
+// Closed reports whether Shutdown has been called.
+func (l *Lifecycle) Closed() bool {
+	if l == nil {
+		return false
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.closed
+}
+
+// Shutdown stops accepting work, cancels the background work and waits for the in-flight work to finish. If the
+// context expires first, the in-flight work is cancelled too and the context error is returned. Calling it more
+// than once is safe.
+func (l *Lifecycle) Shutdown(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	l.closed = true
+	l.start()
+	l.mu.Unlock()
+
+	l.cancelBackground()
+
+	done := make(chan struct{})
+	go func() {
+		l.inflight.Wait()
+		l.workers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		l.cancelWork()
+		return fmt.Errorf("shutdown did not finish draining: %w", ctx.Err())
+	}
+}
+
+// Close shuts down without a deadline.
+func (l *Lifecycle) Close() error {
+	return l.Shutdown(context.Background())
+}
+
+// Shutdowner is implemented by components that can be shut down, e.g., managers embedding a Lifecycle, cache
+// managers and RouteConstructor.
+type Shutdowner interface {
+	Shutdown(ctx context.Context) error
+}
+
+// ShutdownAll shuts down the components implementing Shutdowner in order, each at most once, and joins their
+// errors. Components that do not implement it, or are nil, are skipped.
+func ShutdownAll(ctx context.Context, components ...interface{}) error {
+	var errs []error
+	seen := make(map[interface{}]bool, len(components))
+	for _, component := range components {
+		shutdowner, ok := component.(Shutdowner)
+		if !ok {
+			continue
+		}
+		if value := reflect.ValueOf(component); value.Kind() == reflect.Pointer && value.IsNil() {
+			continue
+		}
+
+		// - A session manager is often its own RBAC manager
+		if reflect.TypeOf(component).Comparable() {
+			if seen[component] {
+				continue
+			}
+			seen[component] = true
+		}
+		if err := shutdowner.Shutdown(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
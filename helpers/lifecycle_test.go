@@ -0,0 +1,105 @@
+package helpers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLifecycle(t *testing.T) {
+	t.Run("Shutdown drains in-flight work and rejects new work", func(t *testing.T) {
+		var lifecycle Lifecycle
+		_, done, err := lifecycle.Begin(context.Background())
+		if err != nil {
+			t.Fatalf("Expected work to be accepted, got %v", err)
+		}
+
+		finished := make(chan struct{})
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			close(finished)
+			done()
+		}()
+
+		if err = lifecycle.Shutdown(context.Background()); err != nil {
+			t.Fatalf("Expected a clean shutdown, got %v", err)
+		}
+		select {
+		case <-finished:
+		default:
+			t.Error("Expected Shutdown to wait for the in-flight work")
+		}
+
+		if _, _, err = lifecycle.Begin(context.Background()); !errors.Is(err, ErrShutdown) {
+			t.Errorf("Expected ErrShutdown, got %v", err)
+		}
+		if lifecycle.Go(func(context.Context) {}) {
+			t.Error("Expected background work to be rejected")
+		}
+		if !lifecycle.Closed() || lifecycle.Close() != nil {
+			t.Error("Expected the lifecycle to stay closed and Close to be idempotent")
+		}
+	})
+
+	t.Run("Background work is cancelled on shutdown", func(t *testing.T) {
+		var lifecycle Lifecycle
+		cancelled := make(chan struct{})
+		lifecycle.Go(func(ctx context.Context) {
+			<-ctx.Done()
+			close(cancelled)
+		})
+
+		if err := lifecycle.Shutdown(context.Background()); err != nil {
+			t.Fatalf("Expected a clean shutdown, got %v", err)
+		}
+		select {
+		case <-cancelled:
+		default:
+			t.Error("Expected the background work to be cancelled and awaited")
+		}
+	})
+
+	t.Run("Deadline cancels in-flight work", func(t *testing.T) {
+		var lifecycle Lifecycle
+		workCtx, done, _ := lifecycle.Begin(context.Background())
+		defer done()
+
+		deadline, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		if err := lifecycle.Shutdown(deadline); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("Expected the deadline to be exceeded, got %v", err)
+		}
+		select {
+		case <-workCtx.Done():
+		case <-time.After(time.Second):
+			t.Error("Expected the in-flight work to be cancelled")
+		}
+	})
+
+	t.Run("Nil lifecycle accepts everything", func(t *testing.T) {
+		var lifecycle *Lifecycle
+		if LifecycleOf(struct{}{}) != nil {
+			t.Error("Expected nil for a value without a lifecycle")
+		}
+		if _, done, err := lifecycle.Begin(context.Background()); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		} else {
+			done()
+		}
+		if lifecycle.Shutdown(context.Background()) != nil || lifecycle.Closed() {
+			t.Error("Expected a nil lifecycle to never shut down")
+		}
+	})
+
+	t.Run("ShutdownAll shuts each component down once", func(t *testing.T) {
+		first, second := &Lifecycle{}, &Lifecycle{}
+		var nilLifecycle *Lifecycle
+		if err := ShutdownAll(context.Background(), first, nil, nilLifecycle, "not a component", first, second); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if !first.Closed() || !second.Closed() {
+			t.Error("Expected every component to be shut down")
+		}
+	})
+}
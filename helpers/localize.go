@@ -0,0 +1,207 @@
+package helpers
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DateStyle selects which part of a timestamp is formatted.
+type DateStyle string
+
+const (
+	DateStyleDate     DateStyle = "date"
+	DateStyleTime     DateStyle = "time"
+	DateStyleDateTime DateStyle = "datetime"
+)
+
+const (
+	// DefaultLocale is used when the subject has no locale, or its locale is not registered.
+	DefaultLocale = "en"
+
+	DefaultCurrencyDigits = 2
+)
+
+// Localizer formats money and dates for a locale, e.g., an adapter around go-playground/locales or
+// golang.org/x/text when the built-in table is not enough.
+type Localizer interface {
+	FormatMoney(locale string, amount float64, currency string) string
+	FormatDate(locale string, t time.Time, style DateStyle) string
+}
+
+// LocaleFormat describes how money and dates are written in a locale.
+type LocaleFormat struct {
+	// Locale is a BCP 47 tag, e.g., "de" or "en-GB"
+	Locale string
+
+	DecimalSeparator string
+	GroupSeparator   string
+
+	// SymbolAfter places the currency symbol after the amount, separated by a space (e.g., "1.234,50 €")
+	SymbolAfter bool
+
+	// DateLayout, TimeLayout and DateTimeLayout are time.Format layouts
+	DateLayout     string
+	TimeLayout     string
+	DateTimeLayout string
+}
+
+// CurrencyFormat holds the symbol and number of minor digits of an ISO 4217 currency.
+type CurrencyFormat struct {
+	Symbol string
+	Digits int
+}
+
+var localeFormats = struct {
+	sync.RWMutex
+	formats    map[string]LocaleFormat
+	currencies map[string]CurrencyFormat
+}{
+	formats: map[string]LocaleFormat{
+		"en":    {Locale: "en", DecimalSeparator: ".", GroupSeparator: ",", DateLayout: "Jan 2, 2006", TimeLayout: "3:04 PM", DateTimeLayout: "Jan 2, 2006, 3:04 PM"},
+		"en-gb": {Locale: "en-GB", DecimalSeparator: ".", GroupSeparator: ",", DateLayout: "2 Jan 2006", TimeLayout: "15:04", DateTimeLayout: "2 Jan 2006, 15:04"},
+		"de":    {Locale: "de", DecimalSeparator: ",", GroupSeparator: ".", SymbolAfter: true, DateLayout: "02.01.2006", TimeLayout: "15:04", DateTimeLayout: "02.01.2006, 15:04"},
+		"fr":    {Locale: "fr", DecimalSeparator: ",", GroupSeparator: " ", SymbolAfter: true, DateLayout: "02/01/2006", TimeLayout: "15:04", DateTimeLayout: "02/01/2006 15:04"},
+		"es":    {Locale: "es", DecimalSeparator: ",", GroupSeparator: ".", SymbolAfter: true, DateLayout: "02/01/2006", TimeLayout: "15:04", DateTimeLayout: "02/01/2006, 15:04"},
+		"pl":    {Locale: "pl", DecimalSeparator: ",", GroupSeparator: " ", SymbolAfter: true, DateLayout: "02.01.2006", TimeLayout: "15:04", DateTimeLayout: "02.01.2006, 15:04"},
+		"ja":    {Locale: "ja", DecimalSeparator: ".", GroupSeparator: ",", DateLayout: "2006/01/02", TimeLayout: "15:04", DateTimeLayout: "2006/01/02 15:04"},
+	},
+	currencies: map[string]CurrencyFormat{
+		"USD": {Symbol: "$", Digits: 2},
+		"EUR": {Symbol: "€", Digits: 2},
+		"GBP": {Symbol: "£", Digits: 2},
+		"JPY": {Symbol: "¥", Digits: 0},
+		"PLN": {Symbol: "zł", Digits: 2},
+		"CHF": {Symbol: "CHF", Digits: 2},
+	},
+}
+
+// RegisterLocaleFormat adds or replaces a locale used by the built-in Localizer.
+func RegisterLocaleFormat(format LocaleFormat) {
+	localeFormats.Lock()
+	defer localeFormats.Unlock()
+	localeFormats.formats[strings.ToLower(format.Locale)] = format
+}
+
+// RegisterCurrencyFormat adds or replaces a currency used by the built-in Localizer.
+func RegisterCurrencyFormat(code string, format CurrencyFormat) {
+	localeFormats.Lock()
+	defer localeFormats.Unlock()
+	localeFormats.currencies[strings.ToUpper(code)] = format
+}
+
+// GetLocaleFormat returns the closest registered locale: the full tag ("de-AT"), then the language ("de"), then
+// DefaultLocale.
+func GetLocaleFormat(locale string) LocaleFormat {
+	localeFormats.RLock()
+	defer localeFormats.RUnlock()
+
+	tag := strings.ToLower(strings.ReplaceAll(locale, "_", "-"))
+	if format, ok := localeFormats.formats[tag]; ok {
+		return format
+	}
+	if language, _, found := strings.Cut(tag, "-"); found {
+		if format, ok := localeFormats.formats[language]; ok {
+			return format
+		}
+	}
+	return localeFormats.formats[DefaultLocale]
+}
+
+// GetCurrencyFormat returns the registered currency, unknown currencies use their code as the symbol.
+func GetCurrencyFormat(code string) CurrencyFormat {
+	localeFormats.RLock()
+	defer localeFormats.RUnlock()
+
+	code = strings.ToUpper(code)
+	if format, ok := localeFormats.currencies[code]; ok {
+		return format
+	}
+	return CurrencyFormat{Symbol: code, Digits: DefaultCurrencyDigits}
+}
+
+// TableLocalizer is the built-in Localizer, backed by RegisterLocaleFormat / RegisterCurrencyFormat.
+type TableLocalizer struct{}
+
+// FormatMoney rounds the amount to the currency's minor digits and groups the integer part, e.g., "$1,234.50"
+// or "1.234,50 €".
+func (TableLocalizer) FormatMoney(locale string, amount float64, currency string) string {
+	format := GetLocaleFormat(locale)
+	currencyFormat := GetCurrencyFormat(currency)
+
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = math.Abs(amount)
+	}
+
+	number := strconv.FormatFloat(amount, 'f', currencyFormat.Digits, 64)
+	integer, fraction, _ := strings.Cut(number, ".")
+	number = groupDigits(integer, format.GroupSeparator)
+	if fraction != "" {
+		number += format.DecimalSeparator + fraction
+	}
+
+	if currencyFormat.Symbol == "" {
+		return sign + number
+	}
+	if format.SymbolAfter {
+		return sign + number + " " + currencyFormat.Symbol
+	}
+	return sign + currencyFormat.Symbol + number
+}
+
+// FormatDate formats the time with the locale's layout for the style.
+func (TableLocalizer) FormatDate(locale string, t time.Time, style DateStyle) string {
+	format := GetLocaleFormat(locale)
+	switch style {
+	case DateStyleTime:
+		return t.Format(format.TimeLayout)
+	case DateStyleDateTime:
+		return t.Format(format.DateTimeLayout)
+	default:
+		return t.Format(format.DateLayout)
+	}
+}
+
+// groupDigits inserts the separator every three digits from the right.
+func groupDigits(digits string, separator string) string {
+	if len(digits) <= 3 || separator == "" {
+		return digits
+	}
+
+	var builder strings.Builder
+	head := len(digits) % 3
+	if head > 0 {
+		builder.WriteString(digits[:head])
+	}
+	for i := head; i < len(digits); i += 3 {
+		if builder.Len() > 0 {
+			builder.WriteString(separator)
+		}
+		builder.WriteString(digits[i : i+3])
+	}
+	return builder.String()
+}
+
+var defaultLocalizer Localizer = TableLocalizer{}
+var defaultLocalizerLock sync.RWMutex
+
+// SetDefaultLocalizer replaces the Localizer used for output localization, nil restores TableLocalizer.
+func SetDefaultLocalizer(localizer Localizer) {
+	if localizer == nil {
+		localizer = TableLocalizer{}
+	}
+	defaultLocalizerLock.Lock()
+	defer defaultLocalizerLock.Unlock()
+	defaultLocalizer = localizer
+}
+
+// GetDefaultLocalizer returns the Localizer used for output localization.
+func GetDefaultLocalizer() Localizer {
+	defaultLocalizerLock.RLock()
+	defer defaultLocalizerLock.RUnlock()
+	return defaultLocalizer
+}
@@ -0,0 +1,65 @@
+package helpers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTableLocalizer_FormatMoney(t *testing.T) {
+	localizer := TableLocalizer{}
+
+	tests := []struct {
+		name     string
+		locale   string
+		amount   float64
+		currency string
+		want     string
+	}{
+		{name: "English dollars", locale: "en", amount: 1234.5, currency: "USD", want: "$1,234.50"},
+		{name: "German euros", locale: "de", amount: 1234.5, currency: "EUR", want: "1.234,50 €"},
+		{name: "Region falls back to language", locale: "de-AT", amount: 1234567.891, currency: "EUR", want: "1.234.567,89 €"},
+		{name: "Currency without minor digits", locale: "ja", amount: 1500, currency: "JPY", want: "¥1,500"},
+		{name: "Negative amount", locale: "en", amount: -12, currency: "GBP", want: "-£12.00"},
+		{name: "Unknown locale and currency", locale: "xx", amount: 5, currency: "abc", want: "ABC5.00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := localizer.FormatMoney(tt.locale, tt.amount, tt.currency); got != tt.want {
+				t.Errorf("Expected '%s', got '%s'", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestTableLocalizer_FormatDate(t *testing.T) {
+	localizer := TableLocalizer{}
+	date := time.Date(2024, time.March, 5, 14, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		locale string
+		style  DateStyle
+		want   string
+	}{
+		{locale: "en", style: DateStyleDate, want: "Mar 5, 2024"},
+		{locale: "en", style: DateStyleTime, want: "2:30 PM"},
+		{locale: "en-GB", style: DateStyleDateTime, want: "5 Mar 2024, 14:30"},
+		{locale: "de", style: DateStyleDate, want: "05.03.2024"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.locale+" "+string(tt.style), func(t *testing.T) {
+			if got := localizer.FormatDate(tt.locale, date, tt.style); got != tt.want {
+				t.Errorf("Expected '%s', got '%s'", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRegisterLocaleFormat(t *testing.T) {
+	RegisterLocaleFormat(LocaleFormat{Locale: "test-TEST", DecimalSeparator: "'", GroupSeparator: "_", DateLayout: "2006"})
+
+	if got := (TableLocalizer{}).FormatMoney("test-test", 1000, "CHF"); got != "CHF1_000'00" {
+		t.Errorf("Expected 'CHF1_000'00', got '%s'", got)
+	}
+}
@@ -0,0 +1,109 @@
+package helpers
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+	"gopkg.in/yaml.v3"
+)
+
+var negotiableFormats = struct {
+	sync.RWMutex
+	offered []string
+}{offered: []string{binding.MIMEJSON, binding.MIMEXML, binding.MIMEXML2, binding.MIMEYAML2, binding.MIMEYAML, binding.MIMEPROTOBUF}}
+
+// SetNegotiableFormats sets the response content types SuccessResponse negotiates with the Accept header, JSON
+// is always offered first and is used when nothing else matches. Calling it without formats disables
+// negotiation, e.g., SetNegotiableFormats(binding.MIMEXML) only adds XML.
+func SetNegotiableFormats(formats ...string) {
+	offered := []string{binding.MIMEJSON}
+	for _, format := range formats {
+		if format != binding.MIMEJSON {
+			offered = append(offered, format)
+		}
+	}
+
+	negotiableFormats.Lock()
+	defer negotiableFormats.Unlock()
+	negotiableFormats.offered = offered
+}
+
+// NegotiateContentType returns the offered content type that best matches the request's Accept header,
+// falling back to JSON.
+func NegotiateContentType(ctx *gin.Context) string {
+	return negotiateContentType(ctx, true)
+}
+
+// negotiateContentType negotiates the offered content types, Protobuf is only offered for proto.Message data.
+func negotiateContentType(ctx *gin.Context, allowProtobuf bool) string {
+	negotiableFormats.RLock()
+	offered := negotiableFormats.offered
+	negotiableFormats.RUnlock()
+
+	if !allowProtobuf {
+		filtered := make([]string, 0, len(offered))
+		for _, format := range offered {
+			if format != binding.MIMEPROTOBUF {
+				filtered = append(filtered, format)
+			}
+		}
+		offered = filtered
+	}
+
+	if ctx == nil || ctx.Request == nil || len(offered) < 2 || ctx.GetHeader("Accept") == "" {
+		return binding.MIMEJSON
+	}
+
+	ctx.Header("Vary", "Accept")
+	if negotiated := ctx.NegotiateFormat(offered...); negotiated != "" {
+		return negotiated
+	}
+	return binding.MIMEJSON
+}
+
+// renderNegotiated writes the data in the negotiated format, data that can not be encoded as XML / YAML
+// (e.g., a map for XML) is written as JSON instead. Protobuf is only negotiated when the data is a proto.Message.
+// The encoded body is compressed / tagged according to the request's ResponseEncoding.
+func renderNegotiated(ctx *gin.Context, statusCode int, data interface{}) {
+	var (
+		body []byte
+		err  error
+	)
+
+	message, isMessage := data.(proto.Message)
+	contentType := negotiateContentType(ctx, isMessage)
+	switch contentType {
+	case binding.MIMEPROTOBUF:
+		body, err = proto.Marshal(message)
+	case binding.MIMEXML, binding.MIMEXML2:
+		body, err = xml.Marshal(data)
+	case binding.MIMEYAML, binding.MIMEYAML2:
+		body, err = yaml.Marshal(data)
+	default:
+		contentType = binding.MIMEJSON
+		body, err = json.Marshal(data)
+	}
+
+	if err != nil && contentType != binding.MIMEJSON {
+		Logger(ctx).Debug("Failed to encode response in the negotiated format, falling back to JSON",
+			zap.String("content_type", contentType), zap.Error(err))
+		contentType = binding.MIMEJSON
+		body, err = json.Marshal(data)
+	}
+
+	// - Leave data JSON can not encode to gin, which reports the error as before
+	if err != nil {
+		ctx.JSON(statusCode, data)
+		return
+	}
+
+	if contentType != binding.MIMEPROTOBUF {
+		contentType += "; charset=utf-8"
+	}
+	writeEncodedBody(ctx, statusCode, contentType, body)
+}
@@ -0,0 +1,107 @@
+package helpers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type negotiatedOutput struct {
+	Name string `json:"name" xml:"name" yaml:"name"`
+}
+
+func TestSuccessResponse_Negotiation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	respond := func(accept string, data interface{}) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+		ctx.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+		if accept != "" {
+			ctx.Request.Header.Set("Accept", accept)
+		}
+		SuccessResponse(ctx, http.StatusOK, data, nil)
+		return recorder
+	}
+
+	tests := []struct {
+		name        string
+		accept      string
+		contentType string
+		body        string
+	}{
+		{name: "No Accept header defaults to JSON", accept: "", contentType: binding.MIMEJSON, body: `{"name":"John"}`},
+		{name: "Wildcard defaults to JSON", accept: "*/*", contentType: binding.MIMEJSON, body: `{"name":"John"}`},
+		{name: "XML", accept: "application/xml", contentType: binding.MIMEXML, body: `<negotiatedOutput><name>John</name></negotiatedOutput>`},
+		{name: "YAML", accept: "application/yaml", contentType: binding.MIMEYAML2, body: "name: John\n"},
+		{name: "Preferred type wins", accept: "text/html, application/x-yaml;q=0.9", contentType: binding.MIMEYAML, body: "name: John\n"},
+		{name: "Unsupported type falls back to JSON", accept: "text/html", contentType: binding.MIMEJSON, body: `{"name":"John"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := respond(tt.accept, &negotiatedOutput{Name: "John"})
+			if contentType := recorder.Header().Get("Content-Type"); !strings.HasPrefix(contentType, tt.contentType) {
+				t.Errorf("Expected content type %s, got %s", tt.contentType, contentType)
+			}
+			if body := recorder.Body.String(); body != tt.body {
+				t.Errorf("Expected body %q, got %q", tt.body, body)
+			}
+		})
+	}
+
+	t.Run("Data that can not be encoded as XML falls back to JSON", func(t *testing.T) {
+		recorder := respond("application/xml", map[string]interface{}{"name": "John"})
+		if contentType := recorder.Header().Get("Content-Type"); !strings.HasPrefix(contentType, binding.MIMEJSON) {
+			t.Errorf("Expected JSON, got %s", contentType)
+		}
+	})
+
+	t.Run("Negotiation can be disabled", func(t *testing.T) {
+		SetNegotiableFormats()
+		defer SetNegotiableFormats(binding.MIMEXML, binding.MIMEXML2, binding.MIMEYAML2, binding.MIMEYAML, binding.MIMEPROTOBUF)
+
+		recorder := respond("application/xml", &negotiatedOutput{Name: "John"})
+		if contentType := recorder.Header().Get("Content-Type"); !strings.HasPrefix(contentType, binding.MIMEJSON) {
+			t.Errorf("Expected JSON, got %s", contentType)
+		}
+	})
+}
+
+func TestSuccessResponse_Protobuf(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	respond := func(data interface{}) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+		ctx.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+		ctx.Request.Header.Set("Accept", binding.MIMEPROTOBUF)
+		SuccessResponse(ctx, http.StatusOK, data, nil)
+		return recorder
+	}
+
+	t.Run("Messages are encoded as Protobuf", func(t *testing.T) {
+		recorder := respond(wrapperspb.String("John"))
+		if contentType := recorder.Header().Get("Content-Type"); contentType != binding.MIMEPROTOBUF {
+			t.Fatalf("Expected %s, got %s", binding.MIMEPROTOBUF, contentType)
+		}
+
+		var decoded wrapperspb.StringValue
+		if err := proto.Unmarshal(recorder.Body.Bytes(), &decoded); err != nil || decoded.GetValue() != "John" {
+			t.Errorf("Expected 'John', got %q (%v)", decoded.GetValue(), err)
+		}
+	})
+
+	t.Run("Other data falls back to JSON", func(t *testing.T) {
+		recorder := respond(&negotiatedOutput{Name: "John"})
+		if contentType := recorder.Header().Get("Content-Type"); !strings.HasPrefix(contentType, binding.MIMEJSON) {
+			t.Errorf("Expected JSON, got %s", contentType)
+		}
+	})
+}
@@ -0,0 +1,99 @@
+package helpers
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	DefaultPageLimit = 20
+
+	// MaxPageLimit is the largest limit PageParams accepts, it matches the limit's validate tag.
+	MaxPageLimit = 100
+
+	PageCursorParam  = "cursor"
+	PageLimitParam   = "limit"
+	TotalCountHeader = "X-Total-Count"
+)
+
+// PageParams are the page parameters of a paginated route, embed them in the route's input to bind and validate
+// them like any other field:
+//
+//	type ListNotesInput struct {
+//		helpers.PageParams
+//		Tag string `form:"tag"`
+//	}
+type PageParams struct {
+	Cursor string `form:"cursor" json:"cursor,omitempty" validate:"omitempty,max=512"`
+	Limit  int    `form:"limit" json:"limit,omitempty" validate:"omitempty,min=1,max=100"`
+}
+
+// PageLimit returns the requested limit, or DefaultPageLimit when none (or 0) was given.
+func (p PageParams) PageLimit() int {
+	if p.Limit <= 0 {
+		return DefaultPageLimit
+	}
+	return p.Limit
+}
+
+// Paginated is implemented by paginated outputs, the executor adds the Link and X-Total-Count headers of
+// routes returning them.
+type Paginated interface {
+	PageInfo() (nextCursor string, total *int64)
+}
+
+// PaginatedResponse is the envelope of a page of items, e.g., as the output of a route:
+//
+//	func(input *ListNotesInput, data *core.Handler[BaseRoute]) (*helpers.PaginatedResponse[Note], *errors.AppError) {
+//		notes, next := store.List(input.Cursor, input.PageLimit())
+//		return helpers.NewPage(notes, next), nil
+//	}
+//
+// An empty NextCursor marks the last page, Total is only sent when it is known.
+type PaginatedResponse[T any] struct {
+	Items      []T    `json:"items" validate:"dive"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	Total      *int64 `json:"total,omitempty"`
+}
+
+// NewPage returns a page of items, nil items are sent as an empty list.
+func NewPage[T any](items []T, nextCursor string) *PaginatedResponse[T] {
+	if items == nil {
+		items = []T{}
+	}
+	return &PaginatedResponse[T]{Items: items, NextCursor: nextCursor}
+}
+
+// WithTotal sets the total number of items across all pages.
+func (p *PaginatedResponse[T]) WithTotal(total int64) *PaginatedResponse[T] {
+	p.Total = &total
+	return p
+}
+
+// PageInfo implements Paginated.
+func (p PaginatedResponse[T]) PageInfo() (string, *int64) {
+	return p.NextCursor, p.Total
+}
+
+// PaginationHeaders returns the headers of a page: a Link to the next page, keeping the request's other query
+// parameters, and the X-Total-Count when the total is known.
+func PaginationHeaders(ctx *gin.Context, page Paginated) map[string]string {
+	headers := make(map[string]string, 2)
+	if page == nil {
+		return headers
+	}
+
+	nextCursor, total := page.PageInfo()
+	if nextCursor != "" && ctx != nil && ctx.Request != nil {
+		next := *ctx.Request.URL
+		query := next.Query()
+		query.Set(PageCursorParam, nextCursor)
+		next.RawQuery = query.Encode()
+		headers["Link"] = "<" + next.RequestURI() + `>; rel="next"`
+	}
+	if total != nil {
+		headers[TotalCountHeader] = strconv.FormatInt(*total, 10)
+	}
+	return headers
+}
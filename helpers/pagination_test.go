@@ -0,0 +1,51 @@
+package helpers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestPaginatedResponse(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Envelope", func(t *testing.T) {
+		encoded, _ := json.Marshal(NewPage[string](nil, ""))
+		if string(encoded) != `{"items":[]}` {
+			t.Errorf("Expected an empty last page, got %s", encoded)
+		}
+		encoded, _ = json.Marshal(NewPage([]int{1, 2}, "abc").WithTotal(7))
+		if string(encoded) != `{"items":[1,2],"next_cursor":"abc","total":7}` {
+			t.Errorf("Unexpected page %s", encoded)
+		}
+	})
+
+	t.Run("Page limit", func(t *testing.T) {
+		if limit := (PageParams{}).PageLimit(); limit != DefaultPageLimit {
+			t.Errorf("Expected the default limit, got %d", limit)
+		}
+		if limit := (PageParams{Limit: 5}).PageLimit(); limit != 5 {
+			t.Errorf("Expected 5, got %d", limit)
+		}
+	})
+
+	t.Run("Headers", func(t *testing.T) {
+		ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+		ctx.Request = httptest.NewRequest(http.MethodGet, "/notes?limit=2&cursor=old&tag=a%20b", nil)
+
+		headers := PaginationHeaders(ctx, NewPage([]int{1}, "next/1").WithTotal(3))
+		if link := headers["Link"]; link != `</notes?cursor=next%2F1&limit=2&tag=a+b>; rel="next"` {
+			t.Errorf("Unexpected Link %q", link)
+		}
+		if total := headers[TotalCountHeader]; total != "3" {
+			t.Errorf("Expected a total of 3, got %q", total)
+		}
+
+		if headers := PaginationHeaders(ctx, NewPage([]int{1}, "")); len(headers) != 0 {
+			t.Errorf("Expected no headers on the last page without a total, got %v", headers)
+		}
+	})
+}
@@ -0,0 +1,102 @@
+package helpers
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const (
+	// RequestIDHeader is read from incoming requests and echoed on every response.
+	RequestIDHeader = "X-Request-ID"
+
+	// RequestIDContextKey is the gin context key holding the request ID.
+	RequestIDContextKey = "gothic_request_id"
+
+	DefaultRequestIDSize = 24
+	MaximumRequestIDSize = 128
+)
+
+// AcceptIncomingRequestID controls whether a well-formed X-Request-ID sent by the client (or a proxy) is reused,
+// when false a new ID is always generated.
+var AcceptIncomingRequestID = true
+
+type requestIDKey struct{}
+
+// validRequestID only allows short, printable IDs so they can be safely logged and echoed.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > MaximumRequestIDSize {
+		return false
+	}
+	for _, c := range id {
+		isAlphaNumeric := (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+		if !isAlphaNumeric && c != '-' && c != '_' && c != '.' && c != ':' {
+			return false
+		}
+	}
+	return true
+}
+
+// EnsureRequestID returns the request ID, accepting a valid incoming X-Request-ID or generating one. The ID is
+// stored on the gin context and the request context, and set on the response header. It is safe to call
+// multiple times.
+func EnsureRequestID(ctx *gin.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	if id := ctx.GetString(RequestIDContextKey); id != "" {
+		return id
+	}
+
+	id := ""
+	if ctx.Request != nil && AcceptIncomingRequestID {
+		if incoming := ctx.GetHeader(RequestIDHeader); validRequestID(incoming) {
+			id = incoming
+		}
+	}
+	if id == "" {
+		generated, err := GenerateID(DefaultRequestIDSize)
+		if err != nil {
+			zap.L().Warn("Failed to generate request ID", zap.Error(err))
+			return ""
+		}
+		id = generated
+	}
+
+	ctx.Set(RequestIDContextKey, id)
+	if ctx.Request != nil {
+		ctx.Request = ctx.Request.WithContext(context.WithValue(ctx.Request.Context(), requestIDKey{}, id))
+	}
+	ctx.Header(RequestIDHeader, id)
+	return id
+}
+
+// RequestIDMiddleware assigns a request ID before any other handler runs.
+func RequestIDMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		EnsureRequestID(ctx)
+		ctx.Next()
+	}
+}
+
+// GetRequestID returns the request ID from a gin context or a context derived from the request context, or an
+// empty string if none was assigned.
+func GetRequestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	if ginCtx, ok := ctx.(*gin.Context); ok {
+		return ginCtx.GetString(RequestIDContextKey)
+	}
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// Logger returns the global zap logger with the request ID attached, if there is one.
+func Logger(ctx context.Context) *zap.Logger {
+	if id := GetRequestID(ctx); id != "" {
+		return zap.L().With(zap.String("request_id", id))
+	}
+	return zap.L()
+}
@@ -0,0 +1,75 @@
+package helpers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grzegorzmaniak/gothic/errors"
+)
+
+func newRequestIDTestContext(incoming string) (*gin.Context, *httptest.ResponseRecorder) {
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	if incoming != "" {
+		ctx.Request.Header.Set(RequestIDHeader, incoming)
+	}
+	return ctx, w
+}
+
+func TestEnsureRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name      string
+		incoming  string
+		wantReuse bool
+	}{
+		{name: "Generates an ID when none is sent", incoming: "", wantReuse: false},
+		{name: "Accepts a valid incoming ID", incoming: "req-123_abc.def:1", wantReuse: true},
+		{name: "Rejects IDs with invalid characters", incoming: "bad id\n", wantReuse: false},
+		{name: "Rejects IDs that are too long", incoming: strings.Repeat("a", MaximumRequestIDSize+1), wantReuse: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, w := newRequestIDTestContext(tt.incoming)
+			id := EnsureRequestID(ctx)
+
+			if id == "" {
+				t.Fatal("Expected a request ID")
+			}
+			if (id == tt.incoming) != tt.wantReuse {
+				t.Errorf("Expected reuse %v, got ID '%s'", tt.wantReuse, id)
+			}
+			if w.Header().Get(RequestIDHeader) != id {
+				t.Errorf("Expected the ID to be echoed, got '%s'", w.Header().Get(RequestIDHeader))
+			}
+			if EnsureRequestID(ctx) != id {
+				t.Error("Expected EnsureRequestID to be idempotent")
+			}
+			if GetRequestID(ctx) != id || GetRequestID(ctx.Request.Context()) != id {
+				t.Error("Expected the ID on both the gin and the request context")
+			}
+		})
+	}
+}
+
+func TestResponses_EchoRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	ctx, w := newRequestIDTestContext("client-id")
+	ErrorResponse(ctx, errors.NewBadRequest("Invalid input", nil))
+	if w.Header().Get(RequestIDHeader) != "client-id" {
+		t.Errorf("Expected ErrorResponse to echo the request ID, got '%s'", w.Header().Get(RequestIDHeader))
+	}
+
+	ctx, w = newRequestIDTestContext("")
+	SuccessResponse(ctx, http.StatusOK, gin.H{"ok": true}, nil)
+	if w.Header().Get(RequestIDHeader) == "" {
+		t.Error("Expected SuccessResponse to set a request ID")
+	}
+}
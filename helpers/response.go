@@ -8,15 +8,52 @@ import (
 	"go.uber.org/zap"
 )
 
-// ErrorResponse sends a JSON error response to the client.
+// ResponseErrorContextKey is the gin context key holding the *errors.AppError sent by ErrorResponse.
+const ResponseErrorContextKey = "gothic_response_error"
+
+// ResponseCaptureContextKey marks requests whose responses are returned to the caller instead of being sent, see
+// SetResponseCapture.
+const ResponseCaptureContextKey = "gothic_response_capture"
+
+// SetResponseCapture makes ErrorResponse and SuccessResponse leave the response unsent, ErrorResponse still
+// records its error for GetResponseError. It is how core.ExecuteRouteE hands the result to its caller.
+func SetResponseCapture(ctx *gin.Context, capture bool) {
+	ctx.Set(ResponseCaptureContextKey, capture)
+}
+
+// ResponseCaptured reports whether the responses of the request are captured, see SetResponseCapture.
+func ResponseCaptured(ctx *gin.Context) bool {
+	return ctx != nil && ctx.GetBool(ResponseCaptureContextKey)
+}
+
+// GetResponseError returns the error ErrorResponse sent for the request, if any, e.g., for audit logging.
+func GetResponseError(ctx *gin.Context) *errors.AppError {
+	if ctx == nil {
+		return nil
+	}
+	if value, ok := ctx.Get(ResponseErrorContextKey); ok {
+		if appErr, ok := value.(*errors.AppError); ok {
+			return appErr
+		}
+	}
+	return nil
+}
+
+// ErrorResponse sends a JSON error response to the client, using the RFC 7807 problem+json format when
+// selected globally (SetDefaultResponseFormat) or for the request (SetResponseFormat).
 func ErrorResponse(ctx *gin.Context, appErr *errors.AppError) {
 	production := gin.Mode() == gin.ReleaseMode
+	EnsureRequestID(ctx)
 
 	if appErr == nil {
-		zap.L().Warn("ErrorResponse called with nil error")
+		Logger(ctx).Warn("ErrorResponse called with nil error")
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "An unexpected error occurred."})
 		return
 	}
+	ctx.Set(ResponseErrorContextKey, appErr)
+	if ResponseCaptured(ctx) {
+		return
+	}
 
 	logFields := []zap.Field{
 		zap.Int("statusCode", appErr.Code),
@@ -31,12 +68,39 @@ func ErrorResponse(ctx *gin.Context, appErr *errors.AppError) {
 		logFields = append(logFields, zap.Any("details", appErr.Details))
 	}
 
-	zap.L().Error("Application error occurred", logFields...)
+	if GetResponseFormat(ctx) == ResponseFormatProblem {
+		problemErrorResponse(ctx, appErr, production, logFields)
+		return
+	}
+
+	Logger(ctx).Error("Application error occurred", logFields...)
 	ctx.AbortWithStatusJSON(appErr.Code, appErr.ToJSONResponse(production))
 }
 
-// SuccessResponse sends a JSON success response.
+// problemErrorResponse sends an RFC 7807 application/problem+json error response. The instance is set on a copy,
+// the same error may be shared by requests, e.g., coalesced ones or a package level error.
+func problemErrorResponse(ctx *gin.Context, appErr *errors.AppError, production bool, logFields []zap.Field) {
+	problem := *appErr
+	if problem.Instance == "" && ProblemInstanceFunc != nil {
+		problem.Instance = ProblemInstanceFunc(ctx)
+	}
+
+	logFields = append(logFields, zap.String("instance", problem.Instance))
+	Logger(ctx).Error("Application error occurred", logFields...)
+
+	ctx.Header("Content-Type", errors.ProblemContentType)
+	ctx.AbortWithStatusJSON(problem.Code, problem.ToProblemResponse(production))
+}
+
+// SuccessResponse sends a success response, JSON unless the Accept header negotiates XML or YAML
+// (see SetNegotiableFormats). Bodies are compressed and tagged with an ETag when enabled globally
+// (SetDefaultResponseEncoding) or for the request (SetResponseEncoding).
 func SuccessResponse(ctx *gin.Context, statusCode int, data interface{}, headers map[string]string) {
+	EnsureRequestID(ctx)
+	if ResponseCaptured(ctx) {
+		return
+	}
+
 	if headers != nil {
 		for key, value := range headers {
 			ctx.Header(key, value)
@@ -48,5 +112,5 @@ func SuccessResponse(ctx *gin.Context, statusCode int, data interface{}, headers
 		return
 	}
 
-	ctx.JSON(statusCode, data)
+	renderNegotiated(ctx, statusCode, data)
 }
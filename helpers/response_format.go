@@ -0,0 +1,78 @@
+package helpers
+
+import (
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ResponseFormat selects how ErrorResponse encodes errors.
+type ResponseFormat int
+
+const (
+	// ResponseFormatDefault inherits the global format, it is the zero value so routes inherit by default.
+	ResponseFormatDefault ResponseFormat = iota
+
+	// ResponseFormatJSON is the original {"error": "...", "details": ...} shape.
+	ResponseFormatJSON
+
+	// ResponseFormatProblem encodes errors as RFC 7807 application/problem+json.
+	ResponseFormatProblem
+)
+
+const (
+	// ResponseFormatContextKey is the gin context key holding the per-request ResponseFormat.
+	ResponseFormatContextKey = "gothic_response_format"
+
+	DefaultProblemInstanceIdSize = 16
+)
+
+var defaultResponseFormat atomic.Int32
+
+func init() {
+	defaultResponseFormat.Store(int32(ResponseFormatJSON))
+}
+
+// SetDefaultResponseFormat sets the global error format, used when no per-request format is set.
+// Passing ResponseFormatDefault restores ResponseFormatJSON.
+func SetDefaultResponseFormat(format ResponseFormat) {
+	if format == ResponseFormatDefault {
+		format = ResponseFormatJSON
+	}
+	defaultResponseFormat.Store(int32(format))
+}
+
+// SetResponseFormat overrides the error format for a single request, ResponseFormatDefault is ignored.
+func SetResponseFormat(ctx *gin.Context, format ResponseFormat) {
+	if ctx == nil || format == ResponseFormatDefault {
+		return
+	}
+	ctx.Set(ResponseFormatContextKey, format)
+}
+
+// GetResponseFormat returns the error format for the request.
+func GetResponseFormat(ctx *gin.Context) ResponseFormat {
+	if ctx != nil {
+		if value, ok := ctx.Get(ResponseFormatContextKey); ok {
+			if format, ok := value.(ResponseFormat); ok {
+				return format
+			}
+		}
+	}
+	return ResponseFormat(defaultResponseFormat.Load())
+}
+
+// ProblemInstanceFunc generates the RFC 7807 instance of errors that do not set one. The instance is logged
+// alongside the error, so it can be used to correlate a client report with the server logs. The request ID
+// is used when there is one.
+var ProblemInstanceFunc = func(ctx *gin.Context) string {
+	if requestID := GetRequestID(ctx); requestID != "" {
+		return "urn:request:" + requestID
+	}
+
+	id, err := GenerateID(DefaultProblemInstanceIdSize)
+	if err != nil {
+		return ""
+	}
+	return "urn:error:" + id
+}
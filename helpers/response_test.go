@@ -273,3 +273,95 @@ func TestSuccessResponse(t *testing.T) {
 		}
 	})
 }
+
+func TestErrorResponse_ProblemFormat(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Per-request format", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+
+		SetResponseFormat(ctx, ResponseFormatProblem)
+		ErrorResponse(ctx, errors.NewBadRequest("Invalid input", nil))
+
+		if contentType := w.Header().Get("Content-Type"); contentType != errors.ProblemContentType {
+			t.Errorf("Expected content type '%s', got '%s'", errors.ProblemContentType, contentType)
+		}
+
+		var response map[string]interface{}
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to parse JSON response: %v", err)
+		}
+		if response["detail"] != "Invalid input" || response["status"] != float64(http.StatusBadRequest) {
+			t.Errorf("Unexpected problem response: %v", response)
+		}
+		if instance, _ := response["instance"].(string); instance == "" {
+			t.Error("Expected a generated instance")
+		}
+	})
+
+	t.Run("Shared errors get an instance per response", func(t *testing.T) {
+		shared := errors.NewBadRequest("Invalid input", nil)
+		instance := func() string {
+			w := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(w)
+			SetResponseFormat(ctx, ResponseFormatProblem)
+			ErrorResponse(ctx, shared)
+
+			var response map[string]interface{}
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to parse JSON response: %v", err)
+			}
+			instance, _ := response["instance"].(string)
+			return instance
+		}
+
+		first, second := instance(), instance()
+		if first == "" || first == second {
+			t.Errorf("Expected a new instance per response, got '%s' and '%s'", first, second)
+		}
+		if shared.Instance != "" {
+			t.Errorf("Expected the shared error to be left untouched, got instance '%s'", shared.Instance)
+		}
+	})
+
+	t.Run("Global format with per-request override", func(t *testing.T) {
+		SetDefaultResponseFormat(ResponseFormatProblem)
+		defer SetDefaultResponseFormat(ResponseFormatJSON)
+
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		if GetResponseFormat(ctx) != ResponseFormatProblem {
+			t.Error("Expected the global problem format")
+		}
+
+		SetResponseFormat(ctx, ResponseFormatJSON)
+		ErrorResponse(ctx, errors.NewBadRequest("Invalid input", nil))
+
+		var response map[string]interface{}
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to parse JSON response: %v", err)
+		}
+		if response["error"] != "Invalid input" {
+			t.Errorf("Expected the JSON format, got %v", response)
+		}
+	})
+}
+
+func TestResponseCapture(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	SetResponseCapture(ctx, true)
+
+	SuccessResponse(ctx, http.StatusOK, map[string]string{"message": "ok"}, nil)
+	appErr := errors.NewBadRequest("Invalid input", nil)
+	ErrorResponse(ctx, appErr)
+
+	if ctx.Writer.Written() || w.Body.Len() != 0 {
+		t.Errorf("Expected nothing to be written, got %s", w.Body.String())
+	}
+	if GetResponseError(ctx) != appErr {
+		t.Error("Expected the captured error to be recorded")
+	}
+}
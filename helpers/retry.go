@@ -0,0 +1,118 @@
+package helpers
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"reflect"
+	"time"
+)
+
+const (
+	DefaultRetryBaseDelay = 20 * time.Millisecond
+	DefaultRetryMaxDelay  = 500 * time.Millisecond
+)
+
+// RetryPolicy retries calls failing with transient errors, e.g., a cache write during a momentary Redis blip. The
+// waits grow exponentially from BaseDelay up to MaxDelay, with full jitter so retrying instances don't fire in
+// lockstep. A nil RetryPolicy makes a single attempt.
+type RetryPolicy struct {
+	// Attempts is how many times the call is made in total, 1 or less never retries
+	Attempts int
+
+	// BaseDelay is the wait before the first retry, before jitter (Default: DefaultRetryBaseDelay)
+	BaseDelay time.Duration
+
+	// MaxDelay caps the wait between attempts, before jitter (Default: DefaultRetryMaxDelay)
+	MaxDelay time.Duration
+
+	// Retryable reports whether an error is transient (Default: every error but context cancellation and
+	// ErrShutdown)
+	Retryable func(err error) bool
+}
+
+// RetryPolicyProvider is implemented by managers whose cache and source calls are retried, see RetryPolicyOf.
+type RetryPolicyProvider interface {
+	GetRetryPolicy() *RetryPolicy
+}
+
+// RetryPolicyOf returns the RetryPolicy of a manager, or nil if it does not provide one.
+func RetryPolicyOf(manager interface{}) *RetryPolicy {
+	// - A typed nil manager would panic when reaching into its fields
+	if value := reflect.ValueOf(manager); value.Kind() == reflect.Pointer && value.IsNil() {
+		return nil
+	}
+	if provider, ok := manager.(RetryPolicyProvider); ok {
+		return provider.GetRetryPolicy()
+	}
+	return nil
+}
+
+type retryPolicyContextKey struct{}
+
+// WithRetryPolicy returns a context carrying the policy, for calls made far from the manager that owns it.
+func WithRetryPolicy(ctx context.Context, policy *RetryPolicy) context.Context {
+	if policy == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, retryPolicyContextKey{}, policy)
+}
+
+// RetryPolicyFromContext returns the policy set with WithRetryPolicy, or nil.
+func RetryPolicyFromContext(ctx context.Context) *RetryPolicy {
+	if ctx == nil {
+		return nil
+	}
+	policy, _ := ctx.Value(retryPolicyContextKey{}).(*RetryPolicy)
+	return policy
+}
+
+// Do calls fn until it succeeds, fails with an error that isn't retryable, runs out of attempts or ctx is done.
+// The last error is returned.
+func (p *RetryPolicy) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	err := fn(ctx)
+	if p == nil {
+		return err
+	}
+
+	for attempt := 1; attempt < p.Attempts && err != nil && p.retryable(err); attempt++ {
+		timer := time.NewTimer(p.delay(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		err = fn(ctx)
+	}
+	return err
+}
+
+// Retry calls fn with the policy, see RetryPolicy.Do.
+func Retry[T any](ctx context.Context, policy *RetryPolicy, fn func(ctx context.Context) (T, error)) (T, error) {
+	var result T
+	err := policy.Do(ctx, func(ctx context.Context) error {
+		var err error
+		result, err = fn(ctx)
+		return err
+	})
+	return result, err
+}
+
+func (p *RetryPolicy) retryable(err error) bool {
+	if p.Retryable != nil {
+		return p.Retryable(err)
+	}
+	return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) && !errors.Is(err, ErrShutdown)
+}
+
+// delay returns the jittered wait before the attempt, a random duration up to BaseDelay * 2^(attempt-1).
+func (p *RetryPolicy) delay(attempt int) time.Duration {
+	base := DefaultTimeDuration(p.BaseDelay, DefaultRetryBaseDelay)
+	ceiling := DefaultTimeDuration(p.MaxDelay, DefaultRetryMaxDelay)
+	backoff := ceiling
+	if shift := attempt - 1; shift < 32 && base<<shift > 0 && base<<shift < ceiling {
+		backoff = base << shift
+	}
+	return rand.N(backoff) + 1
+}
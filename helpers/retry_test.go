@@ -0,0 +1,121 @@
+package helpers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	transient := errors.New("connection reset")
+	policy := &RetryPolicy{Attempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+
+	t.Run("Transient errors are retried until the call succeeds", func(t *testing.T) {
+		calls := 0
+		value, err := Retry(context.Background(), policy, func(context.Context) (string, error) {
+			calls++
+			if calls < 3 {
+				return "", transient
+			}
+			return "ok", nil
+		})
+		if err != nil || value != "ok" || calls != 3 {
+			t.Errorf("Expected success on the third attempt, got '%s' after %d calls: %v", value, calls, err)
+		}
+	})
+
+	t.Run("The last error is returned once the attempts run out", func(t *testing.T) {
+		calls := 0
+		err := policy.Do(context.Background(), func(context.Context) error {
+			calls++
+			return transient
+		})
+		if !errors.Is(err, transient) || calls != 3 {
+			t.Errorf("Expected 3 attempts and the error, got %d: %v", calls, err)
+		}
+	})
+
+	t.Run("Errors that aren't retryable are returned at once", func(t *testing.T) {
+		for _, err := range []error{context.Canceled, ErrShutdown} {
+			calls := 0
+			_ = policy.Do(context.Background(), func(context.Context) error {
+				calls++
+				return err
+			})
+			if calls != 1 {
+				t.Errorf("Expected a single attempt for '%v', got %d", err, calls)
+			}
+		}
+
+		custom := &RetryPolicy{Attempts: 3, Retryable: func(err error) bool { return false }}
+		calls := 0
+		_ = custom.Do(context.Background(), func(context.Context) error {
+			calls++
+			return transient
+		})
+		if calls != 1 {
+			t.Errorf("Expected Retryable to be used, got %d attempts", calls)
+		}
+	})
+
+	t.Run("A nil policy makes a single attempt", func(t *testing.T) {
+		var none *RetryPolicy
+		calls := 0
+		if err := none.Do(context.Background(), func(context.Context) error {
+			calls++
+			return transient
+		}); !errors.Is(err, transient) || calls != 1 {
+			t.Errorf("Expected a single attempt, got %d: %v", calls, err)
+		}
+	})
+
+	t.Run("Waiting stops when the context is done", func(t *testing.T) {
+		slow := &RetryPolicy{Attempts: 5, BaseDelay: time.Hour, MaxDelay: time.Hour}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		started := time.Now()
+		if err := slow.Do(ctx, func(context.Context) error { return transient }); !errors.Is(err, transient) {
+			t.Errorf("Expected the last error, got %v", err)
+		}
+		if time.Since(started) > time.Second {
+			t.Error("Expected the wait to stop with the context")
+		}
+	})
+
+	t.Run("Delays are jittered and capped", func(t *testing.T) {
+		capped := &RetryPolicy{BaseDelay: 10 * time.Millisecond, MaxDelay: 40 * time.Millisecond}
+		for attempt := 1; attempt < 70; attempt++ {
+			if delay := capped.delay(attempt); delay <= 0 || delay > 40*time.Millisecond {
+				t.Fatalf("Expected a delay within the cap for attempt %d, got %v", attempt, delay)
+			}
+		}
+		if delay := capped.delay(1); delay > 10*time.Millisecond {
+			t.Errorf("Expected the first delay within the base delay, got %v", delay)
+		}
+	})
+
+	t.Run("Policies are found on managers and contexts", func(t *testing.T) {
+		var manager *retryingManager
+		if RetryPolicyOf(manager) != nil || RetryPolicyOf(struct{}{}) != nil {
+			t.Error("Expected no policy for nil managers or managers without one")
+		}
+		if RetryPolicyOf(&retryingManager{policy: policy}) != policy {
+			t.Error("Expected the manager's policy")
+		}
+		if RetryPolicyFromContext(WithRetryPolicy(context.Background(), policy)) != policy {
+			t.Error("Expected the context's policy")
+		}
+		if RetryPolicyFromContext(context.Background()) != nil {
+			t.Error("Expected no policy on a plain context")
+		}
+	})
+}
+
+type retryingManager struct {
+	policy *RetryPolicy
+}
+
+func (m *retryingManager) GetRetryPolicy() *RetryPolicy {
+	return m.policy
+}
@@ -51,8 +51,9 @@ func SymmetricEncrypt(key []byte, plaintext []byte, associatedData []byte) ([]by
 	return append(nonce, ciphertext...), nil
 }
 
-// SymmetricDecrypt decrypts ciphertext (which must include a prepended nonce) using AES-GCM.
-func SymmetricDecrypt(key []byte, ciphertextWithNonce []byte, associatedData []byte) ([]byte, error) {
+// NewSymmetricAEAD returns the AES-GCM AEAD for a key, it is safe for concurrent use and can be reused for every
+// SymmetricDecryptInPlace with that key.
+func NewSymmetricAEAD(key []byte) (cipher.AEAD, error) {
 	block, err := aes.NewCipher(key)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create AES cipher block: %w", err)
@@ -62,6 +63,15 @@ func SymmetricDecrypt(key []byte, ciphertextWithNonce []byte, associatedData []b
 	if err != nil {
 		return nil, fmt.Errorf("failed to create GCM AEAD: %w", err)
 	}
+	return gcm, nil
+}
+
+// SymmetricDecrypt decrypts ciphertext (which must include a prepended nonce) using AES-GCM.
+func SymmetricDecrypt(key []byte, ciphertextWithNonce []byte, associatedData []byte) ([]byte, error) {
+	gcm, err := NewSymmetricAEAD(key)
+	if err != nil {
+		return nil, err
+	}
 
 	nonceSize := gcm.NonceSize()
 	if len(ciphertextWithNonce) < nonceSize {
@@ -76,3 +86,20 @@ func SymmetricDecrypt(key []byte, ciphertextWithNonce []byte, associatedData []b
 
 	return plaintext, nil
 }
+
+// SymmetricDecryptInPlace is SymmetricDecrypt with a prepared AEAD (see NewSymmetricAEAD), the plaintext
+// overwrites the ciphertext so nothing is allocated. The ciphertext is garbage if decryption fails.
+func SymmetricDecryptInPlace(gcm cipher.AEAD, ciphertextWithNonce []byte, associatedData []byte) ([]byte, error) {
+	nonceSize := gcm.NonceSize()
+	if len(ciphertextWithNonce) < nonceSize {
+		return nil, fmt.Errorf("ciphertext is too short (missing nonce)")
+	}
+
+	nonce, ciphertext := ciphertextWithNonce[:nonceSize], ciphertextWithNonce[nonceSize:]
+	plaintext, err := gcm.Open(ciphertext[:0], nonce, ciphertext, associatedData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt or authenticate data: %w", err)
+	}
+
+	return plaintext, nil
+}
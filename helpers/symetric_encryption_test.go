@@ -304,3 +304,29 @@ func TestSymmetricEncryptDecrypt(t *testing.T) {
 		}
 	})
 }
+
+func TestSymmetricDecryptInPlace(t *testing.T) {
+	key, _ := GenerateSymmetricKey(AESKeySize32)
+	aead, err := NewSymmetricAEAD(key)
+	if err != nil {
+		t.Fatalf("Failed to create the AEAD: %v", err)
+	}
+
+	plaintext := []byte("Message")
+	ciphertext, _ := SymmetricEncrypt(key, plaintext, []byte("ad"))
+	decrypted, err := SymmetricDecryptInPlace(aead, ciphertext, []byte("ad"))
+	if err != nil || !bytes.Equal(decrypted, plaintext) {
+		t.Fatalf("Expected the plaintext, got %q, %v", decrypted, err)
+	}
+	if &decrypted[0] != &ciphertext[aead.NonceSize()] {
+		t.Error("Expected the plaintext to overwrite the ciphertext")
+	}
+
+	ciphertext, _ = SymmetricEncrypt(key, plaintext, []byte("ad"))
+	if _, err = SymmetricDecryptInPlace(aead, ciphertext, []byte("other")); err == nil {
+		t.Error("Expected a mismatched associated data to fail")
+	}
+	if _, err = SymmetricDecryptInPlace(aead, []byte("short"), nil); err == nil {
+		t.Error("Expected a short ciphertext to fail")
+	}
+}
@@ -0,0 +1,181 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	SubjectRevisionCacheKeyPrefix = "subject_rev:" // Key: subject_rev:<subjectIdentifier>
+)
+
+// ErrAdminUnsupported is returned by the write functions when the manager does not implement ManagerAdmin.
+var ErrAdminUnsupported = errors.New("rbac manager does not support changing roles and permissions")
+
+// PermissionTarget is who a permission is granted to or revoked from, exactly one of Subject and Role is set.
+type PermissionTarget struct {
+	Subject string
+	Role    string
+}
+
+// SubjectTarget targets the permissions granted directly to a subject.
+func SubjectTarget(subjectIdentifier string) PermissionTarget {
+	return PermissionTarget{Subject: subjectIdentifier}
+}
+
+// RoleTarget targets the permissions of a role.
+func RoleTarget(roleIdentifier string) PermissionTarget {
+	return PermissionTarget{Role: roleIdentifier}
+}
+
+func (t PermissionTarget) validate() error {
+	if (t.Subject == "") == (t.Role == "") {
+		return fmt.Errorf("permission target must have exactly one of a subject or a role")
+	}
+	return nil
+}
+
+// ManagerAdmin is implemented by managers whose roles and permissions can be changed through gothic. The methods
+// only persist the change, use the package functions (AssignRole, GrantPermission, ...) so the cached roles and
+// permissions are invalidated as well.
+type ManagerAdmin interface {
+	// AssignRole adds a role to a subject.
+	AssignRole(ctx context.Context, subjectIdentifier string, roleIdentifier string) error
+
+	// RevokeRole removes a role from a subject.
+	RevokeRole(ctx context.Context, subjectIdentifier string, roleIdentifier string) error
+
+	// GrantPermission adds the permission bits to a subject or role.
+	GrantPermission(ctx context.Context, target PermissionTarget, permission *Permission) error
+
+	// RevokePermission removes the permission bits from a subject or role.
+	RevokePermission(ctx context.Context, target PermissionTarget, permission *Permission) error
+}
+
+func managerAdmin(rbacManager Manager) (ManagerAdmin, error) {
+	admin, ok := rbacManager.(ManagerAdmin)
+	if !ok {
+		return nil, ErrAdminUnsupported
+	}
+	return admin, nil
+}
+
+// AssignRole adds a role to a subject and invalidates the subject's cached roles and permissions.
+func AssignRole(ctx context.Context, rbacManager Manager, subjectIdentifier string, roleIdentifier string) error {
+	admin, err := managerAdmin(rbacManager)
+	if err != nil {
+		return err
+	}
+	if err = admin.AssignRole(ctx, subjectIdentifier, roleIdentifier); err != nil {
+		return fmt.Errorf("manager: failed to assign role '%s' to '%s': %w", roleIdentifier, subjectIdentifier, err)
+	}
+	return invalidateSubject(ctx, rbacManager, subjectIdentifier)
+}
+
+// RevokeRole removes a role from a subject and invalidates the subject's cached roles and permissions.
+func RevokeRole(ctx context.Context, rbacManager Manager, subjectIdentifier string, roleIdentifier string) error {
+	admin, err := managerAdmin(rbacManager)
+	if err != nil {
+		return err
+	}
+	if err = admin.RevokeRole(ctx, subjectIdentifier, roleIdentifier); err != nil {
+		return fmt.Errorf("manager: failed to revoke role '%s' from '%s': %w", roleIdentifier, subjectIdentifier, err)
+	}
+	return invalidateSubject(ctx, rbacManager, subjectIdentifier)
+}
+
+// GrantPermission adds the permission bits to a subject or role and invalidates its cached permissions.
+func GrantPermission(ctx context.Context, rbacManager Manager, target PermissionTarget, permission *Permission) error {
+	admin, err := managerAdmin(rbacManager)
+	if err != nil {
+		return err
+	}
+	if err = target.validate(); err != nil {
+		return err
+	}
+	if permission == nil {
+		return fmt.Errorf("permission is nil")
+	}
+	if err = admin.GrantPermission(ctx, target, permission); err != nil {
+		return fmt.Errorf("manager: failed to grant permission: %w", err)
+	}
+	return invalidateTarget(ctx, rbacManager, target)
+}
+
+// RevokePermission removes the permission bits from a subject or role and invalidates its cached permissions.
+func RevokePermission(ctx context.Context, rbacManager Manager, target PermissionTarget, permission *Permission) error {
+	admin, err := managerAdmin(rbacManager)
+	if err != nil {
+		return err
+	}
+	if err = target.validate(); err != nil {
+		return err
+	}
+	if permission == nil {
+		return fmt.Errorf("permission is nil")
+	}
+	if err = admin.RevokePermission(ctx, target, permission); err != nil {
+		return fmt.Errorf("manager: failed to revoke permission: %w", err)
+	}
+	return invalidateTarget(ctx, rbacManager, target)
+}
+
+func invalidateTarget(ctx context.Context, rbacManager Manager, target PermissionTarget) error {
+	if target.Subject != "" {
+		return invalidateSubject(ctx, rbacManager, target.Subject)
+	}
+
+	cacheInstance, err := rbacManager.GetCache()
+	if err != nil || cacheInstance == nil {
+		return err
+	}
+	if err = cacheInstance.Delete(ctx, tenantCacheKey(ctx, RolePermissionsCacheKeyPrefix+target.Role)); err != nil {
+		return fmt.Errorf("failed to invalidate the permissions of role '%s': %w", target.Role, err)
+	}
+	return nil
+}
+
+// invalidateSubject bumps the subject's revision. The subject entries are cached per session (rbacCacheId), so
+// they cannot be deleted by subject, instead the revision is part of their keys (see subjectCacheId).
+func invalidateSubject(ctx context.Context, rbacManager Manager, subjectIdentifier string) error {
+	cacheInstance, err := rbacManager.GetCache()
+	if err != nil || cacheInstance == nil {
+		return err
+	}
+
+	// - The revision outlives every entry cached under the previous one, so losing it never revives them
+	ttl := 2 * max(rbacManager.GetSubjectPermissionsCacheTtl(), rbacManager.GetSubjectRolesCacheTtl())
+	revision := strconv.FormatInt(time.Now().UnixNano(), 36)
+	return setInCache(ctx, cacheInstance, tenantCacheKey(ctx, SubjectRevisionCacheKeyPrefix+subjectIdentifier), revision, ttl, func(v string) ([]byte, error) {
+		return []byte(v), nil
+	})
+}
+
+// subjectCacheId returns the rbacCacheId the subject's entries are cached under. Managers implementing ManagerAdmin
+// can change a subject at any time, so their entries are keyed by the subject's revision as well.
+func subjectCacheId(ctx context.Context, rbacManager Manager, subjectIdentifier string, rbacCacheId string) string {
+	if _, ok := rbacManager.(ManagerAdmin); !ok {
+		return rbacCacheId
+	}
+
+	cacheInstance, err := rbacManager.GetCache()
+	if err != nil || cacheInstance == nil {
+		return rbacCacheId
+	}
+
+	revision, found, err := fetchFromCache(ctx, cacheInstance, tenantCacheKey(ctx, SubjectRevisionCacheKeyPrefix+subjectIdentifier), func(b []byte) (string, error) {
+		return string(b), nil
+	})
+	if err != nil {
+		zap.L().Warn("Failed to read the subject revision from cache", zap.Error(err))
+	}
+	if !found {
+		return rbacCacheId
+	}
+	return rbacCacheId + "@" + revision
+}
@@ -0,0 +1,150 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/eko/gocache/lib/v4/cache"
+)
+
+// adminRbacManager keeps its roles and permissions in memory so they can be changed through ManagerAdmin.
+type adminRbacManager struct {
+	mockRbacManager
+	cache           *mockCache
+	subjectRoles    map[string][]string
+	rolePermissions map[string]Permissions
+}
+
+func newAdminRbacManager() *adminRbacManager {
+	return &adminRbacManager{
+		cache:           &mockCache{},
+		subjectRoles:    map[string][]string{},
+		rolePermissions: map[string]Permissions{},
+	}
+}
+
+func (m *adminRbacManager) GetCache() (cache.CacheInterface[[]byte], error) {
+	return m.cache, nil
+}
+
+func (m *adminRbacManager) GetSubjectRolesAndPermissions(_ context.Context, subjectIdentifier string) (Permissions, []string, error) {
+	return Permissions{}, slices.Clone(m.subjectRoles[subjectIdentifier]), nil
+}
+
+func (m *adminRbacManager) GetRolePermissions(_ context.Context, roleIdentifier string) (Permissions, error) {
+	return append(Permissions{}, m.rolePermissions[roleIdentifier]...), nil
+}
+
+func (m *adminRbacManager) AssignRole(_ context.Context, subjectIdentifier string, roleIdentifier string) error {
+	m.subjectRoles[subjectIdentifier] = append(m.subjectRoles[subjectIdentifier], roleIdentifier)
+	return nil
+}
+
+func (m *adminRbacManager) RevokeRole(_ context.Context, subjectIdentifier string, roleIdentifier string) error {
+	m.subjectRoles[subjectIdentifier] = slices.DeleteFunc(m.subjectRoles[subjectIdentifier], func(role string) bool {
+		return role == roleIdentifier
+	})
+	return nil
+}
+
+func (m *adminRbacManager) GrantPermission(_ context.Context, target PermissionTarget, permission *Permission) error {
+	if target.Role == "" {
+		return errors.New("only role permissions are stored")
+	}
+	m.rolePermissions[target.Role] = append(m.rolePermissions[target.Role], permission)
+	return nil
+}
+
+func (m *adminRbacManager) RevokePermission(_ context.Context, target PermissionTarget, _ *Permission) error {
+	delete(m.rolePermissions, target.Role)
+	return nil
+}
+
+func TestAdminInvalidatesSubjects(t *testing.T) {
+	ctx := context.Background()
+	manager := newAdminRbacManager()
+	manager.rolePermissions["editor"] = Permissions{readWrite}
+	write := AccessRequirements{Permissions: readWrite, Policy: PermissionsOnly}
+
+	check := func() bool {
+		allowed, err := CheckAccess(ctx, manager, "alice", "session-1", write)
+		if err != nil {
+			t.Fatalf("CheckAccess() error = %v", err)
+		}
+		return allowed
+	}
+
+	if check() {
+		t.Fatal("Expected alice to be denied before the role is assigned")
+	}
+
+	if err := AssignRole(ctx, manager, "alice", "editor"); err != nil {
+		t.Fatalf("AssignRole() error = %v", err)
+	}
+	if !check() {
+		t.Error("Expected the assigned role to apply to the cached session")
+	}
+
+	if err := RevokeRole(ctx, manager, "alice", "editor"); err != nil {
+		t.Fatalf("RevokeRole() error = %v", err)
+	}
+	if check() {
+		t.Error("Expected the revoked role to no longer apply to the cached session")
+	}
+}
+
+func TestAdminInvalidatesRoles(t *testing.T) {
+	ctx := context.Background()
+	manager := newAdminRbacManager()
+	manager.subjectRoles["alice"] = []string{"editor"}
+	read := AccessRequirements{Permissions: readOnly, Policy: PermissionsOnly}
+
+	if allowed, _ := CheckAccess(ctx, manager, "alice", "session-1", read); allowed {
+		t.Fatal("Expected the editor role to have no permissions yet")
+	}
+
+	if err := GrantPermission(ctx, manager, RoleTarget("editor"), readOnly); err != nil {
+		t.Fatalf("GrantPermission() error = %v", err)
+	}
+	if allowed, _ := CheckAccess(ctx, manager, "alice", "session-1", read); !allowed {
+		t.Error("Expected the granted permission to replace the cached role permissions")
+	}
+
+	if err := RevokePermission(ctx, manager, RoleTarget("editor"), readOnly); err != nil {
+		t.Fatalf("RevokePermission() error = %v", err)
+	}
+	if allowed, _ := CheckAccess(ctx, manager, "alice", "session-1", read); allowed {
+		t.Error("Expected the revoked permission to replace the cached role permissions")
+	}
+}
+
+func TestAdminValidation(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Targets need exactly one of a subject and a role", func(t *testing.T) {
+		for _, target := range []PermissionTarget{{}, {Subject: "alice", Role: "editor"}} {
+			if err := GrantPermission(ctx, newAdminRbacManager(), target, readOnly); err == nil {
+				t.Errorf("Expected target %+v to be rejected", target)
+			}
+		}
+	})
+
+	t.Run("Managers without ManagerAdmin are rejected", func(t *testing.T) {
+		err := AssignRole(ctx, &mockRbacManager{}, "alice", "editor")
+		if !errors.Is(err, ErrAdminUnsupported) {
+			t.Errorf("Expected ErrAdminUnsupported, got %v", err)
+		}
+	})
+
+	t.Run("Failed changes do not invalidate", func(t *testing.T) {
+		manager := newAdminRbacManager()
+		if err := GrantPermission(ctx, manager, SubjectTarget("alice"), readOnly); err == nil {
+			t.Fatal("Expected the manager's error to be returned")
+		}
+		if len(manager.cache.data) != 0 {
+			t.Errorf("Expected nothing to be cached, got %d entries", len(manager.cache.data))
+		}
+	})
+}
@@ -0,0 +1,129 @@
+package rbac
+
+import (
+	"context"
+	"fmt"
+)
+
+// Attributes is a flat set of subject attributes (usually the session claims) that attribute based
+// policies are evaluated against.
+type Attributes map[string]string
+
+// Get returns the value of an attribute and whether it was present.
+func (a Attributes) Get(key string) (string, bool) {
+	if a == nil {
+		return "", false
+	}
+	value, ok := a[key]
+	return value, ok
+}
+
+// DenyReason describes why an attribute based policy denied access. It is designed to be sent
+// to the client, so it should never contain sensitive information.
+type DenyReason struct {
+	// Code is a short, machine-readable identifier for the reason (e.g., "org_mismatch")
+	Code string `json:"code"`
+
+	// Attribute is the attribute that caused the denial, if applicable
+	Attribute string `json:"attribute,omitempty"`
+
+	// Message is a human-readable explanation of the denial
+	Message string `json:"message,omitempty"`
+}
+
+// AttributeDecision is the outcome of an attribute based policy evaluation.
+type AttributeDecision struct {
+	Allowed bool
+	Reasons []DenyReason
+}
+
+// Allow returns a decision that grants access.
+func Allow() *AttributeDecision {
+	return &AttributeDecision{Allowed: true}
+}
+
+// Deny returns a decision that denies access with the given reasons.
+func Deny(reasons ...DenyReason) *AttributeDecision {
+	return &AttributeDecision{Allowed: false, Reasons: reasons}
+}
+
+// AttributeEvaluator evaluates an attribute based access control (ABAC) policy. It is run after the
+// role / permission check has passed, so it only needs to express the extra conditions, for example
+// "subject.orgID == resource.orgID".
+//
+// The resource is whatever the route is operating on, for routes run by the core executor this is the
+// validated input (a pointer to the input struct, or a map for dynamic routes).
+type AttributeEvaluator interface {
+	Evaluate(ctx context.Context, subject Attributes, resource interface{}) (*AttributeDecision, error)
+}
+
+// AttributeEvaluatorFunc allows a plain function to be used as an AttributeEvaluator.
+type AttributeEvaluatorFunc func(ctx context.Context, subject Attributes, resource interface{}) (*AttributeDecision, error)
+
+func (f AttributeEvaluatorFunc) Evaluate(ctx context.Context, subject Attributes, resource interface{}) (*AttributeDecision, error) {
+	return f(ctx, subject, resource)
+}
+
+// AllOf combines multiple evaluators, access is only granted if every evaluator allows it.
+// All evaluators are run so that the caller receives every deny reason, not just the first one.
+func AllOf(evaluators ...AttributeEvaluator) AttributeEvaluator {
+	return AttributeEvaluatorFunc(func(ctx context.Context, subject Attributes, resource interface{}) (*AttributeDecision, error) {
+		return EvaluateAttributes(ctx, subject, resource, evaluators...)
+	})
+}
+
+// EvaluateAttributes runs each evaluator and merges their decisions. A nil decision is treated as a denial,
+// as silently granting access when an evaluator forgot to return a decision is not a safe default.
+func EvaluateAttributes(
+	ctx context.Context,
+	subject Attributes,
+	resource interface{},
+	evaluators ...AttributeEvaluator,
+) (*AttributeDecision, error) {
+	merged := Allow()
+	for i, evaluator := range evaluators {
+		if evaluator == nil {
+			continue
+		}
+
+		decision, err := evaluator.Evaluate(ctx, subject, resource)
+		if err != nil {
+			return nil, fmt.Errorf("attribute evaluator %d failed: %w", i, err)
+		}
+
+		if decision == nil {
+			merged.Allowed = false
+			merged.Reasons = append(merged.Reasons, DenyReason{Code: "no_decision", Message: "Policy did not return a decision"})
+			continue
+		}
+
+		if !decision.Allowed {
+			merged.Allowed = false
+			merged.Reasons = append(merged.Reasons, decision.Reasons...)
+		}
+	}
+
+	return merged, nil
+}
+
+// RequireAttributeMatch builds an evaluator that grants access only when the subject attribute
+// equals the value extracted from the resource.
+func RequireAttributeMatch(attribute string, resourceValue func(resource interface{}) (string, bool)) AttributeEvaluator {
+	return AttributeEvaluatorFunc(func(ctx context.Context, subject Attributes, resource interface{}) (*AttributeDecision, error) {
+		subjectValue, ok := subject.Get(attribute)
+		if !ok || subjectValue == "" {
+			return Deny(DenyReason{Code: "attribute_missing", Attribute: attribute, Message: "Subject is missing a required attribute"}), nil
+		}
+
+		if resourceValue == nil {
+			return nil, fmt.Errorf("resource value extractor for '%s' is nil", attribute)
+		}
+
+		value, ok := resourceValue(resource)
+		if !ok || value != subjectValue {
+			return Deny(DenyReason{Code: "attribute_mismatch", Attribute: attribute, Message: "Subject attribute does not match the resource"}), nil
+		}
+
+		return Allow(), nil
+	})
+}
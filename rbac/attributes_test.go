@@ -0,0 +1,119 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type attributeTestResource struct {
+	OrgID string
+}
+
+func orgIDFromResource(resource interface{}) (string, bool) {
+	r, ok := resource.(*attributeTestResource)
+	if !ok || r == nil {
+		return "", false
+	}
+	return r.OrgID, true
+}
+
+func TestRequireAttributeMatch(t *testing.T) {
+	ctx := context.Background()
+	evaluator := RequireAttributeMatch("org_id", orgIDFromResource)
+
+	t.Run("Matching attribute grants access", func(t *testing.T) {
+		decision, err := evaluator.Evaluate(ctx, Attributes{"org_id": "org-1"}, &attributeTestResource{OrgID: "org-1"})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if !decision.Allowed {
+			t.Errorf("Expected access to be granted, got reasons %v", decision.Reasons)
+		}
+	})
+
+	t.Run("Mismatched attribute denies access", func(t *testing.T) {
+		decision, err := evaluator.Evaluate(ctx, Attributes{"org_id": "org-1"}, &attributeTestResource{OrgID: "org-2"})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if decision.Allowed {
+			t.Fatal("Expected access to be denied")
+		}
+		if len(decision.Reasons) != 1 || decision.Reasons[0].Code != "attribute_mismatch" {
+			t.Errorf("Expected a single attribute_mismatch reason, got %v", decision.Reasons)
+		}
+	})
+
+	t.Run("Missing subject attribute denies access", func(t *testing.T) {
+		decision, err := evaluator.Evaluate(ctx, nil, &attributeTestResource{OrgID: "org-1"})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if decision.Allowed || decision.Reasons[0].Code != "attribute_missing" {
+			t.Errorf("Expected attribute_missing denial, got %+v", decision)
+		}
+	})
+}
+
+func TestEvaluateAttributes(t *testing.T) {
+	ctx := context.Background()
+	allow := AttributeEvaluatorFunc(func(ctx context.Context, subject Attributes, resource interface{}) (*AttributeDecision, error) {
+		return Allow(), nil
+	})
+	denyA := AttributeEvaluatorFunc(func(ctx context.Context, subject Attributes, resource interface{}) (*AttributeDecision, error) {
+		return Deny(DenyReason{Code: "a"}), nil
+	})
+	denyB := AttributeEvaluatorFunc(func(ctx context.Context, subject Attributes, resource interface{}) (*AttributeDecision, error) {
+		return Deny(DenyReason{Code: "b"}), nil
+	})
+
+	t.Run("No evaluators grants access", func(t *testing.T) {
+		decision, err := EvaluateAttributes(ctx, nil, nil)
+		if err != nil || !decision.Allowed {
+			t.Errorf("Expected access with no evaluators, got %+v, %v", decision, err)
+		}
+	})
+
+	t.Run("Nil evaluators are skipped", func(t *testing.T) {
+		decision, err := EvaluateAttributes(ctx, nil, nil, nil, allow)
+		if err != nil || !decision.Allowed {
+			t.Errorf("Expected access, got %+v, %v", decision, err)
+		}
+	})
+
+	t.Run("All deny reasons are collected", func(t *testing.T) {
+		decision, err := AllOf(allow, denyA, denyB).Evaluate(ctx, nil, nil)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if decision.Allowed {
+			t.Fatal("Expected access to be denied")
+		}
+		if len(decision.Reasons) != 2 || decision.Reasons[0].Code != "a" || decision.Reasons[1].Code != "b" {
+			t.Errorf("Expected reasons [a b], got %v", decision.Reasons)
+		}
+	})
+
+	t.Run("Nil decision is treated as a denial", func(t *testing.T) {
+		nilDecision := AttributeEvaluatorFunc(func(ctx context.Context, subject Attributes, resource interface{}) (*AttributeDecision, error) {
+			return nil, nil
+		})
+		decision, err := EvaluateAttributes(ctx, nil, nil, nilDecision)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if decision.Allowed {
+			t.Error("Expected a nil decision to deny access")
+		}
+	})
+
+	t.Run("Evaluator error is returned", func(t *testing.T) {
+		failing := AttributeEvaluatorFunc(func(ctx context.Context, subject Attributes, resource interface{}) (*AttributeDecision, error) {
+			return nil, errors.New("lookup failed")
+		})
+		if _, err := EvaluateAttributes(ctx, nil, nil, failing); err == nil {
+			t.Error("Expected an error from the failing evaluator")
+		}
+	})
+}
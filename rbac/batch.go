@@ -0,0 +1,54 @@
+package rbac
+
+import (
+	"context"
+	"fmt"
+)
+
+// PermissionCheck is one entry of a CheckPermissionsBatch call, e.g., whether the "Edit invoice" button of a UI
+// should be shown.
+type PermissionCheck struct {
+	// Name identifies the check in the results, it must be unique within a batch.
+	Name string
+
+	AccessRequirements
+}
+
+// CheckPermissionsBatch evaluates many checks for a subject in a single pass, the subject's roles and permissions
+// are fetched once and the permissions of its roles at most once, no matter how many checks need them. This is
+// meant for handlers computing a capabilities matrix, every route should keep using its own requirements.
+//
+// The results are keyed by PermissionCheck.Name, any fetch failure fails the whole batch.
+func CheckPermissionsBatch(
+	ctx context.Context,
+	rbacManager Manager,
+	subjectIdentifier string,
+	rbacCacheId string,
+	checks []PermissionCheck,
+) (map[string]bool, error) {
+	results := make(map[string]bool, len(checks))
+	for _, check := range checks {
+		if _, exists := results[check.Name]; exists {
+			return nil, fmt.Errorf("duplicate permission check '%s'", check.Name)
+		}
+		results[check.Name] = false
+	}
+
+	if len(checks) == 0 {
+		return results, nil
+	}
+
+	access, err := loadSubjectAccess(ctx, rbacManager, subjectIdentifier, rbacCacheId)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, check := range checks {
+		allowed, err := access.check(check.AccessRequirements)
+		if err != nil {
+			return nil, fmt.Errorf("failed to evaluate permission check '%s': %w", check.Name, err)
+		}
+		results[check.Name] = allowed
+	}
+	return results, nil
+}
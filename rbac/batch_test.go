@@ -0,0 +1,89 @@
+package rbac
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+)
+
+// countingRbacManager counts the source fetches made through the mock manager.
+type countingRbacManager struct {
+	mockRbacManager
+	subjectFetches atomic.Int32
+	roleFetches    atomic.Int32
+	namedFetches   atomic.Int32
+}
+
+func (m *countingRbacManager) GetSubjectRolesAndPermissions(ctx context.Context, subjectIdentifier string) (Permissions, []string, error) {
+	m.subjectFetches.Add(1)
+	return m.mockRbacManager.GetSubjectRolesAndPermissions(ctx, subjectIdentifier)
+}
+
+func (m *countingRbacManager) GetRolePermissions(ctx context.Context, roleIdentifier string) (Permissions, error) {
+	m.roleFetches.Add(1)
+	return m.mockRbacManager.GetRolePermissions(ctx, roleIdentifier)
+}
+
+func (m *countingRbacManager) GetSubjectNamedPermissions(ctx context.Context, subjectIdentifier string) (PermissionSet, error) {
+	m.namedFetches.Add(1)
+	return m.mockRbacManager.GetSubjectNamedPermissions(ctx, subjectIdentifier)
+}
+
+func TestCheckPermissionsBatch(t *testing.T) {
+	ctx := context.Background()
+
+	checks := []PermissionCheck{
+		{Name: "read", AccessRequirements: AccessRequirements{Permissions: readOnly, Policy: PermissionsOnly}},
+		{Name: "write", AccessRequirements: AccessRequirements{Permissions: readWrite, Policy: PermissionsOnly}},
+		{Name: "admin", AccessRequirements: AccessRequirements{Roles: map[string]bool{"admin": true}, Policy: RoleOnly}},
+		{Name: "refund", AccessRequirements: AccessRequirements{NamedPermissions: MustPermissionSet("billing.refunds.issue"), Policy: PermissionsOnly}},
+		{Name: "public"},
+	}
+
+	t.Run("Matches CheckAccess and fetches the subject once", func(t *testing.T) {
+		for _, subject := range []string{"admin-user", "readonly-user", "nobody"} {
+			manager := &countingRbacManager{}
+			results, err := CheckPermissionsBatch(ctx, manager, subject, "", checks)
+			if err != nil {
+				t.Fatalf("CheckPermissionsBatch() error = %v", err)
+			}
+
+			for _, check := range checks {
+				want, err := CheckAccess(ctx, &mockRbacManager{}, subject, "", check.AccessRequirements)
+				if err != nil {
+					t.Fatalf("CheckAccess() error = %v", err)
+				}
+				if results[check.Name] != want {
+					t.Errorf("%s: %s = %v, want %v", subject, check.Name, results[check.Name], want)
+				}
+			}
+
+			if manager.subjectFetches.Load() != 1 || manager.namedFetches.Load() > 1 {
+				t.Errorf("%s: expected one subject fetch, got %d (and %d named)", subject, manager.subjectFetches.Load(), manager.namedFetches.Load())
+			}
+			if manager.roleFetches.Load() > 1 {
+				t.Errorf("%s: expected the role permissions to be fetched at most once, got %d", subject, manager.roleFetches.Load())
+			}
+		}
+	})
+
+	t.Run("Duplicate names are rejected", func(t *testing.T) {
+		manager := &countingRbacManager{}
+		_, err := CheckPermissionsBatch(ctx, manager, "admin-user", "", []PermissionCheck{{Name: "read"}, {Name: "read"}})
+		if err == nil {
+			t.Error("Expected duplicate check names to be rejected")
+		}
+		if manager.subjectFetches.Load() != 0 {
+			t.Error("Expected nothing to be fetched for an invalid batch")
+		}
+	})
+
+	t.Run("Fetch failures fail the batch", func(t *testing.T) {
+		if _, err := CheckPermissionsBatch(ctx, &mockRbacManager{}, "user-with-error", "", checks); err == nil {
+			t.Error("Expected the subject fetch failure to be returned")
+		}
+		if _, err := CheckPermissionsBatch(ctx, &mockRbacManager{}, "named-error-user", "", checks); err == nil {
+			t.Error("Expected the named permission fetch failure to be returned")
+		}
+	})
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/eko/gocache/lib/v4/cache"
 	"github.com/eko/gocache/lib/v4/store"
+	"github.com/grzegorzmaniak/gothic/helpers"
 	"go.uber.org/zap"
 )
 
@@ -53,7 +54,11 @@ func setInCache[T any](
 	if err != nil {
 		return fmt.Errorf("cache: failed to marshal key '%s': %w", key, err)
 	}
-	if err := cache.Set(ctx, key, str, store.WithExpiration(ttl)); err != nil {
+	// - Transient write failures are retried with the policy of the fetch, see beginFetch
+	err = helpers.RetryPolicyFromContext(ctx).Do(ctx, func(ctx context.Context) error {
+		return cache.Set(ctx, key, str, store.WithExpiration(ttl))
+	})
+	if err != nil {
 		// Log the error but do not return it to avoid breaking the main flow
 		zap.L().Warn("Failed to set value in cache", zap.String("key", key), zap.Error(err))
 	}
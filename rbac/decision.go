@@ -0,0 +1,257 @@
+package rbac
+
+import (
+	"context"
+	"math/big"
+	"slices"
+)
+
+// The reasons of a Decision, naming the rule that decided the check.
+const (
+	DecisionNoRequirements          = "no_requirements"
+	DecisionDenied                  = "denied"
+	DecisionRole                    = "role"
+	DecisionMissingRole             = "missing_role"
+	DecisionPermissions             = "permissions"
+	DecisionMissingPermissions      = "missing_permissions"
+	DecisionMissingNamedPermissions = "missing_named_permissions"
+)
+
+// Decision is the trace of an access check, see ExplainAccess. It describes why the check passed or failed and
+// where the subject's permissions came from, without enforcing anything.
+type Decision struct {
+	// Allowed is the result CheckAccess returns for the same requirements.
+	Allowed bool `json:"allowed"`
+
+	// Reason names the rule that decided the check, one of the Decision* constants.
+	Reason string `json:"reason"`
+
+	// Policy is the RouteRbacPolicy the requirements were evaluated with.
+	Policy RouteRbacPolicy `json:"policy"`
+
+	// Roles are the subject's roles.
+	Roles []string `json:"roles"`
+
+	// MatchedRoles are the required roles the subject has, MissingRoles the ones it does not have.
+	MatchedRoles []string `json:"matched_roles,omitempty"`
+	MissingRoles []string `json:"missing_roles,omitempty"`
+
+	// DirectPermissions are the required bits granted to the subject directly.
+	DirectPermissions *Permission `json:"direct_permissions,omitempty"`
+
+	// RolePermissions are the required bits each of the subject's roles contributes.
+	RolePermissions map[string]*Permission `json:"role_permissions,omitempty"`
+
+	// MissingPermissions are the required bits the subject's roles lack, when its direct permissions do not grant
+	// every bit either. The two are not combined, one of them has to grant every required bit.
+	MissingPermissions *Permission `json:"missing_permissions,omitempty"`
+
+	// DirectNamedPermissions are the required named permissions granted to the subject directly.
+	DirectNamedPermissions []string `json:"direct_named_permissions,omitempty"`
+
+	// RoleNamedPermissions are the required named permissions each of the subject's roles grants.
+	RoleNamedPermissions map[string][]string `json:"role_named_permissions,omitempty"`
+
+	// MissingNamedPermissions are the required named permissions neither the subject nor its roles have.
+	MissingNamedPermissions []string `json:"missing_named_permissions,omitempty"`
+
+	// DeniedPermissions and DeniedNamedPermissions are the required permissions explicitly denied to the subject,
+	// see DenyManager.
+	DeniedPermissions      *Permission `json:"denied_permissions,omitempty"`
+	DeniedNamedPermissions []string    `json:"denied_named_permissions,omitempty"`
+}
+
+// ExplainAccess evaluates the requirements like CheckAccess, but returns a Decision trace of the evaluation
+// instead of a bool, e.g., to debug a denial or to simulate a new permission before enforcing it. Every role is
+// inspected, so it makes more fetches than CheckAccess and should not be used for every request.
+func ExplainAccess(
+	ctx context.Context,
+	rbacManager Manager,
+	subjectIdentifier string,
+	rbacCacheId string,
+	requirements AccessRequirements,
+) (*Decision, error) {
+	access, err := loadSubjectAccess(ctx, rbacManager, subjectIdentifier, rbacCacheId)
+	if err != nil {
+		return nil, err
+	}
+	return access.explain(requirements)
+}
+
+// ExplainPermissions is the ExplainAccess counterpart of CheckPermissions.
+func ExplainPermissions(
+	ctx context.Context,
+	rbacManager Manager,
+	subjectIdentifier string,
+	rbacCacheId string,
+	requiredPermissions *Permission,
+	requiredRoles map[string]bool,
+	policy RouteRbacPolicy,
+) (*Decision, error) {
+	return ExplainAccess(ctx, rbacManager, subjectIdentifier, rbacCacheId, AccessRequirements{
+		Permissions: requiredPermissions,
+		Roles:       requiredRoles,
+		Policy:      policy,
+	})
+}
+
+// explain evaluates the requirements against the subject, recording the trace.
+func (a *subjectAccess) explain(requirements AccessRequirements) (*Decision, error) {
+	allowed, err := a.check(requirements)
+	if err != nil {
+		return nil, err
+	}
+
+	decision := &Decision{
+		Allowed: allowed,
+		Policy:  requirements.Policy,
+		Roles:   slices.Clone(a.roles),
+	}
+
+	for role := range requirements.Roles {
+		if slices.Contains(a.roles, role) {
+			decision.MatchedRoles = append(decision.MatchedRoles, role)
+		} else {
+			decision.MissingRoles = append(decision.MissingRoles, role)
+		}
+	}
+	slices.Sort(decision.MatchedRoles)
+	slices.Sort(decision.MissingRoles)
+
+	denied, err := a.loadDenied()
+	if err != nil {
+		return nil, err
+	}
+	decision.DeniedPermissions = denied.DeniedBits(requirements.Permissions)
+	decision.DeniedNamedPermissions = denied.DeniedNames(requirements.NamedPermissions)
+
+	if err = a.explainPermissions(decision, requirements.Permissions); err != nil {
+		return nil, err
+	}
+	if err = a.explainNamedPermissions(decision, requirements.NamedPermissions); err != nil {
+		return nil, err
+	}
+
+	decision.Reason = decisionReason(decision, requirements)
+	return decision, nil
+}
+
+// explainPermissions records which of the required bits the subject and each of its roles grant.
+func (a *subjectAccess) explainPermissions(decision *Decision, required *Permission) error {
+	if required == nil || (*big.Int)(required).Sign() == 0 {
+		return nil
+	}
+
+	if direct := a.permissions.And(required); (*big.Int)(direct).Sign() != 0 {
+		decision.DirectPermissions = direct
+	}
+
+	// - Like the check, the direct permissions and the roles' permissions have to grant every bit on their own
+	fromRoles := &Permission{}
+	for _, role := range a.roles {
+		rolePermissions, err := GetRolePermissions(a.ctx, role, a.rbacManager)
+		if err != nil {
+			return err
+		}
+
+		contributed := rolePermissions.Flatten().And(required)
+		if (*big.Int)(contributed).Sign() == 0 {
+			continue
+		}
+		if decision.RolePermissions == nil {
+			decision.RolePermissions = map[string]*Permission{}
+		}
+		decision.RolePermissions[role] = contributed
+		fromRoles = fromRoles.Or(contributed)
+	}
+
+	if a.permissions.Has(required) {
+		return nil
+	}
+	missing := (*Permission)(new(big.Int).AndNot((*big.Int)(required), (*big.Int)(fromRoles)))
+	if (*big.Int)(missing).Sign() != 0 {
+		decision.MissingPermissions = missing
+	}
+	return nil
+}
+
+// explainNamedPermissions records which of the required named permissions the subject and each of its roles grant.
+func (a *subjectAccess) explainNamedPermissions(decision *Decision, required PermissionSet) error {
+	if len(required) == 0 {
+		return nil
+	}
+
+	direct, err := a.loadNamedPermissions()
+	if err != nil {
+		return err
+	}
+	granted := map[string]bool{}
+	for _, name := range required.Names() {
+		if direct.Grants(name) {
+			decision.DirectNamedPermissions = append(decision.DirectNamedPermissions, name)
+			granted[name] = true
+		}
+	}
+
+	for _, role := range a.roles {
+		rolePermissions, err := GetRoleNamedPermissions(a.ctx, role, a.rbacManager)
+		if err != nil {
+			return err
+		}
+
+		for _, name := range required.Names() {
+			if !rolePermissions.Grants(name) {
+				continue
+			}
+			if decision.RoleNamedPermissions == nil {
+				decision.RoleNamedPermissions = map[string][]string{}
+			}
+			decision.RoleNamedPermissions[role] = append(decision.RoleNamedPermissions[role], name)
+			granted[name] = true
+		}
+	}
+
+	for _, name := range required.Names() {
+		if !granted[name] {
+			decision.MissingNamedPermissions = append(decision.MissingNamedPermissions, name)
+		}
+	}
+	return nil
+}
+
+// decisionReason names the rule that decided the check, mirroring the order of subjectAccess.check.
+func decisionReason(decision *Decision, requirements AccessRequirements) string {
+	if len(requirements.Roles) == 0 && requirements.Permissions == nil && len(requirements.NamedPermissions) == 0 {
+		return DecisionNoRequirements
+	}
+	if decision.DeniedPermissions != nil || len(decision.DeniedNamedPermissions) > 0 {
+		return DecisionDenied
+	}
+
+	hasRole := roleCheck(decision.Roles, requirements.Roles, requirements.Policy)
+	switch requirements.Policy {
+	case RoleOnly:
+		if hasRole {
+			return DecisionRole
+		}
+		return DecisionMissingRole
+
+	case PermissionsOrRole, PermissionsOrAllRoles:
+		if hasRole {
+			return DecisionRole
+		}
+
+	case PermissionsAndRole, PermissionsAndAllRoles:
+		if !hasRole {
+			return DecisionMissingRole
+		}
+	}
+
+	switch {
+	case decision.MissingPermissions != nil:
+		return DecisionMissingPermissions
+	case len(decision.MissingNamedPermissions) > 0:
+		return DecisionMissingNamedPermissions
+	}
+	return DecisionPermissions
+}
@@ -0,0 +1,135 @@
+package rbac
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestExplainAccess(t *testing.T) {
+	ctx := context.Background()
+	manager := &mockRbacManager{}
+	readAndWrite := Permissions{readOnly, readWrite}.Flatten()
+
+	tests := []struct {
+		name         string
+		subject      string
+		requirements AccessRequirements
+		allowed      bool
+		reason       string
+		check        func(t *testing.T, decision *Decision)
+	}{
+		{
+			name:    "No requirements",
+			subject: "readonly-user",
+			allowed: true,
+			reason:  DecisionNoRequirements,
+		},
+		{
+			name:         "Granted by a role",
+			subject:      "admin-user",
+			requirements: AccessRequirements{Roles: map[string]bool{"admin": true}, Policy: PermissionsOrRole},
+			allowed:      true,
+			reason:       DecisionRole,
+			check: func(t *testing.T, decision *Decision) {
+				if !slices.Equal(decision.MatchedRoles, []string{"admin"}) {
+					t.Errorf("Expected the admin role to match, got %v", decision.MatchedRoles)
+				}
+			},
+		},
+		{
+			name:         "Missing role",
+			subject:      "readonly-user",
+			requirements: AccessRequirements{Roles: map[string]bool{"admin": true}, Policy: RoleOnly},
+			reason:       DecisionMissingRole,
+			check: func(t *testing.T, decision *Decision) {
+				if !slices.Equal(decision.MissingRoles, []string{"admin"}) {
+					t.Errorf("Expected the admin role to be missing, got %v", decision.MissingRoles)
+				}
+			},
+		},
+		{
+			name:         "Granted by a role's permissions",
+			subject:      "admin-user",
+			requirements: AccessRequirements{Permissions: readWrite, Policy: PermissionsOnly},
+			allowed:      true,
+			reason:       DecisionPermissions,
+			check: func(t *testing.T, decision *Decision) {
+				if admin := decision.RolePermissions["admin"]; admin == nil || !admin.Has(readWrite) {
+					t.Errorf("Expected the admin role to contribute the write permission, got %v", decision.RolePermissions)
+				}
+				if decision.MissingPermissions != nil {
+					t.Errorf("Expected no missing permissions, got %v", decision.MissingPermissions)
+				}
+			},
+		},
+		{
+			name:         "Direct and role bits are not combined",
+			subject:      "admin-user",
+			requirements: AccessRequirements{Permissions: readAndWrite, Policy: PermissionsOnly},
+			reason:       DecisionMissingPermissions,
+			check: func(t *testing.T, decision *Decision) {
+				if decision.DirectPermissions == nil || !decision.DirectPermissions.Has(readOnly) {
+					t.Errorf("Expected the direct read permission, got %v", decision.DirectPermissions)
+				}
+				if decision.MissingPermissions == nil || !decision.MissingPermissions.Has(readOnly) || decision.MissingPermissions.Has(readWrite) {
+					t.Errorf("Expected the roles to lack the read permission, got %v", decision.MissingPermissions)
+				}
+			},
+		},
+		{
+			name:         "Missing permissions",
+			subject:      "readonly-user",
+			requirements: AccessRequirements{Permissions: readAndWrite, Policy: PermissionsOnly},
+			reason:       DecisionMissingPermissions,
+			check: func(t *testing.T, decision *Decision) {
+				if decision.MissingPermissions == nil || !decision.MissingPermissions.Has(readWrite) || decision.MissingPermissions.Has(readOnly) {
+					t.Errorf("Expected only the write permission to be missing, got %v", decision.MissingPermissions)
+				}
+			},
+		},
+		{
+			name:         "Missing named permissions",
+			subject:      "readonly-user",
+			requirements: AccessRequirements{NamedPermissions: MustPermissionSet("billing.invoices.read", "billing.invoices.write"), Policy: PermissionsOnly},
+			reason:       DecisionMissingNamedPermissions,
+			check: func(t *testing.T, decision *Decision) {
+				if !slices.Equal(decision.RoleNamedPermissions["user"], []string{"billing.invoices.read"}) {
+					t.Errorf("Expected the user role to grant the read permission, got %v", decision.RoleNamedPermissions)
+				}
+				if !slices.Equal(decision.MissingNamedPermissions, []string{"billing.invoices.write"}) {
+					t.Errorf("Expected the write permission to be missing, got %v", decision.MissingNamedPermissions)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision, err := ExplainAccess(ctx, manager, tt.subject, "cache-id", tt.requirements)
+			if err != nil {
+				t.Fatalf("ExplainAccess() error = %v", err)
+			}
+
+			allowed, err := CheckAccess(ctx, manager, tt.subject, "cache-id", tt.requirements)
+			if err != nil {
+				t.Fatalf("CheckAccess() error = %v", err)
+			}
+			if decision.Allowed != allowed || allowed != tt.allowed {
+				t.Errorf("Expected allowed %v, got decision %v and check %v", tt.allowed, decision.Allowed, allowed)
+			}
+			if decision.Reason != tt.reason {
+				t.Errorf("Expected reason %q, got %q", tt.reason, decision.Reason)
+			}
+			if tt.check != nil {
+				tt.check(t, decision)
+			}
+		})
+	}
+
+	t.Run("Propagates fetch errors", func(t *testing.T) {
+		if _, err := ExplainAccess(ctx, manager, "user-with-error", "cache-id", AccessRequirements{Permissions: readOnly}); err == nil {
+			t.Error("Expected an error")
+		}
+	})
+}
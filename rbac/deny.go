@@ -0,0 +1,153 @@
+package rbac
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/grzegorzmaniak/gothic/helpers"
+	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	SubjectDeniedCacheKeyPrefix        = "subject_denied:"    // Key: subject_denied:<subjectIdentifier>
+	SubjectDeniedSingleFlightKeyPrefix = "subject_denied_sf:" // Key: subject_denied_sf:<subjectIdentifier>
+)
+
+var deniedRequestGroup singleflight.Group
+
+// DeniedPermissions are permissions explicitly denied to a subject, e.g., "banned from export". A denial always
+// wins over a grant, whether the grant is direct, through a role, or through a role satisfying the route's
+// RouteRbacPolicy on its own.
+type DeniedPermissions struct {
+	// Permissions are the denied bits.
+	Permissions *Permission `json:"permissions,omitempty"`
+
+	// NamedPermissions are the denied named permissions, a trailing wildcard denies the whole namespace, e.g.,
+	// "reports.export.*".
+	NamedPermissions PermissionSet `json:"named_permissions,omitempty"`
+}
+
+// IsEmpty reports whether nothing is denied.
+func (d *DeniedPermissions) IsEmpty() bool {
+	return d == nil || ((d.Permissions == nil || (*big.Int)(d.Permissions).Sign() == 0) && len(d.NamedPermissions) == 0)
+}
+
+// DeniedBits returns the required bits that are denied, nil if none are.
+func (d *DeniedPermissions) DeniedBits(required *Permission) *Permission {
+	if d == nil || d.Permissions == nil || required == nil {
+		return nil
+	}
+	denied := d.Permissions.And(required)
+	if (*big.Int)(denied).Sign() == 0 {
+		return nil
+	}
+	return denied
+}
+
+// DeniedNames returns the required named permissions that are denied, sorted.
+func (d *DeniedPermissions) DeniedNames(required PermissionSet) []string {
+	if d == nil || len(d.NamedPermissions) == 0 {
+		return nil
+	}
+	var denied []string
+	for _, name := range required.Names() {
+		if d.NamedPermissions.Grants(name) {
+			denied = append(denied, name)
+		}
+	}
+	return denied
+}
+
+// Denies reports whether any of the required permissions is denied.
+func (d *DeniedPermissions) Denies(requirements AccessRequirements) bool {
+	return d.DeniedBits(requirements.Permissions) != nil || len(d.DeniedNames(requirements.NamedPermissions)) > 0
+}
+
+// DenyManager is implemented by managers that deny permissions to subjects. Managers without it deny nothing
+// and cost no extra fetches. The context carries the tenant of scoped checks (see TenantFromContext).
+type DenyManager interface {
+	// GetSubjectDeniedPermissions gets the permissions explicitly denied to a subject, nil denies nothing.
+	GetSubjectDeniedPermissions(ctx context.Context, subjectIdentifier string) (*DeniedPermissions, error)
+}
+
+// FetchSubjectDeniedPermissions returns the permissions denied to a subject, using the cache. Entries live for
+// the manager's subject permissions TTL and are invalidated with the subject's other entries.
+func FetchSubjectDeniedPermissions(
+	ctx context.Context,
+	subjectIdentifier string,
+	rbacCacheId string,
+	rbacManager Manager,
+) (*DeniedPermissions, error) {
+	denyManager, ok := rbacManager.(DenyManager)
+	if !ok {
+		return &DeniedPermissions{}, nil
+	}
+
+	ctx, done, err := beginFetch(ctx, rbacManager)
+	if err != nil {
+		return nil, fmt.Errorf("manager: failed to fetch denied permissions for '%s': %w", subjectIdentifier, err)
+	}
+	defer done()
+
+	source := func() (*DeniedPermissions, error) {
+		denied, fetchErr := helpers.Retry(ctx, helpers.RetryPolicyOf(rbacManager), func(ctx context.Context) (*DeniedPermissions, error) {
+			return denyManager.GetSubjectDeniedPermissions(ctx, subjectIdentifier)
+		})
+		if fetchErr != nil {
+			return nil, fmt.Errorf("manager: failed to fetch denied permissions for '%s': %w", subjectIdentifier, fetchErr)
+		}
+		if denied == nil {
+			denied = &DeniedPermissions{}
+		}
+		return denied, nil
+	}
+
+	cacheInstance, err := rbacManager.GetCache()
+	if err != nil || cacheInstance == nil {
+		zap.L().Warn("Cache instance unavailable, fetching denied permissions directly from source")
+		return source()
+	}
+
+	rbacCacheId = subjectCacheId(ctx, rbacManager, subjectIdentifier, rbacCacheId)
+	cacheKey := tenantCacheKey(ctx, SubjectDeniedCacheKeyPrefix+rbacCacheId)
+	cached, found, err := fetchFromCache(ctx, cacheInstance, cacheKey, func(b []byte) (*DeniedPermissions, error) {
+		var denied DeniedPermissions
+		if err := json.Unmarshal(b, &denied); err != nil {
+			return nil, err
+		}
+		return &denied, nil
+	})
+	if err != nil {
+		zap.L().Warn("Failed to read denied permissions from cache, will fetch from source", zap.Error(err))
+		found = false
+	}
+	if found {
+		return cached, nil
+	}
+
+	result, err, _ := deniedRequestGroup.Do(tenantCacheKey(ctx, SubjectDeniedSingleFlightKeyPrefix+rbacCacheId), func() (interface{}, error) {
+		denied, fetchErr := source()
+		if fetchErr != nil {
+			return nil, fetchErr
+		}
+
+		if cacheErr := setInCache(ctx, cacheInstance, cacheKey, denied, rbacManager.GetSubjectPermissionsCacheTtl(), func(v *DeniedPermissions) ([]byte, error) {
+			return json.Marshal(v)
+		}); cacheErr != nil {
+			zap.L().Warn("Failed to cache denied permissions", zap.String("key", cacheKey), zap.Error(cacheErr))
+		}
+		return denied, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	denied, ok := result.(*DeniedPermissions)
+	if !ok {
+		return nil, fmt.Errorf("unexpected type from singleflight result for denied permissions")
+	}
+	return denied, nil
+}
@@ -0,0 +1,140 @@
+package rbac
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/eko/gocache/lib/v4/cache"
+	"github.com/grzegorzmaniak/gothic/helpers"
+)
+
+// denyRbacManager denies the write bit and the invoice named permissions to "admin-user", whose admin role
+// grants both.
+type denyRbacManager struct {
+	mockRbacManager
+	cache    *mockCache
+	calls    int
+	failures int
+}
+
+func (m *denyRbacManager) GetCache() (cache.CacheInterface[[]byte], error) {
+	return m.cache, nil
+}
+
+func (m *denyRbacManager) GetSubjectDeniedPermissions(_ context.Context, subjectIdentifier string) (*DeniedPermissions, error) {
+	m.calls++
+	if m.failures > 0 {
+		m.failures--
+		return nil, errors.New("connection reset")
+	}
+	if subjectIdentifier != "admin-user" {
+		return nil, nil
+	}
+	return &DeniedPermissions{Permissions: readWrite, NamedPermissions: MustPermissionSet("billing.invoices.*")}, nil
+}
+
+func TestDeniedPermissions(t *testing.T) {
+	ctx := context.Background()
+	manager := &denyRbacManager{cache: &mockCache{}}
+
+	t.Run("Denials override role grants", func(t *testing.T) {
+		for _, tt := range []struct {
+			name         string
+			requirements AccessRequirements
+			allowed      bool
+		}{
+			{"Denied bit", AccessRequirements{Permissions: readWrite, Policy: PermissionsOnly}, false},
+			{"Denied bit with a matching role", AccessRequirements{Permissions: readWrite, Roles: map[string]bool{"admin": true}, Policy: PermissionsOrRole}, false},
+			{"Denied named permission", AccessRequirements{NamedPermissions: MustPermissionSet("billing.invoices.read"), Policy: PermissionsOnly}, false},
+			{"Other named permission", AccessRequirements{NamedPermissions: MustPermissionSet("billing.reports.read"), Policy: PermissionsOnly}, true},
+			{"Other bit", AccessRequirements{Permissions: readOnly, Policy: PermissionsOnly}, true},
+			{"Roles only", AccessRequirements{Roles: map[string]bool{"admin": true}, Policy: RoleOnly}, true},
+		} {
+			t.Run(tt.name, func(t *testing.T) {
+				allowed, err := CheckAccess(ctx, manager, "admin-user", "cache-id", tt.requirements)
+				if err != nil {
+					t.Fatalf("CheckAccess() error = %v", err)
+				}
+				if allowed != tt.allowed {
+					t.Errorf("Expected allowed %v, got %v", tt.allowed, allowed)
+				}
+			})
+		}
+	})
+
+	t.Run("Denials are cached", func(t *testing.T) {
+		cached := &denyRbacManager{cache: &mockCache{}}
+		for range 3 {
+			if _, err := FetchSubjectDeniedPermissions(ctx, "admin-user", "cache-id", cached); err != nil {
+				t.Fatalf("FetchSubjectDeniedPermissions() error = %v", err)
+			}
+		}
+		if cached.calls != 1 {
+			t.Errorf("Expected the denials to be fetched once, got %d", cached.calls)
+		}
+
+		denied, _ := FetchSubjectDeniedPermissions(ctx, "admin-user", "cache-id", cached)
+		if denied.DeniedBits(readWrite) == nil || len(denied.DeniedNames(MustPermissionSet("billing.invoices.read"))) != 1 {
+			t.Errorf("Expected the cached denials to round trip, got %+v", denied)
+		}
+	})
+
+	t.Run("Transient errors are retried", func(t *testing.T) {
+		flaky := &denyRbacManager{cache: &mockCache{}, failures: 1}
+		flaky.RetryPolicy = &helpers.RetryPolicy{Attempts: 3, BaseDelay: time.Millisecond}
+		denied, err := FetchSubjectDeniedPermissions(ctx, "admin-user", "retry-id", flaky)
+		if err != nil || denied.DeniedBits(readWrite) == nil {
+			t.Fatalf("Expected the denials after a retry, got %+v: %v", denied, err)
+		}
+		if flaky.calls != 2 {
+			t.Errorf("Expected 2 source calls, got %d", flaky.calls)
+		}
+	})
+
+	t.Run("Effective permissions exclude denials", func(t *testing.T) {
+		effective, err := FetchEffectivePermissions(ctx, manager, "admin-user", "cache-id")
+		if err != nil {
+			t.Fatalf("FetchEffectivePermissions() error = %v", err)
+		}
+		if effective.Permissions.Has(readWrite) || !effective.Permissions.Has(readOnly) {
+			t.Errorf("Expected only the write bit to be removed, got %v", effective.Permissions)
+		}
+		if effective.Denied.IsEmpty() {
+			t.Error("Expected the denials to be returned")
+		}
+	})
+
+	t.Run("Decisions name the denial", func(t *testing.T) {
+		decision, err := ExplainAccess(ctx, manager, "admin-user", "cache-id", AccessRequirements{Permissions: readWrite, Roles: map[string]bool{"admin": true}, Policy: PermissionsOrRole})
+		if err != nil {
+			t.Fatalf("ExplainAccess() error = %v", err)
+		}
+		if decision.Allowed || decision.Reason != DecisionDenied || decision.DeniedPermissions == nil {
+			t.Errorf("Expected a denial, got %+v", decision)
+		}
+	})
+
+	t.Run("Managers without DenyManager deny nothing", func(t *testing.T) {
+		denied, err := FetchSubjectDeniedPermissions(ctx, "admin-user", "cache-id", &mockRbacManager{})
+		if err != nil || !denied.IsEmpty() {
+			t.Errorf("Expected no denials, got %+v, %v", denied, err)
+		}
+	})
+
+	t.Run("JSON round trip", func(t *testing.T) {
+		encoded, err := json.Marshal(DeniedPermissions{Permissions: readWrite, NamedPermissions: MustPermissionSet("reports.export")})
+		if err != nil {
+			t.Fatalf("json.Marshal() error = %v", err)
+		}
+		var decoded DeniedPermissions
+		if err = json.Unmarshal(encoded, &decoded); err != nil {
+			t.Fatalf("json.Unmarshal() error = %v", err)
+		}
+		if !decoded.Permissions.Has(readWrite) || decoded.NamedPermissions.Names()[0] != "reports.export" {
+			t.Errorf("Expected the denials to round trip, got %s", encoded)
+		}
+	})
+}
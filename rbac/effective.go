@@ -0,0 +1,77 @@
+package rbac
+
+import (
+	"context"
+	"math/big"
+	"slices"
+)
+
+// EffectivePermissions is everything a subject is granted, directly or through its roles.
+type EffectivePermissions struct {
+	// Permissions is the union of the subject's and its roles' permission bitsets.
+	Permissions *Permission
+
+	// NamedPermissions is the union of the subject's and its roles' named permissions, wildcards are kept as granted.
+	NamedPermissions PermissionSet
+
+	// Roles are the subject's roles, sorted.
+	Roles []string
+
+	// Denied are the permissions explicitly denied to the subject. Denied bits and named permissions are removed
+	// from Permissions and NamedPermissions, a granted wildcard is kept even if only part of it is denied.
+	Denied *DeniedPermissions
+}
+
+// FetchEffectivePermissions returns the flattened permissions and roles of a subject, e.g., for a frontend to hide
+// what the subject cannot use. Routes must still check their own requirements.
+func FetchEffectivePermissions(
+	ctx context.Context,
+	rbacManager Manager,
+	subjectIdentifier string,
+	rbacCacheId string,
+) (*EffectivePermissions, error) {
+	access, err := loadSubjectAccess(ctx, rbacManager, subjectIdentifier, rbacCacheId)
+	if err != nil {
+		return nil, err
+	}
+	return access.effective()
+}
+
+// effective fetches the permissions of the subject's roles and its named permissions, then flattens them.
+func (a *subjectAccess) effective() (*EffectivePermissions, error) {
+	rolePermissions, err := a.loadRolePermissions()
+	if err != nil {
+		return nil, err
+	}
+
+	named, err := a.loadNamedPermissionsWithRoles()
+	if err != nil {
+		return nil, err
+	}
+
+	denied, err := a.loadDenied()
+	if err != nil {
+		return nil, err
+	}
+
+	permissions := a.permissions.Or(rolePermissions)
+	if denied.Permissions != nil {
+		permissions = (*Permission)(new(big.Int).AndNot((*big.Int)(permissions), (*big.Int)(denied.Permissions)))
+	}
+
+	namedPermissions := PermissionSet{}
+	for name := range named {
+		if !denied.NamedPermissions.Grants(name) {
+			namedPermissions[name] = struct{}{}
+		}
+	}
+
+	roles := slices.Clone(a.roles)
+	slices.Sort(roles)
+	return &EffectivePermissions{
+		Permissions:      permissions,
+		NamedPermissions: namedPermissions,
+		Roles:            slices.Compact(roles),
+		Denied:           denied,
+	}, nil
+}
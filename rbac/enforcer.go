@@ -82,6 +82,34 @@ func mergeRolePermissions(ctx context.Context, subjectRoles []string, rbacManage
 	return mergedPermissions.Flatten(), nil
 }
 
+// mergeRoleNamedPermissions fetches the named permissions for each role in subjectRoles and merges them into a single set.
+func mergeRoleNamedPermissions(ctx context.Context, subjectRoles []string, rbacManager Manager) (PermissionSet, error) {
+	merged := PermissionSet{}
+	for _, role := range subjectRoles {
+		rolePerms, err := GetRoleNamedPermissions(ctx, role, rbacManager)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get named permissions for role '%s': %w", role, err)
+		}
+		merged = merged.Merge(rolePerms)
+	}
+	return merged, nil
+}
+
+// AccessRequirements describes everything a subject needs to access a route.
+type AccessRequirements struct {
+	// Permissions is the required permission bitset, nil requires no bitset permissions.
+	Permissions *Permission
+
+	// NamedPermissions are the required namespaced permissions, wildcards are expanded on the subject's side only.
+	NamedPermissions PermissionSet
+
+	// Roles is the set of roles used by the policy.
+	Roles map[string]bool
+
+	// Policy controls how roles and permissions are combined.
+	Policy RouteRbacPolicy
+}
+
 // CheckPermissions verifies if a subject meets the required permissions and/or roles
 // as defined by an API configuration.
 func CheckPermissions(
@@ -93,16 +121,82 @@ func CheckPermissions(
 	requiredRoles map[string]bool,
 	policy RouteRbacPolicy,
 ) (bool, error) {
+	return CheckAccess(ctx, rbacManager, subjectIdentifier, rbacCacheId, AccessRequirements{
+		Permissions: requiredPermissions,
+		Roles:       requiredRoles,
+		Policy:      policy,
+	})
+}
 
-	// - Fetch subject's roles and direct permissions
-	subjectPermissions, subjectRoles, err := FetchSubjectRolesAndPermissions(ctx, subjectIdentifier, rbacCacheId, rbacManager)
+// CheckAccess verifies if a subject meets the requirements, both the permission bitset and the named
+// permissions have to be satisfied for the permission part of the policy to pass. Requiring a permission that
+// is denied to the subject (see DenyManager) fails the check whatever the policy.
+func CheckAccess(
+	ctx context.Context,
+	rbacManager Manager,
+	subjectIdentifier string,
+	rbacCacheId string,
+	requirements AccessRequirements,
+) (bool, error) {
+	access, err := loadSubjectAccess(ctx, rbacManager, subjectIdentifier, rbacCacheId)
 	if err != nil {
-		return false, fmt.Errorf("failed to fetch subject roles/permissions for '%s': %w", subjectIdentifier, err)
+		return false, err
 	}
+	return access.check(requirements)
+}
 
-	// - If no permissions or roles are required, access is granted.
-	if len(requiredRoles) == 0 && requiredPermissions == nil {
-		return true, nil
+// CheckGrantedAccess verifies the requirements against explicitly granted roles and named permissions instead of
+// the subject's own grants, e.g., the grants of an API key. The permissions of the roles are still resolved through
+// the manager, and the permissions denied to subjectIdentifier (see DenyManager) still apply, so a grant can never
+// get around a denial. An empty subjectIdentifier has no denials.
+func CheckGrantedAccess(
+	ctx context.Context,
+	rbacManager Manager,
+	subjectIdentifier string,
+	rbacCacheId string,
+	roles []string,
+	namedPermissions PermissionSet,
+	requirements AccessRequirements,
+) (bool, error) {
+	if roles == nil {
+		roles = []string{}
+	}
+	access := &subjectAccess{
+		ctx:               ctx,
+		rbacManager:       rbacManager,
+		subjectIdentifier: subjectIdentifier,
+		rbacCacheId:       rbacCacheId,
+		permissions:       &Permission{},
+		roles:             roles,
+		named:             PermissionSet{}.Merge(namedPermissions),
+	}
+	if subjectIdentifier == "" {
+		access.denied = &DeniedPermissions{}
+	}
+	return access.check(requirements)
+}
+
+// subjectAccess holds the roles and permissions of a subject, the permissions of its roles and its named
+// permissions are only fetched once a check needs them, and then reused by later checks.
+type subjectAccess struct {
+	ctx               context.Context
+	rbacManager       Manager
+	subjectIdentifier string
+	rbacCacheId       string
+
+	permissions     *Permission
+	roles           []string
+	rolePermissions *Permission
+	named           PermissionSet
+	namedWithRoles  PermissionSet
+	denied          *DeniedPermissions
+}
+
+// loadSubjectAccess fetches the subject's roles and direct permissions.
+func loadSubjectAccess(ctx context.Context, rbacManager Manager, subjectIdentifier string, rbacCacheId string) (*subjectAccess, error) {
+	subjectPermissions, subjectRoles, err := FetchSubjectRolesAndPermissions(ctx, subjectIdentifier, rbacCacheId, rbacManager)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch subject roles/permissions for '%s': %w", subjectIdentifier, err)
 	}
 
 	if subjectPermissions == nil {
@@ -113,9 +207,35 @@ func CheckPermissions(
 		subjectRoles = []string{}
 	}
 
+	return &subjectAccess{
+		ctx:               ctx,
+		rbacManager:       rbacManager,
+		subjectIdentifier: subjectIdentifier,
+		rbacCacheId:       rbacCacheId,
+		permissions:       subjectPermissions,
+		roles:             subjectRoles,
+	}, nil
+}
+
+// check evaluates the requirements against the subject.
+func (a *subjectAccess) check(requirements AccessRequirements) (bool, error) {
+	// - If no permissions or roles are required, access is granted.
+	if len(requirements.Roles) == 0 && requirements.Permissions == nil && len(requirements.NamedPermissions) == 0 {
+		return true, nil
+	}
+
+	// - Explicit denials win over every grant, so they are checked before a role can satisfy the policy
+	denied, err := a.loadDenied()
+	if err != nil {
+		return false, err
+	}
+	if denied.Denies(requirements) {
+		return false, nil
+	}
+
 	// - Check roles
-	hasRole := roleCheck(subjectRoles, requiredRoles, policy)
-	switch policy {
+	hasRole := roleCheck(a.roles, requirements.Roles, requirements.Policy)
+	switch requirements.Policy {
 	case RoleOnly:
 		// - If only roles are required, return the result of the role check.
 		return hasRole, nil
@@ -138,20 +258,106 @@ func CheckPermissions(
 		}
 	}
 
-	// - Check direct permissions
-	hasDirect := subjectPermissions.Has(requiredPermissions)
+	hasBitset, err := a.hasBitsetPermissions(requirements.Permissions)
+	if err != nil || !hasBitset {
+		return false, err
+	}
+
+	return a.hasNamedPermissions(requirements.NamedPermissions)
+}
+
+// hasBitsetPermissions checks the required bitset against the subject's direct permissions, then its roles.
+func (a *subjectAccess) hasBitsetPermissions(requiredPermissions *Permission) (bool, error) {
+	if requiredPermissions == nil {
+		return true, nil
+	}
 
 	// - 1. Check for direct permissions first. If they exist, the permission requirement is met.
-	if hasDirect {
+	if a.permissions.Has(requiredPermissions) {
 		return true, nil
 	}
 
 	// - 2. If no direct permissions, merge permissions from all of the subject's roles.
-	merged, err := mergeRolePermissions(ctx, subjectRoles, rbacManager)
+	rolePermissions, err := a.loadRolePermissions()
 	if err != nil {
 		return false, err
 	}
 
 	// - 3. Check if the merged role permissions satisfy the requirement.
-	return merged.Has(requiredPermissions), nil
+	return rolePermissions.Has(requiredPermissions), nil
+}
+
+// hasNamedPermissions checks the required named permissions against the subject's direct named permissions,
+// then the union of those and its roles' named permissions.
+func (a *subjectAccess) hasNamedPermissions(requiredPermissions PermissionSet) (bool, error) {
+	if len(requiredPermissions) == 0 {
+		return true, nil
+	}
+
+	direct, err := a.loadNamedPermissions()
+	if err != nil {
+		return false, err
+	}
+	if direct.HasAll(requiredPermissions) {
+		return true, nil
+	}
+
+	withRoles, err := a.loadNamedPermissionsWithRoles()
+	if err != nil {
+		return false, err
+	}
+	return withRoles.HasAll(requiredPermissions), nil
+}
+
+// loadRolePermissions returns the merged permission bitset of the subject's roles, fetching it on first use.
+func (a *subjectAccess) loadRolePermissions() (*Permission, error) {
+	if a.rolePermissions == nil {
+		merged, err := mergeRolePermissions(a.ctx, a.roles, a.rbacManager)
+		if err != nil {
+			return nil, err
+		}
+		a.rolePermissions = merged
+	}
+	return a.rolePermissions, nil
+}
+
+// loadNamedPermissions returns the subject's direct named permissions, fetching them on first use.
+func (a *subjectAccess) loadNamedPermissions() (PermissionSet, error) {
+	if a.named == nil {
+		direct, err := FetchSubjectNamedPermissions(a.ctx, a.subjectIdentifier, a.rbacCacheId, a.rbacManager)
+		if err != nil {
+			return nil, err
+		}
+		a.named = PermissionSet{}.Merge(direct)
+	}
+	return a.named, nil
+}
+
+// loadNamedPermissionsWithRoles returns the union of the subject's and its roles' named permissions, fetching
+// them on first use.
+func (a *subjectAccess) loadNamedPermissionsWithRoles() (PermissionSet, error) {
+	if a.namedWithRoles == nil {
+		direct, err := a.loadNamedPermissions()
+		if err != nil {
+			return nil, err
+		}
+		fromRoles, err := mergeRoleNamedPermissions(a.ctx, a.roles, a.rbacManager)
+		if err != nil {
+			return nil, err
+		}
+		a.namedWithRoles = direct.Merge(fromRoles)
+	}
+	return a.namedWithRoles, nil
+}
+
+// loadDenied returns the subject's denied permissions, fetching them on first use.
+func (a *subjectAccess) loadDenied() (*DeniedPermissions, error) {
+	if a.denied == nil {
+		denied, err := FetchSubjectDeniedPermissions(a.ctx, a.subjectIdentifier, a.rbacCacheId, a.rbacManager)
+		if err != nil {
+			return nil, err
+		}
+		a.denied = denied
+	}
+	return a.denied, nil
 }
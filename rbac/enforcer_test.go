@@ -199,3 +199,81 @@ func TestCheckPermissions(t *testing.T) {
 		})
 	}
 }
+
+func TestCheckAccess_NamedPermissions(t *testing.T) {
+	ctx := context.Background()
+	mockManager := &mockRbacManager{}
+
+	tests := []struct {
+		name              string
+		subjectIdentifier string
+		requirements      AccessRequirements
+		want              bool
+		wantErr           bool
+	}{
+		{
+			name:              "Direct named permission",
+			subjectIdentifier: "admin-user",
+			requirements:      AccessRequirements{NamedPermissions: MustPermissionSet("billing.invoices.read"), Policy: PermissionsOnly},
+			want:              true,
+		},
+		{
+			name:              "Trailing wildcard from role",
+			subjectIdentifier: "admin-user",
+			requirements:      AccessRequirements{NamedPermissions: MustPermissionSet("billing.invoices.write", "billing.refunds.issue.full"), Policy: PermissionsOnly},
+			want:              true,
+		},
+		{
+			name:              "Inner wildcard from role only matches one segment",
+			subjectIdentifier: "readonly-user",
+			requirements:      AccessRequirements{NamedPermissions: MustPermissionSet("billing.invoices.read"), Policy: PermissionsOnly},
+			want:              true,
+		},
+		{
+			name:              "Inner wildcard does not grant other actions",
+			subjectIdentifier: "readonly-user",
+			requirements:      AccessRequirements{NamedPermissions: MustPermissionSet("billing.invoices.write"), Policy: PermissionsOnly},
+			want:              false,
+		},
+		{
+			name:              "Bitset and named permissions must both be satisfied",
+			subjectIdentifier: "readonly-user",
+			requirements: AccessRequirements{
+				Permissions:      readWrite,
+				NamedPermissions: MustPermissionSet("billing.invoices.read"),
+				Policy:           PermissionsOnly,
+			},
+			want: false,
+		},
+		{
+			name:              "Role short-circuits named permissions",
+			subjectIdentifier: "readonly-user",
+			requirements: AccessRequirements{
+				NamedPermissions: MustPermissionSet("admin.users.delete"),
+				Roles:            map[string]bool{"user": true},
+				Policy:           PermissionsOrRole,
+			},
+			want: true,
+		},
+		{
+			name:              "Error fetching named permissions",
+			subjectIdentifier: "named-error-user",
+			requirements:      AccessRequirements{NamedPermissions: MustPermissionSet("billing.invoices.read"), Policy: PermissionsOnly},
+			want:              false,
+			wantErr:           true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CheckAccess(ctx, mockManager, tt.subjectIdentifier, "", tt.requirements)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CheckAccess() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("CheckAccess() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package rbac
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/eko/gocache/lib/v4/cache"
+	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
+)
+
+var namedRequestGroup singleflight.Group
+
+func unmarshalPermissionSet(b []byte) (PermissionSet, error) {
+	var set PermissionSet
+	if err := json.Unmarshal(b, &set); err != nil {
+		return nil, err
+	}
+	return set, nil
+}
+
+func marshalPermissionSet(set PermissionSet) ([]byte, error) {
+	return json.Marshal(set)
+}
+
+func CacheSubjectNamedPermissions(ctx context.Context, rbacCacheId string, cacheInstance cache.CacheInterface[[]byte], permissions PermissionSet, ttl time.Duration) error {
+	if permissions == nil {
+		return nil
+	}
+	key := tenantCacheKey(ctx, SubjectNamedPermissionsCacheKeyPrefix+rbacCacheId)
+	return setInCache(ctx, cacheInstance, key, permissions, ttl, marshalPermissionSet)
+}
+
+func CacheRoleNamedPermissions(ctx context.Context, roleIdentifier string, cacheInstance cache.CacheInterface[[]byte], permissions PermissionSet, ttl time.Duration) error {
+	if permissions == nil {
+		return nil
+	}
+	key := tenantCacheKey(ctx, RoleNamedPermissionsCacheKeyPrefix+roleIdentifier)
+	return setInCache(ctx, cacheInstance, key, permissions, ttl, marshalPermissionSet)
+}
+
+// fetchNamedPermissions reads a PermissionSet from the cache, falling back to the source (deduplicated via
+// singleflight) and populating the cache on a miss.
+func fetchNamedPermissions(
+	ctx context.Context,
+	rbacManager Manager,
+	cacheKey string,
+	singleFlightKey string,
+	source func() (PermissionSet, error),
+	store func(cache.CacheInterface[[]byte], PermissionSet) error,
+) (PermissionSet, error) {
+	cacheInstance, err := rbacManager.GetCache()
+	if err != nil || cacheInstance == nil {
+		zap.L().Warn("Cache instance unavailable, fetching named permissions directly from source")
+		return source()
+	}
+
+	cached, found, err := fetchFromCache(ctx, cacheInstance, cacheKey, unmarshalPermissionSet)
+	if err != nil {
+		zap.L().Warn("Failed to read named permissions from cache, will fetch from source", zap.Error(err))
+		found = false
+	}
+	if found {
+		return cached, nil
+	}
+
+	result, err, _ := namedRequestGroup.Do(singleFlightKey, func() (interface{}, error) {
+		set, fetchErr := source()
+		if fetchErr != nil {
+			return nil, fetchErr
+		}
+		if set == nil {
+			set = PermissionSet{}
+		}
+
+		if cacheErr := store(cacheInstance, set); cacheErr != nil {
+			zap.L().Warn("Failed to cache named permissions", zap.String("key", cacheKey), zap.Error(cacheErr))
+		}
+		return set, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	set, ok := result.(PermissionSet)
+	if !ok {
+		return nil, fmt.Errorf("unexpected type from singleflight result for named permissions")
+	}
+	return set, nil
+}
+
+// FetchSubjectNamedPermissions returns the named permissions granted directly to a subject, using the cache.
+func FetchSubjectNamedPermissions(
+	ctx context.Context,
+	subjectIdentifier string,
+	rbacCacheId string,
+	rbacManager Manager,
+) (PermissionSet, error) {
+	ctx, done, err := beginFetch(ctx, rbacManager)
+	if err != nil {
+		return nil, fmt.Errorf("manager: failed to fetch named permissions for '%s': %w", subjectIdentifier, err)
+	}
+	defer done()
+
+	rbacCacheId = subjectCacheId(ctx, rbacManager, subjectIdentifier, rbacCacheId)
+	set, err := fetchNamedPermissions(
+		ctx,
+		rbacManager,
+		tenantCacheKey(ctx, SubjectNamedPermissionsCacheKeyPrefix+rbacCacheId),
+		tenantCacheKey(ctx, SubjectNamedSingleFlightKeyPrefix+rbacCacheId),
+		func() (PermissionSet, error) {
+			return sourceSubjectNamedPermissions(ctx, rbacManager, subjectIdentifier)
+		},
+		func(cacheInstance cache.CacheInterface[[]byte], set PermissionSet) error {
+			return CacheSubjectNamedPermissions(ctx, rbacCacheId, cacheInstance, set, rbacManager.GetSubjectPermissionsCacheTtl())
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("manager: failed to fetch named permissions for '%s': %w", subjectIdentifier, err)
+	}
+	return set, nil
+}
+
+// GetRoleNamedPermissions returns the named permissions associated with a role, using the cache.
+func GetRoleNamedPermissions(
+	ctx context.Context,
+	roleIdentifier string,
+	rbacManager Manager,
+) (PermissionSet, error) {
+	ctx, done, err := beginFetch(ctx, rbacManager)
+	if err != nil {
+		return nil, fmt.Errorf("manager: failed to fetch named permissions for role '%s': %w", roleIdentifier, err)
+	}
+	defer done()
+
+	set, err := fetchNamedPermissions(
+		ctx,
+		rbacManager,
+		tenantCacheKey(ctx, RoleNamedPermissionsCacheKeyPrefix+roleIdentifier),
+		tenantCacheKey(ctx, RoleNamedSingleFlightKeyPrefix+roleIdentifier),
+		func() (PermissionSet, error) {
+			return sourceRoleNamedPermissions(ctx, rbacManager, roleIdentifier)
+		},
+		func(cacheInstance cache.CacheInterface[[]byte], set PermissionSet) error {
+			return CacheRoleNamedPermissions(ctx, roleIdentifier, cacheInstance, set, rbacManager.GetRolePermissionsCacheTtl())
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("manager: failed to fetch named permissions for role '%s': %w", roleIdentifier, err)
+	}
+	return set, nil
+}
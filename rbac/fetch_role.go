@@ -24,7 +24,7 @@ func CacheRolePermissions(
 		return nil
 	}
 
-	cacheKey := RolePermissionsCacheKeyPrefix + roleIdentifier
+	cacheKey := tenantCacheKey(ctx, RolePermissionsCacheKeyPrefix+roleIdentifier)
 
 	return setInCache(ctx, cacheInstance, cacheKey, permissions, ttlCache, func(p Permissions) ([]byte, error) {
 		return json.Marshal(p)
@@ -36,13 +36,19 @@ func GetRolePermissions(
 	roleIdentifier string,
 	rbacManager Manager,
 ) (Permissions, error) {
+	ctx, done, err := beginFetch(ctx, rbacManager)
+	if err != nil {
+		return nil, fmt.Errorf("manager: failed to fetch role permissions for '%s': %w", roleIdentifier, err)
+	}
+	defer done()
+
 	cacheInstance, err := rbacManager.GetCache()
 	if err != nil || cacheInstance == nil {
 		zap.L().Warn("Cache instance unavailable, fetching role permissions directly from source")
-		return rbacManager.GetRolePermissions(ctx, roleIdentifier)
+		return sourceRolePermissions(ctx, rbacManager, roleIdentifier)
 	}
 
-	cacheKey := RolePermissionsCacheKeyPrefix + roleIdentifier
+	cacheKey := tenantCacheKey(ctx, RolePermissionsCacheKeyPrefix+roleIdentifier)
 
 	cachedPerms, found, err := fetchFromCache(ctx, cacheInstance, cacheKey, func(b []byte) (Permissions, error) {
 		var p Permissions
@@ -62,9 +68,9 @@ func GetRolePermissions(
 		return cachedPerms, nil
 	}
 
-	singleFlightKey := RoleSingleFlightKeyPrefix + roleIdentifier
+	singleFlightKey := tenantCacheKey(ctx, RoleSingleFlightKeyPrefix+roleIdentifier)
 	result, err, _ := roleRequestGroup.Do(singleFlightKey, func() (interface{}, error) {
-		sourcePerms, fetchErr := rbacManager.GetRolePermissions(ctx, roleIdentifier)
+		sourcePerms, fetchErr := sourceRolePermissions(ctx, rbacManager, roleIdentifier)
 		if fetchErr != nil {
 			return nil, fmt.Errorf("manager: failed to fetch role permissions for '%s': %w", roleIdentifier, fetchErr)
 		}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/eko/gocache/lib/v4/cache"
+	"github.com/grzegorzmaniak/gothic/helpers"
 	"go.uber.org/zap"
 	"golang.org/x/sync/singleflight"
 )
@@ -15,7 +16,7 @@ import (
 var subjectRequestGroup singleflight.Group
 
 func FetchSubjectPermissionsFromCache(ctx context.Context, rbacCacheId string, cacheInstance cache.CacheInterface[[]byte]) (*Permission, bool, error) {
-	key := SubjectPermissionsCacheKeyPrefix + rbacCacheId
+	key := tenantCacheKey(ctx, SubjectPermissionsCacheKeyPrefix+rbacCacheId)
 	return fetchFromCache(ctx, cacheInstance, key, func(b []byte) (*Permission, error) {
 		p := new(Permission)
 		err := p.UnmarshalBinary(b)
@@ -24,7 +25,7 @@ func FetchSubjectPermissionsFromCache(ctx context.Context, rbacCacheId string, c
 }
 
 func FetchSubjectRolesFromCache(ctx context.Context, rbacCacheId string, cacheInstance cache.CacheInterface[[]byte]) ([]string, bool, error) {
-	key := SubjectRolesCacheKeyPrefix + rbacCacheId
+	key := tenantCacheKey(ctx, SubjectRolesCacheKeyPrefix+rbacCacheId)
 	return fetchFromCache(ctx, cacheInstance, key, func(b []byte) ([]string, error) {
 		var roles []string
 		if err := json.Unmarshal(b, &roles); err != nil {
@@ -38,7 +39,7 @@ func CacheRoles(ctx context.Context, rbacCacheId string, cacheInstance cache.Cac
 	if roles == nil {
 		return nil
 	}
-	key := SubjectRolesCacheKeyPrefix + rbacCacheId
+	key := tenantCacheKey(ctx, SubjectRolesCacheKeyPrefix+rbacCacheId)
 	return setInCache(ctx, cacheInstance, key, roles, ttl, func(v []string) ([]byte, error) {
 		return json.Marshal(v)
 	})
@@ -48,7 +49,7 @@ func CachePermissions(ctx context.Context, rbacCacheId string, cacheInstance cac
 	if permissions == nil {
 		return nil
 	}
-	key := SubjectPermissionsCacheKeyPrefix + rbacCacheId
+	key := tenantCacheKey(ctx, SubjectPermissionsCacheKeyPrefix+rbacCacheId)
 	return setInCache(ctx, cacheInstance, key, permissions, ttl, func(v *Permission) ([]byte, error) {
 		return v.MarshalBinary()
 	})
@@ -60,15 +61,22 @@ func FetchSubjectRolesAndPermissions(
 	rbacCacheId string,
 	rbacManager Manager,
 ) (*Permission, []string, error) {
+	ctx, done, err := beginFetch(ctx, rbacManager)
+	if err != nil {
+		return nil, nil, fmt.Errorf("manager: failed to fetch subject data for '%s': %w", subjectIdentifier, err)
+	}
+	defer done()
+
 	cacheInstance, err := rbacManager.GetCache()
 	if err != nil || cacheInstance == nil {
 		zap.L().Warn("Cache instance unavailable, fetching subject roles and permissions directly from source")
-		perms, roles, fetchErr := rbacManager.GetSubjectRolesAndPermissions(ctx, subjectIdentifier)
+		perms, roles, fetchErr := sourceSubjectRolesAndPermissions(ctx, rbacManager, subjectIdentifier)
 		if fetchErr != nil {
 			return nil, nil, fmt.Errorf("manager: failed to fetch subject data for '%s': %w", subjectIdentifier, fetchErr)
 		}
 		return perms.Flatten(), roles, nil
 	}
+	rbacCacheId = subjectCacheId(ctx, rbacManager, subjectIdentifier, rbacCacheId)
 
 	var (
 		perms    *Permission
@@ -101,41 +109,126 @@ func FetchSubjectRolesAndPermissions(
 	}()
 	wg.Wait()
 
+	staleWindow := subjectStaleWindow(rbacManager)
 	if hitPerms && hitRoles {
+		// - Entries past their TTL are served while a single background refresh replaces them
+		if staleWindow > 0 && !subjectIsFresh(ctx, rbacCacheId, cacheInstance) {
+			revalidateSubject(ctx, rbacManager, cacheInstance, subjectIdentifier, rbacCacheId, staleWindow)
+		}
 		return perms, roles, nil
 	}
 
-	type subjectData struct {
-		Permissions Permissions
-		Roles       []string
+	data, err := fetchSubjectFromSource(ctx, rbacManager, cacheInstance, subjectIdentifier, rbacCacheId, staleWindow)
+	if err != nil {
+		return nil, nil, fmt.Errorf("manager: failed to fetch subject data for '%s': %w", subjectIdentifier, err)
 	}
 
-	singleFlightKey := SubjectSingleFlightKeyPrefix + rbacCacheId
+	return data.Permissions.Flatten(), data.Roles, nil
+}
+
+type subjectData struct {
+	Permissions Permissions
+	Roles       []string
+}
+
+// fetchSubjectFromSource fetches the subject's roles and permissions from the source and caches them,
+// concurrent fetches of a subject are deduplicated.
+func fetchSubjectFromSource(
+	ctx context.Context,
+	rbacManager Manager,
+	cacheInstance cache.CacheInterface[[]byte],
+	subjectIdentifier string,
+	rbacCacheId string,
+	staleWindow time.Duration,
+) (subjectData, error) {
+	singleFlightKey := tenantCacheKey(ctx, SubjectSingleFlightKeyPrefix+rbacCacheId)
 	result, err, _ := subjectRequestGroup.Do(singleFlightKey, func() (interface{}, error) {
-		srcPerms, srcRoles, fetchErr := rbacManager.GetSubjectRolesAndPermissions(ctx, subjectIdentifier)
+		srcPerms, srcRoles, fetchErr := sourceSubjectRolesAndPermissions(ctx, rbacManager, subjectIdentifier)
 		if fetchErr != nil {
 			return nil, fetchErr
 		}
 
-		if cacheErr := CachePermissions(ctx, rbacCacheId, cacheInstance, srcPerms.Flatten(), rbacManager.GetSubjectPermissionsCacheTtl()); cacheErr != nil {
+		// - With a stale window the entries outlive their TTL, the fresh marker tracks the TTL itself
+		permissionsTtl := rbacManager.GetSubjectPermissionsCacheTtl()
+		rolesTtl := rbacManager.GetSubjectRolesCacheTtl()
+		if cacheErr := CachePermissions(ctx, rbacCacheId, cacheInstance, srcPerms.Flatten(), permissionsTtl+staleWindow); cacheErr != nil {
 			zap.L().Warn(fmt.Sprintf("Failed to cache subject permissions for '%s'", subjectIdentifier), zap.Error(cacheErr))
 		}
 
-		if cacheErr := CacheRoles(ctx, rbacCacheId, cacheInstance, srcRoles, rbacManager.GetSubjectRolesCacheTtl()); cacheErr != nil {
+		if cacheErr := CacheRoles(ctx, rbacCacheId, cacheInstance, srcRoles, rolesTtl+staleWindow); cacheErr != nil {
 			zap.L().Warn(fmt.Sprintf("Failed to cache subject roles for '%s'", subjectIdentifier), zap.Error(cacheErr))
 		}
 
+		if staleWindow > 0 {
+			freshKey := tenantCacheKey(ctx, SubjectFreshCacheKeyPrefix+rbacCacheId)
+			if cacheErr := setInCache(ctx, cacheInstance, freshKey, []byte{1}, min(permissionsTtl, rolesTtl), func(b []byte) ([]byte, error) {
+				return b, nil
+			}); cacheErr != nil {
+				zap.L().Warn(fmt.Sprintf("Failed to mark subject data fresh for '%s'", subjectIdentifier), zap.Error(cacheErr))
+			}
+		}
+
 		return subjectData{Permissions: srcPerms, Roles: srcRoles}, nil
 	})
 
 	if err != nil {
-		return nil, nil, fmt.Errorf("manager: failed to fetch subject data for '%s': %w", subjectIdentifier, err)
+		return subjectData{}, err
 	}
 
 	data, ok := result.(subjectData)
 	if !ok {
-		return nil, nil, fmt.Errorf("unexpected type from singleflight result")
+		return subjectData{}, fmt.Errorf("unexpected type from singleflight result")
 	}
+	return data, nil
+}
 
-	return data.Permissions.Flatten(), data.Roles, nil
+// revalidatingSubjects holds the single flight keys of the subjects being refreshed in the background.
+var revalidatingSubjects sync.Map
+
+func subjectStaleWindow(rbacManager Manager) time.Duration {
+	if provider, ok := rbacManager.(StaleWhileRevalidateManager); ok {
+		return max(provider.GetSubjectStaleWindow(), 0)
+	}
+	return 0
+}
+
+// subjectIsFresh reports whether the subject's entries are still within their TTL.
+func subjectIsFresh(ctx context.Context, rbacCacheId string, cacheInstance cache.CacheInterface[[]byte]) bool {
+	_, err := cacheInstance.Get(ctx, tenantCacheKey(ctx, SubjectFreshCacheKeyPrefix+rbacCacheId))
+	return err == nil
+}
+
+// revalidateSubject refreshes the subject's entries in the background through the manager's Lifecycle, at most
+// once at a time per subject. The refresh keeps the tenant of ctx but not its cancellation, the request that
+// noticed the stale entries has already been answered.
+func revalidateSubject(
+	ctx context.Context,
+	rbacManager Manager,
+	cacheInstance cache.CacheInterface[[]byte],
+	subjectIdentifier string,
+	rbacCacheId string,
+	staleWindow time.Duration,
+) {
+	singleFlightKey := tenantCacheKey(ctx, SubjectSingleFlightKeyPrefix+rbacCacheId)
+	if _, running := revalidatingSubjects.LoadOrStore(singleFlightKey, struct{}{}); running {
+		return
+	}
+
+	tenant := TenantFromContext(ctx)
+	started := helpers.LifecycleOf(rbacManager).Go(func(background context.Context) {
+		defer revalidatingSubjects.Delete(singleFlightKey)
+
+		refreshCtx, done, err := beginFetch(WithTenant(background, tenant), rbacManager)
+		if err != nil {
+			return
+		}
+		defer done()
+
+		if _, err = fetchSubjectFromSource(refreshCtx, rbacManager, cacheInstance, subjectIdentifier, rbacCacheId, staleWindow); err != nil {
+			zap.L().Warn("Failed to revalidate subject data, serving the stale entries until they expire", zap.String("subject", subjectIdentifier), zap.Error(err))
+		}
+	})
+	if !started {
+		revalidatingSubjects.Delete(singleFlightKey)
+	}
 }
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	internalcache "github.com/grzegorzmaniak/gothic/cache"
+	"github.com/grzegorzmaniak/gothic/helpers"
 )
 
 func TestFetchSubjectRolesAndPermissions(t *testing.T) {
@@ -434,3 +435,42 @@ func TestFetchSubjectRolesAndPermissions(t *testing.T) {
 		}
 	})
 }
+
+func TestFetchSubjectRetriesTransientErrors(t *testing.T) {
+	cacheInstance := &mockCache{setFailures: 2}
+	mockMgr := &mockRbacCacheManager{
+		DefaultRBACManager: DefaultRBACManager{
+			DefaultRBACManagerConfig: DefaultRBACManagerConfig{
+				RetryPolicy: &helpers.RetryPolicy{Attempts: 3, BaseDelay: time.Millisecond},
+			},
+		},
+		cacheInstance: cacheInstance,
+	}
+	failures := 1
+	mockMgr.getSubjectRolesAndPermissionsFunc = func(ctx context.Context, subjectIdentifier string) (Permissions, []string, error) {
+		if failures > 0 {
+			failures--
+			return nil, nil, errors.New("connection reset")
+		}
+		return Permissions{readWrite}, []string{"admin"}, nil
+	}
+
+	_, roles, err := FetchSubjectRolesAndPermissions(context.Background(), "user123", "retry-id", mockMgr)
+	if err != nil || len(roles) != 1 {
+		t.Fatalf("Expected the fetch to succeed after a retry, got %v: %v", roles, err)
+	}
+	if mockMgr.subjectCallCount != 2 {
+		t.Errorf("Expected 2 source calls, got %d", mockMgr.subjectCallCount)
+	}
+	if len(cacheInstance.data) != 2 {
+		t.Errorf("Expected the failed cache writes to be retried, got %v", cacheInstance.data)
+	}
+
+	t.Run("Without a policy nothing is retried", func(t *testing.T) {
+		mockMgr.RetryPolicy = nil
+		failures = 1
+		if _, _, err = FetchSubjectRolesAndPermissions(context.Background(), "user456", "retry-id-2", mockMgr); err == nil {
+			t.Error("Expected the transient error to be returned")
+		}
+	})
+}
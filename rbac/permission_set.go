@@ -0,0 +1,204 @@
+package rbac
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	// PermissionNamespaceDelimiter separates the segments of a named permission, e.g., "billing.invoices.read"
+	PermissionNamespaceDelimiter = "."
+
+	// PermissionWildcard matches exactly one segment, or every remaining segment when it is the last one.
+	// "billing.*" grants "billing.invoices" and "billing.invoices.read", "billing.*.read" grants "billing.invoices.read"
+	// but not "billing.invoices.write".
+	PermissionWildcard = "*"
+
+	// PermissionSetSerializeDelimiter is used by Serialize, it can never appear in a valid permission name.
+	PermissionSetSerializeDelimiter = ","
+)
+
+// PermissionSet is a set of namespaced string permissions that can contain wildcards. It lives alongside the
+// Permission bitset for applications that prefer readable, hierarchical permissions ("billing.invoices.*").
+type PermissionSet map[string]struct{}
+
+// ValidatePermissionName checks that a named permission is well-formed: non-empty segments separated by
+// PermissionNamespaceDelimiter, without whitespace or the serialization delimiter.
+func ValidatePermissionName(name string) error {
+	if name == "" {
+		return fmt.Errorf("permission name is empty")
+	}
+	if strings.ContainsAny(name, " \t\r\n"+PermissionSetSerializeDelimiter) {
+		return fmt.Errorf("permission name '%s' contains invalid characters", name)
+	}
+	for _, segment := range splitPermission(name) {
+		if segment == "" {
+			return fmt.Errorf("permission name '%s' contains an empty segment", name)
+		}
+		if strings.Contains(segment, PermissionWildcard) && segment != PermissionWildcard {
+			return fmt.Errorf("permission name '%s' contains a partial wildcard segment", name)
+		}
+	}
+	return nil
+}
+
+// NewPermissionSet creates a PermissionSet from the given names, invalid names are rejected.
+func NewPermissionSet(names ...string) (PermissionSet, error) {
+	set := make(PermissionSet, len(names))
+	for _, name := range names {
+		if err := set.Add(name); err != nil {
+			return nil, err
+		}
+	}
+	return set, nil
+}
+
+// MustPermissionSet is like NewPermissionSet but panics on invalid names, useful for package level route configs.
+func MustPermissionSet(names ...string) PermissionSet {
+	set, err := NewPermissionSet(names...)
+	if err != nil {
+		panic(err)
+	}
+	return set
+}
+
+// Add validates and adds a named permission to the set.
+func (s PermissionSet) Add(name string) error {
+	if err := ValidatePermissionName(name); err != nil {
+		return err
+	}
+	s[name] = struct{}{}
+	return nil
+}
+
+// Remove removes a named permission (exact match, wildcards are not expanded) from the set.
+func (s PermissionSet) Remove(name string) {
+	delete(s, name)
+}
+
+// Names returns the sorted permission names in the set.
+func (s PermissionSet) Names() []string {
+	names := make([]string, 0, len(s))
+	for name := range s {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Merge returns a new set containing the permissions of both sets.
+func (s PermissionSet) Merge(other PermissionSet) PermissionSet {
+	merged := make(PermissionSet, len(s)+len(other))
+	for name := range s {
+		merged[name] = struct{}{}
+	}
+	for name := range other {
+		merged[name] = struct{}{}
+	}
+	return merged
+}
+
+func splitPermission(name string) []string {
+	return strings.Split(name, PermissionNamespaceDelimiter)
+}
+
+// matchPermission reports whether the granted pattern covers the required permission. Wildcards are only
+// expanded on the granted side, a wildcard in the required permission is treated as a literal segment, so a
+// route requiring "billing.*" is only satisfied by a grant of "billing.*" (or broader).
+func matchPermission(granted string, required string) bool {
+	if granted == required {
+		return true
+	}
+
+	grantedSegments := splitPermission(granted)
+	requiredSegments := splitPermission(required)
+
+	for i, segment := range grantedSegments {
+		isLast := i == len(grantedSegments)-1
+		if i >= len(requiredSegments) {
+			return false
+		}
+
+		if segment == PermissionWildcard {
+			if isLast {
+				// - A trailing wildcard covers every remaining segment.
+				return true
+			}
+			continue
+		}
+
+		if segment != requiredSegments[i] {
+			return false
+		}
+	}
+
+	return len(grantedSegments) == len(requiredSegments)
+}
+
+// Grants reports whether any permission in the set covers the required permission.
+func (s PermissionSet) Grants(required string) bool {
+	if _, ok := s[required]; ok {
+		return true
+	}
+	for granted := range s {
+		if matchPermission(granted, required) {
+			return true
+		}
+	}
+	return false
+}
+
+// HasAll reports whether the set covers every required permission. An empty requirement is always satisfied.
+func (s PermissionSet) HasAll(required PermissionSet) bool {
+	for name := range required {
+		if !s.Grants(name) {
+			return false
+		}
+	}
+	return true
+}
+
+// Missing returns the sorted required permissions that the set does not cover.
+func (s PermissionSet) Missing(required PermissionSet) []string {
+	var missing []string
+	for _, name := range required.Names() {
+		if !s.Grants(name) {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
+
+// MarshalJSON encodes the set as a sorted array of names.
+func (s PermissionSet) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.Names())
+}
+
+// UnmarshalJSON decodes a set from an array of names, validating each of them.
+func (s *PermissionSet) UnmarshalJSON(data []byte) error {
+	var names []string
+	if err := json.Unmarshal(data, &names); err != nil {
+		return err
+	}
+	set, err := NewPermissionSet(names...)
+	if err != nil {
+		return err
+	}
+	*s = set
+	return nil
+}
+
+// Serialize returns the set as a comma separated string for use in text-based formats like claims.
+func (s PermissionSet) Serialize() string {
+	return strings.Join(s.Names(), PermissionSetSerializeDelimiter)
+}
+
+// DeserializePermissionSet decodes a string produced by Serialize.
+func DeserializePermissionSet(encoded string) (PermissionSet, error) {
+	if encoded == "" {
+		return PermissionSet{}, nil
+	}
+	return NewPermissionSet(strings.Split(encoded, PermissionSetSerializeDelimiter)...)
+}
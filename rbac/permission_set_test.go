@@ -0,0 +1,107 @@
+package rbac
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestValidatePermissionName(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr bool
+	}{
+		{name: "Simple", input: "read", wantErr: false},
+		{name: "Namespaced", input: "billing.invoices.read", wantErr: false},
+		{name: "Trailing wildcard", input: "billing.*", wantErr: false},
+		{name: "Inner wildcard", input: "billing.*.read", wantErr: false},
+		{name: "Empty", input: "", wantErr: true},
+		{name: "Empty segment", input: "billing..read", wantErr: true},
+		{name: "Partial wildcard", input: "billing.inv*", wantErr: true},
+		{name: "Whitespace", input: "billing read", wantErr: true},
+		{name: "Serialize delimiter", input: "billing,read", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidatePermissionName(tt.input); (err != nil) != tt.wantErr {
+				t.Errorf("ValidatePermissionName(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPermissionSet_Grants(t *testing.T) {
+	tests := []struct {
+		name     string
+		granted  []string
+		required string
+		want     bool
+	}{
+		{name: "Exact match", granted: []string{"billing.invoices.read"}, required: "billing.invoices.read", want: true},
+		{name: "Trailing wildcard matches one segment", granted: []string{"billing.*"}, required: "billing.invoices", want: true},
+		{name: "Trailing wildcard matches many segments", granted: []string{"billing.*"}, required: "billing.invoices.read", want: true},
+		{name: "Trailing wildcard requires a segment", granted: []string{"billing.*"}, required: "billing", want: false},
+		{name: "Inner wildcard", granted: []string{"billing.*.read"}, required: "billing.invoices.read", want: true},
+		{name: "Inner wildcard wrong action", granted: []string{"billing.*.read"}, required: "billing.invoices.write", want: false},
+		{name: "Inner wildcard deeper", granted: []string{"billing.*.read"}, required: "billing.invoices.lines.read", want: false},
+		{name: "Root wildcard", granted: []string{"*"}, required: "anything.at.all", want: true},
+		{name: "Different namespace", granted: []string{"billing.*"}, required: "users.read", want: false},
+		{name: "Wildcard is literal on required side", granted: []string{"billing.invoices"}, required: "billing.*", want: false},
+		{name: "Empty set", granted: nil, required: "read", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			set := MustPermissionSet(tt.granted...)
+			if got := set.Grants(tt.required); got != tt.want {
+				t.Errorf("Grants(%q) = %v, want %v", tt.required, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPermissionSet_HasAllAndMissing(t *testing.T) {
+	set := MustPermissionSet("billing.*", "users.read")
+	required := MustPermissionSet("billing.invoices.read", "users.read", "users.write")
+
+	if set.HasAll(required) {
+		t.Error("Expected HasAll to be false")
+	}
+	if missing := set.Missing(required); !reflect.DeepEqual(missing, []string{"users.write"}) {
+		t.Errorf("Expected [users.write] to be missing, got %v", missing)
+	}
+	if !set.HasAll(PermissionSet{}) {
+		t.Error("Expected an empty requirement to be satisfied")
+	}
+}
+
+func TestPermissionSet_Serialization(t *testing.T) {
+	set := MustPermissionSet("users.read", "billing.*")
+
+	encoded, err := json.Marshal(set)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if string(encoded) != `["billing.*","users.read"]` {
+		t.Errorf("Expected sorted JSON array, got %s", encoded)
+	}
+
+	var decoded PermissionSet
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !reflect.DeepEqual(decoded, set) {
+		t.Errorf("Expected %v, got %v", set, decoded)
+	}
+
+	if err := json.Unmarshal([]byte(`["bad..name"]`), &decoded); err == nil {
+		t.Error("Expected an error for an invalid name")
+	}
+
+	roundTrip, err := DeserializePermissionSet(set.Serialize())
+	if err != nil || !reflect.DeepEqual(roundTrip, set) {
+		t.Errorf("Expected %v, got %v (err: %v)", set, roundTrip, err)
+	}
+}
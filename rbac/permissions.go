@@ -64,6 +64,24 @@ func (p *Permission) UnmarshalBinary(data []byte) error {
 	return nil
 }
 
+// MarshalText implements the encoding.TextMarshaler interface, so permissions are JSON encoded (e.g., the cached
+// role permissions) as their Serialize string instead of an empty object.
+func (p *Permission) MarshalText() ([]byte, error) {
+	if p == nil {
+		return nil, errors.New("cannot marshal nil Permission")
+	}
+	return []byte(p.Serialize()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (p *Permission) UnmarshalText(text []byte) error {
+	decoded, err := DeserializePermission(string(text))
+	if err != nil {
+		return err
+	}
+	return p.UnmarshalBinary((*big.Int)(decoded).Bytes())
+}
+
 // Serialize returns the permission as a base64 encoded string for use in text-based formats like JSON.
 func (p *Permission) Serialize() string {
 	bytes, _ := p.MarshalBinary()
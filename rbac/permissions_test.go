@@ -1,6 +1,7 @@
 package rbac
 
 import (
+	"encoding/json"
 	"math/big"
 	"testing"
 )
@@ -308,6 +309,24 @@ func TestPermissionMarshalUnmarshal(t *testing.T) {
 }
 
 func TestPermissionSerializeDeserialize(t *testing.T) {
+	t.Run("JSON round trip keeps the bits", func(t *testing.T) {
+		perm := NewPermission(3)
+		perm.Set(70)
+
+		encoded, err := json.Marshal(Permissions{perm, NewPermission(0)})
+		if err != nil {
+			t.Fatalf("Marshal failed: %v", err)
+		}
+
+		var restored Permissions
+		if err = json.Unmarshal(encoded, &restored); err != nil {
+			t.Fatalf("Unmarshal failed: %v", err)
+		}
+		if len(restored) != 2 || !restored[0].Has(perm) || !restored[1].Has(NewPermission(0)) {
+			t.Errorf("Expected the bits to survive JSON, got %s", encoded)
+		}
+	})
+
 	t.Run("Serialize and deserialize simple permission", func(t *testing.T) {
 		perm := NewPermission(3)
 		perm.Set(7)
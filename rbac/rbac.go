@@ -2,6 +2,7 @@ package rbac
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/eko/gocache/lib/v4/cache"
@@ -19,7 +20,13 @@ const (
 	SubjectRolesCacheKeyPrefix       = "subject_roles:" // Key: subject_roles:<subjectIdentifier>
 	SubjectPermissionsCacheKeyPrefix = "subject_perms:" // Key: subject_perms:<subjectIdentifier>
 	SubjectSingleFlightKeyPrefix     = "subject_sf:"    // Key: subject_sf:<subjectIdentifier>
+	SubjectFreshCacheKeyPrefix       = "subject_fresh:" // Key: subject_fresh:<subjectIdentifier>
 	RoleSingleFlightKeyPrefix        = "role_sf:"       // Key: role_sf:<roleIdentifier>
+
+	RoleNamedPermissionsCacheKeyPrefix    = "role_named_perms:"    // Key: role_named_perms:<roleIdentifier>
+	SubjectNamedPermissionsCacheKeyPrefix = "subject_named_perms:" // Key: subject_named_perms:<subjectIdentifier>
+	SubjectNamedSingleFlightKeyPrefix     = "subject_named_sf:"    // Key: subject_named_sf:<subjectIdentifier>
+	RoleNamedSingleFlightKeyPrefix        = "role_named_sf:"       // Key: role_named_sf:<roleIdentifier>
 )
 
 type RouteRbacPolicy uint16
@@ -53,6 +60,14 @@ type Manager interface {
 	// GetRolePermissions gets all the permissions associated with a specific role.
 	GetRolePermissions(ctx context.Context, roleIdentifier string) (Permissions, error)
 
+	// GetSubjectNamedPermissions gets the named (string, wildcard-capable) permissions granted directly to a subject.
+	// This is fully optional, DefaultRBACManager returns an empty set.
+	GetSubjectNamedPermissions(ctx context.Context, subjectIdentifier string) (PermissionSet, error)
+
+	// GetRoleNamedPermissions gets the named (string, wildcard-capable) permissions associated with a specific role.
+	// This is fully optional, DefaultRBACManager returns an empty set.
+	GetRoleNamedPermissions(ctx context.Context, roleIdentifier string) (PermissionSet, error)
+
 	// GetCache returns a configured gocache CacheInterface instance.
 	// This cache is used internally by the Manager for optimizing RBAC data retrieval (e.g., caching role-permission mappings or subject roles)
 	GetCache() (cache.CacheInterface[[]byte], error)
@@ -78,6 +93,21 @@ type DefaultRBACManagerConfig struct {
 
 	// RolePermissionsCacheTTL is the Time-To-Live for role-specific permission entries in the cache.
 	RolePermissionsCacheTTL time.Duration
+
+	// SubjectStaleWindow is how long past their TTL subject entries are still served while they are refreshed in
+	// the background, see StaleWhileRevalidateManager (Default: 0, expired entries are fetched before responding)
+	SubjectStaleWindow time.Duration
+
+	// RetryPolicy retries the source fetches and cache writes failing with transient errors, e.g.,
+	// &helpers.RetryPolicy{Attempts: 3} (Default: nil, no retries)
+	RetryPolicy *helpers.RetryPolicy
+}
+
+// StaleWhileRevalidateManager is implemented by managers that serve expired subject roles and permissions for a
+// while, refreshing them in the background instead of blocking the request on the source.
+type StaleWhileRevalidateManager interface {
+	// GetSubjectStaleWindow returns how long past their TTL subject entries may be served, zero disables it.
+	GetSubjectStaleWindow() time.Duration
 }
 
 // DefaultRBACManager is an implementation of the Manager interface that provides
@@ -85,6 +115,30 @@ type DefaultRBACManagerConfig struct {
 type DefaultRBACManager struct {
 	internalcache.DefaultCacheManager
 	DefaultRBACManagerConfig
+
+	// Lifecycle tracks the fetches made through this manager, Shutdown drains them and rejects new ones.
+	helpers.Lifecycle
+}
+
+// Shutdown drains the manager's fetches and background refreshes (see helpers.Lifecycle.Shutdown), then closes its
+// cache. Calling it more than once is safe.
+func (m *DefaultRBACManager) Shutdown(ctx context.Context) error {
+	return errors.Join(m.Lifecycle.Shutdown(ctx), m.DefaultCacheManager.Shutdown(ctx))
+}
+
+// Close shuts down the manager without a deadline.
+func (m *DefaultRBACManager) Close() error {
+	return m.Shutdown(context.Background())
+}
+
+// beginFetch registers a fetch with the manager's Lifecycle (if it embeds one), the returned context is used for
+// the fetch and done must be called once it is finished. It carries the manager's RetryPolicy for the cache writes.
+func beginFetch(ctx context.Context, rbacManager Manager) (context.Context, func(), error) {
+	ctx, done, err := helpers.LifecycleOf(rbacManager).Begin(ctx)
+	if err != nil {
+		return ctx, done, err
+	}
+	return helpers.WithRetryPolicy(ctx, helpers.RetryPolicyOf(rbacManager)), done, nil
 }
 
 func (m *DefaultRBACManager) GetSubjectPermissionsCacheTtl() time.Duration {
@@ -98,3 +152,21 @@ func (m *DefaultRBACManager) GetSubjectRolesCacheTtl() time.Duration {
 func (m *DefaultRBACManager) GetRolePermissionsCacheTtl() time.Duration {
 	return helpers.DefaultTimeDuration(m.RolePermissionsCacheTTL, DefaultRolePermissionsCacheTTL)
 }
+
+func (m *DefaultRBACManager) GetSubjectStaleWindow() time.Duration {
+	return m.SubjectStaleWindow
+}
+
+func (m *DefaultRBACManager) GetRetryPolicy() *helpers.RetryPolicy {
+	return m.RetryPolicy
+}
+
+// GetSubjectNamedPermissions is a no-op, override it to use named permissions.
+func (m *DefaultRBACManager) GetSubjectNamedPermissions(ctx context.Context, subjectIdentifier string) (PermissionSet, error) {
+	return PermissionSet{}, nil
+}
+
+// GetRoleNamedPermissions is a no-op, override it to use named permissions.
+func (m *DefaultRBACManager) GetRoleNamedPermissions(ctx context.Context, roleIdentifier string) (PermissionSet, error) {
+	return PermissionSet{}, nil
+}
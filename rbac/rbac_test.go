@@ -49,9 +49,32 @@ func (rm *mockRbacManager) GetRolePermissions(ctx context.Context, roleIdentifie
 	return Permissions{}, nil
 }
 
+func (rm *mockRbacManager) GetSubjectNamedPermissions(ctx context.Context, subjectIdentifier string) (PermissionSet, error) {
+	switch subjectIdentifier {
+	case "admin-user":
+		return MustPermissionSet("billing.invoices.read"), nil
+	case "named-error-user":
+		return nil, fmt.Errorf("database connection failed")
+	}
+	return PermissionSet{}, nil
+}
+
+func (rm *mockRbacManager) GetRoleNamedPermissions(ctx context.Context, roleIdentifier string) (PermissionSet, error) {
+	switch roleIdentifier {
+	case "admin":
+		return MustPermissionSet("billing.*"), nil
+	case "user":
+		return MustPermissionSet("billing.*.read"), nil
+	}
+	return PermissionSet{}, nil
+}
+
 type mockCache struct {
 	data map[string][]byte
 	err  error
+
+	// setFailures is how many of the next writes fail
+	setFailures int
 }
 
 func (m *mockCache) Get(_ context.Context, key any) ([]byte, error) {
@@ -68,6 +91,10 @@ func (m *mockCache) Set(_ context.Context, key any, value []byte, _ ...store.Opt
 	if m.err != nil {
 		return m.err
 	}
+	if m.setFailures > 0 {
+		m.setFailures--
+		return errors.New("connection reset")
+	}
 	if m.data == nil {
 		m.data = make(map[string][]byte)
 	}
@@ -75,7 +102,8 @@ func (m *mockCache) Set(_ context.Context, key any, value []byte, _ ...store.Opt
 	return nil
 }
 
-func (m *mockCache) Delete(_ context.Context, _ any) error {
+func (m *mockCache) Delete(_ context.Context, key any) error {
+	delete(m.data, key.(string))
 	return nil
 }
 
@@ -0,0 +1,134 @@
+package rbac
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+)
+
+// PermissionRegistry maps named permissions to bits of the Permission bitset, it allows routes and handlers to
+// work with readable names while the enforcer keeps using the compact bitset. Wildcards can be resolved against
+// the registry, "billing.*" resolves to every registered permission in the billing namespace.
+type PermissionRegistry struct {
+	mu     sync.RWMutex
+	byName map[string]int
+	byBit  map[int]string
+}
+
+// NewPermissionRegistry creates an empty PermissionRegistry.
+func NewPermissionRegistry() *PermissionRegistry {
+	return &PermissionRegistry{
+		byName: make(map[string]int),
+		byBit:  make(map[int]string),
+	}
+}
+
+// Register binds a permission name to a bit and returns the bitset Permission for it.
+// Names must be concrete (no wildcards), and both the name and bit must be unique within the registry.
+func (r *PermissionRegistry) Register(name string, bit int) (*Permission, error) {
+	if err := ValidatePermissionName(name); err != nil {
+		return nil, err
+	}
+	for _, segment := range splitPermission(name) {
+		if segment == PermissionWildcard {
+			return nil, fmt.Errorf("cannot register wildcard permission '%s'", name)
+		}
+	}
+	if bit < 0 {
+		return nil, fmt.Errorf("permission bit must be positive, got %d", bit)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if existing, ok := r.byName[name]; ok && existing != bit {
+		return nil, fmt.Errorf("permission '%s' is already registered to bit %d", name, existing)
+	}
+	if existing, ok := r.byBit[bit]; ok && existing != name {
+		return nil, fmt.Errorf("bit %d is already registered to permission '%s'", bit, existing)
+	}
+
+	r.byName[name] = bit
+	r.byBit[bit] = name
+	return NewPermission(bit), nil
+}
+
+// MustRegister is like Register but panics on error, useful when declaring permissions as package level vars.
+func (r *PermissionRegistry) MustRegister(name string, bit int) *Permission {
+	p, err := r.Register(name, bit)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// Resolve converts named permissions (wildcards included) into a bitset. Unknown concrete names are an error,
+// as silently dropping a required permission would weaken the route; a wildcard matching nothing resolves to
+// an empty bitset.
+func (r *PermissionRegistry) Resolve(names ...string) (*Permission, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := new(big.Int)
+	for _, name := range names {
+		if err := ValidatePermissionName(name); err != nil {
+			return nil, err
+		}
+
+		if bit, ok := r.byName[name]; ok {
+			result.SetBit(result, bit, 1)
+			continue
+		}
+
+		isPattern := false
+		for _, segment := range splitPermission(name) {
+			if segment == PermissionWildcard {
+				isPattern = true
+				break
+			}
+		}
+		if !isPattern {
+			return nil, fmt.Errorf("permission '%s' is not registered", name)
+		}
+
+		for registered, bit := range r.byName {
+			if matchPermission(name, registered) {
+				result.SetBit(result, bit, 1)
+			}
+		}
+	}
+
+	return (*Permission)(result), nil
+}
+
+// Names returns the sorted names of every registered permission set in the bitset.
+// Bits without a registered name are ignored.
+func (r *PermissionRegistry) Names(p *Permission) []string {
+	if p == nil {
+		return []string{}
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	bits := (*big.Int)(p)
+	names := make([]string, 0)
+	for bit, name := range r.byBit {
+		if bits.Bit(bit) == 1 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NamedSet converts a bitset into a PermissionSet of the registered names.
+func (r *PermissionRegistry) NamedSet(p *Permission) PermissionSet {
+	names := r.Names(p)
+	set := make(PermissionSet, len(names))
+	for _, name := range names {
+		set[name] = struct{}{}
+	}
+	return set
+}
@@ -0,0 +1,55 @@
+package rbac
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPermissionRegistry(t *testing.T) {
+	registry := NewPermissionRegistry()
+	read := registry.MustRegister("billing.invoices.read", 1)
+	registry.MustRegister("billing.invoices.write", 2)
+	registry.MustRegister("users.read", 3)
+
+	t.Run("Register returns the bit permission", func(t *testing.T) {
+		if !read.Has(NewPermission(1)) {
+			t.Error("Expected the registered permission to have bit 1")
+		}
+	})
+
+	t.Run("Duplicate names and bits are rejected", func(t *testing.T) {
+		if _, err := registry.Register("billing.invoices.read", 4); err == nil {
+			t.Error("Expected an error for a duplicate name")
+		}
+		if _, err := registry.Register("users.write", 1); err == nil {
+			t.Error("Expected an error for a duplicate bit")
+		}
+		if _, err := registry.Register("billing.*", 5); err == nil {
+			t.Error("Expected an error for a wildcard name")
+		}
+	})
+
+	t.Run("Resolve expands wildcards", func(t *testing.T) {
+		p, err := registry.Resolve("billing.*")
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		expected := Permissions{NewPermission(1), NewPermission(2)}.Flatten()
+		if p.Serialize() != expected.Serialize() {
+			t.Errorf("Expected %s, got %s", expected.Serialize(), p.Serialize())
+		}
+	})
+
+	t.Run("Resolve rejects unknown names", func(t *testing.T) {
+		if _, err := registry.Resolve("users.delete"); err == nil {
+			t.Error("Expected an error for an unregistered permission")
+		}
+	})
+
+	t.Run("Names maps bits back to names", func(t *testing.T) {
+		p := Permissions{NewPermission(1), NewPermission(3), NewPermission(9)}.Flatten()
+		if names := registry.Names(p); !reflect.DeepEqual(names, []string{"billing.invoices.read", "users.read"}) {
+			t.Errorf("Unexpected names %v", names)
+		}
+	})
+}
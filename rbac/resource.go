@@ -0,0 +1,158 @@
+package rbac
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+
+	"github.com/grzegorzmaniak/gothic/helpers"
+	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	ResourceActionsCacheKeyPrefix     = "resource_actions:"    // Key: resource_actions:<subject>:<resourceType>:<resourceID>
+	ResourceActionsSingleFlightPrefix = "resource_actions_sf:" // Key: resource_actions_sf:<subject>:<resourceType>:<resourceID>
+
+	// ResourceActionWildcard grants every action on a resource, e.g., to its owner.
+	ResourceActionWildcard = "*"
+)
+
+// ErrResourcePermissionsUnsupported is returned by CheckResourcePermission when the manager does not implement
+// ResourceManager.
+var ErrResourcePermissionsUnsupported = errors.New("rbac manager does not support resource permissions")
+
+var resourceRequestGroup singleflight.Group
+
+// ResourceManager is implemented by managers that grant actions on individual resources (object level
+// permissions), e.g., the owner of /documents/42 may "edit" and "delete" it while a collaborator may only "read".
+type ResourceManager interface {
+	// GetSubjectResourceActions gets the actions a subject may perform on a resource, ResourceActionWildcard grants
+	// every action. Ownership, ACLs and sharing are resolved here.
+	GetSubjectResourceActions(ctx context.Context, subjectIdentifier string, resourceType string, resourceID string) ([]string, error)
+}
+
+// resourceCacheKey builds the cache key of a subject's actions on a resource, the parts are escaped so a
+// delimiter in an identifier cannot collide with another resource.
+func resourceCacheKey(ctx context.Context, prefix string, subjectIdentifier string, resourceType string, resourceID string) string {
+	return tenantCacheKey(ctx, prefix+url.QueryEscape(subjectIdentifier)+":"+url.QueryEscape(resourceType)+":"+url.QueryEscape(resourceID))
+}
+
+// FetchSubjectResourceActions returns the actions a subject may perform on a resource, using the cache. Entries
+// live for the manager's subject permissions TTL, call InvalidateResourceActions once a resource's ACL changes.
+func FetchSubjectResourceActions(
+	ctx context.Context,
+	rbacManager Manager,
+	subjectIdentifier string,
+	resourceType string,
+	resourceID string,
+) ([]string, error) {
+	resourceManager, ok := rbacManager.(ResourceManager)
+	if !ok {
+		return nil, ErrResourcePermissionsUnsupported
+	}
+
+	ctx, done, err := beginFetch(ctx, rbacManager)
+	if err != nil {
+		return nil, fmt.Errorf("manager: failed to fetch actions on %s '%s': %w", resourceType, resourceID, err)
+	}
+	defer done()
+
+	source := func() ([]string, error) {
+		actions, fetchErr := helpers.Retry(ctx, helpers.RetryPolicyOf(rbacManager), func(ctx context.Context) ([]string, error) {
+			return resourceManager.GetSubjectResourceActions(ctx, subjectIdentifier, resourceType, resourceID)
+		})
+		if fetchErr != nil {
+			return nil, fmt.Errorf("manager: failed to fetch actions on %s '%s': %w", resourceType, resourceID, fetchErr)
+		}
+		if actions == nil {
+			actions = []string{}
+		}
+		return actions, nil
+	}
+
+	cacheInstance, err := rbacManager.GetCache()
+	if err != nil || cacheInstance == nil {
+		zap.L().Warn("Cache instance unavailable, fetching resource actions directly from source")
+		return source()
+	}
+
+	cacheKey := resourceCacheKey(ctx, ResourceActionsCacheKeyPrefix, subjectIdentifier, resourceType, resourceID)
+	cached, found, err := fetchFromCache(ctx, cacheInstance, cacheKey, func(b []byte) ([]string, error) {
+		var actions []string
+		if err := json.Unmarshal(b, &actions); err != nil {
+			return nil, err
+		}
+		return actions, nil
+	})
+	if err != nil {
+		zap.L().Warn("Failed to read resource actions from cache, will fetch from source", zap.Error(err))
+		found = false
+	}
+	if found {
+		return cached, nil
+	}
+
+	singleFlightKey := resourceCacheKey(ctx, ResourceActionsSingleFlightPrefix, subjectIdentifier, resourceType, resourceID)
+	result, err, _ := resourceRequestGroup.Do(singleFlightKey, func() (interface{}, error) {
+		actions, fetchErr := source()
+		if fetchErr != nil {
+			return nil, fetchErr
+		}
+
+		if cacheErr := setInCache(ctx, cacheInstance, cacheKey, actions, rbacManager.GetSubjectPermissionsCacheTtl(), func(v []string) ([]byte, error) {
+			return json.Marshal(v)
+		}); cacheErr != nil {
+			zap.L().Warn("Failed to cache resource actions", zap.String("key", cacheKey), zap.Error(cacheErr))
+		}
+		return actions, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	actions, ok := result.([]string)
+	if !ok {
+		return nil, fmt.Errorf("unexpected type from singleflight result for resource actions")
+	}
+	return actions, nil
+}
+
+// InvalidateResourceActions drops the cached actions of a subject on a resource, e.g., after sharing it.
+func InvalidateResourceActions(
+	ctx context.Context,
+	rbacManager Manager,
+	subjectIdentifier string,
+	resourceType string,
+	resourceID string,
+) error {
+	cacheInstance, err := rbacManager.GetCache()
+	if err != nil || cacheInstance == nil {
+		return err
+	}
+	return cacheInstance.Delete(ctx, resourceCacheKey(ctx, ResourceActionsCacheKeyPrefix, subjectIdentifier, resourceType, resourceID))
+}
+
+// CheckResourcePermission verifies that a subject may perform an action on a single resource, e.g., "edit" on
+// the "document" "42". It complements the route level checks, which cannot see which resource is requested.
+func CheckResourcePermission(
+	ctx context.Context,
+	rbacManager Manager,
+	subjectIdentifier string,
+	resourceType string,
+	resourceID string,
+	action string,
+) (bool, error) {
+	if action == "" {
+		return false, fmt.Errorf("resource action is empty")
+	}
+
+	actions, err := FetchSubjectResourceActions(ctx, rbacManager, subjectIdentifier, resourceType, resourceID)
+	if err != nil {
+		return false, err
+	}
+	return slices.Contains(actions, action) || slices.Contains(actions, ResourceActionWildcard), nil
+}
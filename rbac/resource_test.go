@@ -0,0 +1,121 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/eko/gocache/lib/v4/cache"
+	"github.com/grzegorzmaniak/gothic/helpers"
+)
+
+// resourceRbacManager lets "owner" do anything with document 1 and "reader" read it, nothing is granted on other
+// documents.
+type resourceRbacManager struct {
+	mockRbacManager
+	cache   *mockCache
+	fetches atomic.Int32
+	shared  bool
+}
+
+func (m *resourceRbacManager) GetCache() (cache.CacheInterface[[]byte], error) {
+	return m.cache, nil
+}
+
+func (m *resourceRbacManager) GetSubjectResourceActions(_ context.Context, subjectIdentifier string, resourceType string, resourceID string) ([]string, error) {
+	m.fetches.Add(1)
+	if resourceType == "document" && resourceID == "broken" {
+		return nil, errors.New("database connection failed")
+	}
+	if resourceType != "document" || resourceID != "1" {
+		return nil, nil
+	}
+	switch subjectIdentifier {
+	case "owner":
+		return []string{ResourceActionWildcard}, nil
+	case "reader":
+		if m.shared {
+			return []string{"read", "comment"}, nil
+		}
+		return []string{"read"}, nil
+	}
+	return nil, nil
+}
+
+func TestCheckResourcePermission(t *testing.T) {
+	ctx := context.Background()
+	manager := &resourceRbacManager{cache: &mockCache{}}
+
+	tests := []struct {
+		subject, resourceType, resourceID, action string
+		want                                      bool
+	}{
+		{subject: "owner", resourceType: "document", resourceID: "1", action: "delete", want: true},
+		{subject: "reader", resourceType: "document", resourceID: "1", action: "read", want: true},
+		{subject: "reader", resourceType: "document", resourceID: "1", action: "delete", want: false},
+		{subject: "owner", resourceType: "document", resourceID: "2", action: "read", want: false},
+		{subject: "owner", resourceType: "folder", resourceID: "1", action: "read", want: false},
+	}
+	for _, tt := range tests {
+		got, err := CheckResourcePermission(ctx, manager, tt.subject, tt.resourceType, tt.resourceID, tt.action)
+		if err != nil {
+			t.Fatalf("CheckResourcePermission() error = %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("%s %s on %s %s = %v, want %v", tt.subject, tt.action, tt.resourceType, tt.resourceID, got, tt.want)
+		}
+	}
+
+	t.Run("Actions are cached until invalidated", func(t *testing.T) {
+		before := manager.fetches.Load()
+		manager.shared = true
+		if ok, _ := CheckResourcePermission(ctx, manager, "reader", "document", "1", "comment"); ok {
+			t.Error("Expected the cached actions to be used")
+		}
+		if manager.fetches.Load() != before {
+			t.Errorf("Expected no fetch, got %d", manager.fetches.Load()-before)
+		}
+
+		if err := InvalidateResourceActions(ctx, manager, "reader", "document", "1"); err != nil {
+			t.Fatalf("InvalidateResourceActions() error = %v", err)
+		}
+		if ok, _ := CheckResourcePermission(ctx, manager, "reader", "document", "1", "comment"); !ok {
+			t.Error("Expected the new actions after invalidating")
+		}
+	})
+
+	t.Run("Tenants do not share cached actions", func(t *testing.T) {
+		before := manager.fetches.Load()
+		if _, err := CheckResourcePermission(WithTenant(ctx, "acme"), manager, "owner", "document", "1", "read"); err != nil {
+			t.Fatalf("CheckResourcePermission() error = %v", err)
+		}
+		if manager.fetches.Load() != before+1 {
+			t.Error("Expected the tenant scoped check to fetch its own actions")
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		if _, err := CheckResourcePermission(ctx, &mockRbacManager{}, "owner", "document", "1", "read"); !errors.Is(err, ErrResourcePermissionsUnsupported) {
+			t.Errorf("Expected ErrResourcePermissionsUnsupported, got %v", err)
+		}
+		if _, err := CheckResourcePermission(ctx, manager, "owner", "document", "broken", "read"); err == nil {
+			t.Error("Expected the fetch failure to be returned")
+		}
+		if _, err := CheckResourcePermission(ctx, manager, "owner", "document", "1", ""); err == nil {
+			t.Error("Expected an empty action to be rejected")
+		}
+	})
+
+	t.Run("Failed fetches are retried", func(t *testing.T) {
+		retrying := &resourceRbacManager{cache: &mockCache{}}
+		retrying.RetryPolicy = &helpers.RetryPolicy{Attempts: 3, BaseDelay: time.Millisecond}
+		if _, err := CheckResourcePermission(ctx, retrying, "owner", "document", "broken", "read"); err == nil {
+			t.Error("Expected the fetch failure to be returned")
+		}
+		if retrying.fetches.Load() != 3 {
+			t.Errorf("Expected 3 fetches, got %d", retrying.fetches.Load())
+		}
+	})
+}
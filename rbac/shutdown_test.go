@@ -0,0 +1,59 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/grzegorzmaniak/gothic/helpers"
+)
+
+func TestManagerShutdown(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	manager := &mockRbacCacheManager{
+		cacheInstance: &mockCache{},
+		getSubjectRolesAndPermissionsFunc: func(ctx context.Context, subjectIdentifier string) (Permissions, []string, error) {
+			close(started)
+			<-release
+			return Permissions{readOnly}, []string{"user"}, nil
+		},
+	}
+
+	fetched := make(chan error, 1)
+	go func() {
+		_, _, err := FetchSubjectRolesAndPermissions(context.Background(), "user-1", "rbac-cache-id", manager)
+		fetched <- err
+	}()
+	<-started
+
+	shutdown := make(chan error, 1)
+	go func() { shutdown <- manager.Shutdown(context.Background()) }()
+
+	// - Shutdown waits for the in-flight fetch
+	select {
+	case <-shutdown:
+		t.Fatal("Expected Shutdown to wait for the in-flight fetch")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(release)
+	if err := <-fetched; err != nil {
+		t.Errorf("Expected the in-flight fetch to finish, got %v", err)
+	}
+	if err := <-shutdown; err != nil {
+		t.Errorf("Expected a clean shutdown, got %v", err)
+	}
+
+	// - New work is rejected
+	if _, _, err := FetchSubjectRolesAndPermissions(context.Background(), "user-1", "other-cache-id", manager); !errors.Is(err, helpers.ErrShutdown) {
+		t.Errorf("Expected ErrShutdown for subject data, got %v", err)
+	}
+	if _, err := GetRolePermissions(context.Background(), "user", manager); !errors.Is(err, helpers.ErrShutdown) {
+		t.Errorf("Expected ErrShutdown for role permissions, got %v", err)
+	}
+	if _, err := FetchSubjectNamedPermissions(context.Background(), "user-1", "rbac-cache-id", manager); !errors.Is(err, helpers.ErrShutdown) {
+		t.Errorf("Expected ErrShutdown for named permissions, got %v", err)
+	}
+}
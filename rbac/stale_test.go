@@ -0,0 +1,94 @@
+package rbac
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	internalcache "github.com/grzegorzmaniak/gothic/cache"
+)
+
+func TestSubjectStaleWhileRevalidate(t *testing.T) {
+	newManager := func(t *testing.T, staleWindow time.Duration, fetches *atomic.Int64, release chan struct{}) *mockRbacCacheManager {
+		cacheInstance, err := internalcache.BuildDefaultCacheManager(nil).GetCache()
+		if err != nil {
+			t.Fatalf("Failed to build the cache: %v", err)
+		}
+		manager := &mockRbacCacheManager{
+			cacheInstance: cacheInstance,
+			getSubjectRolesAndPermissionsFunc: func(ctx context.Context, subjectIdentifier string) (Permissions, []string, error) {
+				fetch := fetches.Add(1)
+				if fetch > 1 && release != nil {
+					<-release
+				}
+				if fetch > 1 {
+					return Permissions{readWrite}, []string{"admin"}, nil
+				}
+				return Permissions{readOnly}, []string{"user"}, nil
+			},
+		}
+		manager.UserPermissionsCacheTTL = 30 * time.Millisecond
+		manager.UserRolesCacheTTL = 30 * time.Millisecond
+		manager.SubjectStaleWindow = staleWindow
+		return manager
+	}
+
+	fetchRoles := func(t *testing.T, manager Manager) []string {
+		_, roles, err := FetchSubjectRolesAndPermissions(context.Background(), "user-1", "rbac-cache-id", manager)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		return roles
+	}
+
+	t.Run("Expired entries are served while they are refreshed", func(t *testing.T) {
+		var fetches atomic.Int64
+		release := make(chan struct{})
+		manager := newManager(t, time.Second, &fetches, release)
+
+		fetchRoles(t, manager)
+		time.Sleep(10 * time.Millisecond) // - Let the cache settle
+		if roles := fetchRoles(t, manager); roles[0] != "user" || fetches.Load() != 1 {
+			t.Fatalf("Expected a fresh cache hit, got %v after %d fetches", roles, fetches.Load())
+		}
+
+		time.Sleep(40 * time.Millisecond) // - Past the TTL, within the stale window
+		// - The source is blocked until release, so waiting for it would never return
+		if roles := fetchRoles(t, manager); roles[0] != "user" {
+			t.Errorf("Expected the stale roles without waiting for the source, got %v", roles)
+		}
+		fetchRoles(t, manager) // - A refresh is already running
+
+		close(release)
+		time.Sleep(20 * time.Millisecond) // - Let the refresh and the cache settle
+		if roles := fetchRoles(t, manager); roles[0] != "admin" {
+			t.Errorf("Expected the refreshed roles, got %v", roles)
+		}
+		if fetches.Load() != 2 {
+			t.Errorf("Expected a single background refresh, got %d fetches", fetches.Load())
+		}
+	})
+
+	t.Run("Without a stale window expired entries are fetched", func(t *testing.T) {
+		var fetches atomic.Int64
+		manager := newManager(t, 0, &fetches, nil)
+
+		fetchRoles(t, manager)
+		time.Sleep(40 * time.Millisecond) // - Past the TTL
+		if roles := fetchRoles(t, manager); roles[0] != "admin" || fetches.Load() != 2 {
+			t.Errorf("Expected the source to be fetched before responding, got %v after %d fetches", roles, fetches.Load())
+		}
+	})
+
+	t.Run("Entries past the stale window are fetched", func(t *testing.T) {
+		var fetches atomic.Int64
+		manager := newManager(t, 20*time.Millisecond, &fetches, nil)
+
+		fetchRoles(t, manager)
+		time.Sleep(70 * time.Millisecond) // - Past the TTL and the stale window
+		if roles := fetchRoles(t, manager); roles[0] != "admin" {
+			t.Errorf("Expected the source to be fetched before responding, got %v", roles)
+		}
+	})
+}
@@ -0,0 +1,133 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+
+	"github.com/grzegorzmaniak/gothic/helpers"
+)
+
+const (
+	TenantCacheKeyPrefix = "tenant:" // Key: tenant:<tenantIdentifier>:<key>
+)
+
+// ErrTenantsUnsupported is returned when a fetch is scoped to a tenant (see WithTenant) but the manager does not
+// implement TenantManager. Falling back to the global roles would let a role in one tenant authorize another.
+var ErrTenantsUnsupported = errors.New("rbac manager does not support tenants")
+
+// TenantManager is implemented by managers whose roles and permissions are scoped to tenants, a subject can be an
+// "admin" of tenant A and a "viewer" of tenant B. Fetches made with a tenant on the context (see WithTenant) use
+// these methods instead of their Manager counterparts, and are cached under tenant-prefixed keys.
+type TenantManager interface {
+	// GetTenantSubjectRolesAndPermissions gets the permissions and roles of a subject within a tenant.
+	GetTenantSubjectRolesAndPermissions(ctx context.Context, tenantIdentifier string, subjectIdentifier string) (Permissions, []string, error)
+
+	// GetTenantRolePermissions gets the permissions of a role within a tenant, e.g., a tenant's custom role.
+	GetTenantRolePermissions(ctx context.Context, tenantIdentifier string, roleIdentifier string) (Permissions, error)
+
+	// GetTenantSubjectNamedPermissions gets the named permissions granted directly to a subject within a tenant.
+	GetTenantSubjectNamedPermissions(ctx context.Context, tenantIdentifier string, subjectIdentifier string) (PermissionSet, error)
+
+	// GetTenantRoleNamedPermissions gets the named permissions of a role within a tenant.
+	GetTenantRoleNamedPermissions(ctx context.Context, tenantIdentifier string, roleIdentifier string) (PermissionSet, error)
+}
+
+type tenantKey struct{}
+
+// WithTenant scopes the RBAC fetches and checks made with the returned context to a tenant, an empty tenant
+// leaves the context unscoped.
+func WithTenant(ctx context.Context, tenantIdentifier string) context.Context {
+	if tenantIdentifier == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, tenantKey{}, tenantIdentifier)
+}
+
+// TenantFromContext returns the tenant a context is scoped to, or an empty string.
+func TenantFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	tenant, _ := ctx.Value(tenantKey{}).(string)
+	return tenant
+}
+
+// tenantCacheKey prefixes a cache or singleflight key with the context's tenant, so the same subject or role
+// never shares entries across tenants.
+func tenantCacheKey(ctx context.Context, key string) string {
+	tenant := TenantFromContext(ctx)
+	if tenant == "" {
+		return key
+	}
+	return TenantCacheKeyPrefix + tenant + ":" + key
+}
+
+// tenantManagerFor returns the context's tenant and the manager's TenantManager, ok is false for unscoped contexts.
+func tenantManagerFor(ctx context.Context, rbacManager Manager) (string, TenantManager, bool, error) {
+	tenant := TenantFromContext(ctx)
+	if tenant == "" {
+		return "", nil, false, nil
+	}
+	tenantManager, ok := rbacManager.(TenantManager)
+	if !ok {
+		return "", nil, false, ErrTenantsUnsupported
+	}
+	return tenant, tenantManager, true, nil
+}
+
+func sourceSubjectRolesAndPermissions(ctx context.Context, rbacManager Manager, subjectIdentifier string) (Permissions, []string, error) {
+	tenant, tenantManager, scoped, err := tenantManagerFor(ctx, rbacManager)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := helpers.Retry(ctx, helpers.RetryPolicyOf(rbacManager), func(ctx context.Context) (subjectData, error) {
+		var data subjectData
+		var fetchErr error
+		if scoped {
+			data.Permissions, data.Roles, fetchErr = tenantManager.GetTenantSubjectRolesAndPermissions(ctx, tenant, subjectIdentifier)
+		} else {
+			data.Permissions, data.Roles, fetchErr = rbacManager.GetSubjectRolesAndPermissions(ctx, subjectIdentifier)
+		}
+		return data, fetchErr
+	})
+	return data.Permissions, data.Roles, err
+}
+
+func sourceRolePermissions(ctx context.Context, rbacManager Manager, roleIdentifier string) (Permissions, error) {
+	tenant, tenantManager, scoped, err := tenantManagerFor(ctx, rbacManager)
+	if err != nil {
+		return nil, err
+	}
+	return helpers.Retry(ctx, helpers.RetryPolicyOf(rbacManager), func(ctx context.Context) (Permissions, error) {
+		if scoped {
+			return tenantManager.GetTenantRolePermissions(ctx, tenant, roleIdentifier)
+		}
+		return rbacManager.GetRolePermissions(ctx, roleIdentifier)
+	})
+}
+
+func sourceSubjectNamedPermissions(ctx context.Context, rbacManager Manager, subjectIdentifier string) (PermissionSet, error) {
+	tenant, tenantManager, scoped, err := tenantManagerFor(ctx, rbacManager)
+	if err != nil {
+		return nil, err
+	}
+	return helpers.Retry(ctx, helpers.RetryPolicyOf(rbacManager), func(ctx context.Context) (PermissionSet, error) {
+		if scoped {
+			return tenantManager.GetTenantSubjectNamedPermissions(ctx, tenant, subjectIdentifier)
+		}
+		return rbacManager.GetSubjectNamedPermissions(ctx, subjectIdentifier)
+	})
+}
+
+func sourceRoleNamedPermissions(ctx context.Context, rbacManager Manager, roleIdentifier string) (PermissionSet, error) {
+	tenant, tenantManager, scoped, err := tenantManagerFor(ctx, rbacManager)
+	if err != nil {
+		return nil, err
+	}
+	return helpers.Retry(ctx, helpers.RetryPolicyOf(rbacManager), func(ctx context.Context) (PermissionSet, error) {
+		if scoped {
+			return tenantManager.GetTenantRoleNamedPermissions(ctx, tenant, roleIdentifier)
+		}
+		return rbacManager.GetRoleNamedPermissions(ctx, roleIdentifier)
+	})
+}
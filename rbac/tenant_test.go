@@ -0,0 +1,109 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/eko/gocache/lib/v4/cache"
+)
+
+// tenantRbacManager makes "alice" an admin of the "acme" tenant and a viewer of the "globex" tenant.
+type tenantRbacManager struct {
+	mockRbacManager
+	cache *mockCache
+}
+
+func (m *tenantRbacManager) GetCache() (cache.CacheInterface[[]byte], error) {
+	return m.cache, nil
+}
+
+func (m *tenantRbacManager) GetTenantSubjectRolesAndPermissions(_ context.Context, tenantIdentifier string, subjectIdentifier string) (Permissions, []string, error) {
+	if subjectIdentifier != "alice" {
+		return Permissions{}, []string{}, nil
+	}
+	switch tenantIdentifier {
+	case "acme":
+		return Permissions{}, []string{"admin"}, nil
+	case "globex":
+		return Permissions{}, []string{"viewer"}, nil
+	}
+	return Permissions{}, []string{}, nil
+}
+
+func (m *tenantRbacManager) GetTenantRolePermissions(_ context.Context, _ string, roleIdentifier string) (Permissions, error) {
+	if roleIdentifier == "admin" {
+		return Permissions{readWrite}, nil
+	}
+	return Permissions{readOnly}, nil
+}
+
+func (m *tenantRbacManager) GetTenantSubjectNamedPermissions(_ context.Context, tenantIdentifier string, _ string) (PermissionSet, error) {
+	if tenantIdentifier == "acme" {
+		return MustPermissionSet("billing.*"), nil
+	}
+	return PermissionSet{}, nil
+}
+
+func (m *tenantRbacManager) GetTenantRoleNamedPermissions(context.Context, string, string) (PermissionSet, error) {
+	return PermissionSet{}, nil
+}
+
+func TestTenantScoping(t *testing.T) {
+	manager := &tenantRbacManager{cache: &mockCache{}}
+	write := AccessRequirements{Permissions: readWrite, Policy: PermissionsOnly}
+	billing := AccessRequirements{NamedPermissions: MustPermissionSet("billing.invoices.read"), Policy: PermissionsOnly}
+
+	t.Run("Roles only apply within their tenant", func(t *testing.T) {
+		for _, tt := range []struct {
+			tenant       string
+			requirements AccessRequirements
+			want         bool
+		}{
+			{tenant: "acme", requirements: write, want: true},
+			{tenant: "globex", requirements: write, want: false},
+			{tenant: "acme", requirements: billing, want: true},
+			{tenant: "globex", requirements: billing, want: false},
+		} {
+			// - Checked twice, so the second check is served from the tenant's cache entries
+			for range 2 {
+				got, err := CheckAccess(WithTenant(context.Background(), tt.tenant), manager, "alice", "cache-id", tt.requirements)
+				if err != nil {
+					t.Fatalf("CheckAccess() error = %v", err)
+				}
+				if got != tt.want {
+					t.Errorf("CheckAccess() in %s = %v, want %v", tt.tenant, got, tt.want)
+				}
+			}
+		}
+	})
+
+	t.Run("Cache keys are prefixed with the tenant", func(t *testing.T) {
+		for key := range manager.cache.data {
+			if !strings.HasPrefix(key, TenantCacheKeyPrefix+"acme:") && !strings.HasPrefix(key, TenantCacheKeyPrefix+"globex:") {
+				t.Errorf("Expected a tenant-prefixed cache key, got %q", key)
+			}
+		}
+		if _, ok := manager.cache.data[TenantCacheKeyPrefix+"acme:"+SubjectRolesCacheKeyPrefix+"cache-id"]; !ok {
+			t.Error("Expected the acme roles to be cached")
+		}
+	})
+
+	t.Run("Unscoped contexts use the Manager methods", func(t *testing.T) {
+		got, err := CheckAccess(context.Background(), &mockRbacManager{}, "admin-user", "", write)
+		if err != nil || !got {
+			t.Errorf("Expected the unscoped check to pass, got %v, %v", got, err)
+		}
+		if TenantFromContext(WithTenant(context.Background(), "")) != "" {
+			t.Error("Expected an empty tenant to leave the context unscoped")
+		}
+	})
+
+	t.Run("Managers without tenant support fail closed", func(t *testing.T) {
+		_, err := CheckAccess(WithTenant(context.Background(), "acme"), &mockRbacManager{}, "admin-user", "", write)
+		if !errors.Is(err, ErrTenantsUnsupported) {
+			t.Errorf("Expected ErrTenantsUnsupported, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,108 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/grzegorzmaniak/gothic/helpers"
+	"go.uber.org/zap"
+)
+
+// DefaultWarmIntervalRatio is the share of the role permissions TTL WarmRoles waits between refreshes when no
+// interval is given, so entries are replaced before they expire.
+const DefaultWarmIntervalRatio = 0.75
+
+// WarmRoles pre-populates the cached permissions (bitset and named) of roles, then refreshes them in the
+// background every interval. The refresh always reads the source and overwrites the entries before their TTL
+// expires, so requests keep hitting the cache instead of all missing at once (stale-while-revalidate). A zero
+// interval uses DefaultWarmIntervalRatio of the manager's GetRolePermissionsCacheTtl.
+//
+// The first pass runs before WarmRoles returns, its error joins the roles that failed to load. The background job
+// stops when ctx is cancelled or the manager's Lifecycle shuts down, and keeps the tenant ctx is scoped to (see
+// WithTenant). Failed refreshes are logged and the previous entries are kept until they expire.
+func WarmRoles(ctx context.Context, manager Manager, roles []string, interval time.Duration) error {
+	if manager == nil {
+		return fmt.Errorf("rbac: manager is nil, can not warm roles")
+	}
+
+	ttl := manager.GetRolePermissionsCacheTtl()
+	if interval <= 0 {
+		interval = time.Duration(float64(ttl) * DefaultWarmIntervalRatio)
+	}
+	if interval <= 0 {
+		return fmt.Errorf("rbac: invalid warm interval %s", interval)
+	}
+	if interval >= ttl {
+		zap.L().Warn("Role warm interval is not shorter than the cache TTL, entries expire between refreshes",
+			zap.Duration("interval", interval), zap.Duration("ttl", ttl))
+	}
+
+	roles = append([]string(nil), roles...)
+	err := warmRoles(ctx, manager, roles)
+
+	tenant := TenantFromContext(ctx)
+	started := helpers.LifecycleOf(manager).Go(func(background context.Context) {
+		jobCtx, cancel := context.WithCancel(WithTenant(background, tenant))
+		defer cancel()
+		defer context.AfterFunc(ctx, cancel)()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-jobCtx.Done():
+				return
+			case <-ticker.C:
+				if warmErr := warmRoles(jobCtx, manager, roles); warmErr != nil && jobCtx.Err() == nil {
+					zap.L().Warn("Failed to refresh role permissions, keeping the cached ones", zap.Error(warmErr))
+				}
+			}
+		}
+	})
+	if !started {
+		return errors.Join(err, helpers.ErrShutdown)
+	}
+	return err
+}
+
+// warmRoles reads the permissions of every role from the source and caches them.
+func warmRoles(ctx context.Context, manager Manager, roles []string) error {
+	ctx, done, err := beginFetch(ctx, manager)
+	if err != nil {
+		return fmt.Errorf("rbac: failed to warm roles: %w", err)
+	}
+	defer done()
+
+	cacheInstance, err := manager.GetCache()
+	if err != nil || cacheInstance == nil {
+		return fmt.Errorf("rbac: cache is not available, can not warm roles: %w", err)
+	}
+
+	ttl := manager.GetRolePermissionsCacheTtl()
+	var errs []error
+	for _, role := range roles {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		permissions, fetchErr := sourceRolePermissions(ctx, manager, role)
+		if fetchErr == nil {
+			fetchErr = CacheRolePermissions(ctx, role, cacheInstance, permissions, ttl)
+		}
+		if fetchErr != nil {
+			errs = append(errs, fmt.Errorf("role '%s': %w", role, fetchErr))
+			continue
+		}
+
+		named, fetchErr := sourceRoleNamedPermissions(ctx, manager, role)
+		if fetchErr == nil {
+			fetchErr = CacheRoleNamedPermissions(ctx, role, cacheInstance, named, ttl)
+		}
+		if fetchErr != nil {
+			errs = append(errs, fmt.Errorf("role '%s' named permissions: %w", role, fetchErr))
+		}
+	}
+	return errors.Join(errs...)
+}
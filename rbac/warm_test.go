@@ -0,0 +1,103 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	internalcache "github.com/grzegorzmaniak/gothic/cache"
+	"github.com/grzegorzmaniak/gothic/helpers"
+)
+
+func TestWarmRoles(t *testing.T) {
+	newManager := func(t *testing.T, fetches *atomic.Int64) *mockRbacCacheManager {
+		cacheInstance, err := internalcache.BuildDefaultCacheManager(nil).GetCache()
+		if err != nil {
+			t.Fatalf("Failed to build the cache: %v", err)
+		}
+		manager := &mockRbacCacheManager{
+			cacheInstance: cacheInstance,
+			getRolePermissionsFunc: func(ctx context.Context, roleIdentifier string) (Permissions, error) {
+				fetches.Add(1)
+				if roleIdentifier == "broken" {
+					return nil, errors.New("source unavailable")
+				}
+				return Permissions{readOnly}, nil
+			},
+		}
+		manager.RolePermissionsCacheTTL = time.Second
+		return manager
+	}
+
+	t.Run("Roles are cached up front and refreshed", func(t *testing.T) {
+		var fetches atomic.Int64
+		manager := newManager(t, &fetches)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		if err := WarmRoles(ctx, manager, []string{"admin", "user"}, 20*time.Millisecond); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if fetches.Load() != 2 {
+			t.Fatalf("Expected both roles to be fetched up front, got %d fetches", fetches.Load())
+		}
+
+		time.Sleep(10 * time.Millisecond) // - Let the cache settle
+		before := fetches.Load()
+		if _, err := GetRolePermissions(context.Background(), "admin", manager); err != nil || fetches.Load() != before {
+			t.Errorf("Expected a warmed cache hit, got %v with %d fetches", err, fetches.Load()-before)
+		}
+
+		time.Sleep(70 * time.Millisecond)
+		if fetches.Load() < 4 {
+			t.Errorf("Expected the roles to be refreshed in the background, got %d fetches", fetches.Load())
+		}
+
+		cancel()
+		time.Sleep(30 * time.Millisecond)
+		stopped := fetches.Load()
+		time.Sleep(50 * time.Millisecond)
+		if fetches.Load() != stopped {
+			t.Errorf("Expected the job to stop with its context, got %d more fetches", fetches.Load()-stopped)
+		}
+	})
+
+	t.Run("Failed roles are reported", func(t *testing.T) {
+		var fetches atomic.Int64
+		manager := newManager(t, &fetches)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		if err := WarmRoles(ctx, manager, []string{"broken", "user"}, time.Minute); err == nil {
+			t.Error("Expected the broken role to be reported")
+		}
+		time.Sleep(10 * time.Millisecond) // - Let the cache settle
+		before := fetches.Load()
+		if _, err := GetRolePermissions(context.Background(), "user", manager); err != nil || fetches.Load() != before {
+			t.Errorf("Expected the other roles to be warmed, got %v", err)
+		}
+	})
+
+	t.Run("The job stops with the manager", func(t *testing.T) {
+		var fetches atomic.Int64
+		manager := newManager(t, &fetches)
+
+		if err := WarmRoles(context.Background(), manager, []string{"user"}, 10*time.Millisecond); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if err := manager.Shutdown(context.Background()); err != nil {
+			t.Fatalf("Expected a clean shutdown, got %v", err)
+		}
+		stopped := fetches.Load()
+		time.Sleep(40 * time.Millisecond)
+		if fetches.Load() != stopped {
+			t.Errorf("Expected no refreshes after the shutdown, got %d", fetches.Load()-stopped)
+		}
+
+		if err := WarmRoles(context.Background(), manager, []string{"user"}, time.Minute); !errors.Is(err, helpers.ErrShutdown) {
+			t.Errorf("Expected ErrShutdown, got %v", err)
+		}
+	})
+}
@@ -25,6 +25,14 @@ type FieldRule struct {
 	URIName  string     `json:"uri,omitempty" yaml:"uri,omitempty"`
 	Header   string     `json:"header,omitempty" yaml:"header,omitempty"`
 	Nested   FieldRules `json:"nested,omitempty" yaml:"nested,omitempty"`
+
+	// Sanitize normalizes the bound value before it is validated, e.g., "trim,lower", see SanitizeTag.
+	Sanitize string `json:"sanitize,omitempty" yaml:"sanitize,omitempty"`
+
+	// Keys and Values describe the keys and values of "map" types, e.g., Type "map" with Keys {Type: "string",
+	// Tags: "min=2"} and Values {Type: "[]int", Tags: "required"}. Values may be maps or nested structs again.
+	Keys   *FieldRule `json:"keys,omitempty" yaml:"keys,omitempty"`
+	Values *FieldRule `json:"values,omitempty" yaml:"values,omitempty"`
 }
 
 // FieldRules describes a dynamic struct definition keyed by exported field names.
@@ -53,22 +61,44 @@ func (c *dynamicStructCache) Set(key string, value reflect.Type) {
 	c.store.Store(key, value)
 }
 
+func (c *dynamicStructCache) Delete(key string) {
+	if c == nil || key == "" {
+		return
+	}
+	c.store.Delete(key)
+}
+
 func resolveFieldType(rule FieldRule) (reflect.Type, error) {
+	return resolveFieldTypeIn(rule, true)
+}
+
+// resolveFieldTypeIn resolves the type of a rule, allowCustom is false inside maps as their values are never
+// parsed into the custom type.
+func resolveFieldTypeIn(rule FieldRule, allowCustom bool) (reflect.Type, error) {
 	typeName := strings.TrimSpace(rule.Type)
 	if strings.HasPrefix(typeName, "[]") {
 		elemRule := FieldRule{
 			Type:   strings.TrimPrefix(typeName, "[]"),
 			Nested: rule.Nested,
+			Keys:   rule.Keys,
+			Values: rule.Values,
 		}
-		elemType, err := resolveFieldType(elemRule)
+		elemType, err := resolveFieldTypeIn(elemRule, allowCustom)
 		if err != nil {
 			return nil, err
 		}
 		return reflect.SliceOf(elemType), nil
 	}
 
+	if isMapType(typeName) {
+		return resolveMapType(rule)
+	}
+	if rule.Keys != nil || rule.Values != nil {
+		return nil, fmt.Errorf("keys and values rules need a map type, got %q", rule.Type)
+	}
+
 	if len(rule.Nested) > 0 {
-		return buildDynamicStructType(rule.Nested)
+		return buildDynamicStructTypeIn(rule.Nested, allowCustom)
 	}
 
 	switch strings.ToLower(typeName) {
@@ -82,12 +112,99 @@ func resolveFieldType(rule FieldRule) (reflect.Type, error) {
 		return reflect.TypeOf(float64(0)), nil
 	case "bool", "boolean":
 		return reflect.TypeOf(false), nil
+	}
+
+	// - Custom types are bound as their raw string, see DynamicType
+	if _, ok := lookupDynamicType(typeName); ok {
+		if !allowCustom {
+			return nil, fmt.Errorf("custom type %q is not supported inside maps", rule.Type)
+		}
+		return reflect.TypeOf(""), nil
+	}
+	return nil, fmt.Errorf("unsupported dynamic field type %q", rule.Type)
+}
+
+func isMapType(typeName string) bool {
+	typeName = strings.ToLower(typeName)
+	return typeName == "map" || strings.HasPrefix(typeName, "map[")
+}
+
+// resolveMapType resolves "map[K]V" types, or "map" with the key and value types taken from the Keys and Values
+// rules. Without a Values rule the map's Nested rules describe its values, e.g., "map[string]" with Nested.
+func resolveMapType(rule FieldRule) (reflect.Type, error) {
+	keyRule, valueRule := FieldRule{}, FieldRule{Nested: rule.Nested}
+	if rule.Keys != nil {
+		keyRule = *rule.Keys
+	}
+	if rule.Values != nil {
+		if len(rule.Nested) > 0 {
+			return nil, fmt.Errorf("nested rules of a map with a values rule belong in the values rule")
+		}
+		valueRule = *rule.Values
+	}
+
+	typeName := strings.TrimSpace(rule.Type)
+	if len(typeName) > len("map") {
+		closing := strings.Index(typeName, "]")
+		if closing < 0 {
+			return nil, fmt.Errorf("malformed map type %q", rule.Type)
+		}
+		if keyRule.Type != "" || valueRule.Type != "" {
+			return nil, fmt.Errorf("map type %q already sets the key and value types", rule.Type)
+		}
+		keyRule.Type, valueRule.Type = typeName[len("map["):closing], typeName[closing+1:]
+	}
+
+	keyType, err := resolveFieldTypeIn(FieldRule{Type: keyRule.Type}, false)
+	if err != nil {
+		return nil, fmt.Errorf("map key: %w", err)
+	}
+	switch keyType.Kind() {
+	case reflect.String, reflect.Int, reflect.Int64:
 	default:
-		return nil, fmt.Errorf("unsupported dynamic field type %q", rule.Type)
+		return nil, fmt.Errorf("map keys must be string, int or int64, got %q", keyRule.Type)
+	}
+
+	valueType, err := resolveFieldTypeIn(valueRule, false)
+	if err != nil {
+		return nil, fmt.Errorf("map value: %w", err)
+	}
+	return reflect.MapOf(keyType, valueType), nil
+}
+
+// validateTag builds the validate tag of a rule, the Keys and Values tags of maps are applied with dive, keys and
+// endkeys, e.g., "required,dive,keys,min=2,endkeys,email". Maps in slices dive through the slices first.
+func validateTag(rule FieldRule) string {
+	parts := make([]string, 0, 4)
+	if tags := strings.TrimSpace(rule.Tags); tags != "" {
+		parts = append(parts, tags)
+	}
+	if rule.Keys == nil && rule.Values == nil {
+		return strings.Join(parts, ",")
+	}
+
+	dive := make([]string, 0, 4)
+	if rule.Keys != nil {
+		if keyTags := strings.TrimSpace(rule.Keys.Tags); keyTags != "" {
+			dive = append(dive, "keys", keyTags, "endkeys")
+		}
+	}
+	if rule.Values != nil {
+		if valueTags := validateTag(*rule.Values); valueTags != "" {
+			dive = append(dive, valueTags)
+		}
 	}
+	if len(dive) > 0 {
+		for typeName := strings.TrimSpace(rule.Type); strings.HasPrefix(typeName, "[]"); typeName = typeName[2:] {
+			parts = append(parts, "dive")
+		}
+		parts = append(parts, "dive")
+		parts = append(parts, dive...)
+	}
+	return strings.Join(parts, ",")
 }
 
-func buildStructTag(fieldName string, rule FieldRule) reflect.StructTag {
+func buildStructTag(fieldName string, rule FieldRule, fieldType reflect.Type) reflect.StructTag {
 	tagParts := make([]string, 0, 4)
 
 	jsonName := rule.JSONName
@@ -96,8 +213,8 @@ func buildStructTag(fieldName string, rule FieldRule) reflect.StructTag {
 	}
 	tagParts = append(tagParts, fmt.Sprintf(`json:"%s"`, jsonName))
 
-	// Only add form, header, and uri tags if NOT nested
-	if len(rule.Nested) == 0 {
+	// Only add form, header, and uri tags if NOT nested, maps are only bound from the body as well
+	if len(rule.Nested) == 0 && fieldType.Kind() != reflect.Map {
 		formName := rule.FormName
 		if formName == "" {
 			formName = strings.ToLower(fieldName)
@@ -120,14 +237,22 @@ func buildStructTag(fieldName string, rule FieldRule) reflect.StructTag {
 		tagParts = append(tagParts, `uri:"-"`)
 	}
 
-	if strings.TrimSpace(rule.Tags) != "" {
-		tagParts = append(tagParts, fmt.Sprintf(`validate:"%s"`, strings.TrimSpace(rule.Tags)))
+	if rule.Sanitize != "" {
+		tagParts = append(tagParts, fmt.Sprintf(`sanitize:"%s"`, rule.Sanitize))
+	}
+
+	if tags := validateTag(rule); tags != "" {
+		tagParts = append(tagParts, fmt.Sprintf(`validate:"%s"`, tags))
 	}
 
 	return reflect.StructTag(strings.Join(tagParts, " "))
 }
 
 func buildDynamicStructType(rules FieldRules) (reflect.Type, error) {
+	return buildDynamicStructTypeIn(rules, true)
+}
+
+func buildDynamicStructTypeIn(rules FieldRules, allowCustom bool) (reflect.Type, error) {
 	fieldNames := make([]string, 0, len(rules))
 	for name := range rules {
 		fieldNames = append(fieldNames, name)
@@ -145,7 +270,7 @@ func buildDynamicStructType(rules FieldRules) (reflect.Type, error) {
 		}
 
 		rule := rules[fieldName]
-		fieldType, err := resolveFieldType(rule)
+		fieldType, err := resolveFieldTypeIn(rule, allowCustom)
 		if err != nil {
 			return nil, fmt.Errorf("%s: %w", fieldName, err)
 		}
@@ -153,7 +278,7 @@ func buildDynamicStructType(rules FieldRules) (reflect.Type, error) {
 		fields = append(fields, reflect.StructField{
 			Name: fieldName,
 			Type: fieldType,
-			Tag:  buildStructTag(fieldName, rule),
+			Tag:  buildStructTag(fieldName, rule, fieldType),
 		})
 	}
 
@@ -164,6 +289,11 @@ func buildDynamicStructType(rules FieldRules) (reflect.Type, error) {
 	return reflect.StructOf(fields), nil
 }
 
+// DynamicStructType returns the struct type FieldRules are bound into, e.g., to describe a dynamic route.
+func DynamicStructType(rules FieldRules) (reflect.Type, error) {
+	return buildDynamicStructType(rules)
+}
+
 func getDynamicStructType(engine *Engine, cacheID string, rules FieldRules) (reflect.Type, error) {
 	if engine == nil {
 		return nil, errors.NewInternalServerError("Validator is not initialized", nil)
@@ -187,6 +317,37 @@ func getDynamicStructType(engine *Engine, cacheID string, rules FieldRules) (ref
 // validates it using the Engine validator, and returns a simple map of field values.
 // cacheID allows reusing the reflected struct definition across invocations to avoid rebuild costs.
 func DynamicInputData(ctx *gin.Context, engine *Engine, cacheID string, rules FieldRules) (map[string]interface{}, *errors.AppError) {
+	return dynamicInputData(ctx, engine, cacheID, rules, false)
+}
+
+// DynamicPartialInputData is DynamicInputData for partial updates, e.g., PATCH routes: the map only holds the
+// fields present in the request, so an absent field can be told apart from a zero one. Absent fields pass
+// omitempty tags while present ones are validated even when zero, JSON nulls count as absent.
+func DynamicPartialInputData(ctx *gin.Context, engine *Engine, cacheID string, rules FieldRules) (map[string]interface{}, *errors.AppError) {
+	return dynamicInputData(ctx, engine, cacheID, rules, true)
+}
+
+// partialStructType makes every top-level field of structType a pointer, absent fields stay nil.
+func partialStructType(structType reflect.Type) reflect.Type {
+	fields := make([]reflect.StructField, structType.NumField())
+	for i := range fields {
+		fields[i] = structType.Field(i)
+		fields[i].Type = reflect.PointerTo(fields[i].Type)
+	}
+	if len(fields) == 0 {
+		return structType
+	}
+	return reflect.StructOf(fields)
+}
+
+func partialCacheID(cacheID string) string {
+	if cacheID == "" {
+		return ""
+	}
+	return cacheID + "|partial"
+}
+
+func dynamicInputData(ctx *gin.Context, engine *Engine, cacheID string, rules FieldRules, partial bool) (map[string]interface{}, *errors.AppError) {
 	if engine == nil || engine.validator == nil {
 		return nil, errors.NewInternalServerError("Validator is not initialized", nil)
 	}
@@ -196,6 +357,14 @@ func DynamicInputData(ctx *gin.Context, engine *Engine, cacheID string, rules Fi
 		zap.L().Debug("Failed to build dynamic struct type", zap.Error(err), zap.String("cacheId", cacheID))
 		return nil, errors.NewInternalServerError("Failed to prepare dynamic input rules", err)
 	}
+	if partial {
+		if cachedType, ok := engine.dynamicStructCache.Get(partialCacheID(cacheID)); ok {
+			structType = cachedType
+		} else {
+			structType = partialStructType(structType)
+			engine.dynamicStructCache.Set(partialCacheID(cacheID), structType)
+		}
+	}
 
 	target := reflect.New(structType)
 
@@ -203,15 +372,32 @@ func DynamicInputData(ctx *gin.Context, engine *Engine, cacheID string, rules Fi
 		return nil, bindErr
 	}
 
-	if err := engine.validator.Struct(target.Elem().Interface()); err != nil {
+	if err := engine.Sanitize(target.Interface()); err != nil {
+		zap.L().Debug("Dynamic input sanitization failed", zap.Error(err), zap.String("cacheId", cacheID))
+		return nil, errors.NewInternalServerError("Failed to sanitize input", err)
+	}
+
+	if err := engine.ValidateStruct(target.Elem().Interface()); err != nil {
 		zap.L().Debug("Dynamic input validation failed", zap.Error(err))
-		return nil, errors.NewValidationFailed("Input validation failed", err)
+		return nil, engine.ValidationFailed("Input validation failed", err)
 	}
 
 	value := target.Elem()
 	result := make(map[string]interface{}, structType.NumField())
 	for i := 0; i < structType.NumField(); i++ {
-		result[structType.Field(i).Name] = value.Field(i).Interface()
+		field := value.Field(i)
+		if partial {
+			if field.IsNil() {
+				continue
+			}
+			field = field.Elem()
+		}
+		result[structType.Field(i).Name] = field.Interface()
+	}
+
+	if err := parseCustomFields(rules, value, result); err != nil {
+		zap.L().Debug("Dynamic input parsing failed", zap.Error(err))
+		return nil, errors.NewValidationFailed("Input validation failed", err)
 	}
 
 	return result, nil
@@ -255,6 +441,25 @@ func setDynamicFieldValue(field reflect.Value, value interface{}) error {
 		return nil
 	}
 
+	// Handle maps, converting every key and value
+	if field.Kind() == reflect.Map && source.Kind() == reflect.Map {
+		newMap := reflect.MakeMapWithSize(field.Type(), source.Len())
+		iter := source.MapRange()
+		for iter.Next() {
+			key := reflect.New(field.Type().Key()).Elem()
+			if err := setDynamicFieldValue(key, iter.Key().Interface()); err != nil {
+				return err
+			}
+			elem := reflect.New(field.Type().Elem()).Elem()
+			if err := setDynamicFieldValue(elem, iter.Value().Interface()); err != nil {
+				return err
+			}
+			newMap.SetMapIndex(key, elem)
+		}
+		field.Set(newMap)
+		return nil
+	}
+
 	// Handle nested structs (Map -> Struct)
 	if field.Kind() == reflect.Struct && source.Kind() == reflect.Map {
 		if mapVal, ok := value.(map[string]interface{}); ok {
@@ -286,6 +491,12 @@ func DynamicOutputData(engine *Engine, cacheID string, rules FieldRules, output
 		return nil, nil, errors.NewInternalServerError("Failed to prepare dynamic output rules", err)
 	}
 
+	output, err = formatCustomFields(rules, output)
+	if err != nil {
+		zap.L().Debug("Failed to format dynamic output field", zap.Error(err))
+		return nil, nil, errors.NewValidationFailed("Output validation failed", err)
+	}
+
 	target := reflect.New(structType).Elem()
 	for i := 0; i < structType.NumField(); i++ {
 		fieldName := structType.Field(i).Name
@@ -297,9 +508,9 @@ func DynamicOutputData(engine *Engine, cacheID string, rules FieldRules, output
 		}
 	}
 
-	if err := engine.validator.Struct(target.Interface()); err != nil {
+	if err := engine.ValidateStruct(target.Interface()); err != nil {
 		zap.L().Debug("Dynamic output validation failed", zap.Error(err))
-		return nil, nil, errors.NewValidationFailed("Output validation failed", err)
+		return nil, nil, engine.ValidationFailed("Output validation failed", err)
 	}
 
 	headers := make(map[string]string)
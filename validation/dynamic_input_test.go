@@ -90,6 +90,15 @@ func TestGetDynamicStructTypeCaching(t *testing.T) {
 	if first != second {
 		t.Fatalf("expected cached struct types to match for the same cache key")
 	}
+
+	engine.InvalidateDynamicStructs("cache-key")
+	third, err := getDynamicStructType(engine, "cache-key", alteredRules)
+	if err != nil {
+		t.Fatalf("expected no error rebuilding the struct, got %v", err)
+	}
+	if third == first || third.NumField() != 2 {
+		t.Fatalf("expected the invalidated struct to be rebuilt from the new rules")
+	}
 }
 
 func TestBuildDynamicStructType_RejectsUnexportedField(t *testing.T) {
@@ -0,0 +1,127 @@
+package validation
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestDynamicMapTypes(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    FieldRule
+		want    reflect.Type
+		wantErr bool
+	}{
+		{"Shorthand", FieldRule{Type: "map[string]string"}, reflect.TypeOf(map[string]string{}), false},
+		{"Int keys", FieldRule{Type: "map[int64]bool"}, reflect.TypeOf(map[int64]bool{}), false},
+		{"Map of slices", FieldRule{Type: "map[string][]int"}, reflect.TypeOf(map[string][]int{}), false},
+		{"Map of maps", FieldRule{Type: "map[string]map[string]float"}, reflect.TypeOf(map[string]map[string]float64{}), false},
+		{"Slice of slices", FieldRule{Type: "[][]string"}, reflect.TypeOf([][]string{}), false},
+		{"Slice of maps", FieldRule{Type: "[]map[string]int"}, reflect.TypeOf([]map[string]int{}), false},
+		{"Keys and values", FieldRule{Type: "map", Keys: &FieldRule{Type: "int"}, Values: &FieldRule{Type: "[]string"}}, reflect.TypeOf(map[int][]string{}), false},
+		{"Default key and value", FieldRule{Type: "map"}, reflect.TypeOf(map[string]string{}), false},
+		{"Bool keys", FieldRule{Type: "map[bool]string"}, nil, true},
+		{"Slice keys", FieldRule{Type: "map[[]int]string"}, nil, true},
+		{"Malformed", FieldRule{Type: "map[string"}, nil, true},
+		{"Types set twice", FieldRule{Type: "map[string]int", Values: &FieldRule{Type: "int"}}, nil, true},
+		{"Values without a map", FieldRule{Type: "[]string", Values: &FieldRule{Tags: "email"}}, nil, true},
+		{"Unknown value type", FieldRule{Type: "map[string]decimal"}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveFieldType(tt.rule)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolveFieldType() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	t.Run("Validate tags", func(t *testing.T) {
+		rule := FieldRule{Tags: "required", Type: "[]map", Keys: &FieldRule{Tags: "min=2"}, Values: &FieldRule{Tags: "email"}}
+		if tag := validateTag(rule); tag != "required,dive,dive,keys,min=2,endkeys,email" {
+			t.Errorf("Unexpected tag %q", tag)
+		}
+	})
+}
+
+func TestDynamicInputData_Maps(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := NewEngine(nil)
+
+	rules := FieldRules{
+		"Labels": {Tags: "required", Type: "map", Keys: &FieldRule{Tags: "min=2"}, Values: &FieldRule{Tags: "max=5"}},
+		"Scores": {Type: "map[string][]int"},
+		"Users":  {Type: "map[string]", Tags: "dive", Nested: FieldRules{"Name": {Tags: "required"}}},
+		"Grid":   {Type: "[][]int"},
+	}
+
+	bind := func(body string) (map[string]interface{}, error) {
+		ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+		ctx.Request = httptest.NewRequest(http.MethodPost, "/dynamic", bytes.NewBufferString(body))
+		ctx.Request.Header.Set("Content-Type", "application/json")
+		result, appErr := DynamicInputData(ctx, engine, "", rules)
+		if appErr != nil {
+			return nil, appErr
+		}
+		return result, nil
+	}
+
+	result, err := bind(`{"labels":{"env":"prod"},"scores":{"a":[1,2]},"users":{"u1":{"name":"Alice"}},"grid":[[1],[2,3]]}`)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if labels, ok := result["Labels"].(map[string]string); !ok || labels["env"] != "prod" {
+		t.Errorf("Unexpected labels %v", result["Labels"])
+	}
+	if scores, ok := result["Scores"].(map[string][]int); !ok || len(scores["a"]) != 2 {
+		t.Errorf("Unexpected scores %v", result["Scores"])
+	}
+	if grid, ok := result["Grid"].([][]int); !ok || len(grid) != 2 || grid[1][1] != 3 {
+		t.Errorf("Unexpected grid %v", result["Grid"])
+	}
+	if users := reflect.ValueOf(result["Users"]); users.Kind() != reflect.Map || users.Len() != 1 {
+		t.Errorf("Unexpected users %v", result["Users"])
+	}
+
+	for _, body := range []string{
+		`{"labels":{"e":"prod"}}`,
+		`{"labels":{"env":"production"}}`,
+		`{"labels":{"env":"prod"},"users":{"u1":{}}}`,
+		`{"labels":{"env":"prod"},"scores":{"a":"b"}}`,
+	} {
+		if _, err := bind(body); err == nil {
+			t.Errorf("Expected %s to be rejected", body)
+		}
+	}
+}
+
+func TestDynamicOutputData_Maps(t *testing.T) {
+	engine := NewEngine(nil)
+	rules := FieldRules{
+		"Counts": {Type: "map[string]int"},
+		"Tags":   {Type: "map[string][]string"},
+	}
+	output := map[string]interface{}{
+		"Counts": map[string]interface{}{"a": 1},
+		"Tags":   map[string]interface{}{"x": []interface{}{"y"}},
+	}
+	_, body, appErr := DynamicOutputData(engine, "", rules, output)
+	if appErr != nil {
+		t.Fatalf("Expected no error, got %v", appErr)
+	}
+	value := reflect.ValueOf(body)
+	if counts, ok := value.FieldByName("Counts").Interface().(map[string]int); !ok || counts["a"] != 1 {
+		t.Errorf("Unexpected counts %v", value.FieldByName("Counts"))
+	}
+	if tags, ok := value.FieldByName("Tags").Interface().(map[string][]string); !ok || tags["x"][0] != "y" {
+		t.Errorf("Unexpected tags %v", value.FieldByName("Tags"))
+	}
+}
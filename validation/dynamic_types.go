@@ -0,0 +1,221 @@
+package validation
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DynamicType is a custom FieldRule type, e.g., "uuid" or "decimal". Values are bound as strings, so the
+// validate tags apply to the raw value, then parsed into Type before they reach the handler.
+type DynamicType struct {
+	// Type is the Go type Parse returns, handlers receive it in the input map.
+	Type reflect.Type
+
+	// Parse converts the raw value of the request, an error fails the validation.
+	Parse func(raw string) (any, error)
+
+	// Format converts values returned by the handler back into their raw string (Default: fmt.Sprint)
+	Format func(value any) (string, error)
+}
+
+var (
+	dynamicTypesMu sync.RWMutex
+	dynamicTypes   = map[string]DynamicType{}
+)
+
+// DynamicDuration parses values like "1h30m" into a time.Duration, register it with e.g.
+// RegisterDynamicType("duration", DynamicDuration).
+var DynamicDuration = DynamicType{
+	Type:  reflect.TypeOf(time.Duration(0)),
+	Parse: func(raw string) (any, error) { return time.ParseDuration(raw) },
+}
+
+// DynamicDate parses values like "2024-01-31" into a time.Time.
+var DynamicDate = DynamicType{
+	Type:  reflect.TypeOf(time.Time{}),
+	Parse: func(raw string) (any, error) { return time.Parse(time.DateOnly, raw) },
+	Format: func(value any) (string, error) {
+		date, ok := value.(time.Time)
+		if !ok {
+			return "", fmt.Errorf("expected a time.Time, got %T", value)
+		}
+		return date.Format(time.DateOnly), nil
+	},
+}
+
+// RegisterDynamicType makes name usable as a FieldRule Type, also as a slice ("[]name"). Names are case-insensitive
+// and can't replace the built-in types. Register types at startup, before the rules using them are built.
+func RegisterDynamicType(name string, dynamicType DynamicType) error {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" || strings.HasPrefix(name, "[]") {
+		return fmt.Errorf("invalid dynamic type name %q", name)
+	}
+	if dynamicType.Type == nil || dynamicType.Parse == nil {
+		return fmt.Errorf("dynamic type %q needs a Type and a Parse function", name)
+	}
+	if _, err := resolveFieldType(FieldRule{Type: name}); err == nil {
+		return fmt.Errorf("dynamic type %q is already defined", name)
+	}
+
+	dynamicTypesMu.Lock()
+	defer dynamicTypesMu.Unlock()
+	dynamicTypes[name] = dynamicType
+	return nil
+}
+
+func lookupDynamicType(name string) (DynamicType, bool) {
+	dynamicTypesMu.RLock()
+	defer dynamicTypesMu.RUnlock()
+	dynamicType, ok := dynamicTypes[strings.ToLower(strings.TrimSpace(name))]
+	return dynamicType, ok
+}
+
+// customFieldType returns the custom type of a rule, and whether the rule is a slice of it.
+func customFieldType(rule FieldRule) (DynamicType, bool, bool) {
+	if len(rule.Nested) > 0 {
+		return DynamicType{}, false, false
+	}
+	typeName := strings.TrimSpace(rule.Type)
+	slice := strings.HasPrefix(typeName, "[]")
+	dynamicType, ok := lookupDynamicType(strings.TrimPrefix(typeName, "[]"))
+	return dynamicType, slice, ok
+}
+
+func parseCustomValue(dynamicType DynamicType, raw string) (reflect.Value, error) {
+	if raw == "" {
+		return reflect.Zero(dynamicType.Type), nil
+	}
+	parsed, err := dynamicType.Parse(raw)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	value := reflect.ValueOf(parsed)
+	if !value.IsValid() || !value.Type().AssignableTo(dynamicType.Type) {
+		return reflect.Value{}, fmt.Errorf("parsed %T, expected %s", parsed, dynamicType.Type)
+	}
+	return value, nil
+}
+
+// parseCustomFields replaces the raw strings of custom typed fields in result by their parsed values. Custom
+// types inside Nested rules are checked too, but keep their raw string as the nested struct can't hold them.
+func parseCustomFields(rules FieldRules, value reflect.Value, result map[string]interface{}) error {
+	for name, rule := range rules {
+		field := value.FieldByName(name)
+		if field.Kind() == reflect.Pointer {
+			// - Absent fields of partial inputs are nil
+			if field.IsNil() {
+				continue
+			}
+			field = field.Elem()
+		}
+		if !field.IsValid() {
+			continue
+		}
+
+		if len(rule.Nested) > 0 {
+			if err := checkNestedCustomFields(rule.Nested, field); err != nil {
+				return fmt.Errorf("%s.%w", name, err)
+			}
+			continue
+		}
+
+		dynamicType, slice, ok := customFieldType(rule)
+		if !ok {
+			continue
+		}
+		if !slice {
+			parsed, err := parseCustomValue(dynamicType, field.String())
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			if result != nil {
+				result[name] = parsed.Interface()
+			}
+			continue
+		}
+
+		parsedSlice := reflect.MakeSlice(reflect.SliceOf(dynamicType.Type), field.Len(), field.Len())
+		for i := 0; i < field.Len(); i++ {
+			parsed, err := parseCustomValue(dynamicType, field.Index(i).String())
+			if err != nil {
+				return fmt.Errorf("%s[%d]: %w", name, i, err)
+			}
+			parsedSlice.Index(i).Set(parsed)
+		}
+		if result != nil {
+			result[name] = parsedSlice.Interface()
+		}
+	}
+	return nil
+}
+
+func checkNestedCustomFields(rules FieldRules, value reflect.Value) error {
+	switch value.Kind() {
+	case reflect.Struct:
+		return parseCustomFields(rules, value, nil)
+	case reflect.Slice:
+		for i := 0; i < value.Len(); i++ {
+			if err := checkNestedCustomFields(rules, value.Index(i)); err != nil {
+				return fmt.Errorf("[%d]%w", i, err)
+			}
+		}
+	}
+	return nil
+}
+
+func formatCustomValue(dynamicType DynamicType, value any) (string, error) {
+	if raw, ok := value.(string); ok {
+		return raw, nil
+	}
+	if dynamicType.Format != nil {
+		return dynamicType.Format(value)
+	}
+	return fmt.Sprint(value), nil
+}
+
+// formatCustomFields returns output with the top-level custom typed values converted to their raw strings.
+func formatCustomFields(rules FieldRules, output map[string]interface{}) (map[string]interface{}, error) {
+	formatted, copied := output, false
+	for name, rule := range rules {
+		dynamicType, slice, ok := customFieldType(rule)
+		value, exists := output[name]
+		if !ok || !exists || value == nil {
+			continue
+		}
+
+		var raw interface{}
+		if source := reflect.ValueOf(value); slice && source.Kind() == reflect.Slice {
+			rawSlice := make([]string, source.Len())
+			for i := range rawSlice {
+				text, err := formatCustomValue(dynamicType, source.Index(i).Interface())
+				if err != nil {
+					return nil, fmt.Errorf("%s[%d]: %w", name, i, err)
+				}
+				rawSlice[i] = text
+			}
+			raw = rawSlice
+		} else if !slice {
+			text, err := formatCustomValue(dynamicType, value)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			raw = text
+		} else {
+			continue
+		}
+
+		// - The handler's map is copied before the first change
+		if !copied {
+			copied = true
+			formatted = make(map[string]interface{}, len(output))
+			for key, original := range output {
+				formatted[key] = original
+			}
+		}
+		formatted[name] = raw
+	}
+	return formatted, nil
+}
@@ -0,0 +1,117 @@
+package validation
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRegisterDynamicType(t *testing.T) {
+	hex := DynamicType{
+		Type:  reflect.TypeOf(uint64(0)),
+		Parse: func(raw string) (any, error) { return strconv.ParseUint(raw, 16, 64) },
+	}
+
+	tests := []struct {
+		name        string
+		typeName    string
+		dynamicType DynamicType
+		wantErr     bool
+	}{
+		{"Custom type", "test_hex", hex, false},
+		{"Registered twice", "test_hex", hex, true},
+		{"Built-in type", "int", hex, true},
+		{"Slice name", "[]test_other", hex, true},
+		{"Missing Parse", "test_other", DynamicType{Type: hex.Type}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := RegisterDynamicType(tt.typeName, tt.dynamicType); (err != nil) != tt.wantErr {
+				t.Errorf("RegisterDynamicType() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDynamicCustomTypes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := NewEngine(nil)
+	for name, dynamicType := range map[string]DynamicType{"test_duration": DynamicDuration, "test_date": DynamicDate} {
+		if err := RegisterDynamicType(name, dynamicType); err != nil {
+			t.Fatalf("Failed to register %s: %v", name, err)
+		}
+	}
+
+	rules := FieldRules{
+		"Timeout":  {Tags: "required", Type: "test_duration"},
+		"Dates":    {Type: "[]TEST_DATE"},
+		"Optional": {Type: "test_duration"},
+		"Window":   {Nested: FieldRules{"Length": {Type: "test_duration"}}},
+	}
+
+	bind := func(body string) (map[string]interface{}, error) {
+		ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+		ctx.Request = httptest.NewRequest(http.MethodPost, "/dynamic", bytes.NewBufferString(body))
+		ctx.Request.Header.Set("Content-Type", "application/json")
+		result, appErr := DynamicInputData(ctx, engine, "", rules)
+		if appErr != nil {
+			return nil, appErr
+		}
+		return result, nil
+	}
+
+	t.Run("Values are parsed", func(t *testing.T) {
+		result, err := bind(`{"timeout":"1m30s","dates":["2024-01-31"],"window":{"length":"1h"}}`)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if result["Timeout"] != 90*time.Second || result["Optional"] != time.Duration(0) {
+			t.Errorf("Unexpected durations %v, %v", result["Timeout"], result["Optional"])
+		}
+		dates, ok := result["Dates"].([]time.Time)
+		if !ok || len(dates) != 1 || dates[0].Day() != 31 {
+			t.Errorf("Unexpected dates %v", result["Dates"])
+		}
+	})
+
+	t.Run("Invalid values fail the validation", func(t *testing.T) {
+		for _, body := range []string{
+			`{"timeout":"soon"}`,
+			`{"timeout":"1m","dates":["31/01/2024"]}`,
+			`{"timeout":"1m","window":{"length":"long"}}`,
+			`{}`,
+		} {
+			if _, err := bind(body); err == nil {
+				t.Errorf("Expected %s to be rejected", body)
+			}
+		}
+	})
+
+	t.Run("Custom types are not supported in maps", func(t *testing.T) {
+		if _, err := DynamicStructType(FieldRules{"Timeouts": {Type: "map[string]test_duration"}}); err == nil {
+			t.Error("Expected a custom map value type to be rejected")
+		}
+	})
+
+	t.Run("Output values are formatted", func(t *testing.T) {
+		date := time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+		output := map[string]interface{}{"Timeout": 2 * time.Minute, "Dates": []time.Time{date}}
+		_, body, appErr := DynamicOutputData(engine, "", rules, output)
+		if appErr != nil {
+			t.Fatalf("Expected no error, got %v", appErr)
+		}
+		value := reflect.ValueOf(body)
+		if value.FieldByName("Timeout").String() != "2m0s" || value.FieldByName("Dates").Index(0).String() != "2024-01-31" {
+			t.Errorf("Unexpected output %+v", body)
+		}
+		if _, ok := output["Timeout"].(time.Duration); !ok {
+			t.Error("Expected the handler's output not to be modified")
+		}
+	})
+}
@@ -5,9 +5,27 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 	"github.com/grzegorzmaniak/gothic/errors"
+	"google.golang.org/protobuf/proto"
 )
 
+// bodyBindingFor selects the body binding for the request's Content-Type, JSON is the default.
+// Note: XML and YAML bodies are decoded with the `xml` / `yaml` struct tags, not the `json` ones, Protobuf
+// bodies require the input type to be a generated proto.Message.
+func bodyBindingFor(contentType string) (binding.BindingBody, string) {
+	switch contentType {
+	case binding.MIMEXML, binding.MIMEXML2:
+		return binding.XML, "XML"
+	case binding.MIMEYAML, binding.MIMEYAML2, "text/yaml":
+		return binding.YAML, "YAML"
+	case binding.MIMEPROTOBUF, "application/protobuf":
+		return binding.ProtoBuf, "Protobuf"
+	default:
+		return binding.JSON, "JSON"
+	}
+}
+
 func bindInput(ctx *gin.Context, target interface{}) *errors.AppError {
 	// - Bind URI Parameters (Path variables)
 	if err := ctx.ShouldBindUri(target); err != nil {
@@ -24,14 +42,18 @@ func bindInput(ctx *gin.Context, target interface{}) *errors.AppError {
 		return errors.NewValidationFailed("Failed to bind query parameters", err)
 	}
 
-	// - Bind JSON Body (Only for POST/PUT/PATCH requests)
+	// - Bind Body (Only for POST/PUT/PATCH requests), JSON unless the Content-Type is XML or YAML
 	if ctx.Request.Method != http.MethodGet && ctx.Request.Method != http.MethodDelete {
 
 		// - Check if the request has a body and Content-Type is set
 		if ctx.Request.ContentLength > 0 || ctx.GetHeader("Content-Type") != "" {
-			if err := ctx.ShouldBindJSON(target); err != nil {
+			bodyBinding, format := bodyBindingFor(ctx.ContentType())
+			if _, isMessage := target.(proto.Message); bodyBinding == binding.ProtoBuf && !isMessage {
+				return errors.NewAppError(http.StatusUnsupportedMediaType, "Protobuf bodies are not supported by this route", nil)
+			}
+			if err := ctx.ShouldBindWith(target, bodyBinding); err != nil {
 				if err != io.EOF || ctx.Request.ContentLength != 0 {
-					return errors.NewValidationFailed("Failed to bind JSON body", err)
+					return errors.NewValidationFailed("Failed to bind "+format+" body", err)
 				}
 			}
 		}
@@ -51,7 +73,8 @@ func BindInput[T any](ctx *gin.Context) (*T, *errors.AppError) {
 	return &input, nil
 }
 
-// InputData binds and validates the input data from the request context using the Engine's validator.
+// InputData binds, sanitizes (see SanitizeTag) and validates the input data from the request context using the
+// Engine's validator.
 func InputData[T any](ctx *gin.Context, engine *Engine) (*T, *errors.AppError) {
 	if engine == nil || engine.validator == nil {
 		return nil, errors.NewInternalServerError("Validator is not initialized", nil)
@@ -62,8 +85,12 @@ func InputData[T any](ctx *gin.Context, engine *Engine) (*T, *errors.AppError) {
 		return nil, err
 	}
 
-	if err := engine.validator.Struct(*input); err != nil {
-		return nil, errors.NewValidationFailed("Input validation failed", err)
+	if err := engine.Sanitize(input); err != nil {
+		return nil, errors.NewInternalServerError("Failed to sanitize input", err)
+	}
+
+	if err := engine.ValidateStruct(*input); err != nil {
+		return nil, engine.ValidationFailed("Input validation failed", err)
 	}
 
 	return input, nil
@@ -7,7 +7,10 @@ import (
 	"testing"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 	"github.com/go-playground/validator/v10"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
 )
 
 type testInputStruct struct {
@@ -430,3 +433,89 @@ func TestInputDataEdgeCases(t *testing.T) {
 		}
 	})
 }
+
+func TestBindInputContentTypes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	type encodedInput struct {
+		Name string `json:"name" xml:"name" yaml:"name" validate:"required"`
+		Age  int    `json:"age" xml:"age" yaml:"age"`
+	}
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+	}{
+		{name: "XML body", contentType: "application/xml", body: `<input><name>John</name><age>30</age></input>`},
+		{name: "Text XML body", contentType: "text/xml; charset=utf-8", body: `<input><name>John</name><age>30</age></input>`},
+		{name: "YAML body", contentType: "application/yaml", body: "name: John\nage: 30\n"},
+		{name: "Legacy YAML body", contentType: "application/x-yaml", body: "name: John\nage: 30\n"},
+		{name: "JSON body", contentType: "application/json", body: `{"name":"John","age":30}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/test", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+
+			ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+			ctx.Request = req
+
+			input, err := InputData[encodedInput](ctx, NewEngine(nil))
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if input.Name != "John" || input.Age != 30 {
+				t.Errorf("Expected John (30), got %s (%d)", input.Name, input.Age)
+			}
+		})
+	}
+
+	t.Run("Malformed XML is rejected", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/test", bytes.NewBufferString(`<input><name>`))
+		req.Header.Set("Content-Type", "application/xml")
+
+		ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+		ctx.Request = req
+
+		if _, err := BindInput[encodedInput](ctx); err == nil {
+			t.Error("Expected an error for a malformed XML body")
+		}
+	})
+}
+
+func TestBindInputProtobuf(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newContext := func(body []byte) *gin.Context {
+		req := httptest.NewRequest(http.MethodPost, "/test", bytes.NewReader(body))
+		req.Header.Set("Content-Type", binding.MIMEPROTOBUF)
+
+		ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+		ctx.Request = req
+		return ctx
+	}
+
+	body, err := proto.Marshal(wrapperspb.String("John"))
+	if err != nil {
+		t.Fatalf("Failed to marshal message: %v", err)
+	}
+
+	t.Run("Messages are decoded", func(t *testing.T) {
+		input, appErr := InputData[wrapperspb.StringValue](newContext(body), NewEngine(nil))
+		if appErr != nil {
+			t.Fatalf("Expected no error, got %v", appErr)
+		}
+		if input.GetValue() != "John" {
+			t.Errorf("Expected 'John', got '%s'", input.GetValue())
+		}
+	})
+
+	t.Run("Non message inputs are rejected", func(t *testing.T) {
+		_, appErr := BindInput[testInputStruct](newContext(body))
+		if appErr == nil || appErr.Code != http.StatusUnsupportedMediaType {
+			t.Errorf("Expected 415, got %v", appErr)
+		}
+	})
+}
@@ -0,0 +1,145 @@
+package validation
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// Optional wraps a field of a partial update, e.g., a PATCH input, telling an absent field apart from a zero one:
+//
+//	type PatchUserInput struct {
+//		Name Optional[string] `json:"name" validate:"omitempty,min=3"`
+//		Bio  Optional[string] `json:"bio"`
+//	}
+//
+// Fields missing from the JSON body are not Set, explicit nulls are Set and Null. The validate tags apply to the
+// wrapped Value, an absent or null field counts as empty, so partial inputs use omitempty instead of required.
+type Optional[T any] struct {
+	Value T
+	Set   bool
+	Null  bool
+}
+
+// Some returns a present Optional holding value.
+func Some[T any](value T) Optional[T] {
+	return Optional[T]{Value: value, Set: true}
+}
+
+// Get returns the value, and whether it was present and not null.
+func (o Optional[T]) Get() (T, bool) {
+	return o.Value, o.Set && !o.Null
+}
+
+// UnmarshalJSON marks the field as present, null values are only flagged.
+func (o *Optional[T]) UnmarshalJSON(data []byte) error {
+	o.Set = true
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		var zero T
+		o.Value, o.Null = zero, true
+		return nil
+	}
+	o.Null = false
+	return json.Unmarshal(data, &o.Value)
+}
+
+// MarshalJSON encodes the value, or null when it is absent or null.
+func (o Optional[T]) MarshalJSON() ([]byte, error) {
+	if !o.Set || o.Null {
+		return []byte("null"), nil
+	}
+	return json.Marshal(o.Value)
+}
+
+func (o Optional[T]) optionalValue() (interface{}, bool) {
+	return o.Value, o.Set && !o.Null
+}
+
+func (Optional[T]) optionalValueType() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+type optionalField interface {
+	optionalValue() (interface{}, bool)
+	optionalValueType() reflect.Type
+}
+
+var optionalFieldType = reflect.TypeOf((*optionalField)(nil)).Elem()
+
+// OptionalValueType returns the type wrapped by an Optional, e.g., to document the field.
+func OptionalValueType(t reflect.Type) (reflect.Type, bool) {
+	if t == nil || !t.Implements(optionalFieldType) {
+		return nil, false
+	}
+	return reflect.Zero(t).Interface().(optionalField).optionalValueType(), true
+}
+
+// optionalTypes tracks the Optional types registered with each validator, the validate tags of an Optional
+// apply to its Value through a custom type function.
+type optionalTypes struct {
+	sync.RWMutex
+	prepared sync.Map // Key: reflect.Type of the validated struct
+}
+
+func extractOptional(field reflect.Value) interface{} {
+	value, ok := field.Interface().(optionalField).optionalValue()
+	if !ok {
+		return nil
+	}
+	return value
+}
+
+// collectOptionalTypes finds the Optional types used anywhere in t.
+func collectOptionalTypes(t reflect.Type, seen map[reflect.Type]bool, found map[reflect.Type]bool) {
+	if t == nil || seen[t] {
+		return
+	}
+	seen[t] = true
+
+	if t.Implements(optionalFieldType) {
+		found[t] = true
+		inner, _ := OptionalValueType(t)
+		collectOptionalTypes(inner, seen, found)
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Array:
+		collectOptionalTypes(t.Elem(), seen, found)
+	case reflect.Map:
+		collectOptionalTypes(t.Key(), seen, found)
+		collectOptionalTypes(t.Elem(), seen, found)
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			collectOptionalTypes(t.Field(i).Type, seen, found)
+		}
+	}
+}
+
+// ValidateStruct validates value, registering the Optional types it uses with the validator first. Registering
+// is not safe while the validator is in use, so validations share a read lock that registrations take exclusively.
+func (e *Engine) ValidateStruct(value interface{}) error {
+	if e == nil || e.validator == nil {
+		return fmt.Errorf("validator is not initialized")
+	}
+
+	t := reflect.TypeOf(value)
+	if _, ok := e.optionals.prepared.Load(t); !ok {
+		found := map[reflect.Type]bool{}
+		collectOptionalTypes(t, map[reflect.Type]bool{}, found)
+		if len(found) > 0 {
+			e.optionals.Lock()
+			for optionalType := range found {
+				e.validator.RegisterCustomTypeFunc(extractOptional, reflect.Zero(optionalType).Interface())
+			}
+			e.optionals.Unlock()
+		}
+		e.optionals.prepared.Store(t, true)
+	}
+
+	e.optionals.RLock()
+	defer e.optionals.RUnlock()
+	return e.validator.Struct(value)
+}
@@ -0,0 +1,128 @@
+package validation
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+type patchProfileInput struct {
+	Name    Optional[string] `json:"name" validate:"omitempty,min=3"`
+	Age     Optional[int]    `json:"age" validate:"omitempty,gte=18"`
+	Contact Optional[struct {
+		Email string `json:"email" validate:"required,email"`
+	}] `json:"contact"`
+}
+
+func TestOptionalInputData(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := NewEngine(nil)
+
+	bind := func(body string) (*patchProfileInput, error) {
+		ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+		ctx.Request = httptest.NewRequest(http.MethodPatch, "/profile", bytes.NewBufferString(body))
+		ctx.Request.Header.Set("Content-Type", "application/json")
+		input, appErr := InputData[patchProfileInput](ctx, engine)
+		if appErr != nil {
+			return nil, appErr
+		}
+		return input, nil
+	}
+
+	t.Run("Absent, null and zero values", func(t *testing.T) {
+		input, err := bind(`{"name":"Alice","age":null}`)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if name, ok := input.Name.Get(); !ok || name != "Alice" {
+			t.Errorf("Expected the name to be set, got %+v", input.Name)
+		}
+		if !input.Age.Set || !input.Age.Null {
+			t.Errorf("Expected the age to be null, got %+v", input.Age)
+		}
+		if input.Contact.Set {
+			t.Errorf("Expected the contact to be absent, got %+v", input.Contact)
+		}
+	})
+
+	t.Run("Tags apply to present values", func(t *testing.T) {
+		for _, body := range []string{`{"name":"Al"}`, `{"age":12}`, `{"contact":{"email":"nope"}}`} {
+			if _, err := bind(body); err == nil {
+				t.Errorf("Expected %s to be rejected", body)
+			}
+		}
+		if _, err := bind(`{"contact":{"email":"a@example.com"}}`); err != nil {
+			t.Errorf("Expected a valid nested value to pass, got %v", err)
+		}
+	})
+
+	t.Run("Marshalling", func(t *testing.T) {
+		encoded, _ := json.Marshal(patchProfileInput{Name: Some("Bob")})
+		if string(encoded) != `{"name":"Bob","age":null,"contact":null}` {
+			t.Errorf("Unexpected JSON %s", encoded)
+		}
+		if inner, ok := OptionalValueType(reflect.TypeOf(Optional[int]{})); !ok || inner != reflect.TypeOf(0) {
+			t.Errorf("Expected int, got %v", inner)
+		}
+		if _, ok := OptionalValueType(reflect.TypeOf("")); ok {
+			t.Error("Expected a string not to be an Optional")
+		}
+	})
+}
+
+func TestDynamicPartialInputData(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := NewEngine(nil)
+	if err := RegisterDynamicType("test_partial_duration", DynamicDuration); err != nil {
+		t.Fatalf("Failed to register the type: %v", err)
+	}
+	rules := FieldRules{
+		"Name":    {Tags: "omitempty,min=3"},
+		"Age":     {Tags: "omitempty,gte=18", Type: "int"},
+		"Timeout": {Type: "test_partial_duration"},
+		"Bio":     {},
+	}
+
+	bind := func(body string) (map[string]interface{}, error) {
+		ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+		ctx.Request = httptest.NewRequest(http.MethodPatch, "/profile", bytes.NewBufferString(body))
+		ctx.Request.Header.Set("Content-Type", "application/json")
+		result, appErr := DynamicPartialInputData(ctx, engine, "partial", rules)
+		if appErr != nil {
+			return nil, appErr
+		}
+		return result, nil
+	}
+
+	result, err := bind(`{"bio":"","timeout":"1m"}`)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, ok := result["Name"]; ok || len(result) != 2 {
+		t.Errorf("Expected only the present fields, got %v", result)
+	}
+	if result["Bio"] != "" || result["Timeout"] == nil {
+		t.Errorf("Expected the empty bio and the parsed timeout, got %v", result)
+	}
+
+	// - Present zero values are validated too
+	for _, body := range []string{`{"name":"Al"}`, `{"age":0}`} {
+		if _, err = bind(body); err == nil {
+			t.Errorf("Expected %s to be validated", body)
+		}
+	}
+
+	// - The full struct of the same cache id is unaffected
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/profile", bytes.NewBufferString(`{}`))
+	ctx.Request.Header.Set("Content-Type", "application/json")
+	full, appErr := DynamicInputData(ctx, engine, "partial", rules)
+	if appErr != nil || len(full) != 4 {
+		t.Errorf("Expected every field, got %v, %v", full, appErr)
+	}
+}
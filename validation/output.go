@@ -9,9 +9,16 @@ import (
 
 // OutputData validates the output struct and prepares headers and body for response.
 // It returns the header map, the validated output struct, and any error that occurred.
+// Fields tagged with RedactTag are always redacted, use OutputDataWithPermissions to grant them.
 // NOTE: I dont think that this is the fastest way to do this, so if you have any
 // suggestions, please let me know. (Or make a PR)
 func OutputData[Output any](engine *Engine, output *Output) (map[string]string, *Output, *errors.AppError) {
+	return OutputDataWithPermissions(engine, output, nil)
+}
+
+// OutputDataWithPermissions is OutputData for a session, fields tagged with RedactTag are only kept when the
+// checker grants their permission. The output is validated before redaction, and headers are extracted after.
+func OutputDataWithPermissions[Output any](engine *Engine, output *Output, permissions PermissionChecker) (map[string]string, *Output, *errors.AppError) {
 	// - Initialize an empty header map
 	headers := make(map[string]string)
 
@@ -24,10 +31,13 @@ func OutputData[Output any](engine *Engine, output *Output) (map[string]string,
 	}
 
 	// - Validate the output structure
-	if err := engine.validator.Struct(*output); err != nil {
-		return headers, nil, errors.NewValidationFailed("Output data validation failed", err)
+	if err := engine.ValidateStruct(*output); err != nil {
+		return headers, nil, engine.ValidationFailed("Output data validation failed", err)
 	}
 
+	// - Redact the fields the session lacks permission for, the handler's output is left untouched
+	output = RedactOutput(output, permissions)
+
 	// - Extract headers from the struct fields tagged with `header:"X-Header-CookieName"`
 	val := reflect.ValueOf(*output)
 	typ := val.Type()
@@ -0,0 +1,188 @@
+package validation
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+)
+
+const (
+	// RedactTag marks output fields that require a permission, e.g.:
+	//
+	//	Email string `json:"email,omitempty" redact:"perm=users.read_pii"`
+	//	Phone string `json:"phone" redact:"perm=users.read_pii,mask"`
+	//	SSN   string `json:"ssn" redact:"perm=users.read_pii,mask=***-**-****"`
+	//
+	// Without the permission the field is set to its zero value (so it is omitted with omitempty), or replaced
+	// by the mask when "mask" is set on a string field. A tag without "perm=" always redacts the field.
+	RedactTag = "redact"
+
+	DefaultRedactionMask = "[redacted]"
+)
+
+// PermissionChecker reports whether the current session holds the named permission. A nil checker holds no
+// permissions, so every tagged field is redacted.
+type PermissionChecker func(permission string) bool
+
+// redactRule is a parsed RedactTag.
+type redactRule struct {
+	permission string
+	mask       string
+	masked     bool
+}
+
+func parseRedactRule(tag string) redactRule {
+	var rule redactRule
+	for _, option := range strings.Split(tag, ",") {
+		key, value, hasValue := strings.Cut(strings.TrimSpace(option), "=")
+		switch key {
+		case "perm":
+			rule.permission = strings.TrimSpace(value)
+		case "mask":
+			rule.masked = true
+			rule.mask = DefaultRedactionMask
+			if hasValue {
+				rule.mask = value
+			}
+		}
+	}
+	return rule
+}
+
+// allowed reports whether the checker grants the rule's permission.
+func (r redactRule) allowed(permissions PermissionChecker) bool {
+	return r.permission != "" && permissions != nil && permissions(r.permission)
+}
+
+// redactedTypes caches whether a type (transitively) has redact tagged fields.
+var redactedTypes sync.Map // map[reflect.Type]bool
+
+func hasRedactedFields(typ reflect.Type) bool {
+	for typ.Kind() == reflect.Pointer || typ.Kind() == reflect.Slice || typ.Kind() == reflect.Array || typ.Kind() == reflect.Map {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		return false
+	}
+
+	if cached, ok := redactedTypes.Load(typ); ok {
+		return cached.(bool)
+	}
+
+	// - Recursive types are resolved as not redacted until the walk finishes
+	redactedTypes.Store(typ, false)
+	found := false
+	for i := 0; i < typ.NumField() && !found; i++ {
+		field := typ.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		_, tagged := field.Tag.Lookup(RedactTag)
+		found = tagged || hasRedactedFields(field.Type)
+	}
+	redactedTypes.Store(typ, found)
+	return found
+}
+
+// RedactOutput returns a copy of the output with the fields the checker does not grant redacted, the output
+// itself is never modified. Outputs without tagged fields are returned as is.
+func RedactOutput[Output any](output *Output, permissions PermissionChecker) *Output {
+	if output == nil || !hasRedactedFields(reflect.TypeOf(output)) {
+		return output
+	}
+	return redactValue(reflect.ValueOf(output), permissions).Interface().(*Output)
+}
+
+// redactValue copies the value, structs, pointers, slices, arrays and maps leading to tagged fields are copied
+// so the caller's data is left untouched. Interface values are not inspected.
+func redactValue(v reflect.Value, permissions PermissionChecker) reflect.Value {
+	if !hasRedactedFields(v.Type()) {
+		return v
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return v
+		}
+		copied := reflect.New(v.Type().Elem())
+		copied.Elem().Set(redactValue(v.Elem(), permissions))
+		return copied
+
+	case reflect.Struct:
+		copied := reflect.New(v.Type()).Elem()
+		copied.Set(v)
+		redactStructFields(copied, permissions)
+		return copied
+
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		copied := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			copied.Index(i).Set(redactValue(v.Index(i), permissions))
+		}
+		return copied
+
+	case reflect.Array:
+		copied := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			copied.Index(i).Set(redactValue(v.Index(i), permissions))
+		}
+		return copied
+
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		copied := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			copied.SetMapIndex(iter.Key(), redactValue(iter.Value(), permissions))
+		}
+		return copied
+
+	default:
+		return v
+	}
+}
+
+// redactStructFields redacts the tagged fields of an addressable struct copy and recurses into the others.
+func redactStructFields(v reflect.Value, permissions PermissionChecker) {
+	typ := v.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		fieldValue := v.Field(i)
+		tag, tagged := field.Tag.Lookup(RedactTag)
+		if !tagged {
+			fieldValue.Set(redactValue(fieldValue, permissions))
+			continue
+		}
+
+		rule := parseRedactRule(tag)
+		if rule.allowed(permissions) {
+			fieldValue.Set(redactValue(fieldValue, permissions))
+			continue
+		}
+		maskField(fieldValue, rule)
+	}
+}
+
+// maskField replaces string fields (and non-nil string pointers) with the mask, everything else is zeroed.
+func maskField(v reflect.Value, rule redactRule) {
+	switch {
+	case rule.masked && v.Kind() == reflect.String:
+		v.SetString(rule.mask)
+	case rule.masked && v.Kind() == reflect.Pointer && v.Type().Elem().Kind() == reflect.String && !v.IsNil():
+		masked := reflect.New(v.Type().Elem())
+		masked.Elem().SetString(rule.mask)
+		v.Set(masked)
+	default:
+		v.Set(reflect.Zero(v.Type()))
+	}
+}
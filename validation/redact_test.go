@@ -0,0 +1,122 @@
+package validation
+
+import (
+	"testing"
+)
+
+type redactedContact struct {
+	Email string `json:"email,omitempty" redact:"perm=users.read_pii"`
+	Label string `json:"label"`
+}
+
+type redactedOutput struct {
+	Name     string                      `json:"name"`
+	Phone    string                      `json:"phone" redact:"perm=users.read_pii,mask"`
+	SSN      *string                     `json:"ssn" redact:"perm=users.read_pii,mask=***"`
+	Salary   int                         `json:"salary" redact:"perm=users.read_salary"`
+	Secret   string                      `json:"secret" redact:""`
+	Token    string                      `header:"X-Token" redact:"perm=users.read_pii"`
+	Contacts []redactedContact           `json:"contacts"`
+	Primary  *redactedContact            `json:"primary"`
+	ByLabel  map[string]*redactedContact `json:"by_label"`
+}
+
+func newRedactedOutput() *redactedOutput {
+	ssn := "123-45-6789"
+	contact := redactedContact{Email: "john@example.com", Label: "work"}
+	return &redactedOutput{
+		Name:     "John",
+		Phone:    "555-0100",
+		SSN:      &ssn,
+		Salary:   100000,
+		Secret:   "hunter2",
+		Token:    "token",
+		Contacts: []redactedContact{contact},
+		Primary:  &contact,
+		ByLabel:  map[string]*redactedContact{"work": &contact},
+	}
+}
+
+func TestRedactOutput(t *testing.T) {
+	grant := func(names ...string) PermissionChecker {
+		return func(permission string) bool {
+			for _, name := range names {
+				if name == permission {
+					return true
+				}
+			}
+			return false
+		}
+	}
+
+	t.Run("Fields without permission are masked or zeroed", func(t *testing.T) {
+		original := newRedactedOutput()
+		redacted := RedactOutput(original, grant("users.read_salary"))
+
+		if redacted.Phone != DefaultRedactionMask || *redacted.SSN != "***" {
+			t.Errorf("Expected masked fields, got %q / %q", redacted.Phone, *redacted.SSN)
+		}
+		if redacted.Salary != 100000 || redacted.Name != "John" {
+			t.Errorf("Expected granted and untagged fields to be kept, got %+v", redacted)
+		}
+		if redacted.Contacts[0].Email != "" || redacted.Primary.Email != "" || redacted.ByLabel["work"].Email != "" {
+			t.Error("Expected nested fields to be redacted")
+		}
+		if redacted.Contacts[0].Label != "work" {
+			t.Errorf("Expected nested untagged fields to be kept, got %q", redacted.Contacts[0].Label)
+		}
+
+		if original.Phone != "555-0100" || *original.SSN != "123-45-6789" || original.Primary.Email == "" || original.Contacts[0].Email == "" {
+			t.Error("Expected the original output to be untouched")
+		}
+	})
+
+	t.Run("Granted permissions keep the fields", func(t *testing.T) {
+		redacted := RedactOutput(newRedactedOutput(), grant("users.read_pii", "users.read_salary"))
+		if redacted.Phone != "555-0100" || redacted.Primary.Email != "john@example.com" {
+			t.Errorf("Expected fields to be kept, got %+v", redacted)
+		}
+		if redacted.Secret != "" {
+			t.Error("Expected a tag without a permission to always redact")
+		}
+	})
+
+	t.Run("A nil checker redacts everything", func(t *testing.T) {
+		redacted := RedactOutput(newRedactedOutput(), nil)
+		if redacted.Salary != 0 || redacted.Phone != DefaultRedactionMask {
+			t.Errorf("Expected every tagged field to be redacted, got %+v", redacted)
+		}
+	})
+
+	t.Run("Outputs without tags are returned as is", func(t *testing.T) {
+		output := &redactedContact{}
+		type plain struct{ Name string }
+		plainOutput := &plain{Name: "John"}
+		if RedactOutput(plainOutput, nil) != plainOutput {
+			t.Error("Expected the same pointer for an output without tagged fields")
+		}
+		if RedactOutput(output, nil) == output {
+			t.Error("Expected a copy for an output with tagged fields")
+		}
+	})
+}
+
+func TestOutputDataWithPermissions(t *testing.T) {
+	engine := NewEngine(nil)
+
+	headers, output, appErr := OutputDataWithPermissions(engine, newRedactedOutput(), nil)
+	if appErr != nil {
+		t.Fatalf("Expected no error, got %v", appErr)
+	}
+	if headers["X-Token"] != "" {
+		t.Errorf("Expected the redacted header to be empty, got %q", headers["X-Token"])
+	}
+	if output.Phone != DefaultRedactionMask {
+		t.Errorf("Expected the phone to be masked, got %q", output.Phone)
+	}
+
+	headers, _, _ = OutputDataWithPermissions(engine, newRedactedOutput(), func(string) bool { return true })
+	if headers["X-Token"] != "token" {
+		t.Errorf("Expected the granted header, got %q", headers["X-Token"])
+	}
+}
@@ -0,0 +1,58 @@
+package validation
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ReadFieldRules decodes FieldRules from YAML or JSON (JSON is valid YAML), e.g.:
+//
+//	Email:
+//	  tags: required,email
+//	Age:
+//	  tags: gte=18
+//	  type: int
+//
+// Unknown keys, field names that are not exported and types that can not be resolved are rejected, so a broken
+// rule file fails when it is loaded rather than on the first request.
+func ReadFieldRules(reader io.Reader) (FieldRules, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read field rules: %w", err)
+	}
+
+	rules := FieldRules{}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return rules, nil
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err = decoder.Decode(&rules); err != nil {
+		return nil, fmt.Errorf("failed to decode field rules: %w", err)
+	}
+
+	if _, err = buildDynamicStructType(rules); err != nil {
+		return nil, fmt.Errorf("invalid field rules: %w", err)
+	}
+	return rules, nil
+}
+
+// LoadFieldRules reads FieldRules from a YAML or JSON file, see ReadFieldRules.
+func LoadFieldRules(path string) (FieldRules, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open field rules: %w", err)
+	}
+	defer file.Close()
+
+	rules, err := ReadFieldRules(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return rules, nil
+}
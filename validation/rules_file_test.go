@@ -0,0 +1,61 @@
+package validation
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadFieldRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    FieldRules
+		wantErr bool
+	}{
+		{"YAML", "Email:\n  tags: required,email\nAge:\n  type: int\n", FieldRules{"Email": {Tags: "required,email"}, "Age": {Type: "int"}}, false},
+		{"JSON", `{"Title": {"tags": "required", "json": "title"}}`, FieldRules{"Title": {Tags: "required", JSONName: "title"}}, false},
+		{"Nested", "Address:\n  nested:\n    City:\n      tags: required\n", FieldRules{"Address": {Nested: FieldRules{"City": {Tags: "required"}}}}, false},
+		{"Empty", "", FieldRules{}, false},
+		{"Unknown key", "Email:\n  tag: required\n", nil, true},
+		{"Unknown type", "Email:\n  type: uuid\n", nil, true},
+		{"Unexported field", "email:\n  tags: required\n", nil, true},
+		{"Malformed", "{", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, err := ReadFieldRules(strings.NewReader(tt.input))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ReadFieldRules() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(rules) != len(tt.want) {
+				t.Fatalf("Expected %v, got %v", tt.want, rules)
+			}
+			for name, rule := range tt.want {
+				got := rules[name]
+				if got.Tags != rule.Tags || got.Type != rule.Type || got.JSONName != rule.JSONName || len(got.Nested) != len(rule.Nested) {
+					t.Errorf("Expected %s to be %+v, got %+v", name, rule, got)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadFieldRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	if err := os.WriteFile(path, []byte("Title:\n  tags: required\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write rules: %v", err)
+	}
+	rules, err := LoadFieldRules(path)
+	if err != nil || rules["Title"].Tags != "required" {
+		t.Fatalf("Expected the rules to load, got %v, %v", rules, err)
+	}
+
+	if _, err = LoadFieldRules(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("Expected a missing file to fail")
+	}
+}
@@ -0,0 +1,219 @@
+package validation
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// SanitizeTag normalizes string input fields before they are validated, e.g.:
+//
+//	Email string   `json:"email" sanitize:"trim,lower" validate:"required,email"`
+//	Tags  []string `json:"tags" sanitize:"trim,stripctrl"`
+//
+// The sanitizers run left to right on strings, string pointers, slices, arrays and map values of strings, and
+// the wrapped value of an Optional. Untagged struct fields are walked for tagged fields of their own.
+const SanitizeTag = "sanitize"
+
+// Sanitizer normalizes a single string value.
+type Sanitizer func(value string) string
+
+// defaultSanitizers are available to every Engine, RegisterSanitizer can replace them per Engine.
+var defaultSanitizers = map[string]Sanitizer{
+	"trim":      strings.TrimSpace,
+	"lower":     strings.ToLower,
+	"upper":     strings.ToUpper,
+	"stripctrl": stripControl,
+	"collapse":  collapseSpaces,
+}
+
+// stripControl removes control characters, newlines and tabs included.
+func stripControl(value string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, value)
+}
+
+// collapseSpaces replaces runs of whitespace by a single space.
+func collapseSpaces(value string) string {
+	return strings.Join(strings.Fields(value), " ")
+}
+
+// sanitizedTypes caches whether a type (transitively) has sanitize tagged fields.
+var sanitizedTypes sync.Map // map[reflect.Type]bool
+
+func hasSanitizedFields(typ reflect.Type) bool {
+	for typ.Kind() == reflect.Pointer || typ.Kind() == reflect.Slice || typ.Kind() == reflect.Array || typ.Kind() == reflect.Map {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		return false
+	}
+
+	if cached, ok := sanitizedTypes.Load(typ); ok {
+		return cached.(bool)
+	}
+
+	// - Recursive types are resolved as not sanitized until the walk finishes
+	sanitizedTypes.Store(typ, false)
+	found := false
+	for i := 0; i < typ.NumField() && !found; i++ {
+		field := typ.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		_, tagged := field.Tag.Lookup(SanitizeTag)
+		found = tagged || hasSanitizedFields(field.Type)
+	}
+	sanitizedTypes.Store(typ, found)
+	return found
+}
+
+// RegisterSanitizer makes name usable in sanitize tags, replacing the built-in sanitizer of the same name
+// (trim, lower, upper, stripctrl and collapse). Register sanitizers at startup.
+func (e *Engine) RegisterSanitizer(name string, sanitizer Sanitizer) error {
+	if e == nil {
+		return fmt.Errorf("engine is not initialized")
+	}
+	name = strings.TrimSpace(name)
+	if name == "" || strings.Contains(name, ",") || sanitizer == nil {
+		return fmt.Errorf("invalid sanitizer %q", name)
+	}
+	e.sanitizers.Store(name, sanitizer)
+	return nil
+}
+
+func (e *Engine) sanitizer(name string) (Sanitizer, bool) {
+	if registered, ok := e.sanitizers.Load(name); ok {
+		return registered.(Sanitizer), true
+	}
+	sanitizer, ok := defaultSanitizers[name]
+	return sanitizer, ok
+}
+
+// sanitizerChain resolves the sanitizers of a tag, unknown names are an error.
+func (e *Engine) sanitizerChain(tag string) ([]Sanitizer, error) {
+	chain := make([]Sanitizer, 0, 2)
+	for _, name := range strings.Split(tag, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		sanitizer, ok := e.sanitizer(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown sanitizer %q", name)
+		}
+		chain = append(chain, sanitizer)
+	}
+	return chain, nil
+}
+
+// Sanitize applies the sanitize tags of value in place, value must be a pointer to a struct. Values without
+// tagged fields are left as they are.
+func (e *Engine) Sanitize(value interface{}) error {
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Pointer || v.IsNil() || !hasSanitizedFields(v.Type()) {
+		return nil
+	}
+	return e.sanitizeValue(v.Elem())
+}
+
+// sanitizeValue walks an addressable value for tagged struct fields.
+func (e *Engine) sanitizeValue(v reflect.Value) error {
+	if !hasSanitizedFields(v.Type()) {
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return nil
+		}
+		return e.sanitizeValue(v.Elem())
+
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := e.sanitizeValue(v.Index(i)); err != nil {
+				return fmt.Errorf("[%d]%w", i, err)
+			}
+		}
+
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			// - Map values are not addressable, they are sanitized in a copy
+			copied := reflect.New(v.Type().Elem()).Elem()
+			copied.Set(iter.Value())
+			if err := e.sanitizeValue(copied); err != nil {
+				return fmt.Errorf("[%v]%w", iter.Key(), err)
+			}
+			v.SetMapIndex(iter.Key(), copied)
+		}
+
+	case reflect.Struct:
+		typ := v.Type()
+		for i := 0; i < typ.NumField(); i++ {
+			field := typ.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+
+			tag, tagged := field.Tag.Lookup(SanitizeTag)
+			if !tagged {
+				if err := e.sanitizeValue(v.Field(i)); err != nil {
+					return fmt.Errorf("%s.%w", field.Name, err)
+				}
+				continue
+			}
+
+			chain, err := e.sanitizerChain(tag)
+			if err != nil {
+				return fmt.Errorf("%s: %w", field.Name, err)
+			}
+			applySanitizers(v.Field(i), chain)
+		}
+	}
+	return nil
+}
+
+// applySanitizers runs the chain on the strings held by v, other values are left untouched.
+func applySanitizers(v reflect.Value, chain []Sanitizer) {
+	switch v.Kind() {
+	case reflect.String:
+		value := v.String()
+		for _, sanitizer := range chain {
+			value = sanitizer(value)
+		}
+		v.SetString(value)
+
+	case reflect.Pointer:
+		if !v.IsNil() {
+			applySanitizers(v.Elem(), chain)
+		}
+
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			applySanitizers(v.Index(i), chain)
+		}
+
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			copied := reflect.New(v.Type().Elem()).Elem()
+			copied.Set(iter.Value())
+			applySanitizers(copied, chain)
+			v.SetMapIndex(iter.Key(), copied)
+		}
+
+	case reflect.Struct:
+		// - Optional holds its value in the first field
+		if v.Type().Implements(optionalFieldType) {
+			applySanitizers(v.Field(0), chain)
+		}
+	}
+}
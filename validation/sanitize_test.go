@@ -0,0 +1,125 @@
+package validation
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+type sanitizedAddress struct {
+	City string `json:"city" sanitize:"trim,upper"`
+}
+
+type sanitizedSignupInput struct {
+	Email     string            `json:"email" sanitize:"trim,lower" validate:"required,email"`
+	Name      *string           `json:"name" sanitize:"stripctrl,collapse" validate:"omitempty,min=3"`
+	Tags      []string          `json:"tags" sanitize:"trim"`
+	Labels    map[string]string `json:"labels" sanitize:"trim"`
+	Nickname  Optional[string]  `json:"nickname" sanitize:"trim" validate:"omitempty,min=2"`
+	Addresses []sanitizedAddress
+	Raw       string `json:"raw"`
+}
+
+func newSanitizeContext(body string) *gin.Context {
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/signup", bytes.NewBufferString(body))
+	ctx.Request.Header.Set("Content-Type", "application/json")
+	return ctx
+}
+
+func TestSanitizeInputData(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := NewEngine(nil)
+
+	t.Run("Tagged fields are normalized before validation", func(t *testing.T) {
+		ctx := newSanitizeContext(`{
+			"email": "  Alice@Example.COM ",
+			"name": "Al\u0000ice   \t Smith",
+			"tags": [" a ", "b "],
+			"labels": {"team": " core "},
+			"nickname": "  Al ",
+			"Addresses": [{"city": " paris "}],
+			"raw": "  kept  "
+		}`)
+		input, appErr := InputData[sanitizedSignupInput](ctx, engine)
+		if appErr != nil {
+			t.Fatalf("Expected no error, got %v", appErr)
+		}
+
+		if input.Email != "alice@example.com" || *input.Name != "Alice Smith" {
+			t.Errorf("Unexpected email %q or name %q", input.Email, *input.Name)
+		}
+		if !reflect.DeepEqual(input.Tags, []string{"a", "b"}) || input.Labels["team"] != "core" {
+			t.Errorf("Unexpected tags %q or labels %v", input.Tags, input.Labels)
+		}
+		if nickname, _ := input.Nickname.Get(); nickname != "Al" {
+			t.Errorf("Expected the Optional value to be trimmed, got %q", nickname)
+		}
+		if input.Addresses[0].City != "PARIS" || input.Raw != "  kept  " {
+			t.Errorf("Unexpected city %q or raw %q", input.Addresses[0].City, input.Raw)
+		}
+	})
+
+	t.Run("Validation sees the sanitized value", func(t *testing.T) {
+		if _, appErr := InputData[sanitizedSignupInput](newSanitizeContext(`{"email":"   "}`), engine); appErr == nil {
+			t.Error("Expected a blank email to fail the required tag once trimmed")
+		}
+	})
+
+	t.Run("Registered sanitizers", func(t *testing.T) {
+		type slugInput struct {
+			Slug string `json:"slug" sanitize:"trim,slug"`
+		}
+
+		if _, appErr := InputData[slugInput](newSanitizeContext(`{"slug":"a"}`), engine); appErr == nil || appErr.Code != http.StatusInternalServerError {
+			t.Fatalf("Expected an unknown sanitizer to fail with 500, got %v", appErr)
+		}
+
+		custom := NewEngine(nil)
+		if err := custom.RegisterSanitizer("slug", func(value string) string {
+			return strings.ReplaceAll(strings.ToLower(value), " ", "-")
+		}); err != nil {
+			t.Fatalf("Expected the sanitizer to register, got %v", err)
+		}
+		if err := custom.RegisterSanitizer("a,b", strings.TrimSpace); err == nil {
+			t.Error("Expected a name with a comma to be rejected")
+		}
+
+		input, appErr := InputData[slugInput](newSanitizeContext(`{"slug":" Hello World "}`), custom)
+		if appErr != nil || input.Slug != "hello-world" {
+			t.Errorf("Expected hello-world, got %+v (%v)", input, appErr)
+		}
+	})
+}
+
+func TestSanitizeDynamicInputData(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := NewEngine(nil)
+
+	rules := FieldRules{
+		"Email":   {Tags: "required,email", Sanitize: "trim,lower"},
+		"Aliases": {Type: "[]string", Tags: "omitempty,dive,min=2", Sanitize: "trim"},
+		"Profile": {Nested: FieldRules{"Bio": {Sanitize: "collapse"}}},
+	}
+
+	result, appErr := DynamicInputData(newSanitizeContext(`{"email":" Bob@Example.com","aliases":[" bo "],"profile":{"bio":"a   b"}}`), engine, "sanitize", rules)
+	if appErr != nil {
+		t.Fatalf("Expected no error, got %v", appErr)
+	}
+	if result["Email"] != "bob@example.com" || !reflect.DeepEqual(result["Aliases"], []string{"bo"}) {
+		t.Errorf("Unexpected result %v", result)
+	}
+	if bio := reflect.ValueOf(result["Profile"]).FieldByName("Bio").String(); bio != "a b" {
+		t.Errorf("Expected the nested bio to be collapsed, got %q", bio)
+	}
+
+	partial, appErr := DynamicPartialInputData(newSanitizeContext(`{"email":" Eve@Example.com "}`), engine, "sanitize", rules)
+	if appErr != nil || partial["Email"] != "eve@example.com" {
+		t.Errorf("Expected partial inputs to be sanitized, got %v (%v)", partial, appErr)
+	}
+}
@@ -1,11 +1,21 @@
 package validation
 
-import "github.com/go-playground/validator/v10"
+import (
+	"fmt"
+	"sync"
+
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	"github.com/grzegorzmaniak/gothic/errors"
+)
 
 // Engine holds validation state, including the validator instance and dynamic struct cache.
 type Engine struct {
 	validator          *validator.Validate
 	dynamicStructCache dynamicStructCache
+	translator         ut.Translator
+	optionals          optionalTypes
+	sanitizers         sync.Map // Key: sanitizer name, Value: Sanitizer
 }
 
 // NewEngine constructs a validation Engine. If v is nil, a new validator instance is created.
@@ -26,3 +36,78 @@ func (e *Engine) Validator() *validator.Validate {
 	}
 	return e.validator
 }
+
+// InvalidateDynamicStructs drops the cached struct types of the given cache IDs, e.g., after the FieldRules of a
+// dynamic route changed. The next DynamicInputData or DynamicOutputData call rebuilds them.
+func (e *Engine) InvalidateDynamicStructs(cacheIDs ...string) {
+	if e == nil {
+		return
+	}
+	for _, cacheID := range cacheIDs {
+		e.dynamicStructCache.Delete(cacheID)
+		e.dynamicStructCache.Delete(partialCacheID(cacheID))
+	}
+}
+
+// RegisterValidation adds a custom validation tag, e.g., "username" or "slug", usable by typed and dynamic routes
+// validated by the Engine. Like every Register method it is not safe to call while requests are validated,
+// register tags at startup.
+func (e *Engine) RegisterValidation(tag string, fn validator.Func, callValidationEvenIfNull ...bool) error {
+	if e == nil || e.validator == nil {
+		return fmt.Errorf("validator is not initialized")
+	}
+	return e.validator.RegisterValidation(tag, fn, callValidationEvenIfNull...)
+}
+
+// RegisterStructValidation adds a struct level validation for the given types, e.g., to check fields against
+// each other.
+func (e *Engine) RegisterStructValidation(fn validator.StructLevelFunc, types ...interface{}) error {
+	if e == nil || e.validator == nil {
+		return fmt.Errorf("validator is not initialized")
+	}
+	e.validator.RegisterStructValidation(fn, types...)
+	return nil
+}
+
+// RegisterAlias makes alias expand to tags, e.g., RegisterAlias("username", "required,min=3,max=32,alphanum").
+func (e *Engine) RegisterAlias(alias string, tags string) error {
+	if e == nil || e.validator == nil {
+		return fmt.Errorf("validator is not initialized")
+	}
+	e.validator.RegisterAlias(alias, tags)
+	return nil
+}
+
+// SetTranslator makes validation failures carry the messages of trans, e.g., set up with the
+// go-playground/validator/v10/translations packages, instead of the default "failed on validation tag" ones.
+func (e *Engine) SetTranslator(trans ut.Translator) {
+	if e == nil {
+		return
+	}
+	e.translator = trans
+}
+
+// RegisterTranslation registers the message of a tag with the translator set by SetTranslator, e.g., for a tag
+// added with RegisterValidation.
+func (e *Engine) RegisterTranslation(tag string, registerFn validator.RegisterTranslationsFunc, translationFn validator.TranslationFunc) error {
+	if e == nil || e.validator == nil {
+		return fmt.Errorf("validator is not initialized")
+	}
+	if e.translator == nil {
+		return fmt.Errorf("no translator set, see SetTranslator")
+	}
+	return e.validator.RegisterTranslation(tag, e.translator, registerFn, translationFn)
+}
+
+// ValidationFailed returns the 422 AppError of a failed validation, with the field messages translated when a
+// translator is set.
+func (e *Engine) ValidationFailed(message string, err error) *errors.AppError {
+	if e == nil || e.translator == nil {
+		return errors.NewValidationFailed(message, err)
+	}
+
+	translated := errors.FormatTranslatedValidationErrors(err, func(fieldError validator.FieldError) string {
+		return fieldError.Translate(e.translator)
+	})
+	return errors.NewValidationFailed(message, err, translated)
+}
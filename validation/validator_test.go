@@ -1,9 +1,18 @@
 package validation
 
 import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
 	"testing"
 
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/locales/en"
+	ut "github.com/go-playground/universal-translator"
 	"github.com/go-playground/validator/v10"
+	entranslations "github.com/go-playground/validator/v10/translations/en"
+	"github.com/grzegorzmaniak/gothic/errors"
 )
 
 func TestNewEngineUsesProvidedValidator(t *testing.T) {
@@ -33,3 +42,88 @@ func TestNewEngineCreatesDefaultValidator(t *testing.T) {
 		t.Fatal("expected validation error for invalid email")
 	}
 }
+
+func TestEngineCustomValidations(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := NewEngine(nil)
+
+	slug := regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+	if err := engine.RegisterValidation("slug", func(fl validator.FieldLevel) bool {
+		return slug.MatchString(fl.Field().String())
+	}); err != nil {
+		t.Fatalf("Failed to register slug: %v", err)
+	}
+	if err := engine.RegisterAlias("username", "required,min=3,alphanum"); err != nil {
+		t.Fatalf("Failed to register the alias: %v", err)
+	}
+
+	type signup struct {
+		Username string `json:"username" validate:"username"`
+		Slug     string `json:"slug" validate:"slug"`
+		Password string `json:"password"`
+		Confirm  string `json:"confirm"`
+	}
+	if err := engine.RegisterStructValidation(func(sl validator.StructLevel) {
+		if input := sl.Current().Interface().(signup); input.Password != input.Confirm {
+			sl.ReportError(input.Confirm, "Confirm", "Confirm", "eqfield", "Password")
+		}
+	}, signup{}); err != nil {
+		t.Fatalf("Failed to register the struct validation: %v", err)
+	}
+
+	t.Run("Typed routes", func(t *testing.T) {
+		tests := []struct {
+			name    string
+			input   signup
+			wantErr bool
+		}{
+			{"Valid", signup{Username: "alice", Slug: "my-post", Password: "a", Confirm: "a"}, false},
+			{"Invalid slug", signup{Username: "alice", Slug: "My Post"}, true},
+			{"Invalid alias", signup{Username: "a!", Slug: "post"}, true},
+			{"Struct level", signup{Username: "alice", Slug: "post", Password: "a", Confirm: "b"}, true},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				if err := engine.Validator().Struct(tt.input); (err != nil) != tt.wantErr {
+					t.Errorf("Expected error %v, got %v", tt.wantErr, err)
+				}
+			})
+		}
+	})
+
+	t.Run("Dynamic routes", func(t *testing.T) {
+		ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+		ctx.Request = httptest.NewRequest(http.MethodPost, "/dynamic", bytes.NewBufferString(`{"slug":"Not A Slug"}`))
+		ctx.Request.Header.Set("Content-Type", "application/json")
+		if _, appErr := DynamicInputData(ctx, engine, "", FieldRules{"Slug": {Tags: "slug"}}); appErr == nil {
+			t.Error("Expected the custom tag to apply to dynamic rules")
+		}
+	})
+
+	t.Run("Translations", func(t *testing.T) {
+		if err := engine.RegisterTranslation("slug", nil, nil); err == nil {
+			t.Error("Expected an error without a translator")
+		}
+
+		english := en.New()
+		trans, _ := ut.New(english, english).GetTranslator("en")
+		if err := entranslations.RegisterDefaultTranslations(engine.Validator(), trans); err != nil {
+			t.Fatalf("Failed to register the default translations: %v", err)
+		}
+		engine.SetTranslator(trans)
+		if err := engine.RegisterTranslation("slug", func(ut ut.Translator) error {
+			return ut.Add("slug", "{0} must be a slug", true)
+		}, func(ut ut.Translator, fe validator.FieldError) string {
+			message, _ := ut.T("slug", fe.Field())
+			return message
+		}); err != nil {
+			t.Fatalf("Failed to register the translation: %v", err)
+		}
+
+		appErr := engine.ValidationFailed("Input validation failed", engine.Validator().Struct(signup{Username: "alice", Slug: "No"}))
+		details, ok := appErr.Details.([]errors.ValidationErrorDetail)
+		if !ok || len(details) != 1 || details[0].Field != "signup.Slug" || details[0].Message != "Slug must be a slug" {
+			t.Errorf("Expected the translated message, got %#v", appErr.Details)
+		}
+	})
+}